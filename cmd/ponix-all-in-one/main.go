@@ -16,6 +16,10 @@ import (
 	"github.com/ponix-dev/ponix/internal/conf"
 	"github.com/ponix-dev/ponix/internal/connectrpc"
 	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/javascript"
+	"github.com/ponix-dev/ponix/internal/kms"
+	"github.com/ponix-dev/ponix/internal/modbus"
+	"github.com/ponix-dev/ponix/internal/mqtt"
 	"github.com/ponix-dev/ponix/internal/mux"
 	"github.com/ponix-dev/ponix/internal/nats"
 	"github.com/ponix-dev/ponix/internal/postgres"
@@ -95,6 +99,21 @@ func main() {
 	orgStore := postgres.NewOrganizationStore(dbQueries, dbpool)
 	userStore := postgres.NewUserStore(dbQueries, dbpool)
 	userOrgStore := postgres.NewUserOrganizationStore(dbQueries, dbpool)
+	edStatusStore := postgres.NewEndDeviceStatusStore(dbQueries, dbpool)
+	edPresenceStore := postgres.NewEndDevicePresenceStore(dbQueries, dbpool)
+	edTwinStore := postgres.NewEndDeviceTwinStore(dbQueries, dbpool)
+	edProfileStore := postgres.NewEndDeviceProfileStore(dbQueries, dbpool)
+	edDecommissionStore := postgres.NewEndDeviceDecommissionStore(dbQueries, dbpool)
+	edTransferStore := postgres.NewEndDeviceTransferStore(dbQueries, dbpool)
+	edClaimStore := postgres.NewEndDeviceClaimStore(dbQueries, dbpool)
+	euiBlockStore := postgres.NewEUIBlockStore(dbQueries, dbpool)
+	rootKeyStore := postgres.NewRootKeyStore(dbQueries, dbpool)
+	mqttStore := postgres.NewMQTTStore(dbQueries, dbpool)
+	modbusStore := postgres.NewModbusStore(dbQueries, dbpool)
+	payloadDecoderStore := postgres.NewPayloadDecoderStore(dbQueries, dbpool)
+	lorawanDownlinkStore := postgres.NewLoRaWANDownlinkStore(dbQueries, dbpool)
+	gatewayStore := postgres.NewGatewayStore(dbQueries, dbpool)
+	deviceGroupStore := postgres.NewDeviceGroupStore(dbQueries, dbpool)
 
 	pgxAdapter, err := postgres.NewCasbinAdapter(dbpool)
 	if err != nil {
@@ -114,12 +133,39 @@ func main() {
 	organizationEnforcer := casbin.NewOrganizationEnforcer(casbinEnforcer)
 	organizationAccessEnforcer := casbin.NewOrganizationAccessEnforcer(casbinEnforcer)
 	endDeviceEnforcer := casbin.NewEndDeviceEnforcer(casbinEnforcer)
+	deviceGroupEnforcer := casbin.NewDeviceGroupEnforcer(casbinEnforcer)
 	lorawanEnforcer := casbin.NewLoRaWANEnforcer(casbinEnforcer)
+	edClaimEnforcer := casbin.NewEndDeviceClaimEnforcer(casbinEnforcer)
+	edProfileEnforcer := casbin.NewEndDeviceProfileEnforcer(casbinEnforcer)
+	gatewayEnforcer := casbin.NewGatewayEnforcer(casbinEnforcer)
+
+	keyWrapper, err := kms.NewLocalKeyWrapper(
+		cfg.RootKeyPrimaryKeyId,
+		kms.WithMasterKeys(cfg.RootKeyMasterKeys...),
+		kms.WithMasterKeysFile(cfg.RootKeyMasterKeysFile),
+	)
+	if err != nil {
+		logger.Error("could not create root key wrapper", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	rootKeyCipher := domain.NewRootKeyCipher(keyWrapper)
+
+	// Root keys stored before encryption at rest was introduced are sealed before any are read
+	encrypted, err := domain.NewRootKeyManager(rootKeyStore, rootKeyCipher).EncryptPlaintextRootKeys(ctx)
+	if err != nil {
+		logger.Error("could not encrypt plaintext root keys", slog.Any("err", err))
+		os.Exit(1)
+	}
+	if encrypted > 0 {
+		logger.Info("encrypted plaintext root keys", slog.Int("end_devices", encrypted))
+	}
 
 	ttnClient, err := ttn.NewTTNClient(
 		ttn.WithRegion(ttn.TTNRegion(cfg.TTNRegion)),
 		ttn.WithServerName(cfg.TTNServerName),
 		ttn.WithCollaboratorApiKey(cfg.TTNApiKey, cfg.TTNApiCollaborator),
+		ttn.WithRootKeyOpener(rootKeyCipher),
 	)
 	if err != nil {
 		logger.Error("could not create ttn client", slog.Any("err", err))
@@ -161,8 +207,49 @@ func main() {
 	}
 
 	processedEnvelopeProducer := nats.NewProcessedEnvelopeProducer(jetstreamClient, cfg.NatsProcessedEnvelopeStream)
+	edTwinProducer := nats.NewEndDeviceTwinProducer(jetstreamClient, cfg.NatsEndDeviceTwinSubject)
+
+	edStatusMgr := domain.NewEndDeviceStatusManager(edStatusStore, edStore, xid.StringId, cfg.EndDeviceSilencePeriod)
+	edPresenceMgr := domain.NewEndDevicePresenceManager(edPresenceStore, cfg.EndDevicePresenceFields)
+	envelopeManager := domain.NewDataEnvelopeManager(processedEnvelopeProducer, envelopeStore, edStore, edStatusMgr, edPresenceMgr)
+	edTwinMgr := domain.NewEndDeviceTwinManager(edTwinStore, edStore, edTwinProducer)
+
+	mqttBridge := mqtt.NewBridge(
+		domain.NewMQTTUplinkManager(mqttStore, envelopeManager),
+		mqtt.WithBrokerURL(cfg.MQTTBrokerURL),
+		mqtt.WithClientID(cfg.MQTTClientId),
+		mqtt.WithCredentials(cfg.MQTTUsername, cfg.MQTTPassword),
+		mqtt.WithTopicFilter(cfg.MQTTTopicFilter),
+	)
+	modbusPoller := domain.NewModbusPoller(modbusStore, modbus.NewDialer(cfg.ModbusTimeout), envelopeManager)
+	payloadDecoderMgr := domain.NewPayloadDecoderManager(
+		payloadDecoderStore,
+		javascript.NewSandbox(
+			javascript.WithTimeout(cfg.PayloadDecoderTimeout),
+			javascript.WithMemoryLimit(cfg.PayloadDecoderMemoryLimit),
+		),
+	)
+	lorawanDownlinkMgr := domain.NewLoRaWANDownlinkManager(lorawanDownlinkStore, edStore, ttnClient, payloadDecoderMgr, xid.StringId)
+	lorawanUplinkMgr := domain.NewLoRaWANUplinkManager(edStore, envelopeManager, edStatusMgr, edTwinMgr, payloadDecoderMgr, lorawanDownlinkMgr)
 
-	envelopeManager := domain.NewDataEnvelopeManager(processedEnvelopeProducer, envelopeStore, edStore)
+	// The Things Stack MQTT server is an alternative to its webhook; the tenant of a cloud deployment is its server name
+	ttnBrokerURL := cfg.TTNMQTTBrokerURL
+	if ttnBrokerURL == "" {
+		ttnBrokerURL = ttn.MQTTBrokerURL(cfg.TTNServerName, ttn.TTNRegion(cfg.TTNRegion))
+	}
+	ttnTenantId := cfg.TTNTenantId
+	if ttnTenantId == "" {
+		ttnTenantId = cfg.TTNServerName
+	}
+	ttnSubscriber := ttn.NewMQTTSubscriber(
+		lorawanUplinkMgr,
+		cfg.ApplicationId,
+		ttnTenantId,
+		cfg.TTNApiKey,
+		mqtt.WithBrokerURL(ttnBrokerURL),
+		mqtt.WithClientID(cfg.TTNMQTTClientId),
+		mqtt.WithMaxReconnectInterval(cfg.TTNMQTTMaxReconnectInterval),
+	)
 
 	messageHandler := nats.NewProcessedEnvelopeMessageHandler(envelopeManager)
 	consumer, err := nats.NewJetStreamConsumer(context.Background(), jetstreamClient, cfg.NatsProcessedEnvelopeStream, "serviceName", cfg.NatsProcessedEnvelopeSubject)
@@ -175,9 +262,17 @@ func main() {
 		log.Fatalf("Failed to create consumer handler: %v", err)
 	}
 
-	edMgr := domain.NewEndDeviceManager(edStore, ttnClient, cfg.ApplicationId, xid.StringId, protobuf.Validate)
-	edDataMgr := domain.NewEndDeviceDataManager(envelopeStore, protobuf.Validate)
+	euiBlockMgr := domain.NewEUIBlockManager(euiBlockStore)
+	edMgr := domain.NewEndDeviceManager(edStore, ttnClient, euiBlockMgr, rootKeyCipher, edProfileStore, cfg.ApplicationId, xid.StringId, protobuf.Validate)
+	edProfileMgr := domain.NewEndDeviceProfileManager(edProfileStore, edMgr, xid.StringId)
+	edDataMgr := domain.NewEndDeviceDataManager(envelopeStore, edStore, protobuf.Validate)
+	rootKeyRotationMgr := domain.NewRootKeyRotationManager(rootKeyStore, edStore, ttnClient, rootKeyCipher, xid.StringId)
+	edClaimMgr := domain.NewEndDeviceClaimManager(edClaimStore, edStore, ttnClient, rootKeyCipher, xid.StringId)
+	edDecommissionMgr := domain.NewEndDeviceDecommissionManager(edDecommissionStore, edStore, edStatusMgr, ttnClient, envelopeStore, xid.StringId, cfg.EndDeviceDataRetention)
+	edTransferMgr := domain.NewEndDeviceTransferManager(edTransferStore, edStore, envelopeStore, endDeviceEnforcer, xid.StringId)
 	lorawanMgr := domain.NewLoRaWANManager(edStore, xid.StringId, protobuf.Validate)
+	gatewayMgr := domain.NewGatewayManager(gatewayStore, ttnClient, xid.StringId)
+	deviceGroupMgr := domain.NewDeviceGroupManager(deviceGroupStore, xid.StringId)
 	userOrgMgr := domain.NewUserOrganizationManager(userOrgStore, organizationEnforcer, protobuf.Validate)
 	organizationManager := domain.NewOrganizationManager(
 		orgStore,
@@ -233,7 +328,7 @@ func main() {
 
 		// IoT
 		mux.WithHandler(iotv1connect.NewEndDeviceServiceHandler(
-			connectrpc.NewEndDeviceHandler(edMgr, endDeviceEnforcer),
+			connectrpc.NewEndDeviceHandler(edMgr, edStatusMgr, edPresenceMgr, endDeviceEnforcer),
 			connect.WithInterceptors(
 				authenticationInterceptor,
 				superAdminInterceptor,
@@ -242,7 +337,97 @@ func main() {
 		)),
 
 		mux.WithHandler(iotv1connect.NewLoRaWANServiceHandler(
-			connectrpc.NewLoRaWANHandler(lorawanMgr, lorawanEnforcer),
+			connectrpc.NewLoRaWANHandler(lorawanMgr, payloadDecoderMgr, lorawanEnforcer),
+			connect.WithInterceptors(
+				authenticationInterceptor,
+				superAdminInterceptor,
+				protovalidateInterceptor,
+			),
+		)),
+
+		mux.WithHandler(iotv1connect.NewDeviceGroupServiceHandler(
+			connectrpc.NewDeviceGroupHandler(deviceGroupMgr, deviceGroupEnforcer),
+			connect.WithInterceptors(
+				authenticationInterceptor,
+				superAdminInterceptor,
+				protovalidateInterceptor,
+			),
+		)),
+
+		mux.WithHandler(iotv1connect.NewEndDeviceTwinServiceHandler(
+			connectrpc.NewEndDeviceTwinHandler(edTwinMgr, endDeviceEnforcer),
+			connect.WithInterceptors(
+				authenticationInterceptor,
+				superAdminInterceptor,
+				protovalidateInterceptor,
+			),
+		)),
+
+		mux.WithHandler(iotv1connect.NewEndDeviceRootKeyServiceHandler(
+			connectrpc.NewRootKeyHandler(rootKeyRotationMgr, endDeviceEnforcer),
+			connect.WithInterceptors(
+				authenticationInterceptor,
+				superAdminInterceptor,
+				protovalidateInterceptor,
+			),
+		)),
+
+		mux.WithHandler(iotv1connect.NewEndDeviceClaimServiceHandler(
+			connectrpc.NewEndDeviceClaimHandler(edClaimMgr, edClaimEnforcer),
+			connect.WithInterceptors(
+				authenticationInterceptor,
+				superAdminInterceptor,
+				protovalidateInterceptor,
+			),
+		)),
+
+		mux.WithHandler(iotv1connect.NewEndDeviceProfileServiceHandler(
+			connectrpc.NewEndDeviceProfileHandler(edProfileMgr, edProfileEnforcer),
+			connect.WithInterceptors(
+				authenticationInterceptor,
+				superAdminInterceptor,
+				protovalidateInterceptor,
+			),
+		)),
+
+		mux.WithHandler(iotv1connect.NewEndDeviceDecommissionServiceHandler(
+			connectrpc.NewEndDeviceDecommissionHandler(edDecommissionMgr, endDeviceEnforcer),
+			connect.WithInterceptors(
+				authenticationInterceptor,
+				superAdminInterceptor,
+				protovalidateInterceptor,
+			),
+		)),
+
+		mux.WithHandler(iotv1connect.NewEndDeviceTransferServiceHandler(
+			connectrpc.NewEndDeviceTransferHandler(edTransferMgr, endDeviceEnforcer),
+			connect.WithInterceptors(
+				authenticationInterceptor,
+				superAdminInterceptor,
+				protovalidateInterceptor,
+			),
+		)),
+
+		mux.WithHandler(iotv1connect.NewLoRaWANDownlinkServiceHandler(
+			connectrpc.NewLoRaWANDownlinkHandler(lorawanDownlinkMgr, endDeviceEnforcer),
+			connect.WithInterceptors(
+				authenticationInterceptor,
+				superAdminInterceptor,
+				protovalidateInterceptor,
+			),
+		)),
+
+		mux.WithHandler(iotv1connect.NewGatewayServiceHandler(
+			connectrpc.NewGatewayHandler(gatewayMgr, gatewayEnforcer),
+			connect.WithInterceptors(
+				authenticationInterceptor,
+				superAdminInterceptor,
+				protovalidateInterceptor,
+			),
+		)),
+
+		mux.WithHandler(iotv1connect.NewEUIBlockServiceHandler(
+			connectrpc.NewEUIBlockHandler(euiBlockMgr),
 			connect.WithInterceptors(
 				authenticationInterceptor,
 				superAdminInterceptor,
@@ -261,25 +446,43 @@ func main() {
 
 		// Data ingestion handler (no auth interceptors for MVP)
 		mux.WithHandler(iotv1connect.NewDataIngestionServiceHandler(
-			connectrpc.NewIngestionHandler(envelopeManager),
+			connectrpc.NewIngestionHandler(envelopeManager, edTwinMgr),
 			connect.WithInterceptors(protovalidateInterceptor),
 		)),
+
+		// The Things Stack webhook, authenticated with its shared secret
+		mux.WithHandler(ttn.WebhookPath, ttn.NewWebhookHandler(lorawanUplinkMgr, cfg.TTNWebhookSecret)),
 	)
 	if err != nil {
 		logger.Error("could not create server", slog.Any("err", err))
 		os.Exit(1)
 	}
 
-	r := runner.New(
+	runnerOptions := []runner.RunnerOption{
 		runner.WithLogger(logger),
 		runner.WithAppProcess(mux.NewRunner(srv)),
 		runner.WithCloser(mux.NewCloser(srv)),
 		runner.WithCloser(telemetry.MeterProviderCloser(meterProvider)),
 		runner.WithCloser(telemetry.TracerProviderCloser(tracerProvider)),
 		runner.WithAppProcess(nats.ConsumerRunner(consumerHandler)),
+		runner.WithAppProcess(domain.EndDeviceStatusSweepRunner(edStatusMgr, cfg.EndDeviceStatusSweepInterval)),
+		runner.WithAppProcess(domain.EndDevicePresencePruneRunner(edPresenceMgr, cfg.EndDevicePresencePruneInterval)),
+		runner.WithAppProcess(domain.EndDeviceDecommissionRunner(edDecommissionMgr, cfg.EndDeviceDecommissionInterval)),
+		runner.WithAppProcess(domain.GatewayStatusRunner(gatewayMgr, cfg.GatewayStatusInterval)),
 		runner.WithCloser(telemetry.LoggerProviderCloser()),
 		runner.WithCloser(nats.ConnectionCloser(natsConnection)),
-	)
+	}
+	if cfg.MQTTEnabled {
+		runnerOptions = append(runnerOptions, runner.WithAppProcess(mqtt.BridgeRunner(mqttBridge)))
+	}
+	if cfg.ModbusEnabled {
+		runnerOptions = append(runnerOptions, runner.WithAppProcess(domain.ModbusPollRunner(modbusPoller, cfg.ModbusPollTick)))
+	}
+	if cfg.TTNMQTTEnabled {
+		runnerOptions = append(runnerOptions, runner.WithAppProcess(mqtt.BridgeRunner(ttnSubscriber)))
+	}
+
+	r := runner.New(runnerOptions...)
 
 	r.Run()
 }
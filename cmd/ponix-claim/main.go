@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+
+	"github.com/ponix-dev/ponix/internal/casbin"
+	"github.com/ponix-dev/ponix/internal/conf"
+	"github.com/ponix-dev/ponix/internal/postgres"
+)
+
+// ponix-claim grants the manufacturer role to the user given with -manufacturer, letting them issue claim codes
+// with the IssueClaimCode RPC for the end devices of organizations they may create end devices in. Like granting
+// super admin privileges, it is an operator tool that needs direct database access.
+//
+// Claim codes themselves are issued and redeemed with the IssueClaimCode and ClaimEndDevice RPCs, which check the
+// caller's permissions.
+func main() {
+	logger := slog.Default()
+	ctx := context.Background()
+
+	userId := flag.String("manufacturer", "", "user to grant the manufacturer role")
+	flag.Parse()
+
+	if *userId == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	cfg, err := conf.GetConfig[conf.ManagementConfig](ctx)
+	if err != nil {
+		logger.Error("could not get config", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	curl := postgres.NewConnUrl(
+		postgres.WithDB(cfg.Database),
+		postgres.WithUrl(cfg.DatabaseUrl),
+		postgres.WithUser(cfg.DatabaseUsername),
+		postgres.WithPassword(cfg.DatabasePassword),
+	)
+
+	dbpool, err := postgres.NewPool(ctx, curl)
+	if err != nil {
+		logger.Error("could not create db pool", slog.Any("err", err))
+		os.Exit(1)
+	}
+	defer dbpool.Close()
+
+	pgxAdapter, err := postgres.NewCasbinAdapter(dbpool)
+	if err != nil {
+		logger.Error("could not create casbin adapter", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	casbinEnforcer, err := casbin.LoadEnforcer(pgxAdapter)
+	if err != nil {
+		logger.Error("could not create casbin enforcer", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	err = casbin.NewEndDeviceClaimEnforcer(casbinEnforcer).AddManufacturer(ctx, *userId)
+	if err != nil {
+		logger.Error("could not grant manufacturer role", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	logger.Info("granted manufacturer role", slog.String("user_id", *userId))
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/ponix-dev/ponix/internal/conf"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/kms"
+	"github.com/ponix-dev/ponix/internal/postgres"
+	"github.com/ponix-dev/ponix/internal/postgres/sqlc"
+	"github.com/ponix-dev/ponix/internal/protobuf"
+	"github.com/ponix-dev/ponix/internal/ttn"
+	"github.com/ponix-dev/ponix/internal/xid"
+)
+
+// ponix-import creates end devices in bulk from a CSV or NDJSON file and prints a JSON report with the
+// outcome of every row. It exits with status 1 when the import could not run and 2 when any row failed.
+func main() {
+	logger := slog.Default()
+	ctx := context.Background()
+
+	organizationId := flag.String("org", "", "organization to create the end devices in")
+	file := flag.String("file", "", "path of the import file, or - for stdin")
+	format := flag.String("format", "", "import file format: csv or ndjson (defaults to the file extension)")
+	dryRun := flag.Bool("dry-run", false, "validate every row without creating any end devices")
+	batchSize := flag.Int("batch-size", domain.DefaultEndDeviceImportBatchSize, "number of end devices stored per transaction")
+	flag.Parse()
+
+	if *organizationId == "" || *file == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	importFormat := domain.EndDeviceImportFormat(*format)
+	if importFormat == "" {
+		importFormat = domain.EndDeviceImportCSV
+		if strings.HasSuffix(*file, ".ndjson") || strings.HasSuffix(*file, ".jsonl") {
+			importFormat = domain.EndDeviceImportNDJSON
+		}
+	}
+
+	cfg, err := conf.GetConfig[conf.ManagementConfig](ctx)
+	if err != nil {
+		logger.Error("could not get config", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	input := os.Stdin
+	if *file != "-" {
+		input, err = os.Open(*file)
+		if err != nil {
+			logger.Error("could not open import file", slog.Any("err", err))
+			os.Exit(1)
+		}
+		defer input.Close()
+	}
+
+	rows, err := domain.ParseEndDeviceImport(input, importFormat)
+	if err != nil {
+		logger.Error("could not parse import file", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	curl := postgres.NewConnUrl(
+		postgres.WithDB(cfg.Database),
+		postgres.WithUrl(cfg.DatabaseUrl),
+		postgres.WithUser(cfg.DatabaseUsername),
+		postgres.WithPassword(cfg.DatabasePassword),
+	)
+
+	dbpool, err := postgres.NewPool(ctx, curl)
+	if err != nil {
+		logger.Error("could not create db pool", slog.Any("err", err))
+		os.Exit(1)
+	}
+	defer dbpool.Close()
+
+	dbQueries := sqlc.New(dbpool)
+	edStore := postgres.NewEndDeviceStore(dbQueries, dbpool)
+	euiBlockMgr := domain.NewEUIBlockManager(postgres.NewEUIBlockStore(dbQueries, dbpool))
+
+	keyWrapper, err := kms.NewLocalKeyWrapper(
+		cfg.RootKeyPrimaryKeyId,
+		kms.WithMasterKeys(cfg.RootKeyMasterKeys...),
+		kms.WithMasterKeysFile(cfg.RootKeyMasterKeysFile),
+	)
+	if err != nil {
+		logger.Error("could not create root key wrapper", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	rootKeyCipher := domain.NewRootKeyCipher(keyWrapper)
+
+	ttnClient, err := ttn.NewTTNClient(
+		ttn.WithRegion(ttn.TTNRegion(cfg.TTNRegion)),
+		ttn.WithServerName(cfg.TTNServerName),
+		ttn.WithCollaboratorApiKey(cfg.TTNApiKey, cfg.TTNApiCollaborator),
+		ttn.WithRootKeyOpener(rootKeyCipher),
+	)
+	if err != nil {
+		logger.Error("could not create ttn client", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	edMgr := domain.NewEndDeviceManager(edStore, ttnClient, euiBlockMgr, rootKeyCipher, postgres.NewEndDeviceProfileStore(dbQueries, dbpool), cfg.ApplicationId, xid.StringId, protobuf.Validate)
+
+	report, err := edMgr.ImportEndDevices(ctx, *organizationId, rows, domain.EndDeviceImportOptions{
+		DryRun:    *dryRun,
+		BatchSize: *batchSize,
+	})
+	if err != nil {
+		logger.Error("could not import end devices", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(report)
+	if err != nil {
+		logger.Error("could not write import report", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	if report.Failed > 0 {
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"os"
+
+	"github.com/ponix-dev/ponix/internal/conf"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/kms"
+	"github.com/ponix-dev/ponix/internal/postgres"
+	"github.com/ponix-dev/ponix/internal/postgres/sqlc"
+)
+
+// rootKeyReport is the outcome of a ponix-root-keys run.
+type rootKeyReport struct {
+	PrimaryKeyId string `json:"primary_key_id"`
+	Encrypted    int    `json:"encrypted"`
+	Rotated      int    `json:"rotated"`
+}
+
+// ponix-root-keys encrypts LoRaWAN root keys still stored as plaintext and, with -rotate, re-encrypts every
+// stored root key that is not wrapped with the primary master key. It prints the number of end devices changed as JSON.
+//
+// The root keys of a single end device are replaced with the RotateEndDeviceRootKeys RPC, which checks the
+// caller's permissions.
+func main() {
+	logger := slog.Default()
+	ctx := context.Background()
+
+	rotate := flag.Bool("rotate", false, "re-encrypt root keys wrapped with a master key other than the primary one")
+	flag.Parse()
+
+	cfg, err := conf.GetConfig[conf.ManagementConfig](ctx)
+	if err != nil {
+		logger.Error("could not get config", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	keyWrapper, err := kms.NewLocalKeyWrapper(
+		cfg.RootKeyPrimaryKeyId,
+		kms.WithMasterKeys(cfg.RootKeyMasterKeys...),
+		kms.WithMasterKeysFile(cfg.RootKeyMasterKeysFile),
+	)
+	if err != nil {
+		logger.Error("could not create root key wrapper", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	curl := postgres.NewConnUrl(
+		postgres.WithDB(cfg.Database),
+		postgres.WithUrl(cfg.DatabaseUrl),
+		postgres.WithUser(cfg.DatabaseUsername),
+		postgres.WithPassword(cfg.DatabasePassword),
+	)
+
+	dbpool, err := postgres.NewPool(ctx, curl)
+	if err != nil {
+		logger.Error("could not create db pool", slog.Any("err", err))
+		os.Exit(1)
+	}
+	defer dbpool.Close()
+
+	dbQueries := sqlc.New(dbpool)
+	rootKeyStore := postgres.NewRootKeyStore(dbQueries, dbpool)
+	rootKeyCipher := domain.NewRootKeyCipher(keyWrapper)
+
+	rootKeyMgr := domain.NewRootKeyManager(rootKeyStore, rootKeyCipher)
+
+	report := rootKeyReport{
+		PrimaryKeyId: keyWrapper.PrimaryKeyId(),
+	}
+
+	report.Encrypted, err = rootKeyMgr.EncryptPlaintextRootKeys(ctx)
+	if err != nil {
+		logger.Error("could not encrypt plaintext root keys", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	if *rotate {
+		report.Rotated, err = rootKeyMgr.RotateRootKeys(ctx)
+		if err != nil {
+			logger.Error("could not rotate root keys", slog.Any("err", err), slog.Int("rotated", report.Rotated))
+			os.Exit(1)
+		}
+	}
+
+	writeJSON(logger, report)
+}
+
+// writeJSON prints a value as indented JSON, exiting when it cannot be written.
+func writeJSON(logger *slog.Logger, value any) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(value)
+	if err != nil {
+		logger.Error("could not write output", slog.Any("err", err))
+		os.Exit(1)
+	}
+}
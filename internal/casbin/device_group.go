@@ -0,0 +1,52 @@
+package casbin
+
+import (
+	"context"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+)
+
+// DeviceGroupEnforcer manages authorization for device group operations.
+type DeviceGroupEnforcer struct {
+	enforcer *casbin.Enforcer
+}
+
+// NewDeviceGroupEnforcer creates a new device group enforcer instance.
+func NewDeviceGroupEnforcer(enforcer *casbin.Enforcer) *DeviceGroupEnforcer {
+	return &DeviceGroupEnforcer{
+		enforcer: enforcer,
+	}
+}
+
+// CanCreateDeviceGroup checks if a user has permission to create device groups within an organization.
+func (e *DeviceGroupEnforcer) CanCreateDeviceGroup(ctx context.Context, userId string, organizationId string) (bool, error) {
+	_, span := telemetry.Tracer().Start(ctx, "CanCreateDeviceGroup")
+	defer span.End()
+
+	return e.enforcer.Enforce(userId, "device_group", "create", organizationId)
+}
+
+// CanReadDeviceGroup checks if a user has permission to read device groups and their members within an organization.
+func (e *DeviceGroupEnforcer) CanReadDeviceGroup(ctx context.Context, userId string, organizationId string) (bool, error) {
+	_, span := telemetry.Tracer().Start(ctx, "CanReadDeviceGroup")
+	defer span.End()
+
+	return e.enforcer.Enforce(userId, "device_group", "read", organizationId)
+}
+
+// CanUpdateDeviceGroup checks if a user has permission to update device groups and change their members within an organization.
+func (e *DeviceGroupEnforcer) CanUpdateDeviceGroup(ctx context.Context, userId string, organizationId string) (bool, error) {
+	_, span := telemetry.Tracer().Start(ctx, "CanUpdateDeviceGroup")
+	defer span.End()
+
+	return e.enforcer.Enforce(userId, "device_group", "update", organizationId)
+}
+
+// CanDeleteDeviceGroup checks if a user has permission to delete device groups within an organization.
+func (e *DeviceGroupEnforcer) CanDeleteDeviceGroup(ctx context.Context, userId string, organizationId string) (bool, error) {
+	_, span := telemetry.Tracer().Start(ctx, "CanDeleteDeviceGroup")
+	defer span.End()
+
+	return e.enforcer.Enforce(userId, "device_group", "delete", organizationId)
+}
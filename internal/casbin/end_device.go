@@ -50,3 +50,11 @@ func (e *EndDeviceEnforcer) CanDeleteEndDevice(ctx context.Context, userId strin
 
 	return e.enforcer.Enforce(userId, "end_device", "delete", organizationId)
 }
+
+// CanTransferEndDevice checks if a user has permission to transfer end devices into or out of an organization.
+func (e *EndDeviceEnforcer) CanTransferEndDevice(ctx context.Context, userId string, organizationId string) (bool, error) {
+	_, span := telemetry.Tracer().Start(ctx, "CanTransferEndDevice")
+	defer span.End()
+
+	return e.enforcer.Enforce(userId, "end_device", "transfer", organizationId)
+}
@@ -0,0 +1,65 @@
+package casbin
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// manufacturerRole is the global role of accounts that pre-provision end devices for others to claim.
+const manufacturerRole = "manufacturer"
+
+// EndDeviceClaimEnforcer manages authorization for issuing and redeeming end device claim codes.
+type EndDeviceClaimEnforcer struct {
+	enforcer *casbin.Enforcer
+}
+
+// NewEndDeviceClaimEnforcer creates a new end device claim enforcer instance.
+func NewEndDeviceClaimEnforcer(enforcer *casbin.Enforcer) *EndDeviceClaimEnforcer {
+	return &EndDeviceClaimEnforcer{
+		enforcer: enforcer,
+	}
+}
+
+// AddManufacturer grants a user the manufacturer role, letting them issue claim codes for the end devices of
+// organizations they may create end devices in.
+func (e *EndDeviceClaimEnforcer) AddManufacturer(ctx context.Context, userId string) error {
+	_, span := telemetry.Tracer().Start(ctx, "AddManufacturer")
+	defer span.End()
+
+	_, err := e.enforcer.AddRoleForUser(userId, manufacturerRole)
+	if err != nil {
+		return stacktrace.NewStackTraceErrorf("failed to add manufacturer role: %w", err)
+	}
+
+	return e.enforcer.SavePolicy()
+}
+
+// CanIssueClaimCode checks if a user is a manufacturer with permission to create end devices within an organization.
+func (e *EndDeviceClaimEnforcer) CanIssueClaimCode(ctx context.Context, userId string, organizationId string) (bool, error) {
+	_, span := telemetry.Tracer().Start(ctx, "CanIssueClaimCode")
+	defer span.End()
+
+	roles, err := e.enforcer.GetRolesForUser(userId)
+	if err != nil {
+		return false, fmt.Errorf("failed to get user roles: %w", err)
+	}
+
+	if !slices.Contains(roles, manufacturerRole) {
+		return false, nil
+	}
+
+	return e.enforcer.Enforce(userId, "end_device", "create", organizationId)
+}
+
+// CanClaimEndDevice checks if a user has permission to claim end devices into an organization.
+func (e *EndDeviceClaimEnforcer) CanClaimEndDevice(ctx context.Context, userId string, organizationId string) (bool, error) {
+	_, span := telemetry.Tracer().Start(ctx, "CanClaimEndDevice")
+	defer span.End()
+
+	return e.enforcer.Enforce(userId, "end_device", "create", organizationId)
+}
@@ -0,0 +1,52 @@
+package casbin
+
+import (
+	"context"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+)
+
+// EndDeviceProfileEnforcer manages authorization for end device profile operations.
+type EndDeviceProfileEnforcer struct {
+	enforcer *casbin.Enforcer
+}
+
+// NewEndDeviceProfileEnforcer creates a new end device profile enforcer instance.
+func NewEndDeviceProfileEnforcer(enforcer *casbin.Enforcer) *EndDeviceProfileEnforcer {
+	return &EndDeviceProfileEnforcer{
+		enforcer: enforcer,
+	}
+}
+
+// CanCreateEndDeviceProfile checks if a user has permission to create end device profiles within an organization.
+func (e *EndDeviceProfileEnforcer) CanCreateEndDeviceProfile(ctx context.Context, userId string, organizationId string) (bool, error) {
+	_, span := telemetry.Tracer().Start(ctx, "CanCreateEndDeviceProfile")
+	defer span.End()
+
+	return e.enforcer.Enforce(userId, "end_device_profile", "create", organizationId)
+}
+
+// CanReadEndDeviceProfile checks if a user has permission to read end device profiles and preview their changes within an organization.
+func (e *EndDeviceProfileEnforcer) CanReadEndDeviceProfile(ctx context.Context, userId string, organizationId string) (bool, error) {
+	_, span := telemetry.Tracer().Start(ctx, "CanReadEndDeviceProfile")
+	defer span.End()
+
+	return e.enforcer.Enforce(userId, "end_device_profile", "read", organizationId)
+}
+
+// CanUpdateEndDeviceProfile checks if a user has permission to update end device profiles and propagate them to their devices within an organization.
+func (e *EndDeviceProfileEnforcer) CanUpdateEndDeviceProfile(ctx context.Context, userId string, organizationId string) (bool, error) {
+	_, span := telemetry.Tracer().Start(ctx, "CanUpdateEndDeviceProfile")
+	defer span.End()
+
+	return e.enforcer.Enforce(userId, "end_device_profile", "update", organizationId)
+}
+
+// CanDeleteEndDeviceProfile checks if a user has permission to delete end device profiles within an organization.
+func (e *EndDeviceProfileEnforcer) CanDeleteEndDeviceProfile(ctx context.Context, userId string, organizationId string) (bool, error) {
+	_, span := telemetry.Tracer().Start(ctx, "CanDeleteEndDeviceProfile")
+	defer span.End()
+
+	return e.enforcer.Enforce(userId, "end_device_profile", "delete", organizationId)
+}
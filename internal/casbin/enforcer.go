@@ -44,6 +44,17 @@ func NewEnforcer(ctx context.Context, a Adapter) (*casbin.Enforcer, error) {
 	return e, nil
 }
 
+// LoadEnforcer creates a Casbin enforcer with the RBAC model and the policies already stored by the adapter,
+// without resetting them to the defaults. Tools that only check permissions use it next to a running service.
+func LoadEnforcer(a Adapter) (*casbin.Enforcer, error) {
+	e, err := casbin.NewEnforcer(initializeModel(), a)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceErrorf("failed to create enforcer: %w", err)
+	}
+
+	return e, nil
+}
+
 func initializeModel() model.Model {
 	// Create model from code instead of file
 	m := model.NewModel()
@@ -68,6 +79,19 @@ func initializePolicies(e *casbin.Enforcer) error {
 		{"org_admin", "end_device", "read", "*"},
 		{"org_admin", "end_device", "update", "*"},
 		{"org_admin", "end_device", "delete", "*"},
+		{"org_admin", "end_device", "transfer", "*"},
+		{"org_admin", "device_group", "create", "*"},
+		{"org_admin", "device_group", "read", "*"},
+		{"org_admin", "device_group", "update", "*"},
+		{"org_admin", "device_group", "delete", "*"},
+		{"org_admin", "end_device_profile", "create", "*"},
+		{"org_admin", "end_device_profile", "read", "*"},
+		{"org_admin", "end_device_profile", "update", "*"},
+		{"org_admin", "end_device_profile", "delete", "*"},
+		{"org_admin", "gateway", "create", "*"},
+		{"org_admin", "gateway", "read", "*"},
+		{"org_admin", "gateway", "update", "*"},
+		{"org_admin", "gateway", "delete", "*"},
 		{"org_admin", "organization", "read", "*"},
 		{"org_admin", "organization", "update", "*"},
 		{"org_admin", "user", "create", "*"},
@@ -78,11 +102,18 @@ func initializePolicies(e *casbin.Enforcer) error {
 		// Member role policies - read and update access
 		{"org_member", "end_device", "read", "*"},
 		{"org_member", "end_device", "update", "*"},
+		{"org_member", "device_group", "read", "*"},
+		{"org_member", "device_group", "update", "*"},
+		{"org_member", "end_device_profile", "read", "*"},
+		{"org_member", "gateway", "read", "*"},
 		{"org_member", "organization", "read", "*"},
 		{"org_member", "user", "read", "*"},
 
 		// Viewer role policies - read-only access
 		{"org_viewer", "end_device", "read", "*"},
+		{"org_viewer", "device_group", "read", "*"},
+		{"org_viewer", "end_device_profile", "read", "*"},
+		{"org_viewer", "gateway", "read", "*"},
 		{"org_viewer", "organization", "read", "*"},
 		{"org_viewer", "user", "read", "*"},
 	}
@@ -0,0 +1,52 @@
+package casbin
+
+import (
+	"context"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+)
+
+// GatewayEnforcer manages authorization for gateway operations.
+type GatewayEnforcer struct {
+	enforcer *casbin.Enforcer
+}
+
+// NewGatewayEnforcer creates a new gateway enforcer instance.
+func NewGatewayEnforcer(enforcer *casbin.Enforcer) *GatewayEnforcer {
+	return &GatewayEnforcer{
+		enforcer: enforcer,
+	}
+}
+
+// CanCreateGateway checks if a user has permission to create gateways within an organization.
+func (e *GatewayEnforcer) CanCreateGateway(ctx context.Context, userId string, organizationId string) (bool, error) {
+	_, span := telemetry.Tracer().Start(ctx, "CanCreateGateway")
+	defer span.End()
+
+	return e.enforcer.Enforce(userId, "gateway", "create", organizationId)
+}
+
+// CanReadGateway checks if a user has permission to read gateways and their status within an organization.
+func (e *GatewayEnforcer) CanReadGateway(ctx context.Context, userId string, organizationId string) (bool, error) {
+	_, span := telemetry.Tracer().Start(ctx, "CanReadGateway")
+	defer span.End()
+
+	return e.enforcer.Enforce(userId, "gateway", "read", organizationId)
+}
+
+// CanUpdateGateway checks if a user has permission to update gateways within an organization.
+func (e *GatewayEnforcer) CanUpdateGateway(ctx context.Context, userId string, organizationId string) (bool, error) {
+	_, span := telemetry.Tracer().Start(ctx, "CanUpdateGateway")
+	defer span.End()
+
+	return e.enforcer.Enforce(userId, "gateway", "update", organizationId)
+}
+
+// CanDeleteGateway checks if a user has permission to delete gateways within an organization.
+func (e *GatewayEnforcer) CanDeleteGateway(ctx context.Context, userId string, organizationId string) (bool, error) {
+	_, span := telemetry.Tracer().Start(ctx, "CanDeleteGateway")
+	defer span.End()
+
+	return e.enforcer.Enforce(userId, "gateway", "delete", organizationId)
+}
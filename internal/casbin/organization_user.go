@@ -76,6 +76,7 @@ func (e *OrganizationEnforcer) addOrgSpecificPolicies(role domain.OrganizationRo
 			{orgRole, "end_device", "read", organization},
 			{orgRole, "end_device", "update", organization},
 			{orgRole, "end_device", "delete", organization},
+			{orgRole, "end_device", "transfer", organization},
 			{orgRole, "organization", "read", organization},
 			{orgRole, "organization", "update", organization},
 			{orgRole, "user", "create", organization},
@@ -63,6 +63,83 @@ func (es *EnvelopeStore) StoreProcessedEnvelopes(ctx context.Context, envelopes
 	return nil
 }
 
+// PurgeEndDeviceData deletes every processed envelope of an end device. ClickHouse runs the delete as an
+// asynchronous mutation; CountEndDeviceData reports when it has finished.
+func (es *EnvelopeStore) PurgeEndDeviceData(ctx context.Context, endDeviceId string) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "PurgeEndDeviceData")
+	defer span.End()
+
+	err := es.db.Exec(ctx, fmt.Sprintf("ALTER TABLE %s DELETE WHERE end_device_id = ?", es.table), endDeviceId)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// CountEndDeviceData counts the processed envelopes stored for an end device.
+func (es *EnvelopeStore) CountEndDeviceData(ctx context.Context, endDeviceId string) (uint64, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "CountEndDeviceData")
+	defer span.End()
+
+	var count uint64
+	err := es.db.QueryRow(ctx, fmt.Sprintf("SELECT count() FROM %s WHERE end_device_id = ?", es.table), endDeviceId).Scan(&count)
+	if err != nil {
+		return 0, stacktrace.NewStackTraceError(err)
+	}
+
+	return count, nil
+}
+
+// CopyEndDeviceData copies the processed envelopes an end device stored under one organization and processed
+// up to before to another organization. Envelopes already present in the other organization are skipped, so an
+// interrupted copy can be repeated.
+func (es *EnvelopeStore) CopyEndDeviceData(ctx context.Context, endDeviceId string, fromOrganizationId string, toOrganizationId string, before time.Time) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "CopyEndDeviceData")
+	defer span.End()
+
+	query := fmt.Sprintf(`
+		INSERT INTO %[1]s
+		SELECT ? AS organization_id, end_device_id, occurred_at, processed_at, data
+		FROM %[1]s
+		WHERE organization_id = ?
+		  AND end_device_id = ?
+		  AND processed_at <= ?
+		  AND (occurred_at, processed_at) NOT IN (
+			SELECT occurred_at, processed_at
+			FROM %[1]s
+			WHERE organization_id = ? AND end_device_id = ?
+		  )
+	`, es.table)
+
+	err := es.db.Exec(ctx, query, toOrganizationId, fromOrganizationId, endDeviceId, before, toOrganizationId, endDeviceId)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// RetagEndDeviceData moves the processed envelopes an end device stored under one organization and processed up
+// to before to another organization. The organization is part of the table's sorting key and cannot be updated in
+// place, so the envelopes are copied and the originals deleted with an asynchronous mutation.
+func (es *EnvelopeStore) RetagEndDeviceData(ctx context.Context, endDeviceId string, fromOrganizationId string, toOrganizationId string, before time.Time) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "RetagEndDeviceData")
+	defer span.End()
+
+	err := es.CopyEndDeviceData(ctx, endDeviceId, fromOrganizationId, toOrganizationId, before)
+	if err != nil {
+		return err
+	}
+
+	err = es.db.Exec(ctx, fmt.Sprintf("ALTER TABLE %s DELETE WHERE organization_id = ? AND end_device_id = ? AND processed_at <= ?", es.table), fromOrganizationId, endDeviceId, before)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
 // QueryEndDeviceData retrieves sensor data for an organization with histogram aggregation.
 // It returns time-bucketed histograms based on the query parameters.
 func (es *EnvelopeStore) QueryEndDeviceData(
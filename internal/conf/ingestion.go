@@ -13,4 +13,29 @@ type IngestionConfig struct {
 	NatsProcessedEnvelopeSubject     string        `env:"NATS_PROCESSED_ENVELOPE_SUBJECT"`
 	NatsProcessedEnvelopeBatchSize   int           `env:"NATS_PROCESSED_ENVELOPE_BATCH_SIZE"`
 	NatsProcessedEnvelopeBatchWait   time.Duration `env:"NATS_PROCESSED_ENVELOPE_BATCH_WAIT"`
+	NatsEndDeviceTwinSubject         string        `env:"NATS_END_DEVICE_TWIN_SUBJECT, default=end_device_twins"`
+	EndDeviceSilencePeriod           time.Duration `env:"END_DEVICE_SILENCE_PERIOD, default=1h"`
+	EndDeviceStatusSweepInterval     time.Duration `env:"END_DEVICE_STATUS_SWEEP_INTERVAL, default=1m"`
+	EndDevicePresenceFields          []string      `env:"END_DEVICE_PRESENCE_FIELDS"`
+	EndDevicePresencePruneInterval   time.Duration `env:"END_DEVICE_PRESENCE_PRUNE_INTERVAL, default=1h"`
+	EndDeviceDataRetention           time.Duration `env:"END_DEVICE_DATA_RETENTION, default=720h"`
+	EndDeviceDecommissionInterval    time.Duration `env:"END_DEVICE_DECOMMISSION_INTERVAL, default=1m"`
+	MQTTEnabled                      bool          `env:"MQTT_ENABLED, default=false"`
+	MQTTBrokerURL                    string        `env:"MQTT_BROKER_URL, default=tcp://localhost:1883"`
+	MQTTClientId                     string        `env:"MQTT_CLIENT_ID, default=ponix-mqtt-bridge"`
+	MQTTUsername                     string        `env:"MQTT_USERNAME"`
+	MQTTPassword                     string        `env:"MQTT_PASSWORD"`
+	MQTTTopicFilter                  string        `env:"MQTT_TOPIC_FILTER, default=ponix/+/up"`
+	ModbusEnabled                    bool          `env:"MODBUS_ENABLED, default=false"`
+	ModbusPollTick                   time.Duration `env:"MODBUS_POLL_TICK, default=1s"`
+	ModbusTimeout                    time.Duration `env:"MODBUS_TIMEOUT, default=5s"`
+	TTNWebhookSecret                 string        `env:"TTN_WEBHOOK_SECRET"`
+	TTNMQTTEnabled                   bool          `env:"TTN_MQTT_ENABLED, default=false"`
+	TTNMQTTBrokerURL                 string        `env:"TTN_MQTT_BROKER_URL"`
+	TTNMQTTClientId                  string        `env:"TTN_MQTT_CLIENT_ID, default=ponix-ttn-subscriber"`
+	TTNMQTTMaxReconnectInterval      time.Duration `env:"TTN_MQTT_MAX_RECONNECT_INTERVAL, default=2m"`
+	TTNTenantId                      string        `env:"TTN_TENANT"`
+	PayloadDecoderTimeout            time.Duration `env:"PAYLOAD_DECODER_TIMEOUT, default=100ms"`
+	PayloadDecoderMemoryLimit        int           `env:"PAYLOAD_DECODER_MEMORY_LIMIT, default=16777216"`
+	GatewayStatusInterval            time.Duration `env:"GATEWAY_STATUS_INTERVAL, default=1m"`
 }
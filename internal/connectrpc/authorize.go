@@ -0,0 +1,50 @@
+package connectrpc
+
+import (
+	"context"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/ponix-dev/ponix/internal/domain"
+)
+
+// permissionCheck is an authorizer method checking one permission of a user in an organization.
+type permissionCheck func(ctx context.Context, userId string, organizationId string) (bool, error)
+
+// authorize allows super admins, and other users when check grants them the permission in the organization.
+// The action completes the denial message, e.g. "read device groups".
+func authorize(ctx context.Context, organizationId string, check permissionCheck, action string) error {
+	userId, ok := domain.GetUserFromContext(ctx)
+	if !ok {
+		return connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("user not authenticated"))
+	}
+
+	if domain.IsSuperAdminFromContext(ctx) {
+		return nil
+	}
+
+	allowed, err := check(ctx, userId, organizationId)
+	if err != nil {
+		return connect.NewError(connect.CodeInternal, fmt.Errorf("authorization check failed: %w", err))
+	}
+
+	if !allowed {
+		return connect.NewError(connect.CodePermissionDenied, fmt.Errorf("user %s not authorized to %s in organization %s", userId, action, organizationId))
+	}
+
+	return nil
+}
+
+// requireSuperAdmin allows super admins only. The action completes the denial message, e.g. "add EUI blocks".
+func requireSuperAdmin(ctx context.Context, action string) error {
+	userId, ok := domain.GetUserFromContext(ctx)
+	if !ok {
+		return connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("user not authenticated"))
+	}
+
+	if !domain.IsSuperAdminFromContext(ctx) {
+		return connect.NewError(connect.CodePermissionDenied, fmt.Errorf("user %s not authorized to %s", userId, action))
+	}
+
+	return nil
+}
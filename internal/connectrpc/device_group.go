@@ -0,0 +1,272 @@
+package connectrpc
+
+import (
+	"context"
+	"errors"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"connectrpc.com/connect"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// DeviceGroupManager handles device group business operations.
+type DeviceGroupManager interface {
+	CreateDeviceGroup(ctx context.Context, organizationId string, name string, description string) (*domain.DeviceGroup, error)
+	GetDeviceGroup(ctx context.Context, groupId string, organizationId string) (*domain.DeviceGroup, error)
+	ListDeviceGroups(ctx context.Context, organizationId string) ([]*domain.DeviceGroup, error)
+	UpdateDeviceGroup(ctx context.Context, groupId string, organizationId string, name string, description string) (*domain.DeviceGroup, error)
+	DeleteDeviceGroup(ctx context.Context, groupId string, organizationId string) error
+	AddDeviceGroupMembers(ctx context.Context, groupId string, organizationId string, endDeviceIds []string) (int, error)
+	RemoveDeviceGroupMembers(ctx context.Context, groupId string, organizationId string, endDeviceIds []string) (int, error)
+	ListDeviceGroupMembers(ctx context.Context, groupId string, organizationId string) ([]*iotv1.EndDevice, error)
+	ListEndDeviceGroups(ctx context.Context, endDeviceId string, organizationId string) ([]*domain.DeviceGroup, error)
+}
+
+// DeviceGroupAuthorizer checks permissions for device group operations.
+type DeviceGroupAuthorizer interface {
+	CanCreateDeviceGroup(ctx context.Context, userId string, organizationId string) (bool, error)
+	CanReadDeviceGroup(ctx context.Context, userId string, organizationId string) (bool, error)
+	CanUpdateDeviceGroup(ctx context.Context, userId string, organizationId string) (bool, error)
+	CanDeleteDeviceGroup(ctx context.Context, userId string, organizationId string) (bool, error)
+}
+
+// DeviceGroupHandler implements Connect RPC handlers for device group operations.
+type DeviceGroupHandler struct {
+	deviceGroupManager DeviceGroupManager
+	authorizer         DeviceGroupAuthorizer
+}
+
+// NewDeviceGroupHandler creates a new DeviceGroupHandler with the provided dependencies.
+func NewDeviceGroupHandler(dgMgr DeviceGroupManager, authorizer DeviceGroupAuthorizer) *DeviceGroupHandler {
+	return &DeviceGroupHandler{
+		deviceGroupManager: dgMgr,
+		authorizer:         authorizer,
+	}
+}
+
+// CreateDeviceGroup handles RPC requests to create a device group in an organization.
+// Requires super admin privileges or device group creation permission in the organization.
+func (handler *DeviceGroupHandler) CreateDeviceGroup(ctx context.Context, req *connect.Request[iotv1.CreateDeviceGroupRequest]) (*connect.Response[iotv1.CreateDeviceGroupResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "CreateDeviceGroup")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanCreateDeviceGroup, "create device groups")
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := handler.deviceGroupManager.CreateDeviceGroup(ctx, req.Msg.GetOrganizationId(), req.Msg.GetName(), req.Msg.GetDescription())
+	if err != nil {
+		return nil, deviceGroupError(err)
+	}
+
+	return connect.NewResponse(iotv1.CreateDeviceGroupResponse_builder{
+		DeviceGroup: deviceGroupToProto(group),
+	}.Build()), nil
+}
+
+// DeviceGroup handles RPC requests to retrieve a single device group.
+// Requires super admin privileges or device group read permission in the organization.
+func (handler *DeviceGroupHandler) DeviceGroup(ctx context.Context, req *connect.Request[iotv1.DeviceGroupRequest]) (*connect.Response[iotv1.DeviceGroupResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "DeviceGroup")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanReadDeviceGroup, "read device groups")
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := handler.deviceGroupManager.GetDeviceGroup(ctx, req.Msg.GetDeviceGroupId(), req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, deviceGroupError(err)
+	}
+
+	return connect.NewResponse(iotv1.DeviceGroupResponse_builder{
+		DeviceGroup: deviceGroupToProto(group),
+	}.Build()), nil
+}
+
+// OrganizationDeviceGroups handles RPC requests to list the device groups of an organization ordered by name.
+// Requires super admin privileges or device group read permission in the organization.
+func (handler *DeviceGroupHandler) OrganizationDeviceGroups(ctx context.Context, req *connect.Request[iotv1.OrganizationDeviceGroupsRequest]) (*connect.Response[iotv1.OrganizationDeviceGroupsResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "OrganizationDeviceGroups")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanReadDeviceGroup, "read device groups")
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := handler.deviceGroupManager.ListDeviceGroups(ctx, req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(iotv1.OrganizationDeviceGroupsResponse_builder{
+		DeviceGroups: deviceGroupsToProto(groups),
+	}.Build()), nil
+}
+
+// UpdateDeviceGroup handles RPC requests to rename a device group and replace its description.
+// An empty name leaves the name unchanged.
+// Requires super admin privileges or device group update permission in the organization.
+func (handler *DeviceGroupHandler) UpdateDeviceGroup(ctx context.Context, req *connect.Request[iotv1.UpdateDeviceGroupRequest]) (*connect.Response[iotv1.UpdateDeviceGroupResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "UpdateDeviceGroup")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanUpdateDeviceGroup, "update device groups")
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := handler.deviceGroupManager.UpdateDeviceGroup(ctx, req.Msg.GetDeviceGroupId(), req.Msg.GetOrganizationId(), req.Msg.GetName(), req.Msg.GetDescription())
+	if err != nil {
+		return nil, deviceGroupError(err)
+	}
+
+	return connect.NewResponse(iotv1.UpdateDeviceGroupResponse_builder{
+		DeviceGroup: deviceGroupToProto(group),
+	}.Build()), nil
+}
+
+// DeleteDeviceGroup handles RPC requests to delete a device group. Its member devices are not affected.
+// Requires super admin privileges or device group delete permission in the organization.
+func (handler *DeviceGroupHandler) DeleteDeviceGroup(ctx context.Context, req *connect.Request[iotv1.DeleteDeviceGroupRequest]) (*connect.Response[iotv1.DeleteDeviceGroupResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "DeleteDeviceGroup")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanDeleteDeviceGroup, "delete device groups")
+	if err != nil {
+		return nil, err
+	}
+
+	err = handler.deviceGroupManager.DeleteDeviceGroup(ctx, req.Msg.GetDeviceGroupId(), req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, deviceGroupError(err)
+	}
+
+	return connect.NewResponse(iotv1.DeleteDeviceGroupResponse_builder{}.Build()), nil
+}
+
+// AddDeviceGroupMembers handles RPC requests to add end devices of the organization to a device group.
+// Devices that are already members are skipped; the response holds how many were newly added.
+// Requires super admin privileges or device group update permission in the organization.
+func (handler *DeviceGroupHandler) AddDeviceGroupMembers(ctx context.Context, req *connect.Request[iotv1.AddDeviceGroupMembersRequest]) (*connect.Response[iotv1.AddDeviceGroupMembersResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "AddDeviceGroupMembers")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanUpdateDeviceGroup, "update device groups")
+	if err != nil {
+		return nil, err
+	}
+
+	added, err := handler.deviceGroupManager.AddDeviceGroupMembers(ctx, req.Msg.GetDeviceGroupId(), req.Msg.GetOrganizationId(), req.Msg.GetEndDeviceIds())
+	if err != nil {
+		return nil, deviceGroupError(err)
+	}
+
+	return connect.NewResponse(iotv1.AddDeviceGroupMembersResponse_builder{
+		Added: int32(added),
+	}.Build()), nil
+}
+
+// RemoveDeviceGroupMembers handles RPC requests to remove end devices from a device group.
+// Devices that are not members are ignored; the response holds how many were removed.
+// Requires super admin privileges or device group update permission in the organization.
+func (handler *DeviceGroupHandler) RemoveDeviceGroupMembers(ctx context.Context, req *connect.Request[iotv1.RemoveDeviceGroupMembersRequest]) (*connect.Response[iotv1.RemoveDeviceGroupMembersResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "RemoveDeviceGroupMembers")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanUpdateDeviceGroup, "update device groups")
+	if err != nil {
+		return nil, err
+	}
+
+	removed, err := handler.deviceGroupManager.RemoveDeviceGroupMembers(ctx, req.Msg.GetDeviceGroupId(), req.Msg.GetOrganizationId(), req.Msg.GetEndDeviceIds())
+	if err != nil {
+		return nil, deviceGroupError(err)
+	}
+
+	return connect.NewResponse(iotv1.RemoveDeviceGroupMembersResponse_builder{
+		Removed: int32(removed),
+	}.Build()), nil
+}
+
+// DeviceGroupMembers handles RPC requests to list the end devices in a device group ordered by name.
+// Requires super admin privileges or device group read permission in the organization.
+func (handler *DeviceGroupHandler) DeviceGroupMembers(ctx context.Context, req *connect.Request[iotv1.DeviceGroupMembersRequest]) (*connect.Response[iotv1.DeviceGroupMembersResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "DeviceGroupMembers")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanReadDeviceGroup, "read device groups")
+	if err != nil {
+		return nil, err
+	}
+
+	endDevices, err := handler.deviceGroupManager.ListDeviceGroupMembers(ctx, req.Msg.GetDeviceGroupId(), req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, deviceGroupError(err)
+	}
+
+	return connect.NewResponse(iotv1.DeviceGroupMembersResponse_builder{
+		EndDevices: endDevices,
+	}.Build()), nil
+}
+
+// EndDeviceGroups handles RPC requests to list the device groups an end device belongs to.
+// Requires super admin privileges or device group read permission in the organization.
+func (handler *DeviceGroupHandler) EndDeviceGroups(ctx context.Context, req *connect.Request[iotv1.EndDeviceGroupsRequest]) (*connect.Response[iotv1.EndDeviceGroupsResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "EndDeviceGroups")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanReadDeviceGroup, "read device groups")
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := handler.deviceGroupManager.ListEndDeviceGroups(ctx, req.Msg.GetEndDeviceId(), req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(iotv1.EndDeviceGroupsResponse_builder{
+		DeviceGroups: deviceGroupsToProto(groups),
+	}.Build()), nil
+}
+
+// deviceGroupError maps device group errors to Connect error codes.
+func deviceGroupError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrDeviceGroupNotFound), errors.Is(err, domain.ErrEndDeviceNotFound):
+		return connect.NewError(connect.CodeNotFound, err)
+	case errors.Is(err, domain.ErrDeviceGroupExists):
+		return connect.NewError(connect.CodeAlreadyExists, err)
+	case errors.Is(err, domain.ErrInvalidDeviceGroup):
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	return err
+}
+
+// deviceGroupToProto converts a device group to its iot/v1 message.
+func deviceGroupToProto(group *domain.DeviceGroup) *iotv1.DeviceGroup {
+	return iotv1.DeviceGroup_builder{
+		Id:             group.Id,
+		OrganizationId: group.OrganizationId,
+		Name:           group.Name,
+		Description:    group.Description,
+		CreatedAt:      timestamppb.New(group.CreatedAt),
+		UpdatedAt:      timestamppb.New(group.UpdatedAt),
+	}.Build()
+}
+
+// deviceGroupsToProto converts device groups to their iot/v1 messages.
+func deviceGroupsToProto(groups []*domain.DeviceGroup) []*iotv1.DeviceGroup {
+	messages := make([]*iotv1.DeviceGroup, 0, len(groups))
+	for _, group := range groups {
+		messages = append(messages, deviceGroupToProto(group))
+	}
+
+	return messages
+}
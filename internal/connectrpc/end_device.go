@@ -2,7 +2,10 @@ package connectrpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 
 	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
 	"connectrpc.com/connect"
@@ -12,7 +15,19 @@ import (
 
 // EndDeviceManager handles end device business operations.
 type EndDeviceManager interface {
-	CreateEndDevice(ctx context.Context, createReq *iotv1.CreateEndDeviceRequest, organization string) (*iotv1.EndDevice, error)
+	CreateEndDevice(ctx context.Context, createReq *iotv1.CreateEndDeviceRequest, organization string, metadata domain.EndDeviceMetadata, identity domain.LoRaWANIdentity) (*iotv1.EndDevice, error)
+	GetEndDevice(ctx context.Context, endDeviceId string, organization string, includeKeys bool) (*iotv1.EndDevice, error)
+	GetEndDeviceMetadata(ctx context.Context, endDeviceId string, organization string) (domain.EndDeviceMetadata, error)
+	UpdateEndDevice(ctx context.Context, update *iotv1.EndDevice, organization string, metadataUpdate domain.EndDeviceMetadata) (*iotv1.EndDevice, domain.EndDeviceMetadata, error)
+	DeleteEndDevice(ctx context.Context, endDeviceId string, organization string) error
+	ImportEndDevices(ctx context.Context, organization string, rows []domain.EndDeviceImportRow, options domain.EndDeviceImportOptions) (*domain.EndDeviceImportReport, error)
+	ListEndDevices(ctx context.Context, listReq domain.EndDeviceListRequest) (*domain.EndDevicePage, error)
+	SearchEndDevices(ctx context.Context, searchReq domain.EndDeviceSearchRequest) (*domain.EndDeviceSearchPage, error)
+}
+
+// EndDevicePresenceProvider returns the connectivity summary of end devices.
+type EndDevicePresenceProvider interface {
+	GetEndDevicePresence(ctx context.Context, endDeviceIds ...string) (map[string]*domain.EndDevicePresence, error)
 }
 
 // EndDeviceAuthorizer checks permissions for end device operations.
@@ -26,13 +41,17 @@ type EndDeviceAuthorizer interface {
 // EndDeviceHandler implements Connect RPC handlers for end device operations.
 type EndDeviceHandler struct {
 	endDeviceManager EndDeviceManager
+	statusManager    EndDeviceStatusManager
+	presence         EndDevicePresenceProvider
 	authorizer       EndDeviceAuthorizer
 }
 
 // NewEndDeviceHandler creates a new EndDeviceHandler with the provided dependencies.
-func NewEndDeviceHandler(edmgr EndDeviceManager, authorizer EndDeviceAuthorizer) *EndDeviceHandler {
+func NewEndDeviceHandler(edmgr EndDeviceManager, statusmgr EndDeviceStatusManager, presence EndDevicePresenceProvider, authorizer EndDeviceAuthorizer) *EndDeviceHandler {
 	return &EndDeviceHandler{
 		endDeviceManager: edmgr,
+		statusManager:    statusmgr,
+		presence:         presence,
 		authorizer:       authorizer,
 	}
 }
@@ -40,6 +59,11 @@ func NewEndDeviceHandler(edmgr EndDeviceManager, authorizer EndDeviceAuthorizer)
 // CreateEndDevice handles RPC requests to create a new end device.
 // Requires super admin privileges or device creation permission in the organization.
 // Organization ID can be provided in the request or via X-Organization-ID header.
+// An end device profile of the organization supplying defaults can be named in profile_id.
+// Factory-assigned LoRaWAN identifiers can be supplied in lorawan_identity; anything not supplied is allocated
+// or generated.
+// MQTT end devices take their broker credentials in mqtt_config and Modbus end devices their host and register map
+// in modbus_config.
 func (handler *EndDeviceHandler) CreateEndDevice(ctx context.Context, req *connect.Request[iotv1.CreateEndDeviceRequest]) (*connect.Response[iotv1.CreateEndDeviceResponse], error) {
 	ctx, span := telemetry.Tracer().Start(ctx, "CreateEndDevice")
 	defer span.End()
@@ -76,20 +100,46 @@ func (handler *EndDeviceHandler) CreateEndDevice(ctx context.Context, req *conne
 		return nil, connect.NewError(connect.CodePermissionDenied, fmt.Errorf("user %s not authorized to create end devices in organization %s", userId, organization))
 	}
 
-	endDevice, err := handler.endDeviceManager.CreateEndDevice(ctx, req.Msg, organization)
+	metadata := endDeviceMetadataFromCreateRequest(req.Msg)
+
+	endDevice, err := handler.endDeviceManager.CreateEndDevice(ctx, req.Msg, organization, metadata, loRaWANIdentityFromRequest(req.Msg))
 	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidLabel), errors.Is(err, domain.ErrInvalidEUI), errors.Is(err, domain.ErrInvalidRootKey), errors.Is(err, domain.ErrInvalidEndDeviceProfile), errors.Is(err, domain.ErrInvalidMQTTConfig), errors.Is(err, domain.ErrInvalidModbusConfig):
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		case errors.Is(err, domain.ErrEndDeviceProfileNotFound):
+			return nil, connect.NewError(connect.CodeNotFound, err)
+		case errors.Is(err, domain.ErrDeviceEUIInUse), errors.Is(err, domain.ErrMQTTConfigInUse):
+			return nil, connect.NewError(connect.CodeAlreadyExists, err)
+		case errors.Is(err, domain.ErrEUIBlockExhausted):
+			return nil, connect.NewError(connect.CodeResourceExhausted, err)
+		}
 		return nil, err
 	}
 
-	resp := connect.NewResponse(iotv1.CreateEndDeviceResponse_builder{
-		EndDevice: endDevice,
-	}.Build())
+	// A profile adds labels of its own, so return what was stored rather than what was sent
+	if req.Msg.GetProfileId() != "" {
+		metadata, err = handler.endDeviceManager.GetEndDeviceMetadata(ctx, endDevice.GetId(), organization)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	return resp, nil
+	err = setEndDeviceMetadata(endDevice, metadata)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	return connect.NewResponse(iotv1.CreateEndDeviceResponse_builder{
+		EndDevice: endDevice,
+	}.Build()), nil
 }
 
-// EndDevice handles RPC requests to retrieve a single end device.
+// EndDevice handles RPC requests to retrieve a single end device with its complete hardware configuration.
 // Requires super admin privileges or device read permission in the organization.
+// LoRaWAN root keys are redacted unless the caller also has device update permission.
+// The device's connectivity summary is returned in its presence field.
+// With include_status_history set, the device's status transitions are returned as well, most recent first.
 func (handler *EndDeviceHandler) EndDevice(ctx context.Context, req *connect.Request[iotv1.EndDeviceRequest]) (*connect.Response[iotv1.EndDeviceResponse], error) {
 	ctx, span := telemetry.Tracer().Start(ctx, "EndDevice")
 	defer span.End()
@@ -125,11 +175,68 @@ func (handler *EndDeviceHandler) EndDevice(ctx context.Context, req *connect.Req
 		return nil, connect.NewError(connect.CodePermissionDenied, fmt.Errorf("user %s not authorized to read end devices in organization %s", userId, organization))
 	}
 
-	return nil, nil
+	// Root keys are only returned to callers that are allowed to change the device
+	includeKeys := false
+	if domain.IsSuperAdminFromContext(ctx) {
+		includeKeys = true
+	} else {
+		can, err := handler.authorizer.CanUpdateEndDevice(ctx, userId, organization)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("authorization check failed: %w", err))
+		}
+		includeKeys = can
+	}
+
+	endDevice, err := handler.endDeviceManager.GetEndDevice(ctx, req.Msg.GetEndDeviceId(), organization, includeKeys)
+	if err != nil {
+		if errors.Is(err, domain.ErrEndDeviceNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("end device %s not found", req.Msg.GetEndDeviceId()))
+		}
+		return nil, err
+	}
+
+	metadata, err := handler.endDeviceManager.GetEndDeviceMetadata(ctx, endDevice.GetId(), organization)
+	if err != nil {
+		return nil, err
+	}
+
+	presence, err := handler.presence.GetEndDevicePresence(ctx, endDevice.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	err = setEndDeviceMetadata(endDevice, metadata)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	err = setEndDevicePresence(endDevice, presence[endDevice.GetId()])
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	var statusTransitions []*iotv1.EndDeviceStatusTransition
+	if req.Msg.GetIncludeStatusHistory() {
+		statusTransitions, err = handler.endDeviceStatusTransitions(ctx, endDevice.GetId(), organization)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return connect.NewResponse(iotv1.EndDeviceResponse_builder{
+		EndDevice:         endDevice,
+		StatusTransitions: statusTransitions,
+	}.Build()), nil
 }
 
-// OrganizationEndDevices handles RPC requests to list all end devices in an organization.
-// Requires super admin privileges or device read permission in the organization.
+// OrganizationEndDevices handles RPC requests to list the end devices in an organization one page at a time.
+// Paging, filtering and sorting are controlled through the request fields (see endDeviceListRequest),
+// and the token for the following page is returned in next_page_token.
+// The connectivity summary of each device in the page is returned in its presence field.
+// With query set the devices matching it are returned instead, best matches first, and search_organization_ids
+// extends the search to further organizations of the caller; the organization and rank of each match are returned
+// in search_results.
+// Requires super admin privileges or device read permission in every organization searched or listed.
 func (handler *EndDeviceHandler) OrganizationEndDevices(ctx context.Context, req *connect.Request[iotv1.OrganizationEndDevicesRequest]) (*connect.Response[iotv1.OrganizationEndDevicesResponse], error) {
 	ctx, span := telemetry.Tracer().Start(ctx, "OrganizationEndDevices")
 	defer span.End()
@@ -156,9 +263,195 @@ func (handler *EndDeviceHandler) OrganizationEndDevices(ctx context.Context, req
 		return nil, connect.NewError(connect.CodePermissionDenied, fmt.Errorf("user %s not authorized to read end devices in organization %s", userId, organization))
 	}
 
-	resp := connect.NewResponse(iotv1.OrganizationEndDevicesResponse_builder{}.Build())
+	if req.Msg.GetQuery() != "" {
+		return handler.searchEndDevices(ctx, userId, req.Msg)
+	}
+
+	listReq, err := endDeviceListRequest(req.Msg)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
 
-	return resp, nil
+	page, err := handler.endDeviceManager.ListEndDevices(ctx, listReq)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidPageToken) || errors.Is(err, domain.ErrInvalidMessageFormat) {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+		return nil, err
+	}
+
+	endDeviceIds := make([]string, 0, len(page.EndDevices))
+	for _, endDevice := range page.EndDevices {
+		endDeviceIds = append(endDeviceIds, endDevice.GetId())
+	}
+
+	presence, err := handler.presence.GetEndDevicePresence(ctx, endDeviceIds...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setEndDevicePresences(page.EndDevices, presence)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	return connect.NewResponse(iotv1.OrganizationEndDevicesResponse_builder{
+		EndDevices:    page.EndDevices,
+		NextPageToken: page.NextPageToken,
+	}.Build()), nil
+}
+
+// UpdateEndDevice handles RPC requests to change an end device's name, description, labels, attributes and, for
+// LoRaWAN devices, its frequency plan and hardware type (see endDeviceUpdateFromRequest).
+// Requires super admin privileges or device update permission in the organization.
+func (handler *EndDeviceHandler) UpdateEndDevice(ctx context.Context, req *connect.Request[iotv1.UpdateEndDeviceRequest]) (*connect.Response[iotv1.UpdateEndDeviceResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "UpdateEndDevice")
+	defer span.End()
+
+	userId, ok := domain.GetUserFromContext(ctx)
+	if !ok {
+		return nil, connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("user not authenticated"))
+	}
+
+	organization := req.Msg.GetOrganizationId()
+
+	allowed := false
+	if domain.IsSuperAdminFromContext(ctx) {
+		allowed = true
+	} else {
+		can, err := handler.authorizer.CanUpdateEndDevice(ctx, userId, organization)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("authorization check failed: %w", err))
+		}
+		allowed = can
+	}
+
+	if !allowed {
+		return nil, connect.NewError(connect.CodePermissionDenied, fmt.Errorf("user %s not authorized to update end devices in organization %s", userId, organization))
+	}
+
+	update, metadataUpdate, err := endDeviceUpdateFromRequest(req.Msg)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	endDevice, metadata, err := handler.endDeviceManager.UpdateEndDevice(ctx, update, organization, metadataUpdate)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrEndDeviceNotFound):
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("end device %s not found", req.Msg.GetEndDeviceId()))
+		case errors.Is(err, domain.ErrInvalidMessageFormat), errors.Is(err, domain.ErrInvalidLabel):
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+		return nil, err
+	}
+
+	err = setEndDeviceMetadata(endDevice, metadata)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	return connect.NewResponse(iotv1.UpdateEndDeviceResponse_builder{
+		EndDevice: endDevice,
+	}.Build()), nil
+}
+
+// endDeviceUpdateFields are the update_mask paths an UpdateEndDevice request accepts.
+var endDeviceUpdateFields = map[string]bool{
+	"name":             true,
+	"description":      true,
+	"frequency_plan":   true,
+	"hardware_type_id": true,
+	"labels":           true,
+	"attributes":       true,
+}
+
+// endDeviceUpdateFromRequest splits an UpdateEndDevice request into the changes to the device and to its labels
+// and attributes. Without an update_mask every non-empty field is applied. With one, only the fields it names are
+// considered, and naming labels or attributes replaces them even when empty, which clears them; the other fields
+// cannot be cleared and are left unchanged when empty.
+func endDeviceUpdateFromRequest(req *iotv1.UpdateEndDeviceRequest) (*iotv1.EndDevice, domain.EndDeviceMetadata, error) {
+	paths := req.GetUpdateMask().GetPaths()
+	masked := func(string) bool { return true }
+	if len(paths) > 0 {
+		for _, path := range paths {
+			if !endDeviceUpdateFields[path] {
+				return nil, domain.EndDeviceMetadata{}, fmt.Errorf("invalid update_mask: unsupported field %q", path)
+			}
+		}
+		masked = func(field string) bool { return slices.Contains(paths, field) }
+	}
+
+	update := iotv1.EndDevice_builder{Id: req.GetEndDeviceId()}
+	lorawanUpdate := iotv1.LoRaWANConfig_builder{}
+	if masked("name") {
+		update.Name = req.GetName()
+	}
+	if masked("description") {
+		update.Description = req.GetDescription()
+	}
+	if masked("frequency_plan") {
+		lorawanUpdate.FrequencyPlan = req.GetFrequencyPlan()
+	}
+	if masked("hardware_type_id") {
+		lorawanUpdate.HardwareData = iotv1.LoRaWANHardwareData_builder{
+			HardwareTypeId: req.GetHardwareTypeId(),
+		}.Build()
+	}
+	update.LorawanConfig = lorawanUpdate.Build()
+
+	metadata := domain.EndDeviceMetadata{}
+	hasMask := len(paths) > 0
+	if masked("labels") && (hasMask || len(req.GetLabels()) > 0) {
+		metadata.Labels = domain.Labels(maps.Clone(req.GetLabels()))
+		if metadata.Labels == nil {
+			metadata.Labels = domain.Labels{}
+		}
+	}
+	if masked("attributes") && (hasMask || req.HasAttributes()) {
+		metadata.Attributes = req.GetAttributes().AsMap()
+	}
+
+	return update.Build(), metadata, nil
+}
+
+// DeleteEndDevice handles RPC requests to delete an end device, removing LoRaWAN devices from TTN as well.
+// Requires super admin privileges or device delete permission in the organization.
+func (handler *EndDeviceHandler) DeleteEndDevice(ctx context.Context, req *connect.Request[iotv1.DeleteEndDeviceRequest]) (*connect.Response[iotv1.DeleteEndDeviceResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "DeleteEndDevice")
+	defer span.End()
+
+	userId, ok := domain.GetUserFromContext(ctx)
+	if !ok {
+		return nil, connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("user not authenticated"))
+	}
+
+	organization := req.Msg.GetOrganizationId()
+
+	allowed := false
+	if domain.IsSuperAdminFromContext(ctx) {
+		allowed = true
+	} else {
+		can, err := handler.authorizer.CanDeleteEndDevice(ctx, userId, organization)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("authorization check failed: %w", err))
+		}
+		allowed = can
+	}
+
+	if !allowed {
+		return nil, connect.NewError(connect.CodePermissionDenied, fmt.Errorf("user %s not authorized to delete end devices in organization %s", userId, organization))
+	}
+
+	err := handler.endDeviceManager.DeleteEndDevice(ctx, req.Msg.GetEndDeviceId(), organization)
+	if err != nil {
+		if errors.Is(err, domain.ErrEndDeviceNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("end device %s not found", req.Msg.GetEndDeviceId()))
+		}
+		return nil, err
+	}
+
+	return connect.NewResponse(iotv1.DeleteEndDeviceResponse_builder{}.Build()), nil
 }
 
 // EndDeviceData handles RPC requests to retrieve time-series data for an end device.
@@ -0,0 +1,121 @@
+package connectrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"connectrpc.com/connect"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// EndDeviceClaimManager handles issuing and redeeming end device claim codes.
+type EndDeviceClaimManager interface {
+	IssueClaimCode(ctx context.Context, endDeviceId string, organizationId string, ttl time.Duration) (string, *domain.EndDeviceClaim, error)
+	ClaimEndDevice(ctx context.Context, code string, organizationId string, userId string) (*domain.EndDeviceClaim, error)
+}
+
+// EndDeviceClaimAuthorizer checks permissions for end device claim operations.
+type EndDeviceClaimAuthorizer interface {
+	CanIssueClaimCode(ctx context.Context, userId string, organizationId string) (bool, error)
+	CanClaimEndDevice(ctx context.Context, userId string, organizationId string) (bool, error)
+}
+
+// EndDeviceClaimHandler implements Connect RPC handlers for end device claim operations.
+type EndDeviceClaimHandler struct {
+	claimManager EndDeviceClaimManager
+	authorizer   EndDeviceClaimAuthorizer
+}
+
+// NewEndDeviceClaimHandler creates a new EndDeviceClaimHandler with the provided dependencies.
+func NewEndDeviceClaimHandler(claimMgr EndDeviceClaimManager, authorizer EndDeviceClaimAuthorizer) *EndDeviceClaimHandler {
+	return &EndDeviceClaimHandler{
+		claimManager: claimMgr,
+		authorizer:   authorizer,
+	}
+}
+
+// IssueClaimCode handles RPC requests to issue a one-time claim code for a pre-provisioned end device, replacing
+// any earlier code. The code is only returned here, for example to be printed as a QR code on the device's box.
+// Requires super admin privileges or a manufacturer account with device creation permission in the organization.
+func (handler *EndDeviceClaimHandler) IssueClaimCode(ctx context.Context, req *connect.Request[iotv1.IssueClaimCodeRequest]) (*connect.Response[iotv1.IssueClaimCodeResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "IssueClaimCode")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanIssueClaimCode, "issue claim codes")
+	if err != nil {
+		return nil, err
+	}
+
+	code, claim, err := handler.claimManager.IssueClaimCode(ctx, req.Msg.GetEndDeviceId(), req.Msg.GetOrganizationId(), req.Msg.GetTtl().AsDuration())
+	if err != nil {
+		return nil, endDeviceClaimError(err)
+	}
+
+	return connect.NewResponse(iotv1.IssueClaimCodeResponse_builder{
+		Code:  code,
+		Claim: endDeviceClaimToProto(claim),
+	}.Build()), nil
+}
+
+// ClaimEndDevice handles RPC requests to claim the end device a code was issued for into the caller's organization.
+// The device's LoRaWAN root keys are replaced and it is no longer visible to its previous owner.
+// Requires super admin privileges or device creation permission in the claiming organization.
+func (handler *EndDeviceClaimHandler) ClaimEndDevice(ctx context.Context, req *connect.Request[iotv1.ClaimEndDeviceRequest]) (*connect.Response[iotv1.ClaimEndDeviceResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ClaimEndDevice")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanClaimEndDevice, "claim end devices")
+	if err != nil {
+		return nil, err
+	}
+
+	// authorize has already rejected requests without a user
+	userId, _ := domain.GetUserFromContext(ctx)
+
+	claim, err := handler.claimManager.ClaimEndDevice(ctx, req.Msg.GetCode(), req.Msg.GetOrganizationId(), userId)
+	if err != nil {
+		return nil, endDeviceClaimError(err)
+	}
+
+	return connect.NewResponse(iotv1.ClaimEndDeviceResponse_builder{
+		Claim: endDeviceClaimToProto(claim),
+	}.Build()), nil
+}
+
+// endDeviceClaimToProto converts an end device claim to its iot/v1 message.
+func endDeviceClaimToProto(claim *domain.EndDeviceClaim) *iotv1.EndDeviceClaim {
+	message := iotv1.EndDeviceClaim_builder{
+		EndDeviceId:             claim.EndDeviceId,
+		OrganizationId:          claim.OrganizationId,
+		ExpiresAt:               timestamppb.New(claim.ExpiresAt),
+		CreatedAt:               timestamppb.New(claim.CreatedAt),
+		ClaimedByOrganizationId: claim.ClaimedByOrganizationId,
+	}.Build()
+
+	if claim.Claimed() {
+		message.SetClaimedAt(timestamppb.New(claim.ClaimedAt))
+	}
+
+	return message
+}
+
+// endDeviceClaimError maps the errors of end device claim operations to Connect errors.
+func endDeviceClaimError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrEndDeviceNotFound):
+		return connect.NewError(connect.CodeNotFound, err)
+	case errors.Is(err, domain.ErrInvalidClaimCode), errors.Is(err, domain.ErrInvalidClaimCodeTTL):
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	case errors.Is(err, domain.ErrClaimRateLimited):
+		return connect.NewError(connect.CodeResourceExhausted, err)
+	case errors.Is(err, domain.ErrEndDeviceDisabled):
+		return connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("claim failed: %w", err))
+	default:
+		return err
+	}
+}
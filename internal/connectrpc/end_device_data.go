@@ -2,6 +2,7 @@ package connectrpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
@@ -12,7 +13,7 @@ import (
 
 // EndDeviceDataManager handles end device data query operations.
 type EndDeviceDataManager interface {
-	QueryEndDeviceData(ctx context.Context, req *iotv1.QueryEndDeviceDataRequest) (*iotv1.QueryEndDeviceDataResponse, error)
+	QueryEndDeviceData(ctx context.Context, req *iotv1.QueryEndDeviceDataRequest, targets domain.EndDeviceTargets) (*iotv1.QueryEndDeviceDataResponse, error)
 }
 
 // EndDeviceDataHandler implements Connect RPC handlers for end device data operations.
@@ -31,6 +32,8 @@ func NewEndDeviceDataHandler(edDataMgr EndDeviceDataManager, authorizer EndDevic
 
 // QueryEndDeviceData handles RPC requests to query time-series sensor data.
 // Returns Prometheus-style histogram data for visualization.
+// Device groups in device_group_ids are queried along with end_device_ids, and devices can alternatively be
+// selected with label_selector.
 // Requires super admin privileges or device read permission in the organization.
 func (handler *EndDeviceDataHandler) QueryEndDeviceData(
 	ctx context.Context,
@@ -70,9 +73,20 @@ func (handler *EndDeviceDataHandler) QueryEndDeviceData(
 		)
 	}
 
+	targets, err := endDeviceTargets(req.Msg.GetLabelSelector(), req.Msg.GetDeviceGroupIds())
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
 	// Query sensor data
-	response, err := handler.endDeviceDataManager.QueryEndDeviceData(ctx, req.Msg)
+	response, err := handler.endDeviceDataManager.QueryEndDeviceData(ctx, req.Msg, targets)
 	if err != nil {
+		if errors.Is(err, domain.ErrInvalidMessageFormat) {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+		if errors.Is(err, domain.ErrDeviceGroupNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, err)
+		}
 		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to query sensor data: %w", err))
 	}
 
@@ -0,0 +1,175 @@
+package connectrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"connectrpc.com/connect"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// endDeviceDataPolicies maps the data policies of the iot/v1 API to those of end device decommissions. Devices
+// keep their data for the retention period unless a purge is asked for.
+var endDeviceDataPolicies = map[iotv1.EndDeviceDataPolicy]domain.EndDeviceDataPolicy{
+	iotv1.EndDeviceDataPolicy_END_DEVICE_DATA_POLICY_UNSPECIFIED: domain.EndDeviceDataRetain,
+	iotv1.EndDeviceDataPolicy_END_DEVICE_DATA_POLICY_RETAIN:      domain.EndDeviceDataRetain,
+	iotv1.EndDeviceDataPolicy_END_DEVICE_DATA_POLICY_PURGE:       domain.EndDeviceDataPurge,
+}
+
+// endDeviceDecommissionSteps maps the steps of end device decommissions to those of the iot/v1 API.
+var endDeviceDecommissionSteps = map[domain.EndDeviceDecommissionStep]iotv1.EndDeviceDecommissionStep{
+	domain.DecommissionStepRequested:          iotv1.EndDeviceDecommissionStep_END_DEVICE_DECOMMISSION_STEP_REQUESTED,
+	domain.DecommissionStepDisabled:           iotv1.EndDeviceDecommissionStep_END_DEVICE_DECOMMISSION_STEP_DISABLED,
+	domain.DecommissionStepDeregistered:       iotv1.EndDeviceDecommissionStep_END_DEVICE_DECOMMISSION_STEP_DEREGISTERED,
+	domain.DecommissionStepCredentialsRevoked: iotv1.EndDeviceDecommissionStep_END_DEVICE_DECOMMISSION_STEP_CREDENTIALS_REVOKED,
+	domain.DecommissionStepRetainingData:      iotv1.EndDeviceDecommissionStep_END_DEVICE_DECOMMISSION_STEP_RETAINING_DATA,
+	domain.DecommissionStepPurgingData:        iotv1.EndDeviceDecommissionStep_END_DEVICE_DECOMMISSION_STEP_PURGING_DATA,
+	domain.DecommissionStepCompleted:          iotv1.EndDeviceDecommissionStep_END_DEVICE_DECOMMISSION_STEP_COMPLETED,
+}
+
+// EndDeviceDecommissionManager handles the decommission of end devices that left service.
+type EndDeviceDecommissionManager interface {
+	StartEndDeviceDecommission(ctx context.Context, req domain.EndDeviceDecommissionRequest) (*domain.EndDeviceDecommission, error)
+	GetEndDeviceDecommission(ctx context.Context, endDeviceId string, organizationId string) (*domain.EndDeviceDecommission, []*domain.EndDeviceDecommissionEvent, error)
+}
+
+// EndDeviceDecommissionAuthorizer checks permissions for end device decommission operations. Decommissioning
+// removes a device and its data, so it needs the end device delete permission.
+type EndDeviceDecommissionAuthorizer interface {
+	CanReadEndDevice(ctx context.Context, userId string, organizationId string) (bool, error)
+	CanDeleteEndDevice(ctx context.Context, userId string, organizationId string) (bool, error)
+}
+
+// EndDeviceDecommissionHandler implements Connect RPC handlers for end device decommission operations.
+type EndDeviceDecommissionHandler struct {
+	decommissionManager EndDeviceDecommissionManager
+	authorizer          EndDeviceDecommissionAuthorizer
+}
+
+// NewEndDeviceDecommissionHandler creates a new EndDeviceDecommissionHandler with the provided dependencies.
+func NewEndDeviceDecommissionHandler(decommissionMgr EndDeviceDecommissionManager, authorizer EndDeviceDecommissionAuthorizer) *EndDeviceDecommissionHandler {
+	return &EndDeviceDecommissionHandler{
+		decommissionManager: decommissionMgr,
+		authorizer:          authorizer,
+	}
+}
+
+// DecommissionEndDevice handles RPC requests to decommission an end device: it is disabled, removed from TTN and
+// its credentials are revoked. Its data is kept for the retention period and purged afterwards, or purged right
+// away under the purge data policy. Steps that fail or have to wait are finished by the decommission runner, and
+// decommissioning a device again resumes its existing decommission.
+// Requires super admin privileges or device delete permission in the device's organization.
+func (handler *EndDeviceDecommissionHandler) DecommissionEndDevice(ctx context.Context, req *connect.Request[iotv1.DecommissionEndDeviceRequest]) (*connect.Response[iotv1.DecommissionEndDeviceResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "DecommissionEndDevice")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanDeleteEndDevice, "delete end devices")
+	if err != nil {
+		return nil, err
+	}
+
+	dataPolicy, ok := endDeviceDataPolicies[req.Msg.GetDataPolicy()]
+	if !ok {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("unsupported data policy %v", req.Msg.GetDataPolicy()))
+	}
+
+	// authorize has already rejected requests without a user
+	userId, _ := domain.GetUserFromContext(ctx)
+
+	decommission, err := handler.decommissionManager.StartEndDeviceDecommission(ctx, domain.EndDeviceDecommissionRequest{
+		EndDeviceId:    req.Msg.GetEndDeviceId(),
+		OrganizationId: req.Msg.GetOrganizationId(),
+		RequestedBy:    userId,
+		DataPolicy:     dataPolicy,
+		Retention:      req.Msg.GetRetention().AsDuration(),
+	})
+	if err != nil {
+		return nil, endDeviceDecommissionError(err, req.Msg.GetEndDeviceId())
+	}
+
+	return connect.NewResponse(iotv1.DecommissionEndDeviceResponse_builder{
+		Decommission: endDeviceDecommissionToProto(decommission),
+	}.Build()), nil
+}
+
+// EndDeviceDecommission handles RPC requests to retrieve the current step of an end device's decommission
+// together with its audit trail, oldest event first.
+// Requires super admin privileges or device read permission in the device's organization.
+func (handler *EndDeviceDecommissionHandler) EndDeviceDecommission(ctx context.Context, req *connect.Request[iotv1.EndDeviceDecommissionRequest]) (*connect.Response[iotv1.EndDeviceDecommissionResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "EndDeviceDecommission")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanReadEndDevice, "read end devices")
+	if err != nil {
+		return nil, err
+	}
+
+	decommission, events, err := handler.decommissionManager.GetEndDeviceDecommission(ctx, req.Msg.GetEndDeviceId(), req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, endDeviceDecommissionError(err, req.Msg.GetEndDeviceId())
+	}
+
+	messages := make([]*iotv1.EndDeviceDecommissionEvent, 0, len(events))
+	for _, event := range events {
+		messages = append(messages, iotv1.EndDeviceDecommissionEvent_builder{
+			Step:       endDeviceDecommissionSteps[event.Step],
+			Error:      event.Error,
+			OccurredAt: timestamppb.New(event.OccurredAt),
+		}.Build())
+	}
+
+	return connect.NewResponse(iotv1.EndDeviceDecommissionResponse_builder{
+		Decommission: endDeviceDecommissionToProto(decommission),
+		Events:       messages,
+	}.Build()), nil
+}
+
+// endDeviceDecommissionToProto converts an end device decommission to its iot/v1 message.
+func endDeviceDecommissionToProto(decommission *domain.EndDeviceDecommission) *iotv1.EndDeviceDecommission {
+	dataPolicy := iotv1.EndDeviceDataPolicy_END_DEVICE_DATA_POLICY_RETAIN
+	if decommission.DataPolicy == domain.EndDeviceDataPurge {
+		dataPolicy = iotv1.EndDeviceDataPolicy_END_DEVICE_DATA_POLICY_PURGE
+	}
+
+	message := iotv1.EndDeviceDecommission_builder{
+		Id:             decommission.Id,
+		EndDeviceId:    decommission.EndDeviceId,
+		OrganizationId: decommission.OrganizationId,
+		RequestedBy:    decommission.RequestedBy,
+		DataPolicy:     dataPolicy,
+		Step:           endDeviceDecommissionSteps[decommission.Step],
+		PurgeAfter:     timestamppb.New(decommission.PurgeAfter),
+		Attempts:       int32(decommission.Attempts),
+		LastError:      decommission.LastError,
+		CreatedAt:      timestamppb.New(decommission.CreatedAt),
+		UpdatedAt:      timestamppb.New(decommission.UpdatedAt),
+	}.Build()
+
+	if decommission.Completed() {
+		message.SetCompletedAt(timestamppb.New(decommission.CompletedAt))
+	} else {
+		message.SetNextAttemptAt(timestamppb.New(decommission.NextAttemptAt))
+	}
+
+	return message
+}
+
+// endDeviceDecommissionError maps the errors of end device decommission operations to Connect errors.
+func endDeviceDecommissionError(err error, endDeviceId string) error {
+	switch {
+	case errors.Is(err, domain.ErrEndDeviceNotFound):
+		return connect.NewError(connect.CodeNotFound, fmt.Errorf("end device %s not found", endDeviceId))
+	case errors.Is(err, domain.ErrEndDeviceDecommissionNotFound):
+		return connect.NewError(connect.CodeNotFound, fmt.Errorf("end device %s has not been decommissioned", endDeviceId))
+	case errors.Is(err, domain.ErrInvalidEndDeviceDataPolicy):
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	case errors.Is(err, domain.ErrEndDeviceDecommissionConflict):
+		return connect.NewError(connect.CodeAborted, err)
+	default:
+		return err
+	}
+}
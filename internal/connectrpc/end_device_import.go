@@ -0,0 +1,74 @@
+package connectrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"connectrpc.com/connect"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+)
+
+// endDeviceImportFormats maps the import formats of the iot/v1 API to the formats of end device import files.
+var endDeviceImportFormats = map[iotv1.EndDeviceImportFormat]domain.EndDeviceImportFormat{
+	iotv1.EndDeviceImportFormat_END_DEVICE_IMPORT_FORMAT_CSV:    domain.EndDeviceImportCSV,
+	iotv1.EndDeviceImportFormat_END_DEVICE_IMPORT_FORMAT_NDJSON: domain.EndDeviceImportNDJSON,
+}
+
+// ImportEndDevices handles RPC requests to create end devices in bulk from a CSV or NDJSON file.
+// Every row is validated before anything is created and the response reports the outcome of each row; with
+// dry_run set the rows are only validated.
+// Requires super admin privileges or device creation permission in the organization.
+func (handler *EndDeviceHandler) ImportEndDevices(ctx context.Context, req *connect.Request[iotv1.ImportEndDevicesRequest]) (*connect.Response[iotv1.ImportEndDevicesResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ImportEndDevices")
+	defer span.End()
+
+	organization := req.Msg.GetOrganizationId()
+
+	err := authorize(ctx, organization, handler.authorizer.CanCreateEndDevice, "create end devices")
+	if err != nil {
+		return nil, err
+	}
+
+	format, ok := endDeviceImportFormats[req.Msg.GetFormat()]
+	if !ok {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("unsupported import format %v", req.Msg.GetFormat()))
+	}
+
+	rows, err := domain.ParseEndDeviceImport(bytes.NewReader(req.Msg.GetFile()), format)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidImportFile) {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+		return nil, err
+	}
+
+	report, err := handler.endDeviceManager.ImportEndDevices(ctx, organization, rows, domain.EndDeviceImportOptions{
+		DryRun:    req.Msg.GetDryRun(),
+		BatchSize: int(req.Msg.GetBatchSize()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*iotv1.EndDeviceImportResult, 0, len(report.Results))
+	for _, result := range report.Results {
+		results = append(results, iotv1.EndDeviceImportResult_builder{
+			Line:        int32(result.Line),
+			Name:        result.Name,
+			EndDeviceId: result.EndDeviceId,
+			Error:       result.Error,
+		}.Build())
+	}
+
+	return connect.NewResponse(iotv1.ImportEndDevicesResponse_builder{
+		DryRun:  report.DryRun,
+		Valid:   int32(report.Valid),
+		Created: int32(report.Created),
+		Failed:  int32(report.Failed),
+		Results: results,
+	}.Build()), nil
+}
@@ -0,0 +1,325 @@
+package connectrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"connectrpc.com/connect"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// EndDeviceProfileManager handles end device profile business operations.
+type EndDeviceProfileManager interface {
+	CreateEndDeviceProfile(ctx context.Context, organizationId string, profile *domain.EndDeviceProfile) (*domain.EndDeviceProfile, error)
+	GetEndDeviceProfile(ctx context.Context, profileId string, organizationId string) (*domain.EndDeviceProfile, error)
+	ListEndDeviceProfiles(ctx context.Context, organizationId string) ([]*domain.EndDeviceProfile, error)
+	PreviewEndDeviceProfileUpdate(ctx context.Context, update *domain.EndDeviceProfile, organizationId string) ([]domain.EndDeviceProfileChange, error)
+	UpdateEndDeviceProfile(ctx context.Context, update *domain.EndDeviceProfile, organizationId string, propagate bool) (*domain.EndDeviceProfile, []domain.EndDeviceProfileChange, error)
+	DeleteEndDeviceProfile(ctx context.Context, profileId string, organizationId string) error
+}
+
+// EndDeviceProfileAuthorizer checks permissions for end device profile operations.
+type EndDeviceProfileAuthorizer interface {
+	CanCreateEndDeviceProfile(ctx context.Context, userId string, organizationId string) (bool, error)
+	CanReadEndDeviceProfile(ctx context.Context, userId string, organizationId string) (bool, error)
+	CanUpdateEndDeviceProfile(ctx context.Context, userId string, organizationId string) (bool, error)
+	CanDeleteEndDeviceProfile(ctx context.Context, userId string, organizationId string) (bool, error)
+}
+
+// EndDeviceProfileHandler implements Connect RPC handlers for end device profile operations.
+type EndDeviceProfileHandler struct {
+	profileManager EndDeviceProfileManager
+	authorizer     EndDeviceProfileAuthorizer
+}
+
+// NewEndDeviceProfileHandler creates a new EndDeviceProfileHandler with the provided dependencies.
+func NewEndDeviceProfileHandler(profileMgr EndDeviceProfileManager, authorizer EndDeviceProfileAuthorizer) *EndDeviceProfileHandler {
+	return &EndDeviceProfileHandler{
+		profileManager: profileMgr,
+		authorizer:     authorizer,
+	}
+}
+
+// CreateEndDeviceProfile handles RPC requests to create an end device profile in an organization. Devices are
+// created from it by naming it in the profile_id of CreateEndDevice.
+// Requires super admin privileges or profile creation permission in the organization.
+func (handler *EndDeviceProfileHandler) CreateEndDeviceProfile(ctx context.Context, req *connect.Request[iotv1.CreateEndDeviceProfileRequest]) (*connect.Response[iotv1.CreateEndDeviceProfileResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "CreateEndDeviceProfile")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanCreateEndDeviceProfile, "create end device profiles")
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := handler.profileManager.CreateEndDeviceProfile(ctx, req.Msg.GetOrganizationId(), &domain.EndDeviceProfile{
+		Name:           req.Msg.GetName(),
+		Description:    req.Msg.GetDescription(),
+		HardwareType:   req.Msg.GetHardwareType(),
+		HardwareTypeId: req.Msg.GetHardwareTypeId(),
+		FrequencyPlan:  req.Msg.GetFrequencyPlan(),
+		PayloadDecoder: req.Msg.GetPayloadDecoder(),
+		ExpectedFields: req.Msg.GetExpectedFields(),
+		Labels:         domain.Labels(req.Msg.GetLabels()),
+	})
+	if err != nil {
+		return nil, endDeviceProfileError(err)
+	}
+
+	return connect.NewResponse(iotv1.CreateEndDeviceProfileResponse_builder{
+		EndDeviceProfile: endDeviceProfileToProto(profile),
+	}.Build()), nil
+}
+
+// EndDeviceProfile handles RPC requests to retrieve a single end device profile.
+// Requires super admin privileges or profile read permission in the organization.
+func (handler *EndDeviceProfileHandler) EndDeviceProfile(ctx context.Context, req *connect.Request[iotv1.EndDeviceProfileRequest]) (*connect.Response[iotv1.EndDeviceProfileResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "EndDeviceProfile")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanReadEndDeviceProfile, "read end device profiles")
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := handler.profileManager.GetEndDeviceProfile(ctx, req.Msg.GetEndDeviceProfileId(), req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, endDeviceProfileError(err)
+	}
+
+	return connect.NewResponse(iotv1.EndDeviceProfileResponse_builder{
+		EndDeviceProfile: endDeviceProfileToProto(profile),
+	}.Build()), nil
+}
+
+// OrganizationEndDeviceProfiles handles RPC requests to list the end device profiles of an organization by name.
+// Requires super admin privileges or profile read permission in the organization.
+func (handler *EndDeviceProfileHandler) OrganizationEndDeviceProfiles(ctx context.Context, req *connect.Request[iotv1.OrganizationEndDeviceProfilesRequest]) (*connect.Response[iotv1.OrganizationEndDeviceProfilesResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "OrganizationEndDeviceProfiles")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanReadEndDeviceProfile, "read end device profiles")
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := handler.profileManager.ListEndDeviceProfiles(ctx, req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, endDeviceProfileError(err)
+	}
+
+	messages := make([]*iotv1.EndDeviceProfile, 0, len(profiles))
+	for _, profile := range profiles {
+		messages = append(messages, endDeviceProfileToProto(profile))
+	}
+
+	return connect.NewResponse(iotv1.OrganizationEndDeviceProfilesResponse_builder{
+		EndDeviceProfiles: messages,
+	}.Build()), nil
+}
+
+// UpdateEndDeviceProfile handles RPC requests to change an end device profile (see endDeviceProfileUpdate). New
+// devices pick up the change right away; with propagate set, existing devices created from the profile are updated
+// too and the changes applied to them are returned.
+// Requires super admin privileges or profile update permission in the organization.
+func (handler *EndDeviceProfileHandler) UpdateEndDeviceProfile(ctx context.Context, req *connect.Request[iotv1.UpdateEndDeviceProfileRequest]) (*connect.Response[iotv1.UpdateEndDeviceProfileResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "UpdateEndDeviceProfile")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanUpdateEndDeviceProfile, "update end device profiles")
+	if err != nil {
+		return nil, err
+	}
+
+	update, err := handler.endDeviceProfileUpdate(ctx, req.Msg)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, changes, err := handler.profileManager.UpdateEndDeviceProfile(ctx, update, req.Msg.GetOrganizationId(), req.Msg.GetPropagate())
+	if err != nil {
+		return nil, endDeviceProfileError(err)
+	}
+
+	return connect.NewResponse(iotv1.UpdateEndDeviceProfileResponse_builder{
+		EndDeviceProfile: endDeviceProfileToProto(profile),
+		Changes:          endDeviceProfileChangesToProto(changes),
+	}.Build()), nil
+}
+
+// PreviewEndDeviceProfileUpdate handles RPC requests to report which devices created from a profile an update would
+// change, and how, without saving anything. It takes the same fields as UpdateEndDeviceProfile.
+// Requires super admin privileges or profile update permission in the organization.
+func (handler *EndDeviceProfileHandler) PreviewEndDeviceProfileUpdate(ctx context.Context, req *connect.Request[iotv1.PreviewEndDeviceProfileUpdateRequest]) (*connect.Response[iotv1.PreviewEndDeviceProfileUpdateResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "PreviewEndDeviceProfileUpdate")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanUpdateEndDeviceProfile, "update end device profiles")
+	if err != nil {
+		return nil, err
+	}
+
+	update, err := handler.endDeviceProfileUpdate(ctx, req.Msg)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := handler.profileManager.PreviewEndDeviceProfileUpdate(ctx, update, req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, endDeviceProfileError(err)
+	}
+
+	return connect.NewResponse(iotv1.PreviewEndDeviceProfileUpdateResponse_builder{
+		Changes: endDeviceProfileChangesToProto(changes),
+	}.Build()), nil
+}
+
+// DeleteEndDeviceProfile handles RPC requests to delete an end device profile. Devices created from it keep their
+// settings.
+// Requires super admin privileges or profile delete permission in the organization.
+func (handler *EndDeviceProfileHandler) DeleteEndDeviceProfile(ctx context.Context, req *connect.Request[iotv1.DeleteEndDeviceProfileRequest]) (*connect.Response[iotv1.DeleteEndDeviceProfileResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "DeleteEndDeviceProfile")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanDeleteEndDeviceProfile, "delete end device profiles")
+	if err != nil {
+		return nil, err
+	}
+
+	err = handler.profileManager.DeleteEndDeviceProfile(ctx, req.Msg.GetEndDeviceProfileId(), req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, endDeviceProfileError(err)
+	}
+
+	return connect.NewResponse(iotv1.DeleteEndDeviceProfileResponse_builder{}.Build()), nil
+}
+
+// endDeviceProfileUpdateRequest holds the fields shared by UpdateEndDeviceProfile and PreviewEndDeviceProfileUpdate
+// requests.
+type endDeviceProfileUpdateRequest interface {
+	GetEndDeviceProfileId() string
+	GetOrganizationId() string
+	GetName() string
+	GetDescription() string
+	GetHardwareTypeId() string
+	GetFrequencyPlan() string
+	GetPayloadDecoder() string
+	GetExpectedFields() []string
+	GetLabels() map[string]string
+	GetUpdateMask() *fieldmaskpb.FieldMask
+}
+
+// endDeviceProfileUpdateFields are the update_mask paths an end device profile update accepts.
+var endDeviceProfileUpdateFields = map[string]bool{
+	"name":             true,
+	"description":      true,
+	"hardware_type_id": true,
+	"frequency_plan":   true,
+	"payload_decoder":  true,
+	"expected_fields":  true,
+	"labels":           true,
+}
+
+// endDeviceProfileUpdate applies an update request to the current state of the profile it names. Without an
+// update_mask every non-empty field is applied. With one, only the fields it names are applied, even when empty,
+// which clears them.
+func (handler *EndDeviceProfileHandler) endDeviceProfileUpdate(ctx context.Context, req endDeviceProfileUpdateRequest) (*domain.EndDeviceProfile, error) {
+	paths := req.GetUpdateMask().GetPaths()
+	for _, path := range paths {
+		if !endDeviceProfileUpdateFields[path] {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid update_mask: unsupported field %q", path))
+		}
+	}
+
+	applies := func(field string, empty bool) bool {
+		if len(paths) > 0 {
+			return slices.Contains(paths, field)
+		}
+		return !empty
+	}
+
+	update, err := handler.profileManager.GetEndDeviceProfile(ctx, req.GetEndDeviceProfileId(), req.GetOrganizationId())
+	if err != nil {
+		return nil, endDeviceProfileError(err)
+	}
+
+	if applies("name", req.GetName() == "") {
+		update.Name = req.GetName()
+	}
+	if applies("description", req.GetDescription() == "") {
+		update.Description = req.GetDescription()
+	}
+	if applies("hardware_type_id", req.GetHardwareTypeId() == "") {
+		update.HardwareTypeId = req.GetHardwareTypeId()
+	}
+	if applies("frequency_plan", req.GetFrequencyPlan() == "") {
+		update.FrequencyPlan = req.GetFrequencyPlan()
+	}
+	if applies("payload_decoder", req.GetPayloadDecoder() == "") {
+		update.PayloadDecoder = req.GetPayloadDecoder()
+	}
+	if applies("expected_fields", len(req.GetExpectedFields()) == 0) {
+		update.ExpectedFields = slices.Clone(req.GetExpectedFields())
+	}
+	if applies("labels", len(req.GetLabels()) == 0) {
+		update.Labels = domain.Labels(maps.Clone(req.GetLabels()))
+	}
+
+	return update, nil
+}
+
+// endDeviceProfileToProto converts an end device profile to its iot/v1 message.
+func endDeviceProfileToProto(profile *domain.EndDeviceProfile) *iotv1.EndDeviceProfile {
+	return iotv1.EndDeviceProfile_builder{
+		Id:             profile.Id,
+		OrganizationId: profile.OrganizationId,
+		Name:           profile.Name,
+		Description:    profile.Description,
+		HardwareType:   profile.HardwareType,
+		HardwareTypeId: profile.HardwareTypeId,
+		FrequencyPlan:  profile.FrequencyPlan,
+		PayloadDecoder: profile.PayloadDecoder,
+		ExpectedFields: profile.ExpectedFields,
+		Labels:         profile.Labels,
+		CreatedAt:      timestamppb.New(profile.CreatedAt),
+		UpdatedAt:      timestamppb.New(profile.UpdatedAt),
+	}.Build()
+}
+
+// endDeviceProfileChangesToProto converts the changes of a profile update to their iot/v1 messages.
+func endDeviceProfileChangesToProto(changes []domain.EndDeviceProfileChange) []*iotv1.EndDeviceProfileChange {
+	messages := make([]*iotv1.EndDeviceProfileChange, 0, len(changes))
+	for _, change := range changes {
+		messages = append(messages, iotv1.EndDeviceProfileChange_builder{
+			EndDeviceId:    change.EndDeviceId,
+			Name:           change.Name,
+			Fields:         change.Fields,
+			HardwareTypeId: change.HardwareTypeId,
+			FrequencyPlan:  change.FrequencyPlan,
+			Labels:         change.Labels,
+		}.Build())
+	}
+
+	return messages
+}
+
+// endDeviceProfileError maps the errors of end device profile operations to Connect errors.
+func endDeviceProfileError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrEndDeviceProfileNotFound):
+		return connect.NewError(connect.CodeNotFound, err)
+	case errors.Is(err, domain.ErrEndDeviceProfileExists):
+		return connect.NewError(connect.CodeAlreadyExists, err)
+	case errors.Is(err, domain.ErrInvalidEndDeviceProfile), errors.Is(err, domain.ErrInvalidLabel):
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,73 @@
+package connectrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"connectrpc.com/connect"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// EndDeviceStatusManager handles the status lifecycle of end devices.
+type EndDeviceStatusManager interface {
+	DecommissionEndDevice(ctx context.Context, endDeviceId string, organizationId string, reason string) error
+	ListEndDeviceStatusTransitions(ctx context.Context, endDeviceId string, organizationId string) ([]*domain.EndDeviceStatusTransition, error)
+}
+
+// DisableEndDevice handles RPC requests to decommission an end device, moving it to DISABLED so that any further
+// data it sends is rejected. The optional reason is recorded with the status transition.
+// Requires super admin privileges or device update permission in the organization.
+func (handler *EndDeviceHandler) DisableEndDevice(ctx context.Context, req *connect.Request[iotv1.DisableEndDeviceRequest]) (*connect.Response[iotv1.DisableEndDeviceResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "DisableEndDevice")
+	defer span.End()
+
+	organization := req.Msg.GetOrganizationId()
+
+	err := authorize(ctx, organization, handler.authorizer.CanUpdateEndDevice, "update end devices")
+	if err != nil {
+		return nil, err
+	}
+
+	err = handler.statusManager.DecommissionEndDevice(ctx, req.Msg.GetEndDeviceId(), organization, req.Msg.GetReason())
+	if err != nil {
+		return nil, endDeviceStatusError(err, req.Msg.GetEndDeviceId())
+	}
+
+	return connect.NewResponse(iotv1.DisableEndDeviceResponse_builder{}.Build()), nil
+}
+
+// endDeviceStatusTransitions returns the status history of an end device as iot/v1 messages.
+func (handler *EndDeviceHandler) endDeviceStatusTransitions(ctx context.Context, endDeviceId string, organization string) ([]*iotv1.EndDeviceStatusTransition, error) {
+	transitions, err := handler.statusManager.ListEndDeviceStatusTransitions(ctx, endDeviceId, organization)
+	if err != nil {
+		return nil, endDeviceStatusError(err, endDeviceId)
+	}
+
+	messages := make([]*iotv1.EndDeviceStatusTransition, 0, len(transitions))
+	for _, transition := range transitions {
+		messages = append(messages, iotv1.EndDeviceStatusTransition_builder{
+			From:           transition.From,
+			To:             transition.To,
+			Reason:         transition.Reason,
+			TransitionedAt: timestamppb.New(transition.TransitionedAt),
+		}.Build())
+	}
+
+	return messages, nil
+}
+
+// endDeviceStatusError maps the errors of the status lifecycle to Connect errors.
+func endDeviceStatusError(err error, endDeviceId string) error {
+	switch {
+	case errors.Is(err, domain.ErrEndDeviceNotFound):
+		return connect.NewError(connect.CodeNotFound, fmt.Errorf("end device %s not found", endDeviceId))
+	case errors.Is(err, domain.ErrInvalidStatusTransition):
+		return connect.NewError(connect.CodeFailedPrecondition, err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,177 @@
+package connectrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"connectrpc.com/connect"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// endDeviceHistoryModes maps the history modes of the iot/v1 API to those of end device transfers. Unspecified
+// leaves the device's data with its previous organization.
+var endDeviceHistoryModes = map[iotv1.EndDeviceHistoryMode]domain.EndDeviceHistoryMode{
+	iotv1.EndDeviceHistoryMode_END_DEVICE_HISTORY_MODE_UNSPECIFIED: domain.EndDeviceHistoryKeep,
+	iotv1.EndDeviceHistoryMode_END_DEVICE_HISTORY_MODE_KEEP:        domain.EndDeviceHistoryKeep,
+	iotv1.EndDeviceHistoryMode_END_DEVICE_HISTORY_MODE_COPY:        domain.EndDeviceHistoryCopy,
+	iotv1.EndDeviceHistoryMode_END_DEVICE_HISTORY_MODE_RETAG:       domain.EndDeviceHistoryRetag,
+}
+
+// endDeviceHistoryModeMessages maps the history modes of end device transfers to those of the iot/v1 API.
+var endDeviceHistoryModeMessages = map[domain.EndDeviceHistoryMode]iotv1.EndDeviceHistoryMode{
+	domain.EndDeviceHistoryKeep:  iotv1.EndDeviceHistoryMode_END_DEVICE_HISTORY_MODE_KEEP,
+	domain.EndDeviceHistoryCopy:  iotv1.EndDeviceHistoryMode_END_DEVICE_HISTORY_MODE_COPY,
+	domain.EndDeviceHistoryRetag: iotv1.EndDeviceHistoryMode_END_DEVICE_HISTORY_MODE_RETAG,
+}
+
+// EndDeviceTransferManager handles moving end devices between organizations. It checks itself that the user may
+// transfer end devices out of the previous organization and into the new one.
+type EndDeviceTransferManager interface {
+	TransferEndDevice(ctx context.Context, req domain.EndDeviceTransferRequest) (*domain.EndDeviceTransfer, error)
+	ResumeEndDeviceTransfer(ctx context.Context, transferId string, userId string) (*domain.EndDeviceTransfer, error)
+	ListEndDeviceTransfers(ctx context.Context, endDeviceId string, organizationId string) ([]*domain.EndDeviceTransfer, error)
+}
+
+// EndDeviceTransferAuthorizer checks permissions for reading the transfers of an end device.
+type EndDeviceTransferAuthorizer interface {
+	CanReadEndDevice(ctx context.Context, userId string, organizationId string) (bool, error)
+}
+
+// EndDeviceTransferHandler implements Connect RPC handlers for end device transfer operations.
+type EndDeviceTransferHandler struct {
+	transferManager EndDeviceTransferManager
+	authorizer      EndDeviceTransferAuthorizer
+}
+
+// NewEndDeviceTransferHandler creates a new EndDeviceTransferHandler with the provided dependencies.
+func NewEndDeviceTransferHandler(transferMgr EndDeviceTransferManager, authorizer EndDeviceTransferAuthorizer) *EndDeviceTransferHandler {
+	return &EndDeviceTransferHandler{
+		transferManager: transferMgr,
+		authorizer:      authorizer,
+	}
+}
+
+// TransferEndDevice handles RPC requests to move an end device to another organization, keeping its ID,
+// credentials and status. The device leaves the device groups and profile of its previous owner and any claim code
+// issued for it is revoked. The history mode decides whether the data it stored so far is kept, copied or moved.
+// Requires super admin privileges or device transfer permission in both organizations.
+func (handler *EndDeviceTransferHandler) TransferEndDevice(ctx context.Context, req *connect.Request[iotv1.TransferEndDeviceRequest]) (*connect.Response[iotv1.TransferEndDeviceResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "TransferEndDevice")
+	defer span.End()
+
+	userId, ok := domain.GetUserFromContext(ctx)
+	if !ok {
+		return nil, connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("user not authenticated"))
+	}
+
+	history, ok := endDeviceHistoryModes[req.Msg.GetHistory()]
+	if !ok {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("unsupported history mode %v", req.Msg.GetHistory()))
+	}
+
+	transfer, err := handler.transferManager.TransferEndDevice(ctx, domain.EndDeviceTransferRequest{
+		EndDeviceId:        req.Msg.GetEndDeviceId(),
+		FromOrganizationId: req.Msg.GetFromOrganizationId(),
+		ToOrganizationId:   req.Msg.GetToOrganizationId(),
+		TransferredBy:      userId,
+		History:            history,
+	})
+	if err != nil {
+		return nil, endDeviceTransferError(err, transfer)
+	}
+
+	return connect.NewResponse(iotv1.TransferEndDeviceResponse_builder{
+		Transfer: endDeviceTransferToProto(transfer),
+	}.Build()), nil
+}
+
+// ResumeEndDeviceTransfer handles RPC requests to finish copying or moving the data of a transfer whose history
+// could not be transferred before. Transfers without pending history are returned unchanged.
+// Requires super admin privileges or device transfer permission in both organizations of the transfer.
+func (handler *EndDeviceTransferHandler) ResumeEndDeviceTransfer(ctx context.Context, req *connect.Request[iotv1.ResumeEndDeviceTransferRequest]) (*connect.Response[iotv1.ResumeEndDeviceTransferResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ResumeEndDeviceTransfer")
+	defer span.End()
+
+	userId, ok := domain.GetUserFromContext(ctx)
+	if !ok {
+		return nil, connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("user not authenticated"))
+	}
+
+	transfer, err := handler.transferManager.ResumeEndDeviceTransfer(ctx, req.Msg.GetTransferId(), userId)
+	if err != nil {
+		return nil, endDeviceTransferError(err, transfer)
+	}
+
+	return connect.NewResponse(iotv1.ResumeEndDeviceTransferResponse_builder{
+		Transfer: endDeviceTransferToProto(transfer),
+	}.Build()), nil
+}
+
+// EndDeviceTransfers handles RPC requests to list the transfers of an end device, most recent first.
+// Requires super admin privileges or device read permission in the organization the device now belongs to.
+func (handler *EndDeviceTransferHandler) EndDeviceTransfers(ctx context.Context, req *connect.Request[iotv1.EndDeviceTransfersRequest]) (*connect.Response[iotv1.EndDeviceTransfersResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "EndDeviceTransfers")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanReadEndDevice, "read end devices")
+	if err != nil {
+		return nil, err
+	}
+
+	transfers, err := handler.transferManager.ListEndDeviceTransfers(ctx, req.Msg.GetEndDeviceId(), req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, endDeviceTransferError(err, nil)
+	}
+
+	messages := make([]*iotv1.EndDeviceTransfer, 0, len(transfers))
+	for _, transfer := range transfers {
+		messages = append(messages, endDeviceTransferToProto(transfer))
+	}
+
+	return connect.NewResponse(iotv1.EndDeviceTransfersResponse_builder{
+		Transfers: messages,
+	}.Build()), nil
+}
+
+// endDeviceTransferToProto converts an end device transfer to its iot/v1 message.
+func endDeviceTransferToProto(transfer *domain.EndDeviceTransfer) *iotv1.EndDeviceTransfer {
+	message := iotv1.EndDeviceTransfer_builder{
+		Id:                 transfer.Id,
+		EndDeviceId:        transfer.EndDeviceId,
+		FromOrganizationId: transfer.FromOrganizationId,
+		ToOrganizationId:   transfer.ToOrganizationId,
+		TransferredBy:      transfer.TransferredBy,
+		History:            endDeviceHistoryModeMessages[transfer.History],
+		TransferredAt:      timestamppb.New(transfer.TransferredAt),
+		HistoryPending:     transfer.HistoryPending(),
+	}.Build()
+
+	if !transfer.HistoryTransferredAt.IsZero() {
+		message.SetHistoryTransferredAt(timestamppb.New(transfer.HistoryTransferredAt))
+	}
+
+	return message
+}
+
+// endDeviceTransferError maps the errors of end device transfer operations to Connect errors. When the device
+// moved but its history could not be transferred, the error names the transfer to resume.
+func endDeviceTransferError(err error, transfer *domain.EndDeviceTransfer) error {
+	switch {
+	case transfer != nil:
+		return connect.NewError(connect.CodeUnavailable, fmt.Errorf("end device moved by transfer %s, but its history could not be transferred; resume the transfer to finish it: %w", transfer.Id, err))
+	case errors.Is(err, domain.ErrEndDeviceNotFound), errors.Is(err, domain.ErrEndDeviceTransferNotFound):
+		return connect.NewError(connect.CodeNotFound, err)
+	case errors.Is(err, domain.ErrInvalidEndDeviceTransfer):
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	case errors.Is(err, domain.ErrEndDeviceTransferDenied):
+		return connect.NewError(connect.CodePermissionDenied, err)
+	case errors.Is(err, domain.ErrEndDeviceDisabled):
+		return connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("transfer failed: %w", err))
+	default:
+		return err
+	}
+}
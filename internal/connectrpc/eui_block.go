@@ -0,0 +1,110 @@
+package connectrpc
+
+import (
+	"context"
+	"errors"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"connectrpc.com/connect"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// euiBlockTypes maps the IEEE assignment types of EUI blocks to those of the iot/v1 API.
+var euiBlockTypes = map[domain.EUIBlockType]iotv1.EUIBlockType{
+	domain.EUIBlockMAL: iotv1.EUIBlockType_EUI_BLOCK_TYPE_MA_L,
+	domain.EUIBlockMAM: iotv1.EUIBlockType_EUI_BLOCK_TYPE_MA_M,
+	domain.EUIBlockMAS: iotv1.EUIBlockType_EUI_BLOCK_TYPE_MA_S,
+}
+
+// EUIBlockManager handles the IEEE address blocks device EUIs are allocated from.
+type EUIBlockManager interface {
+	AddEUIBlock(ctx context.Context, organizationId string, prefix string) (*domain.EUIBlock, error)
+	ListEUIBlocks(ctx context.Context, organizationId string) ([]*domain.EUIBlock, error)
+}
+
+// EUIBlockHandler implements Connect RPC handlers for EUI block operations.
+type EUIBlockHandler struct {
+	euiBlockManager EUIBlockManager
+}
+
+// NewEUIBlockHandler creates a new EUIBlockHandler with the provided dependencies.
+func NewEUIBlockHandler(euiBlockMgr EUIBlockManager) *EUIBlockHandler {
+	return &EUIBlockHandler{
+		euiBlockManager: euiBlockMgr,
+	}
+}
+
+// AddEUIBlock handles RPC requests to assign an IEEE address block to an organization. The device EUIs of its new
+// LoRaWAN end devices are then allocated from the block.
+// EUI blocks are bought from the IEEE for the whole deployment, so this requires super admin privileges.
+func (handler *EUIBlockHandler) AddEUIBlock(ctx context.Context, req *connect.Request[iotv1.AddEUIBlockRequest]) (*connect.Response[iotv1.AddEUIBlockResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "AddEUIBlock")
+	defer span.End()
+
+	err := requireSuperAdmin(ctx, "add EUI blocks")
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := handler.euiBlockManager.AddEUIBlock(ctx, req.Msg.GetOrganizationId(), req.Msg.GetPrefix())
+	if err != nil {
+		return nil, euiBlockError(err)
+	}
+
+	return connect.NewResponse(iotv1.AddEUIBlockResponse_builder{
+		EuiBlock: euiBlockToProto(block),
+	}.Build()), nil
+}
+
+// OrganizationEUIBlocks handles RPC requests to list the EUI blocks of an organization with how much of them has
+// been handed out, oldest first.
+// Requires super admin privileges.
+func (handler *EUIBlockHandler) OrganizationEUIBlocks(ctx context.Context, req *connect.Request[iotv1.OrganizationEUIBlocksRequest]) (*connect.Response[iotv1.OrganizationEUIBlocksResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "OrganizationEUIBlocks")
+	defer span.End()
+
+	err := requireSuperAdmin(ctx, "read EUI blocks")
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := handler.euiBlockManager.ListEUIBlocks(ctx, req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, euiBlockError(err)
+	}
+
+	messages := make([]*iotv1.EUIBlock, 0, len(blocks))
+	for _, block := range blocks {
+		messages = append(messages, euiBlockToProto(block))
+	}
+
+	return connect.NewResponse(iotv1.OrganizationEUIBlocksResponse_builder{
+		EuiBlocks: messages,
+	}.Build()), nil
+}
+
+// euiBlockToProto converts an EUI block to its iot/v1 message.
+func euiBlockToProto(block *domain.EUIBlock) *iotv1.EUIBlock {
+	return iotv1.EUIBlock_builder{
+		Prefix:         block.Prefix,
+		OrganizationId: block.OrganizationId,
+		Type:           euiBlockTypes[block.Type()],
+		Size:           block.Size,
+		NextOffset:     block.NextOffset,
+		CreatedAt:      timestamppb.New(block.CreatedAt),
+	}.Build()
+}
+
+// euiBlockError maps the errors of EUI block operations to Connect errors.
+func euiBlockError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidEUIBlock):
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	case errors.Is(err, domain.ErrEUIBlockInUse):
+		return connect.NewError(connect.CodeAlreadyExists, err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,278 @@
+package connectrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"connectrpc.com/connect"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// gatewayStatuses maps the connection states of gateways to those of the iot/v1 API.
+var gatewayStatuses = map[domain.GatewayStatus]iotv1.GatewayStatus{
+	domain.GatewayStatusUnknown: iotv1.GatewayStatus_GATEWAY_STATUS_UNKNOWN,
+	domain.GatewayStatusOnline:  iotv1.GatewayStatus_GATEWAY_STATUS_ONLINE,
+	domain.GatewayStatusOffline: iotv1.GatewayStatus_GATEWAY_STATUS_OFFLINE,
+}
+
+// gatewayLocationSources maps the location sources of gateways to those of the iot/v1 API.
+var gatewayLocationSources = map[domain.GatewayLocationSource]iotv1.GatewayLocationSource{
+	domain.GatewayLocationRegistry: iotv1.GatewayLocationSource_GATEWAY_LOCATION_SOURCE_REGISTRY,
+	domain.GatewayLocationStatus:   iotv1.GatewayLocationSource_GATEWAY_LOCATION_SOURCE_STATUS,
+}
+
+// GatewayManager handles gateway business operations.
+type GatewayManager interface {
+	CreateGateway(ctx context.Context, organizationId string, gateway *domain.Gateway) (*domain.Gateway, error)
+	GetGateway(ctx context.Context, gatewayId string, organizationId string) (*domain.Gateway, error)
+	ListGateways(ctx context.Context, organizationId string) ([]*domain.Gateway, error)
+	UpdateGateway(ctx context.Context, update *domain.Gateway, organizationId string) (*domain.Gateway, error)
+	DeleteGateway(ctx context.Context, gatewayId string, organizationId string) error
+}
+
+// GatewayAuthorizer checks permissions for gateway operations.
+type GatewayAuthorizer interface {
+	CanCreateGateway(ctx context.Context, userId string, organizationId string) (bool, error)
+	CanReadGateway(ctx context.Context, userId string, organizationId string) (bool, error)
+	CanUpdateGateway(ctx context.Context, userId string, organizationId string) (bool, error)
+	CanDeleteGateway(ctx context.Context, userId string, organizationId string) (bool, error)
+}
+
+// GatewayHandler implements Connect RPC handlers for gateway operations.
+type GatewayHandler struct {
+	gatewayManager GatewayManager
+	authorizer     GatewayAuthorizer
+}
+
+// NewGatewayHandler creates a new GatewayHandler with the provided dependencies.
+func NewGatewayHandler(gatewayMgr GatewayManager, authorizer GatewayAuthorizer) *GatewayHandler {
+	return &GatewayHandler{
+		gatewayManager: gatewayMgr,
+		authorizer:     authorizer,
+	}
+}
+
+// CreateGateway handles RPC requests to create a gateway in an organization. The gateway is registered with TTN
+// under its ponix ID before it is stored.
+// Requires super admin privileges or gateway creation permission in the organization.
+func (handler *GatewayHandler) CreateGateway(ctx context.Context, req *connect.Request[iotv1.CreateGatewayRequest]) (*connect.Response[iotv1.CreateGatewayResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "CreateGateway")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanCreateGateway, "create gateways")
+	if err != nil {
+		return nil, err
+	}
+
+	gateway := &domain.Gateway{
+		Name:          req.Msg.GetName(),
+		Description:   req.Msg.GetDescription(),
+		GatewayEui:    req.Msg.GetGatewayEui(),
+		FrequencyPlan: req.Msg.GetFrequencyPlan(),
+	}
+	if req.Msg.HasLocation() {
+		gateway.Location = gatewayLocationFromProto(req.Msg.GetLocation())
+	}
+
+	gateway, err = handler.gatewayManager.CreateGateway(ctx, req.Msg.GetOrganizationId(), gateway)
+	if err != nil {
+		return nil, gatewayError(err)
+	}
+
+	return connect.NewResponse(iotv1.CreateGatewayResponse_builder{
+		Gateway: gatewayToProto(gateway),
+	}.Build()), nil
+}
+
+// Gateway handles RPC requests to retrieve a single gateway with its last known status.
+// Requires super admin privileges or gateway read permission in the organization.
+func (handler *GatewayHandler) Gateway(ctx context.Context, req *connect.Request[iotv1.GatewayRequest]) (*connect.Response[iotv1.GatewayResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "Gateway")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanReadGateway, "read gateways")
+	if err != nil {
+		return nil, err
+	}
+
+	gateway, err := handler.gatewayManager.GetGateway(ctx, req.Msg.GetGatewayId(), req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, gatewayError(err)
+	}
+
+	return connect.NewResponse(iotv1.GatewayResponse_builder{
+		Gateway: gatewayToProto(gateway),
+	}.Build()), nil
+}
+
+// OrganizationGateways handles RPC requests to list the gateways of an organization by name with their status.
+// Requires super admin privileges or gateway read permission in the organization.
+func (handler *GatewayHandler) OrganizationGateways(ctx context.Context, req *connect.Request[iotv1.OrganizationGatewaysRequest]) (*connect.Response[iotv1.OrganizationGatewaysResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "OrganizationGateways")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanReadGateway, "read gateways")
+	if err != nil {
+		return nil, err
+	}
+
+	gateways, err := handler.gatewayManager.ListGateways(ctx, req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, gatewayError(err)
+	}
+
+	messages := make([]*iotv1.Gateway, 0, len(gateways))
+	for _, gateway := range gateways {
+		messages = append(messages, gatewayToProto(gateway))
+	}
+
+	return connect.NewResponse(iotv1.OrganizationGatewaysResponse_builder{
+		Gateways: messages,
+	}.Build()), nil
+}
+
+// UpdateGateway handles RPC requests to rename a gateway or change its description, frequency plan or location.
+// Without an update_mask every non-empty field is applied. With one, only the fields it names are applied; an
+// empty description then clears it. The EUI of a gateway cannot change and its location cannot be removed.
+// Requires super admin privileges or gateway update permission in the organization.
+func (handler *GatewayHandler) UpdateGateway(ctx context.Context, req *connect.Request[iotv1.UpdateGatewayRequest]) (*connect.Response[iotv1.UpdateGatewayResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "UpdateGateway")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanUpdateGateway, "update gateways")
+	if err != nil {
+		return nil, err
+	}
+
+	paths := req.Msg.GetUpdateMask().GetPaths()
+	for _, path := range paths {
+		if !gatewayUpdateFields[path] {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid update_mask: unsupported field %q", path))
+		}
+	}
+
+	applies := func(field string, empty bool) bool {
+		if len(paths) > 0 {
+			return slices.Contains(paths, field)
+		}
+		return !empty
+	}
+
+	current, err := handler.gatewayManager.GetGateway(ctx, req.Msg.GetGatewayId(), req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, gatewayError(err)
+	}
+
+	// The manager leaves an empty name and frequency plan and a nil location unchanged, and always sets the
+	// description
+	update := &domain.Gateway{Id: current.Id, Description: current.Description}
+	if applies("name", req.Msg.GetName() == "") {
+		update.Name = req.Msg.GetName()
+	}
+	if applies("description", req.Msg.GetDescription() == "") {
+		update.Description = req.Msg.GetDescription()
+	}
+	if applies("frequency_plan", req.Msg.GetFrequencyPlan() == "") {
+		update.FrequencyPlan = req.Msg.GetFrequencyPlan()
+	}
+	if req.Msg.HasLocation() && applies("location", false) {
+		update.Location = gatewayLocationFromProto(req.Msg.GetLocation())
+	}
+
+	gateway, err := handler.gatewayManager.UpdateGateway(ctx, update, req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, gatewayError(err)
+	}
+
+	return connect.NewResponse(iotv1.UpdateGatewayResponse_builder{
+		Gateway: gatewayToProto(gateway),
+	}.Build()), nil
+}
+
+// DeleteGateway handles RPC requests to delete a gateway. It is removed from TTN before the deletion is committed.
+// Requires super admin privileges or gateway delete permission in the organization.
+func (handler *GatewayHandler) DeleteGateway(ctx context.Context, req *connect.Request[iotv1.DeleteGatewayRequest]) (*connect.Response[iotv1.DeleteGatewayResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "DeleteGateway")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanDeleteGateway, "delete gateways")
+	if err != nil {
+		return nil, err
+	}
+
+	err = handler.gatewayManager.DeleteGateway(ctx, req.Msg.GetGatewayId(), req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, gatewayError(err)
+	}
+
+	return connect.NewResponse(iotv1.DeleteGatewayResponse_builder{}.Build()), nil
+}
+
+// gatewayUpdateFields are the update_mask paths an UpdateGateway request accepts.
+var gatewayUpdateFields = map[string]bool{
+	"name":           true,
+	"description":    true,
+	"frequency_plan": true,
+	"location":       true,
+}
+
+// gatewayLocationFromProto converts the location of a request. Its source is set by the manager.
+func gatewayLocationFromProto(message *iotv1.GatewayLocation) *domain.GatewayLocation {
+	return &domain.GatewayLocation{
+		Latitude:  message.GetLatitude(),
+		Longitude: message.GetLongitude(),
+		Altitude:  message.GetAltitude(),
+	}
+}
+
+// gatewayToProto converts a gateway to its iot/v1 message. The last seen and status check times are left unset
+// until the gateway has been seen or checked.
+func gatewayToProto(gateway *domain.Gateway) *iotv1.Gateway {
+	message := iotv1.Gateway_builder{
+		Id:             gateway.Id,
+		OrganizationId: gateway.OrganizationId,
+		Name:           gateway.Name,
+		Description:    gateway.Description,
+		GatewayEui:     gateway.GatewayEui,
+		FrequencyPlan:  gateway.FrequencyPlan,
+		Status:         gatewayStatuses[gateway.Status],
+		CreatedAt:      timestamppb.New(gateway.CreatedAt),
+		UpdatedAt:      timestamppb.New(gateway.UpdatedAt),
+	}.Build()
+
+	if gateway.Location != nil {
+		message.SetLocation(iotv1.GatewayLocation_builder{
+			Latitude:  gateway.Location.Latitude,
+			Longitude: gateway.Location.Longitude,
+			Altitude:  gateway.Location.Altitude,
+			Source:    gatewayLocationSources[gateway.Location.Source],
+		}.Build())
+	}
+	if !gateway.LastSeenAt.IsZero() {
+		message.SetLastSeenAt(timestamppb.New(gateway.LastSeenAt))
+	}
+	if !gateway.StatusCheckedAt.IsZero() {
+		message.SetStatusCheckedAt(timestamppb.New(gateway.StatusCheckedAt))
+	}
+
+	return message
+}
+
+// gatewayError maps the errors of gateway operations to Connect errors.
+func gatewayError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrGatewayNotFound):
+		return connect.NewError(connect.CodeNotFound, err)
+	case errors.Is(err, domain.ErrGatewayExists):
+		return connect.NewError(connect.CodeAlreadyExists, err)
+	case errors.Is(err, domain.ErrInvalidGateway):
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	default:
+		return err
+	}
+}
@@ -2,13 +2,16 @@ package connectrpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	envelopev1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/envelope/v1"
 	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
 	"connectrpc.com/connect"
+	"github.com/ponix-dev/ponix/internal/domain"
 	"github.com/ponix-dev/ponix/internal/telemetry"
+	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -17,20 +20,31 @@ type DataEnvelopeManager interface {
 	IngestDataEnvelope(ctx context.Context, envelope *envelopev1.DataEnvelope, organizationID string) error
 }
 
+// EndDeviceTwinReporter handles the device side of end device twins
+type EndDeviceTwinReporter interface {
+	GetEndDeviceTwin(ctx context.Context, endDeviceId string, organizationId string) (*domain.EndDeviceTwin, error)
+	ReportEndDeviceTwinState(ctx context.Context, endDeviceId string, organizationId string, reported map[string]any) (*domain.EndDeviceTwin, error)
+}
+
 // IngestionHandler implements ConnectRPC handlers for device data ingestion
 type IngestionHandler struct {
 	envelopeManager DataEnvelopeManager
+	twinReporter    EndDeviceTwinReporter
 }
 
 // NewIngestionHandler creates a new ingestion handler
-func NewIngestionHandler(envelopeManager DataEnvelopeManager) *IngestionHandler {
+func NewIngestionHandler(envelopeManager DataEnvelopeManager, twinReporter EndDeviceTwinReporter) *IngestionHandler {
 	return &IngestionHandler{
 		envelopeManager: envelopeManager,
+		twinReporter:    twinReporter,
 	}
 }
 
 // IngestDeviceData handles RPC requests to ingest device telemetry data
 // No authorization required for MVP (future enhancement)
+// Data for disabled (decommissioned) devices is rejected with FailedPrecondition.
+// A reserved "$reported" object in the data updates the device's twin instead of being stored as telemetry,
+// and the response carries the desired state the device has not reported yet in twin_delta.
 func (handler *IngestionHandler) IngestDeviceData(
 	ctx context.Context,
 	req *connect.Request[iotv1.IngestDeviceDataRequest],
@@ -49,28 +63,60 @@ func (handler *IngestionHandler) IngestDeviceData(
 		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("data is required"))
 	}
 
+	data, reported, err := domain.SplitTwinReported(req.Msg.GetData())
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	// Apply reported state before storing telemetry, so a retried request only re-merges the same state
+	var twin *domain.EndDeviceTwin
+	if reported != nil {
+		twin, err = handler.twinReporter.ReportEndDeviceTwinState(ctx, req.Msg.GetEndDeviceId(), req.Msg.GetOrganizationId(), reported)
+	} else {
+		twin, err = handler.twinReporter.GetEndDeviceTwin(ctx, req.Msg.GetEndDeviceId(), req.Msg.GetOrganizationId())
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrEndDeviceDisabled) {
+			return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("ingestion failed: %w", err))
+		}
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("ingestion failed: %w", err))
+	}
+
 	// Use provided occurred_at or default to now
 	occurredAt := req.Msg.GetOccurredAt()
 	if occurredAt == nil {
 		occurredAt = timestamppb.New(time.Now().UTC())
 	}
 
-	// Build DataEnvelope
-	envelope := envelopev1.DataEnvelope_builder{
-		EndDeviceId: req.Msg.GetEndDeviceId(),
-		OccurredAt:  occurredAt,
-		Data:        req.Msg.GetData(),
-	}.Build()
+	// A message carrying only reported state has no telemetry to store
+	if reported == nil || len(data.GetFields()) > 0 {
+		// Build DataEnvelope
+		envelope := envelopev1.DataEnvelope_builder{
+			EndDeviceId: req.Msg.GetEndDeviceId(),
+			OccurredAt:  occurredAt,
+			Data:        data,
+		}.Build()
 
-	// Ingest with validation (checks device exists and belongs to org)
-	err := handler.envelopeManager.IngestDataEnvelope(ctx, envelope, req.Msg.GetOrganizationId())
+		// Ingest with validation (checks device exists and belongs to org)
+		err = handler.envelopeManager.IngestDataEnvelope(ctx, envelope, req.Msg.GetOrganizationId())
+		if err != nil {
+			if errors.Is(err, domain.ErrEndDeviceDisabled) {
+				return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("ingestion failed: %w", err))
+			}
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("ingestion failed: %w", err))
+		}
+	}
+
+	twinDelta, err := structpb.NewStruct(twin.Delta())
 	if err != nil {
-		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("ingestion failed: %w", err))
+		return nil, connect.NewError(connect.CodeInternal, err)
 	}
 
 	// Return success
 	return connect.NewResponse(iotv1.IngestDeviceDataResponse_builder{
-		Success: true,
-		Message: "Data ingested successfully",
+		Success:            true,
+		Message:            "Data ingested successfully",
+		TwinDelta:          twinDelta,
+		TwinDesiredVersion: twin.DesiredVersion,
 	}.Build()), nil
 }
@@ -0,0 +1,45 @@
+package connectrpc
+
+import (
+	"fmt"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// parseLabelSelector parses the label_selector field of a request, such as "building=north,floor in (2,3)".
+// An empty field yields an empty selector.
+func parseLabelSelector(labelSelector string) (domain.LabelSelector, error) {
+	selector, err := domain.ParseLabelSelector(labelSelector)
+	if err != nil {
+		return selector, fmt.Errorf("invalid label_selector: %w", err)
+	}
+
+	return selector, nil
+}
+
+// endDeviceMetadataFromCreateRequest takes the labels and attributes of a new end device from its create request.
+func endDeviceMetadataFromCreateRequest(req *iotv1.CreateEndDeviceRequest) domain.EndDeviceMetadata {
+	metadata := domain.EndDeviceMetadata{
+		Labels: domain.Labels(req.GetLabels()),
+	}
+	if req.HasAttributes() {
+		metadata.Attributes = req.GetAttributes().AsMap()
+	}
+
+	return metadata
+}
+
+// setEndDeviceMetadata copies an end device's labels and attributes into the message returned for it.
+func setEndDeviceMetadata(endDevice *iotv1.EndDevice, metadata domain.EndDeviceMetadata) error {
+	attributes, err := structpb.NewStruct(metadata.Attributes)
+	if err != nil {
+		return fmt.Errorf("end device attributes cannot be returned: %w", err)
+	}
+
+	endDevice.SetLabels(metadata.Labels)
+	endDevice.SetAttributes(attributes)
+
+	return nil
+}
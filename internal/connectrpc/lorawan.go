@@ -2,6 +2,7 @@ package connectrpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
@@ -19,6 +20,13 @@ type LoRaWANHardwareTypeManager interface {
 	DeleteLoRaWANHardwareType(ctx context.Context, hardwareType string) error
 }
 
+// PayloadDecoderManager handles the payload decoders of LoRaWAN hardware types.
+type PayloadDecoderManager interface {
+	GetLoRaWANHardwareTypePayloadDecoder(ctx context.Context, hardwareTypeId string) (*domain.PayloadDecoder, error)
+	SetLoRaWANHardwareTypePayloadDecoder(ctx context.Context, hardwareTypeId string, decoder *domain.PayloadDecoder) error
+	TestPayloadDecoder(ctx context.Context, decoder domain.PayloadDecoder, input domain.PayloadDecoderInput) (*domain.PayloadDecoderOutput, error)
+}
+
 // LoRaWANAuthorizer checks permissions for LoRaWAN hardware type operations.
 type LoRaWANAuthorizer interface {
 	CanCreateLoRaWANHardwareType(ctx context.Context, userId string, organizationId string) (bool, error)
@@ -29,19 +37,22 @@ type LoRaWANAuthorizer interface {
 
 // LoRaWANHandler implements Connect RPC handlers for LoRaWAN hardware type catalog operations.
 type LoRaWANHandler struct {
-	hardwareTypeManager LoRaWANHardwareTypeManager
-	authorizer          LoRaWANAuthorizer
+	hardwareTypeManager   LoRaWANHardwareTypeManager
+	payloadDecoderManager PayloadDecoderManager
+	authorizer            LoRaWANAuthorizer
 }
 
 // NewLoRaWANHandler creates a new LoRaWANHandler with the provided dependencies.
-func NewLoRaWANHandler(htMgr LoRaWANHardwareTypeManager, authorizer LoRaWANAuthorizer) *LoRaWANHandler {
+func NewLoRaWANHandler(htMgr LoRaWANHardwareTypeManager, pdMgr PayloadDecoderManager, authorizer LoRaWANAuthorizer) *LoRaWANHandler {
 	return &LoRaWANHandler{
-		hardwareTypeManager: htMgr,
-		authorizer:          authorizer,
+		hardwareTypeManager:   htMgr,
+		payloadDecoderManager: pdMgr,
+		authorizer:            authorizer,
 	}
 }
 
-// CreateLoRaWANHardwareType handles RPC requests to create a new LoRaWAN hardware type.
+// CreateLoRaWANHardwareType handles RPC requests to create a new LoRaWAN hardware type, along with the payload
+// decoder sent in payload_decoder.
 // Requires super admin privileges or hardware type creation permission in the organization.
 func (handler *LoRaWANHandler) CreateLoRaWANHardwareType(ctx context.Context, req *connect.Request[iotv1.CreateLoRaWANHardwareTypeRequest]) (*connect.Response[iotv1.CreateLoRaWANHardwareTypeResponse], error) {
 	ctx, span := telemetry.Tracer().Start(ctx, "CreateLoRaWANHardwareType")
@@ -78,19 +89,40 @@ func (handler *LoRaWANHandler) CreateLoRaWANHardwareType(ctx context.Context, re
 		return nil, connect.NewError(connect.CodePermissionDenied, fmt.Errorf("user not authorized to create LoRaWAN hardware types"))
 	}
 
+	decoder, err := payloadDecoderFromProto(req.Msg.GetPayloadDecoder())
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	if decoder != nil {
+		err = decoder.Validate()
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+	}
+
 	hardwareData, err := handler.hardwareTypeManager.CreateLoRaWANHardwareType(ctx, req.Msg)
 	if err != nil {
 		return nil, err
 	}
 
+	if decoder != nil {
+		err = handler.setPayloadDecoder(ctx, hardwareData.GetHardwareTypeId(), decoder)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	resp := connect.NewResponse(iotv1.CreateLoRaWANHardwareTypeResponse_builder{
-		HardwareData: hardwareData,
+		HardwareData:   hardwareData,
+		PayloadDecoder: payloadDecoderToProto(decoder),
 	}.Build())
 
 	return resp, nil
 }
 
-// GetLoRaWANHardwareType handles RPC requests to retrieve a LoRaWAN hardware type by ID.
+// GetLoRaWANHardwareType handles RPC requests to retrieve a LoRaWAN hardware type by ID, along with its payload
+// decoder.
 // Requires super admin privileges or hardware type read permission in the organization.
 func (handler *LoRaWANHandler) GetLoRaWANHardwareType(ctx context.Context, req *connect.Request[iotv1.GetLoRaWANHardwareTypeRequest]) (*connect.Response[iotv1.GetLoRaWANHardwareTypeResponse], error) {
 	ctx, span := telemetry.Tracer().Start(ctx, "GetLoRaWANHardwareType")
@@ -132,8 +164,14 @@ func (handler *LoRaWANHandler) GetLoRaWANHardwareType(ctx context.Context, req *
 		return nil, err
 	}
 
+	decoder, err := handler.payloadDecoderManager.GetLoRaWANHardwareTypePayloadDecoder(ctx, hardwareData.GetHardwareTypeId())
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
 	resp := connect.NewResponse(iotv1.GetLoRaWANHardwareTypeResponse_builder{
-		HardwareData: hardwareData,
+		HardwareData:   hardwareData,
+		PayloadDecoder: payloadDecoderToProto(decoder),
 	}.Build())
 
 	return resp, nil
@@ -188,7 +226,8 @@ func (handler *LoRaWANHandler) ListLoRaWANHardwareTypes(ctx context.Context, req
 	return resp, nil
 }
 
-// UpdateLoRaWANHardwareType handles RPC requests to update an existing LoRaWAN hardware type.
+// UpdateLoRaWANHardwareType handles RPC requests to update an existing LoRaWAN hardware type. A payload decoder
+// sent in payload_decoder replaces the hardware type's, and an empty one removes it.
 // Requires super admin privileges or hardware type update permission in the organization.
 func (handler *LoRaWANHandler) UpdateLoRaWANHardwareType(ctx context.Context, req *connect.Request[iotv1.UpdateLoRaWANHardwareTypeRequest]) (*connect.Response[iotv1.UpdateLoRaWANHardwareTypeResponse], error) {
 	ctx, span := telemetry.Tracer().Start(ctx, "UpdateLoRaWANHardwareType")
@@ -225,11 +264,30 @@ func (handler *LoRaWANHandler) UpdateLoRaWANHardwareType(ctx context.Context, re
 		return nil, connect.NewError(connect.CodePermissionDenied, fmt.Errorf("user not authorized to update LoRaWAN hardware types"))
 	}
 
-	_, err := handler.hardwareTypeManager.UpdateLoRaWANHardwareType(ctx, req.Msg)
+	decoder, err := payloadDecoderFromProto(req.Msg.GetPayloadDecoder())
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	if decoder != nil {
+		err = decoder.Validate()
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+	}
+
+	hardwareData, err := handler.hardwareTypeManager.UpdateLoRaWANHardwareType(ctx, req.Msg)
 	if err != nil {
 		return nil, err
 	}
 
+	if req.Msg.HasPayloadDecoder() {
+		err = handler.setPayloadDecoder(ctx, hardwareData.GetHardwareTypeId(), decoder)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	resp := connect.NewResponse(iotv1.UpdateLoRaWANHardwareTypeResponse_builder{}.Build())
 
 	return resp, nil
@@ -281,3 +339,17 @@ func (handler *LoRaWANHandler) DeleteLoRaWANHardwareType(ctx context.Context, re
 
 	return resp, nil
 }
+
+// setPayloadDecoder sets the payload decoder of a LoRaWAN hardware type, mapping invalid decoders to
+// CodeInvalidArgument.
+func (handler *LoRaWANHandler) setPayloadDecoder(ctx context.Context, hardwareTypeId string, decoder *domain.PayloadDecoder) error {
+	err := handler.payloadDecoderManager.SetLoRaWANHardwareTypePayloadDecoder(ctx, hardwareTypeId, decoder)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidPayloadDecoder) {
+			return connect.NewError(connect.CodeInvalidArgument, err)
+		}
+		return connect.NewError(connect.CodeInternal, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package connectrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"connectrpc.com/connect"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// loRaWANDownlinkStatuses maps the statuses of LoRaWAN downlinks to those of the iot/v1 API.
+var loRaWANDownlinkStatuses = map[domain.LoRaWANDownlinkStatus]iotv1.LoRaWANDownlinkStatus{
+	domain.LoRaWANDownlinkQueued: iotv1.LoRaWANDownlinkStatus_LORAWAN_DOWNLINK_STATUS_QUEUED,
+	domain.LoRaWANDownlinkSent:   iotv1.LoRaWANDownlinkStatus_LORAWAN_DOWNLINK_STATUS_SENT,
+	domain.LoRaWANDownlinkAcked:  iotv1.LoRaWANDownlinkStatus_LORAWAN_DOWNLINK_STATUS_ACKED,
+	domain.LoRaWANDownlinkFailed: iotv1.LoRaWANDownlinkStatus_LORAWAN_DOWNLINK_STATUS_FAILED,
+}
+
+// LoRaWANDownlinkManager handles the downlinks of LoRaWAN end devices.
+type LoRaWANDownlinkManager interface {
+	ScheduleLoRaWANDownlink(ctx context.Context, req domain.LoRaWANDownlinkRequest) (*domain.LoRaWANDownlink, error)
+	ListEndDeviceLoRaWANDownlinks(ctx context.Context, endDeviceId string, organizationId string, limit int) ([]*domain.LoRaWANDownlink, error)
+}
+
+// LoRaWANDownlinkAuthorizer checks permissions for LoRaWAN downlink operations. Downlinks are sent with the
+// permission to update end devices and listed with the permission to read them.
+type LoRaWANDownlinkAuthorizer interface {
+	CanUpdateEndDevice(ctx context.Context, userId string, organizationId string) (bool, error)
+	CanReadEndDevice(ctx context.Context, userId string, organizationId string) (bool, error)
+}
+
+// LoRaWANDownlinkHandler implements Connect RPC handlers for LoRaWAN downlink operations.
+type LoRaWANDownlinkHandler struct {
+	downlinkManager LoRaWANDownlinkManager
+	authorizer      LoRaWANDownlinkAuthorizer
+}
+
+// NewLoRaWANDownlinkHandler creates a new LoRaWANDownlinkHandler with the provided dependencies.
+func NewLoRaWANDownlinkHandler(downlinkMgr LoRaWANDownlinkManager, authorizer LoRaWANDownlinkAuthorizer) *LoRaWANDownlinkHandler {
+	return &LoRaWANDownlinkHandler{
+		downlinkManager: downlinkMgr,
+		authorizer:      authorizer,
+	}
+}
+
+// ScheduleDownlink handles RPC requests to push a downlink to the TTN queue of an enabled LoRaWAN end device. The
+// payload is sent raw in frm_payload, which needs f_port, or as data, which the encodeDownlink function of the
+// device's JavaScript payload decoder turns into bytes; f_port then overrides the port it picks. The downlink's
+// status follows the events The Things Stack sends to the webhook and is returned by EndDeviceDownlinks.
+// Requires super admin privileges or device update permission in the device's organization.
+func (handler *LoRaWANDownlinkHandler) ScheduleDownlink(ctx context.Context, req *connect.Request[iotv1.ScheduleDownlinkRequest]) (*connect.Response[iotv1.ScheduleDownlinkResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ScheduleDownlink")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanUpdateEndDevice, "send downlinks to end devices")
+	if err != nil {
+		return nil, err
+	}
+
+	userId, _ := domain.GetUserFromContext(ctx)
+
+	downlinkReq := domain.LoRaWANDownlinkRequest{
+		EndDeviceId:    req.Msg.GetEndDeviceId(),
+		OrganizationId: req.Msg.GetOrganizationId(),
+		RequestedBy:    userId,
+		FPort:          req.Msg.GetFPort(),
+		FrmPayload:     req.Msg.GetFrmPayload(),
+		Confirmed:      req.Msg.GetConfirmed(),
+	}
+	if req.Msg.HasData() {
+		downlinkReq.Data = req.Msg.GetData().AsMap()
+	}
+
+	downlink, err := handler.downlinkManager.ScheduleLoRaWANDownlink(ctx, downlinkReq)
+	if err != nil {
+		return nil, loRaWANDownlinkError(err, downlink)
+	}
+
+	message, err := loRaWANDownlinkToProto(downlink)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	return connect.NewResponse(iotv1.ScheduleDownlinkResponse_builder{
+		Downlink: message,
+	}.Build()), nil
+}
+
+// EndDeviceDownlinks handles RPC requests to list the most recent downlinks of an end device with their status,
+// newest first. A limit outside 1 to 100 returns 100 downlinks.
+// Requires super admin privileges or device read permission in the device's organization.
+func (handler *LoRaWANDownlinkHandler) EndDeviceDownlinks(ctx context.Context, req *connect.Request[iotv1.EndDeviceDownlinksRequest]) (*connect.Response[iotv1.EndDeviceDownlinksResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "EndDeviceDownlinks")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanReadEndDevice, "read end device downlinks")
+	if err != nil {
+		return nil, err
+	}
+
+	downlinks, err := handler.downlinkManager.ListEndDeviceLoRaWANDownlinks(ctx, req.Msg.GetEndDeviceId(), req.Msg.GetOrganizationId(), int(req.Msg.GetLimit()))
+	if err != nil {
+		return nil, loRaWANDownlinkError(err, nil)
+	}
+
+	messages := make([]*iotv1.LoRaWANDownlink, 0, len(downlinks))
+	for _, downlink := range downlinks {
+		message, err := loRaWANDownlinkToProto(downlink)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInternal, err)
+		}
+		messages = append(messages, message)
+	}
+
+	return connect.NewResponse(iotv1.EndDeviceDownlinksResponse_builder{
+		Downlinks: messages,
+	}.Build()), nil
+}
+
+// loRaWANDownlinkToProto converts a LoRaWAN downlink to its iot/v1 message.
+func loRaWANDownlinkToProto(downlink *domain.LoRaWANDownlink) (*iotv1.LoRaWANDownlink, error) {
+	message := iotv1.LoRaWANDownlink_builder{
+		Id:          downlink.Id,
+		EndDeviceId: downlink.EndDeviceId,
+		FPort:       downlink.FPort,
+		FrmPayload:  downlink.FrmPayload,
+		Confirmed:   downlink.Confirmed,
+		Status:      loRaWANDownlinkStatuses[downlink.Status],
+		Error:       downlink.Error,
+		RequestedBy: downlink.RequestedBy,
+		CreatedAt:   timestamppb.New(downlink.CreatedAt),
+		UpdatedAt:   timestamppb.New(downlink.UpdatedAt),
+	}.Build()
+
+	if downlink.Data != nil {
+		data, err := structpb.NewStruct(downlink.Data)
+		if err != nil {
+			return nil, err
+		}
+		message.SetData(data)
+	}
+
+	return message, nil
+}
+
+// loRaWANDownlinkError maps the errors of LoRaWAN downlink operations to Connect errors. When the downlink was
+// recorded but TTN rejected it, the error names the failed downlink.
+func loRaWANDownlinkError(err error, downlink *domain.LoRaWANDownlink) error {
+	switch {
+	case downlink != nil:
+		return connect.NewError(connect.CodeUnavailable, fmt.Errorf("downlink %s could not be queued: %w", downlink.Id, err))
+	case errors.Is(err, domain.ErrEndDeviceNotFound):
+		return connect.NewError(connect.CodeNotFound, err)
+	case errors.Is(err, domain.ErrInvalidLoRaWANDownlink), errors.Is(err, domain.ErrPayloadDecoderFailed):
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	case errors.Is(err, domain.ErrEndDeviceDisabled):
+		return connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("downlink failed: %w", err))
+	default:
+		return err
+	}
+}
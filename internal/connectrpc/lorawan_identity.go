@@ -0,0 +1,18 @@
+package connectrpc
+
+import (
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/ponix-dev/ponix/internal/domain"
+)
+
+// loRaWANIdentityFromRequest reads the factory-assigned LoRaWAN identifiers sent with a new end device.
+// Missing identifiers are left empty so they are allocated or generated.
+func loRaWANIdentityFromRequest(req *iotv1.CreateEndDeviceRequest) domain.LoRaWANIdentity {
+	identity := req.GetLorawanIdentity()
+
+	return domain.LoRaWANIdentity{
+		DeviceEui:      identity.GetDeviceEui(),
+		JoinEui:        identity.GetJoinEui(),
+		ApplicationKey: identity.GetApplicationKey(),
+	}
+}
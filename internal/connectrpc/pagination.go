@@ -0,0 +1,55 @@
+package connectrpc
+
+import (
+	"fmt"
+	"time"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/ponix-dev/ponix/internal/domain"
+)
+
+// endDeviceSortFields maps the sort orders of the iot/v1 API to the sort fields of end device listings.
+var endDeviceSortFields = map[iotv1.EndDeviceSortBy]domain.EndDeviceSortField{
+	iotv1.EndDeviceSortBy_END_DEVICE_SORT_BY_UNSPECIFIED: "",
+	iotv1.EndDeviceSortBy_END_DEVICE_SORT_BY_NAME:        domain.EndDeviceSortByName,
+	iotv1.EndDeviceSortBy_END_DEVICE_SORT_BY_CREATED_AT:  domain.EndDeviceSortByCreatedAt,
+}
+
+// endDeviceListRequest builds an end device listing request from the paging, sorting and filter fields of an
+// OrganizationEndDevices request. With not_seen_for set, only stale devices that have sent no data for that long are listed.
+func endDeviceListRequest(req *iotv1.OrganizationEndDevicesRequest) (domain.EndDeviceListRequest, error) {
+	sortBy, ok := endDeviceSortFields[req.GetSortBy()]
+	if !ok {
+		return domain.EndDeviceListRequest{}, fmt.Errorf("unsupported sort order %v", req.GetSortBy())
+	}
+
+	listReq := domain.EndDeviceListRequest{
+		OrganizationId: req.GetOrganizationId(),
+		SortBy:         sortBy,
+		PageSize:       int(req.GetPageSize()),
+		PageToken:      req.GetPageToken(),
+		Filter: domain.EndDeviceListFilter{
+			Status:         req.GetStatus(),
+			HardwareType:   req.GetHardwareType(),
+			HardwareTypeId: req.GetHardwareTypeId(),
+			NamePrefix:     req.GetNamePrefix(),
+			GroupIds:       req.GetDeviceGroupIds(),
+		},
+	}
+
+	if req.HasNotSeenFor() {
+		notSeenFor := req.GetNotSeenFor().AsDuration()
+		if notSeenFor <= 0 {
+			return listReq, fmt.Errorf("invalid not_seen_for: expected a positive duration, got %s", notSeenFor)
+		}
+		listReq.Filter.NotSeenSince = time.Now().UTC().Add(-notSeenFor)
+	}
+
+	selector, err := parseLabelSelector(req.GetLabelSelector())
+	if err != nil {
+		return listReq, err
+	}
+	listReq.Filter.Labels = selector
+
+	return listReq, nil
+}
@@ -0,0 +1,169 @@
+package connectrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"connectrpc.com/connect"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// payloadCodecs maps the payload codecs of the iot/v1 API to those of payload decoders.
+var payloadCodecs = map[iotv1.PayloadCodec]domain.PayloadCodec{
+	iotv1.PayloadCodec_PAYLOAD_CODEC_JAVASCRIPT:  domain.PayloadCodecJavaScript,
+	iotv1.PayloadCodec_PAYLOAD_CODEC_CAYENNE_LPP: domain.PayloadCodecCayenneLPP,
+	iotv1.PayloadCodec_PAYLOAD_CODEC_BYTE_LAYOUT: domain.PayloadCodecByteLayout,
+}
+
+// payloadCodecMessages maps the payload codecs of payload decoders to those of the iot/v1 API.
+var payloadCodecMessages = map[domain.PayloadCodec]iotv1.PayloadCodec{
+	domain.PayloadCodecJavaScript: iotv1.PayloadCodec_PAYLOAD_CODEC_JAVASCRIPT,
+	domain.PayloadCodecCayenneLPP: iotv1.PayloadCodec_PAYLOAD_CODEC_CAYENNE_LPP,
+	domain.PayloadCodecByteLayout: iotv1.PayloadCodec_PAYLOAD_CODEC_BYTE_LAYOUT,
+}
+
+// payloadEndiannesses maps the byte orders of the iot/v1 API to those of byte layout fields. Unspecified leaves
+// the field to the default big endian order.
+var payloadEndiannesses = map[iotv1.PayloadEndianness]domain.PayloadEndianness{
+	iotv1.PayloadEndianness_PAYLOAD_ENDIANNESS_UNSPECIFIED: "",
+	iotv1.PayloadEndianness_PAYLOAD_ENDIANNESS_BIG:         domain.PayloadBigEndian,
+	iotv1.PayloadEndianness_PAYLOAD_ENDIANNESS_LITTLE:      domain.PayloadLittleEndian,
+}
+
+// payloadEndiannessMessages maps the byte orders of byte layout fields to those of the iot/v1 API.
+var payloadEndiannessMessages = map[domain.PayloadEndianness]iotv1.PayloadEndianness{
+	domain.PayloadBigEndian:    iotv1.PayloadEndianness_PAYLOAD_ENDIANNESS_BIG,
+	domain.PayloadLittleEndian: iotv1.PayloadEndianness_PAYLOAD_ENDIANNESS_LITTLE,
+}
+
+// TestPayloadDecoder handles RPC requests to run a payload decoder over a sample uplink without storing anything.
+// The decoder sent in payload_decoder is run, or the one of the LoRaWAN hardware type named in hardware_type_id.
+// The uplink's port defaults to 1.
+// Requires super admin privileges or hardware type read permission in the organization.
+func (handler *LoRaWANHandler) TestPayloadDecoder(ctx context.Context, req *connect.Request[iotv1.TestPayloadDecoderRequest]) (*connect.Response[iotv1.TestPayloadDecoderResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "TestPayloadDecoder")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanReadLoRaWANHardwareType, "read LoRaWAN hardware types")
+	if err != nil {
+		return nil, err
+	}
+
+	var decoder *domain.PayloadDecoder
+	if req.Msg.HasPayloadDecoder() {
+		decoder, err = payloadDecoderFromProto(req.Msg.GetPayloadDecoder())
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+	} else if req.Msg.GetHardwareTypeId() != "" {
+		decoder, err = handler.payloadDecoderManager.GetLoRaWANHardwareTypePayloadDecoder(ctx, req.Msg.GetHardwareTypeId())
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInternal, err)
+		}
+	}
+
+	if decoder == nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("no payload decoder to test: send one or name a hardware type that has one"))
+	}
+
+	fPort := req.Msg.GetFPort()
+	if fPort == 0 {
+		fPort = 1
+	}
+
+	output, err := handler.payloadDecoderManager.TestPayloadDecoder(ctx, *decoder, domain.PayloadDecoderInput{
+		Bytes:      req.Msg.GetBytes(),
+		FPort:      fPort,
+		ReceivedAt: time.Now(),
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidPayloadDecoder) {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	data, err := structpb.NewStruct(output.Data)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	return connect.NewResponse(iotv1.TestPayloadDecoderResponse_builder{
+		Data:     data,
+		Warnings: output.Warnings,
+		Errors:   output.Errors,
+	}.Build()), nil
+}
+
+// payloadDecoderFromProto converts the payload decoder of a request. The codec defaults to byte_layout when a
+// layout is sent and to javascript otherwise. An empty message returns nil, which removes a decoder.
+func payloadDecoderFromProto(message *iotv1.PayloadDecoder) (*domain.PayloadDecoder, error) {
+	if message.GetCodec() == iotv1.PayloadCodec_PAYLOAD_CODEC_UNSPECIFIED && message.GetScript() == "" && len(message.GetLayout()) == 0 {
+		return nil, nil
+	}
+
+	decoder := &domain.PayloadDecoder{
+		Codec:  domain.PayloadCodecJavaScript,
+		Script: message.GetScript(),
+	}
+
+	for _, field := range message.GetLayout() {
+		endianness, ok := payloadEndiannesses[field.GetEndianness()]
+		if !ok {
+			return nil, fmt.Errorf("unsupported endianness %v of field %q", field.GetEndianness(), field.GetField())
+		}
+
+		decoder.Layout = append(decoder.Layout, domain.PayloadLayoutField{
+			Field:      field.GetField(),
+			Offset:     int(field.GetOffset()),
+			Length:     int(field.GetLength()),
+			Endianness: endianness,
+			Signed:     field.GetSigned(),
+			Scale:      field.GetScale(),
+		})
+	}
+
+	if len(decoder.Layout) > 0 {
+		decoder.Codec = domain.PayloadCodecByteLayout
+	}
+
+	if message.GetCodec() != iotv1.PayloadCodec_PAYLOAD_CODEC_UNSPECIFIED {
+		codec, ok := payloadCodecs[message.GetCodec()]
+		if !ok {
+			return nil, fmt.Errorf("unsupported payload codec %v", message.GetCodec())
+		}
+		decoder.Codec = codec
+	}
+
+	return decoder, nil
+}
+
+// payloadDecoderToProto converts a payload decoder to its iot/v1 message. It returns nil when there is none.
+func payloadDecoderToProto(decoder *domain.PayloadDecoder) *iotv1.PayloadDecoder {
+	if decoder == nil {
+		return nil
+	}
+
+	layout := make([]*iotv1.PayloadLayoutField, 0, len(decoder.Layout))
+	for _, field := range decoder.Layout {
+		layout = append(layout, iotv1.PayloadLayoutField_builder{
+			Field:      field.Field,
+			Offset:     int32(field.Offset),
+			Length:     int32(field.Length),
+			Endianness: payloadEndiannessMessages[field.Endianness],
+			Signed:     field.Signed,
+			Scale:      field.Scale,
+		}.Build())
+	}
+
+	return iotv1.PayloadDecoder_builder{
+		Codec:  payloadCodecMessages[decoder.Codec],
+		Script: decoder.Script,
+		Layout: layout,
+	}.Build()
+}
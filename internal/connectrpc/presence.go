@@ -0,0 +1,53 @@
+package connectrpc
+
+import (
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// setEndDevicePresence fills in the connectivity summary of an end device.
+// Devices that have never sent data are left without one.
+func setEndDevicePresence(endDevice *iotv1.EndDevice, presence *domain.EndDevicePresence) error {
+	if presence == nil {
+		return nil
+	}
+
+	message := iotv1.EndDevicePresence_builder{
+		LastSeenAt:          timestamppb.New(presence.LastSeenAt),
+		MessageCountLastDay: presence.MessageCount24h,
+	}.Build()
+
+	if !presence.LastOccurredAt.IsZero() {
+		message.SetLastOccurredAt(timestamppb.New(presence.LastOccurredAt))
+	}
+
+	if !presence.LastProcessedAt.IsZero() {
+		message.SetLastProcessedAt(timestamppb.New(presence.LastProcessedAt))
+	}
+
+	if len(presence.LastValues) > 0 {
+		lastValues, err := structpb.NewStruct(presence.LastValues)
+		if err != nil {
+			return err
+		}
+		message.SetLastValues(lastValues)
+	}
+
+	endDevice.SetPresence(message)
+
+	return nil
+}
+
+// setEndDevicePresences fills in the connectivity summary of a page of end devices from their presence keyed by ID.
+func setEndDevicePresences(endDevices []*iotv1.EndDevice, presence map[string]*domain.EndDevicePresence) error {
+	for _, endDevice := range endDevices {
+		err := setEndDevicePresence(endDevice, presence[endDevice.GetId()])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package connectrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"connectrpc.com/connect"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// RootKeyRotationManager handles the replacement of LoRaWAN end device root keys.
+type RootKeyRotationManager interface {
+	RotateEndDeviceRootKeys(ctx context.Context, endDeviceId string, organizationId string, keys domain.RootKeys, reason string) (*domain.RootKeyRotation, error)
+	ListRootKeyRotations(ctx context.Context, endDeviceId string, organizationId string) ([]*domain.RootKeyRotation, error)
+}
+
+// RootKeyAuthorizer checks permissions for root key operations. Root keys belong to their end device, so the
+// end device permissions apply.
+type RootKeyAuthorizer interface {
+	CanReadEndDevice(ctx context.Context, userId string, organizationId string) (bool, error)
+	CanUpdateEndDevice(ctx context.Context, userId string, organizationId string) (bool, error)
+}
+
+// RootKeyHandler implements Connect RPC handlers for LoRaWAN root key operations.
+type RootKeyHandler struct {
+	rotationManager RootKeyRotationManager
+	authorizer      RootKeyAuthorizer
+}
+
+// NewRootKeyHandler creates a new RootKeyHandler with the provided dependencies.
+func NewRootKeyHandler(rotationMgr RootKeyRotationManager, authorizer RootKeyAuthorizer) *RootKeyHandler {
+	return &RootKeyHandler{
+		rotationManager: rotationMgr,
+		authorizer:      authorizer,
+	}
+}
+
+// RotateEndDeviceRootKeys handles RPC requests to replace the root keys of a LoRaWAN end device, for example after
+// they leaked. Keys that are not given are generated. The new keys are pushed to the TTN Join Server and the device
+// is moved back to PENDING until its next uplink shows it re-joined.
+// Requires super admin privileges or device update permission in the device's organization.
+func (handler *RootKeyHandler) RotateEndDeviceRootKeys(ctx context.Context, req *connect.Request[iotv1.RotateEndDeviceRootKeysRequest]) (*connect.Response[iotv1.RotateEndDeviceRootKeysResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "RotateEndDeviceRootKeys")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanUpdateEndDevice, "update end devices")
+	if err != nil {
+		return nil, err
+	}
+
+	rotation, err := handler.rotationManager.RotateEndDeviceRootKeys(ctx, req.Msg.GetEndDeviceId(), req.Msg.GetOrganizationId(), domain.RootKeys{
+		ApplicationKey: req.Msg.GetApplicationKey(),
+		NetworkKey:     req.Msg.GetNetworkKey(),
+	}, req.Msg.GetReason())
+	if err != nil {
+		return nil, rootKeyError(err, req.Msg.GetEndDeviceId())
+	}
+
+	return connect.NewResponse(iotv1.RotateEndDeviceRootKeysResponse_builder{
+		Rotation: rootKeyRotationToProto(rotation),
+	}.Build()), nil
+}
+
+// EndDeviceRootKeyRotations handles RPC requests to list the root key rotation history of an end device, most
+// recent first. The keys themselves are never returned.
+// Requires super admin privileges or device read permission in the device's organization.
+func (handler *RootKeyHandler) EndDeviceRootKeyRotations(ctx context.Context, req *connect.Request[iotv1.EndDeviceRootKeyRotationsRequest]) (*connect.Response[iotv1.EndDeviceRootKeyRotationsResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "EndDeviceRootKeyRotations")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanReadEndDevice, "read end devices")
+	if err != nil {
+		return nil, err
+	}
+
+	rotations, err := handler.rotationManager.ListRootKeyRotations(ctx, req.Msg.GetEndDeviceId(), req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, rootKeyError(err, req.Msg.GetEndDeviceId())
+	}
+
+	messages := make([]*iotv1.RootKeyRotation, 0, len(rotations))
+	for _, rotation := range rotations {
+		messages = append(messages, rootKeyRotationToProto(rotation))
+	}
+
+	return connect.NewResponse(iotv1.EndDeviceRootKeyRotationsResponse_builder{
+		Rotations: messages,
+	}.Build()), nil
+}
+
+// rootKeyRotationToProto converts a root key rotation to its iot/v1 message.
+func rootKeyRotationToProto(rotation *domain.RootKeyRotation) *iotv1.RootKeyRotation {
+	return iotv1.RootKeyRotation_builder{
+		Id:           rotation.Id,
+		EndDeviceId:  rotation.EndDeviceId,
+		Reason:       rotation.Reason,
+		KeysSupplied: rotation.KeysSupplied,
+		RotatedAt:    timestamppb.New(rotation.RotatedAt),
+	}.Build()
+}
+
+// rootKeyError maps the errors of root key operations to Connect errors.
+func rootKeyError(err error, endDeviceId string) error {
+	switch {
+	case errors.Is(err, domain.ErrEndDeviceNotFound):
+		return connect.NewError(connect.CodeNotFound, fmt.Errorf("end device %s not found", endDeviceId))
+	case errors.Is(err, domain.ErrInvalidRootKey):
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	case errors.Is(err, domain.ErrRootKeysUnsupported), errors.Is(err, domain.ErrEndDeviceDisabled):
+		return connect.NewError(connect.CodeFailedPrecondition, err)
+	default:
+		return err
+	}
+}
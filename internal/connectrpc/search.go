@@ -0,0 +1,89 @@
+package connectrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"connectrpc.com/connect"
+	"github.com/ponix-dev/ponix/internal/domain"
+)
+
+// searchEndDevices answers an OrganizationEndDevices request carrying a query. The organization of the request has
+// already been authorized; further organizations from search_organization_ids are checked here.
+func (handler *EndDeviceHandler) searchEndDevices(ctx context.Context, userId string, req *iotv1.OrganizationEndDevicesRequest) (*connect.Response[iotv1.OrganizationEndDevicesResponse], error) {
+	organizationIds := searchOrganizationIds(req)
+	if !domain.IsSuperAdminFromContext(ctx) {
+		for _, organizationId := range organizationIds[1:] {
+			can, err := handler.authorizer.CanReadEndDevice(ctx, userId, organizationId)
+			if err != nil {
+				return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("authorization check failed: %w", err))
+			}
+
+			if !can {
+				return nil, connect.NewError(connect.CodePermissionDenied, fmt.Errorf("user %s not authorized to read end devices in organization %s", userId, organizationId))
+			}
+		}
+	}
+
+	searchReq := domain.EndDeviceSearchRequest{
+		Query:           req.GetQuery(),
+		OrganizationIds: organizationIds,
+		PageSize:        int(req.GetPageSize()),
+		PageToken:       req.GetPageToken(),
+	}
+
+	page, err := handler.endDeviceManager.SearchEndDevices(ctx, searchReq)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidPageToken) || errors.Is(err, domain.ErrInvalidMessageFormat) {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+		return nil, err
+	}
+
+	endDevices := make([]*iotv1.EndDevice, 0, len(page.Results))
+	endDeviceIds := make([]string, 0, len(page.Results))
+	results := make([]*iotv1.EndDeviceSearchResult, 0, len(page.Results))
+	for _, result := range page.Results {
+		endDevices = append(endDevices, result.EndDevice)
+		endDeviceIds = append(endDeviceIds, result.EndDevice.GetId())
+		results = append(results, iotv1.EndDeviceSearchResult_builder{
+			EndDeviceId:    result.EndDevice.GetId(),
+			OrganizationId: result.OrganizationId,
+			Rank:           result.Rank,
+		}.Build())
+	}
+
+	presence, err := handler.presence.GetEndDevicePresence(ctx, endDeviceIds...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setEndDevicePresences(endDevices, presence)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	return connect.NewResponse(iotv1.OrganizationEndDevicesResponse_builder{
+		EndDevices:    endDevices,
+		NextPageToken: page.NextPageToken,
+		SearchResults: results,
+	}.Build()), nil
+}
+
+// searchOrganizationIds lists the organizations to search: the organization of the request first, followed by
+// those of search_organization_ids without blanks or duplicates.
+func searchOrganizationIds(req *iotv1.OrganizationEndDevicesRequest) []string {
+	organizationIds := []string{req.GetOrganizationId()}
+	for _, id := range req.GetSearchOrganizationIds() {
+		id = strings.TrimSpace(id)
+		if id != "" && !slices.Contains(organizationIds, id) {
+			organizationIds = append(organizationIds, id)
+		}
+	}
+
+	return organizationIds
+}
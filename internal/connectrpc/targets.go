@@ -0,0 +1,19 @@
+package connectrpc
+
+import (
+	"github.com/ponix-dev/ponix/internal/domain"
+)
+
+// endDeviceTargets builds the group and label selection of an RPC from the device_group_ids and label_selector
+// fields of its request. Explicit end device IDs come from the request message and are added by the caller.
+func endDeviceTargets(labelSelector string, groupIds []string) (domain.EndDeviceTargets, error) {
+	selector, err := parseLabelSelector(labelSelector)
+	if err != nil {
+		return domain.EndDeviceTargets{}, err
+	}
+
+	return domain.EndDeviceTargets{
+		GroupIds: groupIds,
+		Labels:   selector,
+	}, nil
+}
@@ -0,0 +1,150 @@
+package connectrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"connectrpc.com/connect"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// EndDeviceTwinManager handles the operator side of end device twins.
+type EndDeviceTwinManager interface {
+	GetEndDeviceTwin(ctx context.Context, endDeviceId string, organizationId string) (*domain.EndDeviceTwin, error)
+	SetEndDeviceTwinDesired(ctx context.Context, endDeviceId string, organizationId string, desired map[string]any, expectedVersion int64) (*domain.EndDeviceTwin, error)
+}
+
+// EndDeviceTwinAuthorizer checks permissions for end device twin operations.
+type EndDeviceTwinAuthorizer interface {
+	CanReadEndDevice(ctx context.Context, userId string, organizationId string) (bool, error)
+	CanUpdateEndDevice(ctx context.Context, userId string, organizationId string) (bool, error)
+}
+
+// EndDeviceTwinHandler implements Connect RPC handlers for end device twin operations.
+type EndDeviceTwinHandler struct {
+	twinManager EndDeviceTwinManager
+	authorizer  EndDeviceTwinAuthorizer
+}
+
+// NewEndDeviceTwinHandler creates a new EndDeviceTwinHandler with the provided dependencies.
+func NewEndDeviceTwinHandler(twinMgr EndDeviceTwinManager, authorizer EndDeviceTwinAuthorizer) *EndDeviceTwinHandler {
+	return &EndDeviceTwinHandler{
+		twinManager: twinMgr,
+		authorizer:  authorizer,
+	}
+}
+
+// EndDeviceTwin handles RPC requests to retrieve the desired and reported state of an end device, along with the
+// desired values the device has not reported yet.
+// Requires super admin privileges or device read permission in the organization.
+func (handler *EndDeviceTwinHandler) EndDeviceTwin(ctx context.Context, req *connect.Request[iotv1.EndDeviceTwinRequest]) (*connect.Response[iotv1.EndDeviceTwinResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "EndDeviceTwin")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanReadEndDevice, "read end devices")
+	if err != nil {
+		return nil, err
+	}
+
+	twin, err := handler.twinManager.GetEndDeviceTwin(ctx, req.Msg.GetEndDeviceId(), req.Msg.GetOrganizationId())
+	if err != nil {
+		return nil, endDeviceTwinError(err, req.Msg.GetEndDeviceId())
+	}
+
+	message, err := endDeviceTwinToProto(twin)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	return connect.NewResponse(iotv1.EndDeviceTwinResponse_builder{
+		Twin: message,
+	}.Build()), nil
+}
+
+// SetEndDeviceTwinDesired handles RPC requests to replace the desired state of an end device. expected_version
+// must be the desired version the caller last read; a stale version fails with Aborted.
+// Requires super admin privileges or device update permission in the organization.
+func (handler *EndDeviceTwinHandler) SetEndDeviceTwinDesired(ctx context.Context, req *connect.Request[iotv1.SetEndDeviceTwinDesiredRequest]) (*connect.Response[iotv1.SetEndDeviceTwinDesiredResponse], error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "SetEndDeviceTwinDesired")
+	defer span.End()
+
+	err := authorize(ctx, req.Msg.GetOrganizationId(), handler.authorizer.CanUpdateEndDevice, "update end devices")
+	if err != nil {
+		return nil, err
+	}
+
+	var desired map[string]any
+	if req.Msg.HasDesired() {
+		desired = req.Msg.GetDesired().AsMap()
+	}
+
+	twin, err := handler.twinManager.SetEndDeviceTwinDesired(ctx, req.Msg.GetEndDeviceId(), req.Msg.GetOrganizationId(), desired, req.Msg.GetExpectedVersion())
+	if err != nil {
+		return nil, endDeviceTwinError(err, req.Msg.GetEndDeviceId())
+	}
+
+	message, err := endDeviceTwinToProto(twin)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	return connect.NewResponse(iotv1.SetEndDeviceTwinDesiredResponse_builder{
+		Twin: message,
+	}.Build()), nil
+}
+
+// endDeviceTwinToProto converts an end device twin to its iot/v1 message, including its delta.
+func endDeviceTwinToProto(twin *domain.EndDeviceTwin) (*iotv1.EndDeviceTwin, error) {
+	desired, err := structpb.NewStruct(twin.Desired)
+	if err != nil {
+		return nil, err
+	}
+
+	reported, err := structpb.NewStruct(twin.Reported)
+	if err != nil {
+		return nil, err
+	}
+
+	delta, err := structpb.NewStruct(twin.Delta())
+	if err != nil {
+		return nil, err
+	}
+
+	message := iotv1.EndDeviceTwin_builder{
+		EndDeviceId:     twin.EndDeviceId,
+		Desired:         desired,
+		DesiredVersion:  twin.DesiredVersion,
+		Reported:        reported,
+		ReportedVersion: twin.ReportedVersion,
+		Delta:           delta,
+	}.Build()
+
+	if !twin.DesiredUpdatedAt.IsZero() {
+		message.SetDesiredUpdatedAt(timestamppb.New(twin.DesiredUpdatedAt))
+	}
+
+	if !twin.ReportedUpdatedAt.IsZero() {
+		message.SetReportedUpdatedAt(timestamppb.New(twin.ReportedUpdatedAt))
+	}
+
+	return message, nil
+}
+
+// endDeviceTwinError maps the errors of end device twin operations to Connect errors.
+func endDeviceTwinError(err error, endDeviceId string) error {
+	switch {
+	case errors.Is(err, domain.ErrEndDeviceNotFound):
+		return connect.NewError(connect.CodeNotFound, fmt.Errorf("end device %s not found", endDeviceId))
+	case errors.Is(err, domain.ErrTwinVersionConflict):
+		return connect.NewError(connect.CodeAborted, err)
+	case errors.Is(err, domain.ErrInvalidTwinDocument):
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,131 @@
+package domain
+
+import (
+	"fmt"
+
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// cayenneLPPValue is a value of a Cayenne LPP data type: its name within the type, size in bytes, whether it is
+// signed and what it is divided by.
+type cayenneLPPValue struct {
+	name    string
+	size    int
+	signed  bool
+	divisor float64
+}
+
+// cayenneLPPType is a Cayenne LPP data type. Types with a single unnamed value decode to a number, the others to
+// an object holding their values.
+type cayenneLPPType struct {
+	name   string
+	values []cayenneLPPValue
+}
+
+// size returns the number of bytes the type's values span.
+func (dataType cayenneLPPType) size() int {
+	size := 0
+	for _, value := range dataType.values {
+		size += value.size
+	}
+
+	return size
+}
+
+// cayenneLPPScalar is a Cayenne LPP data type holding a single value.
+func cayenneLPPScalar(name string, size int, signed bool, divisor float64) cayenneLPPType {
+	return cayenneLPPType{name: name, values: []cayenneLPPValue{{size: size, signed: signed, divisor: divisor}}}
+}
+
+// cayenneLPPVector is a Cayenne LPP data type holding values of the same size and resolution, such as the axes of
+// an accelerometer.
+func cayenneLPPVector(name string, size int, signed bool, divisor float64, names ...string) cayenneLPPType {
+	dataType := cayenneLPPType{name: name}
+	for _, valueName := range names {
+		dataType.values = append(dataType.values, cayenneLPPValue{name: valueName, size: size, signed: signed, divisor: divisor})
+	}
+
+	return dataType
+}
+
+// cayenneLPPTypes are the Cayenne LPP data types by type byte: those of the Cayenne LPP specification, named the
+// way The Things Stack names them, and the common extensions of the Electronic Cats CayenneLPP library.
+var cayenneLPPTypes = map[byte]cayenneLPPType{
+	0:   cayenneLPPScalar("digital_in", 1, false, 1),
+	1:   cayenneLPPScalar("digital_out", 1, false, 1),
+	2:   cayenneLPPScalar("analog_in", 2, true, 100),
+	3:   cayenneLPPScalar("analog_out", 2, true, 100),
+	100: cayenneLPPScalar("generic_sensor", 4, false, 1),
+	101: cayenneLPPScalar("luminosity", 2, false, 1),
+	102: cayenneLPPScalar("presence", 1, false, 1),
+	103: cayenneLPPScalar("temperature", 2, true, 10),
+	104: cayenneLPPScalar("relative_humidity", 1, false, 2),
+	113: cayenneLPPVector("accelerometer", 2, true, 1000, "x", "y", "z"),
+	115: cayenneLPPScalar("barometric_pressure", 2, false, 10),
+	116: cayenneLPPScalar("voltage", 2, false, 100),
+	117: cayenneLPPScalar("current", 2, false, 1000),
+	118: cayenneLPPScalar("frequency", 4, false, 1),
+	120: cayenneLPPScalar("percentage", 1, false, 1),
+	121: cayenneLPPScalar("altitude", 2, true, 1),
+	125: cayenneLPPScalar("concentration", 2, false, 1),
+	128: cayenneLPPScalar("power", 2, false, 1),
+	130: cayenneLPPScalar("distance", 4, false, 1000),
+	131: cayenneLPPScalar("energy", 4, false, 1000),
+	132: cayenneLPPScalar("direction", 2, false, 1),
+	133: cayenneLPPScalar("unix_time", 4, false, 1),
+	134: cayenneLPPVector("gyrometer", 2, true, 100, "x", "y", "z"),
+	135: cayenneLPPVector("colour", 1, false, 1, "r", "g", "b"),
+	136: {name: "gps", values: []cayenneLPPValue{
+		{name: "latitude", size: 3, signed: true, divisor: 10000},
+		{name: "longitude", size: 3, signed: true, divisor: 10000},
+		{name: "altitude", size: 3, signed: true, divisor: 100},
+	}},
+	142: cayenneLPPScalar("switch", 1, false, 1),
+}
+
+// decodeCayenneLPP decodes a Cayenne Low Power Payload: a sequence of channel, type and value. Values are stored
+// under their type's name and channel, such as temperature_1. Unknown types and truncated values are reported as
+// ErrPayloadDecoderFailed.
+func decodeCayenneLPP(payload []byte) (map[string]any, error) {
+	data := map[string]any{}
+
+	for offset := 0; offset < len(payload); {
+		if offset+2 > len(payload) {
+			return nil, stacktrace.NewStackTraceErrorf("%w: cayenne lpp: value at byte %d has no type", ErrPayloadDecoderFailed, offset)
+		}
+
+		channel, typeByte := payload[offset], payload[offset+1]
+		offset += 2
+
+		dataType, ok := cayenneLPPTypes[typeByte]
+		if !ok {
+			return nil, stacktrace.NewStackTraceErrorf("%w: cayenne lpp: channel %d has unknown type %d", ErrPayloadDecoderFailed, channel, typeByte)
+		}
+
+		if offset+dataType.size() > len(payload) {
+			return nil, stacktrace.NewStackTraceErrorf("%w: cayenne lpp: %s on channel %d needs %d bytes, %d left", ErrPayloadDecoderFailed, dataType.name, channel, dataType.size(), len(payload)-offset)
+		}
+
+		values := map[string]any{}
+		var value float64
+		for _, valueType := range dataType.values {
+			raw := readPayloadUint(payload[offset:offset+valueType.size], false)
+			offset += valueType.size
+
+			value = float64(raw) / valueType.divisor
+			if valueType.signed {
+				value = float64(signPayloadUint(raw, valueType.size)) / valueType.divisor
+			}
+			values[valueType.name] = value
+		}
+
+		key := fmt.Sprintf("%s_%d", dataType.name, channel)
+		if len(dataType.values) == 1 {
+			data[key] = value
+		} else {
+			data[key] = values
+		}
+	}
+
+	return data, nil
+}
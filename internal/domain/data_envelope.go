@@ -2,9 +2,11 @@ package domain
 
 import (
 	"context"
+	"log/slog"
 	"time"
 
 	envelopev1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/envelope/v1"
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
 	"github.com/ponix-dev/ponix/internal/telemetry"
 	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -20,11 +22,23 @@ type ProcessedEnvelopeStorer interface {
 	StoreProcessedEnvelopes(ctx context.Context, envelope ...*envelopev1.ProcessedEnvelope) error
 }
 
+// EndDeviceActivityRecorder records data received from end devices so their status can follow their traffic.
+type EndDeviceActivityRecorder interface {
+	RecordEndDeviceActivity(ctx context.Context, endDevice *iotv1.EndDevice, seenAt time.Time) error
+}
+
+// EndDevicePresenceRecorder folds processed envelopes into the connectivity summary of their end devices.
+type EndDevicePresenceRecorder interface {
+	RecordProcessedEnvelopes(ctx context.Context, envelopes ...*envelopev1.ProcessedEnvelope) error
+}
+
 // DataEnvelopeManager orchestrates the ingestion and processing of data envelopes.
 type DataEnvelopeManager struct {
-	producer       ProcessedEnvelopeProducer
-	store          ProcessedEnvelopeStorer
-	endDeviceStore EndDeviceStorer
+	producer         ProcessedEnvelopeProducer
+	store            ProcessedEnvelopeStorer
+	endDeviceStore   EndDeviceStorer
+	activityRecorder EndDeviceActivityRecorder
+	presenceRecorder EndDevicePresenceRecorder
 }
 
 // NewDataEnvelopeManager creates a new instance of DataEnvelopeService with the provided producer and store.
@@ -32,22 +46,27 @@ func NewDataEnvelopeManager(
 	producer ProcessedEnvelopeProducer,
 	store ProcessedEnvelopeStorer,
 	endDeviceStore EndDeviceStorer,
+	activityRecorder EndDeviceActivityRecorder,
+	presenceRecorder EndDevicePresenceRecorder,
 ) *DataEnvelopeManager {
 	return &DataEnvelopeManager{
-		producer:       producer,
-		store:          store,
-		endDeviceStore: endDeviceStore,
+		producer:         producer,
+		store:            store,
+		endDeviceStore:   endDeviceStore,
+		activityRecorder: activityRecorder,
+		presenceRecorder: presenceRecorder,
 	}
 }
 
 // IngestDataEnvelope receives a raw data envelope, adds processing metadata, and publishes it to the producer.
-// The organizationID parameter identifies which organization owns the data.
+// The organizationID parameter identifies which organization owns the data. Envelopes for disabled devices are
+// rejected with ErrEndDeviceDisabled, and accepted envelopes mark the device as active.
 func (mgr *DataEnvelopeManager) IngestDataEnvelope(ctx context.Context, envelope *envelopev1.DataEnvelope, organizationID string) error {
 	ctx, span := telemetry.Tracer().Start(ctx, "IngestDataEnvelope")
 	defer span.End()
 
 	// VALIDATION: Verify device exists and belongs to organization
-	_, deviceOrgID, err := mgr.endDeviceStore.GetEndDeviceWithOrganization(ctx, envelope.GetEndDeviceId())
+	endDevice, deviceOrgID, err := mgr.endDeviceStore.GetEndDeviceWithOrganization(ctx, envelope.GetEndDeviceId())
 	if err != nil {
 		return err
 	}
@@ -61,13 +80,19 @@ func (mgr *DataEnvelopeManager) IngestDataEnvelope(ctx context.Context, envelope
 		)
 	}
 
+	if endDevice.GetStatus() == iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED {
+		return stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceDisabled, envelope.GetEndDeviceId())
+	}
+
+	processedAt := time.Now().UTC()
+
 	// Build ProcessedEnvelope with validation complete
 	processedEnvelope := envelopev1.ProcessedEnvelope_builder{
 		OrganizationId: organizationID,
 		EndDeviceId:    envelope.GetEndDeviceId(),
 		OccurredAt:     envelope.GetOccurredAt(),
 		Data:           envelope.GetData(),
-		ProcessedAt:    timestamppb.New(time.Now().UTC()),
+		ProcessedAt:    timestamppb.New(processedAt),
 	}.Build()
 
 	// Publish to NATS
@@ -76,10 +101,21 @@ func (mgr *DataEnvelopeManager) IngestDataEnvelope(ctx context.Context, envelope
 		return stacktrace.NewStackTraceError(err)
 	}
 
+	// The envelope is already published, so a failure here must not make the sender retry it
+	err = mgr.activityRecorder.RecordEndDeviceActivity(ctx, endDevice, processedAt)
+	if err != nil {
+		slog.Error(
+			"failed to record end device activity",
+			slog.String("end_device_id", envelope.GetEndDeviceId()),
+			stacktrace.ErrorAttribute(err),
+		)
+	}
+
 	return nil
 }
 
-// IngestProcessedEnvelope receives a processed envelope and persists it via the writer.
+// IngestProcessedEnvelope receives a processed envelope and persists it via the writer, then updates the
+// presence of the envelopes' end devices.
 // This is typically called by a message consumer after receiving from the producer.
 func (mgr *DataEnvelopeManager) IngestProcessedEnvelope(ctx context.Context, envelopes ...*envelopev1.ProcessedEnvelope) error {
 	ctx, span := telemetry.Tracer().Start(ctx, "IngestProcessedEnvelope")
@@ -90,5 +126,11 @@ func (mgr *DataEnvelopeManager) IngestProcessedEnvelope(ctx context.Context, env
 		return err
 	}
 
+	// The envelopes are already stored, so a failure here must not cause them to be redelivered
+	err = mgr.presenceRecorder.RecordProcessedEnvelopes(ctx, envelopes...)
+	if err != nil {
+		slog.Error("failed to record end device presence", stacktrace.ErrorAttribute(err))
+	}
+
 	return nil
 }
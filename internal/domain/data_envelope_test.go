@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	envelopev1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/envelope/v1"
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestDataEnvelopeManager_IngestDataEnvelope(t *testing.T) {
+	newManager := func(status iotv1.EndDeviceStatus) (*DataEnvelopeManager, *memoryEndDeviceStatusStore, *recordingEnvelopeProducer) {
+		endDevices := newMemoryEndDeviceStore()
+		endDevice := testLoRaWANEndDevice("device-1", "soil probe")
+		endDevice.SetStatus(status)
+		endDevices.put(endDevice, "org-1")
+
+		statusStore := newMemoryEndDeviceStatusStore(endDevices)
+		statusMgr := NewEndDeviceStatusManager(statusStore, endDevices, func() string { return "transition-1" }, time.Hour)
+		producer := &recordingEnvelopeProducer{}
+		return NewDataEnvelopeManager(producer, nil, endDevices, statusMgr, nil), statusStore, producer
+	}
+
+	envelope := func() *envelopev1.DataEnvelope {
+		data, _ := structpb.NewStruct(map[string]any{"moisture": 31.5})
+		return envelopev1.DataEnvelope_builder{
+			EndDeviceId: "device-1",
+			OccurredAt:  timestamppb.New(time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)),
+			Data:        data,
+		}.Build()
+	}
+
+	t.Run("publishes the envelope and activates a pending device", func(t *testing.T) {
+		mgr, statusStore, producer := newManager(iotv1.EndDeviceStatus_END_DEVICE_STATUS_PENDING)
+
+		err := mgr.IngestDataEnvelope(context.Background(), envelope(), "org-1")
+
+		assert.NoError(t, err)
+		if assert.Len(t, producer.produced, 1) {
+			assert.Equal(t, "org-1", producer.produced[0].GetOrganizationId())
+			assert.Equal(t, "device-1", producer.produced[0].GetEndDeviceId())
+		}
+		if assert.Len(t, statusStore.transitions, 1) {
+			assert.Equal(t, iotv1.EndDeviceStatus_END_DEVICE_STATUS_PENDING, statusStore.transitions[0].From)
+			assert.Equal(t, iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE, statusStore.transitions[0].To)
+			assert.Equal(t, StatusReasonFirstEnvelope, statusStore.transitions[0].Reason)
+		}
+		assert.Contains(t, statusStore.seenAt, "device-1")
+	})
+
+	t.Run("reactivates an inactive device", func(t *testing.T) {
+		mgr, statusStore, _ := newManager(iotv1.EndDeviceStatus_END_DEVICE_STATUS_INACTIVE)
+
+		err := mgr.IngestDataEnvelope(context.Background(), envelope(), "org-1")
+
+		assert.NoError(t, err)
+		if assert.Len(t, statusStore.transitions, 1) {
+			assert.Equal(t, StatusReasonTrafficResumed, statusStore.transitions[0].Reason)
+		}
+	})
+
+	t.Run("only records presence for an active device", func(t *testing.T) {
+		mgr, statusStore, producer := newManager(iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE)
+
+		err := mgr.IngestDataEnvelope(context.Background(), envelope(), "org-1")
+
+		assert.NoError(t, err)
+		assert.Len(t, producer.produced, 1)
+		assert.Empty(t, statusStore.transitions)
+		assert.Contains(t, statusStore.seenAt, "device-1")
+	})
+
+	t.Run("rejects envelopes of disabled devices", func(t *testing.T) {
+		mgr, statusStore, producer := newManager(iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED)
+
+		err := mgr.IngestDataEnvelope(context.Background(), envelope(), "org-1")
+
+		assert.ErrorIs(t, err, ErrEndDeviceDisabled)
+		assert.Empty(t, producer.produced)
+		assert.Empty(t, statusStore.transitions)
+		assert.NotContains(t, statusStore.seenAt, "device-1")
+	})
+
+	t.Run("rejects envelopes sent for another organization", func(t *testing.T) {
+		mgr, statusStore, producer := newManager(iotv1.EndDeviceStatus_END_DEVICE_STATUS_PENDING)
+
+		err := mgr.IngestDataEnvelope(context.Background(), envelope(), "org-2")
+
+		assert.Error(t, err)
+		assert.Empty(t, producer.produced)
+		assert.Empty(t, statusStore.transitions)
+	})
+}
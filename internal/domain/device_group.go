@@ -0,0 +1,227 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+var (
+	// ErrDeviceGroupNotFound is returned when a device group does not exist or is not visible to the requesting organization.
+	ErrDeviceGroupNotFound = errors.New("device group not found")
+	// ErrDeviceGroupExists is returned when an organization already has a device group with the same name.
+	ErrDeviceGroupExists = errors.New("device group already exists")
+	// ErrInvalidDeviceGroup is returned when a device group fails validation.
+	ErrInvalidDeviceGroup = errors.New("invalid device group")
+)
+
+// MaxDeviceGroupNameLength is the maximum length of a device group name.
+const MaxDeviceGroupNameLength = 255
+
+// DeviceGroup is a named, organization scoped collection of end devices such as a greenhouse or a delivery van.
+// An end device can belong to any number of groups.
+type DeviceGroup struct {
+	Id             string
+	OrganizationId string
+	Name           string
+	Description    string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Validate checks that the device group has a usable name.
+func (group *DeviceGroup) Validate() error {
+	name := strings.TrimSpace(group.Name)
+	if name == "" {
+		return stacktrace.NewStackTraceErrorf("%w: name is required", ErrInvalidDeviceGroup)
+	}
+
+	if len(name) > MaxDeviceGroupNameLength {
+		return stacktrace.NewStackTraceErrorf("%w: name is longer than %d characters", ErrInvalidDeviceGroup, MaxDeviceGroupNameLength)
+	}
+
+	return nil
+}
+
+// DeviceGroupStorer defines the persistence operations for device groups and their membership.
+type DeviceGroupStorer interface {
+	CreateDeviceGroup(ctx context.Context, group *DeviceGroup) (*DeviceGroup, error)
+	GetDeviceGroup(ctx context.Context, groupId string) (*DeviceGroup, error)
+	ListDeviceGroups(ctx context.Context, organizationId string) ([]*DeviceGroup, error)
+	UpdateDeviceGroup(ctx context.Context, group *DeviceGroup) (*DeviceGroup, error)
+	DeleteDeviceGroup(ctx context.Context, groupId string) error
+	AddDeviceGroupMembers(ctx context.Context, groupId string, organizationId string, endDeviceIds []string) (int, error)
+	RemoveDeviceGroupMembers(ctx context.Context, groupId string, endDeviceIds []string) (int, error)
+	ListDeviceGroupMembers(ctx context.Context, groupId string) ([]*iotv1.EndDevice, error)
+	ListEndDeviceGroups(ctx context.Context, endDeviceId string) ([]*DeviceGroup, error)
+}
+
+// DeviceGroupManager orchestrates device group business logic.
+type DeviceGroupManager struct {
+	deviceGroupStore DeviceGroupStorer
+	stringId         StringId
+}
+
+// NewDeviceGroupManager creates a new instance of DeviceGroupManager with the provided dependencies.
+func NewDeviceGroupManager(dgs DeviceGroupStorer, stringId StringId) *DeviceGroupManager {
+	return &DeviceGroupManager{
+		deviceGroupStore: dgs,
+		stringId:         stringId,
+	}
+}
+
+// CreateDeviceGroup creates a new device group in an organization. Group names are unique within an organization.
+func (mgr *DeviceGroupManager) CreateDeviceGroup(ctx context.Context, organizationId string, name string, description string) (*DeviceGroup, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "CreateDeviceGroup")
+	defer span.End()
+
+	group := &DeviceGroup{
+		Id:             mgr.stringId(),
+		OrganizationId: organizationId,
+		Name:           strings.TrimSpace(name),
+		Description:    description,
+	}
+
+	err := group.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return mgr.deviceGroupStore.CreateDeviceGroup(ctx, group)
+}
+
+// GetDeviceGroup retrieves a device group.
+// Groups that belong to a different organization are reported as ErrDeviceGroupNotFound so their existence is not leaked.
+func (mgr *DeviceGroupManager) GetDeviceGroup(ctx context.Context, groupId string, organizationId string) (*DeviceGroup, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetDeviceGroup")
+	defer span.End()
+
+	group, err := mgr.deviceGroupStore.GetDeviceGroup(ctx, groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	if group.OrganizationId != organizationId {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrDeviceGroupNotFound, groupId)
+	}
+
+	return group, nil
+}
+
+// ListDeviceGroups retrieves every device group in an organization ordered by name.
+func (mgr *DeviceGroupManager) ListDeviceGroups(ctx context.Context, organizationId string) ([]*DeviceGroup, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListDeviceGroups")
+	defer span.End()
+
+	return mgr.deviceGroupStore.ListDeviceGroups(ctx, organizationId)
+}
+
+// UpdateDeviceGroup renames a device group and replaces its description. An empty name leaves the name unchanged.
+func (mgr *DeviceGroupManager) UpdateDeviceGroup(ctx context.Context, groupId string, organizationId string, name string, description string) (*DeviceGroup, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "UpdateDeviceGroup")
+	defer span.End()
+
+	group, err := mgr.GetDeviceGroup(ctx, groupId, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != "" {
+		group.Name = strings.TrimSpace(name)
+	}
+	group.Description = description
+
+	err = group.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return mgr.deviceGroupStore.UpdateDeviceGroup(ctx, group)
+}
+
+// DeleteDeviceGroup deletes a device group. Its member devices are not affected.
+func (mgr *DeviceGroupManager) DeleteDeviceGroup(ctx context.Context, groupId string, organizationId string) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "DeleteDeviceGroup")
+	defer span.End()
+
+	_, err := mgr.GetDeviceGroup(ctx, groupId, organizationId)
+	if err != nil {
+		return err
+	}
+
+	return mgr.deviceGroupStore.DeleteDeviceGroup(ctx, groupId)
+}
+
+// AddDeviceGroupMembers adds end devices of the group's organization to a device group and returns how many were
+// newly added. Devices that are already members are skipped.
+func (mgr *DeviceGroupManager) AddDeviceGroupMembers(ctx context.Context, groupId string, organizationId string, endDeviceIds []string) (int, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "AddDeviceGroupMembers")
+	defer span.End()
+
+	_, err := mgr.GetDeviceGroup(ctx, groupId, organizationId)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(endDeviceIds) == 0 {
+		return 0, nil
+	}
+
+	return mgr.deviceGroupStore.AddDeviceGroupMembers(ctx, groupId, organizationId, endDeviceIds)
+}
+
+// RemoveDeviceGroupMembers removes end devices from a device group and returns how many were removed.
+func (mgr *DeviceGroupManager) RemoveDeviceGroupMembers(ctx context.Context, groupId string, organizationId string, endDeviceIds []string) (int, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "RemoveDeviceGroupMembers")
+	defer span.End()
+
+	_, err := mgr.GetDeviceGroup(ctx, groupId, organizationId)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(endDeviceIds) == 0 {
+		return 0, nil
+	}
+
+	return mgr.deviceGroupStore.RemoveDeviceGroupMembers(ctx, groupId, endDeviceIds)
+}
+
+// ListDeviceGroupMembers retrieves the end devices in a device group ordered by name.
+func (mgr *DeviceGroupManager) ListDeviceGroupMembers(ctx context.Context, groupId string, organizationId string) ([]*iotv1.EndDevice, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListDeviceGroupMembers")
+	defer span.End()
+
+	_, err := mgr.GetDeviceGroup(ctx, groupId, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	return mgr.deviceGroupStore.ListDeviceGroupMembers(ctx, groupId)
+}
+
+// ListEndDeviceGroups retrieves the device groups an end device belongs to.
+func (mgr *DeviceGroupManager) ListEndDeviceGroups(ctx context.Context, endDeviceId string, organizationId string) ([]*DeviceGroup, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListEndDeviceGroups")
+	defer span.End()
+
+	groups, err := mgr.deviceGroupStore.ListEndDeviceGroups(ctx, endDeviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Groups never span organizations, so filtering hides devices owned by other organizations
+	visible := make([]*DeviceGroup, 0, len(groups))
+	for _, group := range groups {
+		if group.OrganizationId == organizationId {
+			visible = append(visible, group)
+		}
+	}
+
+	return visible, nil
+}
@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"context"
+	"testing"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceGroupManager(t *testing.T) {
+	newManager := func() (*DeviceGroupManager, *memoryDeviceGroupStore) {
+		store := newMemoryDeviceGroupStore()
+		store.groups["group-1"] = &DeviceGroup{Id: "group-1", OrganizationId: "org-1", Name: "greenhouse"}
+		store.groups["group-2"] = &DeviceGroup{Id: "group-2", OrganizationId: "org-2", Name: "van"}
+		store.putEndDevice(iotv1.EndDevice_builder{Id: "device-1", Name: "sensor"}.Build(), "org-1")
+		store.putEndDevice(iotv1.EndDevice_builder{Id: "device-2", Name: "probe"}.Build(), "org-1")
+		store.putEndDevice(iotv1.EndDevice_builder{Id: "device-x", Name: "other"}.Build(), "org-2")
+		return NewDeviceGroupManager(store, func() string { return "group-new" }), store
+	}
+
+	t.Run("creates groups with a trimmed name", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, store := newManager()
+
+		group, err := mgr.CreateDeviceGroup(context.Background(), "org-1", "  freezers ", "cold storage")
+
+		assert.NoError(err)
+		assert.Equal("group-new", group.Id)
+		assert.Equal("freezers", store.groups["group-new"].Name)
+		assert.Equal("org-1", store.groups["group-new"].OrganizationId)
+	})
+
+	t.Run("rejects blank and duplicate names", func(t *testing.T) {
+		mgr, _ := newManager()
+
+		_, err := mgr.CreateDeviceGroup(context.Background(), "org-1", "   ", "")
+		assert.ErrorIs(t, err, ErrInvalidDeviceGroup)
+
+		_, err = mgr.CreateDeviceGroup(context.Background(), "org-1", "greenhouse", "")
+		assert.ErrorIs(t, err, ErrDeviceGroupExists)
+	})
+
+	t.Run("hides groups of other organizations", func(t *testing.T) {
+		mgr, store := newManager()
+
+		_, err := mgr.GetDeviceGroup(context.Background(), "group-2", "org-1")
+		assert.ErrorIs(t, err, ErrDeviceGroupNotFound)
+
+		err = mgr.DeleteDeviceGroup(context.Background(), "group-2", "org-1")
+		assert.ErrorIs(t, err, ErrDeviceGroupNotFound)
+		assert.Contains(t, store.groups, "group-2")
+
+		_, err = mgr.AddDeviceGroupMembers(context.Background(), "group-2", "org-1", []string{"device-1"})
+		assert.ErrorIs(t, err, ErrDeviceGroupNotFound)
+	})
+
+	t.Run("keeps the name when renaming to nothing", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, _ := newManager()
+
+		group, err := mgr.UpdateDeviceGroup(context.Background(), "group-1", "org-1", "", "north wing")
+
+		assert.NoError(err)
+		assert.Equal("greenhouse", group.Name)
+		assert.Equal("north wing", group.Description)
+	})
+
+	t.Run("adds and removes members", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, _ := newManager()
+		ctx := context.Background()
+
+		added, err := mgr.AddDeviceGroupMembers(ctx, "group-1", "org-1", []string{"device-1", "device-2"})
+		assert.NoError(err)
+		assert.Equal(2, added)
+
+		added, err = mgr.AddDeviceGroupMembers(ctx, "group-1", "org-1", []string{"device-1"})
+		assert.NoError(err)
+		assert.Equal(0, added)
+
+		removed, err := mgr.RemoveDeviceGroupMembers(ctx, "group-1", "org-1", []string{"device-1", "device-3"})
+		assert.NoError(err)
+		assert.Equal(1, removed)
+
+		members, err := mgr.ListDeviceGroupMembers(ctx, "group-1", "org-1")
+		assert.NoError(err)
+		assert.Len(members, 1)
+		assert.Equal("device-2", members[0].GetId())
+	})
+
+	t.Run("rejects members of other organizations", func(t *testing.T) {
+		mgr, store := newManager()
+
+		_, err := mgr.AddDeviceGroupMembers(context.Background(), "group-1", "org-1", []string{"device-1", "device-x"})
+
+		assert.ErrorIs(t, err, ErrEndDeviceNotFound)
+		assert.Empty(t, store.members["group-1"])
+	})
+
+	t.Run("lists only the groups of the organization a device belongs to", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, store := newManager()
+		store.members["group-1"] = []string{"device-1"}
+		store.members["group-2"] = []string{"device-1"}
+
+		groups, err := mgr.ListEndDeviceGroups(context.Background(), "device-1", "org-1")
+
+		assert.NoError(err)
+		assert.Len(groups, 1)
+		assert.Equal("group-1", groups[0].Id)
+	})
+}
@@ -2,75 +2,266 @@ package domain
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
 
 	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
 	"github.com/ponix-dev/ponix/internal/telemetry"
 	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+	"google.golang.org/protobuf/proto"
 )
 
+var (
+	// ErrEndDeviceNotFound is returned when an end device does not exist or is not visible to the requesting organization.
+	ErrEndDeviceNotFound = errors.New("end device not found")
+	// ErrInvalidPageToken is returned when a listing page token cannot be decoded.
+	ErrInvalidPageToken = errors.New("invalid page token")
+)
+
+const (
+	// DefaultEndDevicePageSize is the number of end devices returned when no page size is requested.
+	DefaultEndDevicePageSize = 50
+	// MaxEndDevicePageSize is the largest number of end devices returned in a single page.
+	MaxEndDevicePageSize = 500
+)
+
+// EndDeviceSortField identifies the column used to order end device listings.
+type EndDeviceSortField string
+
+const (
+	// EndDeviceSortByName orders end devices alphabetically by name.
+	EndDeviceSortByName EndDeviceSortField = "name"
+	// EndDeviceSortByCreatedAt orders end devices from oldest to newest.
+	EndDeviceSortByCreatedAt EndDeviceSortField = "created_at"
+)
+
+// EndDeviceListFilter narrows an end device listing. Zero values are ignored.
+type EndDeviceListFilter struct {
+	Status         iotv1.EndDeviceStatus
+	HardwareType   iotv1.EndDeviceHardwareType
+	HardwareTypeId string
+	NamePrefix     string
+	Labels         LabelSelector
+	GroupIds       []string
+	// NotSeenSince selects stale devices that have sent no data since the given time, including devices never seen.
+	NotSeenSince time.Time
+}
+
+// EndDeviceMetadata holds the user-defined labels and free-form attributes document attached to an end device.
+type EndDeviceMetadata struct {
+	Labels     Labels
+	Attributes map[string]any
+}
+
+// Validate checks that the labels are well formed.
+func (metadata EndDeviceMetadata) Validate() error {
+	return metadata.Labels.Validate()
+}
+
+// EndDeviceListRequest describes a page of end devices to list within an organization.
+type EndDeviceListRequest struct {
+	OrganizationId string
+	Filter         EndDeviceListFilter
+	SortBy         EndDeviceSortField
+	PageSize       int
+	PageToken      string
+}
+
+// EndDevicePage is a single page of an end device listing.
+// NextPageToken is empty when there are no further pages.
+type EndDevicePage struct {
+	EndDevices    []*iotv1.EndDevice
+	NextPageToken string
+}
+
+// EndDeviceCursor marks the position of the last end device returned in a page.
+type EndDeviceCursor struct {
+	Id        string     `json:"id"`
+	Name      string     `json:"name,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	// Rank is the search rank of the end device; it is only set when paging through search results.
+	Rank float64 `json:"rank,omitempty"`
+}
+
+// EndDeviceListQuery is the store-level form of an end device listing request.
+type EndDeviceListQuery struct {
+	OrganizationId string
+	Filter         EndDeviceListFilter
+	SortBy         EndDeviceSortField
+	Limit          int
+	After          *EndDeviceCursor
+}
+
 // EndDeviceRegister defines the operations for registering devices with external systems.
 type EndDeviceRegister interface {
 	RegisterEndDevice(ctx context.Context, endDevice *iotv1.EndDevice) error
+	UpdateEndDevice(ctx context.Context, endDevice *iotv1.EndDevice) error
+	DeleteEndDevice(ctx context.Context, endDevice *iotv1.EndDevice) error
+}
+
+// EndDeviceSync propagates a pending end device change to external systems.
+// Stores run it inside their transaction before committing so both sides change together.
+type EndDeviceSync func(ctx context.Context) error
+
+// PendingEndDevice is a fully built end device waiting to be stored as part of a batch.
+type PendingEndDevice struct {
+	EndDevice      *iotv1.EndDevice
+	OrganizationId string
+	Metadata       EndDeviceMetadata
 }
 
 // EndDeviceStorer defines the persistence operations for end devices.
 type EndDeviceStorer interface {
-	AddEndDevice(ctx context.Context, endDevice *iotv1.EndDevice, organizationId string) error
+	AddEndDevice(ctx context.Context, endDevice *iotv1.EndDevice, organizationId string, metadata EndDeviceMetadata, sync EndDeviceSync) error
+	AddEndDevices(ctx context.Context, endDevices []PendingEndDevice) ([]error, error)
 	GetLoRaWANHardwareType(ctx context.Context, hardwareTypeId string) (*iotv1.LoRaWANHardwareData, error)
 	GetEndDeviceWithOrganization(ctx context.Context, endDeviceID string) (*iotv1.EndDevice, string, error)
+	GetEndDevice(ctx context.Context, endDeviceID string) (*iotv1.EndDevice, string, error)
+	ListEndDevices(ctx context.Context, query EndDeviceListQuery) ([]*iotv1.EndDevice, *EndDeviceCursor, error)
+	SearchEndDevices(ctx context.Context, query EndDeviceSearchQuery) ([]EndDeviceSearchResult, *EndDeviceCursor, error)
+	UpdateEndDevice(ctx context.Context, endDevice *iotv1.EndDevice, metadata EndDeviceMetadata, sync EndDeviceSync) error
+	DeleteEndDevice(ctx context.Context, endDeviceID string, sync EndDeviceSync) error
+	GetEndDeviceMetadata(ctx context.Context, endDeviceID string) (EndDeviceMetadata, string, error)
+}
+
+// DeviceEUIAllocator hands out device EUIs for new LoRaWAN end devices.
+type DeviceEUIAllocator interface {
+	AllocateDeviceEUI(ctx context.Context, organizationId string) (string, error)
+	CheckDeviceEUIAvailable(ctx context.Context, deviceEui string) error
 }
 
 // EndDeviceManager orchestrates end device business logic including creation and external registration.
 type EndDeviceManager struct {
 	endDeviceStore    EndDeviceStorer
 	endDeviceRegister EndDeviceRegister
+	euiAllocator      DeviceEUIAllocator
+	rootKeySealer     RootKeySealer
+	profiles          EndDeviceProfileGetter
 	applicationId     string
 	stringId          StringId
 	validate          Validate
 }
 
 // NewEndDeviceManager creates a new instance of EndDeviceManager with the provided dependencies.
-func NewEndDeviceManager(eds EndDeviceStorer, edr EndDeviceRegister, applicationId string, stringId StringId, validate Validate) *EndDeviceManager {
+func NewEndDeviceManager(eds EndDeviceStorer, edr EndDeviceRegister, euiAllocator DeviceEUIAllocator, rootKeySealer RootKeySealer, profiles EndDeviceProfileGetter, applicationId string, stringId StringId, validate Validate) *EndDeviceManager {
 	return &EndDeviceManager{
 		endDeviceStore:    eds,
 		endDeviceRegister: edr,
+		euiAllocator:      euiAllocator,
+		rootKeySealer:     rootKeySealer,
+		profiles:          profiles,
 		applicationId:     applicationId,
 		stringId:          stringId,
 		validate:          validate,
 	}
 }
 
-// CreateEndDevice creates a new end device, registers it with external systems if needed, and persists it.
-func (mgr *EndDeviceManager) CreateEndDevice(ctx context.Context, createReq *iotv1.CreateEndDeviceRequest, organizationId string) (*iotv1.EndDevice, error) {
+// CreateEndDevice creates a new end device, registers it with external systems if needed, and persists it
+// together with its labels and attributes. LoRaWAN devices use the factory-assigned identifiers in identity;
+// a missing device EUI is allocated from the organization's EUI blocks and missing keys are generated.
+// When the request names an end device profile of the organization in profile_id, the profile fills in the hardware type,
+// hardware type ID, frequency plan and labels the request leaves unset. LoRaWAN devices are registered with TTN before the database write is committed; if the commit then fails,
+// the registration is removed again. The returned device has its root keys redacted.
+func (mgr *EndDeviceManager) CreateEndDevice(ctx context.Context, createReq *iotv1.CreateEndDeviceRequest, organizationId string, metadata EndDeviceMetadata, identity LoRaWANIdentity) (*iotv1.EndDevice, error) {
 	ctx, span := telemetry.Tracer().Start(ctx, "CreateEndDevice")
 	defer span.End()
 
 	endDeviceId := mgr.stringId()
 
+	var profile *EndDeviceProfile
+	if createReq.GetProfileId() != "" {
+		var err error
+		profile, err = getOrganizationEndDeviceProfile(ctx, mgr.profiles, createReq.GetProfileId(), organizationId)
+		if err != nil {
+			return nil, err
+		}
+
+		createReq, err = profile.applyToCreateRequest(createReq)
+		if err != nil {
+			return nil, err
+		}
+		metadata = profile.applyToMetadata(metadata)
+	}
+
 	err := mgr.validate(createReq)
 	if err != nil {
 		return nil, err
 	}
 
-	endDevice, err := mgr.buildEndDeviceFromRequest(ctx, endDeviceId, createReq)
+	err = metadata.Validate()
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	endDevice, err := mgr.buildEndDeviceFromRequest(ctx, endDeviceId, createReq, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	if lorawanConfig := endDevice.GetLorawanConfig(); lorawanConfig != nil && profile != nil && profile.FrequencyPlan != "" {
+		lorawanConfig.SetFrequencyPlan(profile.FrequencyPlan)
+	}
+
+	err = mgr.assignDeviceEUI(ctx, organizationId, endDevice)
 	if err != nil {
 		return nil, err
 	}
 
 	// Only register with external systems for LoRaWAN devices
-	switch endDevice.GetHardwareType() {
-	case iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_LORAWAN:
-		err = mgr.endDeviceRegister.RegisterEndDevice(ctx, endDevice)
-		if err != nil {
-			return nil, stacktrace.NewStackTraceError(err)
+	var sync EndDeviceSync
+	synced := false
+	if endDevice.GetHardwareType() == iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_LORAWAN {
+		sync = func(ctx context.Context) error {
+			err := mgr.endDeviceRegister.RegisterEndDevice(ctx, endDevice)
+			if err != nil {
+				return stacktrace.NewStackTraceError(err)
+			}
+			synced = true
+			return nil
 		}
-	case iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_HTTP:
-		// HTTP devices don't need external registration
-		// Continue to storage
 	}
 
-	// Store the device in the database
-	err = mgr.endDeviceStore.AddEndDevice(ctx, endDevice, organizationId)
+	// Store the device in the database, registering it before the write is committed
+	err = mgr.endDeviceStore.AddEndDevice(ctx, endDevice, organizationId, metadata, sync)
+	if err != nil {
+		if synced {
+			// The registry already knows the device; remove it so nothing is left behind
+			restoreErr := mgr.endDeviceRegister.DeleteEndDevice(ctx, endDevice)
+			if restoreErr != nil {
+				return nil, stacktrace.NewStackTraceError(errors.Join(err, restoreErr))
+			}
+		}
+		return nil, err
+	}
+
+	// Root keys are only handed out through GetEndDevice to callers allowed to read them
+	created := proto.Clone(endDevice).(*iotv1.EndDevice)
+	redactEndDeviceKeys(created)
+
+	return created, nil
+}
+
+// GetEndDevice retrieves an end device with its complete hardware configuration.
+// Devices that belong to a different organization are reported as ErrEndDeviceNotFound so their existence is not leaked.
+// LoRaWAN root keys are stored sealed; they are decrypted when includeKeys is set and redacted otherwise.
+func (mgr *EndDeviceManager) GetEndDevice(ctx context.Context, endDeviceId string, organizationId string, includeKeys bool) (*iotv1.EndDevice, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetEndDevice")
+	defer span.End()
+
+	endDevice, err := mgr.getOrganizationEndDevice(ctx, endDeviceId, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !includeKeys {
+		redactEndDeviceKeys(endDevice)
+		return endDevice, nil
+	}
+
+	err = mgr.openEndDeviceKeys(ctx, endDevice)
 	if err != nil {
 		return nil, err
 	}
@@ -78,8 +269,313 @@ func (mgr *EndDeviceManager) CreateEndDevice(ctx context.Context, createReq *iot
 	return endDevice, nil
 }
 
+// UpdateEndDevice applies changes to an end device's name, description and, for LoRaWAN devices,
+// its frequency plan and hardware type. Empty or unspecified fields in update are left unchanged.
+// The labels and attributes in metadataUpdate replace those of the device; a nil Labels or Attributes map leaves
+// that part unchanged and an empty map clears it. They are written in the same transaction as the device.
+// The status is driven by the device lifecycle, so requests to change it fail with ErrInvalidStatusTransition.
+// LoRaWAN devices are updated in TTN before the database change is committed. TTN keeps a device's settings in
+// several servers, so a failed sync can leave it partly updated; if the sync or the commit fails, the TTN registry
+// is restored to the previous state. The returned device has its root keys redacted.
+func (mgr *EndDeviceManager) UpdateEndDevice(ctx context.Context, update *iotv1.EndDevice, organizationId string, metadataUpdate EndDeviceMetadata) (*iotv1.EndDevice, EndDeviceMetadata, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "UpdateEndDevice")
+	defer span.End()
+
+	current, err := mgr.getOrganizationEndDevice(ctx, update.GetId(), organizationId)
+	if err != nil {
+		return nil, EndDeviceMetadata{}, err
+	}
+
+	metadata, _, err := mgr.endDeviceStore.GetEndDeviceMetadata(ctx, update.GetId())
+	if err != nil {
+		return nil, EndDeviceMetadata{}, err
+	}
+	if metadataUpdate.Labels != nil {
+		metadata.Labels = metadataUpdate.Labels
+	}
+	if metadataUpdate.Attributes != nil {
+		metadata.Attributes = metadataUpdate.Attributes
+	}
+
+	err = metadata.Validate()
+	if err != nil {
+		return nil, EndDeviceMetadata{}, stacktrace.NewStackTraceError(err)
+	}
+
+	updated := proto.Clone(current).(*iotv1.EndDevice)
+	if update.GetName() != "" {
+		updated.SetName(update.GetName())
+	}
+	if update.GetDescription() != "" {
+		updated.SetDescription(update.GetDescription())
+	}
+	if update.GetStatus() != iotv1.EndDeviceStatus_END_DEVICE_STATUS_UNSPECIFIED && update.GetStatus() != current.GetStatus() {
+		// Status follows the device's traffic and is only changed through EndDeviceStatusManager
+		return nil, EndDeviceMetadata{}, stacktrace.NewStackTraceErrorf("%w: status of %s cannot be set directly", ErrInvalidStatusTransition, update.GetId())
+	}
+
+	if lorawanUpdate := update.GetLorawanConfig(); lorawanUpdate != nil && updated.GetLorawanConfig() != nil {
+		lorawanConfig := updated.GetLorawanConfig()
+		if lorawanUpdate.GetFrequencyPlan() != "" {
+			lorawanConfig.SetFrequencyPlan(lorawanUpdate.GetFrequencyPlan())
+		}
+
+		hardwareTypeId := lorawanUpdate.GetHardwareData().GetHardwareTypeId()
+		if hardwareTypeId != "" && hardwareTypeId != lorawanConfig.GetHardwareData().GetHardwareTypeId() {
+			hardwareData, err := mgr.endDeviceStore.GetLoRaWANHardwareType(ctx, hardwareTypeId)
+			if err != nil {
+				return nil, EndDeviceMetadata{}, err
+			}
+			lorawanConfig.SetHardwareData(hardwareData)
+		}
+	}
+
+	err = mgr.validate(updated)
+	if err != nil {
+		return nil, EndDeviceMetadata{}, err
+	}
+
+	var sync EndDeviceSync
+	synced := false
+	if updated.GetHardwareType() == iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_LORAWAN {
+		sync = func(ctx context.Context) error {
+			synced = true
+			return mgr.endDeviceRegister.UpdateEndDevice(ctx, updated)
+		}
+	}
+
+	err = mgr.endDeviceStore.UpdateEndDevice(ctx, updated, metadata, sync)
+	if err != nil {
+		if synced {
+			// The registry may already have some or all of the new state; put it back so both sides agree again
+			restoreErr := mgr.endDeviceRegister.UpdateEndDevice(ctx, current)
+			if restoreErr != nil {
+				return nil, EndDeviceMetadata{}, stacktrace.NewStackTraceError(errors.Join(err, restoreErr))
+			}
+		}
+		return nil, EndDeviceMetadata{}, err
+	}
+
+	redactEndDeviceKeys(updated)
+
+	return updated, metadata, nil
+}
+
+// DeleteEndDevice deletes an end device from the organization.
+// LoRaWAN devices are removed from TTN before the database change is committed; if the commit then fails,
+// the device is registered with TTN again.
+func (mgr *EndDeviceManager) DeleteEndDevice(ctx context.Context, endDeviceId string, organizationId string) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "DeleteEndDevice")
+	defer span.End()
+
+	current, err := mgr.getOrganizationEndDevice(ctx, endDeviceId, organizationId)
+	if err != nil {
+		return err
+	}
+
+	var sync EndDeviceSync
+	synced := false
+	if current.GetHardwareType() == iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_LORAWAN {
+		sync = func(ctx context.Context) error {
+			err := mgr.endDeviceRegister.DeleteEndDevice(ctx, current)
+			if err != nil {
+				return err
+			}
+			synced = true
+			return nil
+		}
+	}
+
+	err = mgr.endDeviceStore.DeleteEndDevice(ctx, endDeviceId, sync)
+	if err != nil {
+		if synced {
+			// The device is already gone from the registry; register it again so both sides agree
+			restoreErr := mgr.endDeviceRegister.RegisterEndDevice(ctx, current)
+			if restoreErr != nil {
+				return stacktrace.NewStackTraceError(errors.Join(err, restoreErr))
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetEndDeviceMetadata retrieves the labels and attributes of an end device.
+func (mgr *EndDeviceManager) GetEndDeviceMetadata(ctx context.Context, endDeviceId string, organizationId string) (EndDeviceMetadata, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetEndDeviceMetadata")
+	defer span.End()
+
+	metadata, deviceOrgId, err := mgr.endDeviceStore.GetEndDeviceMetadata(ctx, endDeviceId)
+	if err != nil {
+		return EndDeviceMetadata{}, err
+	}
+
+	if deviceOrgId != organizationId {
+		return EndDeviceMetadata{}, stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceNotFound, endDeviceId)
+	}
+
+	return metadata, nil
+}
+
+// getOrganizationEndDevice retrieves a complete end device, reporting devices owned by other organizations as not found.
+func (mgr *EndDeviceManager) getOrganizationEndDevice(ctx context.Context, endDeviceId string, organizationId string) (*iotv1.EndDevice, error) {
+	endDevice, deviceOrgId, err := mgr.endDeviceStore.GetEndDevice(ctx, endDeviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if deviceOrgId != organizationId {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceNotFound, endDeviceId)
+	}
+
+	return endDevice, nil
+}
+
+// ListEndDevices retrieves a page of end devices of every hardware type in an organization.
+// Pages are addressed with opaque cursor tokens so listings remain stable while devices are added.
+func (mgr *EndDeviceManager) ListEndDevices(ctx context.Context, listReq EndDeviceListRequest) (*EndDevicePage, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListEndDevices")
+	defer span.End()
+
+	sortBy := listReq.SortBy
+	switch sortBy {
+	case "":
+		sortBy = EndDeviceSortByName
+	case EndDeviceSortByName, EndDeviceSortByCreatedAt:
+	default:
+		return nil, stacktrace.NewStackTraceErrorf("%w: unsupported sort field %q", ErrInvalidMessageFormat, sortBy)
+	}
+
+	after, err := decodeEndDevicePageToken(listReq.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if after != nil && sortBy == EndDeviceSortByCreatedAt && after.CreatedAt == nil {
+		// The token was issued for a listing in a different order
+		return nil, stacktrace.NewStackTraceErrorf("%w: token does not continue a listing by %s", ErrInvalidPageToken, sortBy)
+	}
+
+	endDevices, next, err := mgr.endDeviceStore.ListEndDevices(ctx, EndDeviceListQuery{
+		OrganizationId: listReq.OrganizationId,
+		Filter:         listReq.Filter,
+		SortBy:         sortBy,
+		Limit:          endDevicePageSize(listReq.PageSize),
+		After:          after,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nextPageToken, err := encodeEndDevicePageToken(next)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EndDevicePage{
+		EndDevices:    endDevices,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+// endDevicePageSize clamps a requested page size, falling back to the default when none is requested.
+func endDevicePageSize(pageSize int) int {
+	switch {
+	case pageSize <= 0:
+		return DefaultEndDevicePageSize
+	case pageSize > MaxEndDevicePageSize:
+		return MaxEndDevicePageSize
+	}
+
+	return pageSize
+}
+
+// encodeEndDevicePageToken serializes a cursor into an opaque page token.
+func encodeEndDevicePageToken(cursor *EndDeviceCursor) (string, error) {
+	if cursor == nil {
+		return "", nil
+	}
+
+	raw, err := json.Marshal(cursor)
+	if err != nil {
+		return "", stacktrace.NewStackTraceError(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// decodeEndDevicePageToken parses an opaque page token back into a cursor.
+func decodeEndDevicePageToken(token string) (*EndDeviceCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %w", ErrInvalidPageToken, err)
+	}
+
+	cursor := &EndDeviceCursor{}
+	err = json.Unmarshal(raw, cursor)
+	if err != nil || cursor.Id == "" {
+		return nil, stacktrace.NewStackTraceError(ErrInvalidPageToken)
+	}
+
+	return cursor, nil
+}
+
+// LookupEndDeviceHardwareType resolves a hardware type from its enum name (END_DEVICE_HARDWARE_TYPE_LORAWAN) or its
+// short form (lorawan), ignoring case.
+func LookupEndDeviceHardwareType(name string) (iotv1.EndDeviceHardwareType, bool) {
+	name = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(name)), "END_DEVICE_HARDWARE_TYPE_")
+
+	number, ok := iotv1.EndDeviceHardwareType_value["END_DEVICE_HARDWARE_TYPE_"+name]
+	return iotv1.EndDeviceHardwareType(number), ok
+}
+
+// redactEndDeviceKeys clears secret key material and password hashes from an end device's hardware configuration.
+func redactEndDeviceKeys(endDevice *iotv1.EndDevice) {
+	if lorawanConfig := endDevice.GetLorawanConfig(); lorawanConfig != nil {
+		lorawanConfig.SetApplicationKey("")
+		lorawanConfig.SetNetworkKey("")
+	}
+	if mqttConfig := endDevice.GetMqttConfig(); mqttConfig != nil {
+		mqttConfig.SetPasswordHash("")
+	}
+}
+
+// openEndDeviceKeys replaces the sealed root keys of an end device's hardware configuration with their plaintext.
+// The password hash of MQTT devices is cleared; it is only ever checked by the broker.
+func (mgr *EndDeviceManager) openEndDeviceKeys(ctx context.Context, endDevice *iotv1.EndDevice) error {
+	if mqttConfig := endDevice.GetMqttConfig(); mqttConfig != nil {
+		mqttConfig.SetPasswordHash("")
+	}
+
+	lorawanConfig := endDevice.GetLorawanConfig()
+	if lorawanConfig == nil {
+		return nil
+	}
+
+	applicationKey, err := mgr.rootKeySealer.OpenRootKey(ctx, lorawanConfig.GetApplicationKey())
+	if err != nil {
+		return err
+	}
+
+	networkKey, err := mgr.rootKeySealer.OpenRootKey(ctx, lorawanConfig.GetNetworkKey())
+	if err != nil {
+		return err
+	}
+
+	lorawanConfig.SetApplicationKey(applicationKey)
+	lorawanConfig.SetNetworkKey(networkKey)
+
+	return nil
+}
+
 // buildEndDeviceFromRequest constructs a complete EndDevice from the request including hardware-specific configuration.
-func (mgr *EndDeviceManager) buildEndDeviceFromRequest(ctx context.Context, endDeviceId string, createReq *iotv1.CreateEndDeviceRequest) (*iotv1.EndDevice, error) {
+// The device EUI of LoRaWAN devices is left empty unless supplied in identity; see assignDeviceEUI.
+func (mgr *EndDeviceManager) buildEndDeviceFromRequest(ctx context.Context, endDeviceId string, createReq *iotv1.CreateEndDeviceRequest, identity LoRaWANIdentity) (*iotv1.EndDevice, error) {
 	ctx, span := telemetry.Tracer().Start(ctx, "buildEndDeviceFromRequest")
 	defer span.End()
 
@@ -90,13 +586,14 @@ func (mgr *EndDeviceManager) buildEndDeviceFromRequest(ctx context.Context, endD
 		Description:  createReq.GetDescription(),
 		Status:       iotv1.EndDeviceStatus_END_DEVICE_STATUS_PENDING,
 		HardwareType: createReq.GetHardwareType(),
+		ProfileId:    createReq.GetProfileId(),
 		// Note: data_type is deprecated and not set
 	}
 
 	// Handle hardware-specific configuration
 	switch createReq.GetHardwareType() {
 	case iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_LORAWAN:
-		lorawanConfig, err := mgr.buildLoRaWANConfig(ctx, createReq)
+		lorawanConfig, err := mgr.buildLoRaWANConfig(ctx, createReq, identity)
 		if err != nil {
 			return nil, err
 		}
@@ -104,31 +601,81 @@ func (mgr *EndDeviceManager) buildEndDeviceFromRequest(ctx context.Context, endD
 	case iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_HTTP:
 		// HTTP devices don't need additional configuration
 		// Just validate and continue
+	case iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_MQTT, iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_MODBUS:
+		// The configurations of MQTT and Modbus devices are prepared below; see newEndDeviceMQTTConfig and
+		// newEndDeviceModbusConfig
 	default:
 		return nil, stacktrace.NewStackTraceErrorf("unsupported hardware type: %v", createReq.GetHardwareType())
 	}
 
-	return endDeviceBuilder.Build(), nil
+	endDevice := endDeviceBuilder.Build()
+
+	mqttConfig, err := newEndDeviceMQTTConfig(endDevice, createReq.GetMqttConfig())
+	if err != nil {
+		return nil, err
+	}
+	endDevice.SetMqttConfig(mqttConfig)
+
+	modbusConfig, err := newEndDeviceModbusConfig(endDevice, createReq.GetModbusConfig())
+	if err != nil {
+		return nil, err
+	}
+	endDevice.SetModbusConfig(modbusConfig)
+
+	return endDevice, nil
 }
 
 // buildLoRaWANConfig constructs a complete LoRaWAN configuration including device identifiers, keys, and hardware data.
-func (mgr *EndDeviceManager) buildLoRaWANConfig(ctx context.Context, createReq *iotv1.CreateEndDeviceRequest) (*iotv1.LoRaWANConfig, error) {
+// Supplied identifiers and keys are kept; the join EUI and root keys are otherwise generated with crypto/rand.
+// Root keys are sealed before they leave this function, so only sealed keys are ever stored or returned.
+func (mgr *EndDeviceManager) buildLoRaWANConfig(ctx context.Context, createReq *iotv1.CreateEndDeviceRequest, identity LoRaWANIdentity) (*iotv1.LoRaWANConfig, error) {
 	ctx, span := telemetry.Tracer().Start(ctx, "buildLoRaWANConfig")
 	defer span.End()
 
+	identity, err := identity.Normalize()
+	if err != nil {
+		return nil, err
+	}
+
 	// Fetch hardware type data from database
 	hardwareData, err := mgr.endDeviceStore.GetLoRaWANHardwareType(ctx, createReq.GetHardwareTypeId())
 	if err != nil {
 		return nil, err
 	}
 
+	joinEui := identity.JoinEui
+	if joinEui == "" {
+		joinEui, err = GenerateLocalEUI()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	applicationKey := identity.ApplicationKey
+	if applicationKey == "" {
+		applicationKey, err = GenerateRootKey()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	networkKey, err := GenerateRootKey()
+	if err != nil {
+		return nil, err
+	}
+
+	sealedKeys, err := mgr.rootKeySealer.SealRootKeys(ctx, applicationKey, networkKey)
+	if err != nil {
+		return nil, err
+	}
+
 	// Build LoRaWAN configuration using builder pattern
 	lorawanConfigBuilder := iotv1.LoRaWANConfig_builder{
-		DeviceEui:        generateDeviceEUI(),                           // Generate unique device EUI
-		ApplicationEui:   generateApplicationEUI(),                      // Generate or use default app EUI
+		DeviceEui:        identity.DeviceEui,                            // Allocated by assignDeviceEUI when not supplied
+		ApplicationEui:   joinEui,                                       // Join EUI (AppEUI in LoRaWAN 1.0)
 		ApplicationId:    mgr.applicationId,                             // Should come from context/config
-		ApplicationKey:   generateApplicationKey(),                      // Generate 128-bit key
-		NetworkKey:       generateNetworkKey(),                          // Generate 128-bit key for LoRaWAN 1.1+
+		ApplicationKey:   sealedKeys[0],                                 // Sealed 128-bit root key
+		NetworkKey:       sealedKeys[1],                                 // Sealed 128-bit root key for LoRaWAN 1.1+
 		ActivationMethod: iotv1.ActivationMethod_ACTIVATION_METHOD_OTAA, // Default to OTAA
 		FrequencyPlan:    string(FreqPlanUS902_928),                     // Default US frequency plan
 		HardwareData:     hardwareData,
@@ -137,26 +684,24 @@ func (mgr *EndDeviceManager) buildLoRaWANConfig(ctx context.Context, createReq *
 	return lorawanConfigBuilder.Build(), nil
 }
 
-// generateDeviceEUI generates a unique 64-bit device EUI (16 hex characters).
-// TODO: Implement proper cryptographically secure ID generation.
-func generateDeviceEUI() string {
-	return "0123456789ABCDEF"
-}
+// assignDeviceEUI allocates a device EUI for a LoRaWAN end device that has none, or checks that a
+// factory-assigned one is not taken yet. Other hardware types are left untouched.
+func (mgr *EndDeviceManager) assignDeviceEUI(ctx context.Context, organizationId string, endDevice *iotv1.EndDevice) error {
+	lorawanConfig := endDevice.GetLorawanConfig()
+	if lorawanConfig == nil {
+		return nil
+	}
 
-// generateApplicationEUI generates a 64-bit application EUI (16 hex characters).
-// TODO: Implement proper cryptographically secure ID generation.
-func generateApplicationEUI() string {
-	return "FEDCBA9876543210"
-}
+	if lorawanConfig.GetDeviceEui() != "" {
+		return mgr.euiAllocator.CheckDeviceEUIAvailable(ctx, lorawanConfig.GetDeviceEui())
+	}
 
-// generateApplicationKey generates a 128-bit application key (32 hex characters).
-// TODO: Implement proper cryptographically secure key generation.
-func generateApplicationKey() string {
-	return "00112233445566778899AABBCCDDEEFF"
-}
+	deviceEui, err := mgr.euiAllocator.AllocateDeviceEUI(ctx, organizationId)
+	if err != nil {
+		return err
+	}
+
+	lorawanConfig.SetDeviceEui(deviceEui)
 
-// generateNetworkKey generates a 128-bit network key (32 hex characters).
-// TODO: Implement proper cryptographically secure key generation.
-func generateNetworkKey() string {
-	return "FFEEDDCCBBAA99887766554433221100"
+	return nil
 }
@@ -0,0 +1,272 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+var (
+	// ErrInvalidClaimCode is returned when a claim code is unknown, expired or already used.
+	ErrInvalidClaimCode = errors.New("invalid claim code")
+	// ErrInvalidClaimCodeTTL is returned when a claim code is requested with a lifetime outside the allowed range.
+	ErrInvalidClaimCodeTTL = errors.New("invalid claim code lifetime")
+	// ErrClaimRateLimited is returned when a user made too many failed claim attempts within the attempt window.
+	ErrClaimRateLimited = errors.New("too many failed claim attempts")
+)
+
+const (
+	// DefaultClaimCodeTTL is how long a claim code stays valid when no lifetime is requested.
+	DefaultClaimCodeTTL = 90 * 24 * time.Hour
+	// MaxClaimCodeTTL is the longest lifetime a claim code may be issued with.
+	MaxClaimCodeTTL = 2 * 365 * 24 * time.Hour
+	// maxFailedClaimAttempts is how many failed claim attempts a user may make within claimAttemptWindow.
+	maxFailedClaimAttempts = 5
+	// claimAttemptWindow is the period failed claim attempts are counted over.
+	claimAttemptWindow = 15 * time.Minute
+	// claimCodeBytes is the amount of randomness in a claim code.
+	claimCodeBytes = 15
+	// claimCodeGroupLength is the number of characters between the dashes of a formatted claim code.
+	claimCodeGroupLength = 4
+	// claimCodeGroupDivider separates the groups of a formatted claim code.
+	claimCodeGroupDivider = "-"
+)
+
+// RootKeyReasonClaimed is recorded with the root key rotation of a claimed end device.
+const RootKeyReasonClaimed = "end device claimed"
+
+// claimCodeEncoding encodes claim codes with the RFC 4648 base32 alphabet, which avoids characters that are
+// easily confused when a code is typed from a label.
+var claimCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// EndDeviceClaim is a one-time code that lets another organization take over a pre-provisioned end device.
+// Only a hash of the code is stored.
+type EndDeviceClaim struct {
+	EndDeviceId             string
+	OrganizationId          string
+	ExpiresAt               time.Time
+	CreatedAt               time.Time
+	ClaimedAt               time.Time
+	ClaimedByOrganizationId string
+}
+
+// Claimed reports whether the claim code has been used.
+func (claim *EndDeviceClaim) Claimed() bool {
+	return !claim.ClaimedAt.IsZero()
+}
+
+// GenerateClaimCode returns a random claim code in groups of four characters, such as "ABCD-EFGH-...".
+func GenerateClaimCode() (string, error) {
+	code := make([]byte, claimCodeBytes)
+	_, err := rand.Read(code)
+	if err != nil {
+		return "", stacktrace.NewStackTraceError(err)
+	}
+
+	encoded := claimCodeEncoding.EncodeToString(code)
+
+	groups := make([]string, 0, len(encoded)/claimCodeGroupLength+1)
+	for len(encoded) > claimCodeGroupLength {
+		groups = append(groups, encoded[:claimCodeGroupLength])
+		encoded = encoded[claimCodeGroupLength:]
+	}
+	groups = append(groups, encoded)
+
+	return strings.Join(groups, claimCodeGroupDivider), nil
+}
+
+// HashClaimCode returns the hash a claim code is stored and looked up by. Case, dashes and whitespace are
+// ignored so a code typed from a label matches the scanned one.
+func HashClaimCode(code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer(claimCodeGroupDivider, "", " ", "").Replace(strings.TrimSpace(code)))
+	hash := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(hash[:])
+}
+
+// EndDeviceClaimStorer defines the persistence operations for claiming end devices.
+type EndDeviceClaimStorer interface {
+	// SaveEndDeviceClaim stores the claim code of an end device, replacing any earlier code.
+	SaveEndDeviceClaim(ctx context.Context, claim *EndDeviceClaim, codeHash string) error
+	// GetEndDeviceClaim looks a claim up by the hash of its code. It fails with ErrInvalidClaimCode when none exists.
+	GetEndDeviceClaim(ctx context.Context, codeHash string) (*EndDeviceClaim, error)
+	// CountFailedClaimAttempts counts the failed claim attempts a user made since the given time.
+	CountFailedClaimAttempts(ctx context.Context, userId string, since time.Time) (int, error)
+	// RecordFailedClaimAttempt stores a failed claim attempt and drops the user's attempts older than pruneBefore.
+	RecordFailedClaimAttempt(ctx context.Context, id string, userId string, attemptedAt time.Time, pruneBefore time.Time) error
+	// ClaimEndDevice marks the claim with codeHash as used and moves the end device from previousOrganizationId
+	// into the claiming organization, removing it from the device groups and end device profile of its previous
+	// owner. When rotation is set it also replaces the root keys, records the rotation and applies the status
+	// transition, if any, as RotateRootKeys does. It fails with ErrInvalidClaimCode when the claim was used, reissued or expired
+	// concurrently. The sync function runs before the transaction is committed.
+	ClaimEndDevice(ctx context.Context, claim *EndDeviceClaim, codeHash string, previousOrganizationId string, rotation *RootKeyRotation, previous StoredRootKeys, sealed StoredRootKeys, transition *EndDeviceStatusTransition, sync EndDeviceSync) error
+}
+
+// EndDeviceClaimManager lets manufacturers pre-provision end devices with one-time claim codes, printed for example
+// as a QR code on the device's box, and lets installers claim those devices into their own organization.
+type EndDeviceClaimManager struct {
+	claimStore      EndDeviceClaimStorer
+	endDeviceStore  EndDeviceStorer
+	rootKeyRegister RootKeyRegister
+	rootKeySealer   RootKeySealer
+	stringId        StringId
+}
+
+// NewEndDeviceClaimManager creates a new instance of EndDeviceClaimManager with the provided dependencies.
+func NewEndDeviceClaimManager(claimStore EndDeviceClaimStorer, eds EndDeviceStorer, rkr RootKeyRegister, rootKeySealer RootKeySealer, stringId StringId) *EndDeviceClaimManager {
+	return &EndDeviceClaimManager{
+		claimStore:      claimStore,
+		endDeviceStore:  eds,
+		rootKeyRegister: rkr,
+		rootKeySealer:   rootKeySealer,
+		stringId:        stringId,
+	}
+}
+
+// IssueClaimCode creates a one-time claim code for an end device of the organization that expires after ttl,
+// or after DefaultClaimCodeTTL when ttl is zero. Issuing a new code invalidates any earlier one. The code itself
+// is only returned here; it cannot be read back later. Callers must make sure the user is a super admin or a
+// manufacturer allowed to create end devices in organizationId.
+func (mgr *EndDeviceClaimManager) IssueClaimCode(ctx context.Context, endDeviceId string, organizationId string, ttl time.Duration) (string, *EndDeviceClaim, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "IssueClaimCode")
+	defer span.End()
+
+	if ttl == 0 {
+		ttl = DefaultClaimCodeTTL
+	}
+
+	if ttl < 0 || ttl > MaxClaimCodeTTL {
+		return "", nil, stacktrace.NewStackTraceErrorf("%w: %s must be between 0 and %s", ErrInvalidClaimCodeTTL, ttl, MaxClaimCodeTTL)
+	}
+
+	endDevice, deviceOrgId, err := mgr.endDeviceStore.GetEndDeviceWithOrganization(ctx, endDeviceId)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if deviceOrgId != organizationId {
+		return "", nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceNotFound, endDeviceId)
+	}
+
+	if endDevice.GetStatus() == iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED {
+		return "", nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceDisabled, endDeviceId)
+	}
+
+	code, err := GenerateClaimCode()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now().UTC()
+	claim := &EndDeviceClaim{
+		EndDeviceId:    endDeviceId,
+		OrganizationId: organizationId,
+		ExpiresAt:      now.Add(ttl),
+		CreatedAt:      now,
+	}
+
+	err = mgr.claimStore.SaveEndDeviceClaim(ctx, claim, HashClaimCode(code))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return code, claim, nil
+}
+
+// ClaimEndDevice moves the end device a claim code was issued for into the organization of the claiming user.
+// The code can only be used once and only before it expires. The root keys of LoRaWAN devices are replaced and
+// pushed to the Join Server, so the previous owner can no longer use them, and the device waits in PENDING until it
+// re-joins. Users are limited to maxFailedClaimAttempts failed attempts per claimAttemptWindow. Callers must make
+// sure the user may create end devices in organizationId.
+func (mgr *EndDeviceClaimManager) ClaimEndDevice(ctx context.Context, code string, organizationId string, userId string) (*EndDeviceClaim, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ClaimEndDevice")
+	defer span.End()
+
+	now := time.Now().UTC()
+	windowStart := now.Add(-claimAttemptWindow)
+
+	failed, err := mgr.claimStore.CountFailedClaimAttempts(ctx, userId, windowStart)
+	if err != nil {
+		return nil, err
+	}
+
+	if failed >= maxFailedClaimAttempts {
+		return nil, stacktrace.NewStackTraceErrorf("%w: try again in %s", ErrClaimRateLimited, claimAttemptWindow)
+	}
+
+	codeHash := HashClaimCode(code)
+	claim, err := mgr.claimStore.GetEndDeviceClaim(ctx, codeHash)
+	if err == nil && (claim.Claimed() || !now.Before(claim.ExpiresAt)) {
+		err = stacktrace.NewStackTraceErrorf("%w: code for %s was used or has expired", ErrInvalidClaimCode, claim.EndDeviceId)
+	}
+	if err != nil {
+		if errors.Is(err, ErrInvalidClaimCode) {
+			recordErr := mgr.claimStore.RecordFailedClaimAttempt(ctx, mgr.stringId(), userId, now, windowStart)
+			if recordErr != nil {
+				return nil, stacktrace.NewStackTraceError(errors.Join(err, recordErr))
+			}
+		}
+		return nil, err
+	}
+
+	current, deviceOrgId, err := mgr.endDeviceStore.GetEndDevice(ctx, claim.EndDeviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if current.GetStatus() == iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceDisabled, claim.EndDeviceId)
+	}
+
+	claim.ClaimedAt = now
+	claim.ClaimedByOrganizationId = organizationId
+
+	// Replace the root keys so whoever handled the device before can no longer join it
+	var pending *pendingRootKeyRotation
+	var sync EndDeviceSync
+	synced := false
+	if current.GetLorawanConfig() != nil {
+		pending, err = prepareRootKeyRotation(ctx, mgr.rootKeySealer, mgr.stringId, current, RootKeys{}, RootKeyReasonClaimed, StatusReasonClaimed, now)
+		if err != nil {
+			return nil, err
+		}
+
+		sync = func(ctx context.Context) error {
+			err := mgr.rootKeyRegister.UpdateEndDeviceRootKeys(ctx, pending.rotated)
+			if err != nil {
+				return stacktrace.NewStackTraceError(err)
+			}
+			synced = true
+			return nil
+		}
+	}
+
+	var rotation *RootKeyRotation
+	var previous, sealed StoredRootKeys
+	var transition *EndDeviceStatusTransition
+	if pending != nil {
+		rotation, previous, sealed, transition = pending.rotation, pending.previous, pending.sealed, pending.transition
+	}
+
+	err = mgr.claimStore.ClaimEndDevice(ctx, claim, codeHash, deviceOrgId, rotation, previous, sealed, transition, sync)
+	if err != nil {
+		if synced {
+			// The Join Server already has the new keys; put the previous ones back so both sides agree again
+			restoreErr := mgr.rootKeyRegister.UpdateEndDeviceRootKeys(ctx, current)
+			if restoreErr != nil {
+				return nil, stacktrace.NewStackTraceError(errors.Join(err, restoreErr))
+			}
+		}
+		return nil, err
+	}
+
+	return claim, nil
+}
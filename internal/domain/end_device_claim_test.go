@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateClaimCode(t *testing.T) {
+	code, err := GenerateClaimCode()
+	assert.NoError(t, err)
+	assert.Regexp(t, `^[A-Z2-7]{4}(-[A-Z2-7]{4}){5}$`, code)
+
+	other, err := GenerateClaimCode()
+	assert.NoError(t, err)
+	assert.NotEqual(t, code, other)
+}
+
+func TestHashClaimCode(t *testing.T) {
+	code, err := GenerateClaimCode()
+	assert.NoError(t, err)
+
+	hash := HashClaimCode(code)
+	assert.Len(t, hash, 64)
+
+	// Codes typed from a label match the scanned code
+	assert.Equal(t, hash, HashClaimCode(strings.ToLower(code)))
+	assert.Equal(t, hash, HashClaimCode(" "+strings.ReplaceAll(code, "-", "")+" "))
+	assert.Equal(t, hash, HashClaimCode(strings.ReplaceAll(code, "-", " ")))
+
+	other, err := GenerateClaimCode()
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, HashClaimCode(other))
+}
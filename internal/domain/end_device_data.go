@@ -40,22 +40,26 @@ type EnvelopeQuerier interface {
 
 // EndDeviceDataManager orchestrates end device data query operations.
 type EndDeviceDataManager struct {
-	envelopeStore EnvelopeQuerier
-	validator     Validate
+	envelopeStore  EnvelopeQuerier
+	deviceResolver EndDeviceResolver
+	validator      Validate
 }
 
 // NewEndDeviceDataManager creates a new instance of EndDeviceDataManager.
-func NewEndDeviceDataManager(envelopeStore EnvelopeQuerier, validator Validate) *EndDeviceDataManager {
+func NewEndDeviceDataManager(envelopeStore EnvelopeQuerier, deviceResolver EndDeviceResolver, validator Validate) *EndDeviceDataManager {
 	return &EndDeviceDataManager{
-		envelopeStore: envelopeStore,
-		validator:     validator,
+		envelopeStore:  envelopeStore,
+		deviceResolver: deviceResolver,
+		validator:      validator,
 	}
 }
 
-// QueryEndDeviceData queries time-series sensor data with histogram aggregation
+// QueryEndDeviceData queries time-series sensor data with histogram aggregation.
+// The request's end_device_ids are combined with the device groups in targets, or replaced by its label selector.
 func (mgr *EndDeviceDataManager) QueryEndDeviceData(
 	ctx context.Context,
 	req *iotv1.QueryEndDeviceDataRequest,
+	targets EndDeviceTargets,
 ) (*iotv1.QueryEndDeviceDataResponse, error) {
 	ctx, span := telemetry.Tracer().Start(ctx, "EndDeviceDataManager.QueryEndDeviceData")
 	defer span.End()
@@ -66,11 +70,25 @@ func (mgr *EndDeviceDataManager) QueryEndDeviceData(
 		return nil, stacktrace.NewStackTraceError(err)
 	}
 
+	targets.EndDeviceIds = req.GetEndDeviceIds()
+	deviceIDs := targets.EndDeviceIds
+	if len(targets.GroupIds) > 0 || !targets.Labels.Empty() {
+		deviceIDs, err = ResolveEndDeviceTargets(ctx, mgr.deviceResolver, req.GetOrganizationId(), targets)
+		if err != nil {
+			return nil, err
+		}
+
+		// An empty device list means "every device" to the store, so targets matching nothing must stop here
+		if len(deviceIDs) == 0 {
+			return ConvertToProtoResponse(nil, 0, CalculateTimeBucketInterval(req.GetStartTime().AsTime(), req.GetEndTime().AsTime())), nil
+		}
+	}
+
 	// Query data from store
 	results, err := mgr.envelopeStore.QueryEndDeviceData(
 		ctx,
 		req.GetOrganizationId(),
-		req.GetEndDeviceIds(),
+		deviceIDs,
 		req.GetStartTime().AsTime(),
 		req.GetEndTime().AsTime(),
 		req.GetFieldPath(),
@@ -87,7 +105,7 @@ func (mgr *EndDeviceDataManager) QueryEndDeviceData(
 	)
 
 	// Determine device count
-	deviceCount := len(req.GetEndDeviceIds())
+	deviceCount := len(deviceIDs)
 	if deviceCount == 0 {
 		// Query all devices in organization - would need to fetch count from PostgreSQL
 		// For now, we can leave this as 0 or implement a separate query
@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestEndDeviceDataManager_QueryEndDeviceData(t *testing.T) {
+	newManager := func() (*EndDeviceDataManager, *memoryDeviceGroupStore, *recordingEnvelopeQuerier) {
+		groups := newMemoryDeviceGroupStore()
+		groups.groups["group-1"] = &DeviceGroup{Id: "group-1", OrganizationId: "org-1", Name: "greenhouse"}
+		groups.groups["group-2"] = &DeviceGroup{Id: "group-2", OrganizationId: "org-1", Name: "freezers"}
+		groups.groups["group-x"] = &DeviceGroup{Id: "group-x", OrganizationId: "org-2", Name: "van"}
+		groups.members["group-1"] = []string{"device-2", "device-3"}
+		groups.members["group-2"] = []string{"device-3", "device-4"}
+		groups.members["group-x"] = []string{"device-x"}
+		querier := &recordingEnvelopeQuerier{}
+		return NewEndDeviceDataManager(querier, groups, func(any) error { return nil }), groups, querier
+	}
+
+	request := func(endDeviceIds ...string) *iotv1.QueryEndDeviceDataRequest {
+		start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+		return iotv1.QueryEndDeviceDataRequest_builder{
+			OrganizationId: "org-1",
+			EndDeviceIds:   endDeviceIds,
+			StartTime:      timestamppb.New(start),
+			EndTime:        timestamppb.New(start.Add(time.Hour)),
+			FieldPath:      "temperature",
+		}.Build()
+	}
+
+	t.Run("queries the given devices as they are", func(t *testing.T) {
+		mgr, _, querier := newManager()
+
+		_, err := mgr.QueryEndDeviceData(context.Background(), request("device-1"), EndDeviceTargets{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{{"device-1"}}, querier.deviceIds)
+	})
+
+	t.Run("merges group members into the given devices without duplicates", func(t *testing.T) {
+		mgr, _, querier := newManager()
+
+		_, err := mgr.QueryEndDeviceData(context.Background(), request("device-1", "device-3"), EndDeviceTargets{GroupIds: []string{"group-1", "group-2"}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{{"device-1", "device-3", "device-2", "device-4"}}, querier.deviceIds)
+	})
+
+	t.Run("rejects groups of other organizations", func(t *testing.T) {
+		mgr, _, querier := newManager()
+
+		_, err := mgr.QueryEndDeviceData(context.Background(), request(), EndDeviceTargets{GroupIds: []string{"group-x"}})
+
+		assert.ErrorIs(t, err, ErrDeviceGroupNotFound)
+		assert.Empty(t, querier.deviceIds)
+	})
+
+	t.Run("returns nothing for empty groups instead of querying every device", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, groups, querier := newManager()
+		groups.members["group-1"] = nil
+
+		response, err := mgr.QueryEndDeviceData(context.Background(), request(), EndDeviceTargets{GroupIds: []string{"group-1"}})
+
+		assert.NoError(err)
+		assert.Empty(response.GetTimeSeries())
+		assert.Empty(querier.deviceIds)
+	})
+
+	t.Run("selects devices by label", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, groups, querier := newManager()
+		groups.labelMatches = []string{"device-5"}
+		selector, err := ParseLabelSelector("building=north")
+		assert.NoError(err)
+
+		_, err = mgr.QueryEndDeviceData(context.Background(), request(), EndDeviceTargets{Labels: selector})
+
+		assert.NoError(err)
+		assert.Equal([]LabelSelector{selector}, groups.selectors)
+		assert.Equal([][]string{{"device-5"}}, querier.deviceIds)
+	})
+
+	t.Run("rejects label selectors combined with devices", func(t *testing.T) {
+		mgr, _, _ := newManager()
+		selector, err := ParseLabelSelector("building=north")
+		assert.NoError(t, err)
+
+		_, err = mgr.QueryEndDeviceData(context.Background(), request("device-1"), EndDeviceTargets{Labels: selector})
+
+		assert.ErrorIs(t, err, ErrInvalidMessageFormat)
+	})
+}
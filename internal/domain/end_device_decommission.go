@@ -0,0 +1,466 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/ponix-dev/ponix/internal/runner"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+var (
+	// ErrEndDeviceDecommissionNotFound is returned when an end device has not been decommissioned or its decommission
+	// is not visible to the requesting organization.
+	ErrEndDeviceDecommissionNotFound = errors.New("end device decommission not found")
+	// ErrEndDeviceDecommissionExists is returned when an end device already has a decommission.
+	ErrEndDeviceDecommissionExists = errors.New("end device decommission already exists")
+	// ErrEndDeviceDecommissionConflict is returned when a decommission was advanced concurrently.
+	ErrEndDeviceDecommissionConflict = errors.New("end device decommission changed concurrently")
+	// ErrInvalidEndDeviceDataPolicy is returned when a decommission is requested with an unknown data policy
+	// or a negative retention period.
+	ErrInvalidEndDeviceDataPolicy = errors.New("invalid end device data policy")
+)
+
+const (
+	// DefaultEndDeviceDataRetention is how long the data of a decommissioned end device is kept under the retain
+	// policy when no retention period is requested.
+	DefaultEndDeviceDataRetention = 30 * 24 * time.Hour
+	// decommissionBatchSize is the number of due decommissions advanced per run.
+	decommissionBatchSize = 100
+	// decommissionPurgeCheckInterval is how often a running data purge is checked for completion.
+	decommissionPurgeCheckInterval = time.Minute
+	// decommissionRetryBaseDelay is the delay before a failed decommission step is first retried. It doubles with
+	// every further failure up to decommissionRetryMaxDelay.
+	decommissionRetryBaseDelay = time.Minute
+	decommissionRetryMaxDelay  = time.Hour
+)
+
+// EndDeviceDataPolicy decides what happens to the stored data of a decommissioned end device.
+type EndDeviceDataPolicy string
+
+const (
+	// EndDeviceDataRetain keeps the device's data for a retention period before purging it.
+	EndDeviceDataRetain EndDeviceDataPolicy = "retain"
+	// EndDeviceDataPurge purges the device's data as soon as its credentials are revoked.
+	EndDeviceDataPurge EndDeviceDataPolicy = "purge"
+)
+
+// EndDeviceDecommissionStep is the last completed step of an end device decommission.
+type EndDeviceDecommissionStep string
+
+// Steps of an end device decommission, in the order they complete.
+const (
+	DecommissionStepRequested          EndDeviceDecommissionStep = "requested"
+	DecommissionStepDisabled           EndDeviceDecommissionStep = "disabled"
+	DecommissionStepDeregistered       EndDeviceDecommissionStep = "deregistered"
+	DecommissionStepCredentialsRevoked EndDeviceDecommissionStep = "credentials_revoked"
+	DecommissionStepRetainingData      EndDeviceDecommissionStep = "retaining_data"
+	DecommissionStepPurgingData        EndDeviceDecommissionStep = "purging_data"
+	DecommissionStepCompleted          EndDeviceDecommissionStep = "completed"
+)
+
+// EndDeviceDecommission tracks the decommission of an end device that left service: it is disabled, removed
+// from TTN, its credentials are revoked and its stored data is purged once PurgeAfter has passed.
+// Failed steps are retried with a growing delay until they succeed.
+type EndDeviceDecommission struct {
+	Id             string
+	EndDeviceId    string
+	OrganizationId string
+	// RequestedBy is the user that requested the decommission.
+	RequestedBy string
+	DataPolicy  EndDeviceDataPolicy
+	Step        EndDeviceDecommissionStep
+	// PurgeAfter is when the device's data is purged; it equals CreatedAt under the purge policy.
+	PurgeAfter time.Time
+	// Attempts and LastError describe the failures of the current step since it last succeeded.
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	CompletedAt   time.Time
+}
+
+// Completed reports whether every step of the decommission has finished.
+func (decommission *EndDeviceDecommission) Completed() bool {
+	return decommission.Step == DecommissionStepCompleted
+}
+
+// EndDeviceDecommissionEvent is an audit record of a decommission step completing or failing.
+type EndDeviceDecommissionEvent struct {
+	Id             string
+	DecommissionId string
+	Step           EndDeviceDecommissionStep
+	// Error is empty when the step completed.
+	Error      string
+	OccurredAt time.Time
+}
+
+// EndDeviceDecommissionRequest asks for an end device to be decommissioned.
+type EndDeviceDecommissionRequest struct {
+	EndDeviceId    string
+	OrganizationId string
+	RequestedBy    string
+	DataPolicy     EndDeviceDataPolicy
+	// Retention is how long data is kept under the retain policy; zero uses the manager's default.
+	Retention time.Duration
+}
+
+// purgeAfter works out when the data of a device decommissioned at requestedAt is purged.
+func (req EndDeviceDecommissionRequest) purgeAfter(requestedAt time.Time, defaultRetention time.Duration) (time.Time, error) {
+	switch req.DataPolicy {
+	case EndDeviceDataPurge:
+		return requestedAt, nil
+	case EndDeviceDataRetain:
+		if req.Retention < 0 {
+			return time.Time{}, stacktrace.NewStackTraceErrorf("%w: retention cannot be negative", ErrInvalidEndDeviceDataPolicy)
+		}
+
+		retention := req.Retention
+		if retention == 0 {
+			retention = defaultRetention
+		}
+		return requestedAt.Add(retention), nil
+	default:
+		return time.Time{}, stacktrace.NewStackTraceErrorf("%w: %q", ErrInvalidEndDeviceDataPolicy, req.DataPolicy)
+	}
+}
+
+// decommissionRetryDelay is how long to wait before retrying a step that has failed attempts times in a row.
+func decommissionRetryDelay(attempts int) time.Duration {
+	delay := decommissionRetryBaseDelay
+	for i := 1; i < attempts && delay < decommissionRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, decommissionRetryMaxDelay)
+}
+
+// EndDeviceDecommissionStorer defines the persistence operations for end device decommissions.
+type EndDeviceDecommissionStorer interface {
+	// CreateEndDeviceDecommission stores a new decommission with its first event. It fails with
+	// ErrEndDeviceDecommissionExists when the end device already has one.
+	CreateEndDeviceDecommission(ctx context.Context, decommission *EndDeviceDecommission, event *EndDeviceDecommissionEvent) error
+	// GetEndDeviceDecommission fails with ErrEndDeviceDecommissionNotFound when the end device has none.
+	GetEndDeviceDecommission(ctx context.Context, endDeviceId string) (*EndDeviceDecommission, error)
+	ListEndDeviceDecommissionEvents(ctx context.Context, decommissionId string) ([]*EndDeviceDecommissionEvent, error)
+	// ListDueEndDeviceDecommissions returns up to limit unfinished decommissions whose next attempt is due at now.
+	ListDueEndDeviceDecommissions(ctx context.Context, now time.Time, limit int) ([]*EndDeviceDecommission, error)
+	// SaveEndDeviceDecommissionProgress stores the decommission's state and the event, when one is given, failing
+	// with ErrEndDeviceDecommissionConflict when its step is no longer previousStep.
+	SaveEndDeviceDecommissionProgress(ctx context.Context, decommission *EndDeviceDecommission, previousStep EndDeviceDecommissionStep, event *EndDeviceDecommissionEvent) error
+	// RevokeEndDeviceCredentials clears the stored root keys of an end device and any claim code issued for it.
+	RevokeEndDeviceCredentials(ctx context.Context, endDeviceId string) error
+}
+
+// EndDeviceDisabler disables end devices so that further data they send is rejected.
+type EndDeviceDisabler interface {
+	DecommissionEndDevice(ctx context.Context, endDeviceId string, organizationId string, reason string) error
+}
+
+// EndDeviceDataPurger deletes the stored data of end devices. Purges may run asynchronously; a purge has finished
+// once no data of the device is left.
+type EndDeviceDataPurger interface {
+	PurgeEndDeviceData(ctx context.Context, endDeviceId string) error
+	CountEndDeviceData(ctx context.Context, endDeviceId string) (uint64, error)
+}
+
+// EndDeviceDecommissionManager runs end device decommissions step by step. Every step is safe to repeat, so a
+// decommission interrupted by a failure resumes where it stopped.
+type EndDeviceDecommissionManager struct {
+	decommissionStore EndDeviceDecommissionStorer
+	endDeviceStore    EndDeviceStorer
+	endDeviceDisabler EndDeviceDisabler
+	endDeviceRegister EndDeviceRegister
+	dataPurger        EndDeviceDataPurger
+	stringId          StringId
+	retention         time.Duration
+}
+
+// NewEndDeviceDecommissionManager creates a new instance of EndDeviceDecommissionManager with the provided
+// dependencies. A zero retention falls back to DefaultEndDeviceDataRetention.
+func NewEndDeviceDecommissionManager(decommissionStore EndDeviceDecommissionStorer, eds EndDeviceStorer, disabler EndDeviceDisabler, edr EndDeviceRegister, dataPurger EndDeviceDataPurger, stringId StringId, retention time.Duration) *EndDeviceDecommissionManager {
+	if retention <= 0 {
+		retention = DefaultEndDeviceDataRetention
+	}
+
+	return &EndDeviceDecommissionManager{
+		decommissionStore: decommissionStore,
+		endDeviceStore:    eds,
+		endDeviceDisabler: disabler,
+		endDeviceRegister: edr,
+		dataPurger:        dataPurger,
+		stringId:          stringId,
+		retention:         retention,
+	}
+}
+
+// StartEndDeviceDecommission records the decommission of an end device and runs its steps until one has to wait
+// for the retention period or a running purge, or fails. Failed steps are recorded on the returned decommission
+// and retried by ProcessEndDeviceDecommissions. Starting the decommission of a device that already has one
+// resumes the existing decommission instead.
+func (mgr *EndDeviceDecommissionManager) StartEndDeviceDecommission(ctx context.Context, req EndDeviceDecommissionRequest) (*EndDeviceDecommission, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "StartEndDeviceDecommission")
+	defer span.End()
+
+	_, deviceOrgId, err := mgr.endDeviceStore.GetEndDeviceWithOrganization(ctx, req.EndDeviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if deviceOrgId != req.OrganizationId {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceNotFound, req.EndDeviceId)
+	}
+
+	now := time.Now().UTC()
+	purgeAfter, err := req.purgeAfter(now, mgr.retention)
+	if err != nil {
+		return nil, err
+	}
+
+	decommission := &EndDeviceDecommission{
+		Id:             mgr.stringId(),
+		EndDeviceId:    req.EndDeviceId,
+		OrganizationId: req.OrganizationId,
+		RequestedBy:    req.RequestedBy,
+		DataPolicy:     req.DataPolicy,
+		Step:           DecommissionStepRequested,
+		PurgeAfter:     purgeAfter,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	err = mgr.decommissionStore.CreateEndDeviceDecommission(ctx, decommission, &EndDeviceDecommissionEvent{
+		Id:             mgr.stringId(),
+		DecommissionId: decommission.Id,
+		Step:           DecommissionStepRequested,
+		OccurredAt:     now,
+	})
+	if err != nil {
+		if !errors.Is(err, ErrEndDeviceDecommissionExists) {
+			return nil, err
+		}
+
+		decommission, err = mgr.decommissionStore.GetEndDeviceDecommission(ctx, req.EndDeviceId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = mgr.advance(ctx, decommission)
+	if err != nil {
+		return nil, err
+	}
+
+	return decommission, nil
+}
+
+// GetEndDeviceDecommission retrieves the decommission of an end device together with its audit trail, oldest
+// event first.
+func (mgr *EndDeviceDecommissionManager) GetEndDeviceDecommission(ctx context.Context, endDeviceId string, organizationId string) (*EndDeviceDecommission, []*EndDeviceDecommissionEvent, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetEndDeviceDecommission")
+	defer span.End()
+
+	decommission, err := mgr.decommissionStore.GetEndDeviceDecommission(ctx, endDeviceId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if decommission.OrganizationId != organizationId {
+		return nil, nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceDecommissionNotFound, endDeviceId)
+	}
+
+	events, err := mgr.decommissionStore.ListEndDeviceDecommissionEvents(ctx, decommission.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return decommission, events, nil
+}
+
+// ProcessEndDeviceDecommissions advances up to one batch of decommissions that are due: retries of failed
+// steps, retention periods that ended and purges to check on. It returns how many decommissions completed.
+func (mgr *EndDeviceDecommissionManager) ProcessEndDeviceDecommissions(ctx context.Context) (int, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ProcessEndDeviceDecommissions")
+	defer span.End()
+
+	due, err := mgr.decommissionStore.ListDueEndDeviceDecommissions(ctx, time.Now().UTC(), decommissionBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	completed := 0
+	for _, decommission := range due {
+		err = mgr.advance(ctx, decommission)
+		if err != nil {
+			// Another process advanced the decommission since it was listed
+			if errors.Is(err, ErrEndDeviceDecommissionConflict) {
+				continue
+			}
+			return completed, err
+		}
+
+		if decommission.Completed() {
+			completed++
+		}
+	}
+
+	return completed, nil
+}
+
+// advance runs the steps of a decommission until it completes, has to wait or a step fails, saving its progress
+// after every step. Step failures are saved on the decommission rather than returned.
+func (mgr *EndDeviceDecommissionManager) advance(ctx context.Context, decommission *EndDeviceDecommission) error {
+	for !decommission.Completed() {
+		now := time.Now().UTC()
+		if decommission.NextAttemptAt.After(now) {
+			return nil
+		}
+
+		previousStep := decommission.Step
+		next, err := mgr.runStep(ctx, decommission, now)
+		if err != nil {
+			decommission.Attempts++
+			decommission.LastError = err.Error()
+			decommission.NextAttemptAt = now.Add(decommissionRetryDelay(decommission.Attempts))
+			decommission.UpdatedAt = now
+
+			slog.Warn("end device decommission step failed",
+				slog.String("end_device_id", decommission.EndDeviceId),
+				slog.String("step", string(previousStep)),
+				slog.Int("attempts", decommission.Attempts),
+				stacktrace.ErrorAttribute(err),
+			)
+
+			return mgr.decommissionStore.SaveEndDeviceDecommissionProgress(ctx, decommission, previousStep, &EndDeviceDecommissionEvent{
+				Id:             mgr.stringId(),
+				DecommissionId: decommission.Id,
+				Step:           previousStep,
+				Error:          err.Error(),
+				OccurredAt:     now,
+			})
+		}
+
+		decommission.Attempts = 0
+		decommission.LastError = ""
+		decommission.UpdatedAt = now
+
+		// Waiting on a running purge is not worth an audit event
+		var event *EndDeviceDecommissionEvent
+		if next != previousStep {
+			decommission.Step = next
+			event = &EndDeviceDecommissionEvent{
+				Id:             mgr.stringId(),
+				DecommissionId: decommission.Id,
+				Step:           next,
+				OccurredAt:     now,
+			}
+		}
+
+		switch next {
+		case DecommissionStepRetainingData:
+			decommission.NextAttemptAt = decommission.PurgeAfter
+		case DecommissionStepPurgingData:
+			decommission.NextAttemptAt = now.Add(decommissionPurgeCheckInterval)
+		case DecommissionStepCompleted:
+			decommission.CompletedAt = now
+		}
+
+		err = mgr.decommissionStore.SaveEndDeviceDecommissionProgress(ctx, decommission, previousStep, event)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runStep runs the step that follows the decommission's last completed step and returns the step it reached.
+func (mgr *EndDeviceDecommissionManager) runStep(ctx context.Context, decommission *EndDeviceDecommission, now time.Time) (EndDeviceDecommissionStep, error) {
+	switch decommission.Step {
+	case DecommissionStepRequested:
+		err := mgr.endDeviceDisabler.DecommissionEndDevice(ctx, decommission.EndDeviceId, decommission.OrganizationId, StatusReasonDecommissioned)
+		if err != nil {
+			return "", err
+		}
+		return DecommissionStepDisabled, nil
+
+	case DecommissionStepDisabled:
+		endDevice, _, err := mgr.endDeviceStore.GetEndDevice(ctx, decommission.EndDeviceId)
+		if err != nil {
+			return "", err
+		}
+
+		if endDevice.GetHardwareType() == iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_LORAWAN {
+			err = mgr.endDeviceRegister.DeleteEndDevice(ctx, endDevice)
+			if err != nil {
+				return "", err
+			}
+		}
+		return DecommissionStepDeregistered, nil
+
+	case DecommissionStepDeregistered:
+		err := mgr.decommissionStore.RevokeEndDeviceCredentials(ctx, decommission.EndDeviceId)
+		if err != nil {
+			return "", err
+		}
+		return DecommissionStepCredentialsRevoked, nil
+
+	case DecommissionStepCredentialsRevoked, DecommissionStepRetainingData:
+		if now.Before(decommission.PurgeAfter) {
+			return DecommissionStepRetainingData, nil
+		}
+
+		err := mgr.dataPurger.PurgeEndDeviceData(ctx, decommission.EndDeviceId)
+		if err != nil {
+			return "", err
+		}
+		return DecommissionStepPurgingData, nil
+
+	case DecommissionStepPurgingData:
+		remaining, err := mgr.dataPurger.CountEndDeviceData(ctx, decommission.EndDeviceId)
+		if err != nil {
+			return "", err
+		}
+
+		if remaining > 0 {
+			return DecommissionStepPurgingData, nil
+		}
+		return DecommissionStepCompleted, nil
+
+	default:
+		return "", stacktrace.NewStackTraceErrorf("unknown end device decommission step %q", decommission.Step)
+	}
+}
+
+// EndDeviceDecommissionRunner returns a runner function that advances due end device decommissions every interval
+// until the runner's context is cancelled. Failed runs are logged and retried on the next tick.
+func EndDeviceDecommissionRunner(mgr *EndDeviceDecommissionManager, interval time.Duration) runner.RunnerFunc {
+	return func(ctx context.Context) func() error {
+		return func() error {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+					completed, err := mgr.ProcessEndDeviceDecommissions(ctx)
+					if err != nil {
+						slog.Error("failed to process end device decommissions", stacktrace.ErrorAttribute(err))
+						continue
+					}
+
+					if completed > 0 {
+						slog.Info("completed end device decommissions", slog.Int("count", completed))
+					}
+				}
+			}
+		}
+	}
+}
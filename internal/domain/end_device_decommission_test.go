@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndDeviceDecommissionRequest_purgeAfter(t *testing.T) {
+	requestedAt := time.Date(2025, 11, 8, 12, 0, 0, 0, time.UTC)
+
+	t.Run("purge policy purges right away", func(t *testing.T) {
+		purgeAfter, err := EndDeviceDecommissionRequest{DataPolicy: EndDeviceDataPurge, Retention: time.Hour}.purgeAfter(requestedAt, DefaultEndDeviceDataRetention)
+
+		assert.NoError(t, err)
+		assert.Equal(t, requestedAt, purgeAfter)
+	})
+
+	t.Run("retain policy keeps data for the retention period", func(t *testing.T) {
+		assert := assert.New(t)
+
+		purgeAfter, err := EndDeviceDecommissionRequest{DataPolicy: EndDeviceDataRetain, Retention: 48 * time.Hour}.purgeAfter(requestedAt, DefaultEndDeviceDataRetention)
+		assert.NoError(err)
+		assert.Equal(requestedAt.Add(48*time.Hour), purgeAfter)
+
+		purgeAfter, err = EndDeviceDecommissionRequest{DataPolicy: EndDeviceDataRetain}.purgeAfter(requestedAt, DefaultEndDeviceDataRetention)
+		assert.NoError(err)
+		assert.Equal(requestedAt.Add(DefaultEndDeviceDataRetention), purgeAfter)
+	})
+
+	t.Run("rejects invalid policies", func(t *testing.T) {
+		for _, req := range []EndDeviceDecommissionRequest{
+			{DataPolicy: "archive"},
+			{},
+			{DataPolicy: EndDeviceDataRetain, Retention: -time.Hour},
+		} {
+			_, err := req.purgeAfter(requestedAt, DefaultEndDeviceDataRetention)
+			assert.ErrorIs(t, err, ErrInvalidEndDeviceDataPolicy, string(req.DataPolicy))
+		}
+	})
+}
+
+func TestDecommissionRetryDelay(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(time.Minute, decommissionRetryDelay(1))
+	assert.Equal(2*time.Minute, decommissionRetryDelay(2))
+	assert.Equal(32*time.Minute, decommissionRetryDelay(6))
+	assert.Equal(time.Hour, decommissionRetryDelay(7))
+	assert.Equal(time.Hour, decommissionRetryDelay(100))
+}
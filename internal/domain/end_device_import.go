@@ -0,0 +1,450 @@
+package domain
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+)
+
+var (
+	// ErrInvalidImportFile is returned when an end device import file cannot be read as a whole.
+	ErrInvalidImportFile = errors.New("invalid end device import file")
+)
+
+const (
+	// DefaultEndDeviceImportBatchSize is the number of end devices stored per transaction when no batch size is requested.
+	DefaultEndDeviceImportBatchSize = 100
+	// MaxEndDeviceImportBatchSize is the largest number of end devices stored in a single transaction.
+	MaxEndDeviceImportBatchSize = 1000
+)
+
+// EndDeviceImportFormat identifies the encoding of an end device import file.
+type EndDeviceImportFormat string
+
+const (
+	// EndDeviceImportCSV is a CSV file with a header row naming the columns.
+	// Supported columns are name, description, hardware_type, hardware_type_id, labels, attributes and the
+	// factory-assigned LoRaWAN device_eui, join_eui and application_key.
+	// Labels use the key=value,key=value form and attributes hold a JSON object.
+	EndDeviceImportCSV EndDeviceImportFormat = "csv"
+	// EndDeviceImportNDJSON is a file with one JSON object per line using the same field names as the CSV columns.
+	EndDeviceImportNDJSON EndDeviceImportFormat = "ndjson"
+)
+
+// EndDeviceImportRow is a single end device read from an import file.
+// Err is set when the row could not be parsed; such rows are reported but never created.
+type EndDeviceImportRow struct {
+	Line     int
+	Request  *iotv1.CreateEndDeviceRequest
+	Metadata EndDeviceMetadata
+	Identity LoRaWANIdentity
+	Err      error
+}
+
+// EndDeviceImportOptions controls how an import is carried out.
+type EndDeviceImportOptions struct {
+	// DryRun validates every row without creating any devices.
+	DryRun bool
+	// BatchSize is the number of devices stored per transaction.
+	BatchSize int
+}
+
+// EndDeviceImportResult is the outcome of importing a single row.
+type EndDeviceImportResult struct {
+	Line        int    `json:"line"`
+	Name        string `json:"name,omitempty"`
+	EndDeviceId string `json:"end_device_id,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// EndDeviceImportReport summarizes an import with one result per row.
+// On a dry run Created stays zero and Valid counts the rows that would have been created.
+type EndDeviceImportReport struct {
+	DryRun  bool                    `json:"dry_run"`
+	Valid   int                     `json:"valid"`
+	Created int                     `json:"created"`
+	Failed  int                     `json:"failed"`
+	Results []EndDeviceImportResult `json:"results"`
+}
+
+// endDeviceImportRecord is the field layout shared by both import formats.
+type endDeviceImportRecord struct {
+	Name           string          `json:"name"`
+	Description    string          `json:"description"`
+	HardwareType   json.RawMessage `json:"hardware_type"`
+	HardwareTypeId string          `json:"hardware_type_id"`
+	Labels         Labels          `json:"labels"`
+	Attributes     map[string]any  `json:"attributes"`
+	DeviceEui      string          `json:"device_eui"`
+	JoinEui        string          `json:"join_eui"`
+	ApplicationKey string          `json:"application_key"`
+}
+
+// ParseEndDeviceImport reads every row of an import file. Rows that cannot be parsed are returned with Err set
+// so they can be reported alongside the rest; an error is only returned when the file itself is unusable.
+func ParseEndDeviceImport(reader io.Reader, format EndDeviceImportFormat) ([]EndDeviceImportRow, error) {
+	switch format {
+	case EndDeviceImportCSV:
+		return parseEndDeviceImportCSV(reader)
+	case EndDeviceImportNDJSON:
+		return parseEndDeviceImportNDJSON(reader)
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidImportFile, format)
+	}
+}
+
+// parseEndDeviceImportCSV reads a CSV import file whose first row names the columns.
+func parseEndDeviceImportCSV(reader io.Reader) ([]EndDeviceImportRow, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not read header: %v", ErrInvalidImportFile, err)
+	}
+
+	columns := map[string]int{}
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		switch column {
+		case "name", "description", "hardware_type", "hardware_type_id", "labels", "attributes",
+			"device_eui", "join_eui", "application_key":
+		default:
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidImportFile, column)
+		}
+		columns[column] = i
+	}
+
+	rows := []EndDeviceImportRow{}
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+			}
+			rows = append(rows, EndDeviceImportRow{Line: parseErr.StartLine, Err: err})
+			continue
+		}
+
+		line, _ := csvReader.FieldPos(0)
+
+		if len(record) != len(header) {
+			rows = append(rows, EndDeviceImportRow{Line: line, Err: fmt.Errorf("expected %d columns, got %d", len(header), len(record))})
+			continue
+		}
+
+		field := func(column string) string {
+			if i, ok := columns[column]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row := EndDeviceImportRow{Line: line}
+		importRecord := endDeviceImportRecord{
+			Name:           field("name"),
+			Description:    field("description"),
+			HardwareTypeId: field("hardware_type_id"),
+			DeviceEui:      field("device_eui"),
+			JoinEui:        field("join_eui"),
+			ApplicationKey: field("application_key"),
+		}
+
+		if hardwareType := field("hardware_type"); hardwareType != "" {
+			importRecord.HardwareType, _ = json.Marshal(hardwareType)
+		}
+
+		importRecord.Labels, err = ParseLabels(field("labels"))
+		if err != nil {
+			row.Err = err
+			rows = append(rows, row)
+			continue
+		}
+
+		if attributes := field("attributes"); attributes != "" {
+			err = json.Unmarshal([]byte(attributes), &importRecord.Attributes)
+			if err != nil {
+				row.Err = fmt.Errorf("attributes must be a JSON object: %w", err)
+				rows = append(rows, row)
+				continue
+			}
+		}
+
+		row.Request, row.Metadata, row.Err = importRecord.toRequest()
+		row.Identity = importRecord.identity()
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// parseEndDeviceImportNDJSON reads an import file holding one JSON object per line. Blank lines are skipped.
+func parseEndDeviceImportNDJSON(reader io.Reader) ([]EndDeviceImportRow, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	rows := []EndDeviceImportRow{}
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		row := EndDeviceImportRow{Line: line}
+
+		var importRecord endDeviceImportRecord
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err := decoder.Decode(&importRecord)
+		if err != nil {
+			row.Err = fmt.Errorf("invalid JSON: %w", err)
+			rows = append(rows, row)
+			continue
+		}
+
+		row.Request, row.Metadata, row.Err = importRecord.toRequest()
+		row.Identity = importRecord.identity()
+		rows = append(rows, row)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+
+	return rows, nil
+}
+
+// toRequest converts a parsed record into the create request and metadata used for a single device.
+func (record endDeviceImportRecord) toRequest() (*iotv1.CreateEndDeviceRequest, EndDeviceMetadata, error) {
+	hardwareType, err := parseImportHardwareType(record.HardwareType)
+	if err != nil {
+		return nil, EndDeviceMetadata{}, err
+	}
+
+	createReq := iotv1.CreateEndDeviceRequest_builder{
+		Name:           record.Name,
+		Description:    record.Description,
+		HardwareType:   hardwareType,
+		HardwareTypeId: record.HardwareTypeId,
+	}.Build()
+
+	return createReq, EndDeviceMetadata{Labels: record.Labels, Attributes: record.Attributes}, nil
+}
+
+// identity returns the factory-assigned LoRaWAN identifiers of a parsed record.
+func (record endDeviceImportRecord) identity() LoRaWANIdentity {
+	return LoRaWANIdentity{
+		DeviceEui:      record.DeviceEui,
+		JoinEui:        record.JoinEui,
+		ApplicationKey: record.ApplicationKey,
+	}
+}
+
+// parseImportHardwareType resolves a hardware type given as a JSON string or number.
+// Strings may be the full enum name (END_DEVICE_HARDWARE_TYPE_LORAWAN), its short form (lorawan) or a number.
+func parseImportHardwareType(raw json.RawMessage) (iotv1.EndDeviceHardwareType, error) {
+	if len(raw) == 0 {
+		return iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_UNSPECIFIED, nil
+	}
+
+	var value string
+	err := json.Unmarshal(raw, &value)
+	if err != nil {
+		value = string(raw)
+	}
+
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_UNSPECIFIED, nil
+	}
+	if hardwareType, ok := LookupEndDeviceHardwareType(value); ok {
+		return hardwareType, nil
+	}
+
+	number, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("unknown hardware type %q", value)
+	}
+
+	return iotv1.EndDeviceHardwareType(number), nil
+}
+
+// ImportEndDevices creates end devices in bulk from parsed import rows. Every row is validated with the same
+// rules as CreateEndDevice before anything is written, and rows that fail validation are reported without
+// stopping the import. Valid rows are stored in batches. LoRaWAN devices are registered with TTN before their
+// batch transaction begins, so the transaction only holds database writes, and are removed from TTN again if their
+// row or batch is not committed, so a failed import never leaves devices behind in either system. On a dry run
+// nothing is registered or stored.
+func (mgr *EndDeviceManager) ImportEndDevices(ctx context.Context, organizationId string, rows []EndDeviceImportRow, options EndDeviceImportOptions) (*EndDeviceImportReport, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ImportEndDevices")
+	defer span.End()
+
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultEndDeviceImportBatchSize
+	}
+	if batchSize > MaxEndDeviceImportBatchSize {
+		batchSize = MaxEndDeviceImportBatchSize
+	}
+
+	report := &EndDeviceImportReport{
+		DryRun:  options.DryRun,
+		Results: make([]EndDeviceImportResult, len(rows)),
+	}
+
+	// Validate every row first so a dry run reports exactly what a real import would reject
+	pending := []int{}
+	endDevices := make([]*iotv1.EndDevice, len(rows))
+	for i, row := range rows {
+		report.Results[i] = EndDeviceImportResult{Line: row.Line, Name: row.Request.GetName()}
+
+		endDevice, err := mgr.validateImportRow(ctx, row)
+		if err != nil {
+			report.Results[i].Error = err.Error()
+			report.Failed++
+			continue
+		}
+
+		endDevices[i] = endDevice
+		pending = append(pending, i)
+		report.Valid++
+	}
+
+	if options.DryRun {
+		return report, nil
+	}
+
+	// EUIs are only handed out for a real import so dry runs do not use up the organization's EUI blocks
+	allocated := make([]int, 0, len(pending))
+	for _, rowIndex := range pending {
+		err := mgr.assignDeviceEUI(ctx, organizationId, endDevices[rowIndex])
+		if err != nil {
+			report.Results[rowIndex].Error = err.Error()
+			report.Failed++
+			report.Valid--
+			continue
+		}
+		allocated = append(allocated, rowIndex)
+	}
+	pending = allocated
+
+	for start := 0; start < len(pending); start += batchSize {
+		end := min(start+batchSize, len(pending))
+		mgr.importEndDeviceBatch(ctx, organizationId, rows, endDevices, pending[start:end], report)
+	}
+
+	return report, nil
+}
+
+// validateImportRow checks a single import row and builds the end device it describes.
+func (mgr *EndDeviceManager) validateImportRow(ctx context.Context, row EndDeviceImportRow) (*iotv1.EndDevice, error) {
+	if row.Err != nil {
+		return nil, row.Err
+	}
+
+	err := mgr.validate(row.Request)
+	if err != nil {
+		return nil, err
+	}
+
+	err = row.Metadata.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	// Imports carry no broker credentials or register maps, so MQTT and Modbus devices are rejected here
+	endDevice, err := mgr.buildEndDeviceFromRequest(ctx, mgr.stringId(), row.Request, row.Identity)
+	if err != nil {
+		return nil, err
+	}
+
+	// Factory-assigned EUIs can be checked up front; generated ones are allocated when the import runs
+	if deviceEui := endDevice.GetLorawanConfig().GetDeviceEui(); deviceEui != "" {
+		err = mgr.euiAllocator.CheckDeviceEUIAvailable(ctx, deviceEui)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return endDevice, nil
+}
+
+// importEndDeviceBatch registers one batch of validated rows with TTN, stores them and records the outcome of each
+// in the report. Rows TTN refuses are not stored; rows that are registered but not stored are removed from TTN.
+func (mgr *EndDeviceManager) importEndDeviceBatch(ctx context.Context, organizationId string, rows []EndDeviceImportRow, endDevices []*iotv1.EndDevice, batch []int, report *EndDeviceImportReport) {
+	ctx, span := telemetry.Tracer().Start(ctx, "importEndDeviceBatch")
+	defer span.End()
+
+	registered := map[int]bool{}
+	storing := make([]int, 0, len(batch))
+	pendingEndDevices := make([]PendingEndDevice, 0, len(batch))
+	for _, rowIndex := range batch {
+		endDevice := endDevices[rowIndex]
+		if endDevice.GetHardwareType() == iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_LORAWAN {
+			err := mgr.endDeviceRegister.RegisterEndDevice(ctx, endDevice)
+			if err != nil {
+				report.Results[rowIndex].Error = err.Error()
+				report.Failed++
+				continue
+			}
+			registered[rowIndex] = true
+		}
+
+		storing = append(storing, rowIndex)
+		pendingEndDevices = append(pendingEndDevices, PendingEndDevice{
+			EndDevice:      endDevice,
+			OrganizationId: organizationId,
+			Metadata:       rows[rowIndex].Metadata,
+		})
+	}
+
+	if len(pendingEndDevices) == 0 {
+		return
+	}
+
+	results, batchErr := mgr.endDeviceStore.AddEndDevices(ctx, pendingEndDevices)
+
+	for i, rowIndex := range storing {
+		err := batchErr
+		if err == nil {
+			err = results[i]
+		}
+
+		if err == nil {
+			report.Results[rowIndex].EndDeviceId = endDevices[rowIndex].GetId()
+			report.Created++
+			continue
+		}
+
+		if registered[rowIndex] {
+			// The registry already knows the device but the database does not; remove it so nothing is left behind
+			restoreErr := mgr.endDeviceRegister.DeleteEndDevice(ctx, endDevices[rowIndex])
+			if restoreErr != nil {
+				err = errors.Join(err, restoreErr)
+			}
+		}
+
+		report.Results[rowIndex].Error = err.Error()
+		report.Failed++
+	}
+}
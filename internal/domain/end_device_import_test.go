@@ -0,0 +1,162 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEndDeviceImport(t *testing.T) {
+	t.Run("parses csv rows", func(t *testing.T) {
+		assert := assert.New(t)
+
+		input := "name,hardware_type,hardware_type_id,labels,attributes\n" +
+			"sensor-1,lorawan,ht-1,\"building=north,floor=2\",\"{\"\"serial\"\":\"\"A1\"\"}\"\n" +
+			"sensor-2,END_DEVICE_HARDWARE_TYPE_HTTP,ht-2,,\n"
+
+		rows, err := ParseEndDeviceImport(strings.NewReader(input), EndDeviceImportCSV)
+
+		assert.NoError(err)
+		assert.Len(rows, 2)
+		assert.NoError(rows[0].Err)
+		assert.Equal(2, rows[0].Line)
+		assert.Equal("sensor-1", rows[0].Request.GetName())
+		assert.Equal(iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_LORAWAN, rows[0].Request.GetHardwareType())
+		assert.Equal(Labels{"building": "north", "floor": "2"}, rows[0].Metadata.Labels)
+		assert.Equal(map[string]any{"serial": "A1"}, rows[0].Metadata.Attributes)
+		assert.NoError(rows[1].Err)
+		assert.Equal(iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_HTTP, rows[1].Request.GetHardwareType())
+	})
+
+	t.Run("reports bad csv rows without failing the file", func(t *testing.T) {
+		assert := assert.New(t)
+
+		input := "name,hardware_type\nsensor-1,teleporter\nsensor-2\n"
+
+		rows, err := ParseEndDeviceImport(strings.NewReader(input), EndDeviceImportCSV)
+
+		assert.NoError(err)
+		assert.Len(rows, 2)
+		assert.Error(rows[0].Err)
+		assert.Error(rows[1].Err)
+		assert.Equal(3, rows[1].Line)
+	})
+
+	t.Run("rejects unknown csv columns", func(t *testing.T) {
+		_, err := ParseEndDeviceImport(strings.NewReader("name,colour\n"), EndDeviceImportCSV)
+
+		assert.True(t, errors.Is(err, ErrInvalidImportFile))
+	})
+
+	t.Run("parses ndjson rows", func(t *testing.T) {
+		assert := assert.New(t)
+
+		input := `{"name":"sensor-1","hardware_type":2,"hardware_type_id":"ht-1","labels":{"building":"north"}}` + "\n\n" +
+			`{"name":"sensor-2","unknown":true}` + "\n"
+
+		rows, err := ParseEndDeviceImport(strings.NewReader(input), EndDeviceImportNDJSON)
+
+		assert.NoError(err)
+		assert.Len(rows, 2)
+		assert.NoError(rows[0].Err)
+		assert.Equal(iotv1.EndDeviceHardwareType(2), rows[0].Request.GetHardwareType())
+		assert.Equal(Labels{"building": "north"}, rows[0].Metadata.Labels)
+		assert.Error(rows[1].Err)
+		assert.Equal(3, rows[1].Line)
+	})
+}
+
+func TestEndDeviceManager_ImportEndDevices(t *testing.T) {
+	newManager := func() (*EndDeviceManager, *memoryEndDeviceStore, *recordingEndDeviceRegister) {
+		store := newMemoryEndDeviceStore()
+		store.hardwareTypes["ht-1"] = iotv1.LoRaWANHardwareData_builder{
+			HardwareTypeId: "ht-1",
+			LorawanVersion: iotv1.LORAWANVersion_LORAWAN_VERSION_1_0_3,
+		}.Build()
+		register := &recordingEndDeviceRegister{}
+		return newTestEndDeviceManager(store, register), store, register
+	}
+
+	importRows := func(t *testing.T, names ...string) []EndDeviceImportRow {
+		lines := []string{}
+		for _, name := range names {
+			lines = append(lines, `{"name":"`+name+`","hardware_type":"lorawan","hardware_type_id":"ht-1"}`)
+		}
+		rows, err := ParseEndDeviceImport(strings.NewReader(strings.Join(lines, "\n")), EndDeviceImportNDJSON)
+		assert.NoError(t, err)
+		return rows
+	}
+
+	t.Run("registers and stores the devices in batches", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, store, register := newManager()
+
+		report, err := mgr.ImportEndDevices(context.Background(), "org-1", importRows(t, "a", "b", "c"), EndDeviceImportOptions{BatchSize: 2})
+
+		assert.NoError(err)
+		assert.Equal(3, report.Created)
+		assert.Equal(0, report.Failed)
+		assert.Equal([]int{2, 1}, store.batchSizes)
+		assert.Equal([]string{"register device-new-1 a", "register device-new-2 b", "register device-new-3 c"}, register.calls)
+		assert.Equal("device-new-2", report.Results[1].EndDeviceId)
+		assert.Equal("org-1", store.organizations["device-new-3"])
+	})
+
+	t.Run("touches nothing on a dry run", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, store, register := newManager()
+
+		report, err := mgr.ImportEndDevices(context.Background(), "org-1", importRows(t, "a", "b"), EndDeviceImportOptions{DryRun: true})
+
+		assert.NoError(err)
+		assert.Equal(2, report.Valid)
+		assert.Equal(0, report.Created)
+		assert.Empty(store.batchSizes)
+		assert.Empty(register.calls)
+	})
+
+	t.Run("does not store devices TTN refuses", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, store, register := newManager()
+		register.err = errors.New("identity server unavailable")
+
+		report, err := mgr.ImportEndDevices(context.Background(), "org-1", importRows(t, "a", "b"), EndDeviceImportOptions{})
+
+		assert.NoError(err)
+		assert.Equal(2, report.Failed)
+		assert.Empty(store.batchSizes)
+		assert.Empty(store.devices)
+	})
+
+	t.Run("removes devices from TTN that could not be stored", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, store, register := newManager()
+		store.insertErrs = map[string]error{"b": errors.New("duplicate key")}
+
+		report, err := mgr.ImportEndDevices(context.Background(), "org-1", importRows(t, "a", "b"), EndDeviceImportOptions{})
+
+		assert.NoError(err)
+		assert.Equal(1, report.Created)
+		assert.Equal(1, report.Failed)
+		assert.Equal("duplicate key", report.Results[1].Error)
+		assert.Equal([]string{"register device-new-1 a", "register device-new-2 b", "delete device-new-2 b"}, register.calls)
+	})
+
+	t.Run("removes the whole batch from TTN when it is not committed", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, store, register := newManager()
+		store.commitErr = errors.New("commit failed")
+
+		report, err := mgr.ImportEndDevices(context.Background(), "org-1", importRows(t, "a", "b"), EndDeviceImportOptions{})
+
+		assert.NoError(err)
+		assert.Equal(0, report.Created)
+		assert.Equal(2, report.Failed)
+		assert.Equal([]string{"register device-new-1 a", "register device-new-2 b", "delete device-new-1 a", "delete device-new-2 b"}, register.calls)
+		assert.Empty(store.devices)
+	})
+}
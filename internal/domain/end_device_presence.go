@@ -0,0 +1,183 @@
+package domain
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	envelopev1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/envelope/v1"
+	"github.com/ponix-dev/ponix/internal/runner"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+const (
+	// EndDevicePresenceWindow is the period covered by an end device's rolling message count.
+	EndDevicePresenceWindow = 24 * time.Hour
+	// endDeviceMessageCountBucket is the granularity of the stored message counts.
+	endDeviceMessageCountBucket = time.Hour
+)
+
+// EndDevicePresence summarizes the connectivity of an end device.
+type EndDevicePresence struct {
+	EndDeviceId     string         `json:"end_device_id"`
+	LastSeenAt      time.Time      `json:"last_seen_at"`
+	LastOccurredAt  time.Time      `json:"last_occurred_at,omitzero"`
+	LastProcessedAt time.Time      `json:"last_processed_at,omitzero"`
+	MessageCount24h int64          `json:"message_count_24h"`
+	LastValues      map[string]any `json:"last_values,omitempty"`
+}
+
+// EndDevicePresenceUpdate is the change to a single end device's presence from a batch of processed envelopes.
+type EndDevicePresenceUpdate struct {
+	EndDeviceId     string
+	LastOccurredAt  time.Time
+	LastProcessedAt time.Time
+	LastValues      map[string]any
+	// MessageCounts holds the number of envelopes per hour, keyed by the start of the hour.
+	MessageCounts map[time.Time]int64
+}
+
+// EndDevicePresenceStorer defines the persistence operations for end device presence.
+type EndDevicePresenceStorer interface {
+	RecordEndDevicePresence(ctx context.Context, updates []EndDevicePresenceUpdate) error
+	ListEndDevicePresence(ctx context.Context, endDeviceIds []string, countedSince time.Time) (map[string]*EndDevicePresence, error)
+	DeleteEndDeviceMessageCountsBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+// EndDevicePresenceManager maintains the last-seen and connectivity summary of end devices.
+type EndDevicePresenceManager struct {
+	presenceStore EndDevicePresenceStorer
+	fields        []string
+}
+
+// NewEndDevicePresenceManager creates a new instance of EndDevicePresenceManager. The last value of each of the
+// given data fields is kept with the presence; nested fields are addressed with dots, such as "battery.voltage".
+func NewEndDevicePresenceManager(presenceStore EndDevicePresenceStorer, fields []string) *EndDevicePresenceManager {
+	return &EndDevicePresenceManager{
+		presenceStore: presenceStore,
+		fields:        fields,
+	}
+}
+
+// RecordProcessedEnvelopes folds a batch of processed envelopes into the presence of their end devices.
+func (mgr *EndDevicePresenceManager) RecordProcessedEnvelopes(ctx context.Context, envelopes ...*envelopev1.ProcessedEnvelope) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "RecordProcessedEnvelopes")
+	defer span.End()
+
+	updates := map[string]*EndDevicePresenceUpdate{}
+	order := []string{}
+	for _, envelope := range envelopes {
+		endDeviceId := envelope.GetEndDeviceId()
+		update, ok := updates[endDeviceId]
+		if !ok {
+			update = &EndDevicePresenceUpdate{
+				EndDeviceId:   endDeviceId,
+				LastValues:    map[string]any{},
+				MessageCounts: map[time.Time]int64{},
+			}
+			updates[endDeviceId] = update
+			order = append(order, endDeviceId)
+		}
+
+		occurredAt := envelope.GetOccurredAt().AsTime()
+		processedAt := envelope.GetProcessedAt().AsTime()
+
+		// Values from the most recent reading win, whatever order the batch arrived in
+		if !occurredAt.Before(update.LastOccurredAt) {
+			update.LastOccurredAt = occurredAt
+			for field, value := range mgr.extractFields(envelope) {
+				update.LastValues[field] = value
+			}
+		}
+		if processedAt.After(update.LastProcessedAt) {
+			update.LastProcessedAt = processedAt
+		}
+
+		update.MessageCounts[processedAt.Truncate(endDeviceMessageCountBucket)]++
+	}
+
+	batch := make([]EndDevicePresenceUpdate, 0, len(order))
+	for _, endDeviceId := range order {
+		batch = append(batch, *updates[endDeviceId])
+	}
+
+	return mgr.presenceStore.RecordEndDevicePresence(ctx, batch)
+}
+
+// GetEndDevicePresence returns the presence of the given end devices keyed by ID.
+// Devices that have never sent data are left out.
+func (mgr *EndDevicePresenceManager) GetEndDevicePresence(ctx context.Context, endDeviceIds ...string) (map[string]*EndDevicePresence, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetEndDevicePresence")
+	defer span.End()
+
+	if len(endDeviceIds) == 0 {
+		return map[string]*EndDevicePresence{}, nil
+	}
+
+	return mgr.presenceStore.ListEndDevicePresence(ctx, endDeviceIds, countedSince(time.Now().UTC()))
+}
+
+// PruneEndDeviceMessageCounts removes message counts that have fallen out of the presence window.
+func (mgr *EndDevicePresenceManager) PruneEndDeviceMessageCounts(ctx context.Context) (int, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "PruneEndDeviceMessageCounts")
+	defer span.End()
+
+	return mgr.presenceStore.DeleteEndDeviceMessageCountsBefore(ctx, countedSince(time.Now().UTC()))
+}
+
+// extractFields returns the configured fields present in an envelope's data.
+func (mgr *EndDevicePresenceManager) extractFields(envelope *envelopev1.ProcessedEnvelope) map[string]any {
+	values := map[string]any{}
+	if len(mgr.fields) == 0 || envelope.GetData() == nil {
+		return values
+	}
+
+	data := envelope.GetData().AsMap()
+	for _, field := range mgr.fields {
+		var value any = data
+		for _, key := range strings.Split(field, ".") {
+			object, ok := value.(map[string]any)
+			if !ok {
+				value = nil
+				break
+			}
+			value = object[key]
+		}
+
+		if value != nil {
+			values[field] = value
+		}
+	}
+
+	return values
+}
+
+// countedSince returns the start of the oldest message count bucket inside the presence window.
+func countedSince(now time.Time) time.Time {
+	return now.Add(-EndDevicePresenceWindow).Truncate(endDeviceMessageCountBucket).Add(endDeviceMessageCountBucket)
+}
+
+// EndDevicePresencePruneRunner returns a runner function that removes expired message counts every interval
+// until the runner's context is cancelled.
+func EndDevicePresencePruneRunner(mgr *EndDevicePresenceManager, interval time.Duration) runner.RunnerFunc {
+	return func(ctx context.Context) func() error {
+		return func() error {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+					_, err := mgr.PruneEndDeviceMessageCounts(ctx)
+					if err != nil {
+						slog.Error("failed to prune end device message counts", stacktrace.ErrorAttribute(err))
+					}
+				}
+			}
+		}
+	}
+}
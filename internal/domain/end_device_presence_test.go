@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	envelopev1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/envelope/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type recordingPresenceStore struct {
+	EndDevicePresenceStorer
+	updates []EndDevicePresenceUpdate
+}
+
+func (store *recordingPresenceStore) RecordEndDevicePresence(ctx context.Context, updates []EndDevicePresenceUpdate) error {
+	store.updates = append(store.updates, updates...)
+	return nil
+}
+
+func TestRecordProcessedEnvelopes(t *testing.T) {
+	assert := assert.New(t)
+
+	store := &recordingPresenceStore{}
+	mgr := NewEndDevicePresenceManager(store, []string{"temperature", "battery.voltage"})
+
+	base := time.Date(2025, 11, 7, 10, 30, 0, 0, time.UTC)
+	envelope := func(occurredAt time.Time, data map[string]any) *envelopev1.ProcessedEnvelope {
+		payload, err := structpb.NewStruct(data)
+		assert.NoError(err)
+
+		return envelopev1.ProcessedEnvelope_builder{
+			EndDeviceId: "device-1",
+			OccurredAt:  timestamppb.New(occurredAt),
+			ProcessedAt: timestamppb.New(occurredAt.Add(time.Second)),
+			Data:        payload,
+		}.Build()
+	}
+
+	err := mgr.RecordProcessedEnvelopes(context.Background(),
+		envelope(base.Add(time.Minute), map[string]any{"temperature": 21.5}),
+		envelope(base, map[string]any{"temperature": 19.0, "battery": map[string]any{"voltage": 3.1}}),
+		envelope(base.Add(45*time.Minute), map[string]any{"humidity": 40.0}),
+	)
+
+	assert.NoError(err)
+	assert.Len(store.updates, 1)
+
+	update := store.updates[0]
+	assert.Equal(base.Add(45*time.Minute), update.LastOccurredAt)
+	assert.Equal(base.Add(45*time.Minute+time.Second), update.LastProcessedAt)
+	assert.Equal(map[string]any{"temperature": 21.5}, update.LastValues)
+	assert.Equal(map[time.Time]int64{
+		base.Truncate(time.Hour):                       2,
+		base.Add(45 * time.Minute).Truncate(time.Hour): 1,
+	}, update.MessageCounts)
+}
@@ -0,0 +1,413 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// ErrEndDeviceProfileNotFound is returned when an end device profile does not exist or is not visible to the
+	// requesting organization.
+	ErrEndDeviceProfileNotFound = errors.New("end device profile not found")
+	// ErrEndDeviceProfileExists is returned when an organization already has an end device profile with the same name.
+	ErrEndDeviceProfileExists = errors.New("end device profile already exists")
+	// ErrInvalidEndDeviceProfile is returned when an end device profile fails validation or does not fit a device.
+	ErrInvalidEndDeviceProfile = errors.New("invalid end device profile")
+)
+
+// MaxEndDeviceProfileNameLength is the maximum length of an end device profile name.
+const MaxEndDeviceProfileNameLength = 255
+
+// Names of the end device fields an end device profile change can touch.
+const (
+	EndDeviceProfileFieldHardwareTypeId = "hardware_type_id"
+	EndDeviceProfileFieldFrequencyPlan  = "frequency_plan"
+	EndDeviceProfileFieldLabels         = "labels"
+)
+
+// EndDeviceProfile is an organization scoped template holding the defaults shared by end devices of one kind,
+// such as a sensor model deployed in many places. Devices created from a profile keep a reference to it:
+// the hardware type, frequency plan and labels are copied onto the device, while the payload decoder and
+// expected fields are read from the profile.
+type EndDeviceProfile struct {
+	Id             string
+	OrganizationId string
+	Name           string
+	Description    string
+	HardwareType   iotv1.EndDeviceHardwareType
+	// HardwareTypeId and FrequencyPlan only apply to LoRaWAN profiles.
+	HardwareTypeId string
+	FrequencyPlan  string
+	// PayloadDecoder is the JavaScript payload decoder script applied to uplinks of the profile's devices. It takes
+	// precedence over the decoder of the devices' LoRaWAN hardware type.
+	PayloadDecoder string
+	// ExpectedFields lists the fields every uplink of the profile's devices is expected to carry.
+	ExpectedFields []string
+	Labels         Labels
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Validate checks that the end device profile has a usable name and settings that fit its hardware type.
+func (profile *EndDeviceProfile) Validate() error {
+	name := strings.TrimSpace(profile.Name)
+	if name == "" {
+		return stacktrace.NewStackTraceErrorf("%w: name is required", ErrInvalidEndDeviceProfile)
+	}
+
+	if len(name) > MaxEndDeviceProfileNameLength {
+		return stacktrace.NewStackTraceErrorf("%w: name is longer than %d characters", ErrInvalidEndDeviceProfile, MaxEndDeviceProfileNameLength)
+	}
+
+	switch profile.HardwareType {
+	case iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_LORAWAN:
+	case iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_HTTP, iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_MQTT, iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_MODBUS:
+		if profile.HardwareTypeId != "" || profile.FrequencyPlan != "" {
+			return stacktrace.NewStackTraceErrorf("%w: hardware type ID and frequency plan only apply to LoRaWAN profiles", ErrInvalidEndDeviceProfile)
+		}
+	default:
+		return stacktrace.NewStackTraceErrorf("%w: unsupported hardware type %s", ErrInvalidEndDeviceProfile, profile.HardwareType)
+	}
+
+	if profile.PayloadDecoder != "" {
+		err := ValidatePayloadDecoderScript(profile.PayloadDecoder)
+		if err != nil {
+			return stacktrace.NewStackTraceErrorf("%w: %w", ErrInvalidEndDeviceProfile, err)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, field := range profile.ExpectedFields {
+		if strings.TrimSpace(field) == "" {
+			return stacktrace.NewStackTraceErrorf("%w: expected fields cannot be empty", ErrInvalidEndDeviceProfile)
+		}
+		if seen[field] {
+			return stacktrace.NewStackTraceErrorf("%w: expected field %q is listed twice", ErrInvalidEndDeviceProfile, field)
+		}
+		seen[field] = true
+	}
+
+	return profile.Labels.Validate()
+}
+
+// applyToCreateRequest fills the hardware type and hardware type ID a create request leaves unset with the
+// profile's. A request for a different hardware type than the profile's fails with ErrInvalidEndDeviceProfile.
+func (profile *EndDeviceProfile) applyToCreateRequest(createReq *iotv1.CreateEndDeviceRequest) (*iotv1.CreateEndDeviceRequest, error) {
+	applied := proto.Clone(createReq).(*iotv1.CreateEndDeviceRequest)
+
+	switch applied.GetHardwareType() {
+	case iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_UNSPECIFIED:
+		applied.SetHardwareType(profile.HardwareType)
+	case profile.HardwareType:
+	default:
+		return nil, stacktrace.NewStackTraceErrorf("%w: profile %s is for %s devices", ErrInvalidEndDeviceProfile, profile.Id, profile.HardwareType)
+	}
+
+	if applied.GetHardwareTypeId() == "" {
+		applied.SetHardwareTypeId(profile.HardwareTypeId)
+	}
+
+	return applied, nil
+}
+
+// applyToMetadata adds the profile's labels to the metadata. Labels set on the device win.
+func (profile *EndDeviceProfile) applyToMetadata(metadata EndDeviceMetadata) EndDeviceMetadata {
+	labels := maps.Clone(profile.Labels)
+	if labels == nil {
+		labels = Labels{}
+	}
+	maps.Copy(labels, metadata.Labels)
+
+	metadata.Labels = labels
+
+	return metadata
+}
+
+// EndDeviceProfileDevice is the profile-managed state of an end device created from an end device profile.
+type EndDeviceProfileDevice struct {
+	EndDeviceId    string
+	Name           string
+	HardwareTypeId string
+	FrequencyPlan  string
+	Labels         Labels
+}
+
+// EndDeviceProfileChange describes how an end device changes when a profile update is propagated to it.
+// The hardware type ID, frequency plan and labels hold the device's values after the change.
+type EndDeviceProfileChange struct {
+	EndDeviceId    string
+	Name           string
+	Fields         []string
+	HardwareTypeId string
+	FrequencyPlan  string
+	Labels         Labels
+}
+
+// diffEndDeviceProfile works out how propagating the update of a profile from previous to updated changes device.
+// Profile labels the device still carries with their previous value are dropped when the profile no longer sets
+// them, while labels the device set itself are kept. Fields is empty when the device would not change.
+func diffEndDeviceProfile(previous *EndDeviceProfile, updated *EndDeviceProfile, device EndDeviceProfileDevice) EndDeviceProfileChange {
+	change := EndDeviceProfileChange{
+		EndDeviceId:    device.EndDeviceId,
+		Name:           device.Name,
+		Fields:         []string{},
+		HardwareTypeId: device.HardwareTypeId,
+		FrequencyPlan:  device.FrequencyPlan,
+		Labels:         maps.Clone(device.Labels),
+	}
+	if change.Labels == nil {
+		change.Labels = Labels{}
+	}
+
+	if updated.HardwareTypeId != "" && updated.HardwareTypeId != device.HardwareTypeId {
+		change.HardwareTypeId = updated.HardwareTypeId
+		change.Fields = append(change.Fields, EndDeviceProfileFieldHardwareTypeId)
+	}
+
+	if updated.FrequencyPlan != "" && updated.FrequencyPlan != device.FrequencyPlan {
+		change.FrequencyPlan = updated.FrequencyPlan
+		change.Fields = append(change.Fields, EndDeviceProfileFieldFrequencyPlan)
+	}
+
+	for key, value := range previous.Labels {
+		if _, kept := updated.Labels[key]; !kept && change.Labels[key] == value {
+			delete(change.Labels, key)
+		}
+	}
+	maps.Copy(change.Labels, updated.Labels)
+
+	if !maps.Equal(change.Labels, device.Labels) {
+		change.Fields = append(change.Fields, EndDeviceProfileFieldLabels)
+	}
+
+	return change
+}
+
+// EndDeviceProfileGetter looks up end device profiles.
+type EndDeviceProfileGetter interface {
+	GetEndDeviceProfile(ctx context.Context, profileId string) (*EndDeviceProfile, error)
+}
+
+// EndDeviceProfileStorer defines the persistence operations for end device profiles.
+type EndDeviceProfileStorer interface {
+	EndDeviceProfileGetter
+	CreateEndDeviceProfile(ctx context.Context, profile *EndDeviceProfile) (*EndDeviceProfile, error)
+	ListEndDeviceProfiles(ctx context.Context, organizationId string) ([]*EndDeviceProfile, error)
+	UpdateEndDeviceProfile(ctx context.Context, profile *EndDeviceProfile) (*EndDeviceProfile, error)
+	DeleteEndDeviceProfile(ctx context.Context, profileId string) error
+	ListEndDeviceProfileDevices(ctx context.Context, profileId string) ([]EndDeviceProfileDevice, error)
+}
+
+// EndDeviceUpdater applies changes to existing end devices, keeping external registries in sync.
+type EndDeviceUpdater interface {
+	UpdateEndDevice(ctx context.Context, update *iotv1.EndDevice, organizationId string, metadataUpdate EndDeviceMetadata) (*iotv1.EndDevice, EndDeviceMetadata, error)
+}
+
+// EndDeviceProfileManager orchestrates end device profile business logic.
+type EndDeviceProfileManager struct {
+	profileStore     EndDeviceProfileStorer
+	endDeviceUpdater EndDeviceUpdater
+	stringId         StringId
+}
+
+// NewEndDeviceProfileManager creates a new instance of EndDeviceProfileManager with the provided dependencies.
+func NewEndDeviceProfileManager(profileStore EndDeviceProfileStorer, endDeviceUpdater EndDeviceUpdater, stringId StringId) *EndDeviceProfileManager {
+	return &EndDeviceProfileManager{
+		profileStore:     profileStore,
+		endDeviceUpdater: endDeviceUpdater,
+		stringId:         stringId,
+	}
+}
+
+// CreateEndDeviceProfile creates a new end device profile in an organization. Profile names are unique within
+// an organization.
+func (mgr *EndDeviceProfileManager) CreateEndDeviceProfile(ctx context.Context, organizationId string, profile *EndDeviceProfile) (*EndDeviceProfile, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "CreateEndDeviceProfile")
+	defer span.End()
+
+	created := *profile
+	created.Id = mgr.stringId()
+	created.OrganizationId = organizationId
+	created.Name = strings.TrimSpace(created.Name)
+
+	err := created.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return mgr.profileStore.CreateEndDeviceProfile(ctx, &created)
+}
+
+// GetEndDeviceProfile retrieves an end device profile. Profiles that belong to a different organization are
+// reported as ErrEndDeviceProfileNotFound so their existence is not leaked.
+func (mgr *EndDeviceProfileManager) GetEndDeviceProfile(ctx context.Context, profileId string, organizationId string) (*EndDeviceProfile, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetEndDeviceProfile")
+	defer span.End()
+
+	return getOrganizationEndDeviceProfile(ctx, mgr.profileStore, profileId, organizationId)
+}
+
+// ListEndDeviceProfiles retrieves every end device profile in an organization ordered by name.
+func (mgr *EndDeviceProfileManager) ListEndDeviceProfiles(ctx context.Context, organizationId string) ([]*EndDeviceProfile, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListEndDeviceProfiles")
+	defer span.End()
+
+	return mgr.profileStore.ListEndDeviceProfiles(ctx, organizationId)
+}
+
+// PreviewEndDeviceProfileUpdate reports which end devices created from a profile would change, and how,
+// if update were applied with propagation. Nothing is written.
+func (mgr *EndDeviceProfileManager) PreviewEndDeviceProfileUpdate(ctx context.Context, update *EndDeviceProfile, organizationId string) ([]EndDeviceProfileChange, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "PreviewEndDeviceProfileUpdate")
+	defer span.End()
+
+	current, updated, err := mgr.prepareEndDeviceProfileUpdate(ctx, update, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	return mgr.endDeviceProfileChanges(ctx, current, updated)
+}
+
+// UpdateEndDeviceProfile replaces the settings of an end device profile with those of update. The hardware type of
+// a profile cannot change. New devices pick up the update right away; with propagate set, the hardware type ID,
+// frequency plan and labels of existing devices created from the profile are updated too, one device at a time,
+// and the changes applied are returned. A failing device stops the propagation; devices updated before it keep
+// their changes and the update can be propagated again.
+func (mgr *EndDeviceProfileManager) UpdateEndDeviceProfile(ctx context.Context, update *EndDeviceProfile, organizationId string, propagate bool) (*EndDeviceProfile, []EndDeviceProfileChange, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "UpdateEndDeviceProfile")
+	defer span.End()
+
+	current, updated, err := mgr.prepareEndDeviceProfileUpdate(ctx, update, organizationId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changes := []EndDeviceProfileChange{}
+	if propagate {
+		// Work out the changes before the profile is replaced so labels dropped from the profile can be recognized
+		changes, err = mgr.endDeviceProfileChanges(ctx, current, updated)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	stored, err := mgr.profileStore.UpdateEndDeviceProfile(ctx, updated)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, change := range changes {
+		err = mgr.applyEndDeviceProfileChange(ctx, change, organizationId)
+		if err != nil {
+			return stored, changes[:i], err
+		}
+	}
+
+	return stored, changes, nil
+}
+
+// DeleteEndDeviceProfile deletes an end device profile. Devices created from it keep their settings.
+func (mgr *EndDeviceProfileManager) DeleteEndDeviceProfile(ctx context.Context, profileId string, organizationId string) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "DeleteEndDeviceProfile")
+	defer span.End()
+
+	_, err := getOrganizationEndDeviceProfile(ctx, mgr.profileStore, profileId, organizationId)
+	if err != nil {
+		return err
+	}
+
+	return mgr.profileStore.DeleteEndDeviceProfile(ctx, profileId)
+}
+
+// prepareEndDeviceProfileUpdate loads the current state of the profile being updated and builds its updated state.
+func (mgr *EndDeviceProfileManager) prepareEndDeviceProfileUpdate(ctx context.Context, update *EndDeviceProfile, organizationId string) (*EndDeviceProfile, *EndDeviceProfile, error) {
+	current, err := getOrganizationEndDeviceProfile(ctx, mgr.profileStore, update.Id, organizationId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if update.HardwareType != iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_UNSPECIFIED && update.HardwareType != current.HardwareType {
+		return nil, nil, stacktrace.NewStackTraceErrorf("%w: hardware type of %s cannot change", ErrInvalidEndDeviceProfile, update.Id)
+	}
+
+	updated := *update
+	updated.OrganizationId = current.OrganizationId
+	updated.HardwareType = current.HardwareType
+	updated.CreatedAt = current.CreatedAt
+	updated.Name = strings.TrimSpace(updated.Name)
+	if updated.Name == "" {
+		updated.Name = current.Name
+	}
+
+	err = updated.Validate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return current, &updated, nil
+}
+
+// endDeviceProfileChanges lists the devices created from a profile that change when it moves from current to updated.
+func (mgr *EndDeviceProfileManager) endDeviceProfileChanges(ctx context.Context, current *EndDeviceProfile, updated *EndDeviceProfile) ([]EndDeviceProfileChange, error) {
+	devices, err := mgr.profileStore.ListEndDeviceProfileDevices(ctx, current.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []EndDeviceProfileChange{}
+	for _, device := range devices {
+		change := diffEndDeviceProfile(current, updated, device)
+		if len(change.Fields) > 0 {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, nil
+}
+
+// applyEndDeviceProfileChange writes a propagated profile change to a single end device. Its settings and labels
+// change together.
+func (mgr *EndDeviceProfileManager) applyEndDeviceProfileChange(ctx context.Context, change EndDeviceProfileChange, organizationId string) error {
+	update := iotv1.EndDevice_builder{Id: change.EndDeviceId}
+	if slices.Contains(change.Fields, EndDeviceProfileFieldHardwareTypeId) || slices.Contains(change.Fields, EndDeviceProfileFieldFrequencyPlan) {
+		update.LorawanConfig = iotv1.LoRaWANConfig_builder{
+			FrequencyPlan: change.FrequencyPlan,
+			HardwareData: iotv1.LoRaWANHardwareData_builder{
+				HardwareTypeId: change.HardwareTypeId,
+			}.Build(),
+		}.Build()
+	}
+
+	metadataUpdate := EndDeviceMetadata{}
+	if slices.Contains(change.Fields, EndDeviceProfileFieldLabels) {
+		metadataUpdate.Labels = change.Labels
+	}
+
+	_, _, err := mgr.endDeviceUpdater.UpdateEndDevice(ctx, update.Build(), organizationId, metadataUpdate)
+	return err
+}
+
+// getOrganizationEndDeviceProfile retrieves an end device profile, reporting profiles owned by other organizations
+// as not found.
+func getOrganizationEndDeviceProfile(ctx context.Context, profiles EndDeviceProfileGetter, profileId string, organizationId string) (*EndDeviceProfile, error) {
+	profile, err := profiles.GetEndDeviceProfile(ctx, profileId)
+	if err != nil {
+		return nil, err
+	}
+
+	if profile.OrganizationId != organizationId {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceProfileNotFound, profileId)
+	}
+
+	return profile, nil
+}
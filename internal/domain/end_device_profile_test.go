@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndDeviceProfile_Validate(t *testing.T) {
+	t.Run("accepts a LoRaWAN profile", func(t *testing.T) {
+		profile := &EndDeviceProfile{
+			Name:           "freezer sensor",
+			HardwareType:   iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_LORAWAN,
+			HardwareTypeId: "dragino-lht65",
+			FrequencyPlan:  "EU_863_870",
+			ExpectedFields: []string{"temperature", "humidity"},
+			Labels:         Labels{"kind": "freezer"},
+		}
+
+		assert.NoError(t, profile.Validate())
+	})
+
+	t.Run("rejects invalid profiles", func(t *testing.T) {
+		for name, profile := range map[string]*EndDeviceProfile{
+			"missing name": {
+				HardwareType: iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_LORAWAN,
+			},
+			"long name": {
+				Name:         strings.Repeat("a", MaxEndDeviceProfileNameLength+1),
+				HardwareType: iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_LORAWAN,
+			},
+			"unspecified hardware type": {
+				Name: "sensor",
+			},
+			"frequency plan on HTTP profile": {
+				Name:          "sensor",
+				HardwareType:  iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_HTTP,
+				FrequencyPlan: "EU_863_870",
+			},
+			"blank payload decoder": {
+				Name:           "sensor",
+				HardwareType:   iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_LORAWAN,
+				PayloadDecoder: "  ",
+			},
+			"duplicate expected field": {
+				Name:           "sensor",
+				HardwareType:   iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_HTTP,
+				ExpectedFields: []string{"temperature", "temperature"},
+			},
+		} {
+			assert.ErrorIs(t, profile.Validate(), ErrInvalidEndDeviceProfile, name)
+		}
+	})
+}
+
+func TestEndDeviceProfile_applyToMetadata(t *testing.T) {
+	assert := assert.New(t)
+
+	profile := &EndDeviceProfile{Id: "profile-1", Labels: Labels{"kind": "freezer", "site": "default"}}
+
+	metadata := profile.applyToMetadata(EndDeviceMetadata{Labels: Labels{"site": "store-42"}})
+
+	assert.Equal(Labels{"kind": "freezer", "site": "store-42"}, metadata.Labels)
+}
+
+func TestDiffEndDeviceProfile(t *testing.T) {
+	previous := &EndDeviceProfile{
+		HardwareTypeId: "dragino-lht65",
+		FrequencyPlan:  "EU_863_870",
+		Labels:         Labels{"kind": "freezer", "vendor": "dragino"},
+	}
+
+	t.Run("device matching the update does not change", func(t *testing.T) {
+		assert := assert.New(t)
+
+		change := diffEndDeviceProfile(previous, previous, EndDeviceProfileDevice{
+			EndDeviceId:    "device-1",
+			HardwareTypeId: "dragino-lht65",
+			FrequencyPlan:  "EU_863_870",
+			Labels:         Labels{"kind": "freezer", "vendor": "dragino"},
+		})
+
+		assert.Empty(change.Fields)
+	})
+
+	t.Run("updates settings and profile labels and keeps device labels", func(t *testing.T) {
+		assert := assert.New(t)
+
+		updated := &EndDeviceProfile{
+			HardwareTypeId: "dragino-lht65",
+			FrequencyPlan:  "US_902_928",
+			Labels:         Labels{"kind": "cooler"},
+		}
+
+		change := diffEndDeviceProfile(previous, updated, EndDeviceProfileDevice{
+			EndDeviceId:    "device-1",
+			HardwareTypeId: "dragino-lht65",
+			FrequencyPlan:  "EU_863_870",
+			Labels:         Labels{"kind": "freezer", "vendor": "dragino", "site": "store-42"},
+		})
+
+		assert.Equal([]string{EndDeviceProfileFieldFrequencyPlan, EndDeviceProfileFieldLabels}, change.Fields)
+		assert.Equal("US_902_928", change.FrequencyPlan)
+		assert.Equal("dragino-lht65", change.HardwareTypeId)
+		assert.Equal(Labels{"kind": "cooler", "site": "store-42"}, change.Labels)
+	})
+
+	t.Run("keeps labels the device overrode", func(t *testing.T) {
+		assert := assert.New(t)
+
+		updated := &EndDeviceProfile{Labels: Labels{"kind": "freezer"}}
+
+		change := diffEndDeviceProfile(previous, updated, EndDeviceProfileDevice{
+			EndDeviceId: "device-1",
+			Labels:      Labels{"kind": "freezer", "vendor": "acme"},
+		})
+
+		assert.Empty(change.Fields)
+		assert.Equal(Labels{"kind": "freezer", "vendor": "acme"}, change.Labels)
+	})
+}
@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"context"
+	"strings"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// MaxEndDeviceSearchQueryLength is the longest search query accepted, in bytes.
+const MaxEndDeviceSearchQueryLength = 256
+
+// EndDeviceSearchRequest describes a page of end devices matching a free-text query within a set of organizations.
+// The query uses web search syntax: quoted phrases, "or" and a leading "-" to exclude words.
+type EndDeviceSearchRequest struct {
+	Query           string
+	OrganizationIds []string
+	PageSize        int
+	PageToken       string
+}
+
+// EndDeviceSearchQuery is the store-level form of an end device search request.
+// DeviceEui is set when the query reads as a device EUI; such devices are matched exactly.
+type EndDeviceSearchQuery struct {
+	Query           string
+	DeviceEui       string
+	OrganizationIds []string
+	Limit           int
+	After           *EndDeviceCursor
+}
+
+// EndDeviceSearchResult is an end device matching a search, with the organization it belongs to and how well it
+// matched. Higher ranks are better matches.
+type EndDeviceSearchResult struct {
+	EndDevice      *iotv1.EndDevice
+	OrganizationId string
+	Rank           float64
+}
+
+// EndDeviceSearchPage is a single page of search results, best matches first.
+// NextPageToken is empty when there are no further pages.
+type EndDeviceSearchPage struct {
+	Results       []EndDeviceSearchResult
+	NextPageToken string
+}
+
+// SearchEndDevices retrieves a page of the end devices of the requested organizations that match a free-text query.
+// Names, descriptions and labels are searched as text; a query naming a device EUI or part of a LoRaWAN hardware
+// model matches those devices too. Results are ranked, with device EUI matches ahead of model matches and both ahead
+// of text matches, and are paged with opaque cursor tokens like ListEndDevices.
+// Callers are responsible for checking that the organizations may be read.
+func (mgr *EndDeviceManager) SearchEndDevices(ctx context.Context, searchReq EndDeviceSearchRequest) (*EndDeviceSearchPage, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "SearchEndDevices")
+	defer span.End()
+
+	query := strings.TrimSpace(searchReq.Query)
+	switch {
+	case query == "":
+		return nil, stacktrace.NewStackTraceErrorf("%w: search query is required", ErrInvalidMessageFormat)
+	case len(query) > MaxEndDeviceSearchQueryLength:
+		return nil, stacktrace.NewStackTraceErrorf("%w: search query is longer than %d bytes", ErrInvalidMessageFormat, MaxEndDeviceSearchQueryLength)
+	case len(searchReq.OrganizationIds) == 0:
+		return nil, stacktrace.NewStackTraceErrorf("%w: at least one organization is required", ErrInvalidMessageFormat)
+	}
+
+	after, err := decodeEndDevicePageToken(searchReq.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var deviceEui string
+	if eui := normalizeHex(query); isHexOfLength(eui, 16) {
+		deviceEui = eui
+	}
+
+	limit := endDevicePageSize(searchReq.PageSize)
+	results, next, err := mgr.endDeviceStore.SearchEndDevices(ctx, EndDeviceSearchQuery{
+		Query:           query,
+		DeviceEui:       deviceEui,
+		OrganizationIds: searchReq.OrganizationIds,
+		Limit:           limit,
+		After:           after,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nextPageToken, err := encodeEndDevicePageToken(next)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EndDeviceSearchPage{
+		Results:       results,
+		NextPageToken: nextPageToken,
+	}, nil
+}
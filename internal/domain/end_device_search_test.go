@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingEndDeviceSearcher records the search queries it receives and answers with fixed results.
+// Only searching is implemented.
+type recordingEndDeviceSearcher struct {
+	EndDeviceStorer
+	queries []EndDeviceSearchQuery
+	results []EndDeviceSearchResult
+	next    *EndDeviceCursor
+}
+
+func (store *recordingEndDeviceSearcher) SearchEndDevices(_ context.Context, query EndDeviceSearchQuery) ([]EndDeviceSearchResult, *EndDeviceCursor, error) {
+	store.queries = append(store.queries, query)
+	return store.results, store.next, nil
+}
+
+func TestEndDeviceManager_SearchEndDevices(t *testing.T) {
+	t.Run("pages through ranked results", func(t *testing.T) {
+		assert := assert.New(t)
+		store := &recordingEndDeviceSearcher{
+			results: []EndDeviceSearchResult{{EndDevice: iotv1.EndDevice_builder{Id: "device-1"}.Build(), OrganizationId: "org-1", Rank: 0.5}},
+			next:    &EndDeviceCursor{Id: "device-1", Rank: 0.5},
+		}
+		mgr := &EndDeviceManager{endDeviceStore: store}
+
+		page, err := mgr.SearchEndDevices(context.Background(), EndDeviceSearchRequest{Query: "  freezer store 42 ", OrganizationIds: []string{"org-1"}})
+		assert.NoError(err)
+		assert.Len(page.Results, 1)
+		assert.NotEmpty(page.NextPageToken)
+
+		_, err = mgr.SearchEndDevices(context.Background(), EndDeviceSearchRequest{Query: "freezer store 42", OrganizationIds: []string{"org-1"}, PageSize: 1000, PageToken: page.NextPageToken})
+		assert.NoError(err)
+
+		if assert.Len(store.queries, 2) {
+			assert.Equal("freezer store 42", store.queries[0].Query)
+			assert.Empty(store.queries[0].DeviceEui)
+			assert.Equal(DefaultEndDevicePageSize, store.queries[0].Limit)
+			assert.Nil(store.queries[0].After)
+			assert.Equal(MaxEndDevicePageSize, store.queries[1].Limit)
+			assert.Equal(&EndDeviceCursor{Id: "device-1", Rank: 0.5}, store.queries[1].After)
+		}
+	})
+
+	t.Run("recognizes device EUIs", func(t *testing.T) {
+		for query, expected := range map[string]string{
+			"70b3d57ed0001234":        "70B3D57ED0001234",
+			"70:B3:D5:7E:D0:00:12:34": "70B3D57ED0001234",
+			"70b3d57ed000":            "",
+			"cold room":               "",
+		} {
+			store := &recordingEndDeviceSearcher{}
+			mgr := &EndDeviceManager{endDeviceStore: store}
+
+			_, err := mgr.SearchEndDevices(context.Background(), EndDeviceSearchRequest{Query: query, OrganizationIds: []string{"org-1"}})
+			assert.NoError(t, err, query)
+			if assert.Len(t, store.queries, 1, query) {
+				assert.Equal(t, expected, store.queries[0].DeviceEui, query)
+			}
+		}
+	})
+
+	t.Run("rejects invalid searches", func(t *testing.T) {
+		store := &recordingEndDeviceSearcher{}
+		mgr := &EndDeviceManager{endDeviceStore: store}
+
+		for name, tc := range map[string]struct {
+			req      EndDeviceSearchRequest
+			expected error
+		}{
+			"blank query":      {EndDeviceSearchRequest{Query: "  ", OrganizationIds: []string{"org-1"}}, ErrInvalidMessageFormat},
+			"long query":       {EndDeviceSearchRequest{Query: strings.Repeat("a", MaxEndDeviceSearchQueryLength+1), OrganizationIds: []string{"org-1"}}, ErrInvalidMessageFormat},
+			"no organizations": {EndDeviceSearchRequest{Query: "freezer"}, ErrInvalidMessageFormat},
+			"bad page token":   {EndDeviceSearchRequest{Query: "freezer", OrganizationIds: []string{"org-1"}, PageToken: "not a token"}, ErrInvalidPageToken},
+		} {
+			_, err := mgr.SearchEndDevices(context.Background(), tc.req)
+			assert.ErrorIs(t, err, tc.expected, name)
+		}
+		assert.Empty(t, store.queries)
+	})
+}
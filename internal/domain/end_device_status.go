@@ -0,0 +1,275 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/ponix-dev/ponix/internal/runner"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+var (
+	// ErrEndDeviceDisabled is returned when data is sent for an end device that has been decommissioned.
+	ErrEndDeviceDisabled = errors.New("end device is disabled")
+	// ErrInvalidStatusTransition is returned when an end device cannot move from its current status to the requested one,
+	// including when its status was changed concurrently.
+	ErrInvalidStatusTransition = errors.New("invalid end device status transition")
+)
+
+const (
+	// DefaultEndDeviceSilencePeriod is how long an active end device may go without sending data before it is marked inactive.
+	DefaultEndDeviceSilencePeriod = time.Hour
+	// silentEndDeviceBatchSize is the number of silent end devices marked inactive per query.
+	silentEndDeviceBatchSize = 500
+)
+
+// Reasons recorded with end device status transitions.
+const (
+	StatusReasonFirstEnvelope   = "first data envelope received"
+	StatusReasonTrafficResumed  = "data envelope received after silence"
+	StatusReasonSilent          = "no data received within silence period"
+	StatusReasonDecommissioned  = "decommissioned"
+	StatusReasonRootKeysRotated = "root keys rotated, awaiting re-join"
+	StatusReasonClaimed         = "claimed by a new organization, awaiting re-join"
+	StatusReasonJoined          = "joined the network"
+)
+
+// endDeviceStatusTransitions lists the statuses each status may move to. Disabled devices stay disabled.
+// Devices return to PENDING when their root keys are rotated, until they re-join with the new keys.
+var endDeviceStatusTransitions = map[iotv1.EndDeviceStatus][]iotv1.EndDeviceStatus{
+	iotv1.EndDeviceStatus_END_DEVICE_STATUS_PENDING: {
+		iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE,
+		iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED,
+	},
+	iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE: {
+		iotv1.EndDeviceStatus_END_DEVICE_STATUS_INACTIVE,
+		iotv1.EndDeviceStatus_END_DEVICE_STATUS_PENDING,
+		iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED,
+	},
+	iotv1.EndDeviceStatus_END_DEVICE_STATUS_INACTIVE: {
+		iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE,
+		iotv1.EndDeviceStatus_END_DEVICE_STATUS_PENDING,
+		iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED,
+	},
+}
+
+// CanTransitionEndDeviceStatus reports whether an end device may move from one status to another.
+func CanTransitionEndDeviceStatus(from iotv1.EndDeviceStatus, to iotv1.EndDeviceStatus) bool {
+	return slices.Contains(endDeviceStatusTransitions[from], to)
+}
+
+// EndDeviceStatusTransition records a single change of an end device's status.
+type EndDeviceStatusTransition struct {
+	Id             string
+	EndDeviceId    string
+	From           iotv1.EndDeviceStatus
+	To             iotv1.EndDeviceStatus
+	Reason         string
+	TransitionedAt time.Time
+}
+
+// EndDeviceStatusStorer defines the persistence operations for the end device status lifecycle.
+type EndDeviceStatusStorer interface {
+	// TransitionEndDeviceStatus changes the device's status and records the transition. It fails with
+	// ErrInvalidStatusTransition when the device is no longer in the transition's From status.
+	TransitionEndDeviceStatus(ctx context.Context, transition *EndDeviceStatusTransition) error
+	ListEndDeviceStatusTransitions(ctx context.Context, endDeviceId string) ([]*EndDeviceStatusTransition, error)
+	TouchEndDevicePresence(ctx context.Context, endDeviceId string, seenAt time.Time) error
+	ListSilentEndDevices(ctx context.Context, status iotv1.EndDeviceStatus, seenBefore time.Time, limit int) ([]string, error)
+}
+
+// EndDeviceStatusManager moves end devices through their status lifecycle:
+// PENDING becomes ACTIVE on the first data envelope, ACTIVE becomes INACTIVE after the silence period,
+// INACTIVE becomes ACTIVE again when data resumes, and any status becomes DISABLED on decommission.
+// Rotating the root keys of an ACTIVE or INACTIVE device moves it back to PENDING until its next uplink.
+type EndDeviceStatusManager struct {
+	statusStore    EndDeviceStatusStorer
+	endDeviceStore EndDeviceStorer
+	stringId       StringId
+	silencePeriod  time.Duration
+}
+
+// NewEndDeviceStatusManager creates a new instance of EndDeviceStatusManager with the provided dependencies.
+// A zero silence period falls back to DefaultEndDeviceSilencePeriod.
+func NewEndDeviceStatusManager(statusStore EndDeviceStatusStorer, eds EndDeviceStorer, stringId StringId, silencePeriod time.Duration) *EndDeviceStatusManager {
+	if silencePeriod <= 0 {
+		silencePeriod = DefaultEndDeviceSilencePeriod
+	}
+
+	return &EndDeviceStatusManager{
+		statusStore:    statusStore,
+		endDeviceStore: eds,
+		stringId:       stringId,
+		silencePeriod:  silencePeriod,
+	}
+}
+
+// RecordEndDeviceActivity notes that data was received from an end device and activates it when it was
+// pending or inactive. A concurrent activation by another envelope is not an error.
+func (mgr *EndDeviceStatusManager) RecordEndDeviceActivity(ctx context.Context, endDevice *iotv1.EndDevice, seenAt time.Time) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "RecordEndDeviceActivity")
+	defer span.End()
+
+	return mgr.activate(ctx, endDevice, seenAt, StatusReasonFirstEnvelope)
+}
+
+// RecordEndDeviceJoin notes that a LoRaWAN end device joined the network. Devices waiting in PENDING for their
+// first join or a re-join after their root keys changed become ACTIVE, as do inactive devices.
+func (mgr *EndDeviceStatusManager) RecordEndDeviceJoin(ctx context.Context, endDevice *iotv1.EndDevice, joinedAt time.Time) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "RecordEndDeviceJoin")
+	defer span.End()
+
+	return mgr.activate(ctx, endDevice, joinedAt, StatusReasonJoined)
+}
+
+// activate touches the presence of an end device and moves it to ACTIVE when it was pending or inactive,
+// recording pendingReason for pending devices.
+func (mgr *EndDeviceStatusManager) activate(ctx context.Context, endDevice *iotv1.EndDevice, seenAt time.Time, pendingReason string) error {
+	err := mgr.statusStore.TouchEndDevicePresence(ctx, endDevice.GetId(), seenAt)
+	if err != nil {
+		return err
+	}
+
+	var reason string
+	switch endDevice.GetStatus() {
+	case iotv1.EndDeviceStatus_END_DEVICE_STATUS_PENDING:
+		reason = pendingReason
+	case iotv1.EndDeviceStatus_END_DEVICE_STATUS_INACTIVE:
+		reason = StatusReasonTrafficResumed
+	default:
+		return nil
+	}
+
+	err = mgr.transition(ctx, endDevice.GetId(), endDevice.GetStatus(), iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE, reason, seenAt)
+	if err != nil && !errors.Is(err, ErrInvalidStatusTransition) {
+		return err
+	}
+
+	return nil
+}
+
+// MarkSilentEndDevicesInactive marks every active end device that has not sent data within the silence period
+// as inactive and returns how many devices changed.
+func (mgr *EndDeviceStatusManager) MarkSilentEndDevicesInactive(ctx context.Context) (int, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "MarkSilentEndDevicesInactive")
+	defer span.End()
+
+	now := time.Now().UTC()
+	seenBefore := now.Add(-mgr.silencePeriod)
+
+	marked := 0
+	for {
+		endDeviceIds, err := mgr.statusStore.ListSilentEndDevices(ctx, iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE, seenBefore, silentEndDeviceBatchSize)
+		if err != nil {
+			return marked, err
+		}
+
+		for _, endDeviceId := range endDeviceIds {
+			err = mgr.transition(ctx, endDeviceId, iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE, iotv1.EndDeviceStatus_END_DEVICE_STATUS_INACTIVE, StatusReasonSilent, now)
+			if err != nil {
+				// Data arrived or the device was decommissioned since it was listed
+				if errors.Is(err, ErrInvalidStatusTransition) {
+					continue
+				}
+				return marked, err
+			}
+			marked++
+		}
+
+		if len(endDeviceIds) < silentEndDeviceBatchSize {
+			return marked, nil
+		}
+	}
+}
+
+// DecommissionEndDevice disables an end device so that any further data it sends is rejected.
+// Decommissioning an already disabled device is a no-op.
+func (mgr *EndDeviceStatusManager) DecommissionEndDevice(ctx context.Context, endDeviceId string, organizationId string, reason string) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "DecommissionEndDevice")
+	defer span.End()
+
+	endDevice, deviceOrgId, err := mgr.endDeviceStore.GetEndDeviceWithOrganization(ctx, endDeviceId)
+	if err != nil {
+		return err
+	}
+
+	if deviceOrgId != organizationId {
+		return stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceNotFound, endDeviceId)
+	}
+
+	if endDevice.GetStatus() == iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED {
+		return nil
+	}
+
+	if reason == "" {
+		reason = StatusReasonDecommissioned
+	}
+
+	return mgr.transition(ctx, endDeviceId, endDevice.GetStatus(), iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED, reason, time.Now().UTC())
+}
+
+// ListEndDeviceStatusTransitions returns the status history of an end device, most recent first.
+func (mgr *EndDeviceStatusManager) ListEndDeviceStatusTransitions(ctx context.Context, endDeviceId string, organizationId string) ([]*EndDeviceStatusTransition, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListEndDeviceStatusTransitions")
+	defer span.End()
+
+	_, deviceOrgId, err := mgr.endDeviceStore.GetEndDeviceWithOrganization(ctx, endDeviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if deviceOrgId != organizationId {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceNotFound, endDeviceId)
+	}
+
+	return mgr.statusStore.ListEndDeviceStatusTransitions(ctx, endDeviceId)
+}
+
+// transition checks that a status change is allowed and records it.
+func (mgr *EndDeviceStatusManager) transition(ctx context.Context, endDeviceId string, from iotv1.EndDeviceStatus, to iotv1.EndDeviceStatus, reason string, at time.Time) error {
+	if !CanTransitionEndDeviceStatus(from, to) {
+		return stacktrace.NewStackTraceErrorf("%w: %s cannot move from %s to %s", ErrInvalidStatusTransition, endDeviceId, from, to)
+	}
+
+	return mgr.statusStore.TransitionEndDeviceStatus(ctx, &EndDeviceStatusTransition{
+		Id:             mgr.stringId(),
+		EndDeviceId:    endDeviceId,
+		From:           from,
+		To:             to,
+		Reason:         reason,
+		TransitionedAt: at,
+	})
+}
+
+// EndDeviceStatusSweepRunner returns a runner function that marks silent end devices inactive every interval
+// until the runner's context is cancelled. Failed sweeps are logged and retried on the next tick.
+func EndDeviceStatusSweepRunner(mgr *EndDeviceStatusManager, interval time.Duration) runner.RunnerFunc {
+	return func(ctx context.Context) func() error {
+		return func() error {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+					marked, err := mgr.MarkSilentEndDevicesInactive(ctx)
+					if err != nil {
+						slog.Error("failed to mark silent end devices inactive", stacktrace.ErrorAttribute(err))
+						continue
+					}
+
+					if marked > 0 {
+						slog.Info("marked silent end devices inactive", slog.Int("count", marked))
+					}
+				}
+			}
+		}
+	}
+}
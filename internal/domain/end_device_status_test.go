@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransitionEndDeviceStatus(t *testing.T) {
+	pending := iotv1.EndDeviceStatus_END_DEVICE_STATUS_PENDING
+	active := iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE
+	inactive := iotv1.EndDeviceStatus_END_DEVICE_STATUS_INACTIVE
+	disabled := iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED
+
+	for _, tc := range []struct {
+		from    iotv1.EndDeviceStatus
+		to      iotv1.EndDeviceStatus
+		allowed bool
+	}{
+		{pending, active, true},
+		{pending, inactive, false},
+		{pending, disabled, true},
+		{active, inactive, true},
+		{active, pending, true},
+		{inactive, pending, true},
+		{inactive, active, true},
+		{inactive, disabled, true},
+		{disabled, active, false},
+		{disabled, pending, false},
+	} {
+		assert.Equal(t, tc.allowed, CanTransitionEndDeviceStatus(tc.from, tc.to), "%s -> %s", tc.from, tc.to)
+	}
+}
+
+func TestEndDeviceStatusManager_DecommissionEndDevice(t *testing.T) {
+	newManager := func(status iotv1.EndDeviceStatus) (*EndDeviceStatusManager, *memoryEndDeviceStore, *memoryEndDeviceStatusStore) {
+		endDevices := newMemoryEndDeviceStore()
+		endDevice := testLoRaWANEndDevice("device-1", "soil probe")
+		endDevice.SetStatus(status)
+		endDevices.put(endDevice, "org-1")
+
+		statusStore := newMemoryEndDeviceStatusStore(endDevices)
+		return NewEndDeviceStatusManager(statusStore, endDevices, func() string { return "transition-1" }, time.Hour), endDevices, statusStore
+	}
+
+	t.Run("disables the device and records the reason", func(t *testing.T) {
+		mgr, endDevices, _ := newManager(iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE)
+
+		err := mgr.DecommissionEndDevice(context.Background(), "device-1", "org-1", "sensor removed from field")
+
+		assert.NoError(t, err)
+		assert.Equal(t, iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED, endDevices.devices["device-1"].GetStatus())
+
+		transitions, err := mgr.ListEndDeviceStatusTransitions(context.Background(), "device-1", "org-1")
+		assert.NoError(t, err)
+		if assert.Len(t, transitions, 1) {
+			assert.Equal(t, iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE, transitions[0].From)
+			assert.Equal(t, iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED, transitions[0].To)
+			assert.Equal(t, "sensor removed from field", transitions[0].Reason)
+		}
+	})
+
+	t.Run("falls back to the default reason", func(t *testing.T) {
+		mgr, _, statusStore := newManager(iotv1.EndDeviceStatus_END_DEVICE_STATUS_PENDING)
+
+		err := mgr.DecommissionEndDevice(context.Background(), "device-1", "org-1", "")
+
+		assert.NoError(t, err)
+		if assert.Len(t, statusStore.transitions, 1) {
+			assert.Equal(t, StatusReasonDecommissioned, statusStore.transitions[0].Reason)
+		}
+	})
+
+	t.Run("leaves a disabled device alone", func(t *testing.T) {
+		mgr, _, statusStore := newManager(iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED)
+
+		err := mgr.DecommissionEndDevice(context.Background(), "device-1", "org-1", "")
+
+		assert.NoError(t, err)
+		assert.Empty(t, statusStore.transitions)
+	})
+
+	t.Run("hides devices of other organizations", func(t *testing.T) {
+		mgr, endDevices, _ := newManager(iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE)
+
+		err := mgr.DecommissionEndDevice(context.Background(), "device-1", "org-2", "")
+		assert.ErrorIs(t, err, ErrEndDeviceNotFound)
+		assert.Equal(t, iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE, endDevices.devices["device-1"].GetStatus())
+
+		_, err = mgr.ListEndDeviceStatusTransitions(context.Background(), "device-1", "org-2")
+		assert.ErrorIs(t, err, ErrEndDeviceNotFound)
+	})
+}
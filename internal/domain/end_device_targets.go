@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"context"
+
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// EndDeviceTargets selects the end devices an operation applies to.
+// Explicit IDs and device groups can be combined and are merged; a label selector must be used on its own.
+type EndDeviceTargets struct {
+	EndDeviceIds []string
+	GroupIds     []string
+	Labels       LabelSelector
+}
+
+// Empty reports whether no devices were selected, which operations treat as "every device in the organization".
+func (targets EndDeviceTargets) Empty() bool {
+	return len(targets.EndDeviceIds) == 0 && len(targets.GroupIds) == 0 && targets.Labels.Empty()
+}
+
+// EndDeviceResolver resolves device groups and label selectors to the end devices they contain.
+type EndDeviceResolver interface {
+	ListEndDeviceIDsByGroups(ctx context.Context, organizationID string, groupIDs []string) ([]string, error)
+	ListEndDeviceIDsByLabels(ctx context.Context, organizationID string, selector LabelSelector) ([]string, error)
+}
+
+// ResolveEndDeviceTargets expands targets into a list of end device IDs without duplicates.
+// Explicit IDs are returned as given, followed by the members of the requested groups.
+// Groups that do not belong to the organization are reported as ErrDeviceGroupNotFound.
+func ResolveEndDeviceTargets(ctx context.Context, resolver EndDeviceResolver, organizationId string, targets EndDeviceTargets) ([]string, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ResolveEndDeviceTargets")
+	defer span.End()
+
+	if !targets.Labels.Empty() {
+		if len(targets.EndDeviceIds) > 0 || len(targets.GroupIds) > 0 {
+			return nil, stacktrace.NewStackTraceErrorf("%w: a label selector cannot be combined with end device or group IDs", ErrInvalidMessageFormat)
+		}
+
+		return resolver.ListEndDeviceIDsByLabels(ctx, organizationId, targets.Labels)
+	}
+
+	endDeviceIds := make([]string, 0, len(targets.EndDeviceIds))
+	seen := map[string]bool{}
+	for _, id := range targets.EndDeviceIds {
+		if !seen[id] {
+			seen[id] = true
+			endDeviceIds = append(endDeviceIds, id)
+		}
+	}
+
+	if len(targets.GroupIds) > 0 {
+		members, err := resolver.ListEndDeviceIDsByGroups(ctx, organizationId, targets.GroupIds)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range members {
+			if !seen[id] {
+				seen[id] = true
+				endDeviceIds = append(endDeviceIds, id)
+			}
+		}
+	}
+
+	return endDeviceIds, nil
+}
@@ -0,0 +1,369 @@
+package domain
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndDeviceManager_GetEndDevice(t *testing.T) {
+	newManager := func() *EndDeviceManager {
+		store := newMemoryEndDeviceStore()
+		store.put(testLoRaWANEndDevice("device-1", "sensor"), "org-1")
+		store.put(iotv1.EndDevice_builder{
+			Id:           "device-2",
+			Name:         "gateway probe",
+			HardwareType: iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_HTTP,
+		}.Build(), "org-1")
+		store.put(iotv1.EndDevice_builder{
+			Id:           "device-4",
+			Name:         "press",
+			HardwareType: iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_MQTT,
+			MqttConfig: iotv1.MQTTConfig_builder{
+				Topic:        "plant/line-1/press",
+				Username:     "press-1",
+				PasswordHash: "PBKDF2$sha512$100000$salt$key",
+			}.Build(),
+		}.Build(), "org-1")
+		return newTestEndDeviceManager(store, &recordingEndDeviceRegister{})
+	}
+
+	t.Run("returns the complete hardware configuration with redacted keys", func(t *testing.T) {
+		assert := assert.New(t)
+
+		endDevice, err := newManager().GetEndDevice(context.Background(), "device-1", "org-1", false)
+
+		assert.NoError(err)
+		assert.Equal("sensor", endDevice.GetName())
+		assert.Equal("70b3d57ed0000001", endDevice.GetLorawanConfig().GetDeviceEui())
+		assert.Equal("US_902_928_FSB_2", endDevice.GetLorawanConfig().GetFrequencyPlan())
+		assert.Equal("ht-1", endDevice.GetLorawanConfig().GetHardwareData().GetHardwareTypeId())
+		assert.Empty(endDevice.GetLorawanConfig().GetApplicationKey())
+		assert.Empty(endDevice.GetLorawanConfig().GetNetworkKey())
+	})
+
+	t.Run("opens the root keys when asked to include them", func(t *testing.T) {
+		assert := assert.New(t)
+
+		endDevice, err := newManager().GetEndDevice(context.Background(), "device-1", "org-1", true)
+
+		assert.NoError(err)
+		assert.Equal("00112233445566778899aabbccddeeff", endDevice.GetLorawanConfig().GetApplicationKey())
+		assert.Equal("ffeeddccbbaa99887766554433221100", endDevice.GetLorawanConfig().GetNetworkKey())
+	})
+
+	t.Run("never returns the MQTT password hash", func(t *testing.T) {
+		assert := assert.New(t)
+
+		for _, includeKeys := range []bool{false, true} {
+			endDevice, err := newManager().GetEndDevice(context.Background(), "device-4", "org-1", includeKeys)
+
+			assert.NoError(err)
+			assert.Equal("plant/line-1/press", endDevice.GetMqttConfig().GetTopic())
+			assert.Equal("press-1", endDevice.GetMqttConfig().GetUsername())
+			assert.Empty(endDevice.GetMqttConfig().GetPasswordHash())
+		}
+	})
+
+	t.Run("returns devices without hardware configuration", func(t *testing.T) {
+		assert := assert.New(t)
+
+		endDevice, err := newManager().GetEndDevice(context.Background(), "device-2", "org-1", true)
+
+		assert.NoError(err)
+		assert.Equal(iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_HTTP, endDevice.GetHardwareType())
+		assert.Nil(endDevice.GetLorawanConfig())
+	})
+
+	t.Run("hides devices of other organizations", func(t *testing.T) {
+		_, err := newManager().GetEndDevice(context.Background(), "device-1", "org-2", false)
+
+		assert.ErrorIs(t, err, ErrEndDeviceNotFound)
+	})
+
+	t.Run("reports unknown devices as not found", func(t *testing.T) {
+		_, err := newManager().GetEndDevice(context.Background(), "device-3", "org-1", false)
+
+		assert.ErrorIs(t, err, ErrEndDeviceNotFound)
+	})
+}
+
+func TestEndDeviceManager_ListEndDevices(t *testing.T) {
+	newManager := func() (*EndDeviceManager, *memoryEndDeviceStore) {
+		store := newMemoryEndDeviceStore()
+		for _, name := range []string{"c", "a", "b"} {
+			store.put(testLoRaWANEndDevice("device-"+name, name), "org-1")
+		}
+		store.put(testLoRaWANEndDevice("device-x", "x"), "org-2")
+		return newTestEndDeviceManager(store, &recordingEndDeviceRegister{}), store
+	}
+
+	t.Run("pages through the devices of the organization", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, _ := newManager()
+
+		first, err := mgr.ListEndDevices(context.Background(), EndDeviceListRequest{OrganizationId: "org-1", PageSize: 2})
+		assert.NoError(err)
+		assert.Len(first.EndDevices, 2)
+		assert.Equal("a", first.EndDevices[0].GetName())
+		assert.Equal("b", first.EndDevices[1].GetName())
+		assert.NotEmpty(first.NextPageToken)
+
+		second, err := mgr.ListEndDevices(context.Background(), EndDeviceListRequest{OrganizationId: "org-1", PageSize: 2, PageToken: first.NextPageToken})
+		assert.NoError(err)
+		assert.Len(second.EndDevices, 1)
+		assert.Equal("c", second.EndDevices[0].GetName())
+		assert.Empty(second.NextPageToken)
+	})
+
+	t.Run("defaults and clamps the page size and sort order", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, store := newManager()
+
+		_, err := mgr.ListEndDevices(context.Background(), EndDeviceListRequest{OrganizationId: "org-1"})
+		assert.NoError(err)
+		_, err = mgr.ListEndDevices(context.Background(), EndDeviceListRequest{OrganizationId: "org-1", PageSize: MaxEndDevicePageSize + 1, SortBy: EndDeviceSortByCreatedAt})
+		assert.NoError(err)
+
+		assert.Equal(DefaultEndDevicePageSize, store.listQueries[0].Limit)
+		assert.Equal(EndDeviceSortByName, store.listQueries[0].SortBy)
+		assert.Equal(MaxEndDevicePageSize, store.listQueries[1].Limit)
+		assert.Equal(EndDeviceSortByCreatedAt, store.listQueries[1].SortBy)
+	})
+
+	t.Run("passes the filter on", func(t *testing.T) {
+		mgr, store := newManager()
+		filter := EndDeviceListFilter{Status: iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE, NamePrefix: "a"}
+
+		_, err := mgr.ListEndDevices(context.Background(), EndDeviceListRequest{OrganizationId: "org-1", Filter: filter})
+
+		assert.NoError(t, err)
+		assert.Equal(t, filter, store.listQueries[0].Filter)
+	})
+
+	t.Run("rejects unsupported sort fields", func(t *testing.T) {
+		mgr, _ := newManager()
+
+		_, err := mgr.ListEndDevices(context.Background(), EndDeviceListRequest{OrganizationId: "org-1", SortBy: "status"})
+
+		assert.ErrorIs(t, err, ErrInvalidMessageFormat)
+	})
+
+	t.Run("rejects malformed page tokens", func(t *testing.T) {
+		mgr, _ := newManager()
+
+		for _, token := range []string{"not base64!", "bm90IGpzb24", "e30"} {
+			_, err := mgr.ListEndDevices(context.Background(), EndDeviceListRequest{OrganizationId: "org-1", PageToken: token})
+			assert.ErrorIs(t, err, ErrInvalidPageToken, token)
+		}
+	})
+
+	t.Run("rejects name tokens when listing by creation time", func(t *testing.T) {
+		mgr, _ := newManager()
+		token, err := encodeEndDevicePageToken(&EndDeviceCursor{Id: "device-a", Name: "a"})
+		assert.NoError(t, err)
+
+		_, err = mgr.ListEndDevices(context.Background(), EndDeviceListRequest{OrganizationId: "org-1", SortBy: EndDeviceSortByCreatedAt, PageToken: token})
+
+		assert.ErrorIs(t, err, ErrInvalidPageToken)
+	})
+}
+
+func TestEndDevicePageToken(t *testing.T) {
+	t.Run("round trips a cursor", func(t *testing.T) {
+		assert := assert.New(t)
+		createdAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+		cursor := &EndDeviceCursor{Id: "device-1", Name: "sensor", CreatedAt: &createdAt}
+
+		token, err := encodeEndDevicePageToken(cursor)
+		assert.NoError(err)
+		decoded, err := decodeEndDevicePageToken(token)
+
+		assert.NoError(err)
+		assert.Equal(cursor, decoded)
+	})
+
+	t.Run("leaves out an unset creation time", func(t *testing.T) {
+		assert := assert.New(t)
+
+		token, err := encodeEndDevicePageToken(&EndDeviceCursor{Id: "device-1", Rank: 0.5})
+		assert.NoError(err)
+		raw, err := base64.RawURLEncoding.DecodeString(token)
+
+		assert.NoError(err)
+		assert.JSONEq(`{"id":"device-1","rank":0.5}`, string(raw))
+	})
+
+	t.Run("encodes no cursor as no token", func(t *testing.T) {
+		token, err := encodeEndDevicePageToken(nil)
+
+		assert.NoError(t, err)
+		assert.Empty(t, token)
+	})
+}
+
+func TestEndDeviceManager_UpdateEndDevice(t *testing.T) {
+	newManager := func() (*EndDeviceManager, *memoryEndDeviceStore, *recordingEndDeviceRegister) {
+		store := newMemoryEndDeviceStore()
+		store.put(testLoRaWANEndDevice("device-1", "sensor"), "org-1")
+		store.hardwareTypes["ht-2"] = iotv1.LoRaWANHardwareData_builder{
+			HardwareTypeId: "ht-2",
+			LorawanVersion: iotv1.LORAWANVersion_LORAWAN_VERSION_1_0_4,
+		}.Build()
+		register := &recordingEndDeviceRegister{}
+		return newTestEndDeviceManager(store, register), store, register
+	}
+
+	t.Run("applies the changed fields and syncs TTN", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, store, register := newManager()
+
+		endDevice, _, err := mgr.UpdateEndDevice(context.Background(), iotv1.EndDevice_builder{
+			Id:   "device-1",
+			Name: "renamed",
+			LorawanConfig: iotv1.LoRaWANConfig_builder{
+				FrequencyPlan: "EU_863_870",
+				HardwareData:  iotv1.LoRaWANHardwareData_builder{HardwareTypeId: "ht-2"}.Build(),
+			}.Build(),
+		}.Build(), "org-1", EndDeviceMetadata{})
+
+		assert.NoError(err)
+		assert.Equal("renamed", endDevice.GetName())
+		assert.Empty(endDevice.GetLorawanConfig().GetApplicationKey())
+		assert.Equal([]string{"update device-1 renamed"}, register.calls)
+
+		stored := store.devices["device-1"]
+		assert.Equal("renamed", stored.GetName())
+		assert.Equal("EU_863_870", stored.GetLorawanConfig().GetFrequencyPlan())
+		assert.Equal(iotv1.LORAWANVersion_LORAWAN_VERSION_1_0_4, stored.GetLorawanConfig().GetHardwareData().GetLorawanVersion())
+		assert.Equal("70b3d57ed0000001", stored.GetLorawanConfig().GetDeviceEui())
+	})
+
+	t.Run("writes labels and attributes with the device", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, store, _ := newManager()
+		store.metadata["device-1"] = EndDeviceMetadata{Labels: Labels{"site": "north"}, Attributes: map[string]any{"floor": 2.0}}
+
+		_, metadata, err := mgr.UpdateEndDevice(context.Background(), iotv1.EndDevice_builder{Id: "device-1", Name: "renamed"}.Build(), "org-1", EndDeviceMetadata{
+			Labels: Labels{"site": "south"},
+		})
+
+		assert.NoError(err)
+		assert.Equal(EndDeviceMetadata{Labels: Labels{"site": "south"}, Attributes: map[string]any{"floor": 2.0}}, metadata)
+		assert.Equal(metadata, store.metadata["device-1"])
+	})
+
+	t.Run("rejects invalid labels before syncing TTN", func(t *testing.T) {
+		mgr, _, register := newManager()
+
+		_, _, err := mgr.UpdateEndDevice(context.Background(), iotv1.EndDevice_builder{Id: "device-1", Name: "renamed"}.Build(), "org-1", EndDeviceMetadata{
+			Labels: Labels{"": "south"},
+		})
+
+		assert.ErrorIs(t, err, ErrInvalidLabel)
+		assert.Empty(t, register.calls)
+	})
+
+	t.Run("rejects status changes", func(t *testing.T) {
+		mgr, _, register := newManager()
+
+		_, _, err := mgr.UpdateEndDevice(context.Background(), iotv1.EndDevice_builder{
+			Id:     "device-1",
+			Status: iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED,
+		}.Build(), "org-1", EndDeviceMetadata{})
+
+		assert.ErrorIs(t, err, ErrInvalidStatusTransition)
+		assert.Empty(t, register.calls)
+	})
+
+	t.Run("hides devices of other organizations", func(t *testing.T) {
+		mgr, _, _ := newManager()
+
+		_, _, err := mgr.UpdateEndDevice(context.Background(), iotv1.EndDevice_builder{Id: "device-1", Name: "renamed"}.Build(), "org-2", EndDeviceMetadata{})
+
+		assert.ErrorIs(t, err, ErrEndDeviceNotFound)
+	})
+
+	t.Run("restores TTN when the commit fails", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, store, register := newManager()
+		store.commitErr = errors.New("commit failed")
+
+		_, _, err := mgr.UpdateEndDevice(context.Background(), iotv1.EndDevice_builder{Id: "device-1", Name: "renamed"}.Build(), "org-1", EndDeviceMetadata{})
+
+		assert.ErrorIs(err, store.commitErr)
+		assert.Equal([]string{"update device-1 renamed", "update device-1 sensor"}, register.calls)
+		assert.Equal("sensor", store.devices["device-1"].GetName())
+	})
+
+	t.Run("restores TTN when the sync fails part way", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, store, register := newManager()
+		register.err = errors.New("network server unavailable")
+
+		_, _, err := mgr.UpdateEndDevice(context.Background(), iotv1.EndDevice_builder{Id: "device-1", Name: "renamed"}.Build(), "org-1", EndDeviceMetadata{})
+
+		assert.ErrorIs(err, register.err)
+		assert.Equal([]string{"update device-1 renamed", "update device-1 sensor"}, register.calls)
+		assert.Equal("sensor", store.devices["device-1"].GetName())
+	})
+}
+
+func TestEndDeviceManager_DeleteEndDevice(t *testing.T) {
+	newManager := func() (*EndDeviceManager, *memoryEndDeviceStore, *recordingEndDeviceRegister) {
+		store := newMemoryEndDeviceStore()
+		store.put(testLoRaWANEndDevice("device-1", "sensor"), "org-1")
+		register := &recordingEndDeviceRegister{}
+		return newTestEndDeviceManager(store, register), store, register
+	}
+
+	t.Run("removes the device from TTN and the store", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, store, register := newManager()
+
+		err := mgr.DeleteEndDevice(context.Background(), "device-1", "org-1")
+
+		assert.NoError(err)
+		assert.Equal([]string{"delete device-1 sensor"}, register.calls)
+		assert.NotContains(store.devices, "device-1")
+	})
+
+	t.Run("hides devices of other organizations", func(t *testing.T) {
+		mgr, store, register := newManager()
+
+		err := mgr.DeleteEndDevice(context.Background(), "device-1", "org-2")
+
+		assert.ErrorIs(t, err, ErrEndDeviceNotFound)
+		assert.Empty(t, register.calls)
+		assert.Contains(t, store.devices, "device-1")
+	})
+
+	t.Run("registers the device again when the commit fails", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, store, register := newManager()
+		store.commitErr = errors.New("commit failed")
+
+		err := mgr.DeleteEndDevice(context.Background(), "device-1", "org-1")
+
+		assert.ErrorIs(err, store.commitErr)
+		assert.Equal([]string{"delete device-1 sensor", "register device-1 sensor"}, register.calls)
+		assert.Contains(store.devices, "device-1")
+	})
+
+	t.Run("keeps the device when TTN refuses the deletion", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, store, register := newManager()
+		register.err = errors.New("identity server unavailable")
+
+		err := mgr.DeleteEndDevice(context.Background(), "device-1", "org-1")
+
+		assert.ErrorIs(err, register.err)
+		assert.Equal([]string{"delete device-1 sensor"}, register.calls)
+		assert.Contains(store.devices, "device-1")
+	})
+}
@@ -0,0 +1,279 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+var (
+	// ErrInvalidEndDeviceTransfer is returned when a transfer names no end device, the same organization on both
+	// sides or an unknown history mode.
+	ErrInvalidEndDeviceTransfer = errors.New("invalid end device transfer")
+	// ErrEndDeviceTransferDenied is returned when the user is not an admin of both organizations of a transfer.
+	ErrEndDeviceTransferDenied = errors.New("end device transfer denied")
+	// ErrEndDeviceTransferNotFound is returned when a transfer does not exist.
+	ErrEndDeviceTransferNotFound = errors.New("end device transfer not found")
+)
+
+// EndDeviceHistoryMode decides what happens to the stored data of an end device that moves to another organization.
+type EndDeviceHistoryMode string
+
+const (
+	// EndDeviceHistoryKeep leaves the device's data with the previous organization; the new one only sees data
+	// sent after the transfer.
+	EndDeviceHistoryKeep EndDeviceHistoryMode = "keep"
+	// EndDeviceHistoryCopy copies the device's data to the new organization, so both organizations keep it.
+	EndDeviceHistoryCopy EndDeviceHistoryMode = "copy"
+	// EndDeviceHistoryRetag moves the device's data to the new organization.
+	EndDeviceHistoryRetag EndDeviceHistoryMode = "retag"
+)
+
+// EndDeviceTransfer records an end device moving from one organization to another. The device keeps its ID,
+// credentials and status; its device group memberships, profile and claim code stay behind with the previous owner.
+type EndDeviceTransfer struct {
+	Id                 string
+	EndDeviceId        string
+	FromOrganizationId string
+	ToOrganizationId   string
+	// TransferredBy is the user that transferred the device.
+	TransferredBy string
+	History       EndDeviceHistoryMode
+	TransferredAt time.Time
+	// HistoryTransferredAt is when the device's data was copied or re-tagged. It stays zero under the keep mode
+	// and until the data has been transferred.
+	HistoryTransferredAt time.Time
+}
+
+// HistoryPending reports whether the device's data still has to be copied or re-tagged.
+func (transfer *EndDeviceTransfer) HistoryPending() bool {
+	return transfer.History != EndDeviceHistoryKeep && transfer.HistoryTransferredAt.IsZero()
+}
+
+// EndDeviceTransferRequest asks for an end device to be moved to another organization.
+type EndDeviceTransferRequest struct {
+	EndDeviceId        string
+	FromOrganizationId string
+	ToOrganizationId   string
+	TransferredBy      string
+	// History defaults to EndDeviceHistoryKeep.
+	History EndDeviceHistoryMode
+}
+
+// validate checks the request and fills in the default history mode.
+func (req *EndDeviceTransferRequest) validate() error {
+	if req.EndDeviceId == "" || req.FromOrganizationId == "" || req.ToOrganizationId == "" {
+		return stacktrace.NewStackTraceErrorf("%w: end device and both organizations are required", ErrInvalidEndDeviceTransfer)
+	}
+
+	if req.FromOrganizationId == req.ToOrganizationId {
+		return stacktrace.NewStackTraceErrorf("%w: %s already belongs to %s", ErrInvalidEndDeviceTransfer, req.EndDeviceId, req.ToOrganizationId)
+	}
+
+	if req.History == "" {
+		req.History = EndDeviceHistoryKeep
+	}
+
+	switch req.History {
+	case EndDeviceHistoryKeep, EndDeviceHistoryCopy, EndDeviceHistoryRetag:
+		return nil
+	default:
+		return stacktrace.NewStackTraceErrorf("%w: unknown history mode %q", ErrInvalidEndDeviceTransfer, req.History)
+	}
+}
+
+// EndDeviceTransferStorer defines the persistence operations for end device transfers.
+type EndDeviceTransferStorer interface {
+	// TransferEndDevice moves the end device from the transfer's previous organization into the new one and records
+	// the transfer. It removes the device from the device groups and end device profile of its previous owner and
+	// deletes any claim code issued for it. It fails with ErrEndDeviceNotFound when the device no longer belongs to
+	// the previous organization.
+	TransferEndDevice(ctx context.Context, transfer *EndDeviceTransfer) error
+	// GetEndDeviceTransfer fails with ErrEndDeviceTransferNotFound when the transfer does not exist.
+	GetEndDeviceTransfer(ctx context.Context, transferId string) (*EndDeviceTransfer, error)
+	// ListEndDeviceTransfers returns the transfers of an end device, most recent first.
+	ListEndDeviceTransfers(ctx context.Context, endDeviceId string) ([]*EndDeviceTransfer, error)
+	// MarkEndDeviceTransferHistoryTransferred records when the data of a transfer was copied or re-tagged.
+	MarkEndDeviceTransferHistoryTransferred(ctx context.Context, transferId string, transferredAt time.Time) error
+}
+
+// EndDeviceHistoryTransferrer copies or moves the stored data an end device sent up to a point in time from one
+// organization to another. Both operations are safe to repeat after a failure.
+type EndDeviceHistoryTransferrer interface {
+	CopyEndDeviceData(ctx context.Context, endDeviceId string, fromOrganizationId string, toOrganizationId string, before time.Time) error
+	RetagEndDeviceData(ctx context.Context, endDeviceId string, fromOrganizationId string, toOrganizationId string, before time.Time) error
+}
+
+// EndDeviceTransferAuthorizer checks whether a user may transfer end devices into or out of an organization.
+type EndDeviceTransferAuthorizer interface {
+	CanTransferEndDevice(ctx context.Context, userId string, organizationId string) (bool, error)
+}
+
+// EndDeviceTransferManager moves end devices between organizations, for example when a device is resold or
+// reassigned to a sibling organization, without deleting and recreating it.
+type EndDeviceTransferManager struct {
+	transferStore  EndDeviceTransferStorer
+	endDeviceStore EndDeviceStorer
+	history        EndDeviceHistoryTransferrer
+	authorizer     EndDeviceTransferAuthorizer
+	stringId       StringId
+}
+
+// NewEndDeviceTransferManager creates a new instance of EndDeviceTransferManager with the provided dependencies.
+func NewEndDeviceTransferManager(transferStore EndDeviceTransferStorer, eds EndDeviceStorer, history EndDeviceHistoryTransferrer, authorizer EndDeviceTransferAuthorizer, stringId StringId) *EndDeviceTransferManager {
+	return &EndDeviceTransferManager{
+		transferStore:  transferStore,
+		endDeviceStore: eds,
+		history:        history,
+		authorizer:     authorizer,
+		stringId:       stringId,
+	}
+}
+
+// TransferEndDevice moves an end device into another organization on behalf of a user that is an admin of both,
+// or a super admin. Disabled devices cannot be transferred. Under the copy and retag history modes the device's
+// data stored up to the transfer is copied or moved to the new organization afterwards; when that fails the
+// transfer is still returned along with the error, and ResumeEndDeviceTransfer finishes it.
+func (mgr *EndDeviceTransferManager) TransferEndDevice(ctx context.Context, req EndDeviceTransferRequest) (*EndDeviceTransfer, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "TransferEndDevice")
+	defer span.End()
+
+	err := req.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = mgr.authorize(ctx, req.TransferredBy, req.FromOrganizationId, req.ToOrganizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	endDevice, organizationId, err := mgr.endDeviceStore.GetEndDeviceWithOrganization(ctx, req.EndDeviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if organizationId != req.FromOrganizationId {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceNotFound, req.EndDeviceId)
+	}
+
+	if endDevice.GetStatus() == iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceDisabled, req.EndDeviceId)
+	}
+
+	transfer := &EndDeviceTransfer{
+		Id:                 mgr.stringId(),
+		EndDeviceId:        req.EndDeviceId,
+		FromOrganizationId: req.FromOrganizationId,
+		ToOrganizationId:   req.ToOrganizationId,
+		TransferredBy:      req.TransferredBy,
+		History:            req.History,
+		TransferredAt:      time.Now().UTC(),
+	}
+
+	err = mgr.transferStore.TransferEndDevice(ctx, transfer)
+	if err != nil {
+		return nil, err
+	}
+
+	err = mgr.transferHistory(ctx, transfer)
+	if err != nil {
+		return transfer, err
+	}
+
+	return transfer, nil
+}
+
+// ResumeEndDeviceTransfer copies or re-tags the data of a transfer whose history could not be transferred before.
+// Transfers without pending history are returned unchanged. The user must still be an admin of both organizations.
+func (mgr *EndDeviceTransferManager) ResumeEndDeviceTransfer(ctx context.Context, transferId string, userId string) (*EndDeviceTransfer, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ResumeEndDeviceTransfer")
+	defer span.End()
+
+	transfer, err := mgr.transferStore.GetEndDeviceTransfer(ctx, transferId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = mgr.authorize(ctx, userId, transfer.FromOrganizationId, transfer.ToOrganizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = mgr.transferHistory(ctx, transfer)
+	if err != nil {
+		return transfer, err
+	}
+
+	return transfer, nil
+}
+
+// ListEndDeviceTransfers returns the transfers of an end device of the organization, most recent first.
+func (mgr *EndDeviceTransferManager) ListEndDeviceTransfers(ctx context.Context, endDeviceId string, organizationId string) ([]*EndDeviceTransfer, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListEndDeviceTransfers")
+	defer span.End()
+
+	_, deviceOrgId, err := mgr.endDeviceStore.GetEndDeviceWithOrganization(ctx, endDeviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if deviceOrgId != organizationId {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceNotFound, endDeviceId)
+	}
+
+	return mgr.transferStore.ListEndDeviceTransfers(ctx, endDeviceId)
+}
+
+// authorize checks that the user is an admin of both organizations. Super admins may transfer any end device.
+func (mgr *EndDeviceTransferManager) authorize(ctx context.Context, userId string, organizationIds ...string) error {
+	if IsSuperAdminFromContext(ctx) {
+		return nil
+	}
+
+	for _, organizationId := range organizationIds {
+		allowed, err := mgr.authorizer.CanTransferEndDevice(ctx, userId, organizationId)
+		if err != nil {
+			return stacktrace.NewStackTraceError(err)
+		}
+
+		if !allowed {
+			return stacktrace.NewStackTraceErrorf("%w: user %s is not an admin of organization %s", ErrEndDeviceTransferDenied, userId, organizationId)
+		}
+	}
+
+	return nil
+}
+
+// transferHistory copies or re-tags the data the device stored up to the transfer and records that it is done.
+// Data sent after the transfer is already stored under the new organization.
+func (mgr *EndDeviceTransferManager) transferHistory(ctx context.Context, transfer *EndDeviceTransfer) error {
+	if !transfer.HistoryPending() {
+		return nil
+	}
+
+	var err error
+	switch transfer.History {
+	case EndDeviceHistoryCopy:
+		err = mgr.history.CopyEndDeviceData(ctx, transfer.EndDeviceId, transfer.FromOrganizationId, transfer.ToOrganizationId, transfer.TransferredAt)
+	case EndDeviceHistoryRetag:
+		err = mgr.history.RetagEndDeviceData(ctx, transfer.EndDeviceId, transfer.FromOrganizationId, transfer.ToOrganizationId, transfer.TransferredAt)
+	}
+	if err != nil {
+		return err
+	}
+
+	transferredAt := time.Now().UTC()
+	err = mgr.transferStore.MarkEndDeviceTransferHistoryTransferred(ctx, transfer.Id, transferredAt)
+	if err != nil {
+		return err
+	}
+
+	transfer.HistoryTransferredAt = transferredAt
+
+	return nil
+}
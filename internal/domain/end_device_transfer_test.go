@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestEndDeviceTransferManager(history *recordingHistoryTransferrer) (*EndDeviceTransferManager, *memoryEndDeviceTransferStore) {
+	endDevices := newMemoryEndDeviceStore()
+	endDevices.put(iotv1.EndDevice_builder{Id: "device-1", Status: iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE}.Build(), "org-1")
+	endDevices.put(iotv1.EndDevice_builder{Id: "device-2", Status: iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED}.Build(), "org-1")
+	store := newMemoryEndDeviceTransferStore(endDevices)
+	authorizer := fakeAuthorizer{"admin": {"transfer org-1", "transfer org-2"}, "half-admin": {"transfer org-1"}}
+
+	return NewEndDeviceTransferManager(store, endDevices, history, authorizer, func() string { return "transfer-1" }), store
+}
+
+func TestEndDeviceTransferManager_TransferEndDevice(t *testing.T) {
+	t.Run("moves the device and keeps its history by default", func(t *testing.T) {
+		assert := assert.New(t)
+		history := &recordingHistoryTransferrer{}
+		mgr, store := newTestEndDeviceTransferManager(history)
+
+		transfer, err := mgr.TransferEndDevice(context.Background(), EndDeviceTransferRequest{EndDeviceId: "device-1", FromOrganizationId: "org-1", ToOrganizationId: "org-2", TransferredBy: "admin"})
+		assert.NoError(err)
+		assert.Equal(EndDeviceHistoryKeep, transfer.History)
+		assert.False(transfer.HistoryPending())
+		assert.Equal("org-2", store.endDevices.organizations["device-1"])
+		assert.Empty(history.calls)
+	})
+
+	t.Run("copies or re-tags the history", func(t *testing.T) {
+		for mode, call := range map[EndDeviceHistoryMode]string{
+			EndDeviceHistoryCopy:  "copy device-1 org-1 org-2",
+			EndDeviceHistoryRetag: "retag device-1 org-1 org-2",
+		} {
+			history := &recordingHistoryTransferrer{}
+			mgr, store := newTestEndDeviceTransferManager(history)
+
+			transfer, err := mgr.TransferEndDevice(context.Background(), EndDeviceTransferRequest{EndDeviceId: "device-1", FromOrganizationId: "org-1", ToOrganizationId: "org-2", TransferredBy: "admin", History: mode})
+			assert.NoError(t, err, mode)
+			assert.Equal(t, []string{call}, history.calls, mode)
+			assert.False(t, transfer.HistoryPending(), mode)
+			assert.False(t, store.transfers["transfer-1"].HistoryPending(), mode)
+		}
+	})
+
+	t.Run("resumes a failed history transfer", func(t *testing.T) {
+		assert := assert.New(t)
+		history := &recordingHistoryTransferrer{err: errors.New("clickhouse unavailable")}
+		mgr, store := newTestEndDeviceTransferManager(history)
+
+		transfer, err := mgr.TransferEndDevice(context.Background(), EndDeviceTransferRequest{EndDeviceId: "device-1", FromOrganizationId: "org-1", ToOrganizationId: "org-2", TransferredBy: "admin", History: EndDeviceHistoryRetag})
+		assert.Error(err)
+		if !assert.NotNil(transfer) {
+			return
+		}
+		assert.True(transfer.HistoryPending())
+		assert.Equal("org-2", store.endDevices.organizations["device-1"])
+
+		history.err = nil
+		transfer, err = mgr.ResumeEndDeviceTransfer(context.Background(), transfer.Id, "admin")
+		assert.NoError(err)
+		assert.False(transfer.HistoryPending())
+		assert.Len(history.calls, 2)
+
+		_, err = mgr.ResumeEndDeviceTransfer(context.Background(), transfer.Id, "admin")
+		assert.NoError(err)
+		assert.Len(history.calls, 2, "finished transfers are not repeated")
+	})
+
+	t.Run("requires admin rights in both organizations", func(t *testing.T) {
+		mgr, store := newTestEndDeviceTransferManager(&recordingHistoryTransferrer{})
+
+		for _, userId := range []string{"half-admin", "stranger"} {
+			_, err := mgr.TransferEndDevice(context.Background(), EndDeviceTransferRequest{EndDeviceId: "device-1", FromOrganizationId: "org-1", ToOrganizationId: "org-2", TransferredBy: userId})
+			assert.ErrorIs(t, err, ErrEndDeviceTransferDenied, userId)
+		}
+		assert.Equal(t, "org-1", store.endDevices.organizations["device-1"])
+
+		_, err := mgr.TransferEndDevice(SetSuperAdminContext(context.Background()), EndDeviceTransferRequest{EndDeviceId: "device-1", FromOrganizationId: "org-1", ToOrganizationId: "org-2", TransferredBy: "stranger"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejects invalid transfers", func(t *testing.T) {
+		mgr, _ := newTestEndDeviceTransferManager(&recordingHistoryTransferrer{})
+
+		for name, tc := range map[string]struct {
+			req      EndDeviceTransferRequest
+			expected error
+		}{
+			"same organization":  {EndDeviceTransferRequest{EndDeviceId: "device-1", FromOrganizationId: "org-1", ToOrganizationId: "org-1"}, ErrInvalidEndDeviceTransfer},
+			"missing device":     {EndDeviceTransferRequest{FromOrganizationId: "org-1", ToOrganizationId: "org-2"}, ErrInvalidEndDeviceTransfer},
+			"unknown history":    {EndDeviceTransferRequest{EndDeviceId: "device-1", FromOrganizationId: "org-1", ToOrganizationId: "org-2", History: "archive"}, ErrInvalidEndDeviceTransfer},
+			"other organization": {EndDeviceTransferRequest{EndDeviceId: "device-1", FromOrganizationId: "org-2", ToOrganizationId: "org-1"}, ErrEndDeviceNotFound},
+			"disabled device":    {EndDeviceTransferRequest{EndDeviceId: "device-2", FromOrganizationId: "org-1", ToOrganizationId: "org-2"}, ErrEndDeviceDisabled},
+		} {
+			tc.req.TransferredBy = "admin"
+			_, err := mgr.TransferEndDevice(context.Background(), tc.req)
+			assert.ErrorIs(t, err, tc.expected, name)
+		}
+	})
+}
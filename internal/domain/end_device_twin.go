@@ -0,0 +1,233 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"time"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var (
+	// ErrTwinVersionConflict is returned when a desired document is written against an outdated version.
+	ErrTwinVersionConflict = errors.New("end device twin version conflict")
+	// ErrInvalidTwinDocument is returned when a twin document is not a JSON object.
+	ErrInvalidTwinDocument = errors.New("invalid end device twin document")
+)
+
+// TwinReportedKey is the reserved key of ingested data that carries a device's reported state.
+// Its value is merged into the reported document instead of being stored as telemetry.
+const TwinReportedKey = "$reported"
+
+// Kinds of end device twin changes.
+const (
+	TwinChangeDesired  = "desired"
+	TwinChangeReported = "reported"
+)
+
+// EndDeviceTwin holds the state an end device should have and the state it last reported.
+// Desired and Reported are versioned independently; every write increments the version.
+type EndDeviceTwin struct {
+	EndDeviceId       string         `json:"end_device_id"`
+	Desired           map[string]any `json:"desired"`
+	DesiredVersion    int64          `json:"desired_version"`
+	DesiredUpdatedAt  time.Time      `json:"desired_updated_at,omitzero"`
+	Reported          map[string]any `json:"reported"`
+	ReportedVersion   int64          `json:"reported_version"`
+	ReportedUpdatedAt time.Time      `json:"reported_updated_at,omitzero"`
+}
+
+// Delta returns the desired values that the device has not reported yet. Nested objects are compared key by key;
+// keys that are only reported are ignored.
+func (twin *EndDeviceTwin) Delta() map[string]any {
+	return twinDelta(twin.Desired, twin.Reported)
+}
+
+// twinDelta returns the entries of desired that differ from reported.
+func twinDelta(desired map[string]any, reported map[string]any) map[string]any {
+	delta := map[string]any{}
+	for key, desiredValue := range desired {
+		reportedValue, ok := reported[key]
+		if !ok {
+			delta[key] = desiredValue
+			continue
+		}
+
+		desiredObject, desiredIsObject := desiredValue.(map[string]any)
+		reportedObject, reportedIsObject := reportedValue.(map[string]any)
+		if desiredIsObject && reportedIsObject {
+			if nested := twinDelta(desiredObject, reportedObject); len(nested) > 0 {
+				delta[key] = nested
+			}
+			continue
+		}
+
+		if !reflect.DeepEqual(desiredValue, reportedValue) {
+			delta[key] = desiredValue
+		}
+	}
+
+	return delta
+}
+
+// EndDeviceTwinChange describes a change to an end device twin for subscribers.
+type EndDeviceTwinChange struct {
+	OrganizationId string         `json:"organization_id"`
+	Kind           string         `json:"kind"`
+	Twin           *EndDeviceTwin `json:"twin"`
+	Delta          map[string]any `json:"delta"`
+}
+
+// EndDeviceTwinStorer defines the persistence operations for end device twins.
+type EndDeviceTwinStorer interface {
+	// GetEndDeviceTwin returns the device's twin, or an empty twin at version zero when none was written yet.
+	GetEndDeviceTwin(ctx context.Context, endDeviceId string) (*EndDeviceTwin, error)
+	// SetEndDeviceTwinDesired replaces the desired document, failing with ErrTwinVersionConflict when the stored
+	// desired version is not expectedVersion.
+	SetEndDeviceTwinDesired(ctx context.Context, endDeviceId string, desired map[string]any, expectedVersion int64) (*EndDeviceTwin, error)
+	// MergeEndDeviceTwinReported merges a patch into the reported document; keys set to nil are removed.
+	MergeEndDeviceTwinReported(ctx context.Context, endDeviceId string, reported map[string]any) (*EndDeviceTwin, error)
+}
+
+// EndDeviceTwinNotifier publishes end device twin changes.
+type EndDeviceTwinNotifier interface {
+	PublishEndDeviceTwinChange(ctx context.Context, change *EndDeviceTwinChange) error
+}
+
+// EndDeviceTwinManager orchestrates end device twin business logic.
+type EndDeviceTwinManager struct {
+	twinStore      EndDeviceTwinStorer
+	endDeviceStore EndDeviceStorer
+	notifier       EndDeviceTwinNotifier
+}
+
+// NewEndDeviceTwinManager creates a new instance of EndDeviceTwinManager with the provided dependencies.
+func NewEndDeviceTwinManager(twinStore EndDeviceTwinStorer, eds EndDeviceStorer, notifier EndDeviceTwinNotifier) *EndDeviceTwinManager {
+	return &EndDeviceTwinManager{
+		twinStore:      twinStore,
+		endDeviceStore: eds,
+		notifier:       notifier,
+	}
+}
+
+// GetEndDeviceTwin retrieves the twin of an end device in the organization.
+func (mgr *EndDeviceTwinManager) GetEndDeviceTwin(ctx context.Context, endDeviceId string, organizationId string) (*EndDeviceTwin, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetEndDeviceTwin")
+	defer span.End()
+
+	_, err := mgr.getEndDevice(ctx, endDeviceId, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	return mgr.twinStore.GetEndDeviceTwin(ctx, endDeviceId)
+}
+
+// SetEndDeviceTwinDesired replaces the desired document of an end device. expectedVersion must be the desired
+// version the caller last read, so concurrent writers cannot silently overwrite each other.
+func (mgr *EndDeviceTwinManager) SetEndDeviceTwinDesired(ctx context.Context, endDeviceId string, organizationId string, desired map[string]any, expectedVersion int64) (*EndDeviceTwin, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "SetEndDeviceTwinDesired")
+	defer span.End()
+
+	if desired == nil {
+		return nil, stacktrace.NewStackTraceErrorf("%w: desired must be an object", ErrInvalidTwinDocument)
+	}
+
+	_, err := mgr.getEndDevice(ctx, endDeviceId, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	twin, err := mgr.twinStore.SetEndDeviceTwinDesired(ctx, endDeviceId, desired, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	mgr.notify(ctx, organizationId, TwinChangeDesired, twin)
+
+	return twin, nil
+}
+
+// ReportEndDeviceTwinState merges state reported by an end device into its reported document.
+// Disabled end devices can no longer report state.
+func (mgr *EndDeviceTwinManager) ReportEndDeviceTwinState(ctx context.Context, endDeviceId string, organizationId string, reported map[string]any) (*EndDeviceTwin, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ReportEndDeviceTwinState")
+	defer span.End()
+
+	endDevice, err := mgr.getEndDevice(ctx, endDeviceId, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	if endDevice.GetStatus() == iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceDisabled, endDeviceId)
+	}
+
+	twin, err := mgr.twinStore.MergeEndDeviceTwinReported(ctx, endDeviceId, reported)
+	if err != nil {
+		return nil, err
+	}
+
+	mgr.notify(ctx, organizationId, TwinChangeReported, twin)
+
+	return twin, nil
+}
+
+// getEndDevice retrieves an end device, treating devices of other organizations as not found.
+func (mgr *EndDeviceTwinManager) getEndDevice(ctx context.Context, endDeviceId string, organizationId string) (*iotv1.EndDevice, error) {
+	endDevice, deviceOrgId, err := mgr.endDeviceStore.GetEndDeviceWithOrganization(ctx, endDeviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if deviceOrgId != organizationId {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceNotFound, endDeviceId)
+	}
+
+	return endDevice, nil
+}
+
+// notify publishes a twin change. The change is already stored, so a failure to publish is only logged.
+func (mgr *EndDeviceTwinManager) notify(ctx context.Context, organizationId string, kind string, twin *EndDeviceTwin) {
+	err := mgr.notifier.PublishEndDeviceTwinChange(ctx, &EndDeviceTwinChange{
+		OrganizationId: organizationId,
+		Kind:           kind,
+		Twin:           twin,
+		Delta:          twin.Delta(),
+	})
+	if err != nil {
+		slog.Error(
+			"failed to publish end device twin change",
+			slog.String("end_device_id", twin.EndDeviceId),
+			stacktrace.ErrorAttribute(err),
+		)
+	}
+}
+
+// SplitTwinReported separates the reserved reported state section from ingested data. It returns the remaining
+// telemetry and the reported state, which is nil when the data has no reported section.
+func SplitTwinReported(data *structpb.Struct) (*structpb.Struct, map[string]any, error) {
+	section, ok := data.GetFields()[TwinReportedKey]
+	if !ok {
+		return data, nil, nil
+	}
+
+	reported := section.GetStructValue()
+	if reported == nil {
+		return nil, nil, stacktrace.NewStackTraceErrorf("%w: %s must be an object", ErrInvalidTwinDocument, TwinReportedKey)
+	}
+
+	remaining := &structpb.Struct{Fields: make(map[string]*structpb.Value, len(data.GetFields())-1)}
+	for key, value := range data.GetFields() {
+		if key != TwinReportedKey {
+			remaining.Fields[key] = value
+		}
+	}
+
+	return remaining, reported.AsMap(), nil
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestEndDeviceTwinDelta(t *testing.T) {
+	assert := assert.New(t)
+
+	twin := &EndDeviceTwin{
+		Desired: map[string]any{
+			"interval": float64(60),
+			"led":      "on",
+			"lora":     map[string]any{"adr": true, "dr": float64(3)},
+			"firmware": "1.2.0",
+		},
+		Reported: map[string]any{
+			"interval": float64(60),
+			"led":      "off",
+			"lora":     map[string]any{"adr": true, "dr": float64(1)},
+			"battery":  float64(3.7),
+		},
+	}
+
+	assert.Equal(map[string]any{
+		"led":      "on",
+		"lora":     map[string]any{"dr": float64(3)},
+		"firmware": "1.2.0",
+	}, twin.Delta())
+
+	twin.Reported = twin.Desired
+	assert.Empty(twin.Delta())
+}
+
+func TestSplitTwinReported(t *testing.T) {
+	assert := assert.New(t)
+
+	data, err := structpb.NewStruct(map[string]any{
+		"temperature": 21.5,
+		"$reported":   map[string]any{"led": "on"},
+	})
+	assert.NoError(err)
+
+	remaining, reported, err := SplitTwinReported(data)
+	assert.NoError(err)
+	assert.Equal(map[string]any{"temperature": 21.5}, remaining.AsMap())
+	assert.Equal(map[string]any{"led": "on"}, reported)
+
+	data, err = structpb.NewStruct(map[string]any{"temperature": 21.5})
+	assert.NoError(err)
+
+	remaining, reported, err = SplitTwinReported(data)
+	assert.NoError(err)
+	assert.Same(data, remaining)
+	assert.Nil(reported)
+
+	data, err = structpb.NewStruct(map[string]any{"$reported": "on"})
+	assert.NoError(err)
+
+	_, _, err = SplitTwinReported(data)
+	assert.ErrorIs(err, ErrInvalidTwinDocument)
+}
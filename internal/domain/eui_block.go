@@ -0,0 +1,284 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+var (
+	// ErrInvalidEUI is returned when an EUI is not 64 bits of hex.
+	ErrInvalidEUI = errors.New("invalid EUI")
+	// ErrInvalidRootKey is returned when a LoRaWAN root key is not 128 bits of hex.
+	ErrInvalidRootKey = errors.New("invalid LoRaWAN root key")
+	// ErrDeviceEUIInUse is returned when a device EUI already belongs to another end device.
+	ErrDeviceEUIInUse = errors.New("device EUI already in use")
+	// ErrInvalidEUIBlock is returned when an EUI block prefix is not an MA-L, MA-M or MA-S assignment.
+	ErrInvalidEUIBlock = errors.New("invalid EUI block")
+	// ErrEUIBlockInUse is returned when an EUI block overlaps a block that is already configured.
+	ErrEUIBlockInUse = errors.New("EUI block overlaps an existing block")
+	// ErrEUIBlockExhausted is returned when every EUI block of an organization has been handed out.
+	ErrEUIBlockExhausted = errors.New("EUI blocks exhausted")
+)
+
+const (
+	// euiHexLength is the number of hex digits in an EUI-64.
+	euiHexLength = 16
+	// rootKeyBytes is the size of a LoRaWAN AES-128 root key.
+	rootKeyBytes = 16
+	// maxDeviceEUIAttempts bounds how many EUIs are skipped because they are already taken by factory-assigned devices.
+	maxDeviceEUIAttempts = 32
+)
+
+// EUIBlockType identifies the size of an IEEE address block assignment.
+type EUIBlockType string
+
+const (
+	// EUIBlockMAL is a large block with a 24-bit prefix (OUI).
+	EUIBlockMAL EUIBlockType = "MA-L"
+	// EUIBlockMAM is a medium block with a 28-bit prefix.
+	EUIBlockMAM EUIBlockType = "MA-M"
+	// EUIBlockMAS is a small block with a 36-bit prefix.
+	EUIBlockMAS EUIBlockType = "MA-S"
+)
+
+// euiBlockTypes maps the number of hex digits in a block prefix to its assignment type.
+var euiBlockTypes = map[int]EUIBlockType{
+	6: EUIBlockMAL,
+	7: EUIBlockMAM,
+	9: EUIBlockMAS,
+}
+
+// EUIBlock is an IEEE address block that device EUIs of an organization are handed out from.
+// NextOffset only ever grows, so an EUI from the block is never handed out twice.
+type EUIBlock struct {
+	Prefix         string    `json:"prefix"`
+	OrganizationId string    `json:"organization_id"`
+	Size           int64     `json:"size"`
+	NextOffset     int64     `json:"next_offset"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// NewEUIBlock creates an EUI block for an organization from an IEEE assignment prefix given in hex,
+// such as "70B3D5" for an MA-L or "70B3D57ED" for an MA-S. Separators between hex digits are ignored.
+func NewEUIBlock(organizationId string, prefix string) (*EUIBlock, error) {
+	prefix = normalizeHex(prefix)
+
+	_, err := hex.DecodeString(prefix + strings.Repeat("0", len(prefix)%2))
+	if err != nil {
+		return nil, stacktrace.NewStackTraceErrorf("%w: prefix %q is not hex", ErrInvalidEUIBlock, prefix)
+	}
+
+	if _, ok := euiBlockTypes[len(prefix)]; !ok {
+		return nil, stacktrace.NewStackTraceErrorf("%w: prefix %q must have 6 (MA-L), 7 (MA-M) or 9 (MA-S) hex digits", ErrInvalidEUIBlock, prefix)
+	}
+
+	return &EUIBlock{
+		Prefix:         prefix,
+		OrganizationId: organizationId,
+		Size:           1 << (4 * (euiHexLength - len(prefix))),
+	}, nil
+}
+
+// Type returns the IEEE assignment type of the block.
+func (block *EUIBlock) Type() EUIBlockType {
+	return euiBlockTypes[len(block.Prefix)]
+}
+
+// EUI returns the EUI at the given offset within the block.
+func (block *EUIBlock) EUI(offset int64) string {
+	return euiAt(block.Prefix, offset)
+}
+
+// euiAt returns the EUI at the given offset after a hex prefix.
+func euiAt(prefix string, offset int64) string {
+	return fmt.Sprintf("%s%0*X", prefix, euiHexLength-len(prefix), offset)
+}
+
+// LoRaWANIdentity holds the factory-assigned identifiers and root key of a LoRaWAN end device.
+// Empty fields are generated when the device is created.
+type LoRaWANIdentity struct {
+	DeviceEui      string
+	JoinEui        string
+	ApplicationKey string
+}
+
+// Normalize validates the supplied fields and returns them as uppercase hex without separators.
+func (identity LoRaWANIdentity) Normalize() (LoRaWANIdentity, error) {
+	normalized := LoRaWANIdentity{
+		DeviceEui:      normalizeHex(identity.DeviceEui),
+		JoinEui:        normalizeHex(identity.JoinEui),
+		ApplicationKey: normalizeHex(identity.ApplicationKey),
+	}
+
+	if normalized.DeviceEui != "" && !isHexOfLength(normalized.DeviceEui, euiHexLength) {
+		return LoRaWANIdentity{}, stacktrace.NewStackTraceErrorf("%w: device EUI %q", ErrInvalidEUI, identity.DeviceEui)
+	}
+	if normalized.JoinEui != "" && !isHexOfLength(normalized.JoinEui, euiHexLength) {
+		return LoRaWANIdentity{}, stacktrace.NewStackTraceErrorf("%w: join EUI %q", ErrInvalidEUI, identity.JoinEui)
+	}
+	if normalized.ApplicationKey != "" && !isHexOfLength(normalized.ApplicationKey, 2*rootKeyBytes) {
+		return LoRaWANIdentity{}, stacktrace.NewStackTraceErrorf("%w: application key must be %d hex digits", ErrInvalidRootKey, 2*rootKeyBytes)
+	}
+
+	return normalized, nil
+}
+
+// normalizeHex uppercases a hex string and strips the separators commonly printed on device labels.
+func normalizeHex(value string) string {
+	return strings.ToUpper(strings.NewReplacer(":", "", "-", "", " ", "").Replace(strings.TrimSpace(value)))
+}
+
+// isHexOfLength reports whether value consists of exactly length hex digits.
+func isHexOfLength(value string, length int) bool {
+	if len(value) != length {
+		return false
+	}
+
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
+// GenerateRootKey returns a random 128-bit LoRaWAN root key as uppercase hex.
+func GenerateRootKey() (string, error) {
+	key := make([]byte, rootKeyBytes)
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", stacktrace.NewStackTraceError(err)
+	}
+
+	return strings.ToUpper(hex.EncodeToString(key)), nil
+}
+
+// GenerateLocalEUI returns a random EUI-64 marked as locally administered, so it cannot clash with
+// any IEEE-assigned EUI.
+func GenerateLocalEUI() (string, error) {
+	eui := make([]byte, euiHexLength/2)
+	_, err := rand.Read(eui)
+	if err != nil {
+		return "", stacktrace.NewStackTraceError(err)
+	}
+
+	// Set the locally administered bit and clear the group bit of the first octet
+	eui[0] = (eui[0] | 0x02) &^ 0x01
+
+	return strings.ToUpper(hex.EncodeToString(eui)), nil
+}
+
+// EUIBlockStorer defines the persistence operations for EUI blocks.
+type EUIBlockStorer interface {
+	// AddEUIBlock stores a new block, failing with ErrEUIBlockInUse when it overlaps an existing block.
+	AddEUIBlock(ctx context.Context, block *EUIBlock) (*EUIBlock, error)
+	ListEUIBlocks(ctx context.Context, organizationId string) ([]*EUIBlock, error)
+	// ReserveEUIBlockOffset hands out the next offset of the organization's oldest block with room left and
+	// returns the block's prefix. It fails with ErrEUIBlockExhausted when no block has room.
+	ReserveEUIBlockOffset(ctx context.Context, organizationId string) (string, int64, error)
+	LoRaWANDeviceEUIExists(ctx context.Context, deviceEui string) (bool, error)
+}
+
+// EUIBlockManager allocates device EUIs from the IEEE address blocks configured for each organization.
+type EUIBlockManager struct {
+	blockStore EUIBlockStorer
+}
+
+// NewEUIBlockManager creates a new instance of EUIBlockManager with the provided dependencies.
+func NewEUIBlockManager(blockStore EUIBlockStorer) *EUIBlockManager {
+	return &EUIBlockManager{
+		blockStore: blockStore,
+	}
+}
+
+// AddEUIBlock assigns an IEEE address block to an organization. Blocks are never removed, so the EUIs
+// handed out from them are never reused.
+func (mgr *EUIBlockManager) AddEUIBlock(ctx context.Context, organizationId string, prefix string) (*EUIBlock, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "AddEUIBlock")
+	defer span.End()
+
+	block, err := NewEUIBlock(organizationId, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return mgr.blockStore.AddEUIBlock(ctx, block)
+}
+
+// ListEUIBlocks returns the EUI blocks of an organization, oldest first.
+func (mgr *EUIBlockManager) ListEUIBlocks(ctx context.Context, organizationId string) ([]*EUIBlock, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListEUIBlocks")
+	defer span.End()
+
+	return mgr.blockStore.ListEUIBlocks(ctx, organizationId)
+}
+
+// AllocateDeviceEUI hands out an unused device EUI for an organization. EUIs come from the organization's
+// blocks in order; EUIs already taken by factory-assigned devices are skipped. Organizations without any block
+// get a random locally administered EUI instead.
+func (mgr *EUIBlockManager) AllocateDeviceEUI(ctx context.Context, organizationId string) (string, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "AllocateDeviceEUI")
+	defer span.End()
+
+	for range maxDeviceEUIAttempts {
+		eui, err := mgr.nextDeviceEUI(ctx, organizationId)
+		if err != nil {
+			return "", err
+		}
+
+		exists, err := mgr.blockStore.LoRaWANDeviceEUIExists(ctx, eui)
+		if err != nil {
+			return "", err
+		}
+
+		if !exists {
+			return eui, nil
+		}
+	}
+
+	return "", stacktrace.NewStackTraceErrorf("%w: no free device EUI after %d attempts", ErrDeviceEUIInUse, maxDeviceEUIAttempts)
+}
+
+// CheckDeviceEUIAvailable fails with ErrDeviceEUIInUse when a device EUI already belongs to an end device.
+func (mgr *EUIBlockManager) CheckDeviceEUIAvailable(ctx context.Context, deviceEui string) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "CheckDeviceEUIAvailable")
+	defer span.End()
+
+	exists, err := mgr.blockStore.LoRaWANDeviceEUIExists(ctx, deviceEui)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return stacktrace.NewStackTraceErrorf("%w: %s", ErrDeviceEUIInUse, deviceEui)
+	}
+
+	return nil
+}
+
+// nextDeviceEUI reserves the next EUI from the organization's blocks, or generates one when it has none.
+func (mgr *EUIBlockManager) nextDeviceEUI(ctx context.Context, organizationId string) (string, error) {
+	prefix, offset, err := mgr.blockStore.ReserveEUIBlockOffset(ctx, organizationId)
+	if err == nil {
+		return euiAt(prefix, offset), nil
+	}
+
+	if !errors.Is(err, ErrEUIBlockExhausted) {
+		return "", err
+	}
+
+	blocks, listErr := mgr.blockStore.ListEUIBlocks(ctx, organizationId)
+	if listErr != nil {
+		return "", listErr
+	}
+
+	if len(blocks) > 0 {
+		return "", err
+	}
+
+	return GenerateLocalEUI()
+}
@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryEUIBlockStore struct {
+	EUIBlockStorer
+	blocks []*EUIBlock
+	taken  map[string]bool
+}
+
+func (store *memoryEUIBlockStore) ListEUIBlocks(ctx context.Context, organizationId string) ([]*EUIBlock, error) {
+	return store.blocks, nil
+}
+
+func (store *memoryEUIBlockStore) ReserveEUIBlockOffset(ctx context.Context, organizationId string) (string, int64, error) {
+	for _, block := range store.blocks {
+		if block.NextOffset < block.Size {
+			block.NextOffset++
+			return block.Prefix, block.NextOffset - 1, nil
+		}
+	}
+	return "", 0, stacktrace.NewStackTraceError(ErrEUIBlockExhausted)
+}
+
+func (store *memoryEUIBlockStore) LoRaWANDeviceEUIExists(ctx context.Context, deviceEui string) (bool, error) {
+	return store.taken[deviceEui], nil
+}
+
+func TestNewEUIBlock(t *testing.T) {
+	assert := assert.New(t)
+
+	block, err := NewEUIBlock("org-1", "70-b3-d5")
+	assert.NoError(err)
+	assert.Equal("70B3D5", block.Prefix)
+	assert.Equal(EUIBlockMAL, block.Type())
+	assert.Equal(int64(1)<<40, block.Size)
+	assert.Equal("70B3D5000000002A", block.EUI(42))
+
+	block, err = NewEUIBlock("org-1", "70B3D57ED")
+	assert.NoError(err)
+	assert.Equal(EUIBlockMAS, block.Type())
+	assert.Equal(int64(1)<<28, block.Size)
+	assert.Equal("70B3D57ED0FFFFFF", block.EUI(0xFFFFFF))
+
+	_, err = NewEUIBlock("org-1", "70B3D57E")
+	assert.ErrorIs(err, ErrInvalidEUIBlock)
+
+	_, err = NewEUIBlock("org-1", "70B3DZ")
+	assert.ErrorIs(err, ErrInvalidEUIBlock)
+}
+
+func TestAllocateDeviceEUI(t *testing.T) {
+	assert := assert.New(t)
+
+	block, err := NewEUIBlock("org-1", "70B3D57ED")
+	assert.NoError(err)
+	block.Size = 3
+
+	store := &memoryEUIBlockStore{
+		blocks: []*EUIBlock{block},
+		taken:  map[string]bool{"70B3D57ED0000000": true},
+	}
+	mgr := NewEUIBlockManager(store)
+
+	// The factory-assigned EUI at offset 0 is skipped
+	eui, err := mgr.AllocateDeviceEUI(context.Background(), "org-1")
+	assert.NoError(err)
+	assert.Equal("70B3D57ED0000001", eui)
+
+	eui, err = mgr.AllocateDeviceEUI(context.Background(), "org-1")
+	assert.NoError(err)
+	assert.Equal("70B3D57ED0000002", eui)
+
+	_, err = mgr.AllocateDeviceEUI(context.Background(), "org-1")
+	assert.ErrorIs(err, ErrEUIBlockExhausted)
+
+	// Organizations without blocks get locally administered EUIs
+	mgr = NewEUIBlockManager(&memoryEUIBlockStore{})
+	eui, err = mgr.AllocateDeviceEUI(context.Background(), "org-2")
+	assert.NoError(err)
+
+	raw, err := hex.DecodeString(eui)
+	assert.NoError(err)
+	assert.Len(raw, 8)
+	assert.Equal(byte(0x02), raw[0]&0x03)
+}
+
+func TestLoRaWANIdentityNormalize(t *testing.T) {
+	assert := assert.New(t)
+
+	identity, err := LoRaWANIdentity{
+		DeviceEui:      "70:b3:d5:7e:d0:00:12:34",
+		ApplicationKey: "00112233445566778899aabbccddeeff",
+	}.Normalize()
+	assert.NoError(err)
+	assert.Equal(LoRaWANIdentity{
+		DeviceEui:      "70B3D57ED0001234",
+		ApplicationKey: "00112233445566778899AABBCCDDEEFF",
+	}, identity)
+
+	_, err = LoRaWANIdentity{JoinEui: "70B3D57ED00012"}.Normalize()
+	assert.ErrorIs(err, ErrInvalidEUI)
+
+	_, err = LoRaWANIdentity{ApplicationKey: "not a key"}.Normalize()
+	assert.ErrorIs(err, ErrInvalidRootKey)
+}
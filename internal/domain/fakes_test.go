@@ -0,0 +1,817 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	envelopev1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/envelope/v1"
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// errTestHardwareTypeNotFound is returned by memoryEndDeviceStore for unknown LoRaWAN hardware types.
+var errTestHardwareTypeNotFound = errors.New("hardware type not found")
+
+// memoryEndDeviceStore keeps end devices in memory, keyed by ID. Writes run their sync first and then fail with
+// commitErr, like a transaction whose commit fails. Operations the tests do not need are left to the embedded
+// interface and panic when called.
+type memoryEndDeviceStore struct {
+	EndDeviceStorer
+	devices       map[string]*iotv1.EndDevice
+	organizations map[string]string
+	metadata      map[string]EndDeviceMetadata
+	hardwareTypes map[string]*iotv1.LoRaWANHardwareData
+	listQueries   []EndDeviceListQuery
+	commitErr     error
+	// insertErrs fails the batch insert of the devices with these names.
+	insertErrs map[string]error
+	batchSizes []int
+}
+
+func newMemoryEndDeviceStore() *memoryEndDeviceStore {
+	return &memoryEndDeviceStore{
+		devices:       map[string]*iotv1.EndDevice{},
+		organizations: map[string]string{},
+		metadata:      map[string]EndDeviceMetadata{},
+		hardwareTypes: map[string]*iotv1.LoRaWANHardwareData{},
+	}
+}
+
+// put stores an end device of an organization directly, bypassing sync and commitErr.
+func (store *memoryEndDeviceStore) put(endDevice *iotv1.EndDevice, organizationId string) {
+	store.devices[endDevice.GetId()] = proto.Clone(endDevice).(*iotv1.EndDevice)
+	store.organizations[endDevice.GetId()] = organizationId
+}
+
+func (store *memoryEndDeviceStore) commit(ctx context.Context, sync EndDeviceSync) error {
+	if sync != nil {
+		err := sync(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return store.commitErr
+}
+
+func (store *memoryEndDeviceStore) AddEndDevice(ctx context.Context, endDevice *iotv1.EndDevice, organizationId string, metadata EndDeviceMetadata, sync EndDeviceSync) error {
+	err := store.commit(ctx, sync)
+	if err != nil {
+		return err
+	}
+	store.put(endDevice, organizationId)
+	store.metadata[endDevice.GetId()] = metadata
+	return nil
+}
+
+// AddEndDevices stores a batch, failing the devices named in insertErrs; with commitErr set nothing is stored.
+func (store *memoryEndDeviceStore) AddEndDevices(_ context.Context, endDevices []PendingEndDevice) ([]error, error) {
+	store.batchSizes = append(store.batchSizes, len(endDevices))
+
+	results := make([]error, len(endDevices))
+	for i, pending := range endDevices {
+		results[i] = store.insertErrs[pending.EndDevice.GetName()]
+	}
+	if store.commitErr != nil {
+		return results, store.commitErr
+	}
+
+	for i, pending := range endDevices {
+		if results[i] == nil {
+			store.put(pending.EndDevice, pending.OrganizationId)
+			store.metadata[pending.EndDevice.GetId()] = pending.Metadata
+		}
+	}
+	return results, nil
+}
+
+func (store *memoryEndDeviceStore) GetLoRaWANHardwareType(_ context.Context, hardwareTypeId string) (*iotv1.LoRaWANHardwareData, error) {
+	hardwareData, ok := store.hardwareTypes[hardwareTypeId]
+	if !ok {
+		return nil, errTestHardwareTypeNotFound
+	}
+	return proto.Clone(hardwareData).(*iotv1.LoRaWANHardwareData), nil
+}
+
+func (store *memoryEndDeviceStore) GetEndDevice(_ context.Context, endDeviceId string) (*iotv1.EndDevice, string, error) {
+	endDevice, ok := store.devices[endDeviceId]
+	if !ok {
+		return nil, "", ErrEndDeviceNotFound
+	}
+	return proto.Clone(endDevice).(*iotv1.EndDevice), store.organizations[endDeviceId], nil
+}
+
+func (store *memoryEndDeviceStore) GetEndDeviceWithOrganization(ctx context.Context, endDeviceId string) (*iotv1.EndDevice, string, error) {
+	return store.GetEndDevice(ctx, endDeviceId)
+}
+
+// ListEndDevices pages through the devices of an organization ordered by name and ID. Filters are recorded but not applied.
+func (store *memoryEndDeviceStore) ListEndDevices(_ context.Context, query EndDeviceListQuery) ([]*iotv1.EndDevice, *EndDeviceCursor, error) {
+	store.listQueries = append(store.listQueries, query)
+
+	var endDevices []*iotv1.EndDevice
+	for id, endDevice := range store.devices {
+		if store.organizations[id] == query.OrganizationId {
+			endDevices = append(endDevices, endDevice)
+		}
+	}
+	sort.Slice(endDevices, func(i, j int) bool {
+		return endDeviceListKey(endDevices[i]) < endDeviceListKey(endDevices[j])
+	})
+
+	if query.After != nil {
+		after := query.After.Name + "\x00" + query.After.Id
+		for len(endDevices) > 0 && endDeviceListKey(endDevices[0]) <= after {
+			endDevices = endDevices[1:]
+		}
+	}
+
+	var next *EndDeviceCursor
+	if len(endDevices) > query.Limit {
+		endDevices = endDevices[:query.Limit]
+		last := endDevices[len(endDevices)-1]
+		next = &EndDeviceCursor{Id: last.GetId(), Name: last.GetName()}
+	}
+
+	return endDevices, next, nil
+}
+
+func endDeviceListKey(endDevice *iotv1.EndDevice) string {
+	return strings.Join([]string{endDevice.GetName(), endDevice.GetId()}, "\x00")
+}
+
+func (store *memoryEndDeviceStore) UpdateEndDevice(ctx context.Context, endDevice *iotv1.EndDevice, metadata EndDeviceMetadata, sync EndDeviceSync) error {
+	if _, ok := store.devices[endDevice.GetId()]; !ok {
+		return ErrEndDeviceNotFound
+	}
+	err := store.commit(ctx, sync)
+	if err != nil {
+		return err
+	}
+	store.put(endDevice, store.organizations[endDevice.GetId()])
+	store.metadata[endDevice.GetId()] = metadata
+	return nil
+}
+
+func (store *memoryEndDeviceStore) DeleteEndDevice(ctx context.Context, endDeviceId string, sync EndDeviceSync) error {
+	if _, ok := store.devices[endDeviceId]; !ok {
+		return ErrEndDeviceNotFound
+	}
+	err := store.commit(ctx, sync)
+	if err != nil {
+		return err
+	}
+	delete(store.devices, endDeviceId)
+	delete(store.organizations, endDeviceId)
+	delete(store.metadata, endDeviceId)
+	return nil
+}
+
+func (store *memoryEndDeviceStore) GetEndDeviceMetadata(_ context.Context, endDeviceId string) (EndDeviceMetadata, string, error) {
+	if _, ok := store.devices[endDeviceId]; !ok {
+		return EndDeviceMetadata{}, "", ErrEndDeviceNotFound
+	}
+	return store.metadata[endDeviceId], store.organizations[endDeviceId], nil
+}
+
+// recordingEndDeviceRegister records the registry operations it is asked for, with the device name, and fails
+// while err is set.
+type recordingEndDeviceRegister struct {
+	calls []string
+	err   error
+}
+
+func (register *recordingEndDeviceRegister) RegisterEndDevice(_ context.Context, endDevice *iotv1.EndDevice) error {
+	register.calls = append(register.calls, "register "+endDevice.GetId()+" "+endDevice.GetName())
+	return register.err
+}
+
+func (register *recordingEndDeviceRegister) UpdateEndDevice(_ context.Context, endDevice *iotv1.EndDevice) error {
+	register.calls = append(register.calls, "update "+endDevice.GetId()+" "+endDevice.GetName())
+	return register.err
+}
+
+func (register *recordingEndDeviceRegister) DeleteEndDevice(_ context.Context, endDevice *iotv1.EndDevice) error {
+	register.calls = append(register.calls, "delete "+endDevice.GetId()+" "+endDevice.GetName())
+	return register.err
+}
+
+// prefixRootKeySealer "seals" root keys by prefixing them, so tests can tell sealed keys from plaintext.
+type prefixRootKeySealer struct{}
+
+func (prefixRootKeySealer) SealRootKeys(_ context.Context, rootKeys ...string) ([]string, error) {
+	sealed := make([]string, len(rootKeys))
+	for i, rootKey := range rootKeys {
+		sealed[i] = "sealed:" + rootKey
+	}
+	return sealed, nil
+}
+
+func (prefixRootKeySealer) OpenRootKey(_ context.Context, value string) (string, error) {
+	return strings.TrimPrefix(value, "sealed:"), nil
+}
+
+// sequentialEUIAllocator hands out device EUIs 70b3d57ed0000101, 70b3d57ed0000102 and so on, and reports the
+// EUIs in inUse as taken.
+type sequentialEUIAllocator struct {
+	allocated int
+	inUse     map[string]bool
+}
+
+func (allocator *sequentialEUIAllocator) AllocateDeviceEUI(context.Context, string) (string, error) {
+	allocator.allocated++
+	return fmt.Sprintf("70b3d57ed0000%03x", 0x100+allocator.allocated), nil
+}
+
+func (allocator *sequentialEUIAllocator) CheckDeviceEUIAvailable(_ context.Context, deviceEui string) error {
+	if allocator.inUse[deviceEui] {
+		return ErrDeviceEUIInUse
+	}
+	return nil
+}
+
+// newTestEndDeviceManager builds an EndDeviceManager over the given store and register. Messages are not validated
+// and new end devices get the IDs "device-new-1", "device-new-2" and so on.
+func newTestEndDeviceManager(store *memoryEndDeviceStore, register *recordingEndDeviceRegister) *EndDeviceManager {
+	created := 0
+	newId := func() string {
+		created++
+		return fmt.Sprintf("device-new-%d", created)
+	}
+	return NewEndDeviceManager(store, register, &sequentialEUIAllocator{}, prefixRootKeySealer{}, nil, "ponix", newId, func(any) error { return nil })
+}
+
+// testLoRaWANEndDevice builds an active LoRaWAN end device with sealed root keys.
+func testLoRaWANEndDevice(id string, name string) *iotv1.EndDevice {
+	return iotv1.EndDevice_builder{
+		Id:           id,
+		Name:         name,
+		Status:       iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE,
+		HardwareType: iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_LORAWAN,
+		LorawanConfig: iotv1.LoRaWANConfig_builder{
+			DeviceEui:      "70b3d57ed0000001",
+			ApplicationEui: "0000000000000001",
+			ApplicationKey: "sealed:00112233445566778899aabbccddeeff",
+			NetworkKey:     "sealed:ffeeddccbbaa99887766554433221100",
+			FrequencyPlan:  "US_902_928_FSB_2",
+			HardwareData: iotv1.LoRaWANHardwareData_builder{
+				HardwareTypeId: "ht-1",
+				LorawanVersion: iotv1.LORAWANVersion_LORAWAN_VERSION_1_0_3,
+			}.Build(),
+		}.Build(),
+	}.Build()
+}
+
+// memoryDeviceGroupStore keeps device groups and their members in memory. It also resolves end device targets:
+// groups to their members, and label selectors to labelMatches, recording the selectors it was asked for.
+type memoryDeviceGroupStore struct {
+	groups        map[string]*DeviceGroup
+	members       map[string][]string
+	endDevices    map[string]*iotv1.EndDevice
+	organizations map[string]string
+	labelMatches  []string
+	selectors     []LabelSelector
+}
+
+func newMemoryDeviceGroupStore() *memoryDeviceGroupStore {
+	return &memoryDeviceGroupStore{
+		groups:        map[string]*DeviceGroup{},
+		members:       map[string][]string{},
+		endDevices:    map[string]*iotv1.EndDevice{},
+		organizations: map[string]string{},
+	}
+}
+
+// putEndDevice stores an end device of an organization that can be added to groups.
+func (store *memoryDeviceGroupStore) putEndDevice(endDevice *iotv1.EndDevice, organizationId string) {
+	store.endDevices[endDevice.GetId()] = endDevice
+	store.organizations[endDevice.GetId()] = organizationId
+}
+
+func (store *memoryDeviceGroupStore) CreateDeviceGroup(_ context.Context, group *DeviceGroup) (*DeviceGroup, error) {
+	for _, existing := range store.groups {
+		if existing.OrganizationId == group.OrganizationId && existing.Name == group.Name {
+			return nil, ErrDeviceGroupExists
+		}
+	}
+	stored := *group
+	store.groups[group.Id] = &stored
+	return group, nil
+}
+
+func (store *memoryDeviceGroupStore) GetDeviceGroup(_ context.Context, groupId string) (*DeviceGroup, error) {
+	group, ok := store.groups[groupId]
+	if !ok {
+		return nil, ErrDeviceGroupNotFound
+	}
+	found := *group
+	return &found, nil
+}
+
+func (store *memoryDeviceGroupStore) ListDeviceGroups(_ context.Context, organizationId string) ([]*DeviceGroup, error) {
+	groups := []*DeviceGroup{}
+	for _, group := range store.groups {
+		if group.OrganizationId == organizationId {
+			groups = append(groups, group)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups, nil
+}
+
+func (store *memoryDeviceGroupStore) UpdateDeviceGroup(_ context.Context, group *DeviceGroup) (*DeviceGroup, error) {
+	stored := *group
+	store.groups[group.Id] = &stored
+	return group, nil
+}
+
+func (store *memoryDeviceGroupStore) DeleteDeviceGroup(_ context.Context, groupId string) error {
+	delete(store.groups, groupId)
+	delete(store.members, groupId)
+	return nil
+}
+
+func (store *memoryDeviceGroupStore) AddDeviceGroupMembers(_ context.Context, groupId string, organizationId string, endDeviceIds []string) (int, error) {
+	for _, id := range endDeviceIds {
+		if store.organizations[id] != organizationId {
+			return 0, ErrEndDeviceNotFound
+		}
+	}
+	added := 0
+	for _, id := range endDeviceIds {
+		if !slices.Contains(store.members[groupId], id) {
+			store.members[groupId] = append(store.members[groupId], id)
+			added++
+		}
+	}
+	return added, nil
+}
+
+func (store *memoryDeviceGroupStore) RemoveDeviceGroupMembers(_ context.Context, groupId string, endDeviceIds []string) (int, error) {
+	kept := []string{}
+	for _, id := range store.members[groupId] {
+		if !slices.Contains(endDeviceIds, id) {
+			kept = append(kept, id)
+		}
+	}
+	removed := len(store.members[groupId]) - len(kept)
+	store.members[groupId] = kept
+	return removed, nil
+}
+
+func (store *memoryDeviceGroupStore) ListDeviceGroupMembers(_ context.Context, groupId string) ([]*iotv1.EndDevice, error) {
+	endDevices := []*iotv1.EndDevice{}
+	for _, id := range store.members[groupId] {
+		endDevices = append(endDevices, store.endDevices[id])
+	}
+	return endDevices, nil
+}
+
+func (store *memoryDeviceGroupStore) ListEndDeviceGroups(_ context.Context, endDeviceId string) ([]*DeviceGroup, error) {
+	groups := []*DeviceGroup{}
+	for groupId, members := range store.members {
+		if slices.Contains(members, endDeviceId) {
+			groups = append(groups, store.groups[groupId])
+		}
+	}
+	return groups, nil
+}
+
+func (store *memoryDeviceGroupStore) ListEndDeviceIDsByGroups(_ context.Context, organizationId string, groupIds []string) ([]string, error) {
+	ids := []string{}
+	for _, groupId := range groupIds {
+		group, ok := store.groups[groupId]
+		if !ok || group.OrganizationId != organizationId {
+			return nil, ErrDeviceGroupNotFound
+		}
+		for _, id := range store.members[groupId] {
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func (store *memoryDeviceGroupStore) ListEndDeviceIDsByLabels(_ context.Context, _ string, selector LabelSelector) ([]string, error) {
+	store.selectors = append(store.selectors, selector)
+	return store.labelMatches, nil
+}
+
+// recordingEnvelopeQuerier records the device IDs each data query was asked for and returns no data.
+type recordingEnvelopeQuerier struct {
+	deviceIds [][]string
+}
+
+func (querier *recordingEnvelopeQuerier) QueryEndDeviceData(_ context.Context, _ string, deviceIDs []string, _, _ time.Time, _ string, _ []float64) ([]EndDeviceHistogram, error) {
+	querier.deviceIds = append(querier.deviceIds, deviceIDs)
+	return nil, nil
+}
+
+// memoryEndDeviceStatusStore records status transitions and presence in memory and applies transitions to the
+// end devices of its end device store.
+type memoryEndDeviceStatusStore struct {
+	endDevices  *memoryEndDeviceStore
+	transitions []*EndDeviceStatusTransition
+	seenAt      map[string]time.Time
+}
+
+func newMemoryEndDeviceStatusStore(endDevices *memoryEndDeviceStore) *memoryEndDeviceStatusStore {
+	return &memoryEndDeviceStatusStore{
+		endDevices: endDevices,
+		seenAt:     map[string]time.Time{},
+	}
+}
+
+func (store *memoryEndDeviceStatusStore) TransitionEndDeviceStatus(_ context.Context, transition *EndDeviceStatusTransition) error {
+	endDevice, ok := store.endDevices.devices[transition.EndDeviceId]
+	if !ok {
+		return ErrEndDeviceNotFound
+	}
+	if endDevice.GetStatus() != transition.From {
+		return ErrInvalidStatusTransition
+	}
+
+	endDevice.SetStatus(transition.To)
+	store.transitions = append(store.transitions, transition)
+	return nil
+}
+
+func (store *memoryEndDeviceStatusStore) ListEndDeviceStatusTransitions(_ context.Context, endDeviceId string) ([]*EndDeviceStatusTransition, error) {
+	var transitions []*EndDeviceStatusTransition
+	for _, transition := range slices.Backward(store.transitions) {
+		if transition.EndDeviceId == endDeviceId {
+			transitions = append(transitions, transition)
+		}
+	}
+	return transitions, nil
+}
+
+func (store *memoryEndDeviceStatusStore) TouchEndDevicePresence(_ context.Context, endDeviceId string, seenAt time.Time) error {
+	store.seenAt[endDeviceId] = seenAt
+	return nil
+}
+
+func (store *memoryEndDeviceStatusStore) ListSilentEndDevices(_ context.Context, status iotv1.EndDeviceStatus, seenBefore time.Time, limit int) ([]string, error) {
+	var endDeviceIds []string
+	for endDeviceId, seenAt := range store.seenAt {
+		if store.endDevices.devices[endDeviceId].GetStatus() == status && seenAt.Before(seenBefore) && len(endDeviceIds) < limit {
+			endDeviceIds = append(endDeviceIds, endDeviceId)
+		}
+	}
+	return endDeviceIds, nil
+}
+
+// recordingEnvelopeProducer records the processed envelopes it is asked to publish.
+type recordingEnvelopeProducer struct {
+	produced []*envelopev1.ProcessedEnvelope
+}
+
+func (producer *recordingEnvelopeProducer) ProduceProcessedEnvelope(_ context.Context, envelope *envelopev1.ProcessedEnvelope) error {
+	producer.produced = append(producer.produced, envelope)
+	return nil
+}
+
+// memoryEndDeviceTransferStore keeps transfers in memory and moves the devices of a memoryEndDeviceStore.
+type memoryEndDeviceTransferStore struct {
+	endDevices *memoryEndDeviceStore
+	transfers  map[string]*EndDeviceTransfer
+}
+
+func newMemoryEndDeviceTransferStore(endDevices *memoryEndDeviceStore) *memoryEndDeviceTransferStore {
+	return &memoryEndDeviceTransferStore{
+		endDevices: endDevices,
+		transfers:  map[string]*EndDeviceTransfer{},
+	}
+}
+
+func (store *memoryEndDeviceTransferStore) TransferEndDevice(_ context.Context, transfer *EndDeviceTransfer) error {
+	store.endDevices.organizations[transfer.EndDeviceId] = transfer.ToOrganizationId
+	stored := *transfer
+	store.transfers[transfer.Id] = &stored
+	return nil
+}
+
+func (store *memoryEndDeviceTransferStore) GetEndDeviceTransfer(_ context.Context, transferId string) (*EndDeviceTransfer, error) {
+	transfer, ok := store.transfers[transferId]
+	if !ok {
+		return nil, ErrEndDeviceTransferNotFound
+	}
+	stored := *transfer
+	return &stored, nil
+}
+
+func (store *memoryEndDeviceTransferStore) ListEndDeviceTransfers(_ context.Context, endDeviceId string) ([]*EndDeviceTransfer, error) {
+	var transfers []*EndDeviceTransfer
+	for _, transfer := range store.transfers {
+		if transfer.EndDeviceId == endDeviceId {
+			transfers = append(transfers, transfer)
+		}
+	}
+	return transfers, nil
+}
+
+func (store *memoryEndDeviceTransferStore) MarkEndDeviceTransferHistoryTransferred(_ context.Context, transferId string, transferredAt time.Time) error {
+	store.transfers[transferId].HistoryTransferredAt = transferredAt
+	return nil
+}
+
+// recordingHistoryTransferrer records the history operations it is asked for and fails while err is set.
+type recordingHistoryTransferrer struct {
+	calls []string
+	err   error
+}
+
+func (history *recordingHistoryTransferrer) CopyEndDeviceData(_ context.Context, endDeviceId string, from string, to string, _ time.Time) error {
+	history.calls = append(history.calls, "copy "+endDeviceId+" "+from+" "+to)
+	return history.err
+}
+
+func (history *recordingHistoryTransferrer) RetagEndDeviceData(_ context.Context, endDeviceId string, from string, to string, _ time.Time) error {
+	history.calls = append(history.calls, "retag "+endDeviceId+" "+from+" "+to)
+	return history.err
+}
+
+// fakeAuthorizer grants each user the permissions it lists as "<action> <organization-id>", e.g. "transfer org-1".
+// It implements the authorizer interfaces of the managers that check permissions themselves.
+type fakeAuthorizer map[string][]string
+
+func (authorizer fakeAuthorizer) allows(userId string, action string, organizationId string) bool {
+	return slices.Contains(authorizer[userId], action+" "+organizationId)
+}
+
+func (authorizer fakeAuthorizer) CanTransferEndDevice(_ context.Context, userId string, organizationId string) (bool, error) {
+	return authorizer.allows(userId, "transfer", organizationId), nil
+}
+
+// fakePayloadDecoderStore keeps payload decoders by end device and by LoRaWAN hardware type.
+type fakePayloadDecoderStore struct {
+	endDevices    map[string]*PayloadDecoder
+	hardwareTypes map[string]PayloadDecoder
+}
+
+func (store *fakePayloadDecoderStore) GetEndDevicePayloadDecoder(_ context.Context, endDeviceId string) (*PayloadDecoder, error) {
+	decoder, ok := store.endDevices[endDeviceId]
+	if !ok {
+		return nil, ErrEndDeviceNotFound
+	}
+	return decoder, nil
+}
+
+func (store *fakePayloadDecoderStore) GetLoRaWANHardwareTypePayloadDecoder(_ context.Context, hardwareTypeId string) (*PayloadDecoder, error) {
+	decoder, ok := store.hardwareTypes[hardwareTypeId]
+	if !ok {
+		return nil, nil
+	}
+	return &decoder, nil
+}
+
+func (store *fakePayloadDecoderStore) SetLoRaWANHardwareTypePayloadDecoder(_ context.Context, hardwareTypeId string, decoder PayloadDecoder) error {
+	store.hardwareTypes[hardwareTypeId] = decoder
+	return nil
+}
+
+func (store *fakePayloadDecoderStore) DeleteLoRaWANHardwareTypePayloadDecoder(_ context.Context, hardwareTypeId string) error {
+	delete(store.hardwareTypes, hardwareTypeId)
+	return nil
+}
+
+// scriptedPayloadDecoderRunner returns the output or error registered for a script.
+type scriptedPayloadDecoderRunner map[string]*PayloadDecoderOutput
+
+func (runner scriptedPayloadDecoderRunner) DecodeUplink(_ context.Context, script string, _ PayloadDecoderInput) (*PayloadDecoderOutput, error) {
+	output, ok := runner[script]
+	if !ok {
+		return nil, fmt.Errorf("%w: decodeUplink is not defined", ErrPayloadDecoderFailed)
+	}
+	return output, nil
+}
+
+// EncodeDownlink encodes the command of the data as text on port 5, reporting the errors registered for the script.
+func (runner scriptedPayloadDecoderRunner) EncodeDownlink(_ context.Context, script string, input PayloadEncoderInput) (*PayloadEncoderOutput, error) {
+	output, ok := runner[script]
+	if !ok {
+		return nil, fmt.Errorf("%w: encodeDownlink is not defined", ErrPayloadDecoderFailed)
+	}
+	return &PayloadEncoderOutput{Bytes: []byte(fmt.Sprint(input.Data["command"])), FPort: 5, Errors: output.Errors}, nil
+}
+
+// fakeLoRaWANPayloadDecoder decodes the uplinks of the end devices it holds a result or an error for.
+type fakeLoRaWANPayloadDecoder struct {
+	decoded map[string]map[string]any
+	failed  map[string]error
+}
+
+func (decoder fakeLoRaWANPayloadDecoder) DecodeEndDeviceUplink(_ context.Context, endDeviceId string, _ PayloadDecoderInput) (map[string]any, bool, error) {
+	if err, ok := decoder.failed[endDeviceId]; ok {
+		return nil, true, err
+	}
+	data, ok := decoder.decoded[endDeviceId]
+	return data, ok, nil
+}
+
+// memoryLoRaWANDownlinkStore keeps downlinks in memory in the order they were created.
+type memoryLoRaWANDownlinkStore struct {
+	downlinks []*LoRaWANDownlink
+}
+
+func (store *memoryLoRaWANDownlinkStore) CreateLoRaWANDownlink(_ context.Context, downlink *LoRaWANDownlink) error {
+	stored := *downlink
+	store.downlinks = append(store.downlinks, &stored)
+	return nil
+}
+
+func (store *memoryLoRaWANDownlinkStore) UpdateLoRaWANDownlinkStatus(_ context.Context, downlinkId string, status LoRaWANDownlinkStatus, reason string, updatedAt time.Time, from []LoRaWANDownlinkStatus) (bool, error) {
+	for _, downlink := range store.downlinks {
+		if downlink.Id == downlinkId && slices.Contains(from, downlink.Status) {
+			downlink.Status, downlink.Error, downlink.UpdatedAt = status, reason, updatedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (store *memoryLoRaWANDownlinkStore) ListEndDeviceLoRaWANDownlinks(_ context.Context, endDeviceId string, limit int) ([]*LoRaWANDownlink, error) {
+	var downlinks []*LoRaWANDownlink
+	for _, downlink := range slices.Backward(store.downlinks) {
+		if downlink.EndDeviceId == endDeviceId && len(downlinks) < limit {
+			downlinks = append(downlinks, downlink)
+		}
+	}
+	return downlinks, nil
+}
+
+// recordingDownlinkQueue records the downlinks pushed to it and fails while err is set.
+type recordingDownlinkQueue struct {
+	pushed []*LoRaWANDownlink
+	err    error
+}
+
+func (queue *recordingDownlinkQueue) PushLoRaWANDownlink(_ context.Context, _ *iotv1.EndDevice, downlink *LoRaWANDownlink) error {
+	if queue.err != nil {
+		return queue.err
+	}
+	queue.pushed = append(queue.pushed, downlink)
+	return nil
+}
+
+// fakeLoRaWANPayloadEncoder encodes the data of device-1 as the bytes of its command on port 10. Other devices
+// have no encoder.
+type fakeLoRaWANPayloadEncoder struct{}
+
+func (fakeLoRaWANPayloadEncoder) EncodeEndDeviceDownlink(_ context.Context, endDeviceId string, input PayloadEncoderInput) (*PayloadEncoderOutput, bool, error) {
+	if endDeviceId != "device-1" {
+		return nil, false, nil
+	}
+	command, ok := input.Data["command"].(string)
+	if !ok {
+		return nil, true, ErrPayloadDecoderFailed
+	}
+	return &PayloadEncoderOutput{Bytes: []byte(command), FPort: 10}, true, nil
+}
+
+// memoryGatewayStore keeps gateways in memory, running the sync of a write before applying it like the
+// Postgres store does inside its transaction. commitErr makes writes fail after their sync ran.
+type memoryGatewayStore struct {
+	gateways  map[string]*Gateway
+	commitErr error
+}
+
+func newMemoryGatewayStore(gateways ...*Gateway) *memoryGatewayStore {
+	store := &memoryGatewayStore{gateways: map[string]*Gateway{}}
+	for _, gateway := range gateways {
+		store.gateways[gateway.Id] = gateway
+	}
+	return store
+}
+
+func (store *memoryGatewayStore) write(ctx context.Context, sync GatewaySync) error {
+	err := sync(ctx)
+	if err != nil {
+		return err
+	}
+	return store.commitErr
+}
+
+func (store *memoryGatewayStore) CreateGateway(ctx context.Context, gateway *Gateway, sync GatewaySync) (*Gateway, error) {
+	err := store.write(ctx, sync)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := *gateway
+	store.gateways[gateway.Id] = &stored
+	return &stored, nil
+}
+
+func (store *memoryGatewayStore) GetGateway(ctx context.Context, gatewayId string) (*Gateway, error) {
+	gateway, ok := store.gateways[gatewayId]
+	if !ok {
+		return nil, ErrGatewayNotFound
+	}
+
+	stored := *gateway
+	return &stored, nil
+}
+
+func (store *memoryGatewayStore) ListGateways(ctx context.Context, organizationId string) ([]*Gateway, error) {
+	gateways := []*Gateway{}
+	for _, gateway := range store.gateways {
+		if gateway.OrganizationId == organizationId {
+			gateways = append(gateways, gateway)
+		}
+	}
+	return gateways, nil
+}
+
+func (store *memoryGatewayStore) ListAllGateways(ctx context.Context) ([]*Gateway, error) {
+	gateways := []*Gateway{}
+	for _, gateway := range store.gateways {
+		stored := *gateway
+		gateways = append(gateways, &stored)
+	}
+	return gateways, nil
+}
+
+func (store *memoryGatewayStore) UpdateGateway(ctx context.Context, gateway *Gateway, sync GatewaySync) (*Gateway, error) {
+	err := store.write(ctx, sync)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := *gateway
+	store.gateways[gateway.Id] = &stored
+	return &stored, nil
+}
+
+func (store *memoryGatewayStore) DeleteGateway(ctx context.Context, gatewayId string, sync GatewaySync) error {
+	err := store.write(ctx, sync)
+	if err != nil {
+		return err
+	}
+
+	delete(store.gateways, gatewayId)
+	return nil
+}
+
+func (store *memoryGatewayStore) UpdateGatewayStatus(ctx context.Context, gatewayId string, status GatewayStatus, lastSeenAt time.Time, checkedAt time.Time) error {
+	gateway := store.gateways[gatewayId]
+	gateway.Status = status
+	gateway.LastSeenAt = lastSeenAt
+	gateway.StatusCheckedAt = checkedAt
+	return nil
+}
+
+func (store *memoryGatewayStore) UpdateGatewayReportedLocation(ctx context.Context, gatewayId string, location GatewayLocation) error {
+	location.Source = GatewayLocationStatus
+	store.gateways[gatewayId].Location = &location
+	return nil
+}
+
+// recordingGatewayRegister records the gateways registered with it and answers connection lookups from
+// connections, failing for gateways missing from it.
+type recordingGatewayRegister struct {
+	registered  map[string]*Gateway
+	connections map[string]*GatewayConnection
+	err         error
+}
+
+func newRecordingGatewayRegister() *recordingGatewayRegister {
+	return &recordingGatewayRegister{
+		registered:  map[string]*Gateway{},
+		connections: map[string]*GatewayConnection{},
+	}
+}
+
+func (register *recordingGatewayRegister) RegisterGateway(ctx context.Context, gateway *Gateway) error {
+	if register.err != nil {
+		return register.err
+	}
+	registered := *gateway
+	register.registered[gateway.Id] = &registered
+	return nil
+}
+
+func (register *recordingGatewayRegister) UpdateGateway(ctx context.Context, gateway *Gateway) error {
+	return register.RegisterGateway(ctx, gateway)
+}
+
+func (register *recordingGatewayRegister) DeleteGateway(ctx context.Context, gateway *Gateway) error {
+	if register.err != nil {
+		return register.err
+	}
+	delete(register.registered, gateway.Id)
+	return nil
+}
+
+func (register *recordingGatewayRegister) GetGatewayConnection(ctx context.Context, gateway *Gateway) (*GatewayConnection, error) {
+	connection, ok := register.connections[gateway.Id]
+	if !ok {
+		return nil, errors.New("gateway server unavailable")
+	}
+	return connection, nil
+}
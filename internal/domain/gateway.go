@@ -0,0 +1,402 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ponix-dev/ponix/internal/runner"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+var (
+	// ErrGatewayNotFound is returned when a gateway does not exist or is not visible to the requesting organization.
+	ErrGatewayNotFound = errors.New("gateway not found")
+	// ErrGatewayExists is returned when a gateway with the same EUI is already registered.
+	ErrGatewayExists = errors.New("gateway already exists")
+	// ErrInvalidGateway is returned when a gateway fails validation.
+	ErrInvalidGateway = errors.New("invalid gateway")
+)
+
+// MaxGatewayNameLength is the maximum length of a gateway name.
+const MaxGatewayNameLength = 255
+
+// GatewayStatus is the connection state of a gateway as last seen by the network's Gateway Server.
+type GatewayStatus string
+
+const (
+	// GatewayStatusUnknown is the status of a gateway whose connection has not been checked yet.
+	GatewayStatusUnknown GatewayStatus = "unknown"
+	// GatewayStatusOnline is the status of a gateway connected to the Gateway Server.
+	GatewayStatusOnline GatewayStatus = "online"
+	// GatewayStatusOffline is the status of a gateway not connected to the Gateway Server.
+	GatewayStatusOffline GatewayStatus = "offline"
+)
+
+// GatewayLocationSource tells where the location of a gateway comes from.
+type GatewayLocationSource string
+
+const (
+	// GatewayLocationRegistry marks a location set when the gateway was created or updated.
+	GatewayLocationRegistry GatewayLocationSource = "registry"
+	// GatewayLocationStatus marks a location reported by the GPS of the gateway in its status messages.
+	GatewayLocationStatus GatewayLocationSource = "status"
+)
+
+// GatewayLocation is where the antenna of a gateway is placed.
+type GatewayLocation struct {
+	Latitude  float64
+	Longitude float64
+	// Altitude is in meters above sea level.
+	Altitude int32
+	Source   GatewayLocationSource
+}
+
+// Gateway is a LoRaWAN gateway operated by an organization. Its ID is also its gateway ID in The Things Stack.
+type Gateway struct {
+	Id             string
+	OrganizationId string
+	Name           string
+	Description    string
+	// GatewayEui is the EUI the gateway's packet forwarder identifies itself with, as 16 uppercase hex digits.
+	GatewayEui    string
+	FrequencyPlan string
+	// Location is nil until set or reported by the gateway.
+	Location *GatewayLocation
+	Status   GatewayStatus
+	// LastSeenAt is the last time the gateway connected or sent a status message or uplink; zero if it never did.
+	LastSeenAt      time.Time
+	StatusCheckedAt time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// Validate checks that the gateway has a usable name, EUI, frequency plan and location.
+func (gateway *Gateway) Validate() error {
+	name := strings.TrimSpace(gateway.Name)
+	if name == "" {
+		return stacktrace.NewStackTraceErrorf("%w: name is required", ErrInvalidGateway)
+	}
+
+	if len(name) > MaxGatewayNameLength {
+		return stacktrace.NewStackTraceErrorf("%w: name is longer than %d characters", ErrInvalidGateway, MaxGatewayNameLength)
+	}
+
+	if !isHexOfLength(gateway.GatewayEui, euiHexLength) {
+		return stacktrace.NewStackTraceErrorf("%w: gateway EUI %q", ErrInvalidGateway, gateway.GatewayEui)
+	}
+
+	if gateway.FrequencyPlan == "" {
+		return stacktrace.NewStackTraceErrorf("%w: frequency plan is required", ErrInvalidGateway)
+	}
+
+	if gateway.Location != nil {
+		if gateway.Location.Latitude < -90 || gateway.Location.Latitude > 90 {
+			return stacktrace.NewStackTraceErrorf("%w: latitude %v is not between -90 and 90", ErrInvalidGateway, gateway.Location.Latitude)
+		}
+
+		if gateway.Location.Longitude < -180 || gateway.Location.Longitude > 180 {
+			return stacktrace.NewStackTraceErrorf("%w: longitude %v is not between -180 and 180", ErrInvalidGateway, gateway.Location.Longitude)
+		}
+	}
+
+	return nil
+}
+
+// GatewayConnection is what the Gateway Server knows about the connection of a gateway.
+type GatewayConnection struct {
+	Connected bool
+	// LastSeenAt is the last time the gateway connected or sent a status message or uplink; zero if unknown.
+	LastSeenAt time.Time
+	// Location is the location in the last status message of the gateway, nil if it sent none.
+	Location *GatewayLocation
+}
+
+// GatewaySync propagates a pending gateway change to the network's gateway registry.
+// Stores run it inside their transaction before committing so both sides change together.
+type GatewaySync func(ctx context.Context) error
+
+// GatewayStorer defines the persistence operations for gateways.
+type GatewayStorer interface {
+	CreateGateway(ctx context.Context, gateway *Gateway, sync GatewaySync) (*Gateway, error)
+	GetGateway(ctx context.Context, gatewayId string) (*Gateway, error)
+	ListGateways(ctx context.Context, organizationId string) ([]*Gateway, error)
+	ListAllGateways(ctx context.Context) ([]*Gateway, error)
+	UpdateGateway(ctx context.Context, gateway *Gateway, sync GatewaySync) (*Gateway, error)
+	DeleteGateway(ctx context.Context, gatewayId string, sync GatewaySync) error
+	UpdateGatewayStatus(ctx context.Context, gatewayId string, status GatewayStatus, lastSeenAt time.Time, checkedAt time.Time) error
+	UpdateGatewayReportedLocation(ctx context.Context, gatewayId string, location GatewayLocation) error
+}
+
+// GatewayRegister defines the operations for registering gateways with the network and reading their connection.
+type GatewayRegister interface {
+	RegisterGateway(ctx context.Context, gateway *Gateway) error
+	UpdateGateway(ctx context.Context, gateway *Gateway) error
+	DeleteGateway(ctx context.Context, gateway *Gateway) error
+	GetGatewayConnection(ctx context.Context, gateway *Gateway) (*GatewayConnection, error)
+}
+
+// GatewayManager orchestrates gateway business logic including registration with the network.
+type GatewayManager struct {
+	gatewayStore    GatewayStorer
+	gatewayRegister GatewayRegister
+	stringId        StringId
+}
+
+// NewGatewayManager creates a new instance of GatewayManager with the provided dependencies.
+func NewGatewayManager(gatewayStore GatewayStorer, gatewayRegister GatewayRegister, stringId StringId) *GatewayManager {
+	return &GatewayManager{
+		gatewayStore:    gatewayStore,
+		gatewayRegister: gatewayRegister,
+		stringId:        stringId,
+	}
+}
+
+// CreateGateway creates a new gateway in an organization and registers it with TTN before the database write is
+// committed. If the commit then fails, the gateway is removed from TTN again. Gateway EUIs are unique.
+func (mgr *GatewayManager) CreateGateway(ctx context.Context, organizationId string, gateway *Gateway) (*Gateway, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "CreateGateway")
+	defer span.End()
+
+	created := *gateway
+	created.Id = mgr.stringId()
+	created.OrganizationId = organizationId
+	created.Name = strings.TrimSpace(created.Name)
+	created.GatewayEui = normalizeHex(created.GatewayEui)
+	created.Status = GatewayStatusUnknown
+	created.LastSeenAt = time.Time{}
+	created.StatusCheckedAt = time.Time{}
+	if created.Location != nil {
+		location := *created.Location
+		location.Source = GatewayLocationRegistry
+		created.Location = &location
+	}
+
+	err := created.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	synced := false
+	stored, err := mgr.gatewayStore.CreateGateway(ctx, &created, func(ctx context.Context) error {
+		err := mgr.gatewayRegister.RegisterGateway(ctx, &created)
+		if err != nil {
+			return stacktrace.NewStackTraceError(err)
+		}
+		synced = true
+		return nil
+	})
+	if err != nil {
+		if synced {
+			// The registry already knows the gateway; remove it so nothing is left behind
+			restoreErr := mgr.gatewayRegister.DeleteGateway(ctx, &created)
+			if restoreErr != nil {
+				return nil, stacktrace.NewStackTraceError(errors.Join(err, restoreErr))
+			}
+		}
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+// GetGateway retrieves a gateway. Gateways that belong to a different organization are reported as
+// ErrGatewayNotFound so their existence is not leaked.
+func (mgr *GatewayManager) GetGateway(ctx context.Context, gatewayId string, organizationId string) (*Gateway, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetGateway")
+	defer span.End()
+
+	gateway, err := mgr.gatewayStore.GetGateway(ctx, gatewayId)
+	if err != nil {
+		return nil, err
+	}
+
+	if gateway.OrganizationId != organizationId {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrGatewayNotFound, gatewayId)
+	}
+
+	return gateway, nil
+}
+
+// ListGateways retrieves every gateway in an organization ordered by name.
+func (mgr *GatewayManager) ListGateways(ctx context.Context, organizationId string) ([]*Gateway, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListGateways")
+	defer span.End()
+
+	return mgr.gatewayStore.ListGateways(ctx, organizationId)
+}
+
+// UpdateGateway renames a gateway, replaces its description and changes its frequency plan or location. Empty
+// names and frequency plans and a nil location leave the current ones unchanged; the EUI of a gateway cannot
+// change. TTN is updated before the database change is committed; if the commit then fails, the TTN registry is
+// restored to the previous state.
+func (mgr *GatewayManager) UpdateGateway(ctx context.Context, update *Gateway, organizationId string) (*Gateway, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "UpdateGateway")
+	defer span.End()
+
+	current, err := mgr.GetGateway(ctx, update.Id, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.GatewayEui != "" && normalizeHex(update.GatewayEui) != current.GatewayEui {
+		return nil, stacktrace.NewStackTraceErrorf("%w: EUI of %s cannot change", ErrInvalidGateway, update.Id)
+	}
+
+	updated := *current
+	if name := strings.TrimSpace(update.Name); name != "" {
+		updated.Name = name
+	}
+	updated.Description = update.Description
+	if update.FrequencyPlan != "" {
+		updated.FrequencyPlan = update.FrequencyPlan
+	}
+	if update.Location != nil {
+		location := *update.Location
+		location.Source = GatewayLocationRegistry
+		updated.Location = &location
+	}
+
+	err = updated.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	synced := false
+	stored, err := mgr.gatewayStore.UpdateGateway(ctx, &updated, func(ctx context.Context) error {
+		err := mgr.gatewayRegister.UpdateGateway(ctx, &updated)
+		if err != nil {
+			return stacktrace.NewStackTraceError(err)
+		}
+		synced = true
+		return nil
+	})
+	if err != nil {
+		if synced {
+			// The registry already has the new state; put it back so both sides agree again
+			restoreErr := mgr.gatewayRegister.UpdateGateway(ctx, current)
+			if restoreErr != nil {
+				return nil, stacktrace.NewStackTraceError(errors.Join(err, restoreErr))
+			}
+		}
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+// DeleteGateway deletes a gateway from the organization. It is removed from TTN before the database change is
+// committed; if the commit then fails, the gateway is registered with TTN again.
+func (mgr *GatewayManager) DeleteGateway(ctx context.Context, gatewayId string, organizationId string) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "DeleteGateway")
+	defer span.End()
+
+	current, err := mgr.GetGateway(ctx, gatewayId, organizationId)
+	if err != nil {
+		return err
+	}
+
+	synced := false
+	err = mgr.gatewayStore.DeleteGateway(ctx, gatewayId, func(ctx context.Context) error {
+		err := mgr.gatewayRegister.DeleteGateway(ctx, current)
+		if err != nil {
+			return stacktrace.NewStackTraceError(err)
+		}
+		synced = true
+		return nil
+	})
+	if err != nil {
+		if synced {
+			// The registry no longer knows the gateway; register it again so both sides agree
+			restoreErr := mgr.gatewayRegister.RegisterGateway(ctx, current)
+			if restoreErr != nil {
+				return stacktrace.NewStackTraceError(errors.Join(err, restoreErr))
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// RefreshGatewayStatuses asks TTN for the connection of every gateway and records whether it is online, when it
+// was last seen and, unless a location was set for it, the location its GPS reports. It returns how many gateways
+// changed status. A gateway whose connection cannot be read keeps its status and does not stop the others.
+func (mgr *GatewayManager) RefreshGatewayStatuses(ctx context.Context) (int, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "RefreshGatewayStatuses")
+	defer span.End()
+
+	gateways, err := mgr.gatewayStore.ListAllGateways(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	var errs []error
+	for _, gateway := range gateways {
+		connection, err := mgr.gatewayRegister.GetGatewayConnection(ctx, gateway)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		status := GatewayStatusOffline
+		if connection.Connected {
+			status = GatewayStatusOnline
+		}
+
+		lastSeenAt := gateway.LastSeenAt
+		if connection.LastSeenAt.After(lastSeenAt) {
+			lastSeenAt = connection.LastSeenAt
+		}
+
+		err = mgr.gatewayStore.UpdateGatewayStatus(ctx, gateway.Id, status, lastSeenAt, time.Now().UTC())
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if status != gateway.Status {
+			changed++
+		}
+
+		// Locations set for the gateway win over the one its GPS reports
+		if connection.Location != nil && (gateway.Location == nil || gateway.Location.Source != GatewayLocationRegistry) {
+			err = mgr.gatewayStore.UpdateGatewayReportedLocation(ctx, gateway.Id, *connection.Location)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return changed, errors.Join(errs...)
+}
+
+// GatewayStatusRunner returns a runner function that periodically refreshes the status of all gateways.
+func GatewayStatusRunner(mgr *GatewayManager, interval time.Duration) runner.RunnerFunc {
+	return func(ctx context.Context) func() error {
+		return func() error {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+					changed, err := mgr.RefreshGatewayStatuses(ctx)
+					if err != nil {
+						slog.Error("failed to refresh gateway statuses", stacktrace.ErrorAttribute(err))
+					}
+
+					if changed > 0 {
+						slog.Info("gateways changed status", slog.Int("count", changed))
+					}
+				}
+			}
+		}
+	}
+}
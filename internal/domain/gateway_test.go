@@ -0,0 +1,202 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testGateway(id string, organizationId string) *Gateway {
+	return &Gateway{
+		Id:             id,
+		OrganizationId: organizationId,
+		Name:           "rooftop",
+		GatewayEui:     "58A0CBFFFE800001",
+		FrequencyPlan:  "EU_863_870_TTN",
+		Status:         GatewayStatusUnknown,
+	}
+}
+
+func TestGateway_Validate(t *testing.T) {
+	assert.NoError(t, testGateway("gateway-1", "org-1").Validate())
+
+	for name, mutate := range map[string]func(*Gateway){
+		"missing name":           func(gateway *Gateway) { gateway.Name = " " },
+		"long name":              func(gateway *Gateway) { gateway.Name = strings.Repeat("a", MaxGatewayNameLength+1) },
+		"short EUI":              func(gateway *Gateway) { gateway.GatewayEui = "58A0CBFFFE80" },
+		"non hex EUI":            func(gateway *Gateway) { gateway.GatewayEui = "58A0CBFFFE80000G" },
+		"missing frequency plan": func(gateway *Gateway) { gateway.FrequencyPlan = "" },
+		"latitude out of range":  func(gateway *Gateway) { gateway.Location = &GatewayLocation{Latitude: 91} },
+		"longitude out of range": func(gateway *Gateway) { gateway.Location = &GatewayLocation{Longitude: -181} },
+	} {
+		gateway := testGateway("gateway-1", "org-1")
+		mutate(gateway)
+		assert.ErrorIs(t, gateway.Validate(), ErrInvalidGateway, name)
+	}
+}
+
+func TestGatewayManager_CreateGateway(t *testing.T) {
+	t.Run("registers the gateway with a normalized EUI", func(t *testing.T) {
+		assert := assert.New(t)
+		store := newMemoryGatewayStore()
+		register := newRecordingGatewayRegister()
+		mgr := NewGatewayManager(store, register, func() string { return "gateway-1" })
+
+		gateway, err := mgr.CreateGateway(context.Background(), "org-1", &Gateway{
+			Name:          " rooftop ",
+			GatewayEui:    "58:a0:cb:ff:fe:80:00:01",
+			FrequencyPlan: "EU_863_870_TTN",
+			Location:      &GatewayLocation{Latitude: 52.37, Longitude: 4.89, Altitude: 30},
+			Status:        GatewayStatusOnline,
+		})
+
+		if assert.NoError(err) {
+			assert.Equal("gateway-1", gateway.Id)
+			assert.Equal("org-1", gateway.OrganizationId)
+			assert.Equal("rooftop", gateway.Name)
+			assert.Equal("58A0CBFFFE800001", gateway.GatewayEui)
+			assert.Equal(GatewayStatusUnknown, gateway.Status)
+			assert.Equal(GatewayLocationRegistry, gateway.Location.Source)
+			assert.Contains(register.registered, "gateway-1")
+		}
+	})
+
+	t.Run("removes the registration when the commit fails", func(t *testing.T) {
+		assert := assert.New(t)
+		store := newMemoryGatewayStore()
+		store.commitErr = ErrGatewayExists
+		register := newRecordingGatewayRegister()
+		mgr := NewGatewayManager(store, register, func() string { return "gateway-1" })
+
+		_, err := mgr.CreateGateway(context.Background(), "org-1", testGateway("", ""))
+
+		assert.ErrorIs(err, ErrGatewayExists)
+		assert.Empty(register.registered)
+	})
+
+	t.Run("stores nothing when registration fails", func(t *testing.T) {
+		assert := assert.New(t)
+		store := newMemoryGatewayStore()
+		register := newRecordingGatewayRegister()
+		register.err = errors.New("registry unavailable")
+		mgr := NewGatewayManager(store, register, func() string { return "gateway-1" })
+
+		_, err := mgr.CreateGateway(context.Background(), "org-1", testGateway("", ""))
+
+		assert.Error(err)
+		assert.Empty(store.gateways)
+	})
+}
+
+func TestGatewayManager_UpdateGateway(t *testing.T) {
+	t.Run("keeps unset fields", func(t *testing.T) {
+		assert := assert.New(t)
+		store := newMemoryGatewayStore(testGateway("gateway-1", "org-1"))
+		register := newRecordingGatewayRegister()
+		mgr := NewGatewayManager(store, register, nil)
+
+		gateway, err := mgr.UpdateGateway(context.Background(), &Gateway{
+			Id:          "gateway-1",
+			Description: "on the warehouse roof",
+			Location:    &GatewayLocation{Latitude: 52.37, Longitude: 4.89},
+		}, "org-1")
+
+		if assert.NoError(err) {
+			assert.Equal("rooftop", gateway.Name)
+			assert.Equal("on the warehouse roof", gateway.Description)
+			assert.Equal("EU_863_870_TTN", gateway.FrequencyPlan)
+			assert.Equal(GatewayLocationRegistry, gateway.Location.Source)
+			assert.Equal("on the warehouse roof", register.registered["gateway-1"].Description)
+		}
+	})
+
+	t.Run("restores the registry when the commit fails", func(t *testing.T) {
+		assert := assert.New(t)
+		store := newMemoryGatewayStore(testGateway("gateway-1", "org-1"))
+		store.commitErr = errors.New("commit failed")
+		register := newRecordingGatewayRegister()
+		mgr := NewGatewayManager(store, register, nil)
+
+		_, err := mgr.UpdateGateway(context.Background(), &Gateway{Id: "gateway-1", Name: "basement"}, "org-1")
+
+		assert.Error(err)
+		assert.Equal("rooftop", register.registered["gateway-1"].Name)
+	})
+
+	t.Run("rejects EUI changes and gateways of other organizations", func(t *testing.T) {
+		store := newMemoryGatewayStore(testGateway("gateway-1", "org-1"))
+		mgr := NewGatewayManager(store, newRecordingGatewayRegister(), nil)
+
+		_, err := mgr.UpdateGateway(context.Background(), &Gateway{Id: "gateway-1", GatewayEui: "58A0CBFFFE800002"}, "org-1")
+		assert.ErrorIs(t, err, ErrInvalidGateway)
+
+		_, err = mgr.UpdateGateway(context.Background(), &Gateway{Id: "gateway-1", Name: "basement"}, "org-2")
+		assert.ErrorIs(t, err, ErrGatewayNotFound)
+	})
+}
+
+func TestGatewayManager_DeleteGateway(t *testing.T) {
+	t.Run("removes the gateway from the registry", func(t *testing.T) {
+		assert := assert.New(t)
+		store := newMemoryGatewayStore(testGateway("gateway-1", "org-1"))
+		register := newRecordingGatewayRegister()
+		register.registered["gateway-1"] = testGateway("gateway-1", "org-1")
+		mgr := NewGatewayManager(store, register, nil)
+
+		assert.NoError(mgr.DeleteGateway(context.Background(), "gateway-1", "org-1"))
+		assert.Empty(store.gateways)
+		assert.Empty(register.registered)
+	})
+
+	t.Run("registers the gateway again when the commit fails", func(t *testing.T) {
+		assert := assert.New(t)
+		store := newMemoryGatewayStore(testGateway("gateway-1", "org-1"))
+		store.commitErr = errors.New("commit failed")
+		register := newRecordingGatewayRegister()
+		mgr := NewGatewayManager(store, register, nil)
+
+		assert.Error(mgr.DeleteGateway(context.Background(), "gateway-1", "org-1"))
+		assert.Contains(register.registered, "gateway-1")
+	})
+}
+
+func TestGatewayManager_RefreshGatewayStatuses(t *testing.T) {
+	assert := assert.New(t)
+
+	lastSeen := time.Date(2025, 11, 16, 8, 0, 0, 0, time.UTC)
+	registryLocated := testGateway("gateway-2", "org-1")
+	registryLocated.Location = &GatewayLocation{Latitude: 1, Longitude: 2, Source: GatewayLocationRegistry}
+	store := newMemoryGatewayStore(testGateway("gateway-1", "org-1"), registryLocated, testGateway("gateway-3", "org-1"))
+
+	register := newRecordingGatewayRegister()
+	register.connections["gateway-1"] = &GatewayConnection{
+		Connected:  true,
+		LastSeenAt: lastSeen,
+		Location:   &GatewayLocation{Latitude: 52.37, Longitude: 4.89, Altitude: 30},
+	}
+	register.connections["gateway-2"] = &GatewayConnection{
+		Location: &GatewayLocation{Latitude: 52.37, Longitude: 4.89},
+	}
+	mgr := NewGatewayManager(store, register, nil)
+
+	changed, err := mgr.RefreshGatewayStatuses(context.Background())
+
+	// The connection of gateway-3 cannot be read, which is reported without stopping the others
+	assert.Error(err)
+	assert.Equal(2, changed)
+
+	assert.Equal(GatewayStatusOnline, store.gateways["gateway-1"].Status)
+	assert.Equal(lastSeen, store.gateways["gateway-1"].LastSeenAt)
+	assert.Equal(&GatewayLocation{Latitude: 52.37, Longitude: 4.89, Altitude: 30, Source: GatewayLocationStatus}, store.gateways["gateway-1"].Location)
+
+	assert.Equal(GatewayStatusOffline, store.gateways["gateway-2"].Status)
+	assert.Equal(GatewayLocationRegistry, store.gateways["gateway-2"].Location.Source)
+	assert.Equal(1.0, store.gateways["gateway-2"].Location.Latitude)
+
+	assert.Equal(GatewayStatusUnknown, store.gateways["gateway-3"].Status)
+	assert.True(store.gateways["gateway-3"].StatusCheckedAt.IsZero())
+}
@@ -0,0 +1,372 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrInvalidLabel is returned when a label key or value is malformed.
+	ErrInvalidLabel = errors.New("invalid label")
+	// ErrInvalidLabelSelector is returned when a label selector cannot be parsed.
+	ErrInvalidLabelSelector = errors.New("invalid label selector")
+)
+
+const (
+	// MaxLabelNameLength is the maximum length of a label name and of a label value.
+	MaxLabelNameLength = 63
+	// MaxLabelPrefixLength is the maximum length of the optional DNS prefix of a label key.
+	MaxLabelPrefixLength = 253
+)
+
+var (
+	labelNamePattern   = regexp.MustCompile(`^[A-Za-z0-9]([-._A-Za-z0-9]*[A-Za-z0-9])?$`)
+	labelPrefixPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// Labels are key/value pairs attached to end devices for grouping and selection.
+// Keys follow the Kubernetes format: an optional DNS prefix and slash followed by a name.
+type Labels map[string]string
+
+// Validate checks every key and value against the label format rules.
+func (labels Labels) Validate() error {
+	for key, value := range labels {
+		err := ValidateLabelKey(key)
+		if err != nil {
+			return err
+		}
+
+		err = ValidateLabelValue(value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// String formats labels as a comma separated list of key=value pairs sorted by key.
+func (labels Labels) String() string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + labels[key]
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+// ValidateLabelKey checks that a label key is an optional DNS prefix and slash followed by a valid name.
+func ValidateLabelKey(key string) error {
+	name := key
+	if prefix, rest, found := strings.Cut(key, "/"); found {
+		if prefix == "" || len(prefix) > MaxLabelPrefixLength || !labelPrefixPattern.MatchString(prefix) {
+			return fmt.Errorf("%w: key %q has an invalid prefix", ErrInvalidLabel, key)
+		}
+		name = rest
+	}
+
+	if name == "" || len(name) > MaxLabelNameLength || !labelNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: key %q has an invalid name", ErrInvalidLabel, key)
+	}
+
+	return nil
+}
+
+// ValidateLabelValue checks that a label value is empty or a valid label name.
+func ValidateLabelValue(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	if len(value) > MaxLabelNameLength || !labelNamePattern.MatchString(value) {
+		return fmt.Errorf("%w: value %q is invalid", ErrInvalidLabel, value)
+	}
+
+	return nil
+}
+
+// ParseLabels parses a comma separated list of key=value pairs such as "building=north,floor=2".
+func ParseLabels(input string) (Labels, error) {
+	labels := Labels{}
+	if strings.TrimSpace(input) == "" {
+		return labels, nil
+	}
+
+	for _, pair := range strings.Split(input, ",") {
+		key, value, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("%w: %q is not a key=value pair", ErrInvalidLabel, pair)
+		}
+
+		key = strings.TrimSpace(key)
+		if _, exists := labels[key]; exists {
+			return nil, fmt.Errorf("%w: duplicate key %q", ErrInvalidLabel, key)
+		}
+
+		labels[key] = strings.TrimSpace(value)
+	}
+
+	err := labels.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return labels, nil
+}
+
+// LabelSelector selects end devices by their labels. All requirements must hold for a device to match.
+// Selectors use the Kubernetes syntax, for example "building=north,floor in (2,3),!decommissioned".
+type LabelSelector struct {
+	// Equals requires the key to be present with exactly the given value.
+	Equals map[string]string
+	// In requires the key to be present with one of the given values.
+	In map[string][]string
+	// NotIn requires the key to be absent or to have none of the given values.
+	NotIn map[string][]string
+	// Exists requires the keys to be present.
+	Exists []string
+	// NotExists requires the keys to be absent.
+	NotExists []string
+}
+
+// Empty reports whether the selector has no requirements and therefore matches everything.
+func (selector LabelSelector) Empty() bool {
+	return len(selector.Equals) == 0 &&
+		len(selector.In) == 0 &&
+		len(selector.NotIn) == 0 &&
+		len(selector.Exists) == 0 &&
+		len(selector.NotExists) == 0
+}
+
+// Matches reports whether the given labels satisfy every requirement of the selector.
+func (selector LabelSelector) Matches(labels Labels) bool {
+	for key, value := range selector.Equals {
+		if actual, ok := labels[key]; !ok || actual != value {
+			return false
+		}
+	}
+
+	for key, values := range selector.In {
+		if actual, ok := labels[key]; !ok || !slices.Contains(values, actual) {
+			return false
+		}
+	}
+
+	for key, values := range selector.NotIn {
+		if actual, ok := labels[key]; ok && slices.Contains(values, actual) {
+			return false
+		}
+	}
+
+	for _, key := range selector.Exists {
+		if _, ok := labels[key]; !ok {
+			return false
+		}
+	}
+
+	for _, key := range selector.NotExists {
+		if _, ok := labels[key]; ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParseLabelSelector parses a Kubernetes-style label selector.
+// Supported requirements are key=value, key==value, key!=value, key in (a,b), key notin (a,b), key and !key.
+func ParseLabelSelector(input string) (LabelSelector, error) {
+	selector := LabelSelector{}
+
+	requirements, err := splitSelectorRequirements(input)
+	if err != nil {
+		return selector, err
+	}
+
+	for _, requirement := range requirements {
+		err = selector.addRequirement(requirement)
+		if err != nil {
+			return LabelSelector{}, err
+		}
+	}
+
+	return selector, nil
+}
+
+// splitSelectorRequirements splits a selector on the commas that are not inside a value set.
+func splitSelectorRequirements(input string) ([]string, error) {
+	requirements := []string{}
+	depth := 0
+	start := 0
+
+	for i, r := range input {
+		switch r {
+		case '(':
+			depth++
+			if depth > 1 {
+				return nil, fmt.Errorf("%w: nested parentheses", ErrInvalidLabelSelector)
+			}
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("%w: unbalanced parentheses", ErrInvalidLabelSelector)
+			}
+		case ',':
+			if depth == 0 {
+				requirements = append(requirements, input[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	if depth != 0 {
+		return nil, fmt.Errorf("%w: unbalanced parentheses", ErrInvalidLabelSelector)
+	}
+
+	requirements = append(requirements, input[start:])
+	if len(requirements) == 1 && strings.TrimSpace(requirements[0]) == "" {
+		return nil, nil
+	}
+
+	return requirements, nil
+}
+
+// addRequirement parses a single selector requirement and merges it into the selector.
+func (selector *LabelSelector) addRequirement(requirement string) error {
+	requirement = strings.TrimSpace(requirement)
+	if requirement == "" {
+		return fmt.Errorf("%w: empty requirement", ErrInvalidLabelSelector)
+	}
+
+	if key, values, ok, err := parseSetRequirement(requirement, "notin"); ok || err != nil {
+		if err != nil {
+			return err
+		}
+		if selector.NotIn == nil {
+			selector.NotIn = map[string][]string{}
+		}
+		selector.NotIn[key] = append(selector.NotIn[key], values...)
+		return nil
+	}
+
+	if key, values, ok, err := parseSetRequirement(requirement, "in"); ok || err != nil {
+		if err != nil {
+			return err
+		}
+		if selector.In == nil {
+			selector.In = map[string][]string{}
+		}
+		if existing, found := selector.In[key]; found {
+			// Repeated set requirements on one key must all hold, so only their common values can match
+			values = slices.DeleteFunc(values, func(value string) bool {
+				return !slices.Contains(existing, value)
+			})
+		}
+		selector.In[key] = values
+		return nil
+	}
+
+	if key, value, found := strings.Cut(requirement, "!="); found {
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		err := validateSelectorPair(key, value)
+		if err != nil {
+			return err
+		}
+		if selector.NotIn == nil {
+			selector.NotIn = map[string][]string{}
+		}
+		selector.NotIn[key] = append(selector.NotIn[key], value)
+		return nil
+	}
+
+	if key, value, found := strings.Cut(requirement, "="); found {
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(strings.TrimPrefix(value, "="))
+		err := validateSelectorPair(key, value)
+		if err != nil {
+			return err
+		}
+		if selector.Equals == nil {
+			selector.Equals = map[string]string{}
+		}
+		if existing, found := selector.Equals[key]; found && existing != value {
+			return fmt.Errorf("%w: conflicting values for %q", ErrInvalidLabelSelector, key)
+		}
+		selector.Equals[key] = value
+		return nil
+	}
+
+	if key, found := strings.CutPrefix(requirement, "!"); found {
+		key = strings.TrimSpace(key)
+		err := ValidateLabelKey(key)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidLabelSelector, err)
+		}
+		selector.NotExists = append(selector.NotExists, key)
+		return nil
+	}
+
+	err := ValidateLabelKey(requirement)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidLabelSelector, err)
+	}
+	selector.Exists = append(selector.Exists, requirement)
+
+	return nil
+}
+
+// parseSetRequirement parses "key <operator> (a,b)". ok is false when the requirement does not use the operator.
+func parseSetRequirement(requirement string, operator string) (string, []string, bool, error) {
+	fields := strings.Fields(requirement)
+	if len(fields) < 2 || fields[1] != operator && !strings.HasPrefix(fields[1], operator+"(") {
+		return "", nil, false, nil
+	}
+
+	key := fields[0]
+	set := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(requirement[len(key):]), operator))
+	if !strings.HasPrefix(set, "(") || !strings.HasSuffix(set, ")") {
+		return "", nil, true, fmt.Errorf("%w: %s requires a parenthesized value set", ErrInvalidLabelSelector, operator)
+	}
+
+	err := ValidateLabelKey(key)
+	if err != nil {
+		return "", nil, true, fmt.Errorf("%w: %w", ErrInvalidLabelSelector, err)
+	}
+
+	values := []string{}
+	for _, value := range strings.Split(set[1:len(set)-1], ",") {
+		value = strings.TrimSpace(value)
+		err := ValidateLabelValue(value)
+		if err != nil {
+			return "", nil, true, fmt.Errorf("%w: %w", ErrInvalidLabelSelector, err)
+		}
+		values = append(values, value)
+	}
+
+	return key, values, true, nil
+}
+
+// validateSelectorPair validates the key and value of an equality requirement.
+func validateSelectorPair(key string, value string) error {
+	err := ValidateLabelKey(key)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidLabelSelector, err)
+	}
+
+	err = ValidateLabelValue(value)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidLabelSelector, err)
+	}
+
+	return nil
+}
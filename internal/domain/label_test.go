@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	t.Run("parses every requirement type", func(t *testing.T) {
+		assert := assert.New(t)
+
+		selector, err := ParseLabelSelector("building=north, floor in (2,3), tier==gold, zone!=b, env notin (dev, test), asset, !retired")
+
+		assert.NoError(err)
+		assert.Equal(map[string]string{"building": "north", "tier": "gold"}, selector.Equals)
+		assert.Equal(map[string][]string{"floor": {"2", "3"}}, selector.In)
+		assert.Equal(map[string][]string{"zone": {"b"}, "env": {"dev", "test"}}, selector.NotIn)
+		assert.Equal([]string{"asset"}, selector.Exists)
+		assert.Equal([]string{"retired"}, selector.NotExists)
+	})
+
+	t.Run("empty selector matches everything", func(t *testing.T) {
+		assert := assert.New(t)
+
+		selector, err := ParseLabelSelector("  ")
+
+		assert.NoError(err)
+		assert.True(selector.Empty())
+		assert.True(selector.Matches(Labels{"building": "north"}))
+	})
+
+	t.Run("rejects malformed selectors", func(t *testing.T) {
+		for _, input := range []string{
+			"floor in 2,3",
+			"floor in (2,3",
+			"building=north,",
+			"building=north,building=south",
+			"-bad=key",
+		} {
+			_, err := ParseLabelSelector(input)
+			assert.ErrorIs(t, err, ErrInvalidLabelSelector, input)
+		}
+	})
+}
+
+func TestLabelSelector_Matches(t *testing.T) {
+	selector, err := ParseLabelSelector("building=north,floor in (2,3),zone!=b,!retired")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		labels  Labels
+		matches bool
+	}{
+		{"all requirements hold", Labels{"building": "north", "floor": "2"}, true},
+		{"not equal matches a different value", Labels{"building": "north", "floor": "3", "zone": "a"}, true},
+		{"wrong equality value", Labels{"building": "south", "floor": "2"}, false},
+		{"value outside set", Labels{"building": "north", "floor": "4"}, false},
+		{"set key missing", Labels{"building": "north"}, false},
+		{"excluded value", Labels{"building": "north", "floor": "2", "zone": "b"}, false},
+		{"forbidden key present", Labels{"building": "north", "floor": "2", "retired": ""}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.matches, selector.Matches(test.labels))
+		})
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	t.Run("parses key value pairs", func(t *testing.T) {
+		assert := assert.New(t)
+
+		labels, err := ParseLabels("building=north, example.com/asset=A-42")
+
+		assert.NoError(err)
+		assert.Equal(Labels{"building": "north", "example.com/asset": "A-42"}, labels)
+		assert.Equal("building=north,example.com/asset=A-42", labels.String())
+	})
+
+	t.Run("rejects invalid labels", func(t *testing.T) {
+		for _, input := range []string{"building", "building=north,building=south", "Bad Prefix/name=x", "floor=two words"} {
+			_, err := ParseLabels(input)
+			assert.ErrorIs(t, err, ErrInvalidLabel, input)
+		}
+	})
+}
@@ -0,0 +1,317 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+var (
+	// ErrInvalidLoRaWANDownlink is returned when a downlink has a port outside 1 to 223, a payload that is too long,
+	// both a payload and data to encode, or targets an end device that is not a LoRaWAN device.
+	ErrInvalidLoRaWANDownlink = errors.New("invalid lorawan downlink")
+)
+
+const (
+	// MaxLoRaWANDownlinkPayloadLength is the longest downlink payload in bytes, what the fastest data rates of the
+	// regional parameters allow. Slower data rates allow less; the network server rejects downlinks that do not fit.
+	MaxLoRaWANDownlinkPayloadLength = 242
+	// MaxLoRaWANDownlinkHistory is the largest number of downlinks returned for an end device at once.
+	MaxLoRaWANDownlinkHistory = 100
+	// loRaWANDownlinkCorrelationPrefix starts the correlation ID that ties the network server's events about a
+	// downlink back to it.
+	loRaWANDownlinkCorrelationPrefix = "ponix:downlink:"
+)
+
+// LoRaWANDownlinkStatus is where a downlink stands in the network server's queue.
+type LoRaWANDownlinkStatus string
+
+// Downlink statuses.
+const (
+	// LoRaWANDownlinkQueued downlinks wait in the network server's queue for the end device's next receive window.
+	LoRaWANDownlinkQueued LoRaWANDownlinkStatus = "queued"
+	// LoRaWANDownlinkSent downlinks were transmitted by a gateway.
+	LoRaWANDownlinkSent LoRaWANDownlinkStatus = "sent"
+	// LoRaWANDownlinkAcked downlinks were confirmed and acknowledged by the end device.
+	LoRaWANDownlinkAcked LoRaWANDownlinkStatus = "acked"
+	// LoRaWANDownlinkFailed downlinks could not be queued or were dropped by the network server.
+	LoRaWANDownlinkFailed LoRaWANDownlinkStatus = "failed"
+)
+
+// loRaWANDownlinkTransitions lists the statuses a downlink can move to each status from. Acknowledged and failed
+// downlinks are final; a sent downlink the end device did not acknowledge is queued again by the network server.
+var loRaWANDownlinkTransitions = map[LoRaWANDownlinkStatus][]LoRaWANDownlinkStatus{
+	LoRaWANDownlinkQueued: {LoRaWANDownlinkSent},
+	LoRaWANDownlinkSent:   {LoRaWANDownlinkQueued},
+	LoRaWANDownlinkAcked:  {LoRaWANDownlinkQueued, LoRaWANDownlinkSent},
+	LoRaWANDownlinkFailed: {LoRaWANDownlinkQueued, LoRaWANDownlinkSent},
+}
+
+// LoRaWANDownlink is a downlink pushed to the network server's queue for a LoRaWAN end device.
+type LoRaWANDownlink struct {
+	Id          string
+	EndDeviceId string
+	FPort       uint32
+	FrmPayload  []byte
+	// Data is what FrmPayload was encoded from by the device's payload decoder; nil for raw payloads.
+	Data      map[string]any
+	Confirmed bool
+	Status    LoRaWANDownlinkStatus
+	// Error is why the downlink failed, or why it was queued again after it was sent.
+	Error string
+	// RequestedBy is the user that scheduled the downlink.
+	RequestedBy string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// CorrelationId returns the correlation ID the downlink is pushed with, which the network server repeats in its
+// events about the downlink.
+func (downlink *LoRaWANDownlink) CorrelationId() string {
+	return loRaWANDownlinkCorrelationPrefix + downlink.Id
+}
+
+// LoRaWANDownlinkRequest asks for a downlink to be sent to a LoRaWAN end device. The payload is either given as
+// raw bytes or as data, which the encodeDownlink function of the device's JavaScript payload decoder encodes.
+type LoRaWANDownlinkRequest struct {
+	EndDeviceId    string
+	OrganizationId string
+	RequestedBy    string
+	// FPort is required for raw payloads. For data it defaults to the port returned by encodeDownlink.
+	FPort      uint32
+	FrmPayload []byte
+	Data       map[string]any
+	Confirmed  bool
+}
+
+// validate checks that the request names an end device and carries either a payload or data.
+func (req *LoRaWANDownlinkRequest) validate() error {
+	if req.EndDeviceId == "" || req.OrganizationId == "" {
+		return stacktrace.NewStackTraceErrorf("%w: end device and organization are required", ErrInvalidLoRaWANDownlink)
+	}
+
+	if req.Data != nil && len(req.FrmPayload) > 0 {
+		return stacktrace.NewStackTraceErrorf("%w: either a payload or data to encode is sent, not both", ErrInvalidLoRaWANDownlink)
+	}
+
+	return nil
+}
+
+// LoRaWANDownlinkEvent reports that the network server queued, sent, acknowledged or dropped a downlink.
+type LoRaWANDownlinkEvent struct {
+	DeviceEui  string
+	ReceivedAt time.Time
+	Status     LoRaWANDownlinkStatus
+	// Error is why the downlink failed or was queued again.
+	Error string
+	// CorrelationIds are the correlation IDs of the downlink, including the one it was pushed with.
+	CorrelationIds []string
+}
+
+// LoRaWANDownlinkStorer defines the persistence operations for LoRaWAN downlinks.
+type LoRaWANDownlinkStorer interface {
+	CreateLoRaWANDownlink(ctx context.Context, downlink *LoRaWANDownlink) error
+	// UpdateLoRaWANDownlinkStatus moves a downlink to a status, but only from one of the given statuses. It reports
+	// whether the downlink was updated.
+	UpdateLoRaWANDownlinkStatus(ctx context.Context, downlinkId string, status LoRaWANDownlinkStatus, reason string, updatedAt time.Time, from []LoRaWANDownlinkStatus) (bool, error)
+	// ListEndDeviceLoRaWANDownlinks returns the most recent downlinks of an end device, newest first.
+	ListEndDeviceLoRaWANDownlinks(ctx context.Context, endDeviceId string, limit int) ([]*LoRaWANDownlink, error)
+}
+
+// LoRaWANDownlinkQueue pushes downlinks to the network server's queue of an end device.
+type LoRaWANDownlinkQueue interface {
+	PushLoRaWANDownlink(ctx context.Context, endDevice *iotv1.EndDevice, downlink *LoRaWANDownlink) error
+}
+
+// LoRaWANPayloadEncoder encodes downlink data with the payload decoder of an end device; see
+// PayloadDecoderManager.EncodeEndDeviceDownlink.
+type LoRaWANPayloadEncoder interface {
+	EncodeEndDeviceDownlink(ctx context.Context, endDeviceId string, input PayloadEncoderInput) (*PayloadEncoderOutput, bool, error)
+}
+
+// LoRaWANDownlinkManager sends downlinks to LoRaWAN end devices through the network server and follows their
+// status from the network server's events.
+type LoRaWANDownlinkManager struct {
+	downlinkStore  LoRaWANDownlinkStorer
+	endDeviceStore EndDeviceStorer
+	queue          LoRaWANDownlinkQueue
+	encoder        LoRaWANPayloadEncoder
+	stringId       StringId
+}
+
+// NewLoRaWANDownlinkManager creates a new instance of LoRaWANDownlinkManager with the provided dependencies.
+func NewLoRaWANDownlinkManager(downlinkStore LoRaWANDownlinkStorer, eds EndDeviceStorer, queue LoRaWANDownlinkQueue, encoder LoRaWANPayloadEncoder, stringId StringId) *LoRaWANDownlinkManager {
+	return &LoRaWANDownlinkManager{
+		downlinkStore:  downlinkStore,
+		endDeviceStore: eds,
+		queue:          queue,
+		encoder:        encoder,
+		stringId:       stringId,
+	}
+}
+
+// ScheduleLoRaWANDownlink pushes a downlink to the network server's queue of an enabled LoRaWAN end device on
+// behalf of the requesting user. Data is encoded by the device's JavaScript
+// payload decoder first, failing with ErrPayloadDecoderFailed when encodeDownlink does. The downlink is recorded
+// as queued before it is pushed; when the push fails it is returned as failed along with the error.
+func (mgr *LoRaWANDownlinkManager) ScheduleLoRaWANDownlink(ctx context.Context, req LoRaWANDownlinkRequest) (*LoRaWANDownlink, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ScheduleLoRaWANDownlink")
+	defer span.End()
+
+	err := req.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	endDevice, err := mgr.endDevice(ctx, req.EndDeviceId, req.OrganizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	if endDevice.GetStatus() == iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceDisabled, req.EndDeviceId)
+	}
+
+	if endDevice.GetLorawanConfig() == nil {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s is not a LoRaWAN device", ErrInvalidLoRaWANDownlink, req.EndDeviceId)
+	}
+
+	fPort, payload := req.FPort, req.FrmPayload
+	if req.Data != nil {
+		fPort, payload, err = mgr.encode(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if fPort < 1 || fPort > 223 {
+		return nil, stacktrace.NewStackTraceErrorf("%w: port must be between 1 and 223, got %d", ErrInvalidLoRaWANDownlink, fPort)
+	}
+
+	if len(payload) > MaxLoRaWANDownlinkPayloadLength {
+		return nil, stacktrace.NewStackTraceErrorf("%w: payload is %d bytes, at most %d fit a downlink", ErrInvalidLoRaWANDownlink, len(payload), MaxLoRaWANDownlinkPayloadLength)
+	}
+
+	now := time.Now().UTC()
+	downlink := &LoRaWANDownlink{
+		Id:          mgr.stringId(),
+		EndDeviceId: req.EndDeviceId,
+		FPort:       fPort,
+		FrmPayload:  payload,
+		Data:        req.Data,
+		Confirmed:   req.Confirmed,
+		Status:      LoRaWANDownlinkQueued,
+		RequestedBy: req.RequestedBy,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	// The downlink is stored first so the network server's events about it always find it
+	err = mgr.downlinkStore.CreateLoRaWANDownlink(ctx, downlink)
+	if err != nil {
+		return nil, err
+	}
+
+	pushErr := mgr.queue.PushLoRaWANDownlink(ctx, endDevice, downlink)
+	if pushErr != nil {
+		downlink.Status = LoRaWANDownlinkFailed
+		downlink.Error = pushErr.Error()
+		downlink.UpdatedAt = time.Now().UTC()
+
+		_, err = mgr.downlinkStore.UpdateLoRaWANDownlinkStatus(ctx, downlink.Id, downlink.Status, downlink.Error, downlink.UpdatedAt, loRaWANDownlinkTransitions[downlink.Status])
+		if err != nil {
+			return downlink, errors.Join(pushErr, err)
+		}
+
+		return downlink, pushErr
+	}
+
+	return downlink, nil
+}
+
+// ListEndDeviceLoRaWANDownlinks returns up to limit of the most recent downlinks of an end device of the
+// organization, newest first. A limit outside 1 to MaxLoRaWANDownlinkHistory returns MaxLoRaWANDownlinkHistory
+// downlinks.
+func (mgr *LoRaWANDownlinkManager) ListEndDeviceLoRaWANDownlinks(ctx context.Context, endDeviceId string, organizationId string, limit int) ([]*LoRaWANDownlink, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListEndDeviceLoRaWANDownlinks")
+	defer span.End()
+
+	_, err := mgr.endDevice(ctx, endDeviceId, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit < 1 || limit > MaxLoRaWANDownlinkHistory {
+		limit = MaxLoRaWANDownlinkHistory
+	}
+
+	return mgr.downlinkStore.ListEndDeviceLoRaWANDownlinks(ctx, endDeviceId, limit)
+}
+
+// TrackLoRaWANDownlink moves the downlink a network server event is about to the event's status. Events about
+// downlinks not scheduled through ponix, and events that would move a downlink backwards, are ignored.
+func (mgr *LoRaWANDownlinkManager) TrackLoRaWANDownlink(ctx context.Context, event LoRaWANDownlinkEvent) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "TrackLoRaWANDownlink")
+	defer span.End()
+
+	from, ok := loRaWANDownlinkTransitions[event.Status]
+	if !ok {
+		return stacktrace.NewStackTraceErrorf("%w: unknown downlink status %q", ErrInvalidLoRaWANMessage, event.Status)
+	}
+
+	for _, correlationId := range event.CorrelationIds {
+		downlinkId, ok := strings.CutPrefix(correlationId, loRaWANDownlinkCorrelationPrefix)
+		if !ok {
+			continue
+		}
+
+		_, err := mgr.downlinkStore.UpdateLoRaWANDownlinkStatus(ctx, downlinkId, event.Status, event.Error, event.ReceivedAt, from)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encode runs the data of a request through the encodeDownlink function of the end device's payload decoder. It
+// returns the port of the request, or else the one encodeDownlink chose, along with the payload.
+func (mgr *LoRaWANDownlinkManager) encode(ctx context.Context, req LoRaWANDownlinkRequest) (uint32, []byte, error) {
+	output, ok, err := mgr.encoder.EncodeEndDeviceDownlink(ctx, req.EndDeviceId, PayloadEncoderInput{
+		Data:  req.Data,
+		FPort: req.FPort,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if !ok {
+		return 0, nil, stacktrace.NewStackTraceErrorf("%w: %s has no javascript payload decoder to encode data with", ErrInvalidLoRaWANDownlink, req.EndDeviceId)
+	}
+
+	if req.FPort != 0 {
+		return req.FPort, output.Bytes, nil
+	}
+
+	return output.FPort, output.Bytes, nil
+}
+
+// endDevice returns an end device of the organization, reporting devices of other organizations as
+// ErrEndDeviceNotFound.
+func (mgr *LoRaWANDownlinkManager) endDevice(ctx context.Context, endDeviceId string, organizationId string) (*iotv1.EndDevice, error) {
+	endDevice, deviceOrgId, err := mgr.endDeviceStore.GetEndDeviceWithOrganization(ctx, endDeviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if deviceOrgId != organizationId {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceNotFound, endDeviceId)
+	}
+
+	return endDevice, nil
+}
@@ -0,0 +1,184 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLoRaWANDownlinkManager() (*LoRaWANDownlinkManager, *memoryLoRaWANDownlinkStore, *recordingDownlinkQueue) {
+	lorawanConfig := iotv1.LoRaWANConfig_builder{DeviceEui: "70B3D57ED0001234"}.Build()
+	owners := newMemoryEndDeviceStore()
+	owners.put(iotv1.EndDevice_builder{Id: "device-1", Status: iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE, LorawanConfig: lorawanConfig}.Build(), "org-1")
+	owners.put(iotv1.EndDevice_builder{Id: "device-2", Status: iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE, LorawanConfig: lorawanConfig}.Build(), "org-1")
+	owners.put(iotv1.EndDevice_builder{Id: "device-3", Status: iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED, LorawanConfig: lorawanConfig}.Build(), "org-1")
+	owners.put(iotv1.EndDevice_builder{Id: "device-4", Status: iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE}.Build(), "org-1")
+	owners.put(iotv1.EndDevice_builder{Id: "device-5", Status: iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE, LorawanConfig: lorawanConfig}.Build(), "org-2")
+	store := &memoryLoRaWANDownlinkStore{}
+	queue := &recordingDownlinkQueue{}
+
+	ids := 0
+	stringId := func() string {
+		ids++
+		return fmt.Sprintf("dl-%d", ids)
+	}
+
+	return NewLoRaWANDownlinkManager(store, owners, queue, fakeLoRaWANPayloadEncoder{}, stringId), store, queue
+}
+
+func TestLoRaWANDownlinkManager_ScheduleLoRaWANDownlink(t *testing.T) {
+	t.Run("queues raw payloads", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, store, queue := newTestLoRaWANDownlinkManager()
+
+		downlink, err := mgr.ScheduleLoRaWANDownlink(context.Background(), LoRaWANDownlinkRequest{
+			EndDeviceId:    "device-2",
+			OrganizationId: "org-1",
+			RequestedBy:    "user-1",
+			FPort:          2,
+			FrmPayload:     []byte{0x01, 0x02},
+			Confirmed:      true,
+		})
+		if !assert.NoError(err) {
+			return
+		}
+		assert.Equal("dl-1", downlink.Id)
+		assert.Equal(LoRaWANDownlinkQueued, downlink.Status)
+		assert.Equal("ponix:downlink:dl-1", downlink.CorrelationId())
+		assert.Equal([]*LoRaWANDownlink{downlink}, queue.pushed)
+		assert.Len(store.downlinks, 1)
+	})
+
+	t.Run("encodes data with the device's payload decoder", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, _, _ := newTestLoRaWANDownlinkManager()
+
+		req := LoRaWANDownlinkRequest{EndDeviceId: "device-1", OrganizationId: "org-1", RequestedBy: "user-1", Data: map[string]any{"command": "open"}}
+		downlink, err := mgr.ScheduleLoRaWANDownlink(context.Background(), req)
+		if assert.NoError(err) {
+			assert.Equal([]byte("open"), downlink.FrmPayload)
+			assert.Equal(uint32(10), downlink.FPort)
+			assert.Equal(req.Data, downlink.Data)
+		}
+
+		req.FPort = 4
+		downlink, err = mgr.ScheduleLoRaWANDownlink(context.Background(), req)
+		if assert.NoError(err) {
+			assert.Equal(uint32(4), downlink.FPort)
+		}
+
+		req.Data = map[string]any{"command": 1}
+		_, err = mgr.ScheduleLoRaWANDownlink(context.Background(), req)
+		assert.ErrorIs(err, ErrPayloadDecoderFailed)
+
+		req.EndDeviceId = "device-2"
+		_, err = mgr.ScheduleLoRaWANDownlink(context.Background(), req)
+		assert.ErrorIs(err, ErrInvalidLoRaWANDownlink)
+	})
+
+	t.Run("rejects invalid downlinks", func(t *testing.T) {
+		mgr, store, queue := newTestLoRaWANDownlinkManager()
+
+		for name, test := range map[string]struct {
+			req LoRaWANDownlinkRequest
+			err error
+		}{
+			"no device":        {LoRaWANDownlinkRequest{OrganizationId: "org-1", RequestedBy: "user-1", FPort: 1}, ErrInvalidLoRaWANDownlink},
+			"payload and data": {LoRaWANDownlinkRequest{EndDeviceId: "device-1", OrganizationId: "org-1", RequestedBy: "user-1", FPort: 1, FrmPayload: []byte{1}, Data: map[string]any{}}, ErrInvalidLoRaWANDownlink},
+			"port 0":           {LoRaWANDownlinkRequest{EndDeviceId: "device-2", OrganizationId: "org-1", RequestedBy: "user-1"}, ErrInvalidLoRaWANDownlink},
+			"port 224":         {LoRaWANDownlinkRequest{EndDeviceId: "device-2", OrganizationId: "org-1", RequestedBy: "user-1", FPort: 224}, ErrInvalidLoRaWANDownlink},
+			"long payload":     {LoRaWANDownlinkRequest{EndDeviceId: "device-2", OrganizationId: "org-1", RequestedBy: "user-1", FPort: 1, FrmPayload: make([]byte, MaxLoRaWANDownlinkPayloadLength+1)}, ErrInvalidLoRaWANDownlink},
+			"disabled device":  {LoRaWANDownlinkRequest{EndDeviceId: "device-3", OrganizationId: "org-1", RequestedBy: "user-1", FPort: 1}, ErrEndDeviceDisabled},
+			"not lorawan":      {LoRaWANDownlinkRequest{EndDeviceId: "device-4", OrganizationId: "org-1", RequestedBy: "user-1", FPort: 1}, ErrInvalidLoRaWANDownlink},
+			"other org":        {LoRaWANDownlinkRequest{EndDeviceId: "device-5", OrganizationId: "org-1", RequestedBy: "user-1", FPort: 1}, ErrEndDeviceNotFound},
+		} {
+			_, err := mgr.ScheduleLoRaWANDownlink(context.Background(), test.req)
+			assert.ErrorIs(t, err, test.err, name)
+		}
+
+		assert.Empty(t, store.downlinks)
+		assert.Empty(t, queue.pushed)
+	})
+
+	t.Run("records downlinks the network server refused as failed", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, store, queue := newTestLoRaWANDownlinkManager()
+		queue.err = errors.New("device not found")
+
+		downlink, err := mgr.ScheduleLoRaWANDownlink(context.Background(), LoRaWANDownlinkRequest{EndDeviceId: "device-2", OrganizationId: "org-1", RequestedBy: "user-1", FPort: 1})
+		assert.ErrorIs(err, queue.err)
+		if assert.NotNil(downlink) {
+			assert.Equal(LoRaWANDownlinkFailed, downlink.Status)
+			assert.Equal("device not found", downlink.Error)
+		}
+		assert.Equal(LoRaWANDownlinkFailed, store.downlinks[0].Status)
+	})
+}
+
+func TestLoRaWANDownlinkManager_TrackLoRaWANDownlink(t *testing.T) {
+	assert := assert.New(t)
+	mgr, store, _ := newTestLoRaWANDownlinkManager()
+	receivedAt := time.Date(2025, 11, 15, 9, 0, 0, 0, time.UTC)
+
+	downlink, err := mgr.ScheduleLoRaWANDownlink(context.Background(), LoRaWANDownlinkRequest{EndDeviceId: "device-2", OrganizationId: "org-1", RequestedBy: "user-1", FPort: 1, Confirmed: true})
+	if !assert.NoError(err) {
+		return
+	}
+
+	track := func(status LoRaWANDownlinkStatus, reason string) LoRaWANDownlink {
+		err := mgr.TrackLoRaWANDownlink(context.Background(), LoRaWANDownlinkEvent{
+			ReceivedAt:     receivedAt,
+			Status:         status,
+			Error:          reason,
+			CorrelationIds: []string{"as:downlink:01H", downlink.CorrelationId()},
+		})
+		assert.NoError(err)
+		return *store.downlinks[0]
+	}
+
+	assert.Equal(LoRaWANDownlinkSent, track(LoRaWANDownlinkSent, "").Status)
+
+	requeued := track(LoRaWANDownlinkQueued, "not acknowledged")
+	assert.Equal(LoRaWANDownlinkQueued, requeued.Status)
+	assert.Equal("not acknowledged", requeued.Error)
+
+	assert.Equal(LoRaWANDownlinkSent, track(LoRaWANDownlinkSent, "").Status)
+	acked := track(LoRaWANDownlinkAcked, "")
+	assert.Equal(LoRaWANDownlinkAcked, acked.Status)
+	assert.Equal(receivedAt, acked.UpdatedAt)
+
+	// Acknowledged downlinks are final
+	assert.Equal(LoRaWANDownlinkAcked, track(LoRaWANDownlinkFailed, "dropped").Status)
+	assert.Equal(LoRaWANDownlinkAcked, track(LoRaWANDownlinkQueued, "").Status)
+
+	err = mgr.TrackLoRaWANDownlink(context.Background(), LoRaWANDownlinkEvent{Status: "lost", CorrelationIds: []string{downlink.CorrelationId()}})
+	assert.ErrorIs(err, ErrInvalidLoRaWANMessage)
+}
+
+func TestLoRaWANDownlinkManager_ListEndDeviceLoRaWANDownlinks(t *testing.T) {
+	assert := assert.New(t)
+	mgr, _, _ := newTestLoRaWANDownlinkManager()
+
+	for range 3 {
+		_, err := mgr.ScheduleLoRaWANDownlink(context.Background(), LoRaWANDownlinkRequest{EndDeviceId: "device-2", OrganizationId: "org-1", RequestedBy: "user-1", FPort: 1})
+		assert.NoError(err)
+	}
+
+	downlinks, err := mgr.ListEndDeviceLoRaWANDownlinks(context.Background(), "device-2", "org-1", 2)
+	if assert.NoError(err) && assert.Len(downlinks, 2) {
+		assert.Equal("dl-3", downlinks[0].Id)
+		assert.Equal("dl-2", downlinks[1].Id)
+	}
+
+	downlinks, err = mgr.ListEndDeviceLoRaWANDownlinks(context.Background(), "device-2", "org-1", 0)
+	assert.NoError(err)
+	assert.Len(downlinks, 3)
+
+	_, err = mgr.ListEndDeviceLoRaWANDownlinks(context.Background(), "device-5", "org-1", 0)
+	assert.ErrorIs(err, ErrEndDeviceNotFound)
+}
@@ -0,0 +1,292 @@
+package domain
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"time"
+
+	envelopev1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/envelope/v1"
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ErrInvalidLoRaWANMessage is returned when a message forwarded by the LoRaWAN network server is malformed.
+var ErrInvalidLoRaWANMessage = errors.New("invalid lorawan message")
+
+// LoRaWANTwinLocationKey is the key of the reported twin state holding the last location solved for a LoRaWAN end device.
+const LoRaWANTwinLocationKey = "location"
+
+// LoRaWANUplink is a data uplink of a LoRaWAN end device forwarded by the network server.
+type LoRaWANUplink struct {
+	DeviceEui  string
+	ReceivedAt time.Time
+	FPort      uint32
+	FCnt       uint32
+	// FrmPayload is the raw application payload.
+	FrmPayload []byte
+	// DecodedPayload is the payload decoded by the network server's payload formatter; nil when there is none.
+	DecodedPayload map[string]any
+}
+
+// LoRaWANJoinAccept reports that a LoRaWAN end device joined the network.
+type LoRaWANJoinAccept struct {
+	DeviceEui  string
+	ReceivedAt time.Time
+}
+
+// LoRaWANDownlinkAck reports that a LoRaWAN end device acknowledged a confirmed downlink.
+type LoRaWANDownlinkAck struct {
+	DeviceEui  string
+	ReceivedAt time.Time
+	FPort      uint32
+	FCnt       uint32
+	// CorrelationIds are the correlation IDs of the acknowledged downlink, as assigned by the network server.
+	CorrelationIds []string
+}
+
+// LoRaWANLocation is a location of a LoRaWAN end device solved by the network server or a location service.
+type LoRaWANLocation struct {
+	DeviceEui  string
+	ReceivedAt time.Time
+	Latitude   float64
+	Longitude  float64
+	Altitude   float64
+	// Accuracy is the accuracy of the location in meters, zero when unknown.
+	Accuracy float64
+	// Source is how the location was determined, such as SOURCE_GPS or SOURCE_WIFI_RSSI_GEOLOCATION.
+	Source string
+	// Service is the location service that solved the location, if any.
+	Service string
+}
+
+// LoRaWANDeviceResolver finds the end device a LoRaWAN device EUI belongs to.
+type LoRaWANDeviceResolver interface {
+	// GetEndDeviceByDeviceEUI returns the end device with the given device EUI and its organization,
+	// or ErrEndDeviceNotFound when no device has it.
+	GetEndDeviceByDeviceEUI(ctx context.Context, deviceEui string) (*iotv1.EndDevice, string, error)
+}
+
+// LoRaWANActivityRecorder records the traffic of LoRaWAN end devices so their status can follow it.
+type LoRaWANActivityRecorder interface {
+	RecordEndDeviceActivity(ctx context.Context, endDevice *iotv1.EndDevice, seenAt time.Time) error
+	RecordEndDeviceJoin(ctx context.Context, endDevice *iotv1.EndDevice, joinedAt time.Time) error
+}
+
+// EndDeviceStateReporter merges state reported on behalf of an end device into its twin.
+type EndDeviceStateReporter interface {
+	ReportEndDeviceTwinState(ctx context.Context, endDeviceId string, organizationId string, reported map[string]any) (*EndDeviceTwin, error)
+}
+
+// LoRaWANPayloadDecoder runs the payload decoder of an end device over its uplinks; see
+// PayloadDecoderManager.DecodeEndDeviceUplink.
+type LoRaWANPayloadDecoder interface {
+	DecodeEndDeviceUplink(ctx context.Context, endDeviceId string, input PayloadDecoderInput) (map[string]any, bool, error)
+}
+
+// LoRaWANDownlinkTracker follows the status of downlinks from the network server's events; see
+// LoRaWANDownlinkManager.TrackLoRaWANDownlink.
+type LoRaWANDownlinkTracker interface {
+	TrackLoRaWANDownlink(ctx context.Context, event LoRaWANDownlinkEvent) error
+}
+
+// LoRaWANUplinkManager handles the messages the LoRaWAN network server forwards for end devices: data uplinks
+// become data envelopes, joins and downlink acknowledgements move the device's status, downlink events move the
+// status of downlinks, and solved locations are reported into the device's twin.
+type LoRaWANUplinkManager struct {
+	resolver  LoRaWANDeviceResolver
+	envelopes DataEnvelopeIngester
+	activity  LoRaWANActivityRecorder
+	twins     EndDeviceStateReporter
+	decoder   LoRaWANPayloadDecoder
+	downlinks LoRaWANDownlinkTracker
+}
+
+// NewLoRaWANUplinkManager creates a new LoRaWANUplinkManager.
+func NewLoRaWANUplinkManager(resolver LoRaWANDeviceResolver, envelopes DataEnvelopeIngester, activity LoRaWANActivityRecorder, twins EndDeviceStateReporter, decoder LoRaWANPayloadDecoder, downlinks LoRaWANDownlinkTracker) *LoRaWANUplinkManager {
+	return &LoRaWANUplinkManager{
+		resolver:  resolver,
+		envelopes: envelopes,
+		activity:  activity,
+		twins:     twins,
+		decoder:   decoder,
+		downlinks: downlinks,
+	}
+}
+
+// IngestLoRaWANUplink stores the data of an uplink as a data envelope of the end device with the uplink's device
+// EUI, occurring when the network server received it. The data is the payload as decoded by the device's payload
+// decoder, else by the network server's payload formatter; otherwise the port and raw payload (base64) are stored
+// as f_port and frm_payload. A failing payload decoder does not fail the uplink: the raw payload is stored with the
+// decoder's error under PayloadDecoderErrorKey.
+// Uplinks of unknown devices are reported as ErrEndDeviceNotFound and those of disabled devices as ErrEndDeviceDisabled.
+func (mgr *LoRaWANUplinkManager) IngestLoRaWANUplink(ctx context.Context, uplink LoRaWANUplink) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "IngestLoRaWANUplink")
+	defer span.End()
+
+	endDevice, organizationId, err := mgr.resolve(ctx, uplink.DeviceEui)
+	if err != nil {
+		return err
+	}
+
+	data, err := mgr.uplinkData(ctx, endDevice, uplink)
+	if err != nil {
+		return err
+	}
+
+	envelope := envelopev1.DataEnvelope_builder{
+		EndDeviceId: endDevice.GetId(),
+		OccurredAt:  timestamppb.New(uplink.ReceivedAt),
+		Data:        data,
+	}.Build()
+
+	return mgr.envelopes.IngestDataEnvelope(ctx, envelope, organizationId)
+}
+
+// RecordLoRaWANJoinAccept activates an end device that joined the network; see EndDeviceStatusManager.RecordEndDeviceJoin.
+func (mgr *LoRaWANUplinkManager) RecordLoRaWANJoinAccept(ctx context.Context, join LoRaWANJoinAccept) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "RecordLoRaWANJoinAccept")
+	defer span.End()
+
+	endDevice, _, err := mgr.resolveEnabled(ctx, join.DeviceEui)
+	if err != nil {
+		return err
+	}
+
+	return mgr.activity.RecordEndDeviceJoin(ctx, endDevice, join.ReceivedAt)
+}
+
+// RecordLoRaWANDownlinkAck marks the acknowledged downlink as acked and records the uplink that acknowledged it as
+// activity of the end device.
+func (mgr *LoRaWANUplinkManager) RecordLoRaWANDownlinkAck(ctx context.Context, ack LoRaWANDownlinkAck) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "RecordLoRaWANDownlinkAck")
+	defer span.End()
+
+	err := mgr.downlinks.TrackLoRaWANDownlink(ctx, LoRaWANDownlinkEvent{
+		DeviceEui:      ack.DeviceEui,
+		ReceivedAt:     ack.ReceivedAt,
+		Status:         LoRaWANDownlinkAcked,
+		CorrelationIds: ack.CorrelationIds,
+	})
+	if err != nil {
+		return err
+	}
+
+	endDevice, _, err := mgr.resolveEnabled(ctx, ack.DeviceEui)
+	if err != nil {
+		return err
+	}
+
+	return mgr.activity.RecordEndDeviceActivity(ctx, endDevice, ack.ReceivedAt)
+}
+
+// RecordLoRaWANDownlinkEvent moves a downlink that was queued, sent or dropped by the network server to its new
+// status; see LoRaWANDownlinkManager.TrackLoRaWANDownlink.
+func (mgr *LoRaWANUplinkManager) RecordLoRaWANDownlinkEvent(ctx context.Context, event LoRaWANDownlinkEvent) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "RecordLoRaWANDownlinkEvent")
+	defer span.End()
+
+	return mgr.downlinks.TrackLoRaWANDownlink(ctx, event)
+}
+
+// RecordLoRaWANLocation reports a solved location into the twin of the end device under LoRaWANTwinLocationKey.
+func (mgr *LoRaWANUplinkManager) RecordLoRaWANLocation(ctx context.Context, location LoRaWANLocation) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "RecordLoRaWANLocation")
+	defer span.End()
+
+	endDevice, organizationId, err := mgr.resolveEnabled(ctx, location.DeviceEui)
+	if err != nil {
+		return err
+	}
+
+	reported := map[string]any{
+		"latitude":  location.Latitude,
+		"longitude": location.Longitude,
+		"altitude":  location.Altitude,
+		"solved_at": location.ReceivedAt.UTC().Format(time.RFC3339Nano),
+	}
+	if location.Accuracy > 0 {
+		reported["accuracy"] = location.Accuracy
+	}
+	if location.Source != "" {
+		reported["source"] = location.Source
+	}
+	if location.Service != "" {
+		reported["service"] = location.Service
+	}
+
+	_, err = mgr.twins.ReportEndDeviceTwinState(ctx, endDevice.GetId(), organizationId, map[string]any{LoRaWANTwinLocationKey: reported})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// resolve finds the end device of a device EUI given in any of the usual notations.
+func (mgr *LoRaWANUplinkManager) resolve(ctx context.Context, deviceEui string) (*iotv1.EndDevice, string, error) {
+	eui := normalizeHex(deviceEui)
+	if !isHexOfLength(eui, 16) {
+		return nil, "", stacktrace.NewStackTraceErrorf("%w: device EUI %q is not 16 hex digits", ErrInvalidLoRaWANMessage, deviceEui)
+	}
+
+	return mgr.resolver.GetEndDeviceByDeviceEUI(ctx, eui)
+}
+
+// resolveEnabled finds the end device of a device EUI, reporting disabled devices as ErrEndDeviceDisabled.
+func (mgr *LoRaWANUplinkManager) resolveEnabled(ctx context.Context, deviceEui string) (*iotv1.EndDevice, string, error) {
+	endDevice, organizationId, err := mgr.resolve(ctx, deviceEui)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if endDevice.GetStatus() == iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED {
+		return nil, "", stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceDisabled, endDevice.GetId())
+	}
+
+	return endDevice, organizationId, nil
+}
+
+// uplinkData builds the data stored for an uplink from its payload as decoded by the device's payload decoder,
+// or else by the network server, falling back to the raw payload.
+func (mgr *LoRaWANUplinkManager) uplinkData(ctx context.Context, endDevice *iotv1.EndDevice, uplink LoRaWANUplink) (*structpb.Struct, error) {
+	document, ok, err := mgr.decoder.DecodeEndDeviceUplink(ctx, endDevice.GetId(), PayloadDecoderInput{
+		Bytes:      uplink.FrmPayload,
+		FPort:      uplink.FPort,
+		ReceivedAt: uplink.ReceivedAt,
+	})
+	switch {
+	case errors.Is(err, ErrPayloadDecoderFailed):
+		slog.Warn("payload decoder failed",
+			slog.String("end_device_id", endDevice.GetId()),
+			stacktrace.ErrorAttribute(err),
+		)
+		document = rawLoRaWANUplinkData(uplink)
+		document[PayloadDecoderErrorKey] = err.Error()
+	case err != nil:
+		return nil, err
+	case !ok && uplink.DecodedPayload != nil:
+		document = uplink.DecodedPayload
+	case !ok:
+		document = rawLoRaWANUplinkData(uplink)
+	}
+
+	data, err := structpb.NewStruct(document)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %w", ErrInvalidLoRaWANMessage, err)
+	}
+
+	return data, nil
+}
+
+// rawLoRaWANUplinkData holds the port and raw payload (base64) of an uplink.
+func rawLoRaWANUplinkData(uplink LoRaWANUplink) map[string]any {
+	return map[string]any{
+		"f_port":      float64(uplink.FPort),
+		"frm_payload": base64.StdEncoding.EncodeToString(uplink.FrmPayload),
+	}
+}
@@ -0,0 +1,197 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeLoRaWANDeviceResolver resolves device EUIs from a fixed set of end devices keyed by EUI, all in org-1.
+type fakeLoRaWANDeviceResolver map[string]*iotv1.EndDevice
+
+func (resolver fakeLoRaWANDeviceResolver) GetEndDeviceByDeviceEUI(_ context.Context, deviceEui string) (*iotv1.EndDevice, string, error) {
+	endDevice, ok := resolver[deviceEui]
+	if !ok {
+		return nil, "", ErrEndDeviceNotFound
+	}
+	return endDevice, "org-1", nil
+}
+
+// recordingLoRaWANActivity records the activity and joins of end devices, and the downlink events tracked for them.
+type recordingLoRaWANActivity struct {
+	calls []string
+}
+
+func (activity *recordingLoRaWANActivity) RecordEndDeviceActivity(_ context.Context, endDevice *iotv1.EndDevice, _ time.Time) error {
+	activity.calls = append(activity.calls, "activity "+endDevice.GetId())
+	return nil
+}
+
+func (activity *recordingLoRaWANActivity) RecordEndDeviceJoin(_ context.Context, endDevice *iotv1.EndDevice, _ time.Time) error {
+	activity.calls = append(activity.calls, "join "+endDevice.GetId())
+	return nil
+}
+
+func (activity *recordingLoRaWANActivity) TrackLoRaWANDownlink(_ context.Context, event LoRaWANDownlinkEvent) error {
+	activity.calls = append(activity.calls, fmt.Sprintf("downlink %s %v", event.Status, event.CorrelationIds))
+	return nil
+}
+
+// recordingStateReporter keeps the state reported for each end device.
+type recordingStateReporter map[string]map[string]any
+
+func (reporter recordingStateReporter) ReportEndDeviceTwinState(_ context.Context, endDeviceId string, _ string, reported map[string]any) (*EndDeviceTwin, error) {
+	reporter[endDeviceId] = reported
+	return &EndDeviceTwin{}, nil
+}
+
+func newTestLoRaWANUplinkManager() (*LoRaWANUplinkManager, *recordingEnvelopeIngester, *recordingLoRaWANActivity, recordingStateReporter) {
+	resolver := fakeLoRaWANDeviceResolver{
+		"70B3D57ED0001234": iotv1.EndDevice_builder{Id: "device-1", Status: iotv1.EndDeviceStatus_END_DEVICE_STATUS_PENDING}.Build(),
+		"70B3D57ED0005678": iotv1.EndDevice_builder{Id: "device-2", Status: iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED}.Build(),
+	}
+	ingester := &recordingEnvelopeIngester{}
+	activity := &recordingLoRaWANActivity{}
+	reporter := recordingStateReporter{}
+
+	return NewLoRaWANUplinkManager(resolver, ingester, activity, reporter, fakeLoRaWANPayloadDecoder{}, activity), ingester, activity, reporter
+}
+
+func TestLoRaWANUplinkManager_IngestLoRaWANUplink(t *testing.T) {
+	receivedAt := time.Date(2025, 11, 12, 9, 0, 0, 0, time.UTC)
+
+	t.Run("stores the decoded payload", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, ingester, _, _ := newTestLoRaWANUplinkManager()
+
+		err := mgr.IngestLoRaWANUplink(context.Background(), LoRaWANUplink{
+			DeviceEui:      "70b3d57ed0001234",
+			ReceivedAt:     receivedAt,
+			FPort:          2,
+			FrmPayload:     []byte{0x01, 0x02},
+			DecodedPayload: map[string]any{"temperature": -18.5},
+		})
+		assert.NoError(err)
+
+		if !assert.Len(ingester.envelopes, 1) {
+			return
+		}
+		envelope := ingester.envelopes[0]
+		assert.Equal("device-1", envelope.GetEndDeviceId())
+		assert.Equal("org-1", ingester.organizations[0])
+		assert.Equal(receivedAt, envelope.GetOccurredAt().AsTime())
+		assert.Equal(map[string]any{"temperature": -18.5}, envelope.GetData().AsMap())
+	})
+
+	t.Run("stores the raw payload when it was not decoded", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, ingester, _, _ := newTestLoRaWANUplinkManager()
+
+		err := mgr.IngestLoRaWANUplink(context.Background(), LoRaWANUplink{DeviceEui: "70B3D57ED0001234", ReceivedAt: receivedAt, FPort: 2, FrmPayload: []byte{0x01, 0x02}})
+		assert.NoError(err)
+
+		if assert.Len(ingester.envelopes, 1) {
+			assert.Equal(map[string]any{"f_port": float64(2), "frm_payload": "AQI="}, ingester.envelopes[0].GetData().AsMap())
+		}
+	})
+
+	t.Run("prefers the device's payload decoder", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, ingester, _, _ := newTestLoRaWANUplinkManager()
+		mgr.decoder = fakeLoRaWANPayloadDecoder{decoded: map[string]map[string]any{"device-1": {"temperature": -19.0}}}
+
+		err := mgr.IngestLoRaWANUplink(context.Background(), LoRaWANUplink{
+			DeviceEui:      "70B3D57ED0001234",
+			ReceivedAt:     receivedAt,
+			FPort:          2,
+			FrmPayload:     []byte{0x01, 0x02},
+			DecodedPayload: map[string]any{"temperature": -18.5},
+		})
+		assert.NoError(err)
+
+		if assert.Len(ingester.envelopes, 1) {
+			assert.Equal(map[string]any{"temperature": -19.0}, ingester.envelopes[0].GetData().AsMap())
+		}
+	})
+
+	t.Run("stores the raw payload and the error of a failing payload decoder", func(t *testing.T) {
+		assert := assert.New(t)
+		mgr, ingester, _, _ := newTestLoRaWANUplinkManager()
+		mgr.decoder = fakeLoRaWANPayloadDecoder{failed: map[string]error{
+			"device-1": fmt.Errorf("%w: ReferenceError: temp is not defined", ErrPayloadDecoderFailed),
+		}}
+
+		err := mgr.IngestLoRaWANUplink(context.Background(), LoRaWANUplink{DeviceEui: "70B3D57ED0001234", ReceivedAt: receivedAt, FPort: 2, FrmPayload: []byte{0x01, 0x02}})
+		assert.NoError(err)
+
+		if assert.Len(ingester.envelopes, 1) {
+			assert.Equal(map[string]any{
+				"f_port":               float64(2),
+				"frm_payload":          "AQI=",
+				PayloadDecoderErrorKey: "payload decoder failed: ReferenceError: temp is not defined",
+			}, ingester.envelopes[0].GetData().AsMap())
+		}
+	})
+
+	t.Run("rejects unknown and malformed device EUIs", func(t *testing.T) {
+		mgr, ingester, _, _ := newTestLoRaWANUplinkManager()
+
+		err := mgr.IngestLoRaWANUplink(context.Background(), LoRaWANUplink{DeviceEui: "70B3D57ED0009999", ReceivedAt: receivedAt})
+		assert.ErrorIs(t, err, ErrEndDeviceNotFound)
+
+		err = mgr.IngestLoRaWANUplink(context.Background(), LoRaWANUplink{DeviceEui: "device-1", ReceivedAt: receivedAt})
+		assert.ErrorIs(t, err, ErrInvalidLoRaWANMessage)
+
+		assert.Empty(t, ingester.envelopes)
+	})
+}
+
+func TestLoRaWANUplinkManager_events(t *testing.T) {
+	receivedAt := time.Date(2025, 11, 12, 9, 0, 0, 0, time.UTC)
+
+	t.Run("joins and downlink acks count as activity", func(t *testing.T) {
+		mgr, _, activity, _ := newTestLoRaWANUplinkManager()
+
+		assert.NoError(t, mgr.RecordLoRaWANJoinAccept(context.Background(), LoRaWANJoinAccept{DeviceEui: "70B3D57ED0001234", ReceivedAt: receivedAt}))
+		assert.NoError(t, mgr.RecordLoRaWANDownlinkAck(context.Background(), LoRaWANDownlinkAck{DeviceEui: "70B3D57ED0001234", ReceivedAt: receivedAt, CorrelationIds: []string{"ponix:downlink:dl-1"}}))
+		assert.Equal(t, []string{"join device-1", "downlink acked [ponix:downlink:dl-1]", "activity device-1"}, activity.calls)
+	})
+
+	t.Run("tracks downlink events", func(t *testing.T) {
+		mgr, _, activity, _ := newTestLoRaWANUplinkManager()
+
+		err := mgr.RecordLoRaWANDownlinkEvent(context.Background(), LoRaWANDownlinkEvent{DeviceEui: "70B3D57ED0001234", ReceivedAt: receivedAt, Status: LoRaWANDownlinkSent, CorrelationIds: []string{"ponix:downlink:dl-1"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"downlink sent [ponix:downlink:dl-1]"}, activity.calls)
+	})
+
+	t.Run("reports solved locations into the twin", func(t *testing.T) {
+		mgr, _, _, reporter := newTestLoRaWANUplinkManager()
+
+		err := mgr.RecordLoRaWANLocation(context.Background(), LoRaWANLocation{DeviceEui: "70B3D57ED0001234", ReceivedAt: receivedAt, Latitude: 52.37, Longitude: 4.89, Source: "SOURCE_GPS"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{LoRaWANTwinLocationKey: map[string]any{
+			"latitude":  52.37,
+			"longitude": 4.89,
+			"altitude":  float64(0),
+			"source":    "SOURCE_GPS",
+			"solved_at": "2025-11-12T09:00:00Z",
+		}}, reporter["device-1"])
+	})
+
+	t.Run("ignores disabled devices", func(t *testing.T) {
+		mgr, _, activity, reporter := newTestLoRaWANUplinkManager()
+
+		err := mgr.RecordLoRaWANJoinAccept(context.Background(), LoRaWANJoinAccept{DeviceEui: "70B3D57ED0005678", ReceivedAt: receivedAt})
+		assert.ErrorIs(t, err, ErrEndDeviceDisabled)
+		err = mgr.RecordLoRaWANLocation(context.Background(), LoRaWANLocation{DeviceEui: "70B3D57ED0005678", ReceivedAt: receivedAt})
+		assert.ErrorIs(t, err, ErrEndDeviceDisabled)
+
+		assert.Empty(t, activity.calls)
+		assert.Empty(t, reporter)
+	})
+}
@@ -0,0 +1,549 @@
+package domain
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	envelopev1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/envelope/v1"
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/ponix-dev/ponix/internal/runner"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	// ErrInvalidModbusConfig is returned when the Modbus configuration of an end device is missing or malformed.
+	ErrInvalidModbusConfig = errors.New("invalid modbus config")
+	// ErrModbusPollFailed is returned when a Modbus end device cannot be reached or its registers cannot be read.
+	ErrModbusPollFailed = errors.New("modbus poll failed")
+)
+
+const (
+	// DefaultModbusPort is the port of Modbus TCP end devices whose host has none.
+	DefaultModbusPort = "502"
+	// DefaultModbusPollInterval is how often a Modbus end device is polled when no interval is configured.
+	DefaultModbusPollInterval = time.Minute
+	// MinModbusPollInterval is the shortest interval a Modbus end device can be polled at.
+	MinModbusPollInterval = time.Second
+	// MaxModbusRegisters is the largest number of registers read from a single Modbus end device.
+	MaxModbusRegisters = 100
+	// maxModbusBackoff is the longest a Modbus end device that cannot be polled waits for its next attempt.
+	maxModbusBackoff = 15 * time.Minute
+	// modbusDeviceRefreshInterval is how often the poller reloads the list of Modbus end devices.
+	modbusDeviceRefreshInterval = time.Minute
+	// maxConcurrentModbusPolls is how many Modbus end devices are polled at the same time.
+	maxConcurrentModbusPolls = 16
+)
+
+// ModbusRegisterTable is the Modbus table a register is read from.
+type ModbusRegisterTable string
+
+const (
+	// ModbusHoldingRegister is read with function code 3.
+	ModbusHoldingRegister ModbusRegisterTable = "holding"
+	// ModbusInputRegister is read with function code 4.
+	ModbusInputRegister ModbusRegisterTable = "input"
+)
+
+// ModbusValueType is how the 16-bit words of a register are decoded. 32-bit values span two registers,
+// high word first.
+type ModbusValueType string
+
+// Supported register types.
+const (
+	ModbusUint16  ModbusValueType = "uint16"
+	ModbusInt16   ModbusValueType = "int16"
+	ModbusUint32  ModbusValueType = "uint32"
+	ModbusInt32   ModbusValueType = "int32"
+	ModbusFloat32 ModbusValueType = "float32"
+)
+
+// modbusRegisterTables maps the register tables of the iot/v1 API to those of the poller. Unspecified tables
+// default to holding registers.
+var modbusRegisterTables = map[iotv1.ModbusRegisterTable]ModbusRegisterTable{
+	iotv1.ModbusRegisterTable_MODBUS_REGISTER_TABLE_HOLDING: ModbusHoldingRegister,
+	iotv1.ModbusRegisterTable_MODBUS_REGISTER_TABLE_INPUT:   ModbusInputRegister,
+}
+
+// modbusValueTypes maps the register types of the iot/v1 API to those of the poller.
+var modbusValueTypes = map[iotv1.ModbusValueType]ModbusValueType{
+	iotv1.ModbusValueType_MODBUS_VALUE_TYPE_UINT16:  ModbusUint16,
+	iotv1.ModbusValueType_MODBUS_VALUE_TYPE_INT16:   ModbusInt16,
+	iotv1.ModbusValueType_MODBUS_VALUE_TYPE_UINT32:  ModbusUint32,
+	iotv1.ModbusValueType_MODBUS_VALUE_TYPE_INT32:   ModbusInt32,
+	iotv1.ModbusValueType_MODBUS_VALUE_TYPE_FLOAT32: ModbusFloat32,
+}
+
+// modbusRegisterTableMessages and modbusValueTypeMessages map the register tables and types of the poller back to
+// those of the iot/v1 API.
+var (
+	modbusRegisterTableMessages = map[ModbusRegisterTable]iotv1.ModbusRegisterTable{
+		ModbusHoldingRegister: iotv1.ModbusRegisterTable_MODBUS_REGISTER_TABLE_HOLDING,
+		ModbusInputRegister:   iotv1.ModbusRegisterTable_MODBUS_REGISTER_TABLE_INPUT,
+	}
+	modbusValueTypeMessages = map[ModbusValueType]iotv1.ModbusValueType{
+		ModbusUint16:  iotv1.ModbusValueType_MODBUS_VALUE_TYPE_UINT16,
+		ModbusInt16:   iotv1.ModbusValueType_MODBUS_VALUE_TYPE_INT16,
+		ModbusUint32:  iotv1.ModbusValueType_MODBUS_VALUE_TYPE_UINT32,
+		ModbusInt32:   iotv1.ModbusValueType_MODBUS_VALUE_TYPE_INT32,
+		ModbusFloat32: iotv1.ModbusValueType_MODBUS_VALUE_TYPE_FLOAT32,
+	}
+)
+
+// ModbusRegister maps a register of a Modbus end device to a field of its data.
+type ModbusRegister struct {
+	// Field is the name the value is stored under.
+	Field string `json:"field"`
+	// Address is the zero-based address of the (first) register.
+	Address uint16 `json:"address"`
+	// Table is the table the register is read from. It defaults to holding registers.
+	Table ModbusRegisterTable `json:"table,omitempty"`
+	// Type is how the register is decoded.
+	Type ModbusValueType `json:"type"`
+	// Scale is multiplied with the decoded value, e.g. 0.1 for a register holding tenths. It defaults to 1.
+	Scale float64 `json:"scale,omitempty"`
+}
+
+// wordCount returns the number of 16-bit registers the value spans.
+func (register ModbusRegister) wordCount() uint16 {
+	switch register.Type {
+	case ModbusUint32, ModbusInt32, ModbusFloat32:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// decode turns the raw big-endian register contents into a scaled value.
+func (register ModbusRegister) decode(data []byte) (float64, error) {
+	if len(data) != 2*int(register.wordCount()) {
+		return 0, stacktrace.NewStackTraceErrorf("%w: %s: read %d bytes, expected %d", ErrModbusPollFailed, register.Field, len(data), 2*register.wordCount())
+	}
+
+	var value float64
+	switch register.Type {
+	case ModbusUint16:
+		value = float64(binary.BigEndian.Uint16(data))
+	case ModbusInt16:
+		value = float64(int16(binary.BigEndian.Uint16(data)))
+	case ModbusUint32:
+		value = float64(binary.BigEndian.Uint32(data))
+	case ModbusInt32:
+		value = float64(int32(binary.BigEndian.Uint32(data)))
+	case ModbusFloat32:
+		value = float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	}
+
+	return value * register.Scale, nil
+}
+
+// ModbusConfig holds where a Modbus TCP end device is reached, how often it is polled and which registers are read.
+type ModbusConfig struct {
+	// Host is the host and port of the device. The port defaults to DefaultModbusPort.
+	Host string
+	// UnitId is the unit (slave) ID of the device, used to address devices behind a gateway.
+	UnitId uint8
+	// PollInterval is how often the device is polled, in whole seconds. It defaults to DefaultModbusPollInterval.
+	PollInterval time.Duration
+	Registers    []ModbusRegister
+}
+
+// Validate checks that the device can be reached and every register is readable and stored under its own field.
+func (config ModbusConfig) Validate() error {
+	_, _, err := net.SplitHostPort(config.Host)
+	if config.Host == "" || err != nil {
+		return stacktrace.NewStackTraceErrorf("%w: host %q must be a host and port", ErrInvalidModbusConfig, config.Host)
+	}
+
+	if config.PollInterval < MinModbusPollInterval {
+		return stacktrace.NewStackTraceErrorf("%w: poll interval must be at least %s", ErrInvalidModbusConfig, MinModbusPollInterval)
+	}
+
+	if len(config.Registers) == 0 || len(config.Registers) > MaxModbusRegisters {
+		return stacktrace.NewStackTraceErrorf("%w: between 1 and %d registers are required", ErrInvalidModbusConfig, MaxModbusRegisters)
+	}
+
+	seen := map[string]bool{}
+	for _, register := range config.Registers {
+		switch {
+		case strings.TrimSpace(register.Field) == "":
+			return stacktrace.NewStackTraceErrorf("%w: register %d has no field", ErrInvalidModbusConfig, register.Address)
+		case seen[register.Field]:
+			return stacktrace.NewStackTraceErrorf("%w: field %q is mapped twice", ErrInvalidModbusConfig, register.Field)
+		case register.Table != ModbusHoldingRegister && register.Table != ModbusInputRegister:
+			return stacktrace.NewStackTraceErrorf("%w: %s: unknown register table %q", ErrInvalidModbusConfig, register.Field, register.Table)
+		case !isModbusValueType(register.Type):
+			return stacktrace.NewStackTraceErrorf("%w: %s: unknown type %q", ErrInvalidModbusConfig, register.Field, register.Type)
+		case math.IsNaN(register.Scale) || math.IsInf(register.Scale, 0) || register.Scale == 0:
+			return stacktrace.NewStackTraceErrorf("%w: %s: scale must be a non-zero number", ErrInvalidModbusConfig, register.Field)
+		case int(register.Address)+int(register.wordCount()) > math.MaxUint16+1:
+			return stacktrace.NewStackTraceErrorf("%w: %s: register %d is out of range", ErrInvalidModbusConfig, register.Field, register.Address)
+		}
+		seen[register.Field] = true
+	}
+
+	return nil
+}
+
+// isModbusValueType reports whether valueType is a supported register type.
+func isModbusValueType(valueType ModbusValueType) bool {
+	switch valueType {
+	case ModbusUint16, ModbusInt16, ModbusUint32, ModbusInt32, ModbusFloat32:
+		return true
+	default:
+		return false
+	}
+}
+
+// withDefaults returns a copy of the configuration with the default port, poll interval, register table and scale
+// filled in. Poll intervals are truncated to whole seconds.
+func (config ModbusConfig) withDefaults() ModbusConfig {
+	defaulted := ModbusConfig{
+		Host:         strings.TrimSpace(config.Host),
+		UnitId:       config.UnitId,
+		PollInterval: config.PollInterval.Truncate(time.Second),
+		Registers:    make([]ModbusRegister, len(config.Registers)),
+	}
+
+	if defaulted.Host != "" {
+		_, _, err := net.SplitHostPort(defaulted.Host)
+		if err != nil {
+			defaulted.Host = net.JoinHostPort(defaulted.Host, DefaultModbusPort)
+		}
+	}
+
+	if config.PollInterval == 0 {
+		defaulted.PollInterval = DefaultModbusPollInterval
+	}
+
+	for i, register := range config.Registers {
+		if register.Table == "" {
+			register.Table = ModbusHoldingRegister
+		}
+		if register.Scale == 0 {
+			register.Scale = 1
+		}
+		defaulted.Registers[i] = register
+	}
+
+	return defaulted
+}
+
+// ModbusConfigFromProto converts the Modbus configuration of an iot/v1 message. Unit IDs and addresses that do not
+// fit Modbus and unknown register types are rejected; unspecified register tables are left to withDefaults.
+func ModbusConfigFromProto(message *iotv1.ModbusConfig) (ModbusConfig, error) {
+	if message.GetUnitId() > math.MaxUint8 {
+		return ModbusConfig{}, stacktrace.NewStackTraceErrorf("%w: unit ID must be between 0 and 255", ErrInvalidModbusConfig)
+	}
+
+	config := ModbusConfig{
+		Host:         message.GetHost(),
+		UnitId:       uint8(message.GetUnitId()),
+		PollInterval: message.GetPollInterval().AsDuration(),
+		Registers:    make([]ModbusRegister, 0, len(message.GetRegisters())),
+	}
+
+	for _, register := range message.GetRegisters() {
+		if register.GetAddress() > math.MaxUint16 {
+			return ModbusConfig{}, stacktrace.NewStackTraceErrorf("%w: %s: register %d is out of range", ErrInvalidModbusConfig, register.GetField(), register.GetAddress())
+		}
+
+		table, ok := modbusRegisterTables[register.GetTable()]
+		if !ok && register.GetTable() != iotv1.ModbusRegisterTable_MODBUS_REGISTER_TABLE_UNSPECIFIED {
+			return ModbusConfig{}, stacktrace.NewStackTraceErrorf("%w: %s: unknown register table %v", ErrInvalidModbusConfig, register.GetField(), register.GetTable())
+		}
+
+		valueType, ok := modbusValueTypes[register.GetType()]
+		if !ok {
+			return ModbusConfig{}, stacktrace.NewStackTraceErrorf("%w: %s: unknown type %v", ErrInvalidModbusConfig, register.GetField(), register.GetType())
+		}
+
+		config.Registers = append(config.Registers, ModbusRegister{
+			Field:   register.GetField(),
+			Address: uint16(register.GetAddress()),
+			Table:   table,
+			Type:    valueType,
+			Scale:   register.GetScale(),
+		})
+	}
+
+	return config, nil
+}
+
+// ToProto converts the configuration to its iot/v1 message.
+func (config ModbusConfig) ToProto() *iotv1.ModbusConfig {
+	registers := make([]*iotv1.ModbusRegister, 0, len(config.Registers))
+	for _, register := range config.Registers {
+		registers = append(registers, iotv1.ModbusRegister_builder{
+			Field:   register.Field,
+			Address: uint32(register.Address),
+			Table:   modbusRegisterTableMessages[register.Table],
+			Type:    modbusValueTypeMessages[register.Type],
+			Scale:   register.Scale,
+		}.Build())
+	}
+
+	return iotv1.ModbusConfig_builder{
+		Host:         config.Host,
+		UnitId:       uint32(config.UnitId),
+		PollInterval: durationpb.New(config.PollInterval),
+		Registers:    registers,
+	}.Build()
+}
+
+// newEndDeviceModbusConfig checks the Modbus configuration sent with a new end device and fills in its defaults.
+// Devices of other hardware types must not carry a Modbus configuration; nil is returned for them.
+func newEndDeviceModbusConfig(endDevice *iotv1.EndDevice, message *iotv1.ModbusConfig) (*iotv1.ModbusConfig, error) {
+	if endDevice.GetHardwareType() != iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_MODBUS {
+		if message != nil {
+			return nil, stacktrace.NewStackTraceErrorf("%w: only Modbus end devices have a Modbus configuration", ErrInvalidModbusConfig)
+		}
+		return nil, nil
+	}
+
+	if message == nil {
+		return nil, stacktrace.NewStackTraceErrorf("%w: Modbus end devices require a host and registers", ErrInvalidModbusConfig)
+	}
+
+	config, err := ModbusConfigFromProto(message)
+	if err != nil {
+		return nil, err
+	}
+
+	defaulted := config.withDefaults()
+
+	err = defaulted.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return defaulted.ToProto(), nil
+}
+
+// ModbusEndDevice is a Modbus end device to poll.
+type ModbusEndDevice struct {
+	EndDeviceId    string
+	OrganizationId string
+	Status         iotv1.EndDeviceStatus
+	Config         ModbusConfig
+}
+
+// ModbusEndDeviceLister lists the Modbus end devices to poll.
+type ModbusEndDeviceLister interface {
+	ListModbusEndDevices(ctx context.Context) ([]*ModbusEndDevice, error)
+}
+
+// ModbusConnection reads registers from a connected Modbus end device.
+type ModbusConnection interface {
+	ReadRegisters(table ModbusRegisterTable, address uint16, quantity uint16) ([]byte, error)
+	Close() error
+}
+
+// ModbusDialer connects to Modbus end devices.
+type ModbusDialer interface {
+	DialModbus(ctx context.Context, host string, unitId uint8) (ModbusConnection, error)
+}
+
+// modbusPollSchedule tracks when a Modbus end device is polled next and how many polls in a row failed.
+type modbusPollSchedule struct {
+	nextPollAt time.Time
+	failures   int
+}
+
+// ModbusPoller reads the registers of Modbus end devices at their poll interval and ingests them as data envelopes.
+// Devices that cannot be polled are retried with exponential backoff so unreachable devices do not hold up others.
+type ModbusPoller struct {
+	lister    ModbusEndDeviceLister
+	dialer    ModbusDialer
+	envelopes DataEnvelopeIngester
+
+	devices     []*ModbusEndDevice
+	refreshedAt time.Time
+	schedules   map[string]*modbusPollSchedule
+}
+
+// NewModbusPoller creates a new ModbusPoller.
+func NewModbusPoller(lister ModbusEndDeviceLister, dialer ModbusDialer, envelopes DataEnvelopeIngester) *ModbusPoller {
+	return &ModbusPoller{
+		lister:    lister,
+		dialer:    dialer,
+		envelopes: envelopes,
+		schedules: map[string]*modbusPollSchedule{},
+	}
+}
+
+// PollDueModbusEndDevices polls every Modbus end device whose next poll is due and returns how many were polled.
+// Disabled devices are skipped. New devices are picked up within a minute and polled right away.
+// It is not safe for concurrent use.
+func (poller *ModbusPoller) PollDueModbusEndDevices(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "PollDueModbusEndDevices")
+	defer span.End()
+
+	if poller.devices == nil || now.Sub(poller.refreshedAt) >= modbusDeviceRefreshInterval {
+		err := poller.refresh(ctx, now)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	due := make([]*ModbusEndDevice, 0, len(poller.devices))
+	for _, device := range poller.devices {
+		if device.Status == iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED {
+			continue
+		}
+		if now.Before(poller.schedules[device.EndDeviceId].nextPollAt) {
+			continue
+		}
+		due = append(due, device)
+	}
+
+	results := make([]error, len(due))
+	group := errgroup.Group{}
+	group.SetLimit(maxConcurrentModbusPolls)
+	for i, device := range due {
+		group.Go(func() error {
+			results[i] = poller.pollEndDevice(ctx, device, now)
+			return nil
+		})
+	}
+	_ = group.Wait()
+
+	for i, device := range due {
+		schedule := poller.schedules[device.EndDeviceId]
+		err := results[i]
+		if errors.Is(err, ErrModbusPollFailed) {
+			schedule.failures++
+			schedule.nextPollAt = now.Add(modbusRetryDelay(device.Config.PollInterval, schedule.failures))
+			slog.Warn(
+				"failed to poll modbus end device",
+				slog.String("end_device_id", device.EndDeviceId),
+				slog.Int("failures", schedule.failures),
+				slog.Time("next_poll_at", schedule.nextPollAt),
+				stacktrace.ErrorAttribute(err),
+			)
+			continue
+		}
+
+		if err != nil {
+			// The device answered; only storing its data failed, so it keeps its regular schedule
+			slog.Error("failed to ingest modbus end device data", slog.String("end_device_id", device.EndDeviceId), stacktrace.ErrorAttribute(err))
+		}
+		schedule.failures = 0
+		schedule.nextPollAt = now.Add(device.Config.PollInterval)
+	}
+
+	return len(due), nil
+}
+
+// refresh reloads the Modbus end devices, keeping the schedules of devices that are still present.
+func (poller *ModbusPoller) refresh(ctx context.Context, now time.Time) error {
+	devices, err := poller.lister.ListModbusEndDevices(ctx)
+	if err != nil {
+		return err
+	}
+
+	schedules := make(map[string]*modbusPollSchedule, len(devices))
+	for _, device := range devices {
+		schedule, ok := poller.schedules[device.EndDeviceId]
+		if !ok {
+			schedule = &modbusPollSchedule{nextPollAt: now}
+		}
+		schedules[device.EndDeviceId] = schedule
+	}
+
+	poller.devices = devices
+	poller.schedules = schedules
+	poller.refreshedAt = now
+
+	return nil
+}
+
+// pollEndDevice reads all registers of a Modbus end device and ingests them as one data envelope occurring at now.
+// Failures to reach the device or read its registers are reported as ErrModbusPollFailed.
+func (poller *ModbusPoller) pollEndDevice(ctx context.Context, device *ModbusEndDevice, now time.Time) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "pollModbusEndDevice")
+	defer span.End()
+
+	conn, err := poller.dialer.DialModbus(ctx, device.Config.Host, device.Config.UnitId)
+	if err != nil {
+		return stacktrace.NewStackTraceErrorf("%w: %s: %w", ErrModbusPollFailed, device.Config.Host, err)
+	}
+	defer conn.Close()
+
+	fields := make(map[string]any, len(device.Config.Registers))
+	for _, register := range device.Config.Registers {
+		raw, err := conn.ReadRegisters(register.Table, register.Address, register.wordCount())
+		if err != nil {
+			return stacktrace.NewStackTraceErrorf("%w: reading %s: %w", ErrModbusPollFailed, register.Field, err)
+		}
+
+		value, err := register.decode(raw)
+		if err != nil {
+			return err
+		}
+		fields[register.Field] = value
+	}
+
+	data, err := structpb.NewStruct(fields)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	envelope := envelopev1.DataEnvelope_builder{
+		EndDeviceId: device.EndDeviceId,
+		OccurredAt:  timestamppb.New(now),
+		Data:        data,
+	}.Build()
+
+	return poller.envelopes.IngestDataEnvelope(ctx, envelope, device.OrganizationId)
+}
+
+// modbusRetryDelay returns how long a Modbus end device waits after the given number of failed polls in a row.
+// The poll interval doubles with every failure up to maxModbusBackoff, but never drops below the interval itself.
+func modbusRetryDelay(pollInterval time.Duration, failures int) time.Duration {
+	if pollInterval >= maxModbusBackoff {
+		return pollInterval
+	}
+
+	delay := pollInterval
+	for range failures {
+		delay *= 2
+		if delay >= maxModbusBackoff {
+			return maxModbusBackoff
+		}
+	}
+
+	return delay
+}
+
+// ModbusPollRunner returns a runner function that polls due Modbus end devices on every tick.
+func ModbusPollRunner(poller *ModbusPoller, tick time.Duration) runner.RunnerFunc {
+	return func(ctx context.Context) func() error {
+		return func() error {
+			ticker := time.NewTicker(tick)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case now := <-ticker.C:
+					_, err := poller.PollDueModbusEndDevices(ctx, now.UTC())
+					if err != nil {
+						slog.Error("failed to poll modbus end devices", stacktrace.ErrorAttribute(err))
+					}
+				}
+			}
+		}
+	}
+}
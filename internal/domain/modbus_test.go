@@ -0,0 +1,196 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestNewEndDeviceModbusConfig(t *testing.T) {
+	modbusDevice := iotv1.EndDevice_builder{Id: "meter-1", HardwareType: iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_MODBUS}.Build()
+
+	t.Run("fills in defaults", func(t *testing.T) {
+		assert := assert.New(t)
+
+		config, err := newEndDeviceModbusConfig(modbusDevice, iotv1.ModbusConfig_builder{
+			Host:         "10.0.0.5",
+			UnitId:       3,
+			PollInterval: durationpb.New(1500 * time.Millisecond),
+			Registers: []*iotv1.ModbusRegister{iotv1.ModbusRegister_builder{
+				Field:   "energy",
+				Address: 100,
+				Type:    iotv1.ModbusValueType_MODBUS_VALUE_TYPE_UINT32,
+			}.Build()},
+		}.Build())
+		assert.NoError(err)
+		assert.Equal("10.0.0.5:502", config.GetHost())
+		assert.Equal(uint32(3), config.GetUnitId())
+		assert.Equal(time.Second, config.GetPollInterval().AsDuration())
+		assert.Len(config.GetRegisters(), 1)
+		assert.Equal(uint32(100), config.GetRegisters()[0].GetAddress())
+		assert.Equal(iotv1.ModbusRegisterTable_MODBUS_REGISTER_TABLE_HOLDING, config.GetRegisters()[0].GetTable())
+		assert.Equal(iotv1.ModbusValueType_MODBUS_VALUE_TYPE_UINT32, config.GetRegisters()[0].GetType())
+		assert.Equal(1.0, config.GetRegisters()[0].GetScale())
+
+		config, err = newEndDeviceModbusConfig(modbusDevice, iotv1.ModbusConfig_builder{
+			Host: "plc.local:1502",
+			Registers: []*iotv1.ModbusRegister{iotv1.ModbusRegister_builder{
+				Field: "level",
+				Table: iotv1.ModbusRegisterTable_MODBUS_REGISTER_TABLE_INPUT,
+				Type:  iotv1.ModbusValueType_MODBUS_VALUE_TYPE_INT16,
+				Scale: 0.1,
+			}.Build()},
+		}.Build())
+		assert.NoError(err)
+		assert.Equal("plc.local:1502", config.GetHost())
+		assert.Equal(DefaultModbusPollInterval, config.GetPollInterval().AsDuration())
+		assert.Equal(iotv1.ModbusRegisterTable_MODBUS_REGISTER_TABLE_INPUT, config.GetRegisters()[0].GetTable())
+		assert.Equal(0.1, config.GetRegisters()[0].GetScale())
+	})
+
+	t.Run("rejects invalid configs", func(t *testing.T) {
+		register := func(field string, address uint32, table iotv1.ModbusRegisterTable, valueType iotv1.ModbusValueType) *iotv1.ModbusRegister {
+			return iotv1.ModbusRegister_builder{Field: field, Address: address, Table: table, Type: valueType}.Build()
+		}
+		energy := register("energy", 0, 0, iotv1.ModbusValueType_MODBUS_VALUE_TYPE_UINT16)
+
+		for name, config := range map[string]*iotv1.ModbusConfig{
+			"missing config":    nil,
+			"missing host":      iotv1.ModbusConfig_builder{Registers: []*iotv1.ModbusRegister{energy}}.Build(),
+			"unit ID too large": iotv1.ModbusConfig_builder{Host: "meter", UnitId: 256, Registers: []*iotv1.ModbusRegister{energy}}.Build(),
+			"short interval":    iotv1.ModbusConfig_builder{Host: "meter", PollInterval: durationpb.New(500 * time.Millisecond), Registers: []*iotv1.ModbusRegister{energy}}.Build(),
+			"no registers":      iotv1.ModbusConfig_builder{Host: "meter"}.Build(),
+			"missing field":     iotv1.ModbusConfig_builder{Host: "meter", Registers: []*iotv1.ModbusRegister{register("", 0, 0, iotv1.ModbusValueType_MODBUS_VALUE_TYPE_UINT16)}}.Build(),
+			"duplicate field":   iotv1.ModbusConfig_builder{Host: "meter", Registers: []*iotv1.ModbusRegister{energy, energy}}.Build(),
+			"unknown table":     iotv1.ModbusConfig_builder{Host: "meter", Registers: []*iotv1.ModbusRegister{register("energy", 0, 9, iotv1.ModbusValueType_MODBUS_VALUE_TYPE_UINT16)}}.Build(),
+			"unspecified type":  iotv1.ModbusConfig_builder{Host: "meter", Registers: []*iotv1.ModbusRegister{register("energy", 0, 0, 0)}}.Build(),
+			"address too large": iotv1.ModbusConfig_builder{Host: "meter", Registers: []*iotv1.ModbusRegister{register("energy", 70000, 0, iotv1.ModbusValueType_MODBUS_VALUE_TYPE_UINT16)}}.Build(),
+			"out of range type": iotv1.ModbusConfig_builder{Host: "meter", Registers: []*iotv1.ModbusRegister{register("energy", 65535, 0, iotv1.ModbusValueType_MODBUS_VALUE_TYPE_FLOAT32)}}.Build(),
+		} {
+			_, err := newEndDeviceModbusConfig(modbusDevice, config)
+			assert.ErrorIs(t, err, ErrInvalidModbusConfig, name)
+		}
+	})
+
+	t.Run("only Modbus devices have a config", func(t *testing.T) {
+		httpDevice := iotv1.EndDevice_builder{Id: "device-2", HardwareType: iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_HTTP}.Build()
+
+		_, err := newEndDeviceModbusConfig(httpDevice, iotv1.ModbusConfig_builder{Host: "meter"}.Build())
+		assert.ErrorIs(t, err, ErrInvalidModbusConfig)
+	})
+}
+
+func TestModbusRegister_decode(t *testing.T) {
+	for _, tc := range []struct {
+		register ModbusRegister
+		data     []byte
+		expected float64
+	}{
+		{ModbusRegister{Type: ModbusUint16, Scale: 1}, []byte{0xFF, 0xFE}, 65534},
+		{ModbusRegister{Type: ModbusInt16, Scale: 0.1}, []byte{0xFF, 0xFE}, -0.2},
+		{ModbusRegister{Type: ModbusUint32, Scale: 1}, []byte{0x00, 0x01, 0x00, 0x00}, 65536},
+		{ModbusRegister{Type: ModbusInt32, Scale: 2}, []byte{0xFF, 0xFF, 0xFF, 0xFF}, -2},
+		{ModbusRegister{Type: ModbusFloat32, Scale: 1}, []byte{0x41, 0xAC, 0x00, 0x00}, 21.5},
+	} {
+		value, err := tc.register.decode(tc.data)
+		assert.NoError(t, err, tc.register.Type)
+		assert.InDelta(t, tc.expected, value, 1e-9, tc.register.Type)
+	}
+
+	_, err := ModbusRegister{Type: ModbusUint32, Scale: 1}.decode([]byte{0x00, 0x01})
+	assert.ErrorIs(t, err, ErrModbusPollFailed)
+}
+
+func TestModbusRetryDelay(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(time.Minute, modbusRetryDelay(time.Minute, 0))
+	assert.Equal(2*time.Minute, modbusRetryDelay(time.Minute, 1))
+	assert.Equal(8*time.Minute, modbusRetryDelay(time.Minute, 3))
+	assert.Equal(maxModbusBackoff, modbusRetryDelay(time.Minute, 4))
+	assert.Equal(maxModbusBackoff, modbusRetryDelay(time.Minute, 100))
+	assert.Equal(time.Hour, modbusRetryDelay(time.Hour, 5))
+}
+
+// fakeModbusEndDeviceLister lists a fixed set of Modbus end devices.
+type fakeModbusEndDeviceLister []*ModbusEndDevice
+
+func (lister fakeModbusEndDeviceLister) ListModbusEndDevices(_ context.Context) ([]*ModbusEndDevice, error) {
+	return lister, nil
+}
+
+// fakeModbusDialer connects to simulated devices holding fixed registers. Hosts without registers are unreachable.
+type fakeModbusDialer map[string]map[uint16][]byte
+
+func (dialer fakeModbusDialer) DialModbus(_ context.Context, host string, _ uint8) (ModbusConnection, error) {
+	registers, ok := dialer[host]
+	if !ok {
+		return nil, errors.New("connection refused")
+	}
+	return fakeModbusConnection(registers), nil
+}
+
+// fakeModbusConnection returns the registers of a simulated device by address.
+type fakeModbusConnection map[uint16][]byte
+
+func (conn fakeModbusConnection) ReadRegisters(_ ModbusRegisterTable, address uint16, _ uint16) ([]byte, error) {
+	return conn[address], nil
+}
+
+func (conn fakeModbusConnection) Close() error {
+	return nil
+}
+
+func TestModbusPoller_PollDueModbusEndDevices(t *testing.T) {
+	now := time.Date(2025, 11, 10, 12, 0, 0, 0, time.UTC)
+	registers := []ModbusRegister{
+		{Field: "energy", Address: 0, Table: ModbusHoldingRegister, Type: ModbusUint32, Scale: 1},
+		{Field: "temperature", Address: 2, Table: ModbusHoldingRegister, Type: ModbusInt16, Scale: 0.1},
+	}
+	lister := fakeModbusEndDeviceLister{
+		{EndDeviceId: "meter-1", OrganizationId: "org-1", Status: iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE, Config: ModbusConfig{Host: "meter-1:502", PollInterval: time.Minute, Registers: registers}},
+		{EndDeviceId: "meter-2", OrganizationId: "org-1", Status: iotv1.EndDeviceStatus_END_DEVICE_STATUS_ACTIVE, Config: ModbusConfig{Host: "meter-2:502", PollInterval: time.Minute, Registers: registers}},
+		{EndDeviceId: "meter-3", OrganizationId: "org-1", Status: iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED, Config: ModbusConfig{Host: "meter-1:502", PollInterval: time.Minute, Registers: registers}},
+	}
+	dialer := fakeModbusDialer{"meter-1:502": {0: {0x00, 0x00, 0x04, 0xD2}, 2: {0x00, 0xD7}}}
+	ingester := &recordingEnvelopeIngester{}
+	poller := NewModbusPoller(lister, dialer, ingester)
+
+	t.Run("polls reachable devices and backs off unreachable ones", func(t *testing.T) {
+		assert := assert.New(t)
+
+		polled, err := poller.PollDueModbusEndDevices(context.Background(), now)
+		assert.NoError(err)
+		assert.Equal(2, polled)
+
+		if !assert.Len(ingester.envelopes, 1) {
+			return
+		}
+		envelope := ingester.envelopes[0]
+		assert.Equal("meter-1", envelope.GetEndDeviceId())
+		assert.Equal("org-1", ingester.organizations[0])
+		assert.Equal(now, envelope.GetOccurredAt().AsTime())
+		assert.Equal(1234.0, envelope.GetData().AsMap()["energy"])
+		assert.InDelta(21.5, envelope.GetData().AsMap()["temperature"], 1e-9)
+
+		assert.Equal(now.Add(time.Minute), poller.schedules["meter-1"].nextPollAt)
+		assert.Equal(now.Add(2*time.Minute), poller.schedules["meter-2"].nextPollAt)
+		assert.Equal(1, poller.schedules["meter-2"].failures)
+	})
+
+	t.Run("waits for the next poll", func(t *testing.T) {
+		polled, err := poller.PollDueModbusEndDevices(context.Background(), now.Add(30*time.Second))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, polled)
+
+		polled, err = poller.PollDueModbusEndDevices(context.Background(), now.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, polled)
+		assert.Len(t, ingester.envelopes, 2)
+	})
+}
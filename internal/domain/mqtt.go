@@ -0,0 +1,220 @@
+package domain
+
+import (
+	"context"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	envelopev1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/envelope/v1"
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	// ErrInvalidMQTTConfig is returned when the MQTT configuration of an end device is missing or malformed.
+	ErrInvalidMQTTConfig = errors.New("invalid mqtt config")
+	// ErrMQTTConfigInUse is returned when the MQTT topic or username of an end device is already taken by another device.
+	ErrMQTTConfigInUse = errors.New("mqtt topic or username in use")
+	// ErrInvalidMQTTPayload is returned when an MQTT message does not carry a JSON object.
+	ErrInvalidMQTTPayload = errors.New("invalid mqtt payload")
+)
+
+const (
+	// MinMQTTPasswordLength is the shortest password accepted for an MQTT end device.
+	MinMQTTPasswordLength = 12
+	// MaxMQTTUsernameLength is the longest username accepted for an MQTT end device.
+	MaxMQTTUsernameLength = 255
+	// maxMQTTTopicLength is the longest topic name the MQTT protocol can carry.
+	maxMQTTTopicLength = 65535
+
+	mqttPasswordIterations = 100000
+	mqttPasswordSaltBytes  = 16
+	mqttPasswordKeyBytes   = 64
+)
+
+// MQTTConfig holds the topic an MQTT end device being created publishes its data to and the credentials it connects to
+// the broker with. The password is stored as a PBKDF2 hash in the format of mosquitto-go-auth, in the password_hash
+// of the device's iot/v1 MQTTConfig, so the broker can check the device's credentials against the database.
+type MQTTConfig struct {
+	// Topic is the topic name the device publishes to. It defaults to ponix/<end device id>/up.
+	Topic string
+	// Username is the name the device authenticates with. It defaults to the end device ID.
+	Username string
+	// Password is the plaintext password of the device. It is hashed before it is stored and never read back.
+	Password string
+}
+
+// DefaultMQTTTopic returns the topic an MQTT end device publishes to when none is configured.
+func DefaultMQTTTopic(endDeviceId string) string {
+	return fmt.Sprintf("ponix/%s/up", endDeviceId)
+}
+
+// Validate checks that the topic is a valid topic name without wildcards and that the credentials are usable.
+func (config MQTTConfig) Validate() error {
+	switch {
+	case config.Topic == "":
+		return stacktrace.NewStackTraceErrorf("%w: topic is required", ErrInvalidMQTTConfig)
+	case len(config.Topic) > maxMQTTTopicLength:
+		return stacktrace.NewStackTraceErrorf("%w: topic is longer than %d bytes", ErrInvalidMQTTConfig, maxMQTTTopicLength)
+	case strings.ContainsAny(config.Topic, "+#\x00"):
+		return stacktrace.NewStackTraceErrorf("%w: topic %q contains wildcards", ErrInvalidMQTTConfig, config.Topic)
+	case strings.HasPrefix(config.Topic, "$"):
+		return stacktrace.NewStackTraceErrorf("%w: topic %q is reserved for the broker", ErrInvalidMQTTConfig, config.Topic)
+	case config.Username == "":
+		return stacktrace.NewStackTraceErrorf("%w: username is required", ErrInvalidMQTTConfig)
+	case len(config.Username) > MaxMQTTUsernameLength:
+		return stacktrace.NewStackTraceErrorf("%w: username is longer than %d characters", ErrInvalidMQTTConfig, MaxMQTTUsernameLength)
+	case len(config.Password) < MinMQTTPasswordLength:
+		return stacktrace.NewStackTraceErrorf("%w: password must be at least %d characters", ErrInvalidMQTTConfig, MinMQTTPasswordLength)
+	}
+
+	return nil
+}
+
+// newEndDeviceMQTTConfig checks the MQTT configuration sent with a new end device and prepares it for storage.
+// Missing topics and usernames get their defaults and the password is replaced by its hash.
+// Devices of other hardware types must not carry an MQTT configuration; nil is returned for them.
+func newEndDeviceMQTTConfig(endDevice *iotv1.EndDevice, message *iotv1.MQTTConfig) (*iotv1.MQTTConfig, error) {
+	if endDevice.GetHardwareType() != iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_MQTT {
+		if message != nil {
+			return nil, stacktrace.NewStackTraceErrorf("%w: only MQTT end devices have an MQTT configuration", ErrInvalidMQTTConfig)
+		}
+		return nil, nil
+	}
+
+	if message == nil {
+		return nil, stacktrace.NewStackTraceErrorf("%w: MQTT end devices require a password", ErrInvalidMQTTConfig)
+	}
+
+	config := MQTTConfig{
+		Topic:    strings.TrimSpace(message.GetTopic()),
+		Username: strings.TrimSpace(message.GetUsername()),
+		Password: message.GetPassword(),
+	}
+	if config.Topic == "" {
+		config.Topic = DefaultMQTTTopic(endDevice.GetId())
+	}
+	if config.Username == "" {
+		config.Username = endDevice.GetId()
+	}
+
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := hashMQTTPassword(config.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	return iotv1.MQTTConfig_builder{
+		Topic:        config.Topic,
+		Username:     config.Username,
+		PasswordHash: passwordHash,
+	}.Build(), nil
+}
+
+// hashMQTTPassword hashes a password with PBKDF2-SHA512 and a random salt,
+// formatted as PBKDF2$sha512$<iterations>$<base64 salt>$<base64 key>.
+func hashMQTTPassword(password string) (string, error) {
+	salt := make([]byte, mqttPasswordSaltBytes)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", stacktrace.NewStackTraceError(err)
+	}
+
+	key, err := pbkdf2.Key(sha512.New, password, salt, mqttPasswordIterations, mqttPasswordKeyBytes)
+	if err != nil {
+		return "", stacktrace.NewStackTraceError(err)
+	}
+
+	return fmt.Sprintf(
+		"PBKDF2$sha512$%d$%s$%s",
+		mqttPasswordIterations,
+		base64.StdEncoding.EncodeToString(salt),
+		base64.StdEncoding.EncodeToString(key),
+	), nil
+}
+
+// MQTTTopicResolver finds the end device that publishes to an MQTT topic.
+type MQTTTopicResolver interface {
+	// GetEndDeviceByMQTTTopic returns the ID and organization of the end device publishing to topic,
+	// or ErrEndDeviceNotFound when no device uses it.
+	GetEndDeviceByMQTTTopic(ctx context.Context, topic string) (string, string, error)
+}
+
+// DataEnvelopeIngester stores the data an end device sent.
+type DataEnvelopeIngester interface {
+	IngestDataEnvelope(ctx context.Context, envelope *envelopev1.DataEnvelope, organizationID string) error
+}
+
+// MQTTUplinkManager turns messages received from an MQTT broker into data envelopes of the end devices that published them.
+type MQTTUplinkManager struct {
+	resolver  MQTTTopicResolver
+	envelopes DataEnvelopeIngester
+}
+
+// NewMQTTUplinkManager creates a new MQTTUplinkManager.
+func NewMQTTUplinkManager(resolver MQTTTopicResolver, envelopes DataEnvelopeIngester) *MQTTUplinkManager {
+	return &MQTTUplinkManager{
+		resolver:  resolver,
+		envelopes: envelopes,
+	}
+}
+
+// IngestMQTTMessage ingests a message published to topic. The payload must be a JSON object; it is stored as the data of
+// the end device that publishes to the topic, occurring at receivedAt. Messages on topics no device publishes to are
+// reported as ErrEndDeviceNotFound and data of disabled devices as ErrEndDeviceDisabled.
+func (mgr *MQTTUplinkManager) IngestMQTTMessage(ctx context.Context, topic string, payload []byte, receivedAt time.Time) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "IngestMQTTMessage")
+	defer span.End()
+
+	endDeviceId, organizationId, err := mgr.resolver.GetEndDeviceByMQTTTopic(ctx, topic)
+	if err != nil {
+		return err
+	}
+
+	data, err := mqttPayloadData(payload)
+	if err != nil {
+		return err
+	}
+
+	envelope := envelopev1.DataEnvelope_builder{
+		EndDeviceId: endDeviceId,
+		OccurredAt:  timestamppb.New(receivedAt),
+		Data:        data,
+	}.Build()
+
+	return mgr.envelopes.IngestDataEnvelope(ctx, envelope, organizationId)
+}
+
+// mqttPayloadData decodes the JSON object an MQTT end device published.
+func mqttPayloadData(payload []byte) (*structpb.Struct, error) {
+	var document map[string]any
+	err := json.Unmarshal(payload, &document)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %w", ErrInvalidMQTTPayload, err)
+	}
+
+	if document == nil {
+		return nil, stacktrace.NewStackTraceErrorf("%w: payload must be a JSON object", ErrInvalidMQTTPayload)
+	}
+
+	data, err := structpb.NewStruct(document)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %w", ErrInvalidMQTTPayload, err)
+	}
+
+	return data, nil
+}
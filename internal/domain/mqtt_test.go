@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"context"
+	"crypto/pbkdf2"
+	"crypto/sha512"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	envelopev1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/envelope/v1"
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEndDeviceMQTTConfig(t *testing.T) {
+	mqttDevice := iotv1.EndDevice_builder{Id: "device-1", HardwareType: iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_MQTT}.Build()
+	httpDevice := iotv1.EndDevice_builder{Id: "device-2", HardwareType: iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_HTTP}.Build()
+
+	t.Run("fills in defaults and hashes the password", func(t *testing.T) {
+		assert := assert.New(t)
+
+		config, err := newEndDeviceMQTTConfig(mqttDevice, iotv1.MQTTConfig_builder{Password: "correct horse battery"}.Build())
+		assert.NoError(err)
+		assert.Equal("ponix/device-1/up", config.GetTopic())
+		assert.Equal("device-1", config.GetUsername())
+		assert.Empty(config.GetPassword())
+		assert.True(strings.HasPrefix(config.GetPasswordHash(), "PBKDF2$sha512$100000$"), config.GetPasswordHash())
+	})
+
+	t.Run("keeps the given topic and username", func(t *testing.T) {
+		config, err := newEndDeviceMQTTConfig(mqttDevice, iotv1.MQTTConfig_builder{Topic: " plant/line-1/press ", Username: "press-1", Password: "correct horse battery"}.Build())
+
+		assert.NoError(t, err)
+		assert.Equal(t, "plant/line-1/press", config.GetTopic())
+		assert.Equal(t, "press-1", config.GetUsername())
+	})
+
+	t.Run("rejects invalid configs", func(t *testing.T) {
+		for name, config := range map[string]*iotv1.MQTTConfig{
+			"missing config":      nil,
+			"short password":      iotv1.MQTTConfig_builder{Password: "secret"}.Build(),
+			"wildcard topic":      iotv1.MQTTConfig_builder{Topic: "plant/+/press", Password: "correct horse battery"}.Build(),
+			"multi-level topic":   iotv1.MQTTConfig_builder{Topic: "plant/#", Password: "correct horse battery"}.Build(),
+			"broker topic":        iotv1.MQTTConfig_builder{Topic: "$SYS/broker", Password: "correct horse battery"}.Build(),
+			"overlong username":   iotv1.MQTTConfig_builder{Username: strings.Repeat("u", MaxMQTTUsernameLength+1), Password: "correct horse battery"}.Build(),
+			"whitespace password": iotv1.MQTTConfig_builder{Password: "  "}.Build(),
+		} {
+			_, err := newEndDeviceMQTTConfig(mqttDevice, config)
+			assert.ErrorIs(t, err, ErrInvalidMQTTConfig, name)
+		}
+	})
+
+	t.Run("only MQTT devices have a config", func(t *testing.T) {
+		config, err := newEndDeviceMQTTConfig(httpDevice, nil)
+		assert.NoError(t, err)
+		assert.Nil(t, config)
+
+		_, err = newEndDeviceMQTTConfig(httpDevice, iotv1.MQTTConfig_builder{Password: "correct horse battery"}.Build())
+		assert.ErrorIs(t, err, ErrInvalidMQTTConfig)
+	})
+}
+
+func TestHashMQTTPassword(t *testing.T) {
+	assert := assert.New(t)
+
+	hash, err := hashMQTTPassword("correct horse battery")
+	assert.NoError(err)
+
+	parts := strings.Split(hash, "$")
+	if !assert.Len(parts, 5) {
+		return
+	}
+	assert.Equal([]string{"PBKDF2", "sha512", "100000"}, parts[:3])
+
+	salt, err := base64.StdEncoding.DecodeString(parts[3])
+	assert.NoError(err)
+	key, err := pbkdf2.Key(sha512.New, "correct horse battery", salt, mqttPasswordIterations, mqttPasswordKeyBytes)
+	assert.NoError(err)
+	assert.Equal(base64.StdEncoding.EncodeToString(key), parts[4])
+
+	other, err := hashMQTTPassword("correct horse battery")
+	assert.NoError(err)
+	assert.NotEqual(hash, other, "hashes are salted")
+}
+
+// fakeMQTTTopicResolver resolves the topics of a fixed set of end devices.
+type fakeMQTTTopicResolver map[string][2]string
+
+func (resolver fakeMQTTTopicResolver) GetEndDeviceByMQTTTopic(_ context.Context, topic string) (string, string, error) {
+	device, ok := resolver[topic]
+	if !ok {
+		return "", "", ErrEndDeviceNotFound
+	}
+	return device[0], device[1], nil
+}
+
+// recordingEnvelopeIngester keeps the envelopes it is given.
+type recordingEnvelopeIngester struct {
+	envelopes     []*envelopev1.DataEnvelope
+	organizations []string
+}
+
+func (ingester *recordingEnvelopeIngester) IngestDataEnvelope(_ context.Context, envelope *envelopev1.DataEnvelope, organizationID string) error {
+	ingester.envelopes = append(ingester.envelopes, envelope)
+	ingester.organizations = append(ingester.organizations, organizationID)
+	return nil
+}
+
+func TestMQTTUplinkManager_IngestMQTTMessage(t *testing.T) {
+	receivedAt := time.Date(2025, 11, 10, 9, 0, 0, 0, time.UTC)
+	resolver := fakeMQTTTopicResolver{"ponix/device-1/up": {"device-1", "org-1"}}
+
+	t.Run("ingests JSON objects as the device's data", func(t *testing.T) {
+		assert := assert.New(t)
+		ingester := &recordingEnvelopeIngester{}
+		mgr := NewMQTTUplinkManager(resolver, ingester)
+
+		err := mgr.IngestMQTTMessage(context.Background(), "ponix/device-1/up", []byte(`{"temperature":21.5,"door":"open"}`), receivedAt)
+		assert.NoError(err)
+
+		if !assert.Len(ingester.envelopes, 1) {
+			return
+		}
+		envelope := ingester.envelopes[0]
+		assert.Equal("device-1", envelope.GetEndDeviceId())
+		assert.Equal("org-1", ingester.organizations[0])
+		assert.Equal(receivedAt, envelope.GetOccurredAt().AsTime())
+		assert.Equal(map[string]any{"temperature": 21.5, "door": "open"}, envelope.GetData().AsMap())
+	})
+
+	t.Run("rejects unknown topics and payloads that are no JSON objects", func(t *testing.T) {
+		ingester := &recordingEnvelopeIngester{}
+		mgr := NewMQTTUplinkManager(resolver, ingester)
+
+		err := mgr.IngestMQTTMessage(context.Background(), "ponix/device-2/up", []byte(`{}`), receivedAt)
+		assert.ErrorIs(t, err, ErrEndDeviceNotFound)
+
+		for _, payload := range []string{`21.5`, `[1,2]`, `null`, `not json`} {
+			err = mgr.IngestMQTTMessage(context.Background(), "ponix/device-1/up", []byte(payload), receivedAt)
+			assert.ErrorIs(t, err, ErrInvalidMQTTPayload, payload)
+		}
+
+		assert.Empty(t, ingester.envelopes)
+	})
+}
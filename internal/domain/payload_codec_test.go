@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of the payload codec tests")
+
+// assertGolden compares the JSON form of a payload decoder output with the golden file next to the test input,
+// rewriting the golden file instead when the tests run with -update.
+func assertGolden(t *testing.T, input string, output *PayloadDecoderOutput) {
+	golden := strings.TrimSuffix(input, filepath.Ext(input)) + ".golden.json"
+
+	actual, err := json.MarshalIndent(output, "", "  ")
+	if !assert.NoError(t, err) {
+		return
+	}
+	actual = append(actual, '\n')
+
+	if *updateGolden {
+		assert.NoError(t, os.WriteFile(golden, actual, 0o644))
+		return
+	}
+
+	expected, err := os.ReadFile(golden)
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, string(expected), string(actual))
+	}
+}
+
+func TestPayloadCodecs_CayenneLPP(t *testing.T) {
+	mgr := NewPayloadDecoderManager(nil, nil)
+
+	inputs, err := filepath.Glob("testdata/cayenne_lpp/*.hex")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, inputs)
+
+	for _, input := range inputs {
+		t.Run(filepath.Base(input), func(t *testing.T) {
+			raw, err := os.ReadFile(input)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			payload, err := hex.DecodeString(strings.TrimSpace(string(raw)))
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			output, err := mgr.TestPayloadDecoder(context.Background(), PayloadDecoder{Codec: PayloadCodecCayenneLPP}, PayloadDecoderInput{Bytes: payload, FPort: 1})
+			if assert.NoError(t, err) {
+				assertGolden(t, input, output)
+			}
+		})
+	}
+}
+
+func TestPayloadCodecs_ByteLayout(t *testing.T) {
+	mgr := NewPayloadDecoderManager(nil, nil)
+
+	inputs, err := filepath.Glob("testdata/byte_layout/*.json")
+	assert.NoError(t, err)
+
+	for _, input := range inputs {
+		if strings.HasSuffix(input, ".golden.json") {
+			continue
+		}
+
+		t.Run(filepath.Base(input), func(t *testing.T) {
+			raw, err := os.ReadFile(input)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			var testCase struct {
+				Layout  []PayloadLayoutField `json:"layout"`
+				Payload string               `json:"payload"`
+			}
+			if !assert.NoError(t, json.Unmarshal(raw, &testCase)) {
+				return
+			}
+
+			payload, err := hex.DecodeString(testCase.Payload)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			decoder := PayloadDecoder{Codec: PayloadCodecByteLayout, Layout: testCase.Layout}
+			output, err := mgr.TestPayloadDecoder(context.Background(), decoder, PayloadDecoderInput{Bytes: payload, FPort: 1})
+			if assert.NoError(t, err) {
+				assertGolden(t, input, output)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+var (
+	// ErrInvalidPayloadDecoder is returned when a payload decoder has an unknown codec, or a script or byte layout
+	// that cannot be used.
+	ErrInvalidPayloadDecoder = errors.New("invalid payload decoder")
+	// ErrPayloadDecoderFailed is returned when a payload decoder script throws, exceeds its time or memory limit,
+	// returns something other than a decoded object or encoded bytes or reports errors, or when a payload does not
+	// fit the codec decoding it.
+	ErrPayloadDecoderFailed = errors.New("payload decoder failed")
+)
+
+const (
+	// MaxPayloadDecoderLength is the maximum length of a payload decoder script in bytes.
+	MaxPayloadDecoderLength = 64 << 10
+	// PayloadDecoderErrorKey is the data key the error of a failed payload decoder is stored under, next to the
+	// raw payload of the uplink it failed on.
+	PayloadDecoderErrorKey = "decoder_error"
+)
+
+// PayloadCodec is how a payload decoder turns payloads into data.
+type PayloadCodec string
+
+// Supported payload codecs.
+const (
+	// PayloadCodecJavaScript runs the decodeUplink function of a JavaScript script, and its encodeDownlink function
+	// for downlinks sent as data.
+	PayloadCodecJavaScript PayloadCodec = "javascript"
+	// PayloadCodecCayenneLPP decodes Cayenne Low Power Payload.
+	PayloadCodecCayenneLPP PayloadCodec = "cayenne_lpp"
+	// PayloadCodecByteLayout reads fields at fixed offsets of the payload.
+	PayloadCodecByteLayout PayloadCodec = "byte_layout"
+)
+
+// LookupPayloadCodec returns the payload codec with the given name.
+func LookupPayloadCodec(name string) (PayloadCodec, bool) {
+	switch codec := PayloadCodec(strings.ToLower(strings.TrimSpace(name))); codec {
+	case PayloadCodecJavaScript, PayloadCodecCayenneLPP, PayloadCodecByteLayout:
+		return codec, true
+	default:
+		return "", false
+	}
+}
+
+// PayloadDecoder decodes the uplink payloads of end devices with a payload codec.
+type PayloadDecoder struct {
+	Codec PayloadCodec
+	// Script is the JavaScript defining decodeUplink, and optionally encodeDownlink, of the javascript codec.
+	Script string
+	// Layout lists the fields read by the byte_layout codec.
+	Layout []PayloadLayoutField
+}
+
+// Validate checks that the payload decoder has a known codec and the script or byte layout that codec needs.
+func (decoder PayloadDecoder) Validate() error {
+	switch decoder.Codec {
+	case PayloadCodecJavaScript:
+		if len(decoder.Layout) > 0 {
+			return stacktrace.NewStackTraceErrorf("%w: only byte_layout decoders have a layout", ErrInvalidPayloadDecoder)
+		}
+		return ValidatePayloadDecoderScript(decoder.Script)
+	case PayloadCodecCayenneLPP:
+		if decoder.Script != "" || len(decoder.Layout) > 0 {
+			return stacktrace.NewStackTraceErrorf("%w: cayenne_lpp decoders have no script or layout", ErrInvalidPayloadDecoder)
+		}
+		return nil
+	case PayloadCodecByteLayout:
+		if decoder.Script != "" {
+			return stacktrace.NewStackTraceErrorf("%w: only javascript decoders have a script", ErrInvalidPayloadDecoder)
+		}
+		return validatePayloadLayout(decoder.Layout)
+	default:
+		return stacktrace.NewStackTraceErrorf("%w: unknown codec %q", ErrInvalidPayloadDecoder, decoder.Codec)
+	}
+}
+
+// withDefaults returns a copy of the payload decoder with the byte order and scale of its layout fields filled in.
+func (decoder PayloadDecoder) withDefaults() PayloadDecoder {
+	defaulted := PayloadDecoder{
+		Codec:  decoder.Codec,
+		Script: decoder.Script,
+	}
+
+	for _, field := range decoder.Layout {
+		defaulted.Layout = append(defaulted.Layout, field.withDefaults())
+	}
+
+	return defaulted
+}
+
+// PayloadDecoderInput is the uplink a payload decoder script is run against. Scripts get it the way The Things
+// Stack passes it to payload formatters: decodeUplink({bytes, fPort, recvTime}).
+type PayloadDecoderInput struct {
+	Bytes      []byte
+	FPort      uint32
+	ReceivedAt time.Time
+}
+
+// PayloadDecoderOutput is the result of a payload decoder script's decodeUplink function.
+type PayloadDecoderOutput struct {
+	Data     map[string]any `json:"data"`
+	Warnings []string       `json:"warnings,omitempty"`
+	Errors   []string       `json:"errors,omitempty"`
+}
+
+// PayloadEncoderInput is the downlink data a payload decoder script encodes. Scripts get it the way The Things
+// Stack passes it to payload formatters: encodeDownlink({data, fPort}), where fPort is omitted when not chosen yet.
+type PayloadEncoderInput struct {
+	Data  map[string]any
+	FPort uint32
+}
+
+// PayloadEncoderOutput is the result of a payload decoder script's encodeDownlink function.
+type PayloadEncoderOutput struct {
+	Bytes    []byte   `json:"bytes"`
+	FPort    uint32   `json:"f_port,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// PayloadDecoderRunner runs the decodeUplink and encodeDownlink functions of payload decoder scripts. Scripts that
+// throw, exceed the runner's limits or return an invalid result are reported as ErrPayloadDecoderFailed.
+type PayloadDecoderRunner interface {
+	DecodeUplink(ctx context.Context, script string, input PayloadDecoderInput) (*PayloadDecoderOutput, error)
+	EncodeDownlink(ctx context.Context, script string, input PayloadEncoderInput) (*PayloadEncoderOutput, error)
+}
+
+// PayloadDecoderStorer defines the persistence operations for payload decoders.
+type PayloadDecoderStorer interface {
+	// GetEndDevicePayloadDecoder returns the decoder of the uplinks of an end device: the script of the profile the
+	// device was created from, or else the decoder of its LoRaWAN hardware type. It is nil when neither has one.
+	GetEndDevicePayloadDecoder(ctx context.Context, endDeviceId string) (*PayloadDecoder, error)
+	GetLoRaWANHardwareTypePayloadDecoder(ctx context.Context, hardwareTypeId string) (*PayloadDecoder, error)
+	SetLoRaWANHardwareTypePayloadDecoder(ctx context.Context, hardwareTypeId string, decoder PayloadDecoder) error
+	DeleteLoRaWANHardwareTypePayloadDecoder(ctx context.Context, hardwareTypeId string) error
+}
+
+// PayloadDecoderManager runs the payload decoders that turn the binary payloads of end devices into data.
+// JavaScript decoders run in the PayloadDecoderRunner, while the built-in codecs are decoded natively.
+type PayloadDecoderManager struct {
+	store  PayloadDecoderStorer
+	runner PayloadDecoderRunner
+}
+
+// NewPayloadDecoderManager creates a new PayloadDecoderManager.
+func NewPayloadDecoderManager(store PayloadDecoderStorer, runner PayloadDecoderRunner) *PayloadDecoderManager {
+	return &PayloadDecoderManager{
+		store:  store,
+		runner: runner,
+	}
+}
+
+// ValidatePayloadDecoderScript checks that a payload decoder script is set and not longer than
+// MaxPayloadDecoderLength.
+func ValidatePayloadDecoderScript(script string) error {
+	if strings.TrimSpace(script) == "" {
+		return stacktrace.NewStackTraceErrorf("%w: script is empty", ErrInvalidPayloadDecoder)
+	}
+
+	if len(script) > MaxPayloadDecoderLength {
+		return stacktrace.NewStackTraceErrorf("%w: script is longer than %d bytes", ErrInvalidPayloadDecoder, MaxPayloadDecoderLength)
+	}
+
+	return nil
+}
+
+// GetLoRaWANHardwareTypePayloadDecoder returns the payload decoder of a LoRaWAN hardware type, nil when it has none.
+func (mgr *PayloadDecoderManager) GetLoRaWANHardwareTypePayloadDecoder(ctx context.Context, hardwareTypeId string) (*PayloadDecoder, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetLoRaWANHardwareTypePayloadDecoder")
+	defer span.End()
+
+	return mgr.store.GetLoRaWANHardwareTypePayloadDecoder(ctx, hardwareTypeId)
+}
+
+// SetLoRaWANHardwareTypePayloadDecoder sets the payload decoder of a LoRaWAN hardware type. A nil decoder removes it.
+func (mgr *PayloadDecoderManager) SetLoRaWANHardwareTypePayloadDecoder(ctx context.Context, hardwareTypeId string, decoder *PayloadDecoder) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "SetLoRaWANHardwareTypePayloadDecoder")
+	defer span.End()
+
+	if decoder == nil {
+		return mgr.store.DeleteLoRaWANHardwareTypePayloadDecoder(ctx, hardwareTypeId)
+	}
+
+	defaulted := decoder.withDefaults()
+
+	err := defaulted.Validate()
+	if err != nil {
+		return err
+	}
+
+	return mgr.store.SetLoRaWANHardwareTypePayloadDecoder(ctx, hardwareTypeId, defaulted)
+}
+
+// DecodeEndDeviceUplink runs the payload decoder of an end device over an uplink and returns the decoded data.
+// It reports false when the device has no payload decoder. Errors reported by a script and payloads that do not
+// fit the codec are returned as ErrPayloadDecoderFailed.
+func (mgr *PayloadDecoderManager) DecodeEndDeviceUplink(ctx context.Context, endDeviceId string, input PayloadDecoderInput) (map[string]any, bool, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "DecodeEndDeviceUplink")
+	defer span.End()
+
+	decoder, err := mgr.store.GetEndDevicePayloadDecoder(ctx, endDeviceId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if decoder == nil {
+		return nil, false, nil
+	}
+
+	output, err := mgr.decode(ctx, *decoder, input)
+	if err != nil {
+		return nil, true, err
+	}
+
+	if len(output.Errors) > 0 {
+		return nil, true, stacktrace.NewStackTraceErrorf("%w: %s", ErrPayloadDecoderFailed, strings.Join(output.Errors, "; "))
+	}
+
+	return output.Data, true, nil
+}
+
+// EncodeEndDeviceDownlink runs the encodeDownlink function of an end device's payload decoder over downlink data.
+// It reports false when the device has no JavaScript payload decoder, as the built-in codecs only decode. Errors
+// reported by the script are returned as ErrPayloadDecoderFailed.
+func (mgr *PayloadDecoderManager) EncodeEndDeviceDownlink(ctx context.Context, endDeviceId string, input PayloadEncoderInput) (*PayloadEncoderOutput, bool, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "EncodeEndDeviceDownlink")
+	defer span.End()
+
+	decoder, err := mgr.store.GetEndDevicePayloadDecoder(ctx, endDeviceId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if decoder == nil || decoder.Codec != PayloadCodecJavaScript {
+		return nil, false, nil
+	}
+
+	output, err := mgr.runner.EncodeDownlink(ctx, decoder.Script, input)
+	if err != nil {
+		return nil, true, err
+	}
+
+	if len(output.Errors) > 0 {
+		return nil, true, stacktrace.NewStackTraceErrorf("%w: %s", ErrPayloadDecoderFailed, strings.Join(output.Errors, "; "))
+	}
+
+	return output, true, nil
+}
+
+// TestPayloadDecoder runs a payload decoder over sample input without storing anything. Failures of the decoder
+// are returned in the output's errors, so only an invalid decoder is reported as an error.
+func (mgr *PayloadDecoderManager) TestPayloadDecoder(ctx context.Context, decoder PayloadDecoder, input PayloadDecoderInput) (*PayloadDecoderOutput, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "TestPayloadDecoder")
+	defer span.End()
+
+	defaulted := decoder.withDefaults()
+
+	err := defaulted.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := mgr.decode(ctx, defaulted, input)
+	if errors.Is(err, ErrPayloadDecoderFailed) {
+		return &PayloadDecoderOutput{Errors: []string{err.Error()}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// decode runs a payload decoder over an uplink with its codec.
+func (mgr *PayloadDecoderManager) decode(ctx context.Context, decoder PayloadDecoder, input PayloadDecoderInput) (*PayloadDecoderOutput, error) {
+	var data map[string]any
+	var err error
+
+	switch decoder.Codec {
+	case PayloadCodecCayenneLPP:
+		data, err = decodeCayenneLPP(input.Bytes)
+	case PayloadCodecByteLayout:
+		data, err = decodePayloadLayout(decoder.Layout, input.Bytes)
+	default:
+		return mgr.runner.DecodeUplink(ctx, decoder.Script, input)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &PayloadDecoderOutput{Data: data}, nil
+}
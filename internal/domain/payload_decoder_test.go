@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPayloadDecoderManager() (*PayloadDecoderManager, *fakePayloadDecoderStore) {
+	store := &fakePayloadDecoderStore{
+		endDevices: map[string]*PayloadDecoder{
+			"device-1": {Codec: PayloadCodecJavaScript, Script: "decoder"},
+			"device-2": nil,
+			"device-3": {Codec: PayloadCodecJavaScript, Script: "rejecting decoder"},
+			"device-4": {Codec: PayloadCodecJavaScript, Script: "broken decoder"},
+			"device-5": {Codec: PayloadCodecCayenneLPP},
+		},
+		hardwareTypes: map[string]PayloadDecoder{},
+	}
+	runner := scriptedPayloadDecoderRunner{
+		"decoder":           {Data: map[string]any{"temperature": 21.5}},
+		"rejecting decoder": {Errors: []string{"unknown port"}},
+	}
+
+	return NewPayloadDecoderManager(store, runner), store
+}
+
+func TestPayloadDecoderManager_DecodeEndDeviceUplink(t *testing.T) {
+	mgr, _ := newTestPayloadDecoderManager()
+
+	data, ok, err := mgr.DecodeEndDeviceUplink(context.Background(), "device-1", PayloadDecoderInput{})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]any{"temperature": 21.5}, data)
+
+	_, ok, err = mgr.DecodeEndDeviceUplink(context.Background(), "device-2", PayloadDecoderInput{})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, err = mgr.DecodeEndDeviceUplink(context.Background(), "device-3", PayloadDecoderInput{})
+	assert.ErrorIs(t, err, ErrPayloadDecoderFailed)
+	assert.ErrorContains(t, err, "unknown port")
+
+	_, _, err = mgr.DecodeEndDeviceUplink(context.Background(), "device-4", PayloadDecoderInput{})
+	assert.ErrorIs(t, err, ErrPayloadDecoderFailed)
+
+	data, ok, err = mgr.DecodeEndDeviceUplink(context.Background(), "device-5", PayloadDecoderInput{Bytes: []byte{0x01, 0x67, 0x00, 0xd7}})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]any{"temperature_1": 21.5}, data)
+
+	_, _, err = mgr.DecodeEndDeviceUplink(context.Background(), "device-5", PayloadDecoderInput{Bytes: []byte{0x01, 0x67, 0x00}})
+	assert.ErrorIs(t, err, ErrPayloadDecoderFailed)
+
+	_, _, err = mgr.DecodeEndDeviceUplink(context.Background(), "device-9", PayloadDecoderInput{})
+	assert.ErrorIs(t, err, ErrEndDeviceNotFound)
+}
+
+func TestPayloadDecoderManager_EncodeEndDeviceDownlink(t *testing.T) {
+	mgr, _ := newTestPayloadDecoderManager()
+	input := PayloadEncoderInput{Data: map[string]any{"command": "reboot"}}
+
+	output, ok, err := mgr.EncodeEndDeviceDownlink(context.Background(), "device-1", input)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, &PayloadEncoderOutput{Bytes: []byte("reboot"), FPort: 5}, output)
+
+	for _, endDeviceId := range []string{"device-2", "device-5"} {
+		_, ok, err = mgr.EncodeEndDeviceDownlink(context.Background(), endDeviceId, input)
+		assert.NoError(t, err, endDeviceId)
+		assert.False(t, ok, endDeviceId)
+	}
+
+	_, _, err = mgr.EncodeEndDeviceDownlink(context.Background(), "device-3", input)
+	assert.ErrorIs(t, err, ErrPayloadDecoderFailed)
+	assert.ErrorContains(t, err, "unknown port")
+}
+
+func TestPayloadDecoderManager_SetLoRaWANHardwareTypePayloadDecoder(t *testing.T) {
+	assert := assert.New(t)
+	mgr, store := newTestPayloadDecoderManager()
+
+	decoder := &PayloadDecoder{Codec: PayloadCodecByteLayout, Layout: []PayloadLayoutField{{Field: "temperature", Offset: 0, Length: 2, Signed: true, Scale: 0.01}}}
+	assert.NoError(mgr.SetLoRaWANHardwareTypePayloadDecoder(context.Background(), "dragino-lht65", decoder))
+	assert.Equal(map[string]PayloadDecoder{"dragino-lht65": {Codec: PayloadCodecByteLayout, Layout: []PayloadLayoutField{
+		{Field: "temperature", Offset: 0, Length: 2, Endianness: PayloadBigEndian, Signed: true, Scale: 0.01},
+	}}}, store.hardwareTypes)
+
+	for name, invalid := range map[string]*PayloadDecoder{
+		"long script":     {Codec: PayloadCodecJavaScript, Script: strings.Repeat("a", MaxPayloadDecoderLength+1)},
+		"unknown codec":   {Codec: "protobuf"},
+		"script for lpp":  {Codec: PayloadCodecCayenneLPP, Script: "decoder"},
+		"empty layout":    {Codec: PayloadCodecByteLayout},
+		"long field":      {Codec: PayloadCodecByteLayout, Layout: []PayloadLayoutField{{Field: "count", Length: 9}}},
+		"duplicate field": {Codec: PayloadCodecByteLayout, Layout: []PayloadLayoutField{{Field: "count", Length: 1}, {Field: "count", Offset: 1, Length: 1}}},
+		"bad endianness":  {Codec: PayloadCodecByteLayout, Layout: []PayloadLayoutField{{Field: "count", Length: 2, Endianness: "middle"}}},
+		"out of range":    {Codec: PayloadCodecByteLayout, Layout: []PayloadLayoutField{{Field: "count", Offset: MaxPayloadLayoutLength, Length: 1}}},
+	} {
+		err := mgr.SetLoRaWANHardwareTypePayloadDecoder(context.Background(), "dragino-lht65", invalid)
+		assert.ErrorIs(err, ErrInvalidPayloadDecoder, name)
+	}
+
+	assert.NoError(mgr.SetLoRaWANHardwareTypePayloadDecoder(context.Background(), "dragino-lht65", nil))
+	assert.Empty(store.hardwareTypes)
+}
+
+func TestPayloadDecoderManager_TestPayloadDecoder(t *testing.T) {
+	mgr, _ := newTestPayloadDecoderManager()
+
+	output, err := mgr.TestPayloadDecoder(context.Background(), PayloadDecoder{Codec: PayloadCodecJavaScript, Script: "decoder"}, PayloadDecoderInput{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"temperature": 21.5}, output.Data)
+
+	output, err = mgr.TestPayloadDecoder(context.Background(), PayloadDecoder{Codec: PayloadCodecJavaScript, Script: "broken decoder"}, PayloadDecoderInput{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"payload decoder failed: decodeUplink is not defined"}, output.Errors)
+
+	_, err = mgr.TestPayloadDecoder(context.Background(), PayloadDecoder{Codec: PayloadCodecJavaScript, Script: " "}, PayloadDecoderInput{})
+	assert.ErrorIs(t, err, ErrInvalidPayloadDecoder)
+}
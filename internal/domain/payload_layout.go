@@ -0,0 +1,136 @@
+package domain
+
+import (
+	"math"
+	"strings"
+
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+const (
+	// MaxPayloadLayoutFields is the largest number of fields a byte layout reads.
+	MaxPayloadLayoutFields = 64
+	// MaxPayloadLayoutLength is how far into a payload, in bytes, a byte layout can read.
+	MaxPayloadLayoutLength = 255
+	// maxPayloadFieldLength is the largest number of bytes a single field of a byte layout spans.
+	maxPayloadFieldLength = 8
+)
+
+// PayloadEndianness is the order the bytes of a field of a byte layout are read in.
+type PayloadEndianness string
+
+// Supported byte orders.
+const (
+	PayloadBigEndian    PayloadEndianness = "big"
+	PayloadLittleEndian PayloadEndianness = "little"
+)
+
+// PayloadLayoutField maps bytes at a fixed offset of an uplink payload to a field of its data.
+type PayloadLayoutField struct {
+	// Field is the name the value is stored under.
+	Field string `json:"field"`
+	// Offset is the zero-based position of the field's first byte.
+	Offset int `json:"offset"`
+	// Length is the number of bytes the field spans, from 1 to 8.
+	Length int `json:"length"`
+	// Endianness is the order of the field's bytes. It defaults to big endian.
+	Endianness PayloadEndianness `json:"endianness,omitempty"`
+	// Signed reads the field as a two's complement integer.
+	Signed bool `json:"signed,omitempty"`
+	// Scale is multiplied with the decoded value, e.g. 0.1 for a field holding tenths. It defaults to 1.
+	Scale float64 `json:"scale,omitempty"`
+}
+
+// withDefaults returns a copy of the field with the default endianness and scale filled in.
+func (field PayloadLayoutField) withDefaults() PayloadLayoutField {
+	if field.Endianness == "" {
+		field.Endianness = PayloadBigEndian
+	}
+	if field.Scale == 0 {
+		field.Scale = 1
+	}
+
+	return field
+}
+
+// decode reads the field from a payload and scales it.
+func (field PayloadLayoutField) decode(payload []byte) (float64, error) {
+	end := field.Offset + field.Length
+	if end > len(payload) {
+		return 0, stacktrace.NewStackTraceErrorf("%w: %s: payload is %d bytes, field ends at byte %d", ErrPayloadDecoderFailed, field.Field, len(payload), end)
+	}
+
+	raw := readPayloadUint(payload[field.Offset:end], field.Endianness == PayloadLittleEndian)
+	if field.Signed {
+		return float64(signPayloadUint(raw, field.Length)) * field.Scale, nil
+	}
+
+	return float64(raw) * field.Scale, nil
+}
+
+// validatePayloadLayout checks that a byte layout reads every field from within MaxPayloadLayoutLength bytes and
+// stores it under its own name. Fields without an endianness or scale get the defaults.
+func validatePayloadLayout(layout []PayloadLayoutField) error {
+	if len(layout) == 0 || len(layout) > MaxPayloadLayoutFields {
+		return stacktrace.NewStackTraceErrorf("%w: between 1 and %d layout fields are required", ErrInvalidPayloadDecoder, MaxPayloadLayoutFields)
+	}
+
+	seen := map[string]bool{}
+	for _, field := range layout {
+		field = field.withDefaults()
+		switch {
+		case strings.TrimSpace(field.Field) == "":
+			return stacktrace.NewStackTraceErrorf("%w: layout field at offset %d has no name", ErrInvalidPayloadDecoder, field.Offset)
+		case seen[field.Field]:
+			return stacktrace.NewStackTraceErrorf("%w: field %q is laid out twice", ErrInvalidPayloadDecoder, field.Field)
+		case field.Length < 1 || field.Length > maxPayloadFieldLength:
+			return stacktrace.NewStackTraceErrorf("%w: %s: length must be between 1 and %d bytes", ErrInvalidPayloadDecoder, field.Field, maxPayloadFieldLength)
+		case field.Offset < 0 || field.Offset+field.Length > MaxPayloadLayoutLength:
+			return stacktrace.NewStackTraceErrorf("%w: %s: field must lie within the first %d bytes", ErrInvalidPayloadDecoder, field.Field, MaxPayloadLayoutLength)
+		case field.Endianness != PayloadBigEndian && field.Endianness != PayloadLittleEndian:
+			return stacktrace.NewStackTraceErrorf("%w: %s: unknown endianness %q", ErrInvalidPayloadDecoder, field.Field, field.Endianness)
+		case math.IsNaN(field.Scale) || math.IsInf(field.Scale, 0):
+			return stacktrace.NewStackTraceErrorf("%w: %s: scale must be a finite number", ErrInvalidPayloadDecoder, field.Field)
+		}
+		seen[field.Field] = true
+	}
+
+	return nil
+}
+
+// decodePayloadLayout reads the fields of a byte layout from a payload. Payloads too short for a field are
+// reported as ErrPayloadDecoderFailed.
+func decodePayloadLayout(layout []PayloadLayoutField, payload []byte) (map[string]any, error) {
+	data := map[string]any{}
+
+	for _, field := range layout {
+		value, err := field.decode(payload)
+		if err != nil {
+			return nil, err
+		}
+		data[field.Field] = value
+	}
+
+	return data, nil
+}
+
+// readPayloadUint reads up to 8 bytes as an unsigned integer.
+func readPayloadUint(data []byte, littleEndian bool) uint64 {
+	var value uint64
+	for i := range data {
+		b := data[i]
+		if littleEndian {
+			b = data[len(data)-1-i]
+		}
+		value = value<<8 | uint64(b)
+	}
+
+	return value
+}
+
+// signPayloadUint reads the low length bytes of value as a two's complement integer.
+func signPayloadUint(value uint64, length int) int64 {
+	shift := 64 - 8*length
+
+	return int64(value<<shift) >> shift
+}
@@ -0,0 +1,351 @@
+package domain
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+var (
+	// ErrInvalidSealedRootKey is returned when a stored root key is not a sealed root key or cannot be decrypted.
+	ErrInvalidSealedRootKey = errors.New("invalid sealed root key")
+	// ErrUnknownMasterKey is returned when a data key was wrapped with a master key that is not configured.
+	ErrUnknownMasterKey = errors.New("unknown master key")
+)
+
+const (
+	// sealedRootKeyVersion prefixes every sealed root key so the format can evolve.
+	sealedRootKeyVersion = "v1"
+	// sealedRootKeySeparator separates the parts of a sealed root key; it never occurs in key ids or base64url.
+	sealedRootKeySeparator = ":"
+	// dataKeyBytes is the size of the AES-256 data key generated for every end device.
+	dataKeyBytes = 32
+	// rootKeyBatchSize is the number of end devices re-encrypted per round trip by RootKeyManager.
+	rootKeyBatchSize = 100
+)
+
+// KeyWrapper wraps and unwraps data keys with a master key held by a key management service.
+// Master keys never leave the KeyWrapper; only wrapped data keys are stored next to the data they protect.
+type KeyWrapper interface {
+	// PrimaryKeyId returns the id of the master key new data keys are wrapped with.
+	PrimaryKeyId() string
+	// WrapDataKey encrypts a data key with the primary master key and returns the id of that key.
+	WrapDataKey(ctx context.Context, dataKey []byte) (string, []byte, error)
+	// UnwrapDataKey decrypts a data key wrapped with the given master key.
+	UnwrapDataKey(ctx context.Context, keyId string, wrappedKey []byte) ([]byte, error)
+}
+
+// sealedRootKey is the parsed form of a sealed root key: "v1:<master key id>:<wrapped data key>:<nonce and ciphertext>".
+// The wrapped data key travels with every sealed value, so a sealed root key can be opened on its own.
+type sealedRootKey struct {
+	masterKeyId    string
+	wrappedDataKey []byte
+	ciphertext     []byte
+}
+
+// String encodes the sealed root key.
+func (sealed sealedRootKey) String() string {
+	return strings.Join([]string{
+		sealedRootKeyVersion,
+		sealed.masterKeyId,
+		base64.RawURLEncoding.EncodeToString(sealed.wrappedDataKey),
+		base64.RawURLEncoding.EncodeToString(sealed.ciphertext),
+	}, sealedRootKeySeparator)
+}
+
+// parseSealedRootKey decodes a sealed root key.
+func parseSealedRootKey(value string) (sealedRootKey, error) {
+	parts := strings.Split(value, sealedRootKeySeparator)
+	if len(parts) != 4 || parts[0] != sealedRootKeyVersion || parts[1] == "" {
+		return sealedRootKey{}, stacktrace.NewStackTraceError(ErrInvalidSealedRootKey)
+	}
+
+	wrappedDataKey, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return sealedRootKey{}, stacktrace.NewStackTraceErrorf("%w: %w", ErrInvalidSealedRootKey, err)
+	}
+
+	ciphertext, err := base64.RawURLEncoding.DecodeString(parts[3])
+	if err != nil {
+		return sealedRootKey{}, stacktrace.NewStackTraceErrorf("%w: %w", ErrInvalidSealedRootKey, err)
+	}
+
+	return sealedRootKey{
+		masterKeyId:    parts[1],
+		wrappedDataKey: wrappedDataKey,
+		ciphertext:     ciphertext,
+	}, nil
+}
+
+// IsSealedRootKey reports whether a stored root key value is sealed rather than plaintext hex.
+func IsSealedRootKey(value string) bool {
+	return strings.HasPrefix(value, sealedRootKeyVersion+sealedRootKeySeparator)
+}
+
+// SealedRootKeyMasterKeyId returns the id of the master key a sealed root key is wrapped with,
+// or an empty string when the value is not sealed.
+func SealedRootKeyMasterKeyId(value string) string {
+	if !IsSealedRootKey(value) {
+		return ""
+	}
+
+	sealed, err := parseSealedRootKey(value)
+	if err != nil {
+		return ""
+	}
+
+	return sealed.masterKeyId
+}
+
+// RootKeySealer encrypts and decrypts LoRaWAN root keys for storage.
+type RootKeySealer interface {
+	SealRootKeys(ctx context.Context, rootKeys ...string) ([]string, error)
+	OpenRootKey(ctx context.Context, value string) (string, error)
+}
+
+// RootKeyCipher encrypts LoRaWAN root keys with envelope encryption. Each end device gets its own random
+// AES-256-GCM data key, which is wrapped with the master key of a KeyWrapper.
+type RootKeyCipher struct {
+	keyWrapper KeyWrapper
+}
+
+// NewRootKeyCipher creates a new instance of RootKeyCipher with the provided dependencies.
+func NewRootKeyCipher(keyWrapper KeyWrapper) *RootKeyCipher {
+	return &RootKeyCipher{
+		keyWrapper: keyWrapper,
+	}
+}
+
+// PrimaryKeyId returns the id of the master key new root keys are sealed with.
+func (rkc *RootKeyCipher) PrimaryKeyId() string {
+	return rkc.keyWrapper.PrimaryKeyId()
+}
+
+// SealRootKeys encrypts the root keys of one end device under a single new data key.
+// Empty keys stay empty so optional keys remain optional.
+func (rkc *RootKeyCipher) SealRootKeys(ctx context.Context, rootKeys ...string) ([]string, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "SealRootKeys")
+	defer span.End()
+
+	dataKey := make([]byte, dataKeyBytes)
+	_, err := rand.Read(dataKey)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	masterKeyId, wrappedDataKey, err := rkc.keyWrapper.WrapDataKey(ctx, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newDataKeyAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sealedKeys := make([]string, len(rootKeys))
+	for i, rootKey := range rootKeys {
+		if rootKey == "" {
+			continue
+		}
+
+		nonce := make([]byte, aead.NonceSize())
+		_, err = rand.Read(nonce)
+		if err != nil {
+			return nil, stacktrace.NewStackTraceError(err)
+		}
+
+		sealedKeys[i] = sealedRootKey{
+			masterKeyId:    masterKeyId,
+			wrappedDataKey: wrappedDataKey,
+			ciphertext:     aead.Seal(nonce, nonce, []byte(rootKey), []byte(masterKeyId)),
+		}.String()
+	}
+
+	return sealedKeys, nil
+}
+
+// OpenRootKey decrypts a sealed root key. An empty value opens to an empty key.
+func (rkc *RootKeyCipher) OpenRootKey(ctx context.Context, value string) (string, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "OpenRootKey")
+	defer span.End()
+
+	if value == "" {
+		return "", nil
+	}
+
+	sealed, err := parseSealedRootKey(value)
+	if err != nil {
+		return "", err
+	}
+
+	dataKey, err := rkc.keyWrapper.UnwrapDataKey(ctx, sealed.masterKeyId, sealed.wrappedDataKey)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newDataKeyAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed.ciphertext) < aead.NonceSize() {
+		return "", stacktrace.NewStackTraceError(ErrInvalidSealedRootKey)
+	}
+
+	nonce, ciphertext := sealed.ciphertext[:aead.NonceSize()], sealed.ciphertext[aead.NonceSize():]
+	rootKey, err := aead.Open(nil, nonce, ciphertext, []byte(sealed.masterKeyId))
+	if err != nil {
+		return "", stacktrace.NewStackTraceErrorf("%w: %w", ErrInvalidSealedRootKey, err)
+	}
+
+	return string(rootKey), nil
+}
+
+// ResealRootKeys decrypts the sealed root keys of one end device and seals them again under a new data key
+// wrapped with the primary master key. Empty values stay empty.
+func (rkc *RootKeyCipher) ResealRootKeys(ctx context.Context, values ...string) ([]string, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ResealRootKeys")
+	defer span.End()
+
+	rootKeys := make([]string, len(values))
+	for i, value := range values {
+		rootKey, err := rkc.OpenRootKey(ctx, value)
+		if err != nil {
+			return nil, err
+		}
+		rootKeys[i] = rootKey
+	}
+
+	return rkc.SealRootKeys(ctx, rootKeys...)
+}
+
+// newDataKeyAEAD creates the AES-256-GCM AEAD for a data key.
+func newDataKeyAEAD(dataKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return aead, nil
+}
+
+// StoredRootKeys are the root keys of one LoRaWAN end device as held in the database.
+type StoredRootKeys struct {
+	EndDeviceId    string
+	ApplicationKey string
+	NetworkKey     string
+}
+
+// RootKeyStorer defines the persistence operations for encrypting and rotating stored root keys.
+type RootKeyStorer interface {
+	// ListPlaintextRootKeys returns up to limit end devices whose root keys are not sealed yet.
+	ListPlaintextRootKeys(ctx context.Context, limit int) ([]StoredRootKeys, error)
+	// ListRootKeysNotWrappedWith returns up to limit end devices whose sealed root keys are wrapped with a master key other than masterKeyId.
+	ListRootKeysNotWrappedWith(ctx context.Context, masterKeyId string, limit int) ([]StoredRootKeys, error)
+	// UpdateRootKeys replaces the stored root keys of an end device unless they no longer match previous,
+	// in which case another writer already replaced them and nothing is changed.
+	UpdateRootKeys(ctx context.Context, previous StoredRootKeys, sealed StoredRootKeys) error
+}
+
+// RootKeyManager encrypts root keys stored before encryption at rest was introduced and moves stored
+// root keys to a new master key.
+type RootKeyManager struct {
+	rootKeyStore  RootKeyStorer
+	rootKeyCipher *RootKeyCipher
+}
+
+// NewRootKeyManager creates a new instance of RootKeyManager with the provided dependencies.
+func NewRootKeyManager(rootKeyStore RootKeyStorer, rootKeyCipher *RootKeyCipher) *RootKeyManager {
+	return &RootKeyManager{
+		rootKeyStore:  rootKeyStore,
+		rootKeyCipher: rootKeyCipher,
+	}
+}
+
+// EncryptPlaintextRootKeys seals every root key still stored as plaintext and returns the number of
+// end devices encrypted. It is safe to run repeatedly; encrypted rows are not touched again.
+func (mgr *RootKeyManager) EncryptPlaintextRootKeys(ctx context.Context) (int, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "EncryptPlaintextRootKeys")
+	defer span.End()
+
+	encrypted := 0
+	for {
+		batch, err := mgr.rootKeyStore.ListPlaintextRootKeys(ctx, rootKeyBatchSize)
+		if err != nil {
+			return encrypted, err
+		}
+
+		for _, rootKeys := range batch {
+			sealedKeys, err := mgr.rootKeyCipher.SealRootKeys(ctx, rootKeys.ApplicationKey, rootKeys.NetworkKey)
+			if err != nil {
+				return encrypted, err
+			}
+
+			err = mgr.updateRootKeys(ctx, rootKeys, sealedKeys)
+			if err != nil {
+				return encrypted, err
+			}
+			encrypted++
+		}
+
+		if len(batch) < rootKeyBatchSize {
+			return encrypted, nil
+		}
+	}
+}
+
+// RotateRootKeys re-encrypts every stored root key that is not wrapped with the primary master key and
+// returns the number of end devices rotated. Retired master keys must stay configured until it completes.
+func (mgr *RootKeyManager) RotateRootKeys(ctx context.Context) (int, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "RotateRootKeys")
+	defer span.End()
+
+	primaryKeyId := mgr.rootKeyCipher.PrimaryKeyId()
+
+	rotated := 0
+	for {
+		batch, err := mgr.rootKeyStore.ListRootKeysNotWrappedWith(ctx, primaryKeyId, rootKeyBatchSize)
+		if err != nil {
+			return rotated, err
+		}
+
+		for _, rootKeys := range batch {
+			sealedKeys, err := mgr.rootKeyCipher.ResealRootKeys(ctx, rootKeys.ApplicationKey, rootKeys.NetworkKey)
+			if err != nil {
+				return rotated, stacktrace.NewStackTraceErrorf("end device %s: %w", rootKeys.EndDeviceId, err)
+			}
+
+			err = mgr.updateRootKeys(ctx, rootKeys, sealedKeys)
+			if err != nil {
+				return rotated, err
+			}
+			rotated++
+		}
+
+		if len(batch) < rootKeyBatchSize {
+			return rotated, nil
+		}
+	}
+}
+
+// updateRootKeys replaces the stored root keys of an end device with their sealed form.
+func (mgr *RootKeyManager) updateRootKeys(ctx context.Context, previous StoredRootKeys, sealedKeys []string) error {
+	return mgr.rootKeyStore.UpdateRootKeys(ctx, previous, StoredRootKeys{
+		EndDeviceId:    previous.EndDeviceId,
+		ApplicationKey: sealedKeys[0],
+		NetworkKey:     sealedKeys[1],
+	})
+}
@@ -0,0 +1,248 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// ErrRootKeysUnsupported is returned when root keys are rotated for an end device without LoRaWAN root keys.
+	ErrRootKeysUnsupported = errors.New("end device has no LoRaWAN root keys")
+	// ErrRootKeysChanged is returned when the root keys of an end device were replaced concurrently.
+	ErrRootKeysChanged = errors.New("root keys changed concurrently")
+)
+
+// DefaultRootKeyRotationReason is recorded when a rotation is requested without a reason.
+const DefaultRootKeyRotationReason = "root keys rotated"
+
+// RootKeys are new LoRaWAN root keys for an end device as plaintext hex. Empty keys are generated.
+type RootKeys struct {
+	ApplicationKey string
+	NetworkKey     string
+}
+
+// Normalize validates the supplied keys and returns them as uppercase hex without separators.
+func (keys RootKeys) Normalize() (RootKeys, error) {
+	normalized := RootKeys{
+		ApplicationKey: normalizeHex(keys.ApplicationKey),
+		NetworkKey:     normalizeHex(keys.NetworkKey),
+	}
+
+	if normalized.ApplicationKey != "" && !isHexOfLength(normalized.ApplicationKey, 2*rootKeyBytes) {
+		return RootKeys{}, stacktrace.NewStackTraceErrorf("%w: application key must be %d hex digits", ErrInvalidRootKey, 2*rootKeyBytes)
+	}
+	if normalized.NetworkKey != "" && !isHexOfLength(normalized.NetworkKey, 2*rootKeyBytes) {
+		return RootKeys{}, stacktrace.NewStackTraceErrorf("%w: network key must be %d hex digits", ErrInvalidRootKey, 2*rootKeyBytes)
+	}
+
+	return normalized, nil
+}
+
+// RootKeyRotation records a single replacement of an end device's root keys.
+type RootKeyRotation struct {
+	Id           string    `json:"id"`
+	EndDeviceId  string    `json:"end_device_id"`
+	Reason       string    `json:"reason"`
+	KeysSupplied bool      `json:"keys_supplied"`
+	RotatedAt    time.Time `json:"rotated_at"`
+}
+
+// RootKeyRegister pushes the root keys of an end device to the Join Server of an external network.
+type RootKeyRegister interface {
+	UpdateEndDeviceRootKeys(ctx context.Context, endDevice *iotv1.EndDevice) error
+}
+
+// RootKeyRotationStorer defines the persistence operations for root key rotations.
+type RootKeyRotationStorer interface {
+	// RotateRootKeys replaces the stored root keys of an end device, records the rotation and applies the status
+	// transition, if any, in one transaction. It fails with ErrRootKeysChanged when the stored keys no longer match
+	// previous. The sync function runs before the transaction is committed.
+	RotateRootKeys(ctx context.Context, rotation *RootKeyRotation, previous StoredRootKeys, sealed StoredRootKeys, transition *EndDeviceStatusTransition, sync EndDeviceSync) error
+	ListRootKeyRotations(ctx context.Context, endDeviceId string) ([]*RootKeyRotation, error)
+}
+
+// RootKeyRotationManager replaces the root keys of LoRaWAN end devices, for example after a key has leaked.
+// Rotated devices move back to PENDING until their next uplink shows they re-joined with the new keys.
+type RootKeyRotationManager struct {
+	rotationStore   RootKeyRotationStorer
+	endDeviceStore  EndDeviceStorer
+	rootKeyRegister RootKeyRegister
+	rootKeySealer   RootKeySealer
+	stringId        StringId
+}
+
+// NewRootKeyRotationManager creates a new instance of RootKeyRotationManager with the provided dependencies.
+func NewRootKeyRotationManager(rotationStore RootKeyRotationStorer, eds EndDeviceStorer, rkr RootKeyRegister, rootKeySealer RootKeySealer, stringId StringId) *RootKeyRotationManager {
+	return &RootKeyRotationManager{
+		rotationStore:   rotationStore,
+		endDeviceStore:  eds,
+		rootKeyRegister: rkr,
+		rootKeySealer:   rootKeySealer,
+		stringId:        stringId,
+	}
+}
+
+// RotateEndDeviceRootKeys replaces the root keys of a LoRaWAN end device with the supplied keys, generating any
+// that are empty. The new keys are pushed to the Join Server before the database change is committed; if the
+// commit then fails, the previous keys are pushed again. Disabled devices fail with ErrEndDeviceDisabled.
+func (mgr *RootKeyRotationManager) RotateEndDeviceRootKeys(ctx context.Context, endDeviceId string, organizationId string, keys RootKeys, reason string) (*RootKeyRotation, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "RotateEndDeviceRootKeys")
+	defer span.End()
+
+	keys, err := keys.Normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	current, deviceOrgId, err := mgr.endDeviceStore.GetEndDevice(ctx, endDeviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if deviceOrgId != organizationId {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceNotFound, endDeviceId)
+	}
+
+	if current.GetLorawanConfig() == nil {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrRootKeysUnsupported, endDeviceId)
+	}
+
+	if current.GetStatus() == iotv1.EndDeviceStatus_END_DEVICE_STATUS_DISABLED {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceDisabled, endDeviceId)
+	}
+
+	pending, err := prepareRootKeyRotation(ctx, mgr.rootKeySealer, mgr.stringId, current, keys, reason, StatusReasonRootKeysRotated, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	synced := false
+	sync := func(ctx context.Context) error {
+		err := mgr.rootKeyRegister.UpdateEndDeviceRootKeys(ctx, pending.rotated)
+		if err != nil {
+			return stacktrace.NewStackTraceError(err)
+		}
+		synced = true
+		return nil
+	}
+
+	err = mgr.rotationStore.RotateRootKeys(ctx, pending.rotation, pending.previous, pending.sealed, pending.transition, sync)
+	if err != nil {
+		if synced {
+			// The Join Server already has the new keys; put the previous ones back so both sides agree again
+			restoreErr := mgr.rootKeyRegister.UpdateEndDeviceRootKeys(ctx, current)
+			if restoreErr != nil {
+				return nil, stacktrace.NewStackTraceError(errors.Join(err, restoreErr))
+			}
+		}
+		return nil, err
+	}
+
+	return pending.rotation, nil
+}
+
+// ListRootKeyRotations returns the root key rotation history of an end device, most recent first.
+func (mgr *RootKeyRotationManager) ListRootKeyRotations(ctx context.Context, endDeviceId string, organizationId string) ([]*RootKeyRotation, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListRootKeyRotations")
+	defer span.End()
+
+	_, deviceOrgId, err := mgr.endDeviceStore.GetEndDeviceWithOrganization(ctx, endDeviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if deviceOrgId != organizationId {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s", ErrEndDeviceNotFound, endDeviceId)
+	}
+
+	return mgr.rotationStore.ListRootKeyRotations(ctx, endDeviceId)
+}
+
+// pendingRootKeyRotation is a root key rotation that is ready to be stored and pushed to the Join Server.
+type pendingRootKeyRotation struct {
+	rotation   *RootKeyRotation
+	previous   StoredRootKeys
+	sealed     StoredRootKeys
+	rotated    *iotv1.EndDevice
+	transition *EndDeviceStatusTransition
+}
+
+// prepareRootKeyRotation generates the keys missing from keys, seals them and builds the status transition that
+// sends an ACTIVE or INACTIVE end device back to PENDING until it re-joins. The device must have a LoRaWAN config.
+func prepareRootKeyRotation(ctx context.Context, rootKeySealer RootKeySealer, stringId StringId, current *iotv1.EndDevice, keys RootKeys, reason string, statusReason string, at time.Time) (*pendingRootKeyRotation, error) {
+	rotation := &RootKeyRotation{
+		Id:           stringId(),
+		EndDeviceId:  current.GetId(),
+		Reason:       reason,
+		KeysSupplied: keys.ApplicationKey != "" || keys.NetworkKey != "",
+		RotatedAt:    at,
+	}
+	if rotation.Reason == "" {
+		rotation.Reason = DefaultRootKeyRotationReason
+	}
+
+	var err error
+	applicationKey := keys.ApplicationKey
+	if applicationKey == "" {
+		applicationKey, err = GenerateRootKey()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	networkKey := keys.NetworkKey
+	if networkKey == "" {
+		networkKey, err = GenerateRootKey()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sealedKeys, err := rootKeySealer.SealRootKeys(ctx, applicationKey, networkKey)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated := proto.Clone(current).(*iotv1.EndDevice)
+	rotated.GetLorawanConfig().SetApplicationKey(sealedKeys[0])
+	rotated.GetLorawanConfig().SetNetworkKey(sealedKeys[1])
+
+	// Devices already waiting for their first uplink stay pending
+	var transition *EndDeviceStatusTransition
+	if current.GetStatus() != iotv1.EndDeviceStatus_END_DEVICE_STATUS_PENDING {
+		transition = &EndDeviceStatusTransition{
+			Id:             stringId(),
+			EndDeviceId:    current.GetId(),
+			From:           current.GetStatus(),
+			To:             iotv1.EndDeviceStatus_END_DEVICE_STATUS_PENDING,
+			Reason:         statusReason,
+			TransitionedAt: at,
+		}
+
+		if !CanTransitionEndDeviceStatus(transition.From, transition.To) {
+			return nil, stacktrace.NewStackTraceErrorf("%w: %s cannot move from %s to %s", ErrInvalidStatusTransition, current.GetId(), transition.From, transition.To)
+		}
+	}
+
+	return &pendingRootKeyRotation{
+		rotation: rotation,
+		previous: StoredRootKeys{
+			EndDeviceId:    current.GetId(),
+			ApplicationKey: current.GetLorawanConfig().GetApplicationKey(),
+			NetworkKey:     current.GetLorawanConfig().GetNetworkKey(),
+		},
+		sealed: StoredRootKeys{
+			EndDeviceId:    current.GetId(),
+			ApplicationKey: sealedKeys[0],
+			NetworkKey:     sealedKeys[1],
+		},
+		rotated:    rotated,
+		transition: transition,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeKeyWrapper "wraps" data keys by prefixing them with the master key id.
+type fakeKeyWrapper struct {
+	primaryKeyId string
+}
+
+func (wrapper *fakeKeyWrapper) PrimaryKeyId() string {
+	return wrapper.primaryKeyId
+}
+
+func (wrapper *fakeKeyWrapper) WrapDataKey(ctx context.Context, dataKey []byte) (string, []byte, error) {
+	return wrapper.primaryKeyId, append([]byte(wrapper.primaryKeyId+"/"), dataKey...), nil
+}
+
+func (wrapper *fakeKeyWrapper) UnwrapDataKey(ctx context.Context, keyId string, wrappedKey []byte) ([]byte, error) {
+	dataKey, ok := bytes.CutPrefix(wrappedKey, []byte(keyId+"/"))
+	if !ok {
+		return nil, ErrUnknownMasterKey
+	}
+	return dataKey, nil
+}
+
+func TestRootKeyCipher(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	wrapper := &fakeKeyWrapper{primaryKeyId: "2025-01"}
+	rootKeyCipher := NewRootKeyCipher(wrapper)
+
+	applicationKey := "00112233445566778899AABBCCDDEEFF"
+	sealedKeys, err := rootKeyCipher.SealRootKeys(ctx, applicationKey, "")
+	assert.NoError(err)
+	assert.True(IsSealedRootKey(sealedKeys[0]))
+	assert.NotContains(sealedKeys[0], applicationKey)
+	assert.Equal("2025-01", SealedRootKeyMasterKeyId(sealedKeys[0]))
+	assert.Empty(sealedKeys[1])
+
+	opened, err := rootKeyCipher.OpenRootKey(ctx, sealedKeys[0])
+	assert.NoError(err)
+	assert.Equal(applicationKey, opened)
+
+	wrapper.primaryKeyId = "2025-06"
+	resealedKeys, err := rootKeyCipher.ResealRootKeys(ctx, sealedKeys...)
+	assert.NoError(err)
+	assert.Equal("2025-06", SealedRootKeyMasterKeyId(resealedKeys[0]))
+	assert.Empty(resealedKeys[1])
+
+	opened, err = rootKeyCipher.OpenRootKey(ctx, resealedKeys[0])
+	assert.NoError(err)
+	assert.Equal(applicationKey, opened)
+
+	_, err = rootKeyCipher.OpenRootKey(ctx, applicationKey)
+	assert.ErrorIs(err, ErrInvalidSealedRootKey)
+	assert.Empty(SealedRootKeyMasterKeyId(applicationKey))
+
+	last := resealedKeys[0][len(resealedKeys[0])-1]
+	flipped := "A"
+	if last == 'A' {
+		flipped = "B"
+	}
+	tampered := resealedKeys[0][:len(resealedKeys[0])-1] + flipped
+	_, err = rootKeyCipher.OpenRootKey(ctx, tampered)
+	assert.ErrorIs(err, ErrInvalidSealedRootKey)
+}
@@ -0,0 +1,221 @@
+package javascript
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+	"modernc.org/quickjs"
+)
+
+// SandboxOption configures a Sandbox.
+type SandboxOption func(*Sandbox)
+
+// WithTimeout sets how long a script may run, including defining its functions, before it is stopped.
+func WithTimeout(timeout time.Duration) SandboxOption {
+	return func(sandbox *Sandbox) {
+		sandbox.timeout = timeout
+	}
+}
+
+// WithMemoryLimit sets how many bytes a script may allocate before it fails with an out of memory error.
+func WithMemoryLimit(limit int) SandboxOption {
+	return func(sandbox *Sandbox) {
+		sandbox.memoryLimit = limit
+	}
+}
+
+// Sandbox runs payload decoder scripts in an embedded QuickJS engine. Every run gets a VM of its own that only
+// has the standard built-ins, so scripts cannot reach the file system, the network or each other, and is stopped
+// when it exceeds the time or memory limit.
+type Sandbox struct {
+	timeout     time.Duration
+	memoryLimit int
+}
+
+// NewSandbox creates a new Sandbox. Scripts get 100 milliseconds and 16 MiB unless configured otherwise.
+func NewSandbox(opts ...SandboxOption) *Sandbox {
+	sandbox := &Sandbox{
+		timeout:     100 * time.Millisecond,
+		memoryLimit: 16 << 20,
+	}
+
+	for _, opt := range opts {
+		opt(sandbox)
+	}
+
+	return sandbox
+}
+
+// decoderInput is the JSON form of the input passed to decodeUplink, apart from recvTime which is a Date.
+type decoderInput struct {
+	Bytes []int  `json:"bytes"`
+	FPort uint32 `json:"fPort"`
+}
+
+// decoderOutput is the JSON form of the object returned by decodeUplink.
+type decoderOutput struct {
+	Data     map[string]any `json:"data"`
+	Warnings []string       `json:"warnings"`
+	Errors   []string       `json:"errors"`
+}
+
+// encoderInput is the JSON form of the input passed to encodeDownlink.
+type encoderInput struct {
+	Data  map[string]any `json:"data"`
+	FPort uint32         `json:"fPort,omitempty"`
+}
+
+// encoderOutput is the JSON form of the object returned by encodeDownlink.
+type encoderOutput struct {
+	Bytes    []int    `json:"bytes"`
+	FPort    uint32   `json:"fPort"`
+	Warnings []string `json:"warnings"`
+	Errors   []string `json:"errors"`
+}
+
+// DecodeUplink defines the functions of script and calls its decodeUplink function with input, as The Things Stack
+// does for payload formatters. The result must be an object with a data object, unless it reports errors.
+func (sandbox *Sandbox) DecodeUplink(ctx context.Context, script string, input domain.PayloadDecoderInput) (*domain.PayloadDecoderOutput, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "DecodeUplink")
+	defer span.End()
+
+	bytes := make([]int, len(input.Bytes))
+	for i, b := range input.Bytes {
+		bytes[i] = int(b)
+	}
+
+	rawInput, err := json.Marshal(decoderInput{Bytes: bytes, FPort: input.FPort})
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	call := fmt.Sprintf(
+		"(() => { const input = %s; input.recvTime = new Date(%d); return JSON.stringify(decodeUplink(input)); })()",
+		rawInput,
+		input.ReceivedAt.UnixMilli(),
+	)
+
+	var output decoderOutput
+	err = sandbox.run(ctx, script, "decodeUplink", call, &output)
+	if err != nil {
+		return nil, err
+	}
+
+	if output.Data == nil && len(output.Errors) == 0 {
+		return nil, stacktrace.NewStackTraceErrorf("%w: decodeUplink returned no data object", domain.ErrPayloadDecoderFailed)
+	}
+
+	return &domain.PayloadDecoderOutput{
+		Data:     output.Data,
+		Warnings: output.Warnings,
+		Errors:   output.Errors,
+	}, nil
+}
+
+// EncodeDownlink defines the functions of script and calls its encodeDownlink function with input, as The Things
+// Stack does for payload formatters. The result must be an object with an array of bytes, unless it reports errors.
+func (sandbox *Sandbox) EncodeDownlink(ctx context.Context, script string, input domain.PayloadEncoderInput) (*domain.PayloadEncoderOutput, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "EncodeDownlink")
+	defer span.End()
+
+	rawInput, err := json.Marshal(encoderInput{Data: input.Data, FPort: input.FPort})
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	call := fmt.Sprintf("(() => JSON.stringify(encodeDownlink(%s)))()", rawInput)
+
+	var output encoderOutput
+	err = sandbox.run(ctx, script, "encodeDownlink", call, &output)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(output.Errors) > 0 {
+		return &domain.PayloadEncoderOutput{Warnings: output.Warnings, Errors: output.Errors}, nil
+	}
+
+	if output.Bytes == nil {
+		return nil, stacktrace.NewStackTraceErrorf("%w: encodeDownlink returned no bytes", domain.ErrPayloadDecoderFailed)
+	}
+
+	bytes := make([]byte, len(output.Bytes))
+	for i, b := range output.Bytes {
+		if b < 0 || b > 255 {
+			return nil, stacktrace.NewStackTraceErrorf("%w: encodeDownlink returned %d at index %d, which is not a byte", domain.ErrPayloadDecoderFailed, b, i)
+		}
+		bytes[i] = byte(b)
+	}
+
+	return &domain.PayloadEncoderOutput{
+		Bytes:    bytes,
+		FPort:    output.FPort,
+		Warnings: output.Warnings,
+	}, nil
+}
+
+// run defines the functions of script in a fresh VM, evaluates call, which returns the result of the function
+// named function as JSON, and decodes that into output.
+func (sandbox *Sandbox) run(ctx context.Context, script string, function string, call string, output any) error {
+	timeout := sandbox.timeout
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	if timeout <= 0 {
+		return stacktrace.NewStackTraceError(context.DeadlineExceeded)
+	}
+
+	vm, err := quickjs.NewVM()
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+	defer vm.Close()
+
+	vm.SetMemoryLimit(uintptr(sandbox.memoryLimit))
+
+	// Both evaluations share the timeout, so the second one only gets what the first left over
+	deadline := time.Now().Add(timeout)
+	eval := func(javascript string) (any, error) {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, stacktrace.NewStackTraceErrorf("%w: timed out after %s", domain.ErrPayloadDecoderFailed, timeout)
+		}
+		vm.SetEvalTimeout(remaining)
+
+		result, err := vm.Eval(javascript, quickjs.EvalGlobal)
+		if err != nil {
+			if time.Now().After(deadline) {
+				return nil, stacktrace.NewStackTraceErrorf("%w: timed out after %s", domain.ErrPayloadDecoderFailed, timeout)
+			}
+			return nil, stacktrace.NewStackTraceErrorf("%w: %w", domain.ErrPayloadDecoderFailed, err)
+		}
+		return result, nil
+	}
+
+	_, err = eval(script)
+	if err != nil {
+		return err
+	}
+
+	result, err := eval(call)
+	if err != nil {
+		return err
+	}
+
+	rawOutput, ok := result.(string)
+	if !ok {
+		return stacktrace.NewStackTraceErrorf("%w: %s did not return an object", domain.ErrPayloadDecoderFailed, function)
+	}
+
+	err = json.Unmarshal([]byte(rawOutput), output)
+	if err != nil {
+		return stacktrace.NewStackTraceErrorf("%w: %s returned an invalid result: %w", domain.ErrPayloadDecoderFailed, function, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package javascript
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// temperatureDecoder is a payload formatter as written for The Things Stack: a signed 16 bit temperature in tenths
+// of a degree, followed by the battery voltage in hundredths of a volt.
+const temperatureDecoder = `
+function decodeUplink(input) {
+  if (input.bytes.length < 3) {
+    return { errors: ["expected 3 bytes, got " + input.bytes.length] };
+  }
+  var raw = (input.bytes[0] << 8) | input.bytes[1];
+  if (raw & 0x8000) {
+    raw -= 0x10000;
+  }
+  return {
+    data: {
+      temperature: raw / 10,
+      battery: input.bytes[2] / 100,
+      port: input.fPort,
+      hour: input.recvTime.getUTCHours()
+    },
+    warnings: input.fPort === 2 ? [] : ["unexpected port " + input.fPort]
+  };
+}
+`
+
+func TestSandbox_DecodeUplink(t *testing.T) {
+	input := domain.PayloadDecoderInput{
+		Bytes:      []byte{0xff, 0x47, 0x68},
+		FPort:      2,
+		ReceivedAt: time.Date(2025, 11, 12, 9, 30, 0, 0, time.UTC),
+	}
+
+	t.Run("decodes payloads", func(t *testing.T) {
+		assert := assert.New(t)
+
+		output, err := NewSandbox().DecodeUplink(context.Background(), temperatureDecoder, input)
+		if !assert.NoError(err) {
+			return
+		}
+		assert.Equal(map[string]any{"temperature": -18.5, "battery": 1.04, "port": float64(2), "hour": float64(9)}, output.Data)
+		assert.Empty(output.Warnings)
+		assert.Empty(output.Errors)
+	})
+
+	t.Run("returns warnings and errors reported by the script", func(t *testing.T) {
+		assert := assert.New(t)
+
+		output, err := NewSandbox().DecodeUplink(context.Background(), temperatureDecoder, domain.PayloadDecoderInput{Bytes: []byte{0x00, 0xc8, 0x68}, FPort: 5})
+		if assert.NoError(err) {
+			assert.Equal([]string{"unexpected port 5"}, output.Warnings)
+		}
+
+		output, err = NewSandbox().DecodeUplink(context.Background(), temperatureDecoder, domain.PayloadDecoderInput{Bytes: []byte{0x01}})
+		if assert.NoError(err) {
+			assert.Equal([]string{"expected 3 bytes, got 1"}, output.Errors)
+		}
+	})
+
+	t.Run("reports failing scripts", func(t *testing.T) {
+		for name, script := range map[string]string{
+			"syntax error":    `function decodeUplink(input) {`,
+			"missing decoder": `function decode(input) { return { data: {} }; }`,
+			"exception":       `function decodeUplink(input) { throw new Error("boom"); }`,
+			"no object":       `function decodeUplink(input) { return undefined; }`,
+			"no data":         `function decodeUplink(input) { return { warnings: [] }; }`,
+			"invalid result":  `function decodeUplink(input) { return { data: 42 }; }`,
+		} {
+			_, err := NewSandbox().DecodeUplink(context.Background(), script, input)
+			assert.ErrorIs(t, err, domain.ErrPayloadDecoderFailed, name)
+		}
+	})
+
+	t.Run("stops scripts that run too long", func(t *testing.T) {
+		sandbox := NewSandbox(WithTimeout(50 * time.Millisecond))
+
+		started := time.Now()
+		_, err := sandbox.DecodeUplink(context.Background(), `function decodeUplink(input) { for (;;) {} }`, input)
+		assert.ErrorIs(t, err, domain.ErrPayloadDecoderFailed)
+		assert.ErrorContains(t, err, "timed out")
+		assert.Less(t, time.Since(started), time.Second)
+
+		_, err = sandbox.DecodeUplink(context.Background(), `for (;;) {}`, input)
+		assert.ErrorIs(t, err, domain.ErrPayloadDecoderFailed)
+	})
+
+	t.Run("stops scripts that use too much memory", func(t *testing.T) {
+		sandbox := NewSandbox(WithMemoryLimit(1<<20), WithTimeout(5*time.Second))
+
+		_, err := sandbox.DecodeUplink(context.Background(), `function decodeUplink(input) {
+			var chunks = [];
+			for (;;) { chunks.push(new Array(1024).fill(input.fPort)); }
+		}`, input)
+		assert.ErrorIs(t, err, domain.ErrPayloadDecoderFailed)
+	})
+
+	t.Run("keeps scripts apart", func(t *testing.T) {
+		assert := assert.New(t)
+		sandbox := NewSandbox()
+
+		_, err := sandbox.DecodeUplink(context.Background(), `var leaked = 1; function decodeUplink(input) { return { data: {} }; }`, input)
+		assert.NoError(err)
+
+		output, err := sandbox.DecodeUplink(context.Background(), `function decodeUplink(input) { return { data: { leaked: typeof leaked, require: typeof require, std: typeof std } }; }`, input)
+		if assert.NoError(err) {
+			assert.Equal(map[string]any{"leaked": "undefined", "require": "undefined", "std": "undefined"}, output.Data)
+		}
+	})
+}
+
+// valveEncoder encodes the commands of a valve controller: the valve to open or close and for how many minutes.
+const valveEncoder = `
+function encodeDownlink(input) {
+  if (input.data.valve === undefined) {
+    return { errors: ["valve is required"] };
+  }
+  return {
+    bytes: [input.data.valve, input.data.open ? 1 : 0, input.data.minutes || 0],
+    fPort: input.fPort || 10
+  };
+}
+`
+
+func TestSandbox_EncodeDownlink(t *testing.T) {
+	t.Run("encodes data", func(t *testing.T) {
+		assert := assert.New(t)
+
+		output, err := NewSandbox().EncodeDownlink(context.Background(), valveEncoder, domain.PayloadEncoderInput{Data: map[string]any{"valve": 2, "open": true, "minutes": 15}})
+		if assert.NoError(err) {
+			assert.Equal([]byte{0x02, 0x01, 0x0f}, output.Bytes)
+			assert.Equal(uint32(10), output.FPort)
+		}
+
+		output, err = NewSandbox().EncodeDownlink(context.Background(), valveEncoder, domain.PayloadEncoderInput{Data: map[string]any{"valve": 1}, FPort: 3})
+		if assert.NoError(err) {
+			assert.Equal([]byte{0x01, 0x00, 0x00}, output.Bytes)
+			assert.Equal(uint32(3), output.FPort)
+		}
+	})
+
+	t.Run("returns errors reported by the script", func(t *testing.T) {
+		output, err := NewSandbox().EncodeDownlink(context.Background(), valveEncoder, domain.PayloadEncoderInput{Data: map[string]any{}})
+		if assert.NoError(t, err) {
+			assert.Equal(t, []string{"valve is required"}, output.Errors)
+		}
+	})
+
+	t.Run("reports failing scripts", func(t *testing.T) {
+		for name, script := range map[string]string{
+			"missing encoder": temperatureDecoder,
+			"no bytes":        `function encodeDownlink(input) { return { fPort: 1 }; }`,
+			"not a byte":      `function encodeDownlink(input) { return { bytes: [256] }; }`,
+			"fraction":        `function encodeDownlink(input) { return { bytes: [1.5] }; }`,
+		} {
+			_, err := NewSandbox().EncodeDownlink(context.Background(), script, domain.PayloadEncoderInput{Data: map[string]any{}})
+			assert.ErrorIs(t, err, domain.ErrPayloadDecoderFailed, name)
+		}
+	})
+}
@@ -0,0 +1,193 @@
+package kms
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// masterKeyBytes is the size of an AES-256 master key.
+const masterKeyBytes = 32
+
+// LocalKeyWrapper wraps data keys with AES-256-GCM master keys held in process memory.
+// Master keys are configured as "<id>:<base64 key>" entries, either directly or one per line in a file.
+// Retired master keys stay configured so data keys wrapped with them can still be unwrapped until they are rotated.
+type LocalKeyWrapper struct {
+	primaryKeyId string
+	entries      []string
+	files        []string
+	masterKeys   map[string]cipher.AEAD
+}
+
+// LocalKeyWrapperOption is a functional option for configuring a LocalKeyWrapper.
+type LocalKeyWrapperOption func(*LocalKeyWrapper)
+
+// WithMasterKeys configures master keys given as "<id>:<base64 key>" entries.
+func WithMasterKeys(entries ...string) LocalKeyWrapperOption {
+	return func(wrapper *LocalKeyWrapper) {
+		wrapper.entries = append(wrapper.entries, entries...)
+	}
+}
+
+// WithMasterKeysFile configures master keys read from a file holding one "<id>:<base64 key>" entry per line.
+// Empty lines and lines starting with # are ignored. An empty path is ignored.
+func WithMasterKeysFile(path string) LocalKeyWrapperOption {
+	return func(wrapper *LocalKeyWrapper) {
+		if path != "" {
+			wrapper.files = append(wrapper.files, path)
+		}
+	}
+}
+
+// NewLocalKeyWrapper creates a new LocalKeyWrapper that wraps new data keys with the master key primaryKeyId.
+func NewLocalKeyWrapper(primaryKeyId string, opts ...LocalKeyWrapperOption) (*LocalKeyWrapper, error) {
+	wrapper := &LocalKeyWrapper{
+		primaryKeyId: primaryKeyId,
+		masterKeys:   map[string]cipher.AEAD{},
+	}
+
+	for _, opt := range opts {
+		opt(wrapper)
+	}
+
+	entries := wrapper.entries
+	for _, path := range wrapper.files {
+		fileEntries, err := readMasterKeysFile(path)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+
+	for _, entry := range entries {
+		err := wrapper.addMasterKey(entry)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if wrapper.primaryKeyId == "" {
+		return nil, errors.New("local key wrapper requires a primary master key id")
+	}
+
+	if _, ok := wrapper.masterKeys[wrapper.primaryKeyId]; !ok {
+		return nil, stacktrace.NewStackTraceErrorf("%w: primary master key %q is not configured", domain.ErrUnknownMasterKey, wrapper.primaryKeyId)
+	}
+
+	return wrapper, nil
+}
+
+// PrimaryKeyId returns the id of the master key new data keys are wrapped with.
+func (wrapper *LocalKeyWrapper) PrimaryKeyId() string {
+	return wrapper.primaryKeyId
+}
+
+// WrapDataKey encrypts a data key with the primary master key.
+func (wrapper *LocalKeyWrapper) WrapDataKey(ctx context.Context, dataKey []byte) (string, []byte, error) {
+	_, span := telemetry.Tracer().Start(ctx, "WrapDataKey")
+	defer span.End()
+
+	aead := wrapper.masterKeys[wrapper.primaryKeyId]
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return wrapper.primaryKeyId, aead.Seal(nonce, nonce, dataKey, []byte(wrapper.primaryKeyId)), nil
+}
+
+// UnwrapDataKey decrypts a data key wrapped with the given master key.
+func (wrapper *LocalKeyWrapper) UnwrapDataKey(ctx context.Context, keyId string, wrappedKey []byte) ([]byte, error) {
+	_, span := telemetry.Tracer().Start(ctx, "UnwrapDataKey")
+	defer span.End()
+
+	aead, ok := wrapper.masterKeys[keyId]
+	if !ok {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrUnknownMasterKey, keyId)
+	}
+
+	if len(wrappedKey) < aead.NonceSize() {
+		return nil, stacktrace.NewStackTraceErrorf("%w: wrapped data key is too short", domain.ErrInvalidSealedRootKey)
+	}
+
+	nonce, ciphertext := wrappedKey[:aead.NonceSize()], wrappedKey[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, ciphertext, []byte(keyId))
+	if err != nil {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %w", domain.ErrInvalidSealedRootKey, err)
+	}
+
+	return dataKey, nil
+}
+
+// addMasterKey parses a "<id>:<base64 key>" entry and adds the master key.
+func (wrapper *LocalKeyWrapper) addMasterKey(entry string) error {
+	keyId, encodedKey, ok := strings.Cut(strings.TrimSpace(entry), ":")
+	if !ok || keyId == "" {
+		return errors.New("master key entries must have the form <id>:<base64 key>")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return stacktrace.NewStackTraceErrorf("master key %q is not base64: %w", keyId, err)
+	}
+
+	if len(key) != masterKeyBytes {
+		return stacktrace.NewStackTraceErrorf("master key %q must be %d bytes", keyId, masterKeyBytes)
+	}
+
+	if _, ok := wrapper.masterKeys[keyId]; ok {
+		return stacktrace.NewStackTraceErrorf("master key %q is configured twice", keyId)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	wrapper.masterKeys[keyId] = aead
+
+	return nil
+}
+
+// readMasterKeysFile reads the master key entries of a file, skipping empty lines and comments.
+func readMasterKeysFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+	defer file.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,83 @@
+package modbus
+
+import (
+	"context"
+	"time"
+
+	"github.com/goburrow/modbus"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// DefaultTimeout is how long connecting to a device and each request may take unless configured otherwise.
+const DefaultTimeout = 5 * time.Second
+
+// Dialer connects to Modbus TCP end devices.
+type Dialer struct {
+	timeout time.Duration
+}
+
+// NewDialer creates a new Dialer whose connections and requests time out after timeout.
+// A zero timeout uses DefaultTimeout.
+func NewDialer(timeout time.Duration) *Dialer {
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Dialer{
+		timeout: timeout,
+	}
+}
+
+// DialModbus connects to the Modbus TCP device at host and addresses requests to the given unit ID.
+// The timeout is shortened to the context's deadline when that comes first.
+func (dialer *Dialer) DialModbus(ctx context.Context, host string, unitId uint8) (domain.ModbusConnection, error) {
+	timeout := dialer.timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = min(timeout, time.Until(deadline))
+	}
+
+	handler := modbus.NewTCPClientHandler(host)
+	handler.Timeout = timeout
+	handler.SlaveId = unitId
+
+	err := handler.Connect()
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return &connection{
+		handler: handler,
+		client:  modbus.NewClient(handler),
+	}, nil
+}
+
+// connection is an open connection to a Modbus TCP device.
+type connection struct {
+	handler *modbus.TCPClientHandler
+	client  modbus.Client
+}
+
+// ReadRegisters reads quantity registers starting at address from the holding or input register table.
+func (conn *connection) ReadRegisters(table domain.ModbusRegisterTable, address uint16, quantity uint16) ([]byte, error) {
+	var (
+		data []byte
+		err  error
+	)
+	switch table {
+	case domain.ModbusInputRegister:
+		data, err = conn.client.ReadInputRegisters(address, quantity)
+	default:
+		data, err = conn.client.ReadHoldingRegisters(address, quantity)
+	}
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return data, nil
+}
+
+// Close closes the connection.
+func (conn *connection) Close() error {
+	return conn.handler.Close()
+}
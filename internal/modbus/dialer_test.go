@@ -0,0 +1,121 @@
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// simulator is a minimal Modbus TCP server answering register reads from fixed tables.
+type simulator struct {
+	listener net.Listener
+	holding  map[uint16]uint16
+	input    map[uint16]uint16
+	unitIds  chan byte
+}
+
+// newSimulator starts a simulator on a free local port.
+func newSimulator(t *testing.T, holding map[uint16]uint16, input map[uint16]uint16) *simulator {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not start simulator: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	sim := &simulator{listener: listener, holding: holding, input: input, unitIds: make(chan byte, 16)}
+	go sim.serve()
+
+	return sim
+}
+
+func (sim *simulator) serve() {
+	for {
+		conn, err := sim.listener.Accept()
+		if err != nil {
+			return
+		}
+		go sim.handle(conn)
+	}
+}
+
+// handle answers read holding (3) and read input (4) register requests until the client disconnects.
+func (sim *simulator) handle(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		header := make([]byte, 7)
+		_, err := io.ReadFull(conn, header)
+		if err != nil {
+			return
+		}
+		pdu := make([]byte, binary.BigEndian.Uint16(header[4:6])-1)
+		_, err = io.ReadFull(conn, pdu)
+		if err != nil {
+			return
+		}
+		sim.unitIds <- header[6]
+
+		table := sim.holding
+		if pdu[0] == 4 {
+			table = sim.input
+		}
+		address := binary.BigEndian.Uint16(pdu[1:3])
+		quantity := binary.BigEndian.Uint16(pdu[3:5])
+
+		response := []byte{pdu[0], byte(2 * quantity)}
+		for i := range quantity {
+			response = binary.BigEndian.AppendUint16(response, table[address+i])
+		}
+
+		binary.BigEndian.PutUint16(header[4:6], uint16(len(response)+1))
+		_, err = conn.Write(append(header, response...))
+		if err != nil {
+			return
+		}
+	}
+}
+
+func TestDialer(t *testing.T) {
+	sim := newSimulator(t, map[uint16]uint16{10: 0x0102, 11: 0x0304}, map[uint16]uint16{10: 0xFFFF})
+	dialer := NewDialer(time.Second)
+
+	conn, err := dialer.DialModbus(context.Background(), sim.listener.Addr().String(), 7)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	t.Run("reads holding registers", func(t *testing.T) {
+		data, err := conn.ReadRegisters(domain.ModbusHoldingRegister, 10, 2)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0x01, 0x02, 0x03, 0x04}, data)
+		assert.Equal(t, byte(7), <-sim.unitIds)
+	})
+
+	t.Run("reads input registers", func(t *testing.T) {
+		data, err := conn.ReadRegisters(domain.ModbusInputRegister, 10, 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0xFF, 0xFF}, data)
+		assert.Equal(t, byte(7), <-sim.unitIds)
+	})
+}
+
+func TestDialer_unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	_, err = NewDialer(time.Second).DialModbus(context.Background(), address, 1)
+	assert.Error(t, err)
+}
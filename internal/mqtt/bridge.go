@@ -0,0 +1,184 @@
+package mqtt
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/ponix-dev/ponix/internal/runner"
+)
+
+const (
+	// DefaultTopicFilter matches the default topics of MQTT end devices.
+	DefaultTopicFilter = "ponix/+/up"
+	// disconnectQuiesce is how many milliseconds in-flight work gets to finish when the bridge stops.
+	disconnectQuiesce = 250
+)
+
+// MessageIngester stores the data carried by a message received from the broker.
+type MessageIngester interface {
+	IngestMQTTMessage(ctx context.Context, topic string, payload []byte, receivedAt time.Time) error
+}
+
+// BridgeOption configures a Bridge.
+type BridgeOption func(*Bridge)
+
+// WithBrokerURL sets the URL of the broker, e.g. tcp://localhost:1883.
+func WithBrokerURL(url string) BridgeOption {
+	return func(bridge *Bridge) {
+		bridge.brokerURL = url
+	}
+}
+
+// WithClientID sets the client ID the bridge connects with.
+func WithClientID(clientId string) BridgeOption {
+	return func(bridge *Bridge) {
+		bridge.clientId = clientId
+	}
+}
+
+// WithCredentials sets the username and password the bridge connects with.
+func WithCredentials(username string, password string) BridgeOption {
+	return func(bridge *Bridge) {
+		bridge.username = username
+		bridge.password = password
+	}
+}
+
+// WithTopicFilter sets the topic filter the bridge subscribes to. Devices publishing outside of it are not received.
+func WithTopicFilter(topicFilter string) BridgeOption {
+	return WithTopicFilters(topicFilter)
+}
+
+// WithTopicFilters sets several topic filters the bridge subscribes to, all with the same quality of service.
+func WithTopicFilters(topicFilters ...string) BridgeOption {
+	return func(bridge *Bridge) {
+		bridge.topicFilters = topicFilters
+	}
+}
+
+// WithQoS sets the quality of service the bridge subscribes with.
+func WithQoS(qos byte) BridgeOption {
+	return func(bridge *Bridge) {
+		bridge.qos = qos
+	}
+}
+
+// WithConnectRetryInterval sets how long the bridge waits between attempts to make its first connection.
+func WithConnectRetryInterval(interval time.Duration) BridgeOption {
+	return func(bridge *Bridge) {
+		bridge.connectRetryInterval = interval
+	}
+}
+
+// WithMaxReconnectInterval caps the exponential backoff between attempts to reconnect after losing the connection.
+func WithMaxReconnectInterval(interval time.Duration) BridgeOption {
+	return func(bridge *Bridge) {
+		bridge.maxReconnectInterval = interval
+	}
+}
+
+// Bridge subscribes to an MQTT broker and hands every message it receives to an ingester.
+type Bridge struct {
+	ingester     MessageIngester
+	brokerURL    string
+	clientId     string
+	username     string
+	password     string
+	topicFilters []string
+	qos          byte
+
+	connectRetryInterval time.Duration
+	maxReconnectInterval time.Duration
+}
+
+// NewBridge creates a new Bridge. It connects to tcp://localhost:1883 and subscribes to DefaultTopicFilter
+// with QoS 1 unless configured otherwise. Connecting is retried every 30 seconds and reconnecting backs off to
+// at most 10 minutes between attempts.
+func NewBridge(ingester MessageIngester, opts ...BridgeOption) *Bridge {
+	bridge := &Bridge{
+		ingester:     ingester,
+		brokerURL:    "tcp://localhost:1883",
+		clientId:     "ponix-mqtt-bridge",
+		topicFilters: []string{DefaultTopicFilter},
+		qos:          1,
+
+		connectRetryInterval: 30 * time.Second,
+		maxReconnectInterval: 10 * time.Minute,
+	}
+
+	for _, opt := range opts {
+		opt(bridge)
+	}
+
+	return bridge
+}
+
+// Run connects to the broker and ingests messages until ctx is done. Connecting and reconnecting are retried
+// in the background, so an unavailable broker delays messages instead of stopping the bridge.
+func (bridge *Bridge) Run(ctx context.Context) error {
+	handler := func(_ paho.Client, msg paho.Message) {
+		bridge.handleMessage(ctx, msg)
+	}
+
+	filters := make(map[string]byte, len(bridge.topicFilters))
+	for _, topicFilter := range bridge.topicFilters {
+		filters[topicFilter] = bridge.qos
+	}
+
+	options := paho.NewClientOptions().
+		AddBroker(bridge.brokerURL).
+		SetClientID(bridge.clientId).
+		SetUsername(bridge.username).
+		SetPassword(bridge.password).
+		SetCleanSession(true).
+		SetOrderMatters(false).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(bridge.connectRetryInterval).
+		SetMaxReconnectInterval(bridge.maxReconnectInterval).
+		SetOnConnectHandler(func(client paho.Client) {
+			// Clean sessions lose their subscriptions, so subscribe again after every connect
+			token := client.SubscribeMultiple(filters, handler)
+			if token.Wait() && token.Error() != nil {
+				slog.Error("could not subscribe to mqtt topics", slog.Any("topic_filters", bridge.topicFilters), slog.Any("err", token.Error()))
+				return
+			}
+
+			slog.Info("subscribed to mqtt topics", slog.String("broker", bridge.brokerURL), slog.Any("topic_filters", bridge.topicFilters))
+		}).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			slog.Warn("lost mqtt connection", slog.String("broker", bridge.brokerURL), slog.Any("err", err))
+		})
+
+	client := paho.NewClient(options)
+
+	slog.Info("starting mqtt bridge", slog.String("broker", bridge.brokerURL), slog.String("client_id", bridge.clientId))
+
+	// With connect retry the token only completes once connected, so it is not waited on
+	client.Connect()
+
+	<-ctx.Done()
+
+	client.Disconnect(disconnectQuiesce)
+
+	return nil
+}
+
+// handleMessage ingests a single message. Messages that cannot be ingested are logged and dropped.
+func (bridge *Bridge) handleMessage(ctx context.Context, msg paho.Message) {
+	err := bridge.ingester.IngestMQTTMessage(ctx, msg.Topic(), msg.Payload(), time.Now().UTC())
+	if err != nil {
+		slog.Warn("could not ingest mqtt message", slog.String("topic", msg.Topic()), slog.Any("err", err))
+	}
+}
+
+// BridgeRunner returns a runner function that runs the bridge until the runner stops.
+func BridgeRunner(bridge *Bridge) runner.RunnerFunc {
+	return func(ctx context.Context) func() error {
+		return func() error {
+			return bridge.Run(ctx)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/stretchr/testify/assert"
+)
+
+// ingestedMessage is a message handed to recordingIngester.
+type ingestedMessage struct {
+	topic   string
+	payload string
+}
+
+// recordingIngester passes every ingested message on to a channel.
+type recordingIngester struct {
+	messages chan ingestedMessage
+}
+
+func (ingester *recordingIngester) IngestMQTTMessage(_ context.Context, topic string, payload []byte, _ time.Time) error {
+	ingester.messages <- ingestedMessage{topic: topic, payload: string(payload)}
+	return nil
+}
+
+// TestBridge runs the bridge against the broker in MQTT_TEST_BROKER_URL, e.g. the Mosquitto service of the
+// anonymous test broker of the local Docker Compose setup (tcp://localhost:1884).
+func TestBridge(t *testing.T) {
+	brokerURL := os.Getenv("MQTT_TEST_BROKER_URL")
+	if brokerURL == "" {
+		t.Skip("MQTT_TEST_BROKER_URL is not set")
+	}
+
+	prefix := fmt.Sprintf("ponix-test/%d", time.Now().UnixNano())
+	ingester := &recordingIngester{messages: make(chan ingestedMessage, 16)}
+	bridge := NewBridge(
+		ingester,
+		WithBrokerURL(brokerURL),
+		WithClientID(prefix+"-bridge"),
+		WithTopicFilter(prefix+"/+/up"),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- bridge.Run(ctx)
+	}()
+
+	publisher := paho.NewClient(paho.NewClientOptions().AddBroker(brokerURL).SetClientID(prefix + "-device"))
+	token := publisher.Connect()
+	if !token.WaitTimeout(5*time.Second) || token.Error() != nil {
+		t.Fatalf("publisher did not connect: %v", token.Error())
+	}
+	defer publisher.Disconnect(0)
+
+	topic := prefix + "/device-1/up"
+	payload := `{"temperature":21.5}`
+
+	// The bridge subscribes in the background, so publish until it receives the message
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(10 * time.Second)
+
+	var received ingestedMessage
+receive:
+	for {
+		select {
+		case received = <-ingester.messages:
+			break receive
+		case <-ticker.C:
+			publisher.Publish(topic, 1, false, payload)
+		case <-timeout:
+			t.Fatal("bridge did not receive the message")
+		}
+	}
+
+	assert.Equal(t, topic, received.topic)
+	assert.JSONEq(t, payload, received.payload)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
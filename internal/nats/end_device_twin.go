@@ -0,0 +1,46 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// EndDeviceTwinProducer publishes end device twin changes to a NATS JetStream topic.
+type EndDeviceTwinProducer struct {
+	js      JetstreamPublisher
+	subject string
+}
+
+// NewEndDeviceTwinProducer creates a new NATS JetStream producer for end device twin changes.
+// Changes are published to <subject>.<organization id>.<end device id>.
+func NewEndDeviceTwinProducer(js JetstreamPublisher, subject string) *EndDeviceTwinProducer {
+	return &EndDeviceTwinProducer{
+		js:      js,
+		subject: subject,
+	}
+}
+
+// PublishEndDeviceTwinChange publishes a twin change serialized as JSON.
+func (p *EndDeviceTwinProducer) PublishEndDeviceTwinChange(ctx context.Context, change *domain.EndDeviceTwinChange) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "PublishEndDeviceTwinChange")
+	defer span.End()
+
+	data, err := json.Marshal(change)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	subject := fmt.Sprintf("%s.%s.%s", p.subject, change.OrganizationId, change.Twin.EndDeviceId)
+
+	_, err = p.js.Publish(ctx, subject, data)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
@@ -14,6 +14,11 @@ var (
 			Description: "Stream processed envelopes",
 			Subjects:    []string{"processed_envelopes.>"},
 		},
+		{
+			Name:        "end_device_twins",
+			Description: "Stream end device twin changes",
+			Subjects:    []string{"end_device_twins.>"},
+		},
 	}
 )
 
@@ -0,0 +1,239 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/postgres/sqlc"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// uniqueViolation is the Postgres error code raised when a unique constraint is violated.
+const uniqueViolation = "23505"
+
+// DeviceGroupStore handles database operations for device groups and their membership.
+type DeviceGroupStore struct {
+	db   *sqlc.Queries
+	pool *pgxpool.Pool
+}
+
+// NewDeviceGroupStore creates a new DeviceGroupStore instance.
+func NewDeviceGroupStore(db *sqlc.Queries, pool *pgxpool.Pool) *DeviceGroupStore {
+	return &DeviceGroupStore{
+		db:   db,
+		pool: pool,
+	}
+}
+
+// CreateDeviceGroup inserts a new device group into the database.
+func (store *DeviceGroupStore) CreateDeviceGroup(ctx context.Context, group *domain.DeviceGroup) (*domain.DeviceGroup, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "CreateDeviceGroup")
+	defer span.End()
+
+	row, err := store.db.CreateDeviceGroup(ctx, sqlc.CreateDeviceGroupParams{
+		ID:             group.Id,
+		OrganizationID: group.OrganizationId,
+		Name:           group.Name,
+		Description:    pgtype.Text{String: group.Description, Valid: group.Description != ""},
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrDeviceGroupExists, group.Name)
+		}
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return deviceGroupFromRow(row), nil
+}
+
+// GetDeviceGroup retrieves a device group by ID from the database.
+func (store *DeviceGroupStore) GetDeviceGroup(ctx context.Context, groupID string) (*domain.DeviceGroup, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetDeviceGroup")
+	defer span.End()
+
+	row, err := store.db.GetDeviceGroup(ctx, groupID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrDeviceGroupNotFound, groupID)
+		}
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return deviceGroupFromRow(row), nil
+}
+
+// ListDeviceGroups retrieves all device groups in an organization ordered by name.
+func (store *DeviceGroupStore) ListDeviceGroups(ctx context.Context, organizationID string) ([]*domain.DeviceGroup, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListDeviceGroups")
+	defer span.End()
+
+	rows, err := store.db.ListDeviceGroupsByOrganization(ctx, organizationID)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	groups := make([]*domain.DeviceGroup, len(rows))
+	for i, row := range rows {
+		groups[i] = deviceGroupFromRow(row)
+	}
+
+	return groups, nil
+}
+
+// UpdateDeviceGroup updates the name and description of a device group.
+func (store *DeviceGroupStore) UpdateDeviceGroup(ctx context.Context, group *domain.DeviceGroup) (*domain.DeviceGroup, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "UpdateDeviceGroup")
+	defer span.End()
+
+	row, err := store.db.UpdateDeviceGroup(ctx, sqlc.UpdateDeviceGroupParams{
+		ID:          group.Id,
+		Name:        group.Name,
+		Description: pgtype.Text{String: group.Description, Valid: group.Description != ""},
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrDeviceGroupNotFound, group.Id)
+		}
+		if isUniqueViolation(err) {
+			return nil, stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrDeviceGroupExists, group.Name)
+		}
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return deviceGroupFromRow(row), nil
+}
+
+// DeleteDeviceGroup deletes a device group and, through cascading deletes, its memberships.
+func (store *DeviceGroupStore) DeleteDeviceGroup(ctx context.Context, groupID string) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "DeleteDeviceGroup")
+	defer span.End()
+
+	err := store.db.DeleteDeviceGroup(ctx, groupID)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// AddDeviceGroupMembers adds end devices to a device group within a transaction.
+// Every device must belong to the given organization; otherwise nothing is added and ErrEndDeviceNotFound is returned.
+func (store *DeviceGroupStore) AddDeviceGroupMembers(ctx context.Context, groupID string, organizationID string, endDeviceIDs []string) (int, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "AddDeviceGroupMembers")
+	defer span.End()
+
+	tx, err := store.pool.Begin(ctx)
+	if err != nil {
+		return 0, stacktrace.NewStackTraceError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	txQueries := store.db.WithTx(tx)
+
+	found, err := txQueries.ListEndDeviceIDsInOrganization(ctx, sqlc.ListEndDeviceIDsInOrganizationParams{
+		OrganizationID: organizationID,
+		EndDeviceIds:   endDeviceIDs,
+	})
+	if err != nil {
+		return 0, stacktrace.NewStackTraceError(err)
+	}
+
+	for _, id := range endDeviceIDs {
+		if !slices.Contains(found, id) {
+			return 0, stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrEndDeviceNotFound, id)
+		}
+	}
+
+	added, err := txQueries.AddDeviceGroupMembers(ctx, sqlc.AddDeviceGroupMembersParams{
+		DeviceGroupID: groupID,
+		EndDeviceIds:  endDeviceIDs,
+	})
+	if err != nil {
+		return 0, stacktrace.NewStackTraceError(err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, stacktrace.NewStackTraceError(err)
+	}
+
+	return int(added), nil
+}
+
+// RemoveDeviceGroupMembers removes end devices from a device group. Devices that are not members are ignored.
+func (store *DeviceGroupStore) RemoveDeviceGroupMembers(ctx context.Context, groupID string, endDeviceIDs []string) (int, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "RemoveDeviceGroupMembers")
+	defer span.End()
+
+	removed, err := store.db.RemoveDeviceGroupMembers(ctx, sqlc.RemoveDeviceGroupMembersParams{
+		DeviceGroupID: groupID,
+		EndDeviceIds:  endDeviceIDs,
+	})
+	if err != nil {
+		return 0, stacktrace.NewStackTraceError(err)
+	}
+
+	return int(removed), nil
+}
+
+// ListDeviceGroupMembers retrieves the end devices in a device group without their hardware-specific configuration.
+func (store *DeviceGroupStore) ListDeviceGroupMembers(ctx context.Context, groupID string) ([]*iotv1.EndDevice, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListDeviceGroupMembers")
+	defer span.End()
+
+	rows, err := store.db.ListDeviceGroupMembers(ctx, groupID)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	endDevices := make([]*iotv1.EndDevice, len(rows))
+	for i, row := range rows {
+		endDevices[i] = endDeviceFromRow(row)
+	}
+
+	return endDevices, nil
+}
+
+// ListEndDeviceGroups retrieves the device groups an end device belongs to.
+func (store *DeviceGroupStore) ListEndDeviceGroups(ctx context.Context, endDeviceID string) ([]*domain.DeviceGroup, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListEndDeviceGroups")
+	defer span.End()
+
+	rows, err := store.db.ListEndDeviceGroups(ctx, endDeviceID)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	groups := make([]*domain.DeviceGroup, len(rows))
+	for i, row := range rows {
+		groups[i] = deviceGroupFromRow(row)
+	}
+
+	return groups, nil
+}
+
+// deviceGroupFromRow builds a DeviceGroup from a database row.
+func deviceGroupFromRow(row sqlc.DeviceGroup) *domain.DeviceGroup {
+	return &domain.DeviceGroup{
+		Id:             row.ID,
+		OrganizationId: row.OrganizationID,
+		Name:           row.Name,
+		Description:    row.Description.String,
+		CreatedAt:      row.CreatedAt.Time,
+		UpdatedAt:      row.UpdatedAt.Time,
+	}
+}
+
+// isUniqueViolation reports whether err was caused by a unique constraint violation.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
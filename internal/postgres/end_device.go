@@ -2,12 +2,16 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"strings"
+	"time"
 
 	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ponix-dev/ponix/internal/domain"
 	"github.com/ponix-dev/ponix/internal/postgres/sqlc"
 	"github.com/ponix-dev/ponix/internal/telemetry"
 	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
@@ -30,7 +34,9 @@ func NewEndDeviceStore(db *sqlc.Queries, pool *pgxpool.Pool) *EndDeviceStore {
 
 // AddEndDevice inserts a new end device and its associated configuration into the database.
 // For LoRaWAN devices, this also creates the corresponding LoRaWAN configuration within a transaction.
-func (store *EndDeviceStore) AddEndDevice(ctx context.Context, endDevice *iotv1.EndDevice, organizationID string) error {
+// The sync function runs after the rows are written but before they are committed, so a failure to
+// register the device with an external system leaves nothing behind in the database.
+func (store *EndDeviceStore) AddEndDevice(ctx context.Context, endDevice *iotv1.EndDevice, organizationID string, metadata domain.EndDeviceMetadata, sync domain.EndDeviceSync) error {
 	ctx, span := telemetry.Tracer().Start(ctx, "CreateEndDevice")
 	defer span.End()
 
@@ -40,7 +46,80 @@ func (store *EndDeviceStore) AddEndDevice(ctx context.Context, endDevice *iotv1.
 	}
 	defer tx.Rollback(ctx)
 
-	txQueries := store.db.WithTx(tx)
+	err = insertEndDevice(ctx, store.db.WithTx(tx), endDevice, organizationID, metadata)
+	if err != nil {
+		return err
+	}
+
+	if sync != nil {
+		err = sync(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// AddEndDevices inserts a batch of end devices within a single transaction.
+// Each device is written in its own savepoint, so a device that fails to insert is rolled back
+// without affecting the rest of the batch. The returned slice holds the outcome of each device in order;
+// the error is set when the batch as a whole could not be committed, in which case nothing was stored.
+func (store *EndDeviceStore) AddEndDevices(ctx context.Context, endDevices []domain.PendingEndDevice) ([]error, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "AddEndDevices")
+	defer span.End()
+
+	tx, err := store.pool.Begin(ctx)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	results := make([]error, len(endDevices))
+	for i, pending := range endDevices {
+		results[i] = store.addEndDeviceInSavepoint(ctx, tx, pending)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return results, stacktrace.NewStackTraceError(err)
+	}
+
+	return results, nil
+}
+
+// addEndDeviceInSavepoint writes a single device of a batch inside a savepoint of tx.
+func (store *EndDeviceStore) addEndDeviceInSavepoint(ctx context.Context, tx pgx.Tx, pending domain.PendingEndDevice) error {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+	defer savepoint.Rollback(ctx)
+
+	err = insertEndDevice(ctx, store.db.WithTx(savepoint), pending.EndDevice, pending.OrganizationId, pending.Metadata)
+	if err != nil {
+		return err
+	}
+
+	err = savepoint.Commit(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// insertEndDevice writes an end device and its hardware-specific configuration using the given queries.
+func insertEndDevice(ctx context.Context, queries *sqlc.Queries, endDevice *iotv1.EndDevice, organizationID string, metadata domain.EndDeviceMetadata) error {
+	labels, attributes, err := marshalEndDeviceMetadata(metadata)
+	if err != nil {
+		return err
+	}
 
 	endDeviceParams := sqlc.CreateEndDeviceParams{
 		ID:             endDevice.GetId(),
@@ -50,13 +129,25 @@ func (store *EndDeviceStore) AddEndDevice(ctx context.Context, endDevice *iotv1.
 		Status:         int32(endDevice.GetStatus()),
 		DataType:       int32(endDevice.GetDataType()), // Deprecated field, will be nullable after migration
 		HardwareType:   int32(endDevice.GetHardwareType()),
+		Labels:         labels,
+		Attributes:     attributes,
 	}
 
-	_, err = txQueries.CreateEndDevice(ctx, endDeviceParams)
+	_, err = queries.CreateEndDevice(ctx, endDeviceParams)
 	if err != nil {
 		return stacktrace.NewStackTraceError(err)
 	}
 
+	if endDevice.GetProfileId() != "" {
+		err = queries.AssignEndDeviceProfile(ctx, sqlc.AssignEndDeviceProfileParams{
+			EndDeviceID:        endDevice.GetId(),
+			EndDeviceProfileID: endDevice.GetProfileId(),
+		})
+		if err != nil {
+			return stacktrace.NewStackTraceError(err)
+		}
+	}
+
 	// Add hardware-specific configuration if needed
 	switch endDevice.GetHardwareType() {
 	case iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_LORAWAN:
@@ -78,18 +169,275 @@ func (store *EndDeviceStore) AddEndDevice(ctx context.Context, endDevice *iotv1.
 			HardwareTypeID:   lorawanConfig.GetHardwareData().GetHardwareTypeId(),
 		}
 
-		_, err = txQueries.CreateLoRaWANConfig(ctx, lorawanParams)
+		_, err = queries.CreateLoRaWANConfig(ctx, lorawanParams)
 		if err != nil {
+			// Another device took the same EUI since it was checked
+			if isUniqueViolation(err) {
+				return stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrDeviceEUIInUse, lorawanConfig.GetDeviceEui())
+			}
 			return stacktrace.NewStackTraceError(err)
 		}
 	case iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_HTTP:
 		// HTTP devices don't have additional config tables
 		// No additional operations needed
+	case iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_MQTT:
+		mqttConfig := endDevice.GetMqttConfig()
+		if mqttConfig == nil || mqttConfig.GetPasswordHash() == "" {
+			return stacktrace.NewStackTraceErrorf("MQTT device requires mqtt_config with a password hash")
+		}
+
+		err = queries.CreateMQTTConfig(ctx, sqlc.CreateMQTTConfigParams{
+			ID:           xid.New().String(),
+			EndDeviceID:  endDevice.GetId(),
+			Topic:        mqttConfig.GetTopic(),
+			Username:     mqttConfig.GetUsername(),
+			PasswordHash: mqttConfig.GetPasswordHash(),
+		})
+		if err != nil {
+			if isUniqueViolation(err) {
+				return stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrMQTTConfigInUse, mqttConfig.GetTopic())
+			}
+			return stacktrace.NewStackTraceError(err)
+		}
+	case iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_MODBUS:
+		if !endDevice.HasModbusConfig() {
+			return stacktrace.NewStackTraceErrorf("Modbus device requires modbus_config")
+		}
+
+		modbusConfig, err := domain.ModbusConfigFromProto(endDevice.GetModbusConfig())
+		if err != nil {
+			return err
+		}
+
+		registers, err := json.Marshal(modbusConfig.Registers)
+		if err != nil {
+			return stacktrace.NewStackTraceError(err)
+		}
+
+		err = queries.CreateModbusConfig(ctx, sqlc.CreateModbusConfigParams{
+			ID:                  xid.New().String(),
+			EndDeviceID:         endDevice.GetId(),
+			Host:                modbusConfig.Host,
+			UnitID:              int16(modbusConfig.UnitId),
+			PollIntervalSeconds: int32(modbusConfig.PollInterval / time.Second),
+			Registers:           registers,
+		})
+		if err != nil {
+			return stacktrace.NewStackTraceError(err)
+		}
 	default:
 		return stacktrace.NewStackTraceErrorf("unsupported hardware type: %v", endDevice.GetHardwareType())
 	}
 
-	return tx.Commit(ctx)
+	return nil
+}
+
+// UpdateEndDevice updates an end device, its labels and attributes and its hardware-specific configuration within a
+// transaction. The sync function runs after the changes are written but before they are committed, so a failure to
+// propagate the change to an external registry rolls back the database update.
+func (store *EndDeviceStore) UpdateEndDevice(ctx context.Context, endDevice *iotv1.EndDevice, metadata domain.EndDeviceMetadata, sync domain.EndDeviceSync) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "UpdateEndDevice")
+	defer span.End()
+
+	labels, attributes, err := marshalEndDeviceMetadata(metadata)
+	if err != nil {
+		return err
+	}
+
+	tx, err := store.pool.Begin(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	txQueries := store.db.WithTx(tx)
+
+	_, err = txQueries.UpdateEndDevice(ctx, sqlc.UpdateEndDeviceParams{
+		ID:           endDevice.GetId(),
+		Name:         endDevice.GetName(),
+		Description:  pgtype.Text{String: endDevice.GetDescription(), Valid: endDevice.GetDescription() != ""},
+		Status:       int32(endDevice.GetStatus()),
+		DataType:     int32(endDevice.GetDataType()), // Deprecated field
+		HardwareType: int32(endDevice.GetHardwareType()),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrEndDeviceNotFound, endDevice.GetId())
+		}
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	_, err = txQueries.UpdateEndDeviceMetadata(ctx, sqlc.UpdateEndDeviceMetadataParams{
+		ID:         endDevice.GetId(),
+		Labels:     labels,
+		Attributes: attributes,
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	switch endDevice.GetHardwareType() {
+	case iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_LORAWAN:
+		lorawanConfig := endDevice.GetLorawanConfig()
+		if lorawanConfig == nil {
+			return stacktrace.NewStackTraceErrorf("LoRaWAN device requires lorawan_config")
+		}
+
+		existing, err := txQueries.GetLoRaWANConfigByEndDevice(ctx, endDevice.GetId())
+		if err != nil {
+			return stacktrace.NewStackTraceError(err)
+		}
+
+		_, err = txQueries.UpdateLoRaWANConfig(ctx, sqlc.UpdateLoRaWANConfigParams{
+			ID:               existing.ID,
+			DeviceEui:        lorawanConfig.GetDeviceEui(),
+			ApplicationEui:   lorawanConfig.GetApplicationEui(),
+			ApplicationID:    lorawanConfig.GetApplicationId(),
+			ApplicationKey:   lorawanConfig.GetApplicationKey(),
+			NetworkKey:       pgtype.Text{String: lorawanConfig.GetNetworkKey(), Valid: lorawanConfig.GetNetworkKey() != ""},
+			ActivationMethod: int32(lorawanConfig.GetActivationMethod()),
+			FrequencyPlanID:  lorawanConfig.GetFrequencyPlan(),
+			HardwareTypeID:   lorawanConfig.GetHardwareData().GetHardwareTypeId(),
+		})
+		if err != nil {
+			return stacktrace.NewStackTraceError(err)
+		}
+	case iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_HTTP:
+		// HTTP devices don't have additional config tables
+	case iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_MQTT, iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_MODBUS:
+		// The configuration of MQTT and Modbus devices is only set when they are created
+	default:
+		return stacktrace.NewStackTraceErrorf("unsupported hardware type: %v", endDevice.GetHardwareType())
+	}
+
+	if sync != nil {
+		err = sync(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// DeleteEndDevice deletes an end device and, through cascading deletes, its hardware-specific configuration.
+// The sync function runs before the transaction is committed, so a failure to remove the device from an
+// external registry leaves the database untouched.
+func (store *EndDeviceStore) DeleteEndDevice(ctx context.Context, endDeviceID string, sync domain.EndDeviceSync) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "DeleteEndDevice")
+	defer span.End()
+
+	tx, err := store.pool.Begin(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	txQueries := store.db.WithTx(tx)
+
+	err = txQueries.DeleteEndDevice(ctx, endDeviceID)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	if sync != nil {
+		err = sync(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// GetEndDeviceMetadata retrieves the labels and attributes of an end device and its organization ID.
+func (store *EndDeviceStore) GetEndDeviceMetadata(ctx context.Context, endDeviceID string) (domain.EndDeviceMetadata, string, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetEndDeviceMetadata")
+	defer span.End()
+
+	row, err := store.db.GetEndDevice(ctx, endDeviceID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.EndDeviceMetadata{}, "", stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrEndDeviceNotFound, endDeviceID)
+		}
+		return domain.EndDeviceMetadata{}, "", stacktrace.NewStackTraceError(err)
+	}
+
+	metadata, err := unmarshalEndDeviceMetadata(row.Labels, row.Attributes)
+	if err != nil {
+		return domain.EndDeviceMetadata{}, "", err
+	}
+
+	return metadata, row.OrganizationID, nil
+}
+
+// ListEndDeviceIDsByLabels retrieves the IDs of the end devices in an organization matching a label selector.
+func (store *EndDeviceStore) ListEndDeviceIDsByLabels(ctx context.Context, organizationID string, selector domain.LabelSelector) ([]string, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListEndDeviceIDsByLabels")
+	defer span.End()
+
+	labels, err := newLabelSelectorParams(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := store.db.ListEndDeviceIDsByLabels(ctx, sqlc.ListEndDeviceIDsByLabelsParams{
+		OrganizationID: organizationID,
+		LabelEquals:    labels.equals,
+		LabelIn:        labels.in,
+		LabelNotIn:     labels.notIn,
+		LabelExists:    labels.exists,
+		LabelNotExists: labels.notExists,
+	})
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return ids, nil
+}
+
+// ListEndDeviceIDsByGroups retrieves the IDs of the end devices that belong to any of the given device groups.
+// Groups that do not exist in the organization are reported as ErrDeviceGroupNotFound.
+func (store *EndDeviceStore) ListEndDeviceIDsByGroups(ctx context.Context, organizationID string, groupIDs []string) ([]string, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListEndDeviceIDsByGroups")
+	defer span.End()
+
+	rows, err := store.db.ListDeviceGroupMembersByGroups(ctx, sqlc.ListDeviceGroupMembersByGroupsParams{
+		OrganizationID: organizationID,
+		DeviceGroupIds: groupIDs,
+	})
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	groups := map[string]bool{}
+	seen := map[string]bool{}
+	ids := []string{}
+	for _, row := range rows {
+		groups[row.DeviceGroupID] = true
+		if row.EndDeviceID.Valid && !seen[row.EndDeviceID.String] {
+			seen[row.EndDeviceID.String] = true
+			ids = append(ids, row.EndDeviceID.String)
+		}
+	}
+
+	for _, groupID := range groupIDs {
+		if !groups[groupID] {
+			return nil, stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrDeviceGroupNotFound, groupID)
+		}
+	}
+
+	return ids, nil
 }
 
 // GetLoRaWANHardwareType retrieves a LoRaWAN hardware type by ID from the database.
@@ -126,7 +474,7 @@ func (store *EndDeviceStore) GetEndDeviceWithOrganization(ctx context.Context, e
 	endDeviceRow, err := store.db.GetEndDeviceWithOrganization(ctx, endDeviceID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, "", stacktrace.NewStackTraceErrorf("end device not found: %s", endDeviceID)
+			return nil, "", stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrEndDeviceNotFound, endDeviceID)
 		}
 		return nil, "", stacktrace.NewStackTraceError(err)
 	}
@@ -147,6 +495,102 @@ func (store *EndDeviceStore) GetEndDeviceWithOrganization(ctx context.Context, e
 	return endDeviceBuilder.Build(), endDeviceRow.OrganizationID, nil
 }
 
+// GetEndDeviceByDeviceEUI retrieves the LoRaWAN end device with the given device EUI and its organization ID.
+func (store *EndDeviceStore) GetEndDeviceByDeviceEUI(ctx context.Context, deviceEui string) (*iotv1.EndDevice, string, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetEndDeviceByDeviceEUI")
+	defer span.End()
+
+	lorawanConfig, err := store.db.GetLoRaWANConfigByDeviceEUI(ctx, deviceEui)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, "", stacktrace.NewStackTraceErrorf("%w: no end device with device EUI %s", domain.ErrEndDeviceNotFound, deviceEui)
+		}
+		return nil, "", stacktrace.NewStackTraceError(err)
+	}
+
+	return store.GetEndDeviceWithOrganization(ctx, lorawanConfig.EndDeviceID)
+}
+
+// GetEndDevice retrieves an end device with its complete hardware configuration, the profile it was created from
+// and its organization ID. LoRaWAN devices include their identifiers, sealed root keys, frequency plan and hardware
+// data.
+func (store *EndDeviceStore) GetEndDevice(ctx context.Context, endDeviceID string) (*iotv1.EndDevice, string, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetEndDevice")
+	defer span.End()
+
+	endDevice, organizationID, err := store.GetEndDeviceWithOrganization(ctx, endDeviceID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch endDevice.GetHardwareType() {
+	case iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_LORAWAN:
+		endDevice, err = store.GetCompleteLoRaWANDevice(ctx, endDeviceID)
+		if err != nil {
+			return nil, "", err
+		}
+	case iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_MQTT:
+		mqttConfig, err := store.getMQTTConfig(ctx, endDeviceID)
+		if err != nil {
+			return nil, "", err
+		}
+		endDevice.SetMqttConfig(mqttConfig)
+	case iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_MODBUS:
+		modbusConfig, err := store.getModbusConfig(ctx, endDeviceID)
+		if err != nil {
+			return nil, "", err
+		}
+		endDevice.SetModbusConfig(modbusConfig)
+	case iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_HTTP:
+		// HTTP devices don't have additional config tables
+	}
+
+	profileID, err := store.db.GetEndDeviceProfileID(ctx, endDeviceID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, "", stacktrace.NewStackTraceError(err)
+	}
+	endDevice.SetProfileId(profileID)
+
+	return endDevice, organizationID, nil
+}
+
+// getMQTTConfig retrieves the topic and username of an MQTT end device. The password hash is not read, so it is
+// never returned to clients.
+func (store *EndDeviceStore) getMQTTConfig(ctx context.Context, endDeviceID string) (*iotv1.MQTTConfig, error) {
+	row, err := store.db.GetMQTTConfig(ctx, endDeviceID)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return iotv1.MQTTConfig_builder{
+		Topic:    row.Topic,
+		Username: row.Username,
+	}.Build(), nil
+}
+
+// getModbusConfig retrieves the host, unit ID, poll interval and register map of a Modbus end device.
+func (store *EndDeviceStore) getModbusConfig(ctx context.Context, endDeviceID string) (*iotv1.ModbusConfig, error) {
+	row, err := store.db.GetModbusConfig(ctx, endDeviceID)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	var registers []domain.ModbusRegister
+	err = json.Unmarshal(row.Registers, &registers)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	config := domain.ModbusConfig{
+		Host:         row.Host,
+		UnitId:       uint8(row.UnitID),
+		PollInterval: time.Duration(row.PollIntervalSeconds) * time.Second,
+		Registers:    registers,
+	}
+
+	return config.ToProto(), nil
+}
+
 // GetCompleteLoRaWANDevice retrieves a complete LoRaWAN device with its configuration from the database.
 func (store *EndDeviceStore) GetCompleteLoRaWANDevice(ctx context.Context, endDeviceID string) (*iotv1.EndDevice, error) {
 	ctx, span := telemetry.Tracer().Start(ctx, "GetCompleteLoRaWANDevice")
@@ -154,44 +598,301 @@ func (store *EndDeviceStore) GetCompleteLoRaWANDevice(ctx context.Context, endDe
 
 	deviceData, err := store.db.GetCompleteLoRaWANDevice(ctx, endDeviceID)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrEndDeviceNotFound, endDeviceID)
+		}
+		return nil, stacktrace.NewStackTraceError(err)
 	}
 
+	hardwareData := iotv1.LoRaWANHardwareData_builder{
+		HardwareTypeId:  deviceData.HardwareTypeID,
+		Name:            deviceData.HardwareName,
+		Description:     deviceData.HardwareDescription.String,
+		Manufacturer:    deviceData.Manufacturer,
+		Model:           deviceData.Model,
+		FirmwareVersion: deviceData.FirmwareVersion.String,
+		HardwareVersion: deviceData.HardwareVersion.String,
+		Profile:         deviceData.Profile.String,
+		LorawanVersion:  iotv1.LORAWANVersion(deviceData.LorawanVersion),
+	}.Build()
+
+	lorawanConfig := iotv1.LoRaWANConfig_builder{
+		DeviceEui:        deviceData.DeviceEui,
+		ApplicationEui:   deviceData.ApplicationEui,
+		ApplicationId:    deviceData.ApplicationID,
+		ApplicationKey:   deviceData.ApplicationKey,
+		NetworkKey:       deviceData.NetworkKey.String,
+		ActivationMethod: iotv1.ActivationMethod(deviceData.ActivationMethod),
+		FrequencyPlan:    deviceData.FrequencyPlanID,
+		HardwareData:     hardwareData,
+	}.Build()
+
 	// Build complete end device using builder pattern
 	endDeviceBuilder := iotv1.EndDevice_builder{
-		Id:     deviceData.EndDeviceID,
-		Name:   deviceData.Name,
-		Status: iotv1.EndDeviceStatus(deviceData.Status),
-		// TODO: Add complete LoRaWAN configuration once protobuf structure is confirmed
+		Id:            deviceData.EndDeviceID,
+		Name:          deviceData.Name,
+		Description:   deviceData.Description.String,
+		Status:        iotv1.EndDeviceStatus(deviceData.Status),
+		HardwareType:  iotv1.EndDeviceHardwareType(deviceData.HardwareType),
+		DataType:      iotv1.EndDeviceDataType(deviceData.DataType), // Deprecated field
+		LorawanConfig: lorawanConfig,
 	}
 
 	return endDeviceBuilder.Build(), nil
 }
 
-// ListEndDevicesByOrganization retrieves all end devices belonging to an organization from the database.
-func (store *EndDeviceStore) ListEndDevicesByOrganization(ctx context.Context, organizationID string) ([]*iotv1.EndDevice, error) {
-	ctx, span := telemetry.Tracer().Start(ctx, "ListEndDevicesByOrganization")
+// ListEndDevices retrieves a page of end devices of every hardware type in an organization.
+// One extra row is fetched to determine whether a further page exists; when it does, the cursor of the
+// last returned device is provided for the next request.
+func (store *EndDeviceStore) ListEndDevices(ctx context.Context, query domain.EndDeviceListQuery) ([]*iotv1.EndDevice, *domain.EndDeviceCursor, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListEndDevices")
 	defer span.End()
 
-	devices, err := store.db.ListCompleteLoRaWANDevicesByOrganization(ctx, organizationID)
+	filter := query.Filter
+	status := pgtype.Int4{Int32: int32(filter.Status), Valid: filter.Status != iotv1.EndDeviceStatus_END_DEVICE_STATUS_UNSPECIFIED}
+	hardwareType := pgtype.Int4{Int32: int32(filter.HardwareType), Valid: filter.HardwareType != iotv1.EndDeviceHardwareType_END_DEVICE_HARDWARE_TYPE_UNSPECIFIED}
+	hardwareTypeID := pgtype.Text{String: filter.HardwareTypeId, Valid: filter.HardwareTypeId != ""}
+	namePrefix := pgtype.Text{String: escapeLikePattern(filter.NamePrefix), Valid: filter.NamePrefix != ""}
+	notSeenSince := pgtype.Timestamptz{Time: filter.NotSeenSince, Valid: !filter.NotSeenSince.IsZero()}
+
+	labels, err := newLabelSelectorParams(filter.Labels)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	var cursorID pgtype.Text
+	if query.After != nil {
+		cursorID = pgtype.Text{String: query.After.Id, Valid: true}
+	}
+
+	var rows []sqlc.EndDevice
+	switch query.SortBy {
+	case domain.EndDeviceSortByCreatedAt:
+		params := sqlc.ListEndDevicesPageByCreatedAtParams{
+			OrganizationID: query.OrganizationId,
+			Status:         status,
+			HardwareType:   hardwareType,
+			HardwareTypeID: hardwareTypeID,
+			NamePrefix:     namePrefix,
+			LabelEquals:    labels.equals,
+			LabelIn:        labels.in,
+			LabelNotIn:     labels.notIn,
+			LabelExists:    labels.exists,
+			LabelNotExists: labels.notExists,
+			DeviceGroupIds: filter.GroupIds,
+			NotSeenSince:   notSeenSince,
+			CursorID:       cursorID,
+			PageLimit:      int32(query.Limit + 1),
+		}
+		if query.After != nil && query.After.CreatedAt != nil {
+			params.CursorCreatedAt = pgtype.Timestamptz{Time: *query.After.CreatedAt, Valid: true}
+		}
+
+		rows, err = store.db.ListEndDevicesPageByCreatedAt(ctx, params)
+	default:
+		params := sqlc.ListEndDevicesPageByNameParams{
+			OrganizationID: query.OrganizationId,
+			Status:         status,
+			HardwareType:   hardwareType,
+			HardwareTypeID: hardwareTypeID,
+			NamePrefix:     namePrefix,
+			LabelEquals:    labels.equals,
+			LabelIn:        labels.in,
+			LabelNotIn:     labels.notIn,
+			LabelExists:    labels.exists,
+			LabelNotExists: labels.notExists,
+			DeviceGroupIds: filter.GroupIds,
+			NotSeenSince:   notSeenSince,
+			CursorID:       cursorID,
+			PageLimit:      int32(query.Limit + 1),
+		}
+		if query.After != nil {
+			params.CursorName = pgtype.Text{String: query.After.Name, Valid: true}
+		}
+
+		rows, err = store.db.ListEndDevicesPageByName(ctx, params)
+	}
+	if err != nil {
+		return nil, nil, stacktrace.NewStackTraceError(err)
+	}
+
+	var next *domain.EndDeviceCursor
+	if len(rows) > query.Limit {
+		rows = rows[:query.Limit]
+		last := rows[len(rows)-1]
+		next = &domain.EndDeviceCursor{
+			Id:        last.ID,
+			Name:      last.Name,
+			CreatedAt: &last.CreatedAt.Time,
+		}
+	}
+
+	endDevices := make([]*iotv1.EndDevice, len(rows))
+	for i, row := range rows {
+		endDevices[i] = endDeviceFromRow(row)
+	}
+
+	return endDevices, next, nil
+}
+
+// SearchEndDevices retrieves a page of the end devices of a set of organizations matching a free-text query, best
+// matches first. Like ListEndDevices, one extra row is fetched to determine whether a further page exists.
+func (store *EndDeviceStore) SearchEndDevices(ctx context.Context, query domain.EndDeviceSearchQuery) ([]domain.EndDeviceSearchResult, *domain.EndDeviceCursor, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "SearchEndDevices")
+	defer span.End()
+
+	params := sqlc.SearchEndDevicesParams{
+		Query:           query.Query,
+		DeviceEui:       pgtype.Text{String: query.DeviceEui, Valid: query.DeviceEui != ""},
+		ModelPattern:    escapeLikePattern(query.Query),
+		OrganizationIds: query.OrganizationIds,
+		PageLimit:       int32(query.Limit + 1),
+	}
+	if query.After != nil {
+		params.CursorID = pgtype.Text{String: query.After.Id, Valid: true}
+		params.CursorRank = pgtype.Float8{Float64: query.After.Rank, Valid: true}
+	}
+
+	rows, err := store.db.SearchEndDevices(ctx, params)
+	if err != nil {
+		return nil, nil, stacktrace.NewStackTraceError(err)
+	}
+
+	var next *domain.EndDeviceCursor
+	if len(rows) > query.Limit {
+		rows = rows[:query.Limit]
+		last := rows[len(rows)-1]
+		next = &domain.EndDeviceCursor{
+			Id:   last.ID,
+			Rank: last.Rank,
+		}
+	}
+
+	results := make([]domain.EndDeviceSearchResult, len(rows))
+	for i, row := range rows {
+		results[i] = domain.EndDeviceSearchResult{
+			EndDevice: endDeviceFromRow(sqlc.EndDevice{
+				ID:           row.ID,
+				Name:         row.Name,
+				Description:  row.Description,
+				Status:       row.Status,
+				DataType:     row.DataType,
+				HardwareType: row.HardwareType,
+			}),
+			OrganizationId: row.OrganizationID,
+			Rank:           row.Rank,
+		}
+	}
+
+	return results, next, nil
+}
+
+// endDeviceFromRow builds an EndDevice without hardware-specific configuration from a database row.
+func endDeviceFromRow(row sqlc.EndDevice) *iotv1.EndDevice {
+	return iotv1.EndDevice_builder{
+		Id:           row.ID,
+		Name:         row.Name,
+		Description:  row.Description.String,
+		Status:       iotv1.EndDeviceStatus(row.Status),
+		HardwareType: iotv1.EndDeviceHardwareType(row.HardwareType),
+		DataType:     iotv1.EndDeviceDataType(row.DataType), // Deprecated field
+	}.Build()
+}
+
+// labelSelectorParams holds a label selector in the form expected by the label conditions of the end device queries.
+// Requirements that are not used stay nil so the query skips them.
+type labelSelectorParams struct {
+	equals    []byte
+	in        []byte
+	notIn     []byte
+	exists    []string
+	notExists []string
+}
+
+// newLabelSelectorParams converts a label selector into query parameters.
+func newLabelSelectorParams(selector domain.LabelSelector) (labelSelectorParams, error) {
+	params := labelSelectorParams{
+		exists:    selector.Exists,
+		notExists: selector.NotExists,
 	}
 
-	endDevices := make([]*iotv1.EndDevice, len(devices))
-	for i, device := range devices {
-		// Build each end device using builder pattern
-		endDeviceBuilder := iotv1.EndDevice_builder{
-			Id:     device.EndDeviceID,
-			Name:   device.Name,
-			Status: iotv1.EndDeviceStatus(device.Status),
-			// Note: This simplified version doesn't include full LoRaWAN config
-			// Use GetCompleteLoRaWANDevice for full details
+	var err error
+	if len(selector.Equals) > 0 {
+		params.equals, err = json.Marshal(selector.Equals)
+		if err != nil {
+			return params, stacktrace.NewStackTraceError(err)
+		}
+	}
+
+	if len(selector.In) > 0 {
+		params.in, err = json.Marshal(selector.In)
+		if err != nil {
+			return params, stacktrace.NewStackTraceError(err)
 		}
-		endDevices[i] = endDeviceBuilder.Build()
 	}
 
-	return endDevices, nil
+	if len(selector.NotIn) > 0 {
+		params.notIn, err = json.Marshal(selector.NotIn)
+		if err != nil {
+			return params, stacktrace.NewStackTraceError(err)
+		}
+	}
+
+	return params, nil
+}
+
+// marshalEndDeviceMetadata encodes labels and attributes as JSON objects, storing missing maps as empty objects.
+func marshalEndDeviceMetadata(metadata domain.EndDeviceMetadata) ([]byte, []byte, error) {
+	labels := metadata.Labels
+	if labels == nil {
+		labels = domain.Labels{}
+	}
+
+	attributes := metadata.Attributes
+	if attributes == nil {
+		attributes = map[string]any{}
+	}
+
+	labelsJSON, err := json.Marshal(labels)
+	if err != nil {
+		return nil, nil, stacktrace.NewStackTraceError(err)
+	}
+
+	attributesJSON, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return labelsJSON, attributesJSON, nil
+}
+
+// unmarshalEndDeviceMetadata decodes the labels and attributes columns of an end device.
+func unmarshalEndDeviceMetadata(labelsJSON []byte, attributesJSON []byte) (domain.EndDeviceMetadata, error) {
+	metadata := domain.EndDeviceMetadata{
+		Labels:     domain.Labels{},
+		Attributes: map[string]any{},
+	}
+
+	if len(labelsJSON) > 0 {
+		err := json.Unmarshal(labelsJSON, &metadata.Labels)
+		if err != nil {
+			return metadata, stacktrace.NewStackTraceError(err)
+		}
+	}
+
+	if len(attributesJSON) > 0 {
+		err := json.Unmarshal(attributesJSON, &metadata.Attributes)
+		if err != nil {
+			return metadata, stacktrace.NewStackTraceError(err)
+		}
+	}
+
+	return metadata, nil
+}
+
+// escapeLikePattern escapes LIKE wildcards so user input is matched literally.
+func escapeLikePattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
 }
 
 // AddLoRaWANHardwareType inserts a new LoRaWAN hardware type into the database.
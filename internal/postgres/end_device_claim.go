@@ -0,0 +1,201 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/postgres/sqlc"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// EndDeviceClaimStore handles database operations for end device claim codes and claim attempts.
+type EndDeviceClaimStore struct {
+	db   *sqlc.Queries
+	pool *pgxpool.Pool
+}
+
+// NewEndDeviceClaimStore creates a new EndDeviceClaimStore instance.
+func NewEndDeviceClaimStore(db *sqlc.Queries, pool *pgxpool.Pool) *EndDeviceClaimStore {
+	return &EndDeviceClaimStore{
+		db:   db,
+		pool: pool,
+	}
+}
+
+// SaveEndDeviceClaim stores the claim code of an end device, replacing any earlier code.
+func (store *EndDeviceClaimStore) SaveEndDeviceClaim(ctx context.Context, claim *domain.EndDeviceClaim, codeHash string) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "SaveEndDeviceClaim")
+	defer span.End()
+
+	_, err := store.db.UpsertEndDeviceClaim(ctx, sqlc.UpsertEndDeviceClaimParams{
+		EndDeviceID:    claim.EndDeviceId,
+		OrganizationID: claim.OrganizationId,
+		CodeHash:       codeHash,
+		ExpiresAt:      pgtype.Timestamptz{Time: claim.ExpiresAt, Valid: true},
+		CreatedAt:      pgtype.Timestamptz{Time: claim.CreatedAt, Valid: true},
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// GetEndDeviceClaim retrieves a claim by the hash of its code.
+func (store *EndDeviceClaimStore) GetEndDeviceClaim(ctx context.Context, codeHash string) (*domain.EndDeviceClaim, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetEndDeviceClaim")
+	defer span.End()
+
+	row, err := store.db.GetEndDeviceClaimByCodeHash(ctx, codeHash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, stacktrace.NewStackTraceErrorf("%w: unknown code", domain.ErrInvalidClaimCode)
+		}
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return &domain.EndDeviceClaim{
+		EndDeviceId:             row.EndDeviceID,
+		OrganizationId:          row.OrganizationID,
+		ExpiresAt:               row.ExpiresAt.Time,
+		CreatedAt:               row.CreatedAt.Time,
+		ClaimedAt:               row.ClaimedAt.Time,
+		ClaimedByOrganizationId: row.ClaimedByOrganizationID.String,
+	}, nil
+}
+
+// CountFailedClaimAttempts counts the failed claim attempts a user made since the given time.
+func (store *EndDeviceClaimStore) CountFailedClaimAttempts(ctx context.Context, userId string, since time.Time) (int, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "CountFailedClaimAttempts")
+	defer span.End()
+
+	count, err := store.db.CountEndDeviceClaimAttemptsSince(ctx, sqlc.CountEndDeviceClaimAttemptsSinceParams{
+		UserID:      userId,
+		AttemptedAt: pgtype.Timestamptz{Time: since, Valid: true},
+	})
+	if err != nil {
+		return 0, stacktrace.NewStackTraceError(err)
+	}
+
+	return int(count), nil
+}
+
+// RecordFailedClaimAttempt stores a failed claim attempt and drops the user's attempts older than pruneBefore,
+// which no longer count towards the rate limit.
+func (store *EndDeviceClaimStore) RecordFailedClaimAttempt(ctx context.Context, id string, userId string, attemptedAt time.Time, pruneBefore time.Time) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "RecordFailedClaimAttempt")
+	defer span.End()
+
+	tx, err := store.pool.Begin(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	txQueries := store.db.WithTx(tx)
+
+	err = txQueries.CreateEndDeviceClaimAttempt(ctx, sqlc.CreateEndDeviceClaimAttemptParams{
+		ID:          id,
+		UserID:      userId,
+		AttemptedAt: pgtype.Timestamptz{Time: attemptedAt, Valid: true},
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	_, err = txQueries.DeleteEndDeviceClaimAttemptsBefore(ctx, sqlc.DeleteEndDeviceClaimAttemptsBeforeParams{
+		UserID:      userId,
+		AttemptedAt: pgtype.Timestamptz{Time: pruneBefore, Valid: true},
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// ClaimEndDevice marks the claim as used and moves the end device into the claiming organization within a
+// transaction, together with the root key rotation when one is given. The sync function runs after the changes are
+// written but before they are committed, so a failure to push the new keys to the Join Server rolls back the claim.
+func (store *EndDeviceClaimStore) ClaimEndDevice(ctx context.Context, claim *domain.EndDeviceClaim, codeHash string, previousOrganizationId string, rotation *domain.RootKeyRotation, previous domain.StoredRootKeys, sealed domain.StoredRootKeys, transition *domain.EndDeviceStatusTransition, sync domain.EndDeviceSync) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "ClaimEndDevice")
+	defer span.End()
+
+	tx, err := store.pool.Begin(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	txQueries := store.db.WithTx(tx)
+
+	redeemed, err := txQueries.RedeemEndDeviceClaim(ctx, sqlc.RedeemEndDeviceClaimParams{
+		ClaimedAt:               pgtype.Timestamptz{Time: claim.ClaimedAt, Valid: true},
+		ClaimedByOrganizationID: pgtype.Text{String: claim.ClaimedByOrganizationId, Valid: true},
+		EndDeviceID:             claim.EndDeviceId,
+		CodeHash:                codeHash,
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	if redeemed == 0 {
+		return stacktrace.NewStackTraceErrorf("%w: code for %s was used or has expired", domain.ErrInvalidClaimCode, claim.EndDeviceId)
+	}
+
+	moved, err := txQueries.MoveEndDeviceToOrganization(ctx, sqlc.MoveEndDeviceToOrganizationParams{
+		OrganizationID:         claim.ClaimedByOrganizationId,
+		ID:                     claim.EndDeviceId,
+		PreviousOrganizationID: previousOrganizationId,
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	if moved == 0 {
+		return stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrEndDeviceNotFound, claim.EndDeviceId)
+	}
+
+	_, err = txQueries.RemoveEndDeviceFromDeviceGroups(ctx, claim.EndDeviceId)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	// Profiles are scoped to the previous owner as well
+	_, err = txQueries.UnassignEndDeviceProfile(ctx, claim.EndDeviceId)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	if rotation != nil {
+		err = rotateRootKeys(ctx, txQueries, rotation, previous, sealed, transition)
+		if err != nil {
+			return err
+		}
+	}
+
+	if sync != nil {
+		err = sync(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,255 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/postgres/sqlc"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// EndDeviceDecommissionStore handles database operations for end device decommissions and their audit trail.
+type EndDeviceDecommissionStore struct {
+	db   *sqlc.Queries
+	pool *pgxpool.Pool
+}
+
+// NewEndDeviceDecommissionStore creates a new EndDeviceDecommissionStore instance.
+func NewEndDeviceDecommissionStore(db *sqlc.Queries, pool *pgxpool.Pool) *EndDeviceDecommissionStore {
+	return &EndDeviceDecommissionStore{
+		db:   db,
+		pool: pool,
+	}
+}
+
+// CreateEndDeviceDecommission inserts a new decommission together with its first event within a transaction.
+func (store *EndDeviceDecommissionStore) CreateEndDeviceDecommission(ctx context.Context, decommission *domain.EndDeviceDecommission, event *domain.EndDeviceDecommissionEvent) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "CreateEndDeviceDecommission")
+	defer span.End()
+
+	tx, err := store.pool.Begin(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	txQueries := store.db.WithTx(tx)
+
+	_, err = txQueries.CreateEndDeviceDecommission(ctx, sqlc.CreateEndDeviceDecommissionParams{
+		ID:             decommission.Id,
+		EndDeviceID:    decommission.EndDeviceId,
+		OrganizationID: decommission.OrganizationId,
+		RequestedBy:    decommission.RequestedBy,
+		DataPolicy:     string(decommission.DataPolicy),
+		Step:           string(decommission.Step),
+		PurgeAfter:     pgtype.Timestamptz{Time: decommission.PurgeAfter, Valid: true},
+		NextAttemptAt:  pgtype.Timestamptz{Time: decommission.NextAttemptAt, Valid: true},
+		CreatedAt:      pgtype.Timestamptz{Time: decommission.CreatedAt, Valid: true},
+		UpdatedAt:      pgtype.Timestamptz{Time: decommission.UpdatedAt, Valid: true},
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrEndDeviceDecommissionExists, decommission.EndDeviceId)
+		}
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	err = createEndDeviceDecommissionEvent(ctx, txQueries, event)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// GetEndDeviceDecommission retrieves the decommission of an end device.
+func (store *EndDeviceDecommissionStore) GetEndDeviceDecommission(ctx context.Context, endDeviceId string) (*domain.EndDeviceDecommission, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetEndDeviceDecommission")
+	defer span.End()
+
+	row, err := store.db.GetEndDeviceDecommissionByEndDevice(ctx, endDeviceId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrEndDeviceDecommissionNotFound, endDeviceId)
+		}
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return endDeviceDecommissionFromRow(row), nil
+}
+
+// ListEndDeviceDecommissionEvents retrieves the audit trail of a decommission, oldest event first.
+func (store *EndDeviceDecommissionStore) ListEndDeviceDecommissionEvents(ctx context.Context, decommissionId string) ([]*domain.EndDeviceDecommissionEvent, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListEndDeviceDecommissionEvents")
+	defer span.End()
+
+	rows, err := store.db.ListEndDeviceDecommissionEvents(ctx, decommissionId)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	events := make([]*domain.EndDeviceDecommissionEvent, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, &domain.EndDeviceDecommissionEvent{
+			Id:             row.ID,
+			DecommissionId: row.DecommissionID,
+			Step:           domain.EndDeviceDecommissionStep(row.Step),
+			Error:          row.Error.String,
+			OccurredAt:     row.OccurredAt.Time,
+		})
+	}
+
+	return events, nil
+}
+
+// ListDueEndDeviceDecommissions retrieves up to limit unfinished decommissions whose next attempt is due,
+// longest waiting first.
+func (store *EndDeviceDecommissionStore) ListDueEndDeviceDecommissions(ctx context.Context, now time.Time, limit int) ([]*domain.EndDeviceDecommission, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListDueEndDeviceDecommissions")
+	defer span.End()
+
+	rows, err := store.db.ListDueEndDeviceDecommissions(ctx, sqlc.ListDueEndDeviceDecommissionsParams{
+		Now:      pgtype.Timestamptz{Time: now, Valid: true},
+		RowLimit: int32(limit),
+	})
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	decommissions := make([]*domain.EndDeviceDecommission, 0, len(rows))
+	for _, row := range rows {
+		decommissions = append(decommissions, endDeviceDecommissionFromRow(row))
+	}
+
+	return decommissions, nil
+}
+
+// SaveEndDeviceDecommissionProgress updates the state of a decommission and records the event, when one is given,
+// within a transaction. Nothing is written when the decommission's step is no longer previousStep.
+func (store *EndDeviceDecommissionStore) SaveEndDeviceDecommissionProgress(ctx context.Context, decommission *domain.EndDeviceDecommission, previousStep domain.EndDeviceDecommissionStep, event *domain.EndDeviceDecommissionEvent) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "SaveEndDeviceDecommissionProgress")
+	defer span.End()
+
+	tx, err := store.pool.Begin(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	txQueries := store.db.WithTx(tx)
+
+	updated, err := txQueries.UpdateEndDeviceDecommission(ctx, sqlc.UpdateEndDeviceDecommissionParams{
+		Step:          string(decommission.Step),
+		Attempts:      int32(decommission.Attempts),
+		LastError:     pgtype.Text{String: decommission.LastError, Valid: decommission.LastError != ""},
+		NextAttemptAt: pgtype.Timestamptz{Time: decommission.NextAttemptAt, Valid: true},
+		CompletedAt:   pgtype.Timestamptz{Time: decommission.CompletedAt, Valid: !decommission.CompletedAt.IsZero()},
+		UpdatedAt:     pgtype.Timestamptz{Time: decommission.UpdatedAt, Valid: true},
+		ID:            decommission.Id,
+		PreviousStep:  string(previousStep),
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	if updated == 0 {
+		return stacktrace.NewStackTraceErrorf("%w: %s is no longer %s", domain.ErrEndDeviceDecommissionConflict, decommission.EndDeviceId, previousStep)
+	}
+
+	if event != nil {
+		err = createEndDeviceDecommissionEvent(ctx, txQueries, event)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// RevokeEndDeviceCredentials clears the stored LoRaWAN root keys of an end device and deletes its MQTT broker
+// credentials and claim code within a transaction. Devices without any of them are left unchanged.
+func (store *EndDeviceDecommissionStore) RevokeEndDeviceCredentials(ctx context.Context, endDeviceId string) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "RevokeEndDeviceCredentials")
+	defer span.End()
+
+	tx, err := store.pool.Begin(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	txQueries := store.db.WithTx(tx)
+
+	err = txQueries.RevokeLoRaWANRootKeys(ctx, endDeviceId)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	err = txQueries.DeleteMQTTConfig(ctx, endDeviceId)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	err = txQueries.DeleteEndDeviceClaim(ctx, endDeviceId)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// createEndDeviceDecommissionEvent records a decommission event using the given queries.
+func createEndDeviceDecommissionEvent(ctx context.Context, queries *sqlc.Queries, event *domain.EndDeviceDecommissionEvent) error {
+	err := queries.CreateEndDeviceDecommissionEvent(ctx, sqlc.CreateEndDeviceDecommissionEventParams{
+		ID:             event.Id,
+		DecommissionID: event.DecommissionId,
+		Step:           string(event.Step),
+		Error:          pgtype.Text{String: event.Error, Valid: event.Error != ""},
+		OccurredAt:     pgtype.Timestamptz{Time: event.OccurredAt, Valid: true},
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// endDeviceDecommissionFromRow builds an EndDeviceDecommission from a database row.
+func endDeviceDecommissionFromRow(row sqlc.EndDeviceDecommission) *domain.EndDeviceDecommission {
+	return &domain.EndDeviceDecommission{
+		Id:             row.ID,
+		EndDeviceId:    row.EndDeviceID,
+		OrganizationId: row.OrganizationID,
+		RequestedBy:    row.RequestedBy,
+		DataPolicy:     domain.EndDeviceDataPolicy(row.DataPolicy),
+		Step:           domain.EndDeviceDecommissionStep(row.Step),
+		PurgeAfter:     row.PurgeAfter.Time,
+		Attempts:       int(row.Attempts),
+		LastError:      row.LastError.String,
+		NextAttemptAt:  row.NextAttemptAt.Time,
+		CreatedAt:      row.CreatedAt.Time,
+		UpdatedAt:      row.UpdatedAt.Time,
+		CompletedAt:    row.CompletedAt.Time,
+	}
+}
@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/postgres/sqlc"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// EndDevicePresenceStore handles database operations for end device presence and message counts.
+type EndDevicePresenceStore struct {
+	db   *sqlc.Queries
+	pool *pgxpool.Pool
+}
+
+// NewEndDevicePresenceStore creates a new EndDevicePresenceStore instance.
+func NewEndDevicePresenceStore(db *sqlc.Queries, pool *pgxpool.Pool) *EndDevicePresenceStore {
+	return &EndDevicePresenceStore{
+		db:   db,
+		pool: pool,
+	}
+}
+
+// RecordEndDevicePresence applies a batch of presence updates within a transaction.
+// Updates for end devices that no longer exist are ignored.
+func (store *EndDevicePresenceStore) RecordEndDevicePresence(ctx context.Context, updates []domain.EndDevicePresenceUpdate) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "RecordEndDevicePresence")
+	defer span.End()
+
+	tx, err := store.pool.Begin(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	txQueries := store.db.WithTx(tx)
+
+	for _, update := range updates {
+		lastValues, err := json.Marshal(update.LastValues)
+		if err != nil {
+			return stacktrace.NewStackTraceError(err)
+		}
+
+		err = txQueries.RecordEndDevicePresence(ctx, sqlc.RecordEndDevicePresenceParams{
+			LastProcessedAt: pgtype.Timestamptz{Time: update.LastProcessedAt, Valid: true},
+			LastOccurredAt:  pgtype.Timestamptz{Time: update.LastOccurredAt, Valid: !update.LastOccurredAt.IsZero()},
+			LastValues:      lastValues,
+			EndDeviceID:     update.EndDeviceId,
+		})
+		if err != nil {
+			return stacktrace.NewStackTraceError(err)
+		}
+
+		for hourStart, count := range update.MessageCounts {
+			err = txQueries.AddEndDeviceMessageCount(ctx, sqlc.AddEndDeviceMessageCountParams{
+				HourStart:    pgtype.Timestamptz{Time: hourStart, Valid: true},
+				MessageCount: count,
+				EndDeviceID:  update.EndDeviceId,
+			})
+			if err != nil {
+				return stacktrace.NewStackTraceError(err)
+			}
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// ListEndDevicePresence retrieves the presence of the given end devices keyed by ID, counting messages
+// received since countedSince. Devices without any recorded presence are left out.
+func (store *EndDevicePresenceStore) ListEndDevicePresence(ctx context.Context, endDeviceIDs []string, countedSince time.Time) (map[string]*domain.EndDevicePresence, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListEndDevicePresence")
+	defer span.End()
+
+	rows, err := store.db.ListEndDevicePresence(ctx, sqlc.ListEndDevicePresenceParams{
+		CountedSince: pgtype.Timestamptz{Time: countedSince, Valid: true},
+		EndDeviceIds: endDeviceIDs,
+	})
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	presence := make(map[string]*domain.EndDevicePresence, len(rows))
+	for _, row := range rows {
+		lastValues := map[string]any{}
+		err = json.Unmarshal(row.LastValues, &lastValues)
+		if err != nil {
+			return nil, stacktrace.NewStackTraceError(err)
+		}
+
+		presence[row.EndDeviceID] = &domain.EndDevicePresence{
+			EndDeviceId:     row.EndDeviceID,
+			LastSeenAt:      row.LastSeenAt.Time,
+			LastOccurredAt:  row.LastOccurredAt.Time,
+			LastProcessedAt: row.LastProcessedAt.Time,
+			MessageCount24h: row.MessageCount,
+			LastValues:      lastValues,
+		}
+	}
+
+	return presence, nil
+}
+
+// DeleteEndDeviceMessageCountsBefore removes hourly message counts that started before the given time.
+func (store *EndDevicePresenceStore) DeleteEndDeviceMessageCountsBefore(ctx context.Context, before time.Time) (int, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "DeleteEndDeviceMessageCountsBefore")
+	defer span.End()
+
+	deleted, err := store.db.DeleteEndDeviceMessageCountsBefore(ctx, pgtype.Timestamptz{Time: before, Valid: true})
+	if err != nil {
+		return 0, stacktrace.NewStackTraceError(err)
+	}
+
+	return int(deleted), nil
+}
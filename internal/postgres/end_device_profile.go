@@ -0,0 +1,245 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/postgres/sqlc"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// foreignKeyViolation is the Postgres error code raised when a foreign key constraint is violated.
+const foreignKeyViolation = "23503"
+
+// EndDeviceProfileStore handles database operations for end device profiles and the devices created from them.
+type EndDeviceProfileStore struct {
+	db   *sqlc.Queries
+	pool *pgxpool.Pool
+}
+
+// NewEndDeviceProfileStore creates a new EndDeviceProfileStore instance.
+func NewEndDeviceProfileStore(db *sqlc.Queries, pool *pgxpool.Pool) *EndDeviceProfileStore {
+	return &EndDeviceProfileStore{
+		db:   db,
+		pool: pool,
+	}
+}
+
+// CreateEndDeviceProfile inserts a new end device profile into the database.
+func (store *EndDeviceProfileStore) CreateEndDeviceProfile(ctx context.Context, profile *domain.EndDeviceProfile) (*domain.EndDeviceProfile, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "CreateEndDeviceProfile")
+	defer span.End()
+
+	labels, err := marshalProfileLabels(profile.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := store.db.CreateEndDeviceProfile(ctx, sqlc.CreateEndDeviceProfileParams{
+		ID:              profile.Id,
+		OrganizationID:  profile.OrganizationId,
+		Name:            profile.Name,
+		Description:     pgtype.Text{String: profile.Description, Valid: profile.Description != ""},
+		HardwareType:    int32(profile.HardwareType),
+		HardwareTypeID:  pgtype.Text{String: profile.HardwareTypeId, Valid: profile.HardwareTypeId != ""},
+		FrequencyPlanID: pgtype.Text{String: profile.FrequencyPlan, Valid: profile.FrequencyPlan != ""},
+		PayloadDecoder:  pgtype.Text{String: profile.PayloadDecoder, Valid: profile.PayloadDecoder != ""},
+		ExpectedFields:  expectedFieldsParam(profile.ExpectedFields),
+		Labels:          labels,
+	})
+	if err != nil {
+		return nil, endDeviceProfileError(err, profile)
+	}
+
+	return endDeviceProfileFromRow(row)
+}
+
+// GetEndDeviceProfile retrieves an end device profile by ID from the database.
+func (store *EndDeviceProfileStore) GetEndDeviceProfile(ctx context.Context, profileID string) (*domain.EndDeviceProfile, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetEndDeviceProfile")
+	defer span.End()
+
+	row, err := store.db.GetEndDeviceProfile(ctx, profileID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrEndDeviceProfileNotFound, profileID)
+		}
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return endDeviceProfileFromRow(row)
+}
+
+// ListEndDeviceProfiles retrieves all end device profiles in an organization ordered by name.
+func (store *EndDeviceProfileStore) ListEndDeviceProfiles(ctx context.Context, organizationID string) ([]*domain.EndDeviceProfile, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListEndDeviceProfiles")
+	defer span.End()
+
+	rows, err := store.db.ListEndDeviceProfilesByOrganization(ctx, organizationID)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	profiles := make([]*domain.EndDeviceProfile, len(rows))
+	for i, row := range rows {
+		profiles[i], err = endDeviceProfileFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return profiles, nil
+}
+
+// UpdateEndDeviceProfile replaces the settings of an end device profile. Its organization and hardware type are kept.
+func (store *EndDeviceProfileStore) UpdateEndDeviceProfile(ctx context.Context, profile *domain.EndDeviceProfile) (*domain.EndDeviceProfile, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "UpdateEndDeviceProfile")
+	defer span.End()
+
+	labels, err := marshalProfileLabels(profile.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := store.db.UpdateEndDeviceProfile(ctx, sqlc.UpdateEndDeviceProfileParams{
+		ID:              profile.Id,
+		Name:            profile.Name,
+		Description:     pgtype.Text{String: profile.Description, Valid: profile.Description != ""},
+		HardwareTypeID:  pgtype.Text{String: profile.HardwareTypeId, Valid: profile.HardwareTypeId != ""},
+		FrequencyPlanID: pgtype.Text{String: profile.FrequencyPlan, Valid: profile.FrequencyPlan != ""},
+		PayloadDecoder:  pgtype.Text{String: profile.PayloadDecoder, Valid: profile.PayloadDecoder != ""},
+		ExpectedFields:  expectedFieldsParam(profile.ExpectedFields),
+		Labels:          labels,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrEndDeviceProfileNotFound, profile.Id)
+		}
+		return nil, endDeviceProfileError(err, profile)
+	}
+
+	return endDeviceProfileFromRow(row)
+}
+
+// DeleteEndDeviceProfile deletes an end device profile and, through cascading deletes, the links of the devices
+// created from it.
+func (store *EndDeviceProfileStore) DeleteEndDeviceProfile(ctx context.Context, profileID string) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "DeleteEndDeviceProfile")
+	defer span.End()
+
+	err := store.db.DeleteEndDeviceProfile(ctx, profileID)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// ListEndDeviceProfileDevices retrieves the profile-managed state of the end devices created from a profile,
+// ordered by name.
+func (store *EndDeviceProfileStore) ListEndDeviceProfileDevices(ctx context.Context, profileID string) ([]domain.EndDeviceProfileDevice, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListEndDeviceProfileDevices")
+	defer span.End()
+
+	rows, err := store.db.ListEndDeviceProfileDevices(ctx, profileID)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	devices := make([]domain.EndDeviceProfileDevice, len(rows))
+	for i, row := range rows {
+		labels := domain.Labels{}
+		if len(row.Labels) > 0 {
+			err = json.Unmarshal(row.Labels, &labels)
+			if err != nil {
+				return nil, stacktrace.NewStackTraceError(err)
+			}
+		}
+
+		devices[i] = domain.EndDeviceProfileDevice{
+			EndDeviceId:    row.ID,
+			Name:           row.Name,
+			HardwareTypeId: row.HardwareTypeID.String,
+			FrequencyPlan:  row.FrequencyPlanID.String,
+			Labels:         labels,
+		}
+	}
+
+	return devices, nil
+}
+
+// endDeviceProfileFromRow builds an EndDeviceProfile from a database row.
+func endDeviceProfileFromRow(row sqlc.EndDeviceProfile) (*domain.EndDeviceProfile, error) {
+	labels := domain.Labels{}
+	if len(row.Labels) > 0 {
+		err := json.Unmarshal(row.Labels, &labels)
+		if err != nil {
+			return nil, stacktrace.NewStackTraceError(err)
+		}
+	}
+
+	expectedFields := row.ExpectedFields
+	if expectedFields == nil {
+		expectedFields = []string{}
+	}
+
+	return &domain.EndDeviceProfile{
+		Id:             row.ID,
+		OrganizationId: row.OrganizationID,
+		Name:           row.Name,
+		Description:    row.Description.String,
+		HardwareType:   iotv1.EndDeviceHardwareType(row.HardwareType),
+		HardwareTypeId: row.HardwareTypeID.String,
+		FrequencyPlan:  row.FrequencyPlanID.String,
+		PayloadDecoder: row.PayloadDecoder.String,
+		ExpectedFields: expectedFields,
+		Labels:         labels,
+		CreatedAt:      row.CreatedAt.Time,
+		UpdatedAt:      row.UpdatedAt.Time,
+	}, nil
+}
+
+// marshalProfileLabels encodes the labels of an end device profile as a JSON object.
+func marshalProfileLabels(labels domain.Labels) ([]byte, error) {
+	if labels == nil {
+		labels = domain.Labels{}
+	}
+
+	raw, err := json.Marshal(labels)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return raw, nil
+}
+
+// expectedFieldsParam stores a missing list of expected fields as an empty array.
+func expectedFieldsParam(expectedFields []string) []string {
+	if expectedFields == nil {
+		return []string{}
+	}
+	return expectedFields
+}
+
+// endDeviceProfileError maps constraint violations raised while writing an end device profile to domain errors.
+// Unknown hardware types and frequency plans are reported as invalid profiles.
+func endDeviceProfileError(err error, profile *domain.EndDeviceProfile) error {
+	if isUniqueViolation(err) {
+		return stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrEndDeviceProfileExists, profile.Name)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return stacktrace.NewStackTraceErrorf("%w: unknown hardware type %q or frequency plan %q", domain.ErrInvalidEndDeviceProfile, profile.HardwareTypeId, profile.FrequencyPlan)
+	}
+
+	return stacktrace.NewStackTraceError(err)
+}
@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/postgres/sqlc"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// EndDeviceStatusStore handles database operations for end device status transitions and presence.
+type EndDeviceStatusStore struct {
+	db   *sqlc.Queries
+	pool *pgxpool.Pool
+}
+
+// NewEndDeviceStatusStore creates a new EndDeviceStatusStore instance.
+func NewEndDeviceStatusStore(db *sqlc.Queries, pool *pgxpool.Pool) *EndDeviceStatusStore {
+	return &EndDeviceStatusStore{
+		db:   db,
+		pool: pool,
+	}
+}
+
+// TransitionEndDeviceStatus changes an end device's status and records the transition within a transaction.
+// The status only changes if the device is still in the transition's From status, so concurrent transitions
+// cannot overwrite each other.
+func (store *EndDeviceStatusStore) TransitionEndDeviceStatus(ctx context.Context, transition *domain.EndDeviceStatusTransition) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "TransitionEndDeviceStatus")
+	defer span.End()
+
+	tx, err := store.pool.Begin(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	txQueries := store.db.WithTx(tx)
+
+	updated, err := txQueries.TransitionEndDeviceStatus(ctx, sqlc.TransitionEndDeviceStatusParams{
+		ToStatus:   int32(transition.To),
+		ID:         transition.EndDeviceId,
+		FromStatus: int32(transition.From),
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	if updated == 0 {
+		return stacktrace.NewStackTraceErrorf("%w: %s is no longer %s", domain.ErrInvalidStatusTransition, transition.EndDeviceId, transition.From)
+	}
+
+	_, err = txQueries.CreateEndDeviceStatusTransition(ctx, sqlc.CreateEndDeviceStatusTransitionParams{
+		ID:             transition.Id,
+		EndDeviceID:    transition.EndDeviceId,
+		FromStatus:     int32(transition.From),
+		ToStatus:       int32(transition.To),
+		Reason:         transition.Reason,
+		TransitionedAt: pgtype.Timestamptz{Time: transition.TransitionedAt, Valid: true},
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// ListEndDeviceStatusTransitions retrieves the status history of an end device, most recent first.
+func (store *EndDeviceStatusStore) ListEndDeviceStatusTransitions(ctx context.Context, endDeviceID string) ([]*domain.EndDeviceStatusTransition, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListEndDeviceStatusTransitions")
+	defer span.End()
+
+	rows, err := store.db.ListEndDeviceStatusTransitions(ctx, endDeviceID)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	transitions := make([]*domain.EndDeviceStatusTransition, 0, len(rows))
+	for _, row := range rows {
+		transitions = append(transitions, &domain.EndDeviceStatusTransition{
+			Id:             row.ID,
+			EndDeviceId:    row.EndDeviceID,
+			From:           iotv1.EndDeviceStatus(row.FromStatus),
+			To:             iotv1.EndDeviceStatus(row.ToStatus),
+			Reason:         row.Reason,
+			TransitionedAt: row.TransitionedAt.Time,
+		})
+	}
+
+	return transitions, nil
+}
+
+// TouchEndDevicePresence records that data was seen from an end device. Older timestamps never replace newer ones.
+func (store *EndDeviceStatusStore) TouchEndDevicePresence(ctx context.Context, endDeviceID string, seenAt time.Time) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "TouchEndDevicePresence")
+	defer span.End()
+
+	err := store.db.TouchEndDevicePresence(ctx, sqlc.TouchEndDevicePresenceParams{
+		EndDeviceID: endDeviceID,
+		LastSeenAt:  pgtype.Timestamptz{Time: seenAt, Valid: true},
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// ListSilentEndDevices retrieves up to limit IDs of end devices in the given status that have not been seen
+// since seenBefore, longest silent first.
+func (store *EndDeviceStatusStore) ListSilentEndDevices(ctx context.Context, status iotv1.EndDeviceStatus, seenBefore time.Time, limit int) ([]string, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListSilentEndDevices")
+	defer span.End()
+
+	endDeviceIDs, err := store.db.ListSilentEndDevices(ctx, sqlc.ListSilentEndDevicesParams{
+		Status:     int32(status),
+		SeenBefore: pgtype.Timestamptz{Time: seenBefore, Valid: true},
+		PageLimit:  int32(limit),
+	})
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return endDeviceIDs, nil
+}
@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/postgres/sqlc"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// EndDeviceTransferStore handles database operations for transfers of end devices between organizations.
+type EndDeviceTransferStore struct {
+	db   *sqlc.Queries
+	pool *pgxpool.Pool
+}
+
+// NewEndDeviceTransferStore creates a new EndDeviceTransferStore instance.
+func NewEndDeviceTransferStore(db *sqlc.Queries, pool *pgxpool.Pool) *EndDeviceTransferStore {
+	return &EndDeviceTransferStore{
+		db:   db,
+		pool: pool,
+	}
+}
+
+// TransferEndDevice moves the end device into the new organization and records the transfer within a transaction.
+// Device group memberships, the end device profile and any claim code belong to the previous owner and are removed.
+func (store *EndDeviceTransferStore) TransferEndDevice(ctx context.Context, transfer *domain.EndDeviceTransfer) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "TransferEndDevice")
+	defer span.End()
+
+	tx, err := store.pool.Begin(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	txQueries := store.db.WithTx(tx)
+
+	moved, err := txQueries.MoveEndDeviceToOrganization(ctx, sqlc.MoveEndDeviceToOrganizationParams{
+		OrganizationID:         transfer.ToOrganizationId,
+		ID:                     transfer.EndDeviceId,
+		PreviousOrganizationID: transfer.FromOrganizationId,
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	if moved == 0 {
+		return stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrEndDeviceNotFound, transfer.EndDeviceId)
+	}
+
+	_, err = txQueries.RemoveEndDeviceFromDeviceGroups(ctx, transfer.EndDeviceId)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	_, err = txQueries.UnassignEndDeviceProfile(ctx, transfer.EndDeviceId)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	// A claim code issued by the previous owner would let someone take the device out of the new organization
+	err = txQueries.DeleteEndDeviceClaim(ctx, transfer.EndDeviceId)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	err = txQueries.CreateEndDeviceTransfer(ctx, sqlc.CreateEndDeviceTransferParams{
+		ID:                 transfer.Id,
+		EndDeviceID:        transfer.EndDeviceId,
+		FromOrganizationID: transfer.FromOrganizationId,
+		ToOrganizationID:   transfer.ToOrganizationId,
+		TransferredBy:      transfer.TransferredBy,
+		History:            string(transfer.History),
+		TransferredAt:      pgtype.Timestamptz{Time: transfer.TransferredAt, Valid: true},
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// GetEndDeviceTransfer retrieves a transfer by its ID.
+func (store *EndDeviceTransferStore) GetEndDeviceTransfer(ctx context.Context, transferId string) (*domain.EndDeviceTransfer, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetEndDeviceTransfer")
+	defer span.End()
+
+	row, err := store.db.GetEndDeviceTransfer(ctx, transferId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrEndDeviceTransferNotFound, transferId)
+		}
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return endDeviceTransferFromRow(row), nil
+}
+
+// ListEndDeviceTransfers retrieves the transfers of an end device, most recent first.
+func (store *EndDeviceTransferStore) ListEndDeviceTransfers(ctx context.Context, endDeviceId string) ([]*domain.EndDeviceTransfer, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListEndDeviceTransfers")
+	defer span.End()
+
+	rows, err := store.db.ListEndDeviceTransfers(ctx, endDeviceId)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	transfers := make([]*domain.EndDeviceTransfer, 0, len(rows))
+	for _, row := range rows {
+		transfers = append(transfers, endDeviceTransferFromRow(row))
+	}
+
+	return transfers, nil
+}
+
+// MarkEndDeviceTransferHistoryTransferred records when the data of a transfer was copied or re-tagged.
+func (store *EndDeviceTransferStore) MarkEndDeviceTransferHistoryTransferred(ctx context.Context, transferId string, transferredAt time.Time) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "MarkEndDeviceTransferHistoryTransferred")
+	defer span.End()
+
+	err := store.db.SetEndDeviceTransferHistoryTransferred(ctx, sqlc.SetEndDeviceTransferHistoryTransferredParams{
+		ID:                   transferId,
+		HistoryTransferredAt: pgtype.Timestamptz{Time: transferredAt, Valid: true},
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// endDeviceTransferFromRow converts a stored transfer to its domain representation.
+func endDeviceTransferFromRow(row sqlc.EndDeviceTransfer) *domain.EndDeviceTransfer {
+	return &domain.EndDeviceTransfer{
+		Id:                   row.ID,
+		EndDeviceId:          row.EndDeviceID,
+		FromOrganizationId:   row.FromOrganizationID,
+		ToOrganizationId:     row.ToOrganizationID,
+		TransferredBy:        row.TransferredBy,
+		History:              domain.EndDeviceHistoryMode(row.History),
+		TransferredAt:        row.TransferredAt.Time,
+		HistoryTransferredAt: row.HistoryTransferredAt.Time,
+	}
+}
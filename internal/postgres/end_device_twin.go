@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/postgres/sqlc"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// EndDeviceTwinStore handles database operations for end device twins.
+type EndDeviceTwinStore struct {
+	db   *sqlc.Queries
+	pool *pgxpool.Pool
+}
+
+// NewEndDeviceTwinStore creates a new EndDeviceTwinStore instance.
+func NewEndDeviceTwinStore(db *sqlc.Queries, pool *pgxpool.Pool) *EndDeviceTwinStore {
+	return &EndDeviceTwinStore{
+		db:   db,
+		pool: pool,
+	}
+}
+
+// GetEndDeviceTwin retrieves an end device's twin. A device without a stored twin gets an empty twin at version zero.
+func (store *EndDeviceTwinStore) GetEndDeviceTwin(ctx context.Context, endDeviceID string) (*domain.EndDeviceTwin, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetEndDeviceTwin")
+	defer span.End()
+
+	row, err := store.db.GetEndDeviceTwin(ctx, endDeviceID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &domain.EndDeviceTwin{
+				EndDeviceId: endDeviceID,
+				Desired:     map[string]any{},
+				Reported:    map[string]any{},
+			}, nil
+		}
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return endDeviceTwinFromRow(row)
+}
+
+// SetEndDeviceTwinDesired replaces an end device's desired document if its desired version is still expectedVersion.
+func (store *EndDeviceTwinStore) SetEndDeviceTwinDesired(ctx context.Context, endDeviceID string, desired map[string]any, expectedVersion int64) (*domain.EndDeviceTwin, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "SetEndDeviceTwinDesired")
+	defer span.End()
+
+	document, err := json.Marshal(desired)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	row, err := store.db.SetEndDeviceTwinDesired(ctx, sqlc.SetEndDeviceTwinDesiredParams{
+		EndDeviceID:     endDeviceID,
+		Desired:         document,
+		ExpectedVersion: expectedVersion,
+	})
+	if err != nil {
+		// No row is returned when the stored version did not match
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, stacktrace.NewStackTraceErrorf("%w: %s is not at desired version %d", domain.ErrTwinVersionConflict, endDeviceID, expectedVersion)
+		}
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return endDeviceTwinFromRow(row)
+}
+
+// MergeEndDeviceTwinReported merges a patch into an end device's reported document.
+func (store *EndDeviceTwinStore) MergeEndDeviceTwinReported(ctx context.Context, endDeviceID string, reported map[string]any) (*domain.EndDeviceTwin, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "MergeEndDeviceTwinReported")
+	defer span.End()
+
+	document, err := json.Marshal(reported)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	row, err := store.db.MergeEndDeviceTwinReported(ctx, sqlc.MergeEndDeviceTwinReportedParams{
+		EndDeviceID: endDeviceID,
+		Reported:    document,
+	})
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return endDeviceTwinFromRow(row)
+}
+
+// endDeviceTwinFromRow converts a stored twin into its domain representation.
+func endDeviceTwinFromRow(row sqlc.EndDeviceTwin) (*domain.EndDeviceTwin, error) {
+	twin := &domain.EndDeviceTwin{
+		EndDeviceId:       row.EndDeviceID,
+		Desired:           map[string]any{},
+		DesiredVersion:    row.DesiredVersion,
+		DesiredUpdatedAt:  row.DesiredUpdatedAt.Time,
+		Reported:          map[string]any{},
+		ReportedVersion:   row.ReportedVersion,
+		ReportedUpdatedAt: row.ReportedUpdatedAt.Time,
+	}
+
+	err := json.Unmarshal(row.Desired, &twin.Desired)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	err = json.Unmarshal(row.Reported, &twin.Reported)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return twin, nil
+}
@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/postgres/sqlc"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// EUIBlockStore handles database operations for EUI blocks and device EUI allocation.
+type EUIBlockStore struct {
+	db   *sqlc.Queries
+	pool *pgxpool.Pool
+}
+
+// NewEUIBlockStore creates a new EUIBlockStore instance.
+func NewEUIBlockStore(db *sqlc.Queries, pool *pgxpool.Pool) *EUIBlockStore {
+	return &EUIBlockStore{
+		db:   db,
+		pool: pool,
+	}
+}
+
+// AddEUIBlock stores a new EUI block after checking within a transaction that it does not overlap any existing block.
+func (store *EUIBlockStore) AddEUIBlock(ctx context.Context, block *domain.EUIBlock) (*domain.EUIBlock, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "AddEUIBlock")
+	defer span.End()
+
+	tx, err := store.pool.Begin(ctx)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	txQueries := store.db.WithTx(tx)
+
+	overlapping, err := txQueries.ListOverlappingEUIBlocks(ctx, block.Prefix)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	if len(overlapping) > 0 {
+		return nil, stacktrace.NewStackTraceErrorf("%w: %s overlaps %s", domain.ErrEUIBlockInUse, block.Prefix, overlapping[0].Prefix)
+	}
+
+	row, err := txQueries.CreateEUIBlock(ctx, sqlc.CreateEUIBlockParams{
+		Prefix:         block.Prefix,
+		OrganizationID: block.OrganizationId,
+		Size:           block.Size,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrEUIBlockInUse, block.Prefix)
+		}
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return euiBlockFromRow(row), nil
+}
+
+// ListEUIBlocks retrieves the EUI blocks of an organization, oldest first.
+func (store *EUIBlockStore) ListEUIBlocks(ctx context.Context, organizationID string) ([]*domain.EUIBlock, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListEUIBlocks")
+	defer span.End()
+
+	rows, err := store.db.ListEUIBlocks(ctx, organizationID)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	blocks := make([]*domain.EUIBlock, 0, len(rows))
+	for _, row := range rows {
+		blocks = append(blocks, euiBlockFromRow(row))
+	}
+
+	return blocks, nil
+}
+
+// ReserveEUIBlockOffset hands out the next offset of the organization's oldest EUI block that has room left.
+func (store *EUIBlockStore) ReserveEUIBlockOffset(ctx context.Context, organizationID string) (string, int64, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ReserveEUIBlockOffset")
+	defer span.End()
+
+	row, err := store.db.ReserveEUIBlockOffset(ctx, organizationID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", 0, stacktrace.NewStackTraceErrorf("%w: organization %s", domain.ErrEUIBlockExhausted, organizationID)
+		}
+		return "", 0, stacktrace.NewStackTraceError(err)
+	}
+
+	return row.Prefix, row.ReservedOffset, nil
+}
+
+// LoRaWANDeviceEUIExists reports whether a LoRaWAN end device with the given device EUI exists.
+func (store *EUIBlockStore) LoRaWANDeviceEUIExists(ctx context.Context, deviceEui string) (bool, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "LoRaWANDeviceEUIExists")
+	defer span.End()
+
+	exists, err := store.db.LoRaWANDeviceEUIExists(ctx, deviceEui)
+	if err != nil {
+		return false, stacktrace.NewStackTraceError(err)
+	}
+
+	return exists, nil
+}
+
+// euiBlockFromRow converts a stored EUI block into its domain representation.
+func euiBlockFromRow(row sqlc.EuiBlock) *domain.EUIBlock {
+	return &domain.EUIBlock{
+		Prefix:         row.Prefix,
+		OrganizationId: row.OrganizationID,
+		Size:           row.Size,
+		NextOffset:     row.NextOffset,
+		CreatedAt:      row.CreatedAt.Time,
+	}
+}
@@ -0,0 +1,291 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/postgres/sqlc"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// GatewayStore handles database operations for LoRaWAN gateways.
+type GatewayStore struct {
+	db   *sqlc.Queries
+	pool *pgxpool.Pool
+}
+
+// NewGatewayStore creates a new GatewayStore instance.
+func NewGatewayStore(db *sqlc.Queries, pool *pgxpool.Pool) *GatewayStore {
+	return &GatewayStore{
+		db:   db,
+		pool: pool,
+	}
+}
+
+// CreateGateway inserts a new gateway into the database, running sync before the insert is committed.
+func (store *GatewayStore) CreateGateway(ctx context.Context, gateway *domain.Gateway, sync domain.GatewaySync) (*domain.Gateway, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "CreateGateway")
+	defer span.End()
+
+	latitude, longitude, altitude, source := gatewayLocationParams(gateway.Location)
+
+	var row sqlc.Gateway
+	err := store.inTx(ctx, sync, func(txQueries *sqlc.Queries) error {
+		var err error
+		row, err = txQueries.CreateGateway(ctx, sqlc.CreateGatewayParams{
+			ID:              gateway.Id,
+			OrganizationID:  gateway.OrganizationId,
+			Name:            gateway.Name,
+			Description:     pgtype.Text{String: gateway.Description, Valid: gateway.Description != ""},
+			GatewayEui:      gateway.GatewayEui,
+			FrequencyPlanID: gateway.FrequencyPlan,
+			Latitude:        latitude,
+			Longitude:       longitude,
+			Altitude:        altitude,
+			LocationSource:  source,
+		})
+		if err != nil {
+			return gatewayError(err, gateway)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return gatewayFromRow(row), nil
+}
+
+// GetGateway retrieves a gateway by ID from the database.
+func (store *GatewayStore) GetGateway(ctx context.Context, gatewayID string) (*domain.Gateway, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetGateway")
+	defer span.End()
+
+	row, err := store.db.GetGateway(ctx, gatewayID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrGatewayNotFound, gatewayID)
+		}
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return gatewayFromRow(row), nil
+}
+
+// ListGateways retrieves all gateways in an organization ordered by name.
+func (store *GatewayStore) ListGateways(ctx context.Context, organizationID string) ([]*domain.Gateway, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListGateways")
+	defer span.End()
+
+	rows, err := store.db.ListGatewaysByOrganization(ctx, organizationID)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return gatewaysFromRows(rows), nil
+}
+
+// ListAllGateways retrieves the gateways of every organization.
+func (store *GatewayStore) ListAllGateways(ctx context.Context) ([]*domain.Gateway, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListAllGateways")
+	defer span.End()
+
+	rows, err := store.db.ListGateways(ctx)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return gatewaysFromRows(rows), nil
+}
+
+// UpdateGateway replaces the name, description, frequency plan and location of a gateway, running sync before the
+// update is committed.
+func (store *GatewayStore) UpdateGateway(ctx context.Context, gateway *domain.Gateway, sync domain.GatewaySync) (*domain.Gateway, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "UpdateGateway")
+	defer span.End()
+
+	latitude, longitude, altitude, source := gatewayLocationParams(gateway.Location)
+
+	var row sqlc.Gateway
+	err := store.inTx(ctx, sync, func(txQueries *sqlc.Queries) error {
+		var err error
+		row, err = txQueries.UpdateGateway(ctx, sqlc.UpdateGatewayParams{
+			ID:              gateway.Id,
+			Name:            gateway.Name,
+			Description:     pgtype.Text{String: gateway.Description, Valid: gateway.Description != ""},
+			FrequencyPlanID: gateway.FrequencyPlan,
+			Latitude:        latitude,
+			Longitude:       longitude,
+			Altitude:        altitude,
+			LocationSource:  source,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrGatewayNotFound, gateway.Id)
+			}
+			return gatewayError(err, gateway)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return gatewayFromRow(row), nil
+}
+
+// DeleteGateway deletes a gateway, running sync before the deletion is committed.
+func (store *GatewayStore) DeleteGateway(ctx context.Context, gatewayID string, sync domain.GatewaySync) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "DeleteGateway")
+	defer span.End()
+
+	return store.inTx(ctx, sync, func(txQueries *sqlc.Queries) error {
+		deleted, err := txQueries.DeleteGateway(ctx, gatewayID)
+		if err != nil {
+			return stacktrace.NewStackTraceError(err)
+		}
+
+		if deleted == 0 {
+			return stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrGatewayNotFound, gatewayID)
+		}
+
+		return nil
+	})
+}
+
+// UpdateGatewayStatus records the connection status of a gateway and when it was last seen and checked.
+func (store *GatewayStore) UpdateGatewayStatus(ctx context.Context, gatewayID string, status domain.GatewayStatus, lastSeenAt time.Time, checkedAt time.Time) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "UpdateGatewayStatus")
+	defer span.End()
+
+	err := store.db.UpdateGatewayStatus(ctx, sqlc.UpdateGatewayStatusParams{
+		ID:              gatewayID,
+		Status:          string(status),
+		LastSeenAt:      pgtype.Timestamptz{Time: lastSeenAt, Valid: !lastSeenAt.IsZero()},
+		StatusCheckedAt: pgtype.Timestamptz{Time: checkedAt, Valid: true},
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// UpdateGatewayReportedLocation records the location reported by the GPS of a gateway. A location set for the
+// gateway is kept, even if it was set after the report was read.
+func (store *GatewayStore) UpdateGatewayReportedLocation(ctx context.Context, gatewayID string, location domain.GatewayLocation) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "UpdateGatewayReportedLocation")
+	defer span.End()
+
+	err := store.db.UpdateGatewayReportedLocation(ctx, sqlc.UpdateGatewayReportedLocationParams{
+		ID:        gatewayID,
+		Latitude:  pgtype.Float8{Float64: location.Latitude, Valid: true},
+		Longitude: pgtype.Float8{Float64: location.Longitude, Valid: true},
+		Altitude:  pgtype.Int4{Int32: location.Altitude, Valid: true},
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// inTx runs write and then sync in a transaction, committing only when both succeed.
+func (store *GatewayStore) inTx(ctx context.Context, sync domain.GatewaySync, write func(txQueries *sqlc.Queries) error) error {
+	tx, err := store.pool.Begin(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = write(store.db.WithTx(tx))
+	if err != nil {
+		return err
+	}
+
+	if sync != nil {
+		err = sync(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// gatewayLocationParams converts the location of a gateway to its columns, all NULL without a location.
+func gatewayLocationParams(location *domain.GatewayLocation) (pgtype.Float8, pgtype.Float8, pgtype.Int4, pgtype.Text) {
+	if location == nil {
+		return pgtype.Float8{}, pgtype.Float8{}, pgtype.Int4{}, pgtype.Text{}
+	}
+
+	return pgtype.Float8{Float64: location.Latitude, Valid: true},
+		pgtype.Float8{Float64: location.Longitude, Valid: true},
+		pgtype.Int4{Int32: location.Altitude, Valid: true},
+		pgtype.Text{String: string(location.Source), Valid: true}
+}
+
+// gatewayError maps constraint violations raised while writing a gateway to domain errors. Unknown frequency plans
+// are reported as invalid gateways.
+func gatewayError(err error, gateway *domain.Gateway) error {
+	if isUniqueViolation(err) {
+		return stacktrace.NewStackTraceErrorf("%w: EUI %s", domain.ErrGatewayExists, gateway.GatewayEui)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return stacktrace.NewStackTraceErrorf("%w: unknown frequency plan %q", domain.ErrInvalidGateway, gateway.FrequencyPlan)
+	}
+
+	return stacktrace.NewStackTraceError(err)
+}
+
+// gatewaysFromRows converts stored gateways to their domain representation.
+func gatewaysFromRows(rows []sqlc.Gateway) []*domain.Gateway {
+	gateways := make([]*domain.Gateway, len(rows))
+	for i, row := range rows {
+		gateways[i] = gatewayFromRow(row)
+	}
+
+	return gateways
+}
+
+// gatewayFromRow converts a stored gateway to its domain representation.
+func gatewayFromRow(row sqlc.Gateway) *domain.Gateway {
+	gateway := &domain.Gateway{
+		Id:              row.ID,
+		OrganizationId:  row.OrganizationID,
+		Name:            row.Name,
+		Description:     row.Description.String,
+		GatewayEui:      row.GatewayEui,
+		FrequencyPlan:   row.FrequencyPlanID,
+		Status:          domain.GatewayStatus(row.Status),
+		LastSeenAt:      row.LastSeenAt.Time,
+		StatusCheckedAt: row.StatusCheckedAt.Time,
+		CreatedAt:       row.CreatedAt.Time,
+		UpdatedAt:       row.UpdatedAt.Time,
+	}
+
+	if row.Latitude.Valid && row.Longitude.Valid {
+		gateway.Location = &domain.GatewayLocation{
+			Latitude:  row.Latitude.Float64,
+			Longitude: row.Longitude.Float64,
+			Altitude:  row.Altitude.Int32,
+			Source:    domain.GatewayLocationSource(row.LocationSource.String),
+		}
+	}
+
+	return gateway
+}
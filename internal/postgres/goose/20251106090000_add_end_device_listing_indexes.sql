@@ -0,0 +1,11 @@
+-- +goose Up
+-- Indexes supporting cursor pagination of end devices within an organization
+CREATE INDEX IF NOT EXISTS idx_end_devices_org_name_id
+ON end_devices(organization_id, name, id);
+
+CREATE INDEX IF NOT EXISTS idx_end_devices_org_created_at_id
+ON end_devices(organization_id, created_at, id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_end_devices_org_created_at_id;
+DROP INDEX IF EXISTS idx_end_devices_org_name_id;
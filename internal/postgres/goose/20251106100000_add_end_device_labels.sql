@@ -0,0 +1,24 @@
+-- +goose Up
+-- Key/value labels for selecting devices and a free-form attributes document
+ALTER TABLE end_devices
+ADD COLUMN labels JSONB NOT NULL DEFAULT '{}'::jsonb,
+ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+ALTER TABLE end_devices
+ADD CONSTRAINT check_labels_object CHECK (jsonb_typeof(labels) = 'object'),
+ADD CONSTRAINT check_attributes_object CHECK (jsonb_typeof(attributes) = 'object');
+
+-- Supports containment (@>) and key existence (?, ?&, ?|) label selector queries
+CREATE INDEX IF NOT EXISTS idx_end_devices_labels
+ON end_devices USING GIN (labels);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_end_devices_labels;
+
+ALTER TABLE end_devices
+DROP CONSTRAINT IF EXISTS check_attributes_object,
+DROP CONSTRAINT IF EXISTS check_labels_object;
+
+ALTER TABLE end_devices
+DROP COLUMN IF EXISTS attributes,
+DROP COLUMN IF EXISTS labels;
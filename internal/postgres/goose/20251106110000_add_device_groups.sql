@@ -0,0 +1,27 @@
+-- +goose Up
+-- Named, organization scoped groups of end devices (fleets)
+CREATE TABLE IF NOT EXISTS device_groups (
+    id CHAR(20) PRIMARY KEY,
+    organization_id CHAR(20) NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_device_group_name UNIQUE (organization_id, name)
+);
+
+-- Many-to-many membership between device groups and end devices
+CREATE TABLE IF NOT EXISTS device_group_members (
+    device_group_id CHAR(20) NOT NULL REFERENCES device_groups(id) ON DELETE CASCADE,
+    end_device_id CHAR(20) NOT NULL REFERENCES end_devices(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (device_group_id, end_device_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_group_members_end_device_id
+ON device_group_members(end_device_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_device_group_members_end_device_id;
+DROP TABLE IF EXISTS device_group_members;
+DROP TABLE IF EXISTS device_groups;
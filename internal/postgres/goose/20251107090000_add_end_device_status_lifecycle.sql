@@ -0,0 +1,28 @@
+-- +goose Up
+-- Latest traffic seen from each end device, used to detect devices that have gone silent
+CREATE TABLE IF NOT EXISTS end_device_presence (
+    end_device_id CHAR(20) PRIMARY KEY REFERENCES end_devices(id) ON DELETE CASCADE,
+    last_seen_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_end_device_presence_last_seen_at
+ON end_device_presence(last_seen_at);
+
+-- Audit trail of every end device status change
+CREATE TABLE IF NOT EXISTS end_device_status_transitions (
+    id CHAR(20) PRIMARY KEY,
+    end_device_id CHAR(20) NOT NULL REFERENCES end_devices(id) ON DELETE CASCADE,
+    from_status INTEGER NOT NULL, -- maps to EndDeviceStatus enum
+    to_status INTEGER NOT NULL, -- maps to EndDeviceStatus enum
+    reason TEXT NOT NULL,
+    transitioned_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_end_device_status_transitions_end_device_id
+ON end_device_status_transitions(end_device_id, transitioned_at DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_end_device_status_transitions_end_device_id;
+DROP TABLE IF EXISTS end_device_status_transitions;
+DROP INDEX IF EXISTS idx_end_device_presence_last_seen_at;
+DROP TABLE IF EXISTS end_device_presence;
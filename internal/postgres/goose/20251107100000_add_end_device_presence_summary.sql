@@ -0,0 +1,25 @@
+-- +goose Up
+-- Connectivity summary maintained by the processed envelope consumer
+ALTER TABLE end_device_presence
+    ADD COLUMN IF NOT EXISTS last_occurred_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS last_processed_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS last_values JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+-- Hourly message counts per end device, summed for the rolling 24 hour count
+CREATE TABLE IF NOT EXISTS end_device_message_counts (
+    end_device_id CHAR(20) NOT NULL REFERENCES end_devices(id) ON DELETE CASCADE,
+    hour_start TIMESTAMPTZ NOT NULL,
+    message_count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (end_device_id, hour_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_end_device_message_counts_hour_start
+ON end_device_message_counts(hour_start);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_end_device_message_counts_hour_start;
+DROP TABLE IF EXISTS end_device_message_counts;
+ALTER TABLE end_device_presence
+    DROP COLUMN IF EXISTS last_values,
+    DROP COLUMN IF EXISTS last_processed_at,
+    DROP COLUMN IF EXISTS last_occurred_at;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Desired and reported state documents of each end device
+CREATE TABLE IF NOT EXISTS end_device_twins (
+    end_device_id CHAR(20) PRIMARY KEY REFERENCES end_devices(id) ON DELETE CASCADE,
+    desired JSONB NOT NULL DEFAULT '{}'::jsonb,
+    desired_version BIGINT NOT NULL DEFAULT 0,
+    desired_updated_at TIMESTAMPTZ,
+    reported JSONB NOT NULL DEFAULT '{}'::jsonb,
+    reported_version BIGINT NOT NULL DEFAULT 0,
+    reported_updated_at TIMESTAMPTZ,
+    CONSTRAINT check_desired_object CHECK (jsonb_typeof(desired) = 'object'),
+    CONSTRAINT check_reported_object CHECK (jsonb_typeof(reported) = 'object')
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS end_device_twins;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- IEEE address blocks that the device EUIs of an organization are allocated from
+CREATE TABLE IF NOT EXISTS eui_blocks (
+    prefix VARCHAR(9) PRIMARY KEY,
+    organization_id CHAR(20) NOT NULL REFERENCES organizations(id),
+    size BIGINT NOT NULL,
+    next_offset BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT valid_eui_block_prefix CHECK (prefix ~ '^[0-9A-F]{6}([0-9A-F]|[0-9A-F]{3})?$'),
+    CONSTRAINT valid_eui_block_offset CHECK (next_offset >= 0 AND next_offset <= size)
+);
+
+CREATE INDEX IF NOT EXISTS idx_eui_blocks_organization ON eui_blocks(organization_id, created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_eui_blocks_organization;
+DROP TABLE IF EXISTS eui_blocks;
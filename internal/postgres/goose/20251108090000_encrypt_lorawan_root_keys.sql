@@ -0,0 +1,14 @@
+-- +goose Up
+-- LoRaWAN root keys are stored sealed with envelope encryption, which no longer fits the plaintext hex columns.
+-- Existing plaintext keys are sealed by the root key backfill that runs when the service starts.
+ALTER TABLE lorawan_configs DROP CONSTRAINT IF EXISTS valid_application_key;
+ALTER TABLE lorawan_configs DROP CONSTRAINT IF EXISTS valid_network_key;
+ALTER TABLE lorawan_configs ALTER COLUMN application_key TYPE TEXT;
+ALTER TABLE lorawan_configs ALTER COLUMN network_key TYPE TEXT;
+
+-- +goose Down
+-- Sealed root keys do not fit the plaintext columns; decrypt them before migrating down
+ALTER TABLE lorawan_configs ALTER COLUMN network_key TYPE CHAR(32);
+ALTER TABLE lorawan_configs ALTER COLUMN application_key TYPE CHAR(32);
+ALTER TABLE lorawan_configs ADD CONSTRAINT valid_network_key CHECK (network_key IS NULL OR network_key ~ '^[0-9A-Fa-f]{32}$');
+ALTER TABLE lorawan_configs ADD CONSTRAINT valid_application_key CHECK (application_key ~ '^[0-9A-Fa-f]{32}$');
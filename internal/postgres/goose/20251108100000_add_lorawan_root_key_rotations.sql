@@ -0,0 +1,16 @@
+-- +goose Up
+-- Audit trail of every replacement of the root keys of a LoRaWAN end device
+CREATE TABLE IF NOT EXISTS lorawan_root_key_rotations (
+    id CHAR(20) PRIMARY KEY,
+    end_device_id CHAR(20) NOT NULL REFERENCES end_devices(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    keys_supplied BOOLEAN NOT NULL, -- false when the new keys were generated
+    rotated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_lorawan_root_key_rotations_end_device_id
+ON lorawan_root_key_rotations(end_device_id, rotated_at DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_lorawan_root_key_rotations_end_device_id;
+DROP TABLE IF EXISTS lorawan_root_key_rotations;
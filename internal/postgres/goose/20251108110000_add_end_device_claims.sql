@@ -0,0 +1,26 @@
+-- +goose Up
+-- One-time codes that let another organization claim a pre-provisioned end device
+CREATE TABLE IF NOT EXISTS end_device_claims (
+    end_device_id CHAR(20) PRIMARY KEY REFERENCES end_devices(id) ON DELETE CASCADE,
+    organization_id CHAR(20) NOT NULL REFERENCES organizations(id), -- organization that issued the code
+    code_hash CHAR(64) NOT NULL UNIQUE, -- SHA-256 of the normalized claim code (hex); the code itself is never stored
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    claimed_at TIMESTAMPTZ,
+    claimed_by_organization_id CHAR(20) REFERENCES organizations(id)
+);
+
+-- Failed claim attempts per user, counted to rate-limit guessing of claim codes
+CREATE TABLE IF NOT EXISTS end_device_claim_attempts (
+    id CHAR(20) PRIMARY KEY,
+    user_id CHAR(20) NOT NULL,
+    attempted_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_end_device_claim_attempts_user_id
+ON end_device_claim_attempts(user_id, attempted_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_end_device_claim_attempts_user_id;
+DROP TABLE IF EXISTS end_device_claim_attempts;
+DROP TABLE IF EXISTS end_device_claims;
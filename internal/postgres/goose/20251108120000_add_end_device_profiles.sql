@@ -0,0 +1,32 @@
+-- +goose Up
+-- Organization scoped templates holding the defaults shared by end devices of one kind
+CREATE TABLE IF NOT EXISTS end_device_profiles (
+    id CHAR(20) PRIMARY KEY,
+    organization_id CHAR(20) NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT,
+    hardware_type INTEGER NOT NULL, -- maps to EndDeviceHardwareType enum
+    hardware_type_id CHAR(20) REFERENCES lorawan_hardware_types(id), -- LoRaWAN profiles only
+    frequency_plan_id VARCHAR(50) REFERENCES lorawan_frequency_plans(id), -- LoRaWAN profiles only
+    payload_decoder TEXT,
+    expected_fields TEXT[] NOT NULL DEFAULT '{}',
+    labels JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_end_device_profile_name UNIQUE (organization_id, name)
+);
+
+-- The end device profile each end device was created from
+CREATE TABLE IF NOT EXISTS end_device_profile_devices (
+    end_device_id CHAR(20) PRIMARY KEY REFERENCES end_devices(id) ON DELETE CASCADE,
+    end_device_profile_id CHAR(20) NOT NULL REFERENCES end_device_profiles(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_end_device_profile_devices_profile_id
+ON end_device_profile_devices(end_device_profile_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_end_device_profile_devices_profile_id;
+DROP TABLE IF EXISTS end_device_profile_devices;
+DROP TABLE IF EXISTS end_device_profiles;
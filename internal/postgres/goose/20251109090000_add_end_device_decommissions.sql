@@ -0,0 +1,41 @@
+-- +goose Up
+-- Decommission workflows of end devices that left service. Rows outlive the end device so the decommission
+-- stays auditable.
+CREATE TABLE IF NOT EXISTS end_device_decommissions (
+    id CHAR(20) PRIMARY KEY,
+    end_device_id CHAR(20) NOT NULL UNIQUE,
+    organization_id CHAR(20) NOT NULL REFERENCES organizations(id),
+    requested_by CHAR(20) NOT NULL, -- user that requested the decommission
+    data_policy TEXT NOT NULL, -- 'retain' or 'purge'
+    step TEXT NOT NULL, -- last completed step
+    purge_after TIMESTAMPTZ NOT NULL, -- when the device's ClickHouse data is purged
+    attempts INTEGER NOT NULL DEFAULT 0, -- failures of the current step since it last succeeded
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ,
+    CONSTRAINT valid_data_policy CHECK (data_policy IN ('retain', 'purge')),
+    CONSTRAINT valid_decommission_step CHECK (step IN ('requested', 'disabled', 'deregistered', 'credentials_revoked', 'retaining_data', 'purging_data', 'completed'))
+);
+
+-- Audit trail of every completed or failed step of an end device decommission
+CREATE TABLE IF NOT EXISTS end_device_decommission_events (
+    id CHAR(20) PRIMARY KEY,
+    decommission_id CHAR(20) NOT NULL REFERENCES end_device_decommissions(id) ON DELETE CASCADE,
+    step TEXT NOT NULL,
+    error TEXT, -- NULL when the step completed
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_end_device_decommissions_next_attempt_at
+ON end_device_decommissions(next_attempt_at) WHERE step <> 'completed';
+
+CREATE INDEX IF NOT EXISTS idx_end_device_decommission_events_decommission_id
+ON end_device_decommission_events(decommission_id, occurred_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_end_device_decommission_events_decommission_id;
+DROP INDEX IF EXISTS idx_end_device_decommissions_next_attempt_at;
+DROP TABLE IF EXISTS end_device_decommission_events;
+DROP TABLE IF EXISTS end_device_decommissions;
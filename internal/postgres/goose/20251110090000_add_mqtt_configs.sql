@@ -0,0 +1,27 @@
+-- +goose Up
+-- Allow MQTT end devices (hardware type 3)
+ALTER TABLE end_devices DROP CONSTRAINT IF EXISTS check_hardware_type;
+ALTER TABLE end_devices
+ADD CONSTRAINT check_hardware_type CHECK (hardware_type IN (0, 1, 2, 3));
+
+-- Topic and broker credentials of MQTT end devices
+CREATE TABLE mqtt_configs (
+    id CHAR(20) PRIMARY KEY,
+    end_device_id CHAR(20) NOT NULL REFERENCES end_devices(id) ON DELETE CASCADE,
+    topic TEXT NOT NULL,
+    username TEXT NOT NULL,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_mqtt_end_device_id UNIQUE (end_device_id),
+    CONSTRAINT unique_mqtt_topic UNIQUE (topic),
+    CONSTRAINT unique_mqtt_username UNIQUE (username)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS mqtt_configs;
+
+-- MQTT end devices that are left stay as they are but no new ones can be added
+ALTER TABLE end_devices DROP CONSTRAINT IF EXISTS check_hardware_type;
+ALTER TABLE end_devices
+ADD CONSTRAINT check_hardware_type CHECK (hardware_type IN (0, 1, 2)) NOT VALID;
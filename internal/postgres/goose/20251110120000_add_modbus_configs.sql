@@ -0,0 +1,28 @@
+-- +goose Up
+-- Allow Modbus end devices (hardware type 4)
+ALTER TABLE end_devices DROP CONSTRAINT IF EXISTS check_hardware_type;
+ALTER TABLE end_devices
+ADD CONSTRAINT check_hardware_type CHECK (hardware_type IN (0, 1, 2, 3, 4));
+
+-- Connection and register map of Modbus TCP end devices, whose registers are polled
+CREATE TABLE modbus_configs (
+    id CHAR(20) PRIMARY KEY,
+    end_device_id CHAR(20) NOT NULL REFERENCES end_devices(id) ON DELETE CASCADE,
+    host TEXT NOT NULL,
+    unit_id SMALLINT NOT NULL,
+    poll_interval_seconds INTEGER NOT NULL,
+    registers JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_modbus_end_device_id UNIQUE (end_device_id),
+    CONSTRAINT valid_modbus_unit_id CHECK (unit_id BETWEEN 0 AND 255),
+    CONSTRAINT valid_modbus_poll_interval CHECK (poll_interval_seconds > 0)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS modbus_configs;
+
+-- Modbus end devices that are left stay as they are but no new ones can be added
+ALTER TABLE end_devices DROP CONSTRAINT IF EXISTS check_hardware_type;
+ALTER TABLE end_devices
+ADD CONSTRAINT check_hardware_type CHECK (hardware_type IN (0, 1, 2, 3)) NOT VALID;
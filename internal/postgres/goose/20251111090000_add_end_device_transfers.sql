@@ -0,0 +1,21 @@
+-- +goose Up
+-- Transfers of end devices between organizations. Rows outlive the end device so the transfer stays auditable.
+CREATE TABLE IF NOT EXISTS end_device_transfers (
+    id CHAR(20) PRIMARY KEY,
+    end_device_id CHAR(20) NOT NULL,
+    from_organization_id CHAR(20) NOT NULL REFERENCES organizations(id),
+    to_organization_id CHAR(20) NOT NULL REFERENCES organizations(id),
+    transferred_by CHAR(20) NOT NULL, -- user that transferred the device
+    history TEXT NOT NULL, -- 'keep', 'copy' or 'retag'
+    transferred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    history_transferred_at TIMESTAMPTZ, -- when the ClickHouse data was copied or re-tagged
+    CONSTRAINT valid_transfer_history CHECK (history IN ('keep', 'copy', 'retag')),
+    CONSTRAINT distinct_transfer_organizations CHECK (from_organization_id <> to_organization_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_end_device_transfers_end_device_id
+ON end_device_transfers(end_device_id, transferred_at DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_end_device_transfers_end_device_id;
+DROP TABLE IF EXISTS end_device_transfers;
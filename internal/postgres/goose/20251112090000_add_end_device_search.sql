@@ -0,0 +1,19 @@
+-- +goose Up
+-- Full-text document of an end device, searched by SearchEndDevices. Names weigh most, then descriptions, then the
+-- keys and values of labels. The function is immutable so the document can be indexed without storing it.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION end_device_search_document(name TEXT, description TEXT, labels JSONB) RETURNS tsvector
+LANGUAGE SQL IMMUTABLE PARALLEL SAFE
+AS $$
+    SELECT setweight(to_tsvector('english', name), 'A')
+        || setweight(to_tsvector('english', coalesce(description, '')), 'B')
+        || setweight(jsonb_to_tsvector('english', labels, '["key", "string"]'), 'C')
+$$;
+-- +goose StatementEnd
+
+CREATE INDEX IF NOT EXISTS idx_end_devices_search
+ON end_devices USING GIN (end_device_search_document(name, description, labels));
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_end_devices_search;
+DROP FUNCTION IF EXISTS end_device_search_document(TEXT, TEXT, JSONB);
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Payload decoders of LoRaWAN hardware types
+CREATE TABLE lorawan_hardware_type_decoders (
+    hardware_type_id CHAR(20) PRIMARY KEY REFERENCES lorawan_hardware_types(id) ON DELETE CASCADE,
+    script TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS lorawan_hardware_type_decoders;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- Built-in codecs LoRaWAN hardware types can decode payloads with instead of a script
+ALTER TABLE lorawan_hardware_type_decoders
+ADD COLUMN codec TEXT NOT NULL DEFAULT 'javascript',
+ADD COLUMN byte_layout JSONB,
+ALTER COLUMN script SET DEFAULT '';
+
+-- +goose Down
+ALTER TABLE lorawan_hardware_type_decoders
+DROP COLUMN IF EXISTS byte_layout,
+DROP COLUMN IF EXISTS codec,
+ALTER COLUMN script DROP DEFAULT;
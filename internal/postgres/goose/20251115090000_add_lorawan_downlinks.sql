@@ -0,0 +1,24 @@
+-- +goose Up
+-- Downlinks pushed to the network server's queue of LoRaWAN end devices, newest first per device
+CREATE TABLE IF NOT EXISTS lorawan_downlinks (
+    id CHAR(20) PRIMARY KEY,
+    end_device_id CHAR(20) NOT NULL REFERENCES end_devices(id) ON DELETE CASCADE,
+    f_port INTEGER NOT NULL,
+    frm_payload BYTEA NOT NULL,
+    data JSONB, -- what frm_payload was encoded from by the device's payload decoder; NULL for raw payloads
+    confirmed BOOLEAN NOT NULL DEFAULT FALSE,
+    status TEXT NOT NULL, -- 'queued', 'sent', 'acked' or 'failed'
+    error TEXT NOT NULL DEFAULT '', -- why the downlink failed or was queued again
+    requested_by CHAR(20) NOT NULL, -- user that scheduled the downlink
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT valid_downlink_f_port CHECK (f_port BETWEEN 1 AND 223),
+    CONSTRAINT valid_downlink_status CHECK (status IN ('queued', 'sent', 'acked', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_lorawan_downlinks_end_device_id
+ON lorawan_downlinks(end_device_id, created_at DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_lorawan_downlinks_end_device_id;
+DROP TABLE IF EXISTS lorawan_downlinks;
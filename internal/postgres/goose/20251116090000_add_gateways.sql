@@ -0,0 +1,31 @@
+-- +goose Up
+-- LoRaWAN gateways operated by an organization, registered with The Things Stack under their ID
+CREATE TABLE IF NOT EXISTS gateways (
+    id CHAR(20) PRIMARY KEY, -- also the gateway ID in The Things Stack
+    organization_id CHAR(20) NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT,
+    gateway_eui CHAR(16) NOT NULL,
+    frequency_plan_id VARCHAR(50) NOT NULL REFERENCES lorawan_frequency_plans(id),
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    altitude INTEGER, -- meters above sea level
+    location_source TEXT, -- 'registry' when set through ponix, 'status' when reported by the gateway's GPS
+    status TEXT NOT NULL DEFAULT 'unknown', -- 'unknown' until the Gateway Server is asked, then 'online' or 'offline'
+    last_seen_at TIMESTAMPTZ, -- last connection, status message or uplink of the gateway
+    status_checked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_gateway_eui UNIQUE (gateway_eui),
+    CONSTRAINT valid_gateway_eui CHECK (gateway_eui ~ '^[0-9A-F]{16}$'),
+    CONSTRAINT valid_gateway_status CHECK (status IN ('unknown', 'online', 'offline')),
+    CONSTRAINT valid_gateway_location_source CHECK (location_source IN ('registry', 'status')),
+    CONSTRAINT complete_gateway_location CHECK ((latitude IS NULL) = (longitude IS NULL) AND (latitude IS NULL) = (location_source IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_gateways_organization_id
+ON gateways(organization_id, name);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_gateways_organization_id;
+DROP TABLE IF EXISTS gateways;
@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/postgres/sqlc"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// LoRaWANDownlinkStore handles database operations for the downlinks sent to LoRaWAN end devices.
+type LoRaWANDownlinkStore struct {
+	db   *sqlc.Queries
+	pool *pgxpool.Pool
+}
+
+// NewLoRaWANDownlinkStore creates a new LoRaWANDownlinkStore instance.
+func NewLoRaWANDownlinkStore(db *sqlc.Queries, pool *pgxpool.Pool) *LoRaWANDownlinkStore {
+	return &LoRaWANDownlinkStore{
+		db:   db,
+		pool: pool,
+	}
+}
+
+// CreateLoRaWANDownlink records a downlink.
+func (store *LoRaWANDownlinkStore) CreateLoRaWANDownlink(ctx context.Context, downlink *domain.LoRaWANDownlink) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "CreateLoRaWANDownlink")
+	defer span.End()
+
+	var data []byte
+	if downlink.Data != nil {
+		var err error
+		data, err = json.Marshal(downlink.Data)
+		if err != nil {
+			return stacktrace.NewStackTraceError(err)
+		}
+	}
+
+	// An empty payload is stored as such; a nil one would be NULL
+	payload := downlink.FrmPayload
+	if payload == nil {
+		payload = []byte{}
+	}
+
+	err := store.db.CreateLoRaWANDownlink(ctx, sqlc.CreateLoRaWANDownlinkParams{
+		ID:          downlink.Id,
+		EndDeviceID: downlink.EndDeviceId,
+		FPort:       int32(downlink.FPort),
+		FrmPayload:  payload,
+		Data:        data,
+		Confirmed:   downlink.Confirmed,
+		Status:      string(downlink.Status),
+		Error:       downlink.Error,
+		RequestedBy: downlink.RequestedBy,
+		CreatedAt:   pgtype.Timestamptz{Time: downlink.CreatedAt, Valid: true},
+		UpdatedAt:   pgtype.Timestamptz{Time: downlink.UpdatedAt, Valid: true},
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// UpdateLoRaWANDownlinkStatus moves a downlink to a status if it currently has one of the from statuses, and
+// reports whether it did.
+func (store *LoRaWANDownlinkStore) UpdateLoRaWANDownlinkStatus(ctx context.Context, downlinkId string, status domain.LoRaWANDownlinkStatus, reason string, updatedAt time.Time, from []domain.LoRaWANDownlinkStatus) (bool, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "UpdateLoRaWANDownlinkStatus")
+	defer span.End()
+
+	fromStatuses := make([]string, len(from))
+	for i, fromStatus := range from {
+		fromStatuses[i] = string(fromStatus)
+	}
+
+	updated, err := store.db.UpdateLoRaWANDownlinkStatus(ctx, sqlc.UpdateLoRaWANDownlinkStatusParams{
+		Status:       string(status),
+		Error:        reason,
+		UpdatedAt:    pgtype.Timestamptz{Time: updatedAt, Valid: true},
+		ID:           downlinkId,
+		FromStatuses: fromStatuses,
+	})
+	if err != nil {
+		return false, stacktrace.NewStackTraceError(err)
+	}
+
+	return updated > 0, nil
+}
+
+// ListEndDeviceLoRaWANDownlinks retrieves the most recent downlinks of an end device, newest first.
+func (store *LoRaWANDownlinkStore) ListEndDeviceLoRaWANDownlinks(ctx context.Context, endDeviceId string, limit int) ([]*domain.LoRaWANDownlink, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListEndDeviceLoRaWANDownlinks")
+	defer span.End()
+
+	rows, err := store.db.ListEndDeviceLoRaWANDownlinks(ctx, sqlc.ListEndDeviceLoRaWANDownlinksParams{
+		EndDeviceID: endDeviceId,
+		Limit:       int32(limit),
+	})
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	downlinks := make([]*domain.LoRaWANDownlink, 0, len(rows))
+	for _, row := range rows {
+		downlink, err := loRaWANDownlinkFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+		downlinks = append(downlinks, downlink)
+	}
+
+	return downlinks, nil
+}
+
+// loRaWANDownlinkFromRow converts a stored downlink to its domain representation.
+func loRaWANDownlinkFromRow(row sqlc.LorawanDownlink) (*domain.LoRaWANDownlink, error) {
+	downlink := &domain.LoRaWANDownlink{
+		Id:          row.ID,
+		EndDeviceId: row.EndDeviceID,
+		FPort:       uint32(row.FPort),
+		FrmPayload:  row.FrmPayload,
+		Confirmed:   row.Confirmed,
+		Status:      domain.LoRaWANDownlinkStatus(row.Status),
+		Error:       row.Error,
+		RequestedBy: row.RequestedBy,
+		CreatedAt:   row.CreatedAt.Time,
+		UpdatedAt:   row.UpdatedAt.Time,
+	}
+
+	if row.Data != nil {
+		err := json.Unmarshal(row.Data, &downlink.Data)
+		if err != nil {
+			return nil, stacktrace.NewStackTraceError(err)
+		}
+	}
+
+	return downlink, nil
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/postgres/sqlc"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// ModbusStore handles database lookups of the Modbus end devices to poll.
+type ModbusStore struct {
+	db   *sqlc.Queries
+	pool *pgxpool.Pool
+}
+
+// NewModbusStore creates a new ModbusStore instance.
+func NewModbusStore(db *sqlc.Queries, pool *pgxpool.Pool) *ModbusStore {
+	return &ModbusStore{
+		db:   db,
+		pool: pool,
+	}
+}
+
+// ListModbusEndDevices retrieves every Modbus end device with its status and configuration.
+func (store *ModbusStore) ListModbusEndDevices(ctx context.Context) ([]*domain.ModbusEndDevice, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListModbusEndDevices")
+	defer span.End()
+
+	rows, err := store.db.ListModbusEndDevices(ctx)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	devices := make([]*domain.ModbusEndDevice, 0, len(rows))
+	for _, row := range rows {
+		var registers []domain.ModbusRegister
+		err = json.Unmarshal(row.Registers, &registers)
+		if err != nil {
+			return nil, stacktrace.NewStackTraceError(err)
+		}
+
+		devices = append(devices, &domain.ModbusEndDevice{
+			EndDeviceId:    row.EndDeviceID,
+			OrganizationId: row.OrganizationID,
+			Status:         iotv1.EndDeviceStatus(row.Status),
+			Config: domain.ModbusConfig{
+				Host:         row.Host,
+				UnitId:       uint8(row.UnitID),
+				PollInterval: time.Duration(row.PollIntervalSeconds) * time.Second,
+				Registers:    registers,
+			},
+		})
+	}
+
+	return devices, nil
+}
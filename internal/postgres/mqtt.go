@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/postgres/sqlc"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// MQTTStore handles database lookups of the topics MQTT end devices publish to.
+type MQTTStore struct {
+	db   *sqlc.Queries
+	pool *pgxpool.Pool
+}
+
+// NewMQTTStore creates a new MQTTStore instance.
+func NewMQTTStore(db *sqlc.Queries, pool *pgxpool.Pool) *MQTTStore {
+	return &MQTTStore{
+		db:   db,
+		pool: pool,
+	}
+}
+
+// GetEndDeviceByMQTTTopic returns the ID and organization ID of the end device publishing to topic.
+func (store *MQTTStore) GetEndDeviceByMQTTTopic(ctx context.Context, topic string) (string, string, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetEndDeviceByMQTTTopic")
+	defer span.End()
+
+	row, err := store.db.GetEndDeviceByMQTTTopic(ctx, topic)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", "", stacktrace.NewStackTraceErrorf("%w: no end device publishes to %s", domain.ErrEndDeviceNotFound, topic)
+		}
+		return "", "", stacktrace.NewStackTraceError(err)
+	}
+
+	return row.ID, row.OrganizationID, nil
+}
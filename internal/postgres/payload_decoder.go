@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/postgres/sqlc"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// PayloadDecoderStore handles database operations for the payload decoders of end devices.
+type PayloadDecoderStore struct {
+	db   *sqlc.Queries
+	pool *pgxpool.Pool
+}
+
+// NewPayloadDecoderStore creates a new PayloadDecoderStore instance.
+func NewPayloadDecoderStore(db *sqlc.Queries, pool *pgxpool.Pool) *PayloadDecoderStore {
+	return &PayloadDecoderStore{
+		db:   db,
+		pool: pool,
+	}
+}
+
+// GetEndDevicePayloadDecoder returns the decoder of the uplinks of an end device, preferring the script of its
+// profile over the decoder of its LoRaWAN hardware type. It is nil when neither has one.
+func (store *PayloadDecoderStore) GetEndDevicePayloadDecoder(ctx context.Context, endDeviceId string) (*domain.PayloadDecoder, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetEndDevicePayloadDecoder")
+	defer span.End()
+
+	row, err := store.db.GetEndDevicePayloadDecoder(ctx, endDeviceId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrEndDeviceNotFound, endDeviceId)
+		}
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	if row.Codec == "" {
+		return nil, nil
+	}
+
+	return payloadDecoderFromRow(row.Codec, row.Script, row.ByteLayout)
+}
+
+// GetLoRaWANHardwareTypePayloadDecoder returns the payload decoder of a LoRaWAN hardware type, nil when it has none.
+func (store *PayloadDecoderStore) GetLoRaWANHardwareTypePayloadDecoder(ctx context.Context, hardwareTypeId string) (*domain.PayloadDecoder, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetLoRaWANHardwareTypePayloadDecoder")
+	defer span.End()
+
+	row, err := store.db.GetLoRaWANHardwareTypeDecoder(ctx, hardwareTypeId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	return payloadDecoderFromRow(row.Codec, row.Script, row.ByteLayout)
+}
+
+// SetLoRaWANHardwareTypePayloadDecoder sets or replaces the payload decoder of a LoRaWAN hardware type.
+func (store *PayloadDecoderStore) SetLoRaWANHardwareTypePayloadDecoder(ctx context.Context, hardwareTypeId string, decoder domain.PayloadDecoder) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "SetLoRaWANHardwareTypePayloadDecoder")
+	defer span.End()
+
+	var layout []byte
+	if decoder.Layout != nil {
+		var err error
+		layout, err = json.Marshal(decoder.Layout)
+		if err != nil {
+			return stacktrace.NewStackTraceError(err)
+		}
+	}
+
+	err := store.db.UpsertLoRaWANHardwareTypeDecoder(ctx, sqlc.UpsertLoRaWANHardwareTypeDecoderParams{
+		HardwareTypeID: hardwareTypeId,
+		Codec:          string(decoder.Codec),
+		Script:         decoder.Script,
+		ByteLayout:     layout,
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// DeleteLoRaWANHardwareTypePayloadDecoder removes the payload decoder of a LoRaWAN hardware type.
+func (store *PayloadDecoderStore) DeleteLoRaWANHardwareTypePayloadDecoder(ctx context.Context, hardwareTypeId string) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "DeleteLoRaWANHardwareTypePayloadDecoder")
+	defer span.End()
+
+	err := store.db.DeleteLoRaWANHardwareTypeDecoder(ctx, hardwareTypeId)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// payloadDecoderFromRow converts the stored codec, script and byte layout of a payload decoder.
+func payloadDecoderFromRow(codec string, script string, byteLayout []byte) (*domain.PayloadDecoder, error) {
+	decoder := &domain.PayloadDecoder{
+		Codec:  domain.PayloadCodec(codec),
+		Script: script,
+	}
+
+	if byteLayout != nil {
+		err := json.Unmarshal(byteLayout, &decoder.Layout)
+		if err != nil {
+			return nil, stacktrace.NewStackTraceError(err)
+		}
+	}
+
+	return decoder, nil
+}
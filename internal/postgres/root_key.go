@@ -0,0 +1,208 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/postgres/sqlc"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// RootKeyStore handles database operations for encrypting, re-wrapping and rotating stored LoRaWAN root keys.
+type RootKeyStore struct {
+	db   *sqlc.Queries
+	pool *pgxpool.Pool
+}
+
+// NewRootKeyStore creates a new RootKeyStore instance.
+func NewRootKeyStore(db *sqlc.Queries, pool *pgxpool.Pool) *RootKeyStore {
+	return &RootKeyStore{
+		db:   db,
+		pool: pool,
+	}
+}
+
+// ListPlaintextRootKeys retrieves up to limit end devices whose root keys are still stored as plaintext.
+func (store *RootKeyStore) ListPlaintextRootKeys(ctx context.Context, limit int) ([]domain.StoredRootKeys, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListPlaintextRootKeys")
+	defer span.End()
+
+	rows, err := store.db.ListPlaintextRootKeys(ctx, int32(limit))
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	rootKeys := make([]domain.StoredRootKeys, 0, len(rows))
+	for _, row := range rows {
+		rootKeys = append(rootKeys, domain.StoredRootKeys{
+			EndDeviceId:    row.EndDeviceID,
+			ApplicationKey: row.ApplicationKey,
+			NetworkKey:     row.NetworkKey.String,
+		})
+	}
+
+	return rootKeys, nil
+}
+
+// ListRootKeysNotWrappedWith retrieves up to limit end devices whose sealed root keys are wrapped with a
+// master key other than masterKeyId.
+func (store *RootKeyStore) ListRootKeysNotWrappedWith(ctx context.Context, masterKeyId string, limit int) ([]domain.StoredRootKeys, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListRootKeysNotWrappedWith")
+	defer span.End()
+
+	rows, err := store.db.ListRootKeysNotWrappedWith(ctx, sqlc.ListRootKeysNotWrappedWithParams{
+		MasterKeyID: masterKeyId,
+		RowLimit:    int32(limit),
+	})
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	rootKeys := make([]domain.StoredRootKeys, 0, len(rows))
+	for _, row := range rows {
+		rootKeys = append(rootKeys, domain.StoredRootKeys{
+			EndDeviceId:    row.EndDeviceID,
+			ApplicationKey: row.ApplicationKey,
+			NetworkKey:     row.NetworkKey.String,
+		})
+	}
+
+	return rootKeys, nil
+}
+
+// UpdateRootKeys replaces the root keys of an end device unless its application key no longer matches previous.
+func (store *RootKeyStore) UpdateRootKeys(ctx context.Context, previous domain.StoredRootKeys, sealed domain.StoredRootKeys) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "UpdateRootKeys")
+	defer span.End()
+
+	_, err := store.db.UpdateRootKeys(ctx, sqlc.UpdateRootKeysParams{
+		ApplicationKey:         sealed.ApplicationKey,
+		NetworkKey:             pgtype.Text{String: sealed.NetworkKey, Valid: sealed.NetworkKey != ""},
+		EndDeviceID:            sealed.EndDeviceId,
+		PreviousApplicationKey: previous.ApplicationKey,
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// RotateRootKeys replaces the root keys of an end device, records the rotation and applies the status transition
+// within a transaction. The sync function runs after the changes are written but before they are committed, so a
+// failure to push the keys to the Join Server rolls back the database update.
+func (store *RootKeyStore) RotateRootKeys(ctx context.Context, rotation *domain.RootKeyRotation, previous domain.StoredRootKeys, sealed domain.StoredRootKeys, transition *domain.EndDeviceStatusTransition, sync domain.EndDeviceSync) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "RotateRootKeys")
+	defer span.End()
+
+	tx, err := store.pool.Begin(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	txQueries := store.db.WithTx(tx)
+
+	err = rotateRootKeys(ctx, txQueries, rotation, previous, sealed, transition)
+	if err != nil {
+		return err
+	}
+
+	if sync != nil {
+		err = sync(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	return nil
+}
+
+// ListRootKeyRotations retrieves the root key rotation history of an end device, most recent first.
+func (store *RootKeyStore) ListRootKeyRotations(ctx context.Context, endDeviceID string) ([]*domain.RootKeyRotation, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ListRootKeyRotations")
+	defer span.End()
+
+	rows, err := store.db.ListRootKeyRotations(ctx, endDeviceID)
+	if err != nil {
+		return nil, stacktrace.NewStackTraceError(err)
+	}
+
+	rotations := make([]*domain.RootKeyRotation, 0, len(rows))
+	for _, row := range rows {
+		rotations = append(rotations, &domain.RootKeyRotation{
+			Id:           row.ID,
+			EndDeviceId:  row.EndDeviceID,
+			Reason:       row.Reason,
+			KeysSupplied: row.KeysSupplied,
+			RotatedAt:    row.RotatedAt.Time,
+		})
+	}
+
+	return rotations, nil
+}
+
+// rotateRootKeys writes a root key rotation and its status transition, if any, using the queries of a transaction.
+func rotateRootKeys(ctx context.Context, txQueries *sqlc.Queries, rotation *domain.RootKeyRotation, previous domain.StoredRootKeys, sealed domain.StoredRootKeys, transition *domain.EndDeviceStatusTransition) error {
+	updated, err := txQueries.UpdateRootKeys(ctx, sqlc.UpdateRootKeysParams{
+		ApplicationKey:         sealed.ApplicationKey,
+		NetworkKey:             pgtype.Text{String: sealed.NetworkKey, Valid: sealed.NetworkKey != ""},
+		EndDeviceID:            sealed.EndDeviceId,
+		PreviousApplicationKey: previous.ApplicationKey,
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	if updated == 0 {
+		return stacktrace.NewStackTraceErrorf("%w: %s", domain.ErrRootKeysChanged, sealed.EndDeviceId)
+	}
+
+	_, err = txQueries.CreateRootKeyRotation(ctx, sqlc.CreateRootKeyRotationParams{
+		ID:           rotation.Id,
+		EndDeviceID:  rotation.EndDeviceId,
+		Reason:       rotation.Reason,
+		KeysSupplied: rotation.KeysSupplied,
+		RotatedAt:    pgtype.Timestamptz{Time: rotation.RotatedAt, Valid: true},
+	})
+	if err != nil {
+		return stacktrace.NewStackTraceError(err)
+	}
+
+	if transition != nil {
+		updated, err = txQueries.TransitionEndDeviceStatus(ctx, sqlc.TransitionEndDeviceStatusParams{
+			ToStatus:   int32(transition.To),
+			ID:         transition.EndDeviceId,
+			FromStatus: int32(transition.From),
+		})
+		if err != nil {
+			return stacktrace.NewStackTraceError(err)
+		}
+
+		if updated == 0 {
+			return stacktrace.NewStackTraceErrorf("%w: %s is no longer %s", domain.ErrInvalidStatusTransition, transition.EndDeviceId, transition.From)
+		}
+
+		_, err = txQueries.CreateEndDeviceStatusTransition(ctx, sqlc.CreateEndDeviceStatusTransitionParams{
+			ID:             transition.Id,
+			EndDeviceID:    transition.EndDeviceId,
+			FromStatus:     int32(transition.From),
+			ToStatus:       int32(transition.To),
+			Reason:         transition.Reason,
+			TransitionedAt: pgtype.Timestamptz{Time: transition.TransitionedAt, Valid: true},
+		})
+		if err != nil {
+			return stacktrace.NewStackTraceError(err)
+		}
+	}
+
+	return nil
+}
@@ -13,9 +13,9 @@ import (
 
 const createEndDevice = `-- name: CreateEndDevice :one
 
-INSERT INTO end_devices (id, name, description, organization_id, status, data_type, hardware_type)
-VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING id, name, description, organization_id, status, data_type, hardware_type, created_at, updated_at
+INSERT INTO end_devices (id, name, description, organization_id, status, data_type, hardware_type, labels, attributes)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, name, description, organization_id, status, data_type, hardware_type, created_at, updated_at, labels, attributes
 `
 
 type CreateEndDeviceParams struct {
@@ -26,6 +26,8 @@ type CreateEndDeviceParams struct {
 	Status         int32
 	DataType       int32
 	HardwareType   int32
+	Labels         []byte
+	Attributes     []byte
 }
 
 // ===== End Devices (Generic) =====
@@ -38,6 +40,8 @@ func (q *Queries) CreateEndDevice(ctx context.Context, arg CreateEndDeviceParams
 		arg.Status,
 		arg.DataType,
 		arg.HardwareType,
+		arg.Labels,
+		arg.Attributes,
 	)
 	var i EndDevice
 	err := row.Scan(
@@ -50,6 +54,8 @@ func (q *Queries) CreateEndDevice(ctx context.Context, arg CreateEndDeviceParams
 		&i.HardwareType,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Labels,
+		&i.Attributes,
 	)
 	return i, err
 }
@@ -65,7 +71,7 @@ func (q *Queries) DeleteEndDevice(ctx context.Context, id string) error {
 }
 
 const getEndDevice = `-- name: GetEndDevice :one
-SELECT id, name, description, organization_id, status, data_type, hardware_type, created_at, updated_at FROM end_devices
+SELECT id, name, description, organization_id, status, data_type, hardware_type, created_at, updated_at, labels, attributes FROM end_devices
 WHERE id = $1
 `
 
@@ -82,12 +88,14 @@ func (q *Queries) GetEndDevice(ctx context.Context, id string) (EndDevice, error
 		&i.HardwareType,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Labels,
+		&i.Attributes,
 	)
 	return i, err
 }
 
 const getEndDeviceWithOrganization = `-- name: GetEndDeviceWithOrganization :one
-SELECT id, name, description, organization_id, status, data_type, hardware_type, created_at, updated_at
+SELECT id, name, description, organization_id, status, data_type, hardware_type, created_at, updated_at, labels, attributes
 FROM end_devices
 WHERE id = $1
 `
@@ -105,12 +113,102 @@ func (q *Queries) GetEndDeviceWithOrganization(ctx context.Context, id string) (
 		&i.HardwareType,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Labels,
+		&i.Attributes,
 	)
 	return i, err
 }
 
+const listEndDeviceIDsByLabels = `-- name: ListEndDeviceIDsByLabels :many
+SELECT ed.id
+FROM end_devices ed
+WHERE ed.organization_id = $1
+  AND ($2::JSONB IS NULL OR ed.labels @> $2)
+  AND ($3::JSONB IS NULL OR NOT EXISTS (
+    SELECT 1 FROM jsonb_each($3) AS s(key, vals)
+    WHERE NOT COALESCE(s.vals ? (ed.labels ->> s.key), false)))
+  AND ($4::JSONB IS NULL OR NOT EXISTS (
+    SELECT 1 FROM jsonb_each($4) AS s(key, vals)
+    WHERE COALESCE(s.vals ? (ed.labels ->> s.key), false)))
+  AND ($5::TEXT[] IS NULL OR ed.labels ?& $5)
+  AND ($6::TEXT[] IS NULL OR NOT ed.labels ?| $6)
+ORDER BY ed.id
+`
+
+type ListEndDeviceIDsByLabelsParams struct {
+	OrganizationID string
+	LabelEquals    []byte
+	LabelIn        []byte
+	LabelNotIn     []byte
+	LabelExists    []string
+	LabelNotExists []string
+}
+
+func (q *Queries) ListEndDeviceIDsByLabels(ctx context.Context, arg ListEndDeviceIDsByLabelsParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, listEndDeviceIDsByLabels,
+		arg.OrganizationID,
+		arg.LabelEquals,
+		arg.LabelIn,
+		arg.LabelNotIn,
+		arg.LabelExists,
+		arg.LabelNotExists,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEndDeviceIDsInOrganization = `-- name: ListEndDeviceIDsInOrganization :many
+SELECT id
+FROM end_devices
+WHERE organization_id = $1
+  AND id = ANY($2::TEXT[])
+ORDER BY id
+`
+
+type ListEndDeviceIDsInOrganizationParams struct {
+	OrganizationID string
+	EndDeviceIds   []string
+}
+
+func (q *Queries) ListEndDeviceIDsInOrganization(ctx context.Context, arg ListEndDeviceIDsInOrganizationParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, listEndDeviceIDsInOrganization,
+		arg.OrganizationID,
+		arg.EndDeviceIds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listEndDevicesByOrganization = `-- name: ListEndDevicesByOrganization :many
-SELECT id, name, description, organization_id, status, data_type, hardware_type, created_at, updated_at FROM end_devices
+SELECT id, name, description, organization_id, status, data_type, hardware_type, created_at, updated_at, labels, attributes FROM end_devices
 WHERE organization_id = $1
 ORDER BY name
 `
@@ -134,6 +232,286 @@ func (q *Queries) ListEndDevicesByOrganization(ctx context.Context, organization
 			&i.HardwareType,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Labels,
+			&i.Attributes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEndDevicesPageByCreatedAt = `-- name: ListEndDevicesPageByCreatedAt :many
+SELECT ed.id, ed.name, ed.description, ed.organization_id, ed.status, ed.data_type, ed.hardware_type, ed.created_at, ed.updated_at, ed.labels, ed.attributes
+FROM end_devices ed
+LEFT JOIN lorawan_configs lc ON ed.id = lc.end_device_id
+WHERE ed.organization_id = $1
+  AND ($2::INTEGER IS NULL OR ed.status = $2)
+  AND ($3::INTEGER IS NULL OR ed.hardware_type = $3)
+  AND ($4::TEXT IS NULL OR lc.hardware_type_id = $4)
+  AND ($5::TEXT IS NULL OR ed.name LIKE $5 || '%')
+  AND ($6::JSONB IS NULL OR ed.labels @> $6)
+  AND ($7::JSONB IS NULL OR NOT EXISTS (
+    SELECT 1 FROM jsonb_each($7) AS s(key, vals)
+    WHERE NOT COALESCE(s.vals ? (ed.labels ->> s.key), false)))
+  AND ($8::JSONB IS NULL OR NOT EXISTS (
+    SELECT 1 FROM jsonb_each($8) AS s(key, vals)
+    WHERE COALESCE(s.vals ? (ed.labels ->> s.key), false)))
+  AND ($9::TEXT[] IS NULL OR ed.labels ?& $9)
+  AND ($10::TEXT[] IS NULL OR NOT ed.labels ?| $10)
+  AND ($11::TEXT[] IS NULL OR EXISTS (
+    SELECT 1 FROM device_group_members m
+    WHERE m.end_device_id = ed.id AND m.device_group_id = ANY($11)))
+  AND ($12::TIMESTAMPTZ IS NULL OR NOT EXISTS (
+    SELECT 1 FROM end_device_presence p
+    WHERE p.end_device_id = ed.id AND p.last_seen_at >= $12))
+  AND ($13::TEXT IS NULL OR (ed.created_at, ed.id) > ($14::TIMESTAMPTZ, $13))
+ORDER BY ed.created_at, ed.id
+LIMIT $15
+`
+
+type ListEndDevicesPageByCreatedAtParams struct {
+	OrganizationID  string
+	Status          pgtype.Int4
+	HardwareType    pgtype.Int4
+	HardwareTypeID  pgtype.Text
+	NamePrefix      pgtype.Text
+	LabelEquals     []byte
+	LabelIn         []byte
+	LabelNotIn      []byte
+	LabelExists     []string
+	LabelNotExists  []string
+	DeviceGroupIds  []string
+	NotSeenSince    pgtype.Timestamptz
+	CursorID        pgtype.Text
+	CursorCreatedAt pgtype.Timestamptz
+	PageLimit       int32
+}
+
+func (q *Queries) ListEndDevicesPageByCreatedAt(ctx context.Context, arg ListEndDevicesPageByCreatedAtParams) ([]EndDevice, error) {
+	rows, err := q.db.Query(ctx, listEndDevicesPageByCreatedAt,
+		arg.OrganizationID,
+		arg.Status,
+		arg.HardwareType,
+		arg.HardwareTypeID,
+		arg.NamePrefix,
+		arg.LabelEquals,
+		arg.LabelIn,
+		arg.LabelNotIn,
+		arg.LabelExists,
+		arg.LabelNotExists,
+		arg.DeviceGroupIds,
+		arg.NotSeenSince,
+		arg.CursorID,
+		arg.CursorCreatedAt,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EndDevice
+	for rows.Next() {
+		var i EndDevice
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.OrganizationID,
+			&i.Status,
+			&i.DataType,
+			&i.HardwareType,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Labels,
+			&i.Attributes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEndDevicesPageByName = `-- name: ListEndDevicesPageByName :many
+SELECT ed.id, ed.name, ed.description, ed.organization_id, ed.status, ed.data_type, ed.hardware_type, ed.created_at, ed.updated_at, ed.labels, ed.attributes
+FROM end_devices ed
+LEFT JOIN lorawan_configs lc ON ed.id = lc.end_device_id
+WHERE ed.organization_id = $1
+  AND ($2::INTEGER IS NULL OR ed.status = $2)
+  AND ($3::INTEGER IS NULL OR ed.hardware_type = $3)
+  AND ($4::TEXT IS NULL OR lc.hardware_type_id = $4)
+  AND ($5::TEXT IS NULL OR ed.name LIKE $5 || '%')
+  AND ($6::JSONB IS NULL OR ed.labels @> $6)
+  AND ($7::JSONB IS NULL OR NOT EXISTS (
+    SELECT 1 FROM jsonb_each($7) AS s(key, vals)
+    WHERE NOT COALESCE(s.vals ? (ed.labels ->> s.key), false)))
+  AND ($8::JSONB IS NULL OR NOT EXISTS (
+    SELECT 1 FROM jsonb_each($8) AS s(key, vals)
+    WHERE COALESCE(s.vals ? (ed.labels ->> s.key), false)))
+  AND ($9::TEXT[] IS NULL OR ed.labels ?& $9)
+  AND ($10::TEXT[] IS NULL OR NOT ed.labels ?| $10)
+  AND ($11::TEXT[] IS NULL OR EXISTS (
+    SELECT 1 FROM device_group_members m
+    WHERE m.end_device_id = ed.id AND m.device_group_id = ANY($11)))
+  AND ($12::TIMESTAMPTZ IS NULL OR NOT EXISTS (
+    SELECT 1 FROM end_device_presence p
+    WHERE p.end_device_id = ed.id AND p.last_seen_at >= $12))
+  AND ($13::TEXT IS NULL OR (ed.name, ed.id) > ($14::TEXT, $13))
+ORDER BY ed.name, ed.id
+LIMIT $15
+`
+
+type ListEndDevicesPageByNameParams struct {
+	OrganizationID string
+	Status         pgtype.Int4
+	HardwareType   pgtype.Int4
+	HardwareTypeID pgtype.Text
+	NamePrefix     pgtype.Text
+	LabelEquals    []byte
+	LabelIn        []byte
+	LabelNotIn     []byte
+	LabelExists    []string
+	LabelNotExists []string
+	DeviceGroupIds []string
+	NotSeenSince   pgtype.Timestamptz
+	CursorID       pgtype.Text
+	CursorName     pgtype.Text
+	PageLimit      int32
+}
+
+func (q *Queries) ListEndDevicesPageByName(ctx context.Context, arg ListEndDevicesPageByNameParams) ([]EndDevice, error) {
+	rows, err := q.db.Query(ctx, listEndDevicesPageByName,
+		arg.OrganizationID,
+		arg.Status,
+		arg.HardwareType,
+		arg.HardwareTypeID,
+		arg.NamePrefix,
+		arg.LabelEquals,
+		arg.LabelIn,
+		arg.LabelNotIn,
+		arg.LabelExists,
+		arg.LabelNotExists,
+		arg.DeviceGroupIds,
+		arg.NotSeenSince,
+		arg.CursorID,
+		arg.CursorName,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EndDevice
+	for rows.Next() {
+		var i EndDevice
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.OrganizationID,
+			&i.Status,
+			&i.DataType,
+			&i.HardwareType,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Labels,
+			&i.Attributes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchEndDevices = `-- name: SearchEndDevices :many
+SELECT id, name, description, organization_id, status, data_type, hardware_type, created_at, updated_at, labels, attributes, rank
+FROM (
+  SELECT ed.id, ed.name, ed.description, ed.organization_id, ed.status, ed.data_type, ed.hardware_type, ed.created_at, ed.updated_at, ed.labels, ed.attributes,
+    (ts_rank_cd(end_device_search_document(ed.name, ed.description, ed.labels), websearch_to_tsquery('english', $1::TEXT), 32)
+      + CASE WHEN lc.device_eui = $2::TEXT THEN 2 ELSE 0 END
+      + CASE WHEN lht.model ILIKE '%' || $3::TEXT || '%' THEN 1 ELSE 0 END)::FLOAT8 AS rank
+  FROM end_devices ed
+  LEFT JOIN lorawan_configs lc ON ed.id = lc.end_device_id
+  LEFT JOIN lorawan_hardware_types lht ON lc.hardware_type_id = lht.id
+  WHERE ed.organization_id = ANY($4::TEXT[])
+    AND (end_device_search_document(ed.name, ed.description, ed.labels) @@ websearch_to_tsquery('english', $1::TEXT)
+      OR lc.device_eui = $2::TEXT
+      OR lht.model ILIKE '%' || $3::TEXT || '%')
+) AS matches
+WHERE $5::TEXT IS NULL
+  OR rank < $6::FLOAT8
+  OR (rank = $6::FLOAT8 AND id > $5::TEXT)
+ORDER BY rank DESC, id
+LIMIT $7
+`
+
+type SearchEndDevicesParams struct {
+	Query           string
+	DeviceEui       pgtype.Text
+	ModelPattern    string
+	OrganizationIds []string
+	CursorID        pgtype.Text
+	CursorRank      pgtype.Float8
+	PageLimit       int32
+}
+
+type SearchEndDevicesRow struct {
+	ID             string
+	Name           string
+	Description    pgtype.Text
+	OrganizationID string
+	Status         int32
+	DataType       int32
+	HardwareType   int32
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+	Labels         []byte
+	Attributes     []byte
+	Rank           float64
+}
+
+func (q *Queries) SearchEndDevices(ctx context.Context, arg SearchEndDevicesParams) ([]SearchEndDevicesRow, error) {
+	rows, err := q.db.Query(ctx, searchEndDevices,
+		arg.Query,
+		arg.DeviceEui,
+		arg.ModelPattern,
+		arg.OrganizationIds,
+		arg.CursorID,
+		arg.CursorRank,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchEndDevicesRow
+	for rows.Next() {
+		var i SearchEndDevicesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.OrganizationID,
+			&i.Status,
+			&i.DataType,
+			&i.HardwareType,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Labels,
+			&i.Attributes,
+			&i.Rank,
 		); err != nil {
 			return nil, err
 		}
@@ -149,7 +527,7 @@ const updateEndDevice = `-- name: UpdateEndDevice :one
 UPDATE end_devices
 SET name = $2, description = $3, status = $4, data_type = $5, hardware_type = $6, updated_at = NOW()
 WHERE id = $1
-RETURNING id, name, description, organization_id, status, data_type, hardware_type, created_at, updated_at
+RETURNING id, name, description, organization_id, status, data_type, hardware_type, created_at, updated_at, labels, attributes
 `
 
 type UpdateEndDeviceParams struct {
@@ -181,6 +559,44 @@ func (q *Queries) UpdateEndDevice(ctx context.Context, arg UpdateEndDeviceParams
 		&i.HardwareType,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Labels,
+		&i.Attributes,
+	)
+	return i, err
+}
+
+const updateEndDeviceMetadata = `-- name: UpdateEndDeviceMetadata :one
+UPDATE end_devices
+SET labels = $2, attributes = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING id, name, description, organization_id, status, data_type, hardware_type, created_at, updated_at, labels, attributes
+`
+
+type UpdateEndDeviceMetadataParams struct {
+	ID         string
+	Labels     []byte
+	Attributes []byte
+}
+
+func (q *Queries) UpdateEndDeviceMetadata(ctx context.Context, arg UpdateEndDeviceMetadataParams) (EndDevice, error) {
+	row := q.db.QueryRow(ctx, updateEndDeviceMetadata,
+		arg.ID,
+		arg.Labels,
+		arg.Attributes,
+	)
+	var i EndDevice
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.OrganizationID,
+		&i.Status,
+		&i.DataType,
+		&i.HardwareType,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Labels,
+		&i.Attributes,
 	)
 	return i, err
 }
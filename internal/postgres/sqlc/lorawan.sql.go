@@ -168,7 +168,8 @@ SELECT
     lht.firmware_version,
     lht.hardware_version,
     lht.lorawan_version,
-    lht.profile
+    lht.profile,
+    lht.description as hardware_description
 FROM end_devices ed
 JOIN lorawan_configs lc ON ed.id = lc.end_device_id
 JOIN lorawan_hardware_types lht ON lc.hardware_type_id = lht.id
@@ -176,31 +177,32 @@ WHERE ed.id = $1 AND lht.deleted_at IS NULL
 `
 
 type GetCompleteLoRaWANDeviceRow struct {
-	EndDeviceID      string
-	Name             string
-	Description      pgtype.Text
-	OrganizationID   string
-	Status           int32
-	DataType         int32
-	HardwareType     int32
-	DeviceCreatedAt  pgtype.Timestamptz
-	DeviceUpdatedAt  pgtype.Timestamptz
-	LorawanConfigID  string
-	DeviceEui        string
-	ApplicationEui   string
-	ApplicationID    string
-	ApplicationKey   string
-	NetworkKey       pgtype.Text
-	ActivationMethod int32
-	FrequencyPlanID  string
-	HardwareTypeID   string
-	HardwareName     string
-	Manufacturer     string
-	Model            string
-	FirmwareVersion  pgtype.Text
-	HardwareVersion  pgtype.Text
-	LorawanVersion   int32
-	Profile          pgtype.Text
+	EndDeviceID         string
+	Name                string
+	Description         pgtype.Text
+	OrganizationID      string
+	Status              int32
+	DataType            int32
+	HardwareType        int32
+	DeviceCreatedAt     pgtype.Timestamptz
+	DeviceUpdatedAt     pgtype.Timestamptz
+	LorawanConfigID     string
+	DeviceEui           string
+	ApplicationEui      string
+	ApplicationID       string
+	ApplicationKey      string
+	NetworkKey          pgtype.Text
+	ActivationMethod    int32
+	FrequencyPlanID     string
+	HardwareTypeID      string
+	HardwareName        string
+	Manufacturer        string
+	Model               string
+	FirmwareVersion     pgtype.Text
+	HardwareVersion     pgtype.Text
+	LorawanVersion      int32
+	Profile             pgtype.Text
+	HardwareDescription pgtype.Text
 }
 
 // ===== Combined Queries (Joining End Device with LoRaWAN Config) =====
@@ -233,6 +235,7 @@ func (q *Queries) GetCompleteLoRaWANDevice(ctx context.Context, id string) (GetC
 		&i.HardwareVersion,
 		&i.LorawanVersion,
 		&i.Profile,
+		&i.HardwareDescription,
 	)
 	return i, err
 }
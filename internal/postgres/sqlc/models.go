@@ -19,6 +19,21 @@ type CasbinRule struct {
 	V5    pgtype.Text
 }
 
+type DeviceGroup struct {
+	ID             string
+	OrganizationID string
+	Name           string
+	Description    pgtype.Text
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+}
+
+type DeviceGroupMember struct {
+	DeviceGroupID string
+	EndDeviceID   string
+	CreatedAt     pgtype.Timestamptz
+}
+
 type EndDevice struct {
 	ID             string
 	Name           string
@@ -29,6 +44,139 @@ type EndDevice struct {
 	HardwareType   int32
 	CreatedAt      pgtype.Timestamptz
 	UpdatedAt      pgtype.Timestamptz
+	Labels         []byte
+	Attributes     []byte
+}
+
+type EndDeviceClaim struct {
+	EndDeviceID             string
+	OrganizationID          string
+	CodeHash                string
+	ExpiresAt               pgtype.Timestamptz
+	CreatedAt               pgtype.Timestamptz
+	ClaimedAt               pgtype.Timestamptz
+	ClaimedByOrganizationID pgtype.Text
+}
+
+type EndDeviceClaimAttempt struct {
+	ID          string
+	UserID      string
+	AttemptedAt pgtype.Timestamptz
+}
+
+type EndDeviceDecommission struct {
+	ID             string
+	EndDeviceID    string
+	OrganizationID string
+	RequestedBy    string
+	DataPolicy     string
+	Step           string
+	PurgeAfter     pgtype.Timestamptz
+	Attempts       int32
+	LastError      pgtype.Text
+	NextAttemptAt  pgtype.Timestamptz
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+	CompletedAt    pgtype.Timestamptz
+}
+
+type EndDeviceDecommissionEvent struct {
+	ID             string
+	DecommissionID string
+	Step           string
+	Error          pgtype.Text
+	OccurredAt     pgtype.Timestamptz
+}
+
+type EndDeviceMessageCount struct {
+	EndDeviceID  string
+	HourStart    pgtype.Timestamptz
+	MessageCount int64
+}
+
+type EndDevicePresence struct {
+	EndDeviceID     string
+	LastSeenAt      pgtype.Timestamptz
+	LastOccurredAt  pgtype.Timestamptz
+	LastProcessedAt pgtype.Timestamptz
+	LastValues      []byte
+}
+
+type EndDeviceProfile struct {
+	ID              string
+	OrganizationID  string
+	Name            string
+	Description     pgtype.Text
+	HardwareType    int32
+	HardwareTypeID  pgtype.Text
+	FrequencyPlanID pgtype.Text
+	PayloadDecoder  pgtype.Text
+	ExpectedFields  []string
+	Labels          []byte
+	CreatedAt       pgtype.Timestamptz
+	UpdatedAt       pgtype.Timestamptz
+}
+
+type EndDeviceProfileDevice struct {
+	EndDeviceID        string
+	EndDeviceProfileID string
+	CreatedAt          pgtype.Timestamptz
+}
+
+type EndDeviceStatusTransition struct {
+	ID             string
+	EndDeviceID    string
+	FromStatus     int32
+	ToStatus       int32
+	Reason         string
+	TransitionedAt pgtype.Timestamptz
+}
+
+type EndDeviceTransfer struct {
+	ID                   string
+	EndDeviceID          string
+	FromOrganizationID   string
+	ToOrganizationID     string
+	TransferredBy        string
+	History              string
+	TransferredAt        pgtype.Timestamptz
+	HistoryTransferredAt pgtype.Timestamptz
+}
+
+type EndDeviceTwin struct {
+	EndDeviceID       string
+	Desired           []byte
+	DesiredVersion    int64
+	DesiredUpdatedAt  pgtype.Timestamptz
+	Reported          []byte
+	ReportedVersion   int64
+	ReportedUpdatedAt pgtype.Timestamptz
+}
+
+type EuiBlock struct {
+	Prefix         string
+	OrganizationID string
+	Size           int64
+	NextOffset     int64
+	CreatedAt      pgtype.Timestamptz
+}
+
+type Gateway struct {
+	ID              string
+	OrganizationID  string
+	Name            string
+	Description     pgtype.Text
+	GatewayEui      string
+	FrequencyPlanID string
+	Latitude        pgtype.Float8
+	Longitude       pgtype.Float8
+	Altitude        pgtype.Int4
+	LocationSource  pgtype.Text
+	Status          string
+	LastSeenAt      pgtype.Timestamptz
+	StatusCheckedAt pgtype.Timestamptz
+	CreatedAt       pgtype.Timestamptz
+	UpdatedAt       pgtype.Timestamptz
 }
 
 type LorawanConfig struct {
@@ -46,6 +194,20 @@ type LorawanConfig struct {
 	UpdatedAt        pgtype.Timestamptz
 }
 
+type LorawanDownlink struct {
+	ID          string
+	EndDeviceID string
+	FPort       int32
+	FrmPayload  []byte
+	Data        []byte
+	Confirmed   bool
+	Status      string
+	Error       string
+	RequestedBy string
+	CreatedAt   pgtype.Timestamptz
+	UpdatedAt   pgtype.Timestamptz
+}
+
 type LorawanFrequencyPlan struct {
 	ID          string
 	Name        string
@@ -69,6 +231,44 @@ type LorawanHardwareType struct {
 	DeletedAt       pgtype.Timestamptz
 }
 
+type LorawanHardwareTypeDecoder struct {
+	HardwareTypeID string
+	Script         string
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+	Codec          string
+	ByteLayout     []byte
+}
+
+type LorawanRootKeyRotation struct {
+	ID           string
+	EndDeviceID  string
+	Reason       string
+	KeysSupplied bool
+	RotatedAt    pgtype.Timestamptz
+}
+
+type ModbusConfig struct {
+	ID                  string
+	EndDeviceID         string
+	Host                string
+	UnitID              int16
+	PollIntervalSeconds int32
+	Registers           []byte
+	CreatedAt           pgtype.Timestamptz
+	UpdatedAt           pgtype.Timestamptz
+}
+
+type MqttConfig struct {
+	ID           string
+	EndDeviceID  string
+	Topic        string
+	Username     string
+	PasswordHash string
+	CreatedAt    pgtype.Timestamptz
+	UpdatedAt    pgtype.Timestamptz
+}
+
 type Organization struct {
 	ID        string
 	Name      string
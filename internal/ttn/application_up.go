@@ -0,0 +1,173 @@
+package ttn
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+// ApplicationUpHandler handles the end device messages The Things Stack forwards to applications.
+type ApplicationUpHandler interface {
+	IngestLoRaWANUplink(ctx context.Context, uplink domain.LoRaWANUplink) error
+	RecordLoRaWANJoinAccept(ctx context.Context, join domain.LoRaWANJoinAccept) error
+	RecordLoRaWANDownlinkAck(ctx context.Context, ack domain.LoRaWANDownlinkAck) error
+	RecordLoRaWANDownlinkEvent(ctx context.Context, event domain.LoRaWANDownlinkEvent) error
+	RecordLoRaWANLocation(ctx context.Context, location domain.LoRaWANLocation) error
+}
+
+// applicationUp is the JSON form of a The Things Stack ApplicationUp message, as sent by webhooks and published on
+// the MQTT server. Exactly one of the message fields is set; only the ones ponix handles are declared.
+type applicationUp struct {
+	EndDeviceIds struct {
+		DeviceId string `json:"device_id"`
+		DevEui   string `json:"dev_eui"`
+	} `json:"end_device_ids"`
+	ReceivedAt     time.Time            `json:"received_at"`
+	UplinkMessage  *uplinkMessage       `json:"uplink_message"`
+	JoinAccept     *joinAccept          `json:"join_accept"`
+	DownlinkAck    *applicationDownlink `json:"downlink_ack"`
+	DownlinkQueued *applicationDownlink `json:"downlink_queued"`
+	DownlinkSent   *applicationDownlink `json:"downlink_sent"`
+	DownlinkNack   *applicationDownlink `json:"downlink_nack"`
+	DownlinkFailed *downlinkFailed      `json:"downlink_failed"`
+	LocationSolved *locationSolved      `json:"location_solved"`
+}
+
+type uplinkMessage struct {
+	FPort          uint32         `json:"f_port"`
+	FCnt           uint32         `json:"f_cnt"`
+	FrmPayload     []byte         `json:"frm_payload"`
+	DecodedPayload map[string]any `json:"decoded_payload"`
+}
+
+type joinAccept struct {
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+type applicationDownlink struct {
+	FPort          uint32   `json:"f_port"`
+	FCnt           uint32   `json:"f_cnt"`
+	CorrelationIds []string `json:"correlation_ids"`
+}
+
+type downlinkFailed struct {
+	Downlink applicationDownlink `json:"downlink"`
+	Error    struct {
+		Namespace     string `json:"namespace"`
+		Name          string `json:"name"`
+		MessageFormat string `json:"message_format"`
+	} `json:"error"`
+}
+
+// reason describes why the downlink failed, preferring the error's message over its name.
+func (failed *downlinkFailed) reason() string {
+	if failed.Error.MessageFormat != "" {
+		return failed.Error.MessageFormat
+	}
+	if failed.Error.Name != "" {
+		return failed.Error.Namespace + ":" + failed.Error.Name
+	}
+	return "downlink failed"
+}
+
+type locationSolved struct {
+	Service  string `json:"service"`
+	Location struct {
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+		Altitude  float64 `json:"altitude"`
+		Accuracy  float64 `json:"accuracy"`
+		Source    string  `json:"source"`
+	} `json:"location"`
+}
+
+// HandleApplicationUp decodes a JSON ApplicationUp message and hands the uplink, join-accept, downlink event or
+// location-solved message it carries to handler. Downlinks the end device did not acknowledge are queued again by
+// The Things Stack, so a nack is handed on as the downlink being queued. It reports whether the message was of one
+// of those types; other messages, such as service data, are ignored. Messages that cannot be decoded are reported
+// as domain.ErrInvalidLoRaWANMessage.
+func HandleApplicationUp(ctx context.Context, handler ApplicationUpHandler, payload []byte) (bool, error) {
+	var up applicationUp
+	err := json.Unmarshal(payload, &up)
+	if err != nil {
+		return false, stacktrace.NewStackTraceErrorf("%w: %w", domain.ErrInvalidLoRaWANMessage, err)
+	}
+
+	receivedAt := up.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now().UTC()
+	}
+	deviceEui := up.EndDeviceIds.DevEui
+
+	switch {
+	case up.UplinkMessage != nil:
+		return true, handler.IngestLoRaWANUplink(ctx, domain.LoRaWANUplink{
+			DeviceEui:      deviceEui,
+			ReceivedAt:     receivedAt,
+			FPort:          up.UplinkMessage.FPort,
+			FCnt:           up.UplinkMessage.FCnt,
+			FrmPayload:     up.UplinkMessage.FrmPayload,
+			DecodedPayload: up.UplinkMessage.DecodedPayload,
+		})
+	case up.JoinAccept != nil:
+		return true, handler.RecordLoRaWANJoinAccept(ctx, domain.LoRaWANJoinAccept{
+			DeviceEui:  deviceEui,
+			ReceivedAt: receivedAt,
+		})
+	case up.DownlinkAck != nil:
+		return true, handler.RecordLoRaWANDownlinkAck(ctx, domain.LoRaWANDownlinkAck{
+			DeviceEui:      deviceEui,
+			ReceivedAt:     receivedAt,
+			FPort:          up.DownlinkAck.FPort,
+			FCnt:           up.DownlinkAck.FCnt,
+			CorrelationIds: up.DownlinkAck.CorrelationIds,
+		})
+	case up.DownlinkQueued != nil:
+		return true, handler.RecordLoRaWANDownlinkEvent(ctx, domain.LoRaWANDownlinkEvent{
+			DeviceEui:      deviceEui,
+			ReceivedAt:     receivedAt,
+			Status:         domain.LoRaWANDownlinkQueued,
+			CorrelationIds: up.DownlinkQueued.CorrelationIds,
+		})
+	case up.DownlinkSent != nil:
+		return true, handler.RecordLoRaWANDownlinkEvent(ctx, domain.LoRaWANDownlinkEvent{
+			DeviceEui:      deviceEui,
+			ReceivedAt:     receivedAt,
+			Status:         domain.LoRaWANDownlinkSent,
+			CorrelationIds: up.DownlinkSent.CorrelationIds,
+		})
+	case up.DownlinkNack != nil:
+		return true, handler.RecordLoRaWANDownlinkEvent(ctx, domain.LoRaWANDownlinkEvent{
+			DeviceEui:      deviceEui,
+			ReceivedAt:     receivedAt,
+			Status:         domain.LoRaWANDownlinkQueued,
+			Error:          "not acknowledged by the end device",
+			CorrelationIds: up.DownlinkNack.CorrelationIds,
+		})
+	case up.DownlinkFailed != nil:
+		return true, handler.RecordLoRaWANDownlinkEvent(ctx, domain.LoRaWANDownlinkEvent{
+			DeviceEui:      deviceEui,
+			ReceivedAt:     receivedAt,
+			Status:         domain.LoRaWANDownlinkFailed,
+			Error:          up.DownlinkFailed.reason(),
+			CorrelationIds: up.DownlinkFailed.Downlink.CorrelationIds,
+		})
+	case up.LocationSolved != nil:
+		location := up.LocationSolved.Location
+		return true, handler.RecordLoRaWANLocation(ctx, domain.LoRaWANLocation{
+			DeviceEui:  deviceEui,
+			ReceivedAt: receivedAt,
+			Latitude:   location.Latitude,
+			Longitude:  location.Longitude,
+			Altitude:   location.Altitude,
+			Accuracy:   location.Accuracy,
+			Source:     location.Source,
+			Service:    up.LocationSolved.Service,
+		})
+	}
+
+	return false, nil
+}
@@ -10,12 +10,17 @@ import (
 	iotv1 "buf.build/gen/go/ponix/ponix/protocolbuffers/go/iot/v1"
 	"buf.build/gen/go/thethingsindustries/lorawan-stack/grpc/go/ttn/lorawan/v3/lorawanv3grpc"
 	lorawanv3 "buf.build/gen/go/thethingsindustries/lorawan-stack/protocolbuffers/go/ttn/lorawan/v3"
+	"github.com/ponix-dev/ponix/internal/domain"
 	"github.com/ponix-dev/ponix/internal/telemetry"
 	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // TTNRegion represents a The Things Network cloud region identifier.
@@ -42,22 +47,33 @@ func (region TTNRegion) String() string {
 	return string(region)
 }
 
-// TTNClient provides integration with The Things Network for LoRaWAN device and application management.
+// TTNClient provides integration with The Things Network for LoRaWAN device, gateway and application management.
 // It manages gRPC connections to TTN's Identity, Application, Gateway, Network, and Join Servers.
 type TTNClient struct {
-	ServerName               string
-	Region                   TTNRegion
-	ApiKey                   string
-	ApiCollaborator          string
-	IdentityServerAddress    string
-	GatewayServerAddress     string
-	NetworkServerAddress     string
-	ApplicationServerAddress string
-	JoinServerAddress        string
-	grpcConns                map[string]*grpc.ClientConn
-	appRegistryClient        lorawanv3grpc.ApplicationRegistryClient
-	gatewayRegistryClient    lorawanv3grpc.GatewayRegistryClient
-	endDeviceRegistryClient  lorawanv3grpc.EndDeviceRegistryClient
+	ServerName                string
+	Region                    TTNRegion
+	ApiKey                    string
+	ApiCollaborator           string
+	IdentityServerAddress     string
+	GatewayServerAddress      string
+	NetworkServerAddress      string
+	ApplicationServerAddress  string
+	JoinServerAddress         string
+	grpcConns                 map[string]*grpc.ClientConn
+	appRegistryClient         lorawanv3grpc.ApplicationRegistryClient
+	gatewayRegistryClient     lorawanv3grpc.GatewayRegistryClient
+	endDeviceRegistryClient   lorawanv3grpc.EndDeviceRegistryClient
+	jsEndDeviceRegistryClient lorawanv3grpc.JsEndDeviceRegistryClient
+	nsEndDeviceRegistryClient lorawanv3grpc.NsEndDeviceRegistryClient
+	asEndDeviceRegistryClient lorawanv3grpc.AsEndDeviceRegistryClient
+	appAsClient               lorawanv3grpc.AppAsClient
+	gsClient                  lorawanv3grpc.GsClient
+	rootKeyOpener             RootKeyOpener
+}
+
+// RootKeyOpener decrypts the sealed LoRaWAN root keys carried by end devices.
+type RootKeyOpener interface {
+	OpenRootKey(ctx context.Context, value string) (string, error)
 }
 
 // TTNClientOption is a functional option for configuring a TTNClient.
@@ -85,6 +101,13 @@ func WithCollaboratorApiKey(key string, collab string) TTNClientOption {
 	}
 }
 
+// WithRootKeyOpener configures how the sealed root keys of end devices are decrypted for registration.
+func WithRootKeyOpener(opener RootKeyOpener) TTNClientOption {
+	return func(t *TTNClient) {
+		t.rootKeyOpener = opener
+	}
+}
+
 // TODO: implement address options
 
 // NewTTNClient creates a new TTN client with the specified options.
@@ -107,6 +130,10 @@ func NewTTNClient(opts ...TTNClientOption) (*TTNClient, error) {
 		return nil, errors.New("ttn client requires an api key")
 	}
 
+	if ttnClient.rootKeyOpener == nil {
+		return nil, errors.New("ttn client requires a root key opener")
+	}
+
 	var err error
 	switch ttnClient.Region {
 	case TTNRegionLocal:
@@ -125,6 +152,11 @@ func NewTTNClient(opts ...TTNClientOption) (*TTNClient, error) {
 	ttnClient.appRegistryClient = lorawanv3grpc.NewApplicationRegistryClient(ttnClient.grpcConns[ttnClient.IdentityServerAddress])
 	ttnClient.gatewayRegistryClient = lorawanv3grpc.NewGatewayRegistryClient(ttnClient.grpcConns[ttnClient.IdentityServerAddress])
 	ttnClient.endDeviceRegistryClient = lorawanv3grpc.NewEndDeviceRegistryClient(ttnClient.grpcConns[ttnClient.IdentityServerAddress])
+	ttnClient.jsEndDeviceRegistryClient = lorawanv3grpc.NewJsEndDeviceRegistryClient(ttnClient.grpcConns[ttnClient.JoinServerAddress])
+	ttnClient.nsEndDeviceRegistryClient = lorawanv3grpc.NewNsEndDeviceRegistryClient(ttnClient.grpcConns[ttnClient.NetworkServerAddress])
+	ttnClient.asEndDeviceRegistryClient = lorawanv3grpc.NewAsEndDeviceRegistryClient(ttnClient.grpcConns[ttnClient.ApplicationServerAddress])
+	ttnClient.appAsClient = lorawanv3grpc.NewAppAsClient(ttnClient.grpcConns[ttnClient.ApplicationServerAddress])
+	ttnClient.gsClient = lorawanv3grpc.NewGsClient(ttnClient.grpcConns[ttnClient.GatewayServerAddress])
 	return ttnClient, nil
 }
 
@@ -195,58 +227,124 @@ func grpcConn(address string) (conn *grpc.ClientConn, err error) {
 	return grpc.NewClient(address, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{})))
 }
 
-// func (ttnClient *TTNClient) CreateGateway(ctx context.Context, gateway *iotv1.Gateway) error {
-// 	ctx, span := telemetry.Tracer().Start(ctx, "CreateGateway")
-// 	defer span.End()
+// RegisterGateway registers a gateway with The Things Network under its ID, owned by the API collaborator.
+// It connects to the Gateway Server of the client's region with the gateway's EUI and frequency plan.
+func (ttnClient *TTNClient) RegisterGateway(ctx context.Context, gateway *domain.Gateway) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "RegisterGateway")
+	defer span.End()
 
-// 	ctx = setAuthorizationContext(ctx, ttnClient.ApiKey)
+	ctx = setAuthorizationContext(ctx, ttnClient.ApiKey)
 
-// 	req := lorawanv3.CreateGatewayRequest_builder{
-// 		Collaborator: apiCollaborator(ttnClient.ApiCollaborator),
-// 		Gateway:      lorawanv3.Gateway_builder{}.Build(),
-// 	}.Build()
+	req := lorawanv3.CreateGatewayRequest_builder{
+		Collaborator: apiCollaborator(ttnClient.ApiCollaborator),
+		Gateway: lorawanv3.Gateway_builder{
+			Ids:                  gatewayIdentifiers(gateway),
+			Name:                 gateway.Name,
+			Description:          gateway.Description,
+			GatewayServerAddress: ttnClient.GatewayServerAddress,
+			FrequencyPlanId:      gateway.FrequencyPlan,
+			FrequencyPlanIds:     []string{gateway.FrequencyPlan},
+			Antennas:             gatewayAntennas(gateway),
+			EnforceDutyCycle:     true,
+		}.Build(),
+	}.Build()
 
-// 	_, err := ttnClient.gatewayRegistryClient.Create(ctx, req)
-// 	if err != nil {
-// 		return err
-// 	}
+	_, err := ttnClient.gatewayRegistryClient.Create(ctx, req)
+	if err != nil {
+		return stacktrace.NewStackTraceErrorf("failed to register gateway with TTN: %w", err)
+	}
 
-// 	return nil
-// }
+	return nil
+}
 
-// func (ttnClient *TTNClient) ListGateways(ctx context.Context) ([]*iotv1.Gateway, error) {
-// 	ctx, span := telemetry.Tracer().Start(ctx, "ListGateways")
-// 	defer span.End()
+// UpdateGateway updates the name, description, frequency plan and antenna location of a gateway in The Things Network.
+func (ttnClient *TTNClient) UpdateGateway(ctx context.Context, gateway *domain.Gateway) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "UpdateGateway")
+	defer span.End()
 
-// 	ctx = setAuthorizationContext(ctx, ttnClient.ApiKey)
+	ctx = setAuthorizationContext(ctx, ttnClient.ApiKey)
 
-// 	req := lorawanv3.ListGatewaysRequest_builder{
-// 		Collaborator: apiCollaborator(ttnClient.ApiCollaborator),
-// 		FieldMask:    gatewayFieldMask(),
-// 	}.Build()
+	req := lorawanv3.UpdateGatewayRequest_builder{
+		Gateway: lorawanv3.Gateway_builder{
+			Ids:              gatewayIdentifiers(gateway),
+			Name:             gateway.Name,
+			Description:      gateway.Description,
+			FrequencyPlanId:  gateway.FrequencyPlan,
+			FrequencyPlanIds: []string{gateway.FrequencyPlan},
+			Antennas:         gatewayAntennas(gateway),
+		}.Build(),
+		FieldMask: gatewayUpdateFieldMask(),
+	}.Build()
 
-// 	resp, err := ttnClient.gatewayRegistryClient.List(ctx, req)
-// 	if err != nil {
-// 		return nil, err
-// 	}
+	_, err := ttnClient.gatewayRegistryClient.Update(ctx, req)
+	if err != nil {
+		return stacktrace.NewStackTraceErrorf("failed to update gateway in TTN: %w", err)
+	}
 
-// 	respGws := resp.GetGateways()
+	return nil
+}
 
-// 	gws := make([]*iotv1.Gateway, len(respGws))
+// DeleteGateway removes a gateway from The Things Network. A gateway TTN no longer knows counts as removed, so an
+// interrupted deletion can be repeated.
+func (ttnClient *TTNClient) DeleteGateway(ctx context.Context, gateway *domain.Gateway) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "DeleteGateway")
+	defer span.End()
 
-// 	for i, rgw := range respGws {
-// 		gw := iotv1.Gateway_builder{
-// 			Name: rgw.GetName(),
-// 		}.Build()
+	ctx = setAuthorizationContext(ctx, ttnClient.ApiKey)
 
-// 		gws[i] = gw
-// 	}
+	_, err := ttnClient.gatewayRegistryClient.Delete(ctx, gatewayIdentifiers(gateway))
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		return stacktrace.NewStackTraceErrorf("failed to delete gateway from TTN: %w", err)
+	}
 
-// 	return gws, nil
-// }
+	return nil
+}
 
-// RegisterEndDevice registers a LoRaWAN end device with The Things Network.
-// It configures the device with OTAA activation, frequency plan, and hardware version information.
+// GetGatewayConnection reads the connection statistics of a gateway from the TTN Gateway Server. The Gateway Server
+// only knows gateways that are or recently were connected; others are reported as not connected.
+func (ttnClient *TTNClient) GetGatewayConnection(ctx context.Context, gateway *domain.Gateway) (*domain.GatewayConnection, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "GetGatewayConnection")
+	defer span.End()
+
+	ctx = setAuthorizationContext(ctx, ttnClient.ApiKey)
+
+	stats, err := ttnClient.gsClient.GetGatewayConnectionStats(ctx, gatewayIdentifiers(gateway))
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return &domain.GatewayConnection{}, nil
+		}
+		return nil, stacktrace.NewStackTraceErrorf("failed to get gateway connection stats from TTN: %w", err)
+	}
+
+	connection := &domain.GatewayConnection{
+		Connected: stats.HasConnectedAt() && !stats.HasDisconnectedAt(),
+	}
+
+	for _, seenAt := range []*timestamppb.Timestamp{stats.GetConnectedAt(), stats.GetLastStatusReceivedAt(), stats.GetLastUplinkReceivedAt()} {
+		if seenAt != nil && seenAt.AsTime().After(connection.LastSeenAt) {
+			connection.LastSeenAt = seenAt.AsTime()
+		}
+	}
+
+	if locations := stats.GetLastStatus().GetAntennaLocations(); len(locations) > 0 {
+		connection.Location = &domain.GatewayLocation{
+			Latitude:  locations[0].GetLatitude(),
+			Longitude: locations[0].GetLongitude(),
+			Altitude:  locations[0].GetAltitude(),
+			Source:    domain.GatewayLocationStatus,
+		}
+	}
+
+	return connection, nil
+}
+
+// RegisterEndDevice registers a LoRaWAN end device with The Things Network. The device is created in the Identity
+// Server registry and then set in the Network, Application and Join Server registries, the Join Server only for
+// OTAA devices. When a registry rejects the device, the registrations made so far are removed again.
+// The sealed root keys of the device are decrypted here, just before they are handed to the Join Server.
 func (ttnClient *TTNClient) RegisterEndDevice(ctx context.Context, endDevice *iotv1.EndDevice) error {
 	ctx, span := telemetry.Tracer().Start(ctx, "CreateEndDevice")
 	defer span.End()
@@ -259,68 +357,277 @@ func (ttnClient *TTNClient) RegisterEndDevice(ctx context.Context, endDevice *io
 		return stacktrace.NewStackTraceErrorf("LoRaWAN configuration is required for TTN registration")
 	}
 
-	// Build TTN end device identifiers
-	endDeviceIds := lorawanv3.EndDeviceIdentifiers_builder{
-		ApplicationIds: lorawanv3.ApplicationIdentifiers_builder{
-			ApplicationId: lorawanConfig.GetApplicationId(),
-		}.Build(),
-		DeviceId: endDevice.GetId(),
-		DevEui:   parseEUI(lorawanConfig.GetDeviceEui()),
-		JoinEui:  parseEUI(lorawanConfig.GetApplicationEui()),
-	}.Build()
+	otaa := lorawanConfig.GetActivationMethod() == iotv1.ActivationMethod_ACTIVATION_METHOD_OTAA
 
-	// Build root keys for OTAA
+	// Decrypt the root keys up front so a bad key fails before anything is registered
 	var rootKeys *lorawanv3.RootKeys
-	if lorawanConfig.GetActivationMethod() == iotv1.ActivationMethod_ACTIVATION_METHOD_OTAA {
-		rootKeysBuilder := lorawanv3.RootKeys_builder{
-			AppKey: lorawanv3.KeyEnvelope_builder{
-				Key: parseKey(lorawanConfig.GetApplicationKey()),
-			}.Build(),
-		}
-
-		// Add network key for LoRaWAN 1.1+
-		if lorawanConfig.GetNetworkKey() != "" {
-			rootKeysBuilder.NwkKey = lorawanv3.KeyEnvelope_builder{
-				Key: parseKey(lorawanConfig.GetNetworkKey()),
-			}.Build()
+	if otaa {
+		var err error
+		rootKeys, err = ttnClient.rootKeys(ctx, lorawanConfig)
+		if err != nil {
+			return err
 		}
-
-		rootKeys = rootKeysBuilder.Build()
 	}
 
-	// Build the complete TTN end device
-	ttnEndDevice := lorawanv3.EndDevice_builder{
-		Ids:                      endDeviceIds,
+	isEndDevice := lorawanv3.EndDevice_builder{
+		Ids:                      endDeviceIdentifiers(endDevice, lorawanConfig),
 		Name:                     endDevice.GetName(),
 		Description:              endDevice.GetDescription(),
 		NetworkServerAddress:     ttnClient.NetworkServerAddress,
 		ApplicationServerAddress: ttnClient.ApplicationServerAddress,
-		JoinServerAddress:        ttnClient.JoinServerAddress,
+		VersionIds:               buildVersionIds(lorawanConfig.GetHardwareData(), lorawanConfig.GetFrequencyPlan()),
+		Attributes:               buildDeviceAttributes(endDevice, lorawanConfig),
+	}.Build()
+	if otaa {
+		isEndDevice.SetJoinServerAddress(ttnClient.JoinServerAddress)
+	}
 
-		// LoRaWAN version configuration
-		LorawanVersion: convertLoRaWANVersion(lorawanConfig.GetHardwareData().GetLorawanVersion()),
+	_, err := ttnClient.endDeviceRegistryClient.Create(ctx, lorawanv3.CreateEndDeviceRequest_builder{
+		EndDevice: isEndDevice,
+	}.Build())
+	if err != nil {
+		return stacktrace.NewStackTraceErrorf("failed to register end device with TTN: %w", err)
+	}
+
+	err = ttnClient.setEndDevice(ctx, endDevice, lorawanConfig, rootKeys)
+	if err != nil {
+		deleteErr := ttnClient.DeleteEndDevice(ctx, endDevice)
+		if deleteErr != nil {
+			return errors.Join(err, deleteErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// setEndDevice sets a LoRaWAN end device registered with the Identity Server in the Network, Application and Join
+// Server registries. The Join Server is left out when rootKeys is nil.
+func (ttnClient *TTNClient) setEndDevice(ctx context.Context, endDevice *iotv1.EndDevice, lorawanConfig *iotv1.LoRaWANConfig, rootKeys *lorawanv3.RootKeys) error {
+	_, err := ttnClient.nsEndDeviceRegistryClient.Set(ctx, nsSetEndDeviceRequest(endDevice, lorawanConfig))
+	if err != nil {
+		return stacktrace.NewStackTraceErrorf("failed to register end device with the TTN Network Server: %w", err)
+	}
+
+	_, err = ttnClient.asEndDeviceRegistryClient.Set(ctx, asSetEndDeviceRequest(endDevice, lorawanConfig))
+	if err != nil {
+		return stacktrace.NewStackTraceErrorf("failed to register end device with the TTN Application Server: %w", err)
+	}
 
-		// Frequency plan
-		FrequencyPlanId: lorawanConfig.GetFrequencyPlan(),
+	if rootKeys == nil {
+		return nil
+	}
+
+	_, err = ttnClient.jsEndDeviceRegistryClient.Set(ctx, lorawanv3.SetEndDeviceRequest_builder{
+		EndDevice: lorawanv3.EndDevice_builder{
+			Ids:                      endDeviceIdentifiers(endDevice, lorawanConfig),
+			NetworkServerAddress:     ttnClient.NetworkServerAddress,
+			ApplicationServerAddress: ttnClient.ApplicationServerAddress,
+			RootKeys:                 rootKeys,
+		}.Build(),
+		FieldMask: &fieldmaskpb.FieldMask{
+			Paths: append(jsEndDeviceFieldMask().GetPaths(), rootKeysFieldMask().GetPaths()...),
+		},
+	}.Build())
+	if err != nil {
+		return stacktrace.NewStackTraceErrorf("failed to register end device with the TTN Join Server: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateEndDevice updates a LoRaWAN end device in The Things Network.
+// The metadata (name, description, attributes and version identifiers) is updated in the Identity Server registry,
+// the radio settings (frequency plan and LoRaWAN versions) and version identifiers in the Network Server, which
+// schedules traffic by them, and the version identifiers in the Application Server, which picks payload formatters
+// by them. OTAA devices also get the server addresses of the client in the Join Server.
+func (ttnClient *TTNClient) UpdateEndDevice(ctx context.Context, endDevice *iotv1.EndDevice) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "UpdateEndDevice")
+	defer span.End()
 
-		// Root keys for OTAA
-		RootKeys: rootKeys,
+	ctx = setAuthorizationContext(ctx, ttnClient.ApiKey)
 
-		// Hardware and version info from hardware data
-		VersionIds: buildVersionIds(lorawanConfig.GetHardwareData(), lorawanConfig.GetFrequencyPlan()),
+	lorawanConfig := endDevice.GetLorawanConfig()
+	if lorawanConfig == nil {
+		return stacktrace.NewStackTraceErrorf("LoRaWAN configuration is required for TTN update")
+	}
 
-		// Additional attributes
-		Attributes: buildDeviceAttributes(endDevice, lorawanConfig),
+	ttnEndDevice := lorawanv3.EndDevice_builder{
+		Ids:         endDeviceIdentifiers(endDevice, lorawanConfig),
+		Name:        endDevice.GetName(),
+		Description: endDevice.GetDescription(),
+		VersionIds:  buildVersionIds(lorawanConfig.GetHardwareData(), lorawanConfig.GetFrequencyPlan()),
+		Attributes:  buildDeviceAttributes(endDevice, lorawanConfig),
 	}.Build()
 
-	// Create the registration request
-	createRequest := lorawanv3.CreateEndDeviceRequest_builder{
+	updateRequest := lorawanv3.UpdateEndDeviceRequest_builder{
 		EndDevice: ttnEndDevice,
+		FieldMask: endDeviceUpdateFieldMask(),
 	}.Build()
 
-	_, err := ttnClient.endDeviceRegistryClient.Create(ctx, createRequest)
+	_, err := ttnClient.endDeviceRegistryClient.Update(ctx, updateRequest)
 	if err != nil {
-		return stacktrace.NewStackTraceErrorf("failed to register end device with TTN: %w", err)
+		return stacktrace.NewStackTraceErrorf("failed to update end device in TTN: %w", err)
+	}
+
+	_, err = ttnClient.nsEndDeviceRegistryClient.Set(ctx, nsSetEndDeviceRequest(endDevice, lorawanConfig))
+	if err != nil {
+		return stacktrace.NewStackTraceErrorf("failed to update end device radio settings in TTN: %w", err)
+	}
+
+	_, err = ttnClient.asEndDeviceRegistryClient.Set(ctx, asSetEndDeviceRequest(endDevice, lorawanConfig))
+	if err != nil {
+		return stacktrace.NewStackTraceErrorf("failed to update end device in the TTN Application Server: %w", err)
+	}
+
+	if lorawanConfig.GetActivationMethod() != iotv1.ActivationMethod_ACTIVATION_METHOD_OTAA {
+		return nil
+	}
+
+	_, err = ttnClient.jsEndDeviceRegistryClient.Set(ctx, lorawanv3.SetEndDeviceRequest_builder{
+		EndDevice: lorawanv3.EndDevice_builder{
+			Ids:                      endDeviceIdentifiers(endDevice, lorawanConfig),
+			NetworkServerAddress:     ttnClient.NetworkServerAddress,
+			ApplicationServerAddress: ttnClient.ApplicationServerAddress,
+		}.Build(),
+		FieldMask: jsEndDeviceFieldMask(),
+	}.Build())
+	if err != nil {
+		return stacktrace.NewStackTraceErrorf("failed to update end device in the TTN Join Server: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateEndDeviceRootKeys replaces the root keys of a LoRaWAN end device in the TTN Join Server.
+// The sealed root keys of the device are decrypted here, just before they are handed to the Join Server.
+// The device has to join again before its traffic is accepted with the new keys.
+func (ttnClient *TTNClient) UpdateEndDeviceRootKeys(ctx context.Context, endDevice *iotv1.EndDevice) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "UpdateEndDeviceRootKeys")
+	defer span.End()
+
+	ctx = setAuthorizationContext(ctx, ttnClient.ApiKey)
+
+	lorawanConfig := endDevice.GetLorawanConfig()
+	if lorawanConfig == nil {
+		return stacktrace.NewStackTraceErrorf("LoRaWAN configuration is required for TTN root key update")
+	}
+
+	rootKeys, err := ttnClient.rootKeys(ctx, lorawanConfig)
+	if err != nil {
+		return err
+	}
+
+	setRequest := lorawanv3.SetEndDeviceRequest_builder{
+		EndDevice: lorawanv3.EndDevice_builder{
+			Ids:      endDeviceIdentifiers(endDevice, lorawanConfig),
+			RootKeys: rootKeys,
+		}.Build(),
+		FieldMask: rootKeysFieldMask(),
+	}.Build()
+
+	_, err = ttnClient.jsEndDeviceRegistryClient.Set(ctx, setRequest)
+	if err != nil {
+		return stacktrace.NewStackTraceErrorf("failed to update end device root keys in TTN: %w", err)
+	}
+
+	return nil
+}
+
+// rootKeys decrypts the sealed root keys of a LoRaWAN end device for the Join Server. The network key is only
+// set for LoRaWAN 1.1 devices, which have one.
+func (ttnClient *TTNClient) rootKeys(ctx context.Context, lorawanConfig *iotv1.LoRaWANConfig) (*lorawanv3.RootKeys, error) {
+	applicationKey, err := ttnClient.rootKeyOpener.OpenRootKey(ctx, lorawanConfig.GetApplicationKey())
+	if err != nil {
+		return nil, stacktrace.NewStackTraceErrorf("failed to decrypt application key: %w", err)
+	}
+
+	networkKey, err := ttnClient.rootKeyOpener.OpenRootKey(ctx, lorawanConfig.GetNetworkKey())
+	if err != nil {
+		return nil, stacktrace.NewStackTraceErrorf("failed to decrypt network key: %w", err)
+	}
+
+	rootKeysBuilder := lorawanv3.RootKeys_builder{
+		AppKey: lorawanv3.KeyEnvelope_builder{
+			Key: parseKey(applicationKey),
+		}.Build(),
+	}
+
+	if networkKey != "" {
+		rootKeysBuilder.NwkKey = lorawanv3.KeyEnvelope_builder{
+			Key: parseKey(networkKey),
+		}.Build()
+	}
+
+	return rootKeysBuilder.Build(), nil
+}
+
+// DeleteEndDevice removes a LoRaWAN end device from The Things Network. It is deleted from the Join, Application
+// and Network Server registries before the Identity Server, the reverse of how it is registered. A device a
+// registry no longer knows counts as removed there, so an interrupted deletion can be repeated.
+func (ttnClient *TTNClient) DeleteEndDevice(ctx context.Context, endDevice *iotv1.EndDevice) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "DeleteEndDevice")
+	defer span.End()
+
+	ctx = setAuthorizationContext(ctx, ttnClient.ApiKey)
+
+	lorawanConfig := endDevice.GetLorawanConfig()
+	if lorawanConfig == nil {
+		return stacktrace.NewStackTraceErrorf("LoRaWAN configuration is required for TTN deletion")
+	}
+
+	endDeviceIds := endDeviceIdentifiers(endDevice, lorawanConfig)
+
+	registries := []struct {
+		name   string
+		delete func(context.Context, *lorawanv3.EndDeviceIdentifiers, ...grpc.CallOption) (*emptypb.Empty, error)
+	}{
+		{"Join Server", ttnClient.jsEndDeviceRegistryClient.Delete},
+		{"Application Server", ttnClient.asEndDeviceRegistryClient.Delete},
+		{"Network Server", ttnClient.nsEndDeviceRegistryClient.Delete},
+		{"Identity Server", ttnClient.endDeviceRegistryClient.Delete},
+	}
+
+	for _, registry := range registries {
+		_, err := registry.delete(ctx, endDeviceIds)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return stacktrace.NewStackTraceErrorf("failed to delete end device from the TTN %s: %w", registry.name, err)
+		}
+	}
+
+	return nil
+}
+
+// PushLoRaWANDownlink appends a downlink to the queue of a LoRaWAN end device in the TTN Application Server.
+// The downlink carries its correlation ID, which TTN repeats in the queued, sent, ack, nack and failed events
+// about it.
+func (ttnClient *TTNClient) PushLoRaWANDownlink(ctx context.Context, endDevice *iotv1.EndDevice, downlink *domain.LoRaWANDownlink) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "PushLoRaWANDownlink")
+	defer span.End()
+
+	ctx = setAuthorizationContext(ctx, ttnClient.ApiKey)
+
+	lorawanConfig := endDevice.GetLorawanConfig()
+	if lorawanConfig == nil {
+		return stacktrace.NewStackTraceErrorf("LoRaWAN configuration is required for TTN downlinks")
+	}
+
+	req := lorawanv3.DownlinkQueueRequest_builder{
+		EndDeviceIds: endDeviceIdentifiers(endDevice, lorawanConfig),
+		Downlinks: []*lorawanv3.ApplicationDownlink{
+			lorawanv3.ApplicationDownlink_builder{
+				FPort:          downlink.FPort,
+				FrmPayload:     downlink.FrmPayload,
+				Confirmed:      downlink.Confirmed,
+				Priority:       lorawanv3.TxSchedulePriority_NORMAL,
+				CorrelationIds: []string{downlink.CorrelationId()},
+			}.Build(),
+		},
+	}.Build()
+
+	_, err := ttnClient.appAsClient.DownlinkQueuePush(ctx, req)
+	if err != nil {
+		return stacktrace.NewStackTraceErrorf("failed to push downlink to TTN: %w", err)
 	}
 
 	return nil
@@ -395,11 +702,14 @@ func (ttnClient *TTNClient) ListApplications(ctx context.Context) ([]*Applicatio
 	return apps, nil
 }
 
-func gatewayFieldMask() *fieldmaskpb.FieldMask {
+func gatewayUpdateFieldMask() *fieldmaskpb.FieldMask {
 	return &fieldmaskpb.FieldMask{
 		Paths: []string{
-			"description",
 			"name",
+			"description",
+			"frequency_plan_id",
+			"frequency_plan_ids",
+			"antennas",
 		},
 	}
 }
@@ -414,6 +724,83 @@ func endDeviceFieldMask() *fieldmaskpb.FieldMask {
 	}
 }
 
+func endDeviceUpdateFieldMask() *fieldmaskpb.FieldMask {
+	return &fieldmaskpb.FieldMask{
+		Paths: []string{
+			"name",
+			"description",
+			"attributes",
+			"version_ids",
+		},
+	}
+}
+
+func nsEndDeviceFieldMask() *fieldmaskpb.FieldMask {
+	return &fieldmaskpb.FieldMask{
+		Paths: []string{
+			"frequency_plan_id",
+			"lorawan_version",
+			"lorawan_phy_version",
+			"supports_join",
+			"version_ids",
+		},
+	}
+}
+
+func asEndDeviceFieldMask() *fieldmaskpb.FieldMask {
+	return &fieldmaskpb.FieldMask{
+		Paths: []string{
+			"version_ids",
+		},
+	}
+}
+
+func jsEndDeviceFieldMask() *fieldmaskpb.FieldMask {
+	return &fieldmaskpb.FieldMask{
+		Paths: []string{
+			"network_server_address",
+			"application_server_address",
+		},
+	}
+}
+
+// nsSetEndDeviceRequest sets the radio settings and version identifiers of an end device in the Network Server.
+func nsSetEndDeviceRequest(endDevice *iotv1.EndDevice, lorawanConfig *iotv1.LoRaWANConfig) *lorawanv3.SetEndDeviceRequest {
+	lorawanVersion := lorawanConfig.GetHardwareData().GetLorawanVersion()
+
+	return lorawanv3.SetEndDeviceRequest_builder{
+		EndDevice: lorawanv3.EndDevice_builder{
+			Ids:               endDeviceIdentifiers(endDevice, lorawanConfig),
+			FrequencyPlanId:   lorawanConfig.GetFrequencyPlan(),
+			LorawanVersion:    convertLoRaWANVersion(lorawanVersion),
+			LorawanPhyVersion: convertLoRaWANPHYVersion(lorawanVersion),
+			SupportsJoin:      lorawanConfig.GetActivationMethod() == iotv1.ActivationMethod_ACTIVATION_METHOD_OTAA,
+			VersionIds:        buildVersionIds(lorawanConfig.GetHardwareData(), lorawanConfig.GetFrequencyPlan()),
+		}.Build(),
+		FieldMask: nsEndDeviceFieldMask(),
+	}.Build()
+}
+
+// asSetEndDeviceRequest sets the version identifiers of an end device in the Application Server.
+func asSetEndDeviceRequest(endDevice *iotv1.EndDevice, lorawanConfig *iotv1.LoRaWANConfig) *lorawanv3.SetEndDeviceRequest {
+	return lorawanv3.SetEndDeviceRequest_builder{
+		EndDevice: lorawanv3.EndDevice_builder{
+			Ids:        endDeviceIdentifiers(endDevice, lorawanConfig),
+			VersionIds: buildVersionIds(lorawanConfig.GetHardwareData(), lorawanConfig.GetFrequencyPlan()),
+		}.Build(),
+		FieldMask: asEndDeviceFieldMask(),
+	}.Build()
+}
+
+func rootKeysFieldMask() *fieldmaskpb.FieldMask {
+	return &fieldmaskpb.FieldMask{
+		Paths: []string{
+			"root_keys.app_key.key",
+			"root_keys.nwk_key.key",
+		},
+	}
+}
+
 func applicationFieldMask() *fieldmaskpb.FieldMask {
 	return &fieldmaskpb.FieldMask{
 		Paths: []string{
@@ -440,6 +827,49 @@ func apiCollaborator(collab string) *lorawanv3.OrganizationOrUserIdentifiers {
 	}.Build()
 }
 
+// endDeviceIdentifiers builds the TTN identifiers of an end device from its LoRaWAN configuration
+func endDeviceIdentifiers(endDevice *iotv1.EndDevice, lorawanConfig *iotv1.LoRaWANConfig) *lorawanv3.EndDeviceIdentifiers {
+	return lorawanv3.EndDeviceIdentifiers_builder{
+		ApplicationIds: lorawanv3.ApplicationIdentifiers_builder{
+			ApplicationId: lorawanConfig.GetApplicationId(),
+		}.Build(),
+		DeviceId: endDevice.GetId(),
+		DevEui:   parseEUI(lorawanConfig.GetDeviceEui()),
+		JoinEui:  parseEUI(lorawanConfig.GetApplicationEui()),
+	}.Build()
+}
+
+// gatewayIdentifiers builds the TTN identifiers of a gateway, whose gateway ID is its ponix ID
+func gatewayIdentifiers(gateway *domain.Gateway) *lorawanv3.GatewayIdentifiers {
+	return lorawanv3.GatewayIdentifiers_builder{
+		GatewayId: gateway.Id,
+		Eui:       parseEUI(gateway.GatewayEui),
+	}.Build()
+}
+
+// gatewayAntennas describes the antenna of a gateway at its location, if it has one
+func gatewayAntennas(gateway *domain.Gateway) []*lorawanv3.GatewayAntenna {
+	if gateway.Location == nil {
+		return nil
+	}
+
+	source := lorawanv3.LocationSource_SOURCE_REGISTRY
+	if gateway.Location.Source == domain.GatewayLocationStatus {
+		source = lorawanv3.LocationSource_SOURCE_GPS
+	}
+
+	return []*lorawanv3.GatewayAntenna{
+		lorawanv3.GatewayAntenna_builder{
+			Location: lorawanv3.Location_builder{
+				Latitude:  gateway.Location.Latitude,
+				Longitude: gateway.Location.Longitude,
+				Altitude:  gateway.Location.Altitude,
+				Source:    source,
+			}.Build(),
+		}.Build(),
+	}
+}
+
 // parseEUI converts a hex string to an 8-byte EUI
 func parseEUI(hexStr string) []byte {
 	bytes, err := hex.DecodeString(hexStr)
@@ -480,6 +910,26 @@ func convertLoRaWANVersion(version iotv1.LORAWANVersion) lorawanv3.MACVersion {
 	}
 }
 
+// convertLoRaWANPHYVersion picks the regional parameters version TTN pairs with our LoRaWAN version
+func convertLoRaWANPHYVersion(version iotv1.LORAWANVersion) lorawanv3.PHYVersion {
+	switch version {
+	case iotv1.LORAWANVersion_LORAWAN_VERSION_1_0_0:
+		return lorawanv3.PHYVersion_PHY_V1_0
+	case iotv1.LORAWANVersion_LORAWAN_VERSION_1_0_1:
+		return lorawanv3.PHYVersion_PHY_V1_0_1
+	case iotv1.LORAWANVersion_LORAWAN_VERSION_1_0_2:
+		return lorawanv3.PHYVersion_PHY_V1_0_2_REV_B
+	case iotv1.LORAWANVersion_LORAWAN_VERSION_1_0_3:
+		return lorawanv3.PHYVersion_PHY_V1_0_3_REV_A
+	case iotv1.LORAWANVersion_LORAWAN_VERSION_1_0_4:
+		return lorawanv3.PHYVersion_RP002_V1_0_1
+	case iotv1.LORAWANVersion_LORAWAN_VERSION_1_1_0:
+		return lorawanv3.PHYVersion_PHY_V1_1_REV_B
+	default:
+		return lorawanv3.PHYVersion_PHY_V1_0_3_REV_A // Matches the 1.0.3 default of convertLoRaWANVersion
+	}
+}
+
 // buildVersionIds creates version identifiers from hardware data
 func buildVersionIds(hardwareData *iotv1.LoRaWANHardwareData, frequencyPlan string) *lorawanv3.EndDeviceVersionIdentifiers {
 	if hardwareData == nil {
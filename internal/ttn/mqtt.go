@@ -0,0 +1,82 @@
+package ttn
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ponix-dev/ponix/internal/mqtt"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+)
+
+// MQTTBrokerURL returns the URL of the MQTT server of a The Things Stack cloud deployment, which only accepts TLS
+// connections.
+func MQTTBrokerURL(serverName string, region TTNRegion) string {
+	return fmt.Sprintf("mqtts://%s:8883", formatTTNCloudAddress(serverName, region))
+}
+
+// MQTTUsername returns the username an application connects to The Things Stack MQTT server with. The password is
+// an API key of the application.
+func MQTTUsername(applicationId string, tenantId string) string {
+	return fmt.Sprintf("%s@%s", applicationId, tenantId)
+}
+
+// mqttTopics are the topics, below v3/<application>@<tenant>/devices/<device>, of the messages The Things Stack
+// publishes that HandleApplicationUp handles: uplinks, join accepts, downlink events and solved locations.
+var mqttTopics = []string{
+	"up",
+	"join",
+	"down/queued",
+	"down/sent",
+	"down/ack",
+	"down/nack",
+	"down/failed",
+	"location/solved",
+}
+
+// MQTTTopicFilters returns the topic filters matching the messages of every end device of an application that are
+// handled the same way as the messages of a webhook.
+func MQTTTopicFilters(applicationId string, tenantId string) []string {
+	filters := make([]string, 0, len(mqttTopics))
+	for _, topic := range mqttTopics {
+		filters = append(filters, fmt.Sprintf("v3/%s/devices/+/%s", MQTTUsername(applicationId, tenantId), topic))
+	}
+	return filters
+}
+
+// MQTTIngester hands the messages an application receives from The Things Stack MQTT server to an
+// ApplicationUpHandler, the same way WebhookHandler does for webhook messages.
+type MQTTIngester struct {
+	handler ApplicationUpHandler
+}
+
+// NewMQTTIngester creates a new MQTTIngester.
+func NewMQTTIngester(handler ApplicationUpHandler) *MQTTIngester {
+	return &MQTTIngester{
+		handler: handler,
+	}
+}
+
+// IngestMQTTMessage handles a single ApplicationUp message, whichever topic it was published on. The message carries
+// the time The Things Stack received it, so receivedAt is not used.
+func (ingester *MQTTIngester) IngestMQTTMessage(ctx context.Context, _ string, payload []byte, _ time.Time) error {
+	ctx, span := telemetry.Tracer().Start(ctx, "TTNMQTTMessage")
+	defer span.End()
+
+	_, err := HandleApplicationUp(ctx, ingester.handler, payload)
+	return err
+}
+
+// NewMQTTSubscriber creates an mqtt.Bridge that subscribes to the uplinks, join accepts, downlink events and
+// locations of an application on The Things Stack MQTT server, authenticated with apiKey, and hands them to
+// handler. It is an alternative to receiving them with WebhookHandler; opts configure the broker URL, client ID
+// and backoff.
+func NewMQTTSubscriber(handler ApplicationUpHandler, applicationId string, tenantId string, apiKey string, opts ...mqtt.BridgeOption) *mqtt.Bridge {
+	opts = append([]mqtt.BridgeOption{
+		mqtt.WithClientID("ponix-ttn-subscriber"),
+		mqtt.WithCredentials(MQTTUsername(applicationId, tenantId), apiKey),
+		mqtt.WithTopicFilters(MQTTTopicFilters(applicationId, tenantId)...),
+	}, opts...)
+
+	return mqtt.NewBridge(NewMQTTIngester(handler), opts...)
+}
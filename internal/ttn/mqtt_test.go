@@ -0,0 +1,181 @@
+package ttn
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/mqtt"
+	"github.com/stretchr/testify/assert"
+)
+
+// readFixture reads a recorded The Things Stack message from testdata.
+func readFixture(t *testing.T, name string) []byte {
+	payload, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("could not read fixture %s: %v", name, err)
+	}
+	return payload
+}
+
+// channelUplinkHandler passes every uplink on to a channel, as the bridge handles messages concurrently.
+type channelUplinkHandler struct {
+	recordingApplicationUpHandler
+	uplinks chan domain.LoRaWANUplink
+}
+
+func (handler *channelUplinkHandler) IngestLoRaWANUplink(_ context.Context, uplink domain.LoRaWANUplink) error {
+	handler.uplinks <- uplink
+	return nil
+}
+
+func TestMQTTTopics(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("ponix-cloud@ponix", MQTTUsername("ponix-cloud", "ponix"))
+	assert.Equal([]string{
+		"v3/ponix-cloud@ponix/devices/+/up",
+		"v3/ponix-cloud@ponix/devices/+/join",
+		"v3/ponix-cloud@ponix/devices/+/down/queued",
+		"v3/ponix-cloud@ponix/devices/+/down/sent",
+		"v3/ponix-cloud@ponix/devices/+/down/ack",
+		"v3/ponix-cloud@ponix/devices/+/down/nack",
+		"v3/ponix-cloud@ponix/devices/+/down/failed",
+		"v3/ponix-cloud@ponix/devices/+/location/solved",
+	}, MQTTTopicFilters("ponix-cloud", "ponix"))
+	assert.Equal("mqtts://ponix.nam1.cloud.thethings.industries:8883", MQTTBrokerURL("ponix", TTNRegionNam))
+}
+
+func TestMQTTIngester(t *testing.T) {
+	t.Run("ingests decoded uplinks", func(t *testing.T) {
+		assert := assert.New(t)
+		recorder := &recordingApplicationUpHandler{}
+
+		err := NewMQTTIngester(recorder).IngestMQTTMessage(context.Background(), "v3/ponix-cloud@ponix/devices/freezer-42/up", readFixture(t, "uplink.json"), time.Now())
+		assert.NoError(err)
+
+		if !assert.Len(recorder.uplinks, 1) {
+			return
+		}
+		received := recorder.uplinks[0]
+		assert.Equal("70B3D57ED0001234", received.DeviceEui)
+		assert.Equal(time.Date(2025, 11, 12, 9, 0, 0, 123456789, time.UTC), received.ReceivedAt)
+		assert.Equal(uint32(2), received.FPort)
+		assert.Equal(uint32(17), received.FCnt)
+		assert.Equal([]byte{0xf8, 0xcb, 0x01}, received.FrmPayload)
+		assert.Equal(map[string]any{"battery": 3.6, "temperature": -18.5}, received.DecodedPayload)
+	})
+
+	t.Run("ingests uplinks without a payload formatter", func(t *testing.T) {
+		assert := assert.New(t)
+		recorder := &recordingApplicationUpHandler{}
+
+		err := NewMQTTIngester(recorder).IngestMQTTMessage(context.Background(), "v3/ponix-cloud@ponix/devices/meter-7/up", readFixture(t, "uplink_undecoded.json"), time.Now())
+		assert.NoError(err)
+
+		if assert.Len(recorder.uplinks, 1) {
+			assert.Equal("70B3D57ED0005678", recorder.uplinks[0].DeviceEui)
+			assert.Equal([]byte{0x01, 0x02, 0x03, 0x04}, recorder.uplinks[0].FrmPayload)
+			assert.Nil(recorder.uplinks[0].DecodedPayload)
+		}
+	})
+
+	t.Run("ingests join accepts, downlink events and locations", func(t *testing.T) {
+		assert := assert.New(t)
+		recorder := &recordingApplicationUpHandler{}
+		ingester := NewMQTTIngester(recorder)
+
+		for topic, payload := range map[string]string{
+			"join":            `{"end_device_ids": {"dev_eui": "70B3D57ED0001234"}, "join_accept": {"session_key_id": "AYfg"}}`,
+			"down/ack":        `{"end_device_ids": {"dev_eui": "70B3D57ED0001234"}, "downlink_ack": {"f_port": 1, "f_cnt": 3, "correlation_ids": ["as:downlink:01"]}}`,
+			"down/sent":       `{"end_device_ids": {"dev_eui": "70B3D57ED0001234"}, "downlink_sent": {"correlation_ids": ["as:downlink:01"]}}`,
+			"location/solved": `{"end_device_ids": {"dev_eui": "70B3D57ED0001234"}, "location_solved": {"service": "lora-cloud", "location": {"latitude": 52.37, "longitude": 4.89, "source": "SOURCE_GPS"}}}`,
+		} {
+			err := ingester.IngestMQTTMessage(context.Background(), "v3/ponix-cloud@ponix/devices/freezer-42/"+topic, []byte(payload), time.Now())
+			assert.NoError(err, topic)
+		}
+
+		assert.Len(recorder.joins, 1)
+		assert.Len(recorder.acks, 1)
+		assert.Len(recorder.locations, 1)
+		if assert.Len(recorder.downlinks, 1) {
+			assert.Equal(domain.LoRaWANDownlinkSent, recorder.downlinks[0].Status)
+		}
+	})
+
+	t.Run("returns handler errors", func(t *testing.T) {
+		recorder := &recordingApplicationUpHandler{err: domain.ErrEndDeviceNotFound}
+
+		err := NewMQTTIngester(recorder).IngestMQTTMessage(context.Background(), "v3/ponix-cloud@ponix/devices/freezer-42/up", readFixture(t, "uplink.json"), time.Now())
+		assert.ErrorIs(t, err, domain.ErrEndDeviceNotFound)
+
+		err = NewMQTTIngester(recorder).IngestMQTTMessage(context.Background(), "v3/ponix-cloud@ponix/devices/freezer-42/up", []byte("not json"), time.Now())
+		assert.ErrorIs(t, err, domain.ErrInvalidLoRaWANMessage)
+	})
+}
+
+// TestMQTTSubscriber runs the subscriber against the broker in MQTT_TEST_BROKER_URL, e.g. the Mosquitto service of
+// the anonymous test broker of the local Docker Compose setup (tcp://localhost:1884), which stands in for The Things Stack MQTT server.
+func TestMQTTSubscriber(t *testing.T) {
+	brokerURL := os.Getenv("MQTT_TEST_BROKER_URL")
+	if brokerURL == "" {
+		t.Skip("MQTT_TEST_BROKER_URL is not set")
+	}
+
+	applicationId := fmt.Sprintf("ponix-test-%d", time.Now().UnixNano())
+	handler := &channelUplinkHandler{uplinks: make(chan domain.LoRaWANUplink, 16)}
+	subscriber := NewMQTTSubscriber(
+		handler,
+		applicationId,
+		"ttn",
+		"NNSXS.TEST",
+		mqtt.WithBrokerURL(brokerURL),
+		mqtt.WithClientID(applicationId+"-subscriber"),
+		mqtt.WithConnectRetryInterval(time.Second),
+		mqtt.WithMaxReconnectInterval(time.Second),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- subscriber.Run(ctx)
+	}()
+
+	publisher := paho.NewClient(paho.NewClientOptions().AddBroker(brokerURL).SetClientID(applicationId + "-tts"))
+	token := publisher.Connect()
+	if !token.WaitTimeout(5*time.Second) || token.Error() != nil {
+		t.Fatalf("publisher did not connect: %v", token.Error())
+	}
+	defer publisher.Disconnect(0)
+
+	topic := fmt.Sprintf("v3/%s@ttn/devices/freezer-42/up", applicationId)
+	payload := readFixture(t, "uplink.json")
+
+	// The subscriber subscribes in the background, so publish until it receives the uplink
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(10 * time.Second)
+
+	var received domain.LoRaWANUplink
+receive:
+	for {
+		select {
+		case received = <-handler.uplinks:
+			break receive
+		case <-ticker.C:
+			publisher.Publish(topic, 1, false, payload)
+		case <-timeout:
+			t.Fatal("subscriber did not receive the uplink")
+		}
+	}
+
+	assert.Equal(t, "70B3D57ED0001234", received.DeviceEui)
+	assert.Equal(t, uint32(17), received.FCnt)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
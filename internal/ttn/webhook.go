@@ -0,0 +1,93 @@
+package ttn
+
+import (
+	"crypto/subtle"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/ponix-dev/ponix/internal/telemetry"
+	"github.com/ponix-dev/ponix/internal/telemetry/stacktrace"
+)
+
+const (
+	// WebhookPath is the path the webhook handler is registered at. The Things Stack webhook's base URL points
+	// here; message paths below it are accepted as well, so every message type can use its own path.
+	WebhookPath = "/ttn/webhook"
+	// WebhookSecretHeader is the request header carrying the shared secret configured as an additional header of
+	// The Things Stack webhook.
+	WebhookSecretHeader = "X-Webhook-Secret"
+	// maxWebhookBodyBytes is the largest webhook message accepted.
+	maxWebhookBodyBytes = 1 << 20
+)
+
+// WebhookHandler receives the end device messages of a The Things Stack webhook over HTTP.
+type WebhookHandler struct {
+	handler ApplicationUpHandler
+	secret  string
+}
+
+// NewWebhookHandler creates a new WebhookHandler that accepts messages carrying secret in the WebhookSecretHeader
+// header. Without a secret every message is rejected.
+func NewWebhookHandler(handler ApplicationUpHandler, secret string) *WebhookHandler {
+	return &WebhookHandler{
+		handler: handler,
+		secret:  secret,
+	}
+}
+
+// ServeHTTP handles a single webhook message. Handled and ignored messages are answered with 204 No Content.
+// Messages of unknown end devices get 404 Not Found and those of disabled ones 409 Conflict, so they show up as
+// failures in The Things Stack.
+func (webhook *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := telemetry.Tracer().Start(r.Context(), "TTNWebhook")
+	defer span.End()
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	secret := r.Header.Get(WebhookSecretHeader)
+	if webhook.secret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(webhook.secret)) != 1 {
+		http.Error(w, "invalid webhook secret", http.StatusUnauthorized)
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes))
+	if err != nil {
+		http.Error(w, "could not read message", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	_, err = HandleApplicationUp(ctx, webhook.handler, payload)
+	if err != nil {
+		status := webhookErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			slog.Error("failed to handle ttn webhook message", stacktrace.ErrorAttribute(err))
+			http.Error(w, "could not handle message", status)
+			return
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// webhookErrorStatus maps an error handling a webhook message to the HTTP status it is answered with.
+func webhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidLoRaWANMessage):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrEndDeviceNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrEndDeviceDisabled):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,144 @@
+package ttn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ponix-dev/ponix/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingApplicationUpHandler keeps the messages it is given and fails with err while it is set.
+type recordingApplicationUpHandler struct {
+	uplinks   []domain.LoRaWANUplink
+	joins     []domain.LoRaWANJoinAccept
+	acks      []domain.LoRaWANDownlinkAck
+	downlinks []domain.LoRaWANDownlinkEvent
+	locations []domain.LoRaWANLocation
+	err       error
+}
+
+func (handler *recordingApplicationUpHandler) IngestLoRaWANUplink(_ context.Context, uplink domain.LoRaWANUplink) error {
+	handler.uplinks = append(handler.uplinks, uplink)
+	return handler.err
+}
+
+func (handler *recordingApplicationUpHandler) RecordLoRaWANJoinAccept(_ context.Context, join domain.LoRaWANJoinAccept) error {
+	handler.joins = append(handler.joins, join)
+	return handler.err
+}
+
+func (handler *recordingApplicationUpHandler) RecordLoRaWANDownlinkAck(_ context.Context, ack domain.LoRaWANDownlinkAck) error {
+	handler.acks = append(handler.acks, ack)
+	return handler.err
+}
+
+func (handler *recordingApplicationUpHandler) RecordLoRaWANDownlinkEvent(_ context.Context, event domain.LoRaWANDownlinkEvent) error {
+	handler.downlinks = append(handler.downlinks, event)
+	return handler.err
+}
+
+func (handler *recordingApplicationUpHandler) RecordLoRaWANLocation(_ context.Context, location domain.LoRaWANLocation) error {
+	handler.locations = append(handler.locations, location)
+	return handler.err
+}
+
+func postWebhook(handler http.Handler, secret string, body string) int {
+	req := httptest.NewRequest(http.MethodPost, WebhookPath+"/uplink", strings.NewReader(body))
+	if secret != "" {
+		req.Header.Set(WebhookSecretHeader, secret)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestWebhookHandler(t *testing.T) {
+	uplink := `{
+		"end_device_ids": {"device_id": "freezer-42", "dev_eui": "70B3D57ED0001234"},
+		"received_at": "2025-11-12T09:00:00.123Z",
+		"uplink_message": {"f_port": 2, "f_cnt": 17, "frm_payload": "AQI=", "decoded_payload": {"temperature": -18.5}}
+	}`
+
+	t.Run("hands uplinks to the handler", func(t *testing.T) {
+		assert := assert.New(t)
+		recorder := &recordingApplicationUpHandler{}
+
+		assert.Equal(http.StatusNoContent, postWebhook(NewWebhookHandler(recorder, "s3cret"), "s3cret", uplink))
+		if !assert.Len(recorder.uplinks, 1) {
+			return
+		}
+		received := recorder.uplinks[0]
+		assert.Equal("70B3D57ED0001234", received.DeviceEui)
+		assert.Equal(time.Date(2025, 11, 12, 9, 0, 0, 123000000, time.UTC), received.ReceivedAt)
+		assert.Equal(uint32(2), received.FPort)
+		assert.Equal(uint32(17), received.FCnt)
+		assert.Equal([]byte{0x01, 0x02}, received.FrmPayload)
+		assert.Equal(map[string]any{"temperature": -18.5}, received.DecodedPayload)
+	})
+
+	t.Run("dispatches events by type", func(t *testing.T) {
+		assert := assert.New(t)
+		recorder := &recordingApplicationUpHandler{}
+		webhook := NewWebhookHandler(recorder, "s3cret")
+
+		for _, body := range []string{
+			`{"end_device_ids": {"dev_eui": "70B3D57ED0001234"}, "join_accept": {"session_key_id": "AYfg"}}`,
+			`{"end_device_ids": {"dev_eui": "70B3D57ED0001234"}, "downlink_ack": {"f_port": 1, "f_cnt": 3, "correlation_ids": ["as:downlink:01"]}}`,
+			`{"end_device_ids": {"dev_eui": "70B3D57ED0001234"}, "location_solved": {"service": "lora-cloud", "location": {"latitude": 52.37, "longitude": 4.89, "source": "SOURCE_GPS"}}}`,
+			`{"end_device_ids": {"dev_eui": "70B3D57ED0001234"}, "downlink_queued": {"f_port": 1, "correlation_ids": ["ponix:downlink:dl-1"]}}`,
+			`{"end_device_ids": {"dev_eui": "70B3D57ED0001234"}, "downlink_sent": {"f_port": 1, "correlation_ids": ["ponix:downlink:dl-1"]}}`,
+			`{"end_device_ids": {"dev_eui": "70B3D57ED0001234"}, "downlink_nack": {"f_port": 1, "correlation_ids": ["ponix:downlink:dl-1"]}}`,
+			`{"end_device_ids": {"dev_eui": "70B3D57ED0001234"}, "downlink_failed": {"downlink": {"f_port": 1, "correlation_ids": ["ponix:downlink:dl-2"]}, "error": {"namespace": "pkg/networkserver", "name": "application_downlink_too_long"}}}`,
+			`{"end_device_ids": {"dev_eui": "70B3D57ED0001234"}, "service_data": {"service": "lora-cloud"}}`,
+		} {
+			assert.Equal(http.StatusNoContent, postWebhook(webhook, "s3cret", body), body)
+		}
+
+		assert.Len(recorder.joins, 1)
+		if assert.Len(recorder.acks, 1) {
+			assert.Equal([]string{"as:downlink:01"}, recorder.acks[0].CorrelationIds)
+		}
+		if assert.Len(recorder.locations, 1) {
+			assert.Equal(52.37, recorder.locations[0].Latitude)
+			assert.Equal("lora-cloud", recorder.locations[0].Service)
+		}
+		statuses := []domain.LoRaWANDownlinkStatus{}
+		for _, event := range recorder.downlinks {
+			statuses = append(statuses, event.Status)
+		}
+		assert.Equal([]domain.LoRaWANDownlinkStatus{domain.LoRaWANDownlinkQueued, domain.LoRaWANDownlinkSent, domain.LoRaWANDownlinkQueued, domain.LoRaWANDownlinkFailed}, statuses)
+		if assert.Len(recorder.downlinks, 4) {
+			assert.Equal("not acknowledged by the end device", recorder.downlinks[2].Error)
+			assert.Equal("pkg/networkserver:application_downlink_too_long", recorder.downlinks[3].Error)
+			assert.Equal([]string{"ponix:downlink:dl-2"}, recorder.downlinks[3].CorrelationIds)
+		}
+		assert.Empty(recorder.uplinks)
+	})
+
+	t.Run("requires the shared secret", func(t *testing.T) {
+		recorder := &recordingApplicationUpHandler{}
+
+		assert.Equal(t, http.StatusUnauthorized, postWebhook(NewWebhookHandler(recorder, "s3cret"), "wrong", uplink))
+		assert.Equal(t, http.StatusUnauthorized, postWebhook(NewWebhookHandler(recorder, "s3cret"), "", uplink))
+		assert.Equal(t, http.StatusUnauthorized, postWebhook(NewWebhookHandler(recorder, ""), "", uplink))
+		assert.Empty(t, recorder.uplinks)
+	})
+
+	t.Run("maps errors to statuses", func(t *testing.T) {
+		for expected, err := range map[int]error{
+			http.StatusNotFound: domain.ErrEndDeviceNotFound,
+			http.StatusConflict: domain.ErrEndDeviceDisabled,
+		} {
+			webhook := NewWebhookHandler(&recordingApplicationUpHandler{err: err}, "s3cret")
+			assert.Equal(t, expected, postWebhook(webhook, "s3cret", uplink), err.Error())
+		}
+
+		webhook := NewWebhookHandler(&recordingApplicationUpHandler{}, "s3cret")
+		assert.Equal(t, http.StatusBadRequest, postWebhook(webhook, "s3cret", `not json`))
+	})
+}
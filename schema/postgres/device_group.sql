@@ -0,0 +1,59 @@
+-- ===== Device Groups =====
+
+-- name: CreateDeviceGroup :one
+INSERT INTO device_groups (id, organization_id, name, description)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetDeviceGroup :one
+SELECT * FROM device_groups
+WHERE id = $1;
+
+-- name: ListDeviceGroupsByOrganization :many
+SELECT * FROM device_groups
+WHERE organization_id = $1
+ORDER BY name;
+
+-- name: UpdateDeviceGroup :one
+UPDATE device_groups
+SET name = $2, description = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteDeviceGroup :exec
+DELETE FROM device_groups
+WHERE id = $1;
+
+-- ===== Device Group Membership =====
+
+-- name: AddDeviceGroupMembers :execrows
+INSERT INTO device_group_members (device_group_id, end_device_id)
+SELECT @device_group_id::TEXT, unnest(@end_device_ids::TEXT[])
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveDeviceGroupMembers :execrows
+DELETE FROM device_group_members
+WHERE device_group_id = @device_group_id
+  AND end_device_id = ANY(@end_device_ids::TEXT[]);
+
+-- name: ListDeviceGroupMembers :many
+SELECT ed.id, ed.name, ed.description, ed.organization_id, ed.status, ed.data_type, ed.hardware_type, ed.created_at, ed.updated_at, ed.labels, ed.attributes
+FROM end_devices ed
+JOIN device_group_members m ON ed.id = m.end_device_id
+WHERE m.device_group_id = $1
+ORDER BY ed.name, ed.id;
+
+-- name: ListEndDeviceGroups :many
+SELECT dg.id, dg.organization_id, dg.name, dg.description, dg.created_at, dg.updated_at
+FROM device_groups dg
+JOIN device_group_members m ON dg.id = m.device_group_id
+WHERE m.end_device_id = $1
+ORDER BY dg.name;
+
+-- name: ListDeviceGroupMembersByGroups :many
+SELECT dg.id AS device_group_id, m.end_device_id
+FROM device_groups dg
+LEFT JOIN device_group_members m ON dg.id = m.device_group_id
+WHERE dg.organization_id = @organization_id
+  AND dg.id = ANY(@device_group_ids::TEXT[])
+ORDER BY dg.id, m.end_device_id;
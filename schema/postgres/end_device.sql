@@ -1,8 +1,8 @@
 -- ===== End Devices (Generic) =====
 
 -- name: CreateEndDevice :one
-INSERT INTO end_devices (id, name, description, organization_id, status, data_type, hardware_type)
-VALUES ($1, $2, $3, $4, $5, $6, $7)
+INSERT INTO end_devices (id, name, description, organization_id, status, data_type, hardware_type, labels, attributes)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING *;
 
 -- name: GetEndDevice :one
@@ -25,6 +25,111 @@ DELETE FROM end_devices
 WHERE id = $1;
 
 -- name: GetEndDeviceWithOrganization :one
-SELECT id, name, description, organization_id, status, data_type, hardware_type, created_at, updated_at
+SELECT id, name, description, organization_id, status, data_type, hardware_type, created_at, updated_at, labels, attributes
 FROM end_devices
 WHERE id = $1;
+
+-- name: UpdateEndDeviceMetadata :one
+UPDATE end_devices
+SET labels = $2, attributes = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListEndDevicesPageByName :many
+SELECT ed.id, ed.name, ed.description, ed.organization_id, ed.status, ed.data_type, ed.hardware_type, ed.created_at, ed.updated_at, ed.labels, ed.attributes
+FROM end_devices ed
+LEFT JOIN lorawan_configs lc ON ed.id = lc.end_device_id
+WHERE ed.organization_id = @organization_id
+  AND (sqlc.narg('status')::INTEGER IS NULL OR ed.status = sqlc.narg('status'))
+  AND (sqlc.narg('hardware_type')::INTEGER IS NULL OR ed.hardware_type = sqlc.narg('hardware_type'))
+  AND (sqlc.narg('hardware_type_id')::TEXT IS NULL OR lc.hardware_type_id = sqlc.narg('hardware_type_id'))
+  AND (sqlc.narg('name_prefix')::TEXT IS NULL OR ed.name LIKE sqlc.narg('name_prefix') || '%')
+  AND (sqlc.narg('label_equals')::JSONB IS NULL OR ed.labels @> sqlc.narg('label_equals'))
+  AND (sqlc.narg('label_in')::JSONB IS NULL OR NOT EXISTS (
+    SELECT 1 FROM jsonb_each(sqlc.narg('label_in')) AS s(key, vals)
+    WHERE NOT COALESCE(s.vals ? (ed.labels ->> s.key), false)))
+  AND (sqlc.narg('label_not_in')::JSONB IS NULL OR NOT EXISTS (
+    SELECT 1 FROM jsonb_each(sqlc.narg('label_not_in')) AS s(key, vals)
+    WHERE COALESCE(s.vals ? (ed.labels ->> s.key), false)))
+  AND (sqlc.narg('label_exists')::TEXT[] IS NULL OR ed.labels ?& sqlc.narg('label_exists'))
+  AND (sqlc.narg('label_not_exists')::TEXT[] IS NULL OR NOT ed.labels ?| sqlc.narg('label_not_exists'))
+  AND (sqlc.narg('device_group_ids')::TEXT[] IS NULL OR EXISTS (
+    SELECT 1 FROM device_group_members m
+    WHERE m.end_device_id = ed.id AND m.device_group_id = ANY(sqlc.narg('device_group_ids'))))
+  AND (sqlc.narg('not_seen_since')::TIMESTAMPTZ IS NULL OR NOT EXISTS (
+    SELECT 1 FROM end_device_presence p
+    WHERE p.end_device_id = ed.id AND p.last_seen_at >= sqlc.narg('not_seen_since')))
+  AND (sqlc.narg('cursor_id')::TEXT IS NULL OR (ed.name, ed.id) > (sqlc.narg('cursor_name')::TEXT, sqlc.narg('cursor_id')))
+ORDER BY ed.name, ed.id
+LIMIT @page_limit;
+
+-- name: ListEndDevicesPageByCreatedAt :many
+SELECT ed.id, ed.name, ed.description, ed.organization_id, ed.status, ed.data_type, ed.hardware_type, ed.created_at, ed.updated_at, ed.labels, ed.attributes
+FROM end_devices ed
+LEFT JOIN lorawan_configs lc ON ed.id = lc.end_device_id
+WHERE ed.organization_id = @organization_id
+  AND (sqlc.narg('status')::INTEGER IS NULL OR ed.status = sqlc.narg('status'))
+  AND (sqlc.narg('hardware_type')::INTEGER IS NULL OR ed.hardware_type = sqlc.narg('hardware_type'))
+  AND (sqlc.narg('hardware_type_id')::TEXT IS NULL OR lc.hardware_type_id = sqlc.narg('hardware_type_id'))
+  AND (sqlc.narg('name_prefix')::TEXT IS NULL OR ed.name LIKE sqlc.narg('name_prefix') || '%')
+  AND (sqlc.narg('label_equals')::JSONB IS NULL OR ed.labels @> sqlc.narg('label_equals'))
+  AND (sqlc.narg('label_in')::JSONB IS NULL OR NOT EXISTS (
+    SELECT 1 FROM jsonb_each(sqlc.narg('label_in')) AS s(key, vals)
+    WHERE NOT COALESCE(s.vals ? (ed.labels ->> s.key), false)))
+  AND (sqlc.narg('label_not_in')::JSONB IS NULL OR NOT EXISTS (
+    SELECT 1 FROM jsonb_each(sqlc.narg('label_not_in')) AS s(key, vals)
+    WHERE COALESCE(s.vals ? (ed.labels ->> s.key), false)))
+  AND (sqlc.narg('label_exists')::TEXT[] IS NULL OR ed.labels ?& sqlc.narg('label_exists'))
+  AND (sqlc.narg('label_not_exists')::TEXT[] IS NULL OR NOT ed.labels ?| sqlc.narg('label_not_exists'))
+  AND (sqlc.narg('device_group_ids')::TEXT[] IS NULL OR EXISTS (
+    SELECT 1 FROM device_group_members m
+    WHERE m.end_device_id = ed.id AND m.device_group_id = ANY(sqlc.narg('device_group_ids'))))
+  AND (sqlc.narg('not_seen_since')::TIMESTAMPTZ IS NULL OR NOT EXISTS (
+    SELECT 1 FROM end_device_presence p
+    WHERE p.end_device_id = ed.id AND p.last_seen_at >= sqlc.narg('not_seen_since')))
+  AND (sqlc.narg('cursor_id')::TEXT IS NULL OR (ed.created_at, ed.id) > (sqlc.narg('cursor_created_at')::TIMESTAMPTZ, sqlc.narg('cursor_id')))
+ORDER BY ed.created_at, ed.id
+LIMIT @page_limit;
+
+-- name: ListEndDeviceIDsByLabels :many
+SELECT ed.id
+FROM end_devices ed
+WHERE ed.organization_id = @organization_id
+  AND (sqlc.narg('label_equals')::JSONB IS NULL OR ed.labels @> sqlc.narg('label_equals'))
+  AND (sqlc.narg('label_in')::JSONB IS NULL OR NOT EXISTS (
+    SELECT 1 FROM jsonb_each(sqlc.narg('label_in')) AS s(key, vals)
+    WHERE NOT COALESCE(s.vals ? (ed.labels ->> s.key), false)))
+  AND (sqlc.narg('label_not_in')::JSONB IS NULL OR NOT EXISTS (
+    SELECT 1 FROM jsonb_each(sqlc.narg('label_not_in')) AS s(key, vals)
+    WHERE COALESCE(s.vals ? (ed.labels ->> s.key), false)))
+  AND (sqlc.narg('label_exists')::TEXT[] IS NULL OR ed.labels ?& sqlc.narg('label_exists'))
+  AND (sqlc.narg('label_not_exists')::TEXT[] IS NULL OR NOT ed.labels ?| sqlc.narg('label_not_exists'))
+ORDER BY ed.id;
+
+-- name: ListEndDeviceIDsInOrganization :many
+SELECT id
+FROM end_devices
+WHERE organization_id = @organization_id
+  AND id = ANY(@end_device_ids::TEXT[])
+ORDER BY id;
+
+-- name: SearchEndDevices :many
+SELECT id, name, description, organization_id, status, data_type, hardware_type, created_at, updated_at, labels, attributes, rank
+FROM (
+  SELECT ed.id, ed.name, ed.description, ed.organization_id, ed.status, ed.data_type, ed.hardware_type, ed.created_at, ed.updated_at, ed.labels, ed.attributes,
+    (ts_rank_cd(end_device_search_document(ed.name, ed.description, ed.labels), websearch_to_tsquery('english', @query::TEXT), 32)
+      + CASE WHEN lc.device_eui = sqlc.narg('device_eui')::TEXT THEN 2 ELSE 0 END
+      + CASE WHEN lht.model ILIKE '%' || @model_pattern::TEXT || '%' THEN 1 ELSE 0 END)::FLOAT8 AS rank
+  FROM end_devices ed
+  LEFT JOIN lorawan_configs lc ON ed.id = lc.end_device_id
+  LEFT JOIN lorawan_hardware_types lht ON lc.hardware_type_id = lht.id
+  WHERE ed.organization_id = ANY(@organization_ids::TEXT[])
+    AND (end_device_search_document(ed.name, ed.description, ed.labels) @@ websearch_to_tsquery('english', @query::TEXT)
+      OR lc.device_eui = sqlc.narg('device_eui')::TEXT
+      OR lht.model ILIKE '%' || @model_pattern::TEXT || '%')
+) AS matches
+WHERE sqlc.narg('cursor_id')::TEXT IS NULL
+  OR rank < sqlc.narg('cursor_rank')::FLOAT8
+  OR (rank = sqlc.narg('cursor_rank')::FLOAT8 AND id > sqlc.narg('cursor_id')::TEXT)
+ORDER BY rank DESC, id
+LIMIT @page_limit;
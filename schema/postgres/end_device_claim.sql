@@ -0,0 +1,50 @@
+-- ===== End Device Claims =====
+
+-- Stores the claim code of an end device, replacing any earlier code.
+-- name: UpsertEndDeviceClaim :one
+INSERT INTO end_device_claims (end_device_id, organization_id, code_hash, expires_at, created_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (end_device_id) DO UPDATE
+SET organization_id = EXCLUDED.organization_id,
+    code_hash = EXCLUDED.code_hash,
+    expires_at = EXCLUDED.expires_at,
+    created_at = EXCLUDED.created_at,
+    claimed_at = NULL,
+    claimed_by_organization_id = NULL
+RETURNING *;
+
+-- name: GetEndDeviceClaimByCodeHash :one
+SELECT * FROM end_device_claims
+WHERE code_hash = $1;
+
+-- Marks a claim as used unless it was used or expired in the meantime.
+-- name: RedeemEndDeviceClaim :execrows
+UPDATE end_device_claims
+SET claimed_at = @claimed_at, claimed_by_organization_id = @claimed_by_organization_id
+WHERE end_device_id = @end_device_id
+  AND code_hash = @code_hash
+  AND claimed_at IS NULL
+  AND expires_at > @claimed_at;
+
+-- name: MoveEndDeviceToOrganization :execrows
+UPDATE end_devices
+SET organization_id = @organization_id, updated_at = NOW()
+WHERE id = @id AND organization_id = @previous_organization_id;
+
+-- name: RemoveEndDeviceFromDeviceGroups :execrows
+DELETE FROM device_group_members
+WHERE end_device_id = $1;
+
+-- ===== End Device Claim Attempts =====
+
+-- name: CreateEndDeviceClaimAttempt :exec
+INSERT INTO end_device_claim_attempts (id, user_id, attempted_at)
+VALUES ($1, $2, $3);
+
+-- name: CountEndDeviceClaimAttemptsSince :one
+SELECT COUNT(*) FROM end_device_claim_attempts
+WHERE user_id = $1 AND attempted_at >= $2;
+
+-- name: DeleteEndDeviceClaimAttemptsBefore :execrows
+DELETE FROM end_device_claim_attempts
+WHERE user_id = $1 AND attempted_at < $2;
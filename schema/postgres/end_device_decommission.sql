@@ -0,0 +1,50 @@
+-- ===== End Device Decommissions =====
+
+-- name: CreateEndDeviceDecommission :one
+INSERT INTO end_device_decommissions (id, end_device_id, organization_id, requested_by, data_policy, step, purge_after, next_attempt_at, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: GetEndDeviceDecommissionByEndDevice :one
+SELECT * FROM end_device_decommissions
+WHERE end_device_id = $1;
+
+-- name: ListDueEndDeviceDecommissions :many
+SELECT * FROM end_device_decommissions
+WHERE step <> 'completed' AND next_attempt_at <= @now
+ORDER BY next_attempt_at, id
+LIMIT @row_limit;
+
+-- Saves the progress of a decommission unless another process advanced it since it was read.
+-- name: UpdateEndDeviceDecommission :execrows
+UPDATE end_device_decommissions
+SET step = @step,
+    attempts = @attempts,
+    last_error = @last_error,
+    next_attempt_at = @next_attempt_at,
+    completed_at = @completed_at,
+    updated_at = @updated_at
+WHERE id = @id AND step = @previous_step;
+
+-- ===== End Device Decommission Events =====
+
+-- name: CreateEndDeviceDecommissionEvent :exec
+INSERT INTO end_device_decommission_events (id, decommission_id, step, error, occurred_at)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: ListEndDeviceDecommissionEvents :many
+SELECT * FROM end_device_decommission_events
+WHERE decommission_id = $1
+ORDER BY occurred_at, id;
+
+-- ===== Credential Revocation =====
+
+-- Clears the root keys of a LoRaWAN end device; an empty application key opens to no key.
+-- name: RevokeLoRaWANRootKeys :exec
+UPDATE lorawan_configs
+SET application_key = '', network_key = NULL, updated_at = NOW()
+WHERE end_device_id = $1;
+
+-- name: DeleteEndDeviceClaim :exec
+DELETE FROM end_device_claims
+WHERE end_device_id = $1;
@@ -0,0 +1,50 @@
+-- ===== End Device Presence =====
+
+-- name: RecordEndDevicePresence :exec
+INSERT INTO end_device_presence (end_device_id, last_seen_at, last_occurred_at, last_processed_at, last_values)
+SELECT ed.id, @last_processed_at::TIMESTAMPTZ, sqlc.narg('last_occurred_at')::TIMESTAMPTZ, @last_processed_at::TIMESTAMPTZ, @last_values::JSONB
+FROM end_devices ed
+WHERE ed.id = @end_device_id
+ON CONFLICT (end_device_id) DO UPDATE
+SET last_seen_at = GREATEST(end_device_presence.last_seen_at, EXCLUDED.last_seen_at),
+    last_occurred_at = GREATEST(end_device_presence.last_occurred_at, EXCLUDED.last_occurred_at),
+    last_processed_at = GREATEST(end_device_presence.last_processed_at, EXCLUDED.last_processed_at),
+    last_values = CASE
+        WHEN EXCLUDED.last_occurred_at >= COALESCE(end_device_presence.last_occurred_at, '-infinity')
+        THEN end_device_presence.last_values || EXCLUDED.last_values
+        ELSE EXCLUDED.last_values || end_device_presence.last_values
+    END;
+
+-- name: AddEndDeviceMessageCount :exec
+INSERT INTO end_device_message_counts (end_device_id, hour_start, message_count)
+SELECT ed.id, @hour_start::TIMESTAMPTZ, @message_count::BIGINT
+FROM end_devices ed
+WHERE ed.id = @end_device_id
+ON CONFLICT (end_device_id, hour_start) DO UPDATE
+SET message_count = end_device_message_counts.message_count + EXCLUDED.message_count;
+
+-- name: ListEndDevicePresence :many
+SELECT p.end_device_id, p.last_seen_at, p.last_occurred_at, p.last_processed_at, p.last_values,
+    COALESCE((
+        SELECT SUM(c.message_count) FROM end_device_message_counts c
+        WHERE c.end_device_id = p.end_device_id AND c.hour_start >= @counted_since
+    ), 0)::BIGINT AS message_count
+FROM end_device_presence p
+WHERE p.end_device_id = ANY(@end_device_ids::TEXT[]);
+
+-- name: DeleteEndDeviceMessageCountsBefore :execrows
+DELETE FROM end_device_message_counts
+WHERE hour_start < $1;
+
+-- name: TouchEndDevicePresence :exec
+INSERT INTO end_device_presence (end_device_id, last_seen_at)
+VALUES ($1, $2)
+ON CONFLICT (end_device_id) DO UPDATE
+SET last_seen_at = GREATEST(end_device_presence.last_seen_at, EXCLUDED.last_seen_at);
+
+-- name: ListSilentEndDevices :many
+SELECT ed.id FROM end_devices ed
+JOIN end_device_presence p ON p.end_device_id = ed.id
+WHERE ed.status = @status AND p.last_seen_at < @seen_before
+ORDER BY p.last_seen_at
+LIMIT @page_limit;
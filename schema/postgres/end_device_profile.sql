@@ -0,0 +1,47 @@
+-- ===== End Device Profiles =====
+
+-- name: CreateEndDeviceProfile :one
+INSERT INTO end_device_profiles (id, organization_id, name, description, hardware_type, hardware_type_id, frequency_plan_id, payload_decoder, expected_fields, labels)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: GetEndDeviceProfile :one
+SELECT * FROM end_device_profiles
+WHERE id = $1;
+
+-- name: ListEndDeviceProfilesByOrganization :many
+SELECT * FROM end_device_profiles
+WHERE organization_id = $1
+ORDER BY name;
+
+-- name: UpdateEndDeviceProfile :one
+UPDATE end_device_profiles
+SET name = $2, description = $3, hardware_type_id = $4, frequency_plan_id = $5, payload_decoder = $6, expected_fields = $7, labels = $8, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteEndDeviceProfile :exec
+DELETE FROM end_device_profiles
+WHERE id = $1;
+
+-- ===== End Device Profile Devices =====
+
+-- name: AssignEndDeviceProfile :exec
+INSERT INTO end_device_profile_devices (end_device_id, end_device_profile_id)
+VALUES ($1, $2);
+
+-- name: GetEndDeviceProfileID :one
+SELECT end_device_profile_id FROM end_device_profile_devices
+WHERE end_device_id = $1;
+
+-- name: UnassignEndDeviceProfile :execrows
+DELETE FROM end_device_profile_devices
+WHERE end_device_id = $1;
+
+-- name: ListEndDeviceProfileDevices :many
+SELECT ed.id, ed.name, ed.labels, lc.hardware_type_id, lc.frequency_plan_id
+FROM end_devices ed
+JOIN end_device_profile_devices pd ON ed.id = pd.end_device_id
+LEFT JOIN lorawan_configs lc ON ed.id = lc.end_device_id
+WHERE pd.end_device_profile_id = $1
+ORDER BY ed.name, ed.id;
@@ -0,0 +1,16 @@
+-- ===== End Device Status Lifecycle =====
+
+-- name: TransitionEndDeviceStatus :execrows
+UPDATE end_devices
+SET status = @to_status, updated_at = NOW()
+WHERE id = @id AND status = @from_status;
+
+-- name: CreateEndDeviceStatusTransition :one
+INSERT INTO end_device_status_transitions (id, end_device_id, from_status, to_status, reason, transitioned_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ListEndDeviceStatusTransitions :many
+SELECT * FROM end_device_status_transitions
+WHERE end_device_id = $1
+ORDER BY transitioned_at DESC, id DESC;
@@ -0,0 +1,19 @@
+-- ===== End Device Transfers =====
+
+-- name: CreateEndDeviceTransfer :exec
+INSERT INTO end_device_transfers (id, end_device_id, from_organization_id, to_organization_id, transferred_by, history, transferred_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: GetEndDeviceTransfer :one
+SELECT * FROM end_device_transfers
+WHERE id = $1;
+
+-- name: ListEndDeviceTransfers :many
+SELECT * FROM end_device_transfers
+WHERE end_device_id = $1
+ORDER BY transferred_at DESC, id;
+
+-- name: SetEndDeviceTransferHistoryTransferred :exec
+UPDATE end_device_transfers
+SET history_transferred_at = $2
+WHERE id = $1;
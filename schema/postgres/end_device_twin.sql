@@ -0,0 +1,27 @@
+-- ===== End Device Twins =====
+
+-- name: GetEndDeviceTwin :one
+SELECT * FROM end_device_twins
+WHERE end_device_id = $1;
+
+-- Replaces the desired document when desired_version still matches the expected version.
+-- name: SetEndDeviceTwinDesired :one
+INSERT INTO end_device_twins (end_device_id, desired, desired_version, desired_updated_at)
+SELECT @end_device_id::TEXT, @desired::JSONB, 1, NOW()
+WHERE @expected_version::BIGINT = 0
+ON CONFLICT (end_device_id) DO UPDATE
+SET desired = EXCLUDED.desired,
+    desired_version = end_device_twins.desired_version + 1,
+    desired_updated_at = NOW()
+WHERE end_device_twins.desired_version = @expected_version::BIGINT
+RETURNING *;
+
+-- Merges a reported patch into the reported document; keys set to null are removed.
+-- name: MergeEndDeviceTwinReported :one
+INSERT INTO end_device_twins (end_device_id, reported, reported_version, reported_updated_at)
+VALUES (@end_device_id, jsonb_strip_nulls(@reported::JSONB), 1, NOW())
+ON CONFLICT (end_device_id) DO UPDATE
+SET reported = jsonb_strip_nulls(end_device_twins.reported || EXCLUDED.reported),
+    reported_version = end_device_twins.reported_version + 1,
+    reported_updated_at = NOW()
+RETURNING *;
@@ -0,0 +1,37 @@
+-- ===== EUI Blocks =====
+
+-- name: CreateEUIBlock :one
+INSERT INTO eui_blocks (prefix, organization_id, size)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: ListEUIBlocks :many
+SELECT * FROM eui_blocks
+WHERE organization_id = $1
+ORDER BY created_at, prefix;
+
+-- Lists blocks that contain or are contained in the given prefix.
+-- name: ListOverlappingEUIBlocks :many
+SELECT * FROM eui_blocks
+WHERE starts_with(@prefix::TEXT, prefix) OR starts_with(prefix, @prefix::TEXT);
+
+-- Hands out the next offset of the organization's oldest block that still has room.
+-- The offset only ever grows, so an EUI is never handed out twice.
+-- name: ReserveEUIBlockOffset :one
+UPDATE eui_blocks
+SET next_offset = next_offset + 1
+WHERE prefix = (
+    SELECT candidate.prefix FROM eui_blocks AS candidate
+    WHERE candidate.organization_id = @organization_id AND candidate.next_offset < candidate.size
+    ORDER BY candidate.created_at, candidate.prefix
+    LIMIT 1
+    FOR UPDATE
+)
+AND next_offset < size
+RETURNING prefix, (next_offset - 1)::BIGINT AS reserved_offset;
+
+-- name: LoRaWANDeviceEUIExists :one
+SELECT EXISTS (
+    SELECT 1 FROM lorawan_configs
+    WHERE device_eui = $1
+) AS device_eui_exists;
@@ -0,0 +1,40 @@
+-- ===== Gateways =====
+
+-- name: CreateGateway :one
+INSERT INTO gateways (id, organization_id, name, description, gateway_eui, frequency_plan_id, latitude, longitude, altitude, location_source)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: GetGateway :one
+SELECT * FROM gateways
+WHERE id = $1;
+
+-- name: ListGatewaysByOrganization :many
+SELECT * FROM gateways
+WHERE organization_id = $1
+ORDER BY name, id;
+
+-- name: ListGateways :many
+SELECT * FROM gateways
+ORDER BY id;
+
+-- name: UpdateGateway :one
+UPDATE gateways
+SET name = $2, description = $3, frequency_plan_id = $4, latitude = $5, longitude = $6, altitude = $7, location_source = $8, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteGateway :execrows
+DELETE FROM gateways
+WHERE id = $1;
+
+-- name: UpdateGatewayStatus :exec
+UPDATE gateways
+SET status = $2, last_seen_at = $3, status_checked_at = $4
+WHERE id = $1;
+
+-- name: UpdateGatewayReportedLocation :exec
+UPDATE gateways
+SET latitude = $2, longitude = $3, altitude = $4, location_source = 'status'
+WHERE id = $1
+  AND location_source IS DISTINCT FROM 'registry';
@@ -98,7 +98,8 @@ SELECT
     lht.firmware_version,
     lht.hardware_version,
     lht.lorawan_version,
-    lht.profile
+    lht.profile,
+    lht.description as hardware_description
 FROM end_devices ed
 JOIN lorawan_configs lc ON ed.id = lc.end_device_id
 JOIN lorawan_hardware_types lht ON lc.hardware_type_id = lht.id
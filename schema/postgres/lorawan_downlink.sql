@@ -0,0 +1,17 @@
+-- ===== LoRaWAN Downlinks =====
+
+-- name: CreateLoRaWANDownlink :exec
+INSERT INTO lorawan_downlinks (id, end_device_id, f_port, frm_payload, data, confirmed, status, error, requested_by, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);
+
+-- name: ListEndDeviceLoRaWANDownlinks :many
+SELECT * FROM lorawan_downlinks
+WHERE end_device_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2;
+
+-- name: UpdateLoRaWANDownlinkStatus :execrows
+UPDATE lorawan_downlinks
+SET status = @status, error = @error, updated_at = @updated_at
+WHERE id = @id
+  AND status = ANY(@from_statuses::TEXT[]);
@@ -0,0 +1,18 @@
+-- ===== Modbus Configs =====
+
+-- name: CreateModbusConfig :exec
+INSERT INTO modbus_configs (id, end_device_id, host, unit_id, poll_interval_seconds, registers)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- Lists every Modbus end device with its configuration for the poller.
+-- name: ListModbusEndDevices :many
+SELECT mc.end_device_id, ed.organization_id, ed.status, mc.host, mc.unit_id, mc.poll_interval_seconds, mc.registers
+FROM modbus_configs mc
+JOIN end_devices ed ON ed.id = mc.end_device_id
+ORDER BY mc.end_device_id;
+
+-- Gets the configuration of a Modbus end device.
+-- name: GetModbusConfig :one
+SELECT host, unit_id, poll_interval_seconds, registers
+FROM modbus_configs
+WHERE end_device_id = $1;
@@ -0,0 +1,23 @@
+-- ===== MQTT Configs =====
+
+-- name: CreateMQTTConfig :exec
+INSERT INTO mqtt_configs (id, end_device_id, topic, username, password_hash)
+VALUES ($1, $2, $3, $4, $5);
+
+-- Finds the end device publishing to a topic for the MQTT bridge.
+-- name: GetEndDeviceByMQTTTopic :one
+SELECT ed.id, ed.organization_id
+FROM mqtt_configs mc
+JOIN end_devices ed ON ed.id = mc.end_device_id
+WHERE mc.topic = $1;
+
+-- Removes the broker credentials of an MQTT end device so the broker stops accepting it.
+-- name: DeleteMQTTConfig :exec
+DELETE FROM mqtt_configs
+WHERE end_device_id = $1;
+
+-- Gets the topic and username of an MQTT end device. Its password hash is never read back.
+-- name: GetMQTTConfig :one
+SELECT topic, username
+FROM mqtt_configs
+WHERE end_device_id = $1;
@@ -0,0 +1,29 @@
+-- ===== Payload Decoders =====
+
+-- name: UpsertLoRaWANHardwareTypeDecoder :exec
+INSERT INTO lorawan_hardware_type_decoders (hardware_type_id, codec, script, byte_layout)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (hardware_type_id) DO UPDATE
+SET codec = EXCLUDED.codec, script = EXCLUDED.script, byte_layout = EXCLUDED.byte_layout, updated_at = NOW();
+
+-- name: GetLoRaWANHardwareTypeDecoder :one
+SELECT * FROM lorawan_hardware_type_decoders
+WHERE hardware_type_id = $1;
+
+-- name: DeleteLoRaWANHardwareTypeDecoder :exec
+DELETE FROM lorawan_hardware_type_decoders
+WHERE hardware_type_id = $1;
+
+-- Finds the decoder of the uplinks of an end device: the script of its profile, or else the decoder of its
+-- LoRaWAN hardware type. The codec is empty when neither has one.
+-- name: GetEndDevicePayloadDecoder :one
+SELECT
+    (CASE WHEN edp.payload_decoder IS NOT NULL THEN 'javascript' ELSE COALESCE(lhtd.codec, '') END)::TEXT AS codec,
+    COALESCE(edp.payload_decoder, lhtd.script, '')::TEXT AS script,
+    (CASE WHEN edp.payload_decoder IS NULL THEN lhtd.byte_layout END)::JSONB AS byte_layout
+FROM end_devices ed
+LEFT JOIN end_device_profile_devices edpd ON edpd.end_device_id = ed.id
+LEFT JOIN end_device_profiles edp ON edp.id = edpd.end_device_profile_id
+LEFT JOIN lorawan_configs lc ON lc.end_device_id = ed.id
+LEFT JOIN lorawan_hardware_type_decoders lhtd ON lhtd.hardware_type_id = lc.hardware_type_id
+WHERE ed.id = $1;
@@ -0,0 +1,34 @@
+-- ===== LoRaWAN Root Keys =====
+
+-- Lists end devices whose root keys are still stored as plaintext hex. Revoked keys are empty and skipped.
+-- name: ListPlaintextRootKeys :many
+SELECT end_device_id, application_key, network_key
+FROM lorawan_configs
+WHERE application_key NOT LIKE 'v1:%' AND application_key <> ''
+ORDER BY end_device_id
+LIMIT $1;
+
+-- Lists end devices whose sealed root keys are wrapped with a master key other than the given one.
+-- name: ListRootKeysNotWrappedWith :many
+SELECT end_device_id, application_key, network_key
+FROM lorawan_configs
+WHERE application_key LIKE 'v1:%'
+  AND split_part(application_key, ':', 2) <> @master_key_id::TEXT
+ORDER BY end_device_id
+LIMIT @row_limit;
+
+-- Replaces the root keys of an end device unless they changed since they were read.
+-- name: UpdateRootKeys :execrows
+UPDATE lorawan_configs
+SET application_key = @application_key, network_key = @network_key, updated_at = NOW()
+WHERE end_device_id = @end_device_id AND application_key = @previous_application_key;
+
+-- name: CreateRootKeyRotation :one
+INSERT INTO lorawan_root_key_rotations (id, end_device_id, reason, keys_supplied, rotated_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ListRootKeyRotations :many
+SELECT * FROM lorawan_root_key_rotations
+WHERE end_device_id = $1
+ORDER BY rotated_at DESC, id DESC;
@@ -76,7 +76,11 @@ CREATE TABLE end_devices (
     
     -- Audit fields
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    -- Metadata
+    labels JSONB NOT NULL DEFAULT '{}'::jsonb, -- key/value labels used by label selectors
+    attributes JSONB NOT NULL DEFAULT '{}'::jsonb -- free-form attributes document
 );
 
 -- LoRaWAN-specific configuration (maps to LoRaWANConfig message)
@@ -89,9 +93,9 @@ CREATE TABLE lorawan_configs (
     application_eui CHAR(16) NOT NULL, -- 64-bit application EUI (hex)
     application_id CHAR(20) NOT NULL, -- LoRaWAN application identifier
     
-    -- LoRaWAN keys (encrypted storage recommended in production)
-    application_key CHAR(32) NOT NULL, -- 128-bit application key (hex)
-    network_key CHAR(32), -- 128-bit network key for LoRaWAN 1.1+ (hex)
+    -- LoRaWAN keys, sealed with envelope encryption ("v1:<master key id>:<wrapped data key>:<ciphertext>")
+    application_key TEXT NOT NULL, -- sealed 128-bit application key
+    network_key TEXT, -- sealed 128-bit network key for LoRaWAN 1.1+
     
     -- LoRaWAN configuration
     activation_method INTEGER NOT NULL DEFAULT 1, -- maps to ActivationMethod enum (OTAA default)
@@ -108,11 +112,259 @@ CREATE TABLE lorawan_configs (
     CONSTRAINT unique_device_eui UNIQUE (device_eui),
     CONSTRAINT unique_end_device_id UNIQUE (end_device_id),
     CONSTRAINT valid_device_eui CHECK (device_eui ~ '^[0-9A-Fa-f]{16}$'),
-    CONSTRAINT valid_application_eui CHECK (application_eui ~ '^[0-9A-Fa-f]{16}$'),
-    CONSTRAINT valid_application_key CHECK (application_key ~ '^[0-9A-Fa-f]{32}$'),
-    CONSTRAINT valid_network_key CHECK (network_key IS NULL OR network_key ~ '^[0-9A-Fa-f]{32}$')
+    CONSTRAINT valid_application_eui CHECK (application_eui ~ '^[0-9A-Fa-f]{16}$')
+);
+
+-- Named, organization scoped groups of end devices (fleets)
+CREATE TABLE device_groups (
+    id CHAR(20) PRIMARY KEY,
+    organization_id CHAR(20) NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_device_group_name UNIQUE (organization_id, name)
+);
+
+-- Many-to-many membership between device groups and end devices
+CREATE TABLE device_group_members (
+    device_group_id CHAR(20) NOT NULL REFERENCES device_groups(id) ON DELETE CASCADE,
+    end_device_id CHAR(20) NOT NULL REFERENCES end_devices(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (device_group_id, end_device_id)
+);
+
+-- Latest traffic seen from each end device, used to detect devices that have gone silent
+CREATE TABLE end_device_presence (
+    end_device_id CHAR(20) PRIMARY KEY REFERENCES end_devices(id) ON DELETE CASCADE,
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    last_occurred_at TIMESTAMPTZ,
+    last_processed_at TIMESTAMPTZ,
+    last_values JSONB NOT NULL DEFAULT '{}'::jsonb -- last value of each configured presence field
+);
+
+-- Hourly message counts per end device, summed for the rolling 24 hour count
+CREATE TABLE end_device_message_counts (
+    end_device_id CHAR(20) NOT NULL REFERENCES end_devices(id) ON DELETE CASCADE,
+    hour_start TIMESTAMPTZ NOT NULL,
+    message_count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (end_device_id, hour_start)
+);
+
+-- Audit trail of every end device status change
+CREATE TABLE end_device_status_transitions (
+    id CHAR(20) PRIMARY KEY,
+    end_device_id CHAR(20) NOT NULL REFERENCES end_devices(id) ON DELETE CASCADE,
+    from_status INTEGER NOT NULL, -- maps to EndDeviceStatus enum
+    to_status INTEGER NOT NULL, -- maps to EndDeviceStatus enum
+    reason TEXT NOT NULL,
+    transitioned_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Desired and reported state documents of each end device
+CREATE TABLE end_device_twins (
+    end_device_id CHAR(20) PRIMARY KEY REFERENCES end_devices(id) ON DELETE CASCADE,
+    desired JSONB NOT NULL DEFAULT '{}'::jsonb,
+    desired_version BIGINT NOT NULL DEFAULT 0,
+    desired_updated_at TIMESTAMPTZ,
+    reported JSONB NOT NULL DEFAULT '{}'::jsonb,
+    reported_version BIGINT NOT NULL DEFAULT 0,
+    reported_updated_at TIMESTAMPTZ
+);
+
+-- IEEE address blocks (MA-L, MA-M or MA-S) that the device EUIs of an organization are allocated from
+CREATE TABLE eui_blocks (
+    prefix VARCHAR(9) PRIMARY KEY, -- block prefix as uppercase hex: 6 digits for MA-L, 7 for MA-M, 9 for MA-S
+    organization_id CHAR(20) NOT NULL REFERENCES organizations(id),
+    size BIGINT NOT NULL, -- number of EUIs in the block
+    next_offset BIGINT NOT NULL DEFAULT 0, -- offset of the next EUI to hand out; never decreases so EUIs are never reused
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Audit trail of every replacement of the root keys of a LoRaWAN end device
+CREATE TABLE lorawan_root_key_rotations (
+    id CHAR(20) PRIMARY KEY,
+    end_device_id CHAR(20) NOT NULL REFERENCES end_devices(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    keys_supplied BOOLEAN NOT NULL, -- false when the new keys were generated
+    rotated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One-time codes that let another organization claim a pre-provisioned end device
+CREATE TABLE end_device_claims (
+    end_device_id CHAR(20) PRIMARY KEY REFERENCES end_devices(id) ON DELETE CASCADE,
+    organization_id CHAR(20) NOT NULL REFERENCES organizations(id), -- organization that issued the code
+    code_hash CHAR(64) NOT NULL UNIQUE, -- SHA-256 of the normalized claim code (hex); the code itself is never stored
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    claimed_at TIMESTAMPTZ,
+    claimed_by_organization_id CHAR(20) REFERENCES organizations(id)
+);
+
+-- Failed claim attempts per user, counted to rate-limit guessing of claim codes
+CREATE TABLE end_device_claim_attempts (
+    id CHAR(20) PRIMARY KEY,
+    user_id CHAR(20) NOT NULL,
+    attempted_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Organization scoped templates holding the defaults shared by end devices of one kind
+CREATE TABLE end_device_profiles (
+    id CHAR(20) PRIMARY KEY,
+    organization_id CHAR(20) NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT,
+    hardware_type INTEGER NOT NULL, -- maps to EndDeviceHardwareType enum
+    hardware_type_id CHAR(20) REFERENCES lorawan_hardware_types(id), -- LoRaWAN profiles only
+    frequency_plan_id VARCHAR(50) REFERENCES lorawan_frequency_plans(id), -- LoRaWAN profiles only
+    payload_decoder TEXT,
+    expected_fields TEXT[] NOT NULL DEFAULT '{}',
+    labels JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_end_device_profile_name UNIQUE (organization_id, name)
 );
 
+-- The end device profile each end device was created from
+CREATE TABLE end_device_profile_devices (
+    end_device_id CHAR(20) PRIMARY KEY REFERENCES end_devices(id) ON DELETE CASCADE,
+    end_device_profile_id CHAR(20) NOT NULL REFERENCES end_device_profiles(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Decommission workflows of end devices that left service. Rows outlive the end device so the decommission
+-- stays auditable.
+CREATE TABLE end_device_decommissions (
+    id CHAR(20) PRIMARY KEY,
+    end_device_id CHAR(20) NOT NULL UNIQUE,
+    organization_id CHAR(20) NOT NULL REFERENCES organizations(id),
+    requested_by CHAR(20) NOT NULL, -- user that requested the decommission
+    data_policy TEXT NOT NULL, -- 'retain' or 'purge'
+    step TEXT NOT NULL, -- last completed step
+    purge_after TIMESTAMPTZ NOT NULL, -- when the device's ClickHouse data is purged
+    attempts INTEGER NOT NULL DEFAULT 0, -- failures of the current step since it last succeeded
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ,
+    CONSTRAINT valid_data_policy CHECK (data_policy IN ('retain', 'purge')),
+    CONSTRAINT valid_decommission_step CHECK (step IN ('requested', 'disabled', 'deregistered', 'credentials_revoked', 'retaining_data', 'purging_data', 'completed'))
+);
+
+-- Audit trail of every completed or failed step of an end device decommission
+CREATE TABLE end_device_decommission_events (
+    id CHAR(20) PRIMARY KEY,
+    decommission_id CHAR(20) NOT NULL REFERENCES end_device_decommissions(id) ON DELETE CASCADE,
+    step TEXT NOT NULL,
+    error TEXT, -- NULL when the step completed
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Topic and broker credentials of MQTT end devices
+CREATE TABLE mqtt_configs (
+    id CHAR(20) PRIMARY KEY,
+    end_device_id CHAR(20) NOT NULL REFERENCES end_devices(id) ON DELETE CASCADE,
+    topic TEXT NOT NULL, -- topic name the device publishes its data to
+    username TEXT NOT NULL, -- username the device connects to the broker with
+    password_hash TEXT NOT NULL, -- PBKDF2 hash ("PBKDF2$sha512$<iterations>$<salt>$<key>") checked by the broker
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_mqtt_end_device_id UNIQUE (end_device_id),
+    CONSTRAINT unique_mqtt_topic UNIQUE (topic),
+    CONSTRAINT unique_mqtt_username UNIQUE (username)
+);
+
+-- Connection and register map of Modbus TCP end devices, whose registers are polled
+CREATE TABLE modbus_configs (
+    id CHAR(20) PRIMARY KEY,
+    end_device_id CHAR(20) NOT NULL REFERENCES end_devices(id) ON DELETE CASCADE,
+    host TEXT NOT NULL, -- host:port of the device
+    unit_id SMALLINT NOT NULL, -- Modbus unit (slave) ID
+    poll_interval_seconds INTEGER NOT NULL,
+    registers JSONB NOT NULL, -- [{"field", "address", "table", "type", "scale"}]
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_modbus_end_device_id UNIQUE (end_device_id),
+    CONSTRAINT valid_modbus_unit_id CHECK (unit_id BETWEEN 0 AND 255),
+    CONSTRAINT valid_modbus_poll_interval CHECK (poll_interval_seconds > 0)
+);
+
+-- Transfers of end devices between organizations. Rows outlive the end device so the transfer stays auditable.
+CREATE TABLE end_device_transfers (
+    id CHAR(20) PRIMARY KEY,
+    end_device_id CHAR(20) NOT NULL,
+    from_organization_id CHAR(20) NOT NULL REFERENCES organizations(id),
+    to_organization_id CHAR(20) NOT NULL REFERENCES organizations(id),
+    transferred_by CHAR(20) NOT NULL, -- user that transferred the device
+    history TEXT NOT NULL, -- 'keep', 'copy' or 'retag'
+    transferred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    history_transferred_at TIMESTAMPTZ, -- when the ClickHouse data was copied or re-tagged
+    CONSTRAINT valid_transfer_history CHECK (history IN ('keep', 'copy', 'retag')),
+    CONSTRAINT distinct_transfer_organizations CHECK (from_organization_id <> to_organization_id)
+);
+
+-- Payload decoders of LoRaWAN hardware types, run over the uplinks of their devices unless a device profile has one
+CREATE TABLE lorawan_hardware_type_decoders (
+    hardware_type_id CHAR(20) PRIMARY KEY REFERENCES lorawan_hardware_types(id) ON DELETE CASCADE,
+    script TEXT NOT NULL DEFAULT '', -- JavaScript defining decodeUplink(input) like a The Things Stack payload formatter
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    codec TEXT NOT NULL DEFAULT 'javascript', -- javascript, cayenne_lpp or byte_layout
+    byte_layout JSONB -- [{"field", "offset", "length", "endianness", "signed", "scale"}] of byte_layout decoders
+);
+
+-- Downlinks pushed to the network server's queue of LoRaWAN end devices, newest first per device
+CREATE TABLE lorawan_downlinks (
+    id CHAR(20) PRIMARY KEY,
+    end_device_id CHAR(20) NOT NULL REFERENCES end_devices(id) ON DELETE CASCADE,
+    f_port INTEGER NOT NULL,
+    frm_payload BYTEA NOT NULL,
+    data JSONB, -- what frm_payload was encoded from by the device's payload decoder; NULL for raw payloads
+    confirmed BOOLEAN NOT NULL DEFAULT FALSE,
+    status TEXT NOT NULL, -- 'queued', 'sent', 'acked' or 'failed'
+    error TEXT NOT NULL DEFAULT '', -- why the downlink failed or was queued again
+    requested_by CHAR(20) NOT NULL, -- user that scheduled the downlink
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT valid_downlink_f_port CHECK (f_port BETWEEN 1 AND 223),
+    CONSTRAINT valid_downlink_status CHECK (status IN ('queued', 'sent', 'acked', 'failed'))
+);
+
+-- LoRaWAN gateways operated by an organization, registered with The Things Stack under their ID
+CREATE TABLE gateways (
+    id CHAR(20) PRIMARY KEY, -- also the gateway ID in The Things Stack
+    organization_id CHAR(20) NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT,
+    gateway_eui CHAR(16) NOT NULL,
+    frequency_plan_id VARCHAR(50) NOT NULL REFERENCES lorawan_frequency_plans(id),
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    altitude INTEGER, -- meters above sea level
+    location_source TEXT, -- 'registry' when set through ponix, 'status' when reported by the gateway's GPS
+    status TEXT NOT NULL DEFAULT 'unknown', -- 'unknown' until the Gateway Server is asked, then 'online' or 'offline'
+    last_seen_at TIMESTAMPTZ, -- last connection, status message or uplink of the gateway
+    status_checked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_gateway_eui UNIQUE (gateway_eui),
+    CONSTRAINT valid_gateway_eui CHECK (gateway_eui ~ '^[0-9A-F]{16}$'),
+    CONSTRAINT valid_gateway_status CHECK (status IN ('unknown', 'online', 'offline')),
+    CONSTRAINT valid_gateway_location_source CHECK (location_source IN ('registry', 'status')),
+    CONSTRAINT complete_gateway_location CHECK ((latitude IS NULL) = (longitude IS NULL) AND (latitude IS NULL) = (location_source IS NULL))
+);
+
+-- Full-text document of an end device, searched by SearchEndDevices. Names weigh most, then descriptions, then the
+-- keys and values of labels. The function is immutable so the document can be indexed without storing it.
+CREATE FUNCTION end_device_search_document(name TEXT, description TEXT, labels JSONB) RETURNS tsvector
+LANGUAGE SQL IMMUTABLE PARALLEL SAFE
+AS $$
+    SELECT setweight(to_tsvector('english', name), 'A')
+        || setweight(to_tsvector('english', coalesce(description, '')), 'B')
+        || setweight(jsonb_to_tsvector('english', labels, '["key", "string"]'), 'C')
+$$;
+
 -- Indexes for performance
 CREATE INDEX idx_end_devices_org_id ON end_devices(organization_id);
 CREATE INDEX idx_end_devices_status ON end_devices(status);
@@ -122,6 +374,20 @@ CREATE INDEX idx_lorawan_configs_app_id ON lorawan_configs(application_id);
 CREATE INDEX idx_lorawan_configs_hardware_type ON lorawan_configs(hardware_type_id);
 CREATE INDEX idx_user_organizations_user_id ON user_organizations(user_id);
 CREATE INDEX idx_user_organizations_org_id ON user_organizations(organization_id);
+CREATE INDEX idx_device_group_members_end_device_id ON device_group_members(end_device_id);
+CREATE INDEX idx_end_device_presence_last_seen_at ON end_device_presence(last_seen_at);
+CREATE INDEX idx_end_device_message_counts_hour_start ON end_device_message_counts(hour_start);
+CREATE INDEX idx_end_device_status_transitions_end_device_id ON end_device_status_transitions(end_device_id, transitioned_at DESC);
+CREATE INDEX idx_eui_blocks_organization ON eui_blocks(organization_id, created_at);
+CREATE INDEX idx_lorawan_root_key_rotations_end_device_id ON lorawan_root_key_rotations(end_device_id, rotated_at DESC);
+CREATE INDEX idx_end_device_claim_attempts_user_id ON end_device_claim_attempts(user_id, attempted_at);
+CREATE INDEX idx_end_device_profile_devices_profile_id ON end_device_profile_devices(end_device_profile_id);
+CREATE INDEX idx_end_device_decommissions_next_attempt_at ON end_device_decommissions(next_attempt_at) WHERE step <> 'completed';
+CREATE INDEX idx_end_device_decommission_events_decommission_id ON end_device_decommission_events(decommission_id, occurred_at);
+CREATE INDEX idx_end_device_transfers_end_device_id ON end_device_transfers(end_device_id, transferred_at DESC);
+CREATE INDEX idx_lorawan_downlinks_end_device_id ON lorawan_downlinks(end_device_id, created_at DESC);
+CREATE INDEX idx_gateways_organization_id ON gateways(organization_id, name);
+CREATE INDEX idx_end_devices_search ON end_devices USING GIN (end_device_search_document(name, description, labels));
 
 -- Insert default frequency plans
 INSERT INTO lorawan_frequency_plans (id, name, description, region) VALUES